@@ -0,0 +1,155 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"database/sql/driver"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// inFlightQueries are the request IDs of the queries submitted by a connection and not yet returned, and the
+// number of the statements being run by the connection.
+type inFlightQueries struct {
+	mu         sync.Mutex
+	requestIDs map[uuid.UUID]struct{}
+	running    int
+	idle       chan struct{} // closed when no statement runs. nil if none runs
+}
+
+// begin records that a statement runs until the returned function is called.
+func (q *inFlightQueries) begin() func() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.running == 0 {
+		q.idle = make(chan struct{})
+	}
+	q.running++
+	return func() {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		if q.running--; q.running == 0 {
+			close(q.idle)
+			q.idle = nil
+		}
+	}
+}
+
+// wait waits until no statement runs or the context is done.
+func (q *inFlightQueries) wait(ctx context.Context) error {
+	q.mu.Lock()
+	idle := q.idle
+	q.mu.Unlock()
+	if idle == nil {
+		return nil
+	}
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q *inFlightQueries) add(requestID uuid.UUID) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.requestIDs == nil {
+		q.requestIDs = make(map[uuid.UUID]struct{})
+	}
+	q.requestIDs[requestID] = struct{}{}
+}
+
+func (q *inFlightQueries) remove(requestID uuid.UUID) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.requestIDs, requestID)
+}
+
+func (q *inFlightQueries) list() []uuid.UUID {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	ids := make([]uuid.UUID, 0, len(q.requestIDs))
+	for id := range q.requestIDs {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// AbortAll aborts every query the connection has submitted and that has not returned yet, e.g., for a fast
+// shutdown of a batch worker. The aborted queries return an error. The asynchronous queries, which return once
+// Snowflake accepts them, are not aborted. It is safe to call while the connection runs queries, and returns the
+// first error of the aborts, if any.
+func (sc *snowflakeConn) AbortAll(ctx context.Context) error {
+	rest := sc.rest
+	if rest == nil {
+		return driver.ErrBadConn
+	}
+	var firstErr error
+	for _, requestID := range sc.inFlight.list() {
+		glog.V(1).Infof("aborting the query of request %v", requestID)
+		requestID := requestID
+		if err := rest.FuncCancelQuery(ctx, rest, &requestID, rest.RequestTimeout); err != nil {
+			glog.V(1).Infof("failed to abort the query of request %v. err: %v", requestID, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// abortOnClose aborts the queries in flight before the connection is closed, and waits until they return, so
+// that the session is closed only after.
+func (sc *snowflakeConn) abortOnClose() {
+	ctx := context.Background()
+	if sc.rest.RequestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, sc.rest.RequestTimeout)
+		defer cancel()
+	}
+	if err := sc.AbortAll(ctx); err != nil {
+		glog.V(1).Infof("failed to abort the queries on close. err: %v", err)
+	}
+	if err := sc.inFlight.wait(ctx); err != nil {
+		glog.V(1).Infof("the aborted queries didn't return. err: %v", err)
+	}
+}
+
+// openConns are the open connections of a Connector, whose queries its AbortAll aborts.
+type openConns struct {
+	mu    sync.Mutex
+	conns map[*snowflakeConn]struct{}
+}
+
+func (c *openConns) add(sc *snowflakeConn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conns == nil {
+		c.conns = make(map[*snowflakeConn]struct{})
+	}
+	c.conns[sc] = struct{}{}
+	sc.openConns = c
+}
+
+func (c *openConns) remove(sc *snowflakeConn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.conns, sc)
+}
+
+// abortAll aborts the queries in flight of the connections. The connections are not closed meanwhile, as they
+// remove themselves first.
+func (c *openConns) abortAll(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var firstErr error
+	for sc := range c.conns {
+		if err := sc.AbortAll(ctx); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,196 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestAbortAll(t *testing.T) {
+	errAborted := errors.New("aborted")
+	submitted := make(chan uuid.UUID, 2)
+	aborted := make(chan uuid.UUID, 2)
+	sc := &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{
+			FuncPostQuery: func(ctx context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, _ []byte, _ time.Duration, requestID *uuid.UUID) (*execResponse, error) {
+				submitted <- *requestID
+				select {
+				case id := <-aborted:
+					if id != *requestID {
+						t.Errorf("unexpected request aborted. expected: %v, got: %v", *requestID, id)
+					}
+					return nil, errAborted
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				}
+			},
+			FuncCancelQuery: func(_ context.Context, _ *snowflakeRestful, requestID *uuid.UUID, _ time.Duration) error {
+				aborted <- *requestID
+				return nil
+			},
+			FuncCloseSession: func(context.Context, *snowflakeRestful, time.Duration) error {
+				return nil
+			},
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		_, err := sc.ExecContext(ctx, "CALL long_running()", nil)
+		done <- err
+	}()
+	<-submitted
+	if err := sc.AbortAll(ctx); err != nil {
+		t.Fatalf("failed to abort. err: %v", err)
+	}
+	if err := <-done; err != errAborted {
+		t.Fatalf("the query should have been aborted. err: %v", err)
+	}
+	if ids := sc.inFlight.list(); len(ids) != 0 {
+		t.Fatalf("no query should be in flight. got: %v", ids)
+	}
+	if err := sc.AbortAll(ctx); err != nil {
+		t.Fatalf("should do nothing without queries in flight. err: %v", err)
+	}
+
+	sc.cfg.AbortOnClose = true
+	go func() {
+		_, err := sc.ExecContext(ctx, "CALL long_running()", nil)
+		done <- err
+	}()
+	<-submitted
+	if err := sc.Close(); err != nil {
+		t.Fatalf("failed to close. err: %v", err)
+	}
+	if err := <-done; err != errAborted {
+		t.Fatalf("the query should have been aborted on close. err: %v", err)
+	}
+}
+
+// abortTestServer is a Snowflake whose queries run until they are aborted.
+type abortTestServer struct {
+	*httptest.Server
+	submitted chan string
+	mu        sync.Mutex
+	running   map[string]chan struct{}
+}
+
+func newAbortTestServer() *abortTestServer {
+	s := &abortTestServer{submitted: make(chan string, 10), running: make(map[string]chan struct{})}
+	respond := func(w http.ResponseWriter, data interface{}, code string, success bool) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data, "code": code, "success": success})
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/session/v1/login-request", func(w http.ResponseWriter, _ *http.Request) {
+		respond(w, map[string]interface{}{"token": "t", "masterToken": "m", "sessionId": 1}, "", true)
+	})
+	mux.HandleFunc("/session", func(w http.ResponseWriter, _ *http.Request) {
+		respond(w, nil, "", true)
+	})
+	mux.HandleFunc("/queries/v1/query-request", func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.URL.Query().Get(requestIDKey)
+		aborted := make(chan struct{})
+		s.mu.Lock()
+		s.running[requestID] = aborted
+		s.mu.Unlock()
+		s.submitted <- requestID
+		select {
+		case <-aborted:
+			respond(w, nil, "000604", false)
+		case <-r.Context().Done():
+		}
+	})
+	mux.HandleFunc("/queries/v1/abort-request", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		json.NewDecoder(r.Body).Decode(&req)
+		s.mu.Lock()
+		if aborted, ok := s.running[req[requestIDKey]]; ok {
+			close(aborted)
+			delete(s.running, req[requestIDKey])
+		}
+		s.mu.Unlock()
+		respond(w, nil, "", true)
+	})
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+func (s *abortTestServer) config() Config {
+	u, _ := url.Parse(s.URL)
+	port, _ := strconv.Atoi(u.Port())
+	return Config{Account: "a", User: "u", Password: "p", Protocol: "http", Host: u.Hostname(), Port: port}
+}
+
+func TestConnectorAbortAll(t *testing.T) {
+	srv := newAbortTestServer()
+	defer srv.Close()
+	connector := NewConnector(SnowflakeDriver{}, srv.config())
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	done := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := db.ExecContext(ctx, "CALL long_running()")
+			done <- err
+		}()
+	}
+	<-srv.submitted
+	<-srv.submitted
+	if err := connector.AbortAll(ctx); err != nil {
+		t.Fatalf("failed to abort. err: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		err := <-done
+		if driverErr, ok := err.(*SnowflakeError); !ok || driverErr.Number != 604 {
+			t.Fatalf("the queries of every connection should be aborted. err: %v", err)
+		}
+	}
+	if db.Stats().OpenConnections != 2 || len(connector.conns.conns) != 2 {
+		t.Fatalf("the connections should stay open. stats: %+v", db.Stats())
+	}
+	db.SetMaxIdleConns(0)
+	if len(connector.conns.conns) != 0 {
+		t.Fatalf("the closed connections should be forgotten. connections: %v", len(connector.conns.conns))
+	}
+}
+
+func TestConnectorAbortOnClose(t *testing.T) {
+	srv := newAbortTestServer()
+	defer srv.Close()
+	cfg := srv.config()
+	cfg.AbortOnClose = true
+	db := sql.OpenDB(NewConnector(SnowflakeDriver{}, cfg))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		_, err := db.ExecContext(ctx, "CALL long_running()")
+		done <- err
+	}()
+	<-srv.submitted
+	if err := db.Close(); err != nil {
+		t.Fatalf("failed to close. err: %v", err)
+	}
+	err := <-done
+	if driverErr, ok := err.(*SnowflakeError); !ok || driverErr.Number != 604 {
+		t.Fatalf("the query should be aborted when the sql.DB is closed. err: %v", err)
+	}
+}
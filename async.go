@@ -0,0 +1,106 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"time"
+)
+
+// asyncPollInterval is the interval to poll the status of an asynchronous query.
+var asyncPollInterval = time.Second
+
+// AsyncQueryResult is the completion of an asynchronous query. Status is the final status reported by
+// Snowflake, e.g., SUCCESS or FAILED_WITH_ERROR. Err is set if the query didn't succeed or its status
+// could not be retrieved.
+type AsyncQueryResult struct {
+	QueryID string
+	Status  string
+	Err     error
+}
+
+// AsyncCompletionFunc is called once when an asynchronous query completes. It is called from a goroutine
+// of the driver.
+type AsyncCompletionFunc func(AsyncQueryResult)
+
+// WithAsyncCompletion returns a context that runs the statements executed with ExecContext asynchronously.
+// ExecContext returns as soon as Snowflake accepts the statement, with the query ID available from
+// SnowflakeResult, and the driver polls the status of the query until it completes and calls fn. The result
+// of a query can be fetched afterwards with WithFetchResultByID. Polling outlives the context, which may well
+// be canceled once ExecContext returns, and stops only when Config.BackgroundContext is done. If fn is nil, the
+// statements are run asynchronously without polling their status.
+func WithAsyncCompletion(ctx context.Context, fn AsyncCompletionFunc) context.Context {
+	return context.WithValue(ctx, asyncCompletion, fn)
+}
+
+// WithAsyncCompletionChan is the same as WithAsyncCompletion except that the completion is sent to ch. As the
+// polling, the send outlives the context, and is abandoned only if Config.BackgroundContext is done before ch is
+// ready to receive.
+func WithAsyncCompletionChan(ctx context.Context, ch chan<- AsyncQueryResult) context.Context {
+	return WithAsyncCompletion(context.WithValue(ctx, asyncCompletionChan, ch), nil)
+}
+
+// asyncNotifier returns the function notified of the completion of the asynchronous query of the context, or nil
+// if the query is not polled. The completion of WithAsyncCompletionChan is sent until the background context of
+// the connection is done.
+func asyncNotifier(ctx context.Context, bgCtx context.Context) AsyncCompletionFunc {
+	if ch, ok := ctx.Value(asyncCompletionChan).(chan<- AsyncQueryResult); ok {
+		return func(res AsyncQueryResult) {
+			select {
+			case ch <- res:
+				return
+			default:
+			}
+			select {
+			case ch <- res:
+			case <-bgCtx.Done():
+			}
+		}
+	}
+	fn, _ := ctx.Value(asyncCompletion).(AsyncCompletionFunc)
+	return fn
+}
+
+func isAsyncMode(ctx context.Context) bool {
+	_, ok := ctx.Value(asyncCompletion).(AsyncCompletionFunc)
+	return ok
+}
+
+// detachedContext has the values of a context, e.g., of the statement, but the deadline and the cancellation
+// of another, e.g., the background context of the connection.
+type detachedContext struct {
+	context.Context
+	values context.Context
+}
+
+func (dc detachedContext) Value(key interface{}) interface{} {
+	return dc.values.Value(key)
+}
+
+// watchAsyncQuery polls the status of the query until it completes and notifies the AsyncCompletionFunc
+// of the context. The polling keeps the values of the context but not its cancellation, and stops when the
+// background context of the connection is done.
+func (sc *snowflakeConn) watchAsyncQuery(ctx context.Context, bgCtx context.Context, qid string) {
+	fn := asyncNotifier(ctx, bgCtx)
+	ctx = detachedContext{Context: bgCtx, values: ctx}
+	for {
+		status, err := sc.getQueryStatus(ctx, qid)
+		if err != nil {
+			glog.V(1).Infof("failed to get the status of query %v. err: %v", qid, err)
+			fn(AsyncQueryResult{QueryID: qid, Err: err})
+			return
+		}
+		if !isQueryRunning(status.Status) {
+			fn(AsyncQueryResult{QueryID: qid, Status: status.Status, Err: status.err()})
+			return
+		}
+		await := time.NewTimer(asyncPollInterval)
+		select {
+		case <-await.C:
+		case <-ctx.Done():
+			await.Stop()
+			fn(AsyncQueryResult{QueryID: qid, Status: status.Status, Err: ctx.Err()})
+			return
+		}
+	}
+}
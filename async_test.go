@@ -0,0 +1,279 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func postAsyncQueryMock(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte, _ time.Duration, _ *uuid.UUID) (*execResponse, error) {
+	var req execRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+	if !req.AsyncExec {
+		return nil, &SnowflakeError{Number: -1, Message: "the query should be asynchronous"}
+	}
+	return &execResponse{
+		Data:    execResponseData{QueryID: "async-1"},
+		Code:    queryInProgressAsyncCode,
+		Success: true,
+	}, nil
+}
+
+func getQueryStatusMock(statuses ...monitoringQuery) func(context.Context, *snowflakeRestful, *url.URL, map[string]string, time.Duration) (*http.Response, error) {
+	var polled int
+	return func(_ context.Context, _ *snowflakeRestful, fullURL *url.URL, _ map[string]string, _ time.Duration) (*http.Response, error) {
+		if fullURL.Path != "/monitoring/queries/async-1" {
+			return &http.Response{StatusCode: http.StatusNotFound, Body: &fakeResponseBody{}}, nil
+		}
+		status := statuses[intMin(polled, len(statuses)-1)]
+		polled++
+		ba, err := json.Marshal(&monitoringResponse{
+			Data:    monitoringResponseData{Queries: []monitoringQuery{status}},
+			Success: true,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(bytes.NewReader(ba))}, nil
+	}
+}
+
+func TestAsyncCompletionChan(t *testing.T) {
+	origInterval := asyncPollInterval
+	asyncPollInterval = time.Millisecond
+	defer func() { asyncPollInterval = origInterval }()
+
+	sc := &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{
+			FuncPostQuery: postAsyncQueryMock,
+			FuncGet: getQueryStatusMock(
+				monitoringQuery{ID: "async-1", Status: queryStatusQueued},
+				monitoringQuery{ID: "async-1", Status: queryStatusRunning},
+				monitoringQuery{ID: "async-1", Status: queryStatusSuccess}),
+		},
+	}
+	ch := make(chan AsyncQueryResult, 1)
+	res, err := sc.ExecContext(WithAsyncCompletionChan(context.Background(), ch), "INSERT INTO t VALUES(1)", nil)
+	if err != nil {
+		t.Fatalf("failed to execute the statement. err: %v", err)
+	}
+	if qid := res.(SnowflakeResult).QueryID(); qid != "async-1" {
+		t.Fatalf("unexpected query ID: %v", qid)
+	}
+	select {
+	case completion := <-ch:
+		if completion.QueryID != "async-1" || completion.Status != queryStatusSuccess || completion.Err != nil {
+			t.Fatalf("unexpected completion: %+v", completion)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the completion was not delivered")
+	}
+}
+
+func TestAsyncCompletionCanceledContext(t *testing.T) {
+	origInterval := asyncPollInterval
+	asyncPollInterval = time.Millisecond
+	defer func() { asyncPollInterval = origInterval }()
+
+	sc := &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{
+			FuncPostQuery: postAsyncQueryMock,
+			FuncGet: getQueryStatusMock(
+				monitoringQuery{ID: "async-1", Status: queryStatusRunning},
+				monitoringQuery{ID: "async-1", Status: queryStatusSuccess}),
+		},
+	}
+	done := make(chan AsyncQueryResult, 1)
+	ctx, cancel := context.WithCancel(WithAsyncCompletion(context.Background(), func(res AsyncQueryResult) {
+		done <- res
+	}))
+	if _, err := sc.ExecContext(ctx, "INSERT INTO t VALUES(1)", nil); err != nil {
+		t.Fatalf("failed to execute the statement. err: %v", err)
+	}
+	// the context of the statement ends with ExecContext
+	cancel()
+	select {
+	case completion := <-done:
+		if completion.Status != queryStatusSuccess || completion.Err != nil {
+			t.Fatalf("the polling should outlive the context of the statement. completion: %+v", completion)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the completion was not delivered")
+	}
+}
+
+func TestAsyncCompletionChanCanceledContext(t *testing.T) {
+	origInterval := asyncPollInterval
+	asyncPollInterval = time.Millisecond
+	defer func() { asyncPollInterval = origInterval }()
+
+	sc := &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{
+			FuncPostQuery: postAsyncQueryMock,
+			FuncGet:       getQueryStatusMock(monitoringQuery{ID: "async-1", Status: queryStatusSuccess}),
+		},
+	}
+	ch := make(chan AsyncQueryResult)
+	ctx, cancel := context.WithCancel(WithAsyncCompletionChan(context.Background(), ch))
+	if _, err := sc.ExecContext(ctx, "INSERT INTO t VALUES(1)", nil); err != nil {
+		t.Fatalf("failed to execute the statement. err: %v", err)
+	}
+	cancel()
+	// the completion is sent once ch is ready to receive, well after the context of the statement is canceled
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case completion := <-ch:
+		if completion.Status != queryStatusSuccess || completion.Err != nil {
+			t.Fatalf("unexpected completion: %+v", completion)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the send should not be abandoned when the context of the statement is canceled")
+	}
+}
+
+func TestAsyncCompletionFailed(t *testing.T) {
+	origInterval := asyncPollInterval
+	asyncPollInterval = time.Millisecond
+	defer func() { asyncPollInterval = origInterval }()
+
+	sc := &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{
+			FuncPostQuery: postAsyncQueryMock,
+			FuncGet: getQueryStatusMock(
+				monitoringQuery{ID: "async-1", Status: queryStatusNoData},
+				monitoringQuery{ID: "async-1", Status: "FAILED_WITH_ERROR", ErrorCode: "002003", ErrorMessage: "Table 'T' does not exist"}),
+		},
+	}
+	done := make(chan AsyncQueryResult, 1)
+	ctx := WithAsyncCompletion(context.Background(), func(res AsyncQueryResult) {
+		done <- res
+	})
+	if _, err := sc.ExecContext(ctx, "INSERT INTO t VALUES(1)", nil); err != nil {
+		t.Fatalf("failed to execute the statement. err: %v", err)
+	}
+	select {
+	case completion := <-done:
+		driverErr, ok := completion.Err.(*SnowflakeError)
+		if !ok || driverErr.Number != 2003 || driverErr.QueryID != "async-1" {
+			t.Fatalf("unexpected error: %v", completion.Err)
+		}
+		if completion.Status != "FAILED_WITH_ERROR" {
+			t.Fatalf("unexpected status: %v", completion.Status)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the completion was not delivered")
+	}
+}
+
+func TestQueryStatus(t *testing.T) {
+	sc := &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{
+			FuncGet: getQueryStatusMock(monitoringQuery{
+				ID:               "async-1",
+				Status:           queryStatusQueued,
+				WarehouseName:    "WH1",
+				StartTime:        1600000000000,
+				QueryLoadPercent: 40,
+				Stats: monitoringQueryStats{
+					QueuedProvisioningTime: 1500,
+					QueuedOverloadTime:     2500,
+				},
+			}),
+		},
+	}
+	qs, err := sc.QueryStatus(context.Background(), "async-1")
+	if err != nil {
+		t.Fatalf("failed to get the query status. err: %v", err)
+	}
+	if !qs.Running || qs.Err != nil || qs.Status != queryStatusQueued || qs.WarehouseName != "WH1" {
+		t.Fatalf("unexpected status: %+v", qs)
+	}
+	if qs.QueuedTime() != 4*time.Second || qs.QueuedOverloadTime != 2500*time.Millisecond {
+		t.Fatalf("unexpected queued time: %v", qs.QueuedTime())
+	}
+	if qs.LoadPercent != 40 || !qs.StartTime.Equal(time.Unix(1600000000, 0)) || !qs.EndTime.IsZero() {
+		t.Fatalf("unexpected status: %+v", qs)
+	}
+
+	sc.rest.FuncGet = getQueryStatusMock(monitoringQuery{
+		ID:               "async-1",
+		Status:           queryStatusSuccess,
+		WarehouseName:    "WH1",
+		WarehouseSize:    "Medium",
+		TotalDuration:    3604000,
+		QueryLoadPercent: 50,
+		Stats: monitoringQueryStats{
+			QueuedOverloadTime:     4000,
+			ScanBytes:              1 << 20,
+			ScanAssignedPartitions: 12,
+			ScanOriginalPartitions: 300,
+		},
+	})
+	if qs, err = sc.QueryStatus(context.Background(), "async-1"); err != nil {
+		t.Fatalf("failed to get the query status. err: %v", err)
+	}
+	if qs.TotalElapsed != 3604*time.Second || qs.WarehouseSize != "Medium" || qs.ScanBytes != 1<<20 ||
+		qs.PartitionsScanned != 12 || qs.PartitionsTotal != 300 {
+		t.Fatalf("unexpected status: %+v", qs)
+	}
+	// an hour on a medium warehouse, which consumes 4 credits per hour, with half of its load
+	if credits := qs.EstimatedCredits(); credits != 2 {
+		t.Fatalf("unexpected estimated credits: %v", credits)
+	}
+	for _, size := range []string{"X-Small", "XSMALL", "x-small"} {
+		if credits := (&QueryStatus{WarehouseSize: size, TotalElapsed: time.Hour}).EstimatedCredits(); credits != 1 {
+			t.Errorf("unexpected estimated credits of %v: %v", size, credits)
+		}
+	}
+	if credits := (&QueryStatus{TotalElapsed: time.Hour}).EstimatedCredits(); credits != 0 {
+		t.Errorf("the credits of an unknown size should be 0: %v", credits)
+	}
+
+	if _, err = sc.QueryStatus(context.Background(), "unknown"); err == nil {
+		t.Fatal("should have failed to get the status of an unknown query")
+	}
+}
+
+func TestAsyncCompletionBackgroundContext(t *testing.T) {
+	origInterval := asyncPollInterval
+	asyncPollInterval = time.Millisecond
+	defer func() { asyncPollInterval = origInterval }()
+
+	bgCtx, cancel := context.WithCancel(context.Background())
+	sc := &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}, BackgroundContext: bgCtx},
+		rest: &snowflakeRestful{
+			FuncPostQuery: postAsyncQueryMock,
+			FuncGet:       getQueryStatusMock(monitoringQuery{ID: "async-1", Status: queryStatusRunning}),
+		},
+	}
+	ch := make(chan AsyncQueryResult, 1)
+	if _, err := sc.ExecContext(WithAsyncCompletionChan(context.Background(), ch), "INSERT INTO t VALUES(1)", nil); err != nil {
+		t.Fatalf("failed to execute the statement. err: %v", err)
+	}
+	cancel()
+	select {
+	case completion := <-ch:
+		if completion.QueryID != "async-1" || completion.Err != context.Canceled {
+			t.Fatalf("unexpected completion: %+v", completion)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the watch should stop with the background context")
+	}
+}
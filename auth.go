@@ -4,14 +4,17 @@ package gosnowflake
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/dgrijalva/jwt-go/v4"
@@ -24,6 +27,8 @@ import (
 
 const (
 	clientType = "Go"
+	// nextActionPasswordChange is the next action of a login rejected because the password has expired
+	nextActionPasswordChange = "PWD_CHANGE"
 )
 
 // AuthType indicates the type of authentication in Snowflake
@@ -40,6 +45,9 @@ const (
 	AuthTypeOkta
 	// AuthTypeJwt is to use Jwt to perform authentication
 	AuthTypeJwt
+	// AuthTypeAzureAD is to use an Azure AD token acquired from the managed identity or workload identity
+	// of the environment to perform External OAuth authentication
+	AuthTypeAzureAD
 )
 
 func determineAuthenticatorType(cfg *Config, value string) error {
@@ -57,6 +65,9 @@ func determineAuthenticatorType(cfg *Config, value string) error {
 	} else if upperCaseValue == AuthTypeExternalBrowser.String() {
 		cfg.Authenticator = AuthTypeExternalBrowser
 		return nil
+	} else if upperCaseValue == AuthTypeAzureAD.String() {
+		cfg.Authenticator = AuthTypeAzureAD
+		return nil
 	} else {
 		// possibly Okta case
 		oktaURLString, err := url.QueryUnescape(lowerCaseValue)
@@ -102,6 +113,8 @@ func (authType AuthType) String() string {
 		return "OKTA"
 	case AuthTypeJwt:
 		return "SNOWFLAKE_JWT"
+	case AuthTypeAzureAD:
+		return "AZURE_AD"
 	default:
 		return "UNKNOWN"
 	}
@@ -122,12 +135,37 @@ var userAgent = fmt.Sprintf("%v/%v/%v/%v/%v-%v",
 	operatingSystem,
 	runtime.GOARCH)
 
+// userAgentFor returns the User-Agent including the application name if it is given.
+func userAgentFor(cfg *Config) string {
+	if cfg.Application == "" || cfg.Application == clientType {
+		return userAgent
+	}
+	return fmt.Sprintf("%v (%v)", userAgent, cfg.Application)
+}
+
 type authRequestClientEnvironment struct {
-	Application string `json:"APPLICATION"`
-	Os          string `json:"OS"`
-	OsVersion   string `json:"OS_VERSION"`
-	OCSPMode    string `json:"OCSP_MODE"`
+	Application string            `json:"APPLICATION"`
+	Os          string            `json:"OS"`
+	OsVersion   string            `json:"OS_VERSION"`
+	OCSPMode    string            `json:"OCSP_MODE"`
+	Extra       map[string]string `json:"-"`
 }
+
+// MarshalJSON adds the extra client environment entries to the fixed ones. The fixed entries take precedence.
+func (env authRequestClientEnvironment) MarshalJSON() ([]byte, error) {
+	m := make(map[string]string, len(env.Extra)+4)
+	for k, v := range env.Extra {
+		m[strings.ToUpper(k)] = v
+	}
+	m["APPLICATION"] = env.Application
+	m["OS"] = env.Os
+	m["OS_VERSION"] = env.OsVersion
+	if env.OCSPMode != "" {
+		m["OCSP_MODE"] = env.OCSPMode
+	}
+	return json.Marshal(m)
+}
+
 type authRequestData struct {
 	ClientAppID             string                       `json:"CLIENT_APP_ID"`
 	ClientAppVersion        string                       `json:"CLIENT_APP_VERSION"`
@@ -144,9 +182,11 @@ type authRequestData struct {
 	BrowserModeRedirectPort string                       `json:"BROWSER_MODE_REDIRECT_PORT,omitempty"`
 	ProofKey                string                       `json:"PROOF_KEY,omitempty"`
 	Token                   string                       `json:"TOKEN,omitempty"`
+	ChosenNewPassword       string                       `json:"CHOSEN_NEW_PASSWORD,omitempty"`
 }
 type authRequest struct {
-	Data authRequestData `json:"data"`
+	Data        authRequestData `json:"data"`
+	InFlightCtx string          `json:"inFlightCtx,omitempty"`
 }
 
 type nameValueParameter struct {
@@ -179,6 +219,8 @@ type authResponseMain struct {
 	TokenURL            string                  `json:"tokenUrl,omitempty"`
 	SSOURL              string                  `json:"ssoUrl,omitempty"`
 	ProofKey            string                  `json:"proofKey,omitempty"`
+	NextAction          string                  `json:"nextAction,omitempty"`
+	InFlightCtx         string                  `json:"inFlightCtx,omitempty"`
 }
 type authResponse struct {
 	Data    authResponseMain `json:"data"`
@@ -200,11 +242,16 @@ func postAuth(
 
 	fullURL := sr.getFullURL(loginRequestPath, params)
 	glog.V(2).Infof("full URL: %v", fullURL)
+	// the redirects are validated by the driver as the login has the credentials
+	ctx = context.WithValue(ctx, noFollowRedirect, true)
 	resp, err := sr.FuncPost(ctx, sr, fullURL, headers, body, timeout, true)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	defer drainAndClose(resp.Body)
+	if isRedirectStatus(resp.StatusCode) {
+		return nil, newClientRedirectError(resp, fullURL)
+	}
 	if resp.StatusCode == http.StatusOK {
 		var respd authResponse
 		err = json.NewDecoder(resp.Body).Decode(&respd)
@@ -216,6 +263,20 @@ func postAuth(
 		return &respd, nil
 	}
 	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		var retryAfter time.Duration
+		if sec, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && sec > 0 {
+			retryAfter = time.Duration(sec) * time.Second
+		}
+		return nil, &LoginThrottledError{
+			SnowflakeError: SnowflakeError{
+				Number:      ErrCodeLoginThrottled,
+				SQLState:    SQLStateConnectionWasNotEstablished,
+				Message:     errMsgLoginThrottled,
+				MessageArgs: []interface{}{resp.StatusCode, fullURL},
+			},
+			RetryAfter: retryAfter,
+		}
 	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
 		// service availability or connectivity issue. Most likely server side issue.
 		return nil, &SnowflakeError{
@@ -252,14 +313,141 @@ func postAuth(
 
 // Generates a map of headers needed to authenticate
 // with Snowflake.
-func getHeaders() map[string]string {
+func getHeaders(sr *snowflakeRestful) map[string]string {
 	headers := make(map[string]string)
 	headers["Content-Type"] = headerContentTypeApplicationJSON
 	headers["accept"] = headerAcceptTypeApplicationSnowflake
-	headers["User-Agent"] = userAgent
+	headers["User-Agent"] = sr.getUserAgent()
 	return headers
 }
 
+// loginThrottleWaitAlgo is the backoff for the logins throttled by Snowflake.
+var loginThrottleWaitAlgo = &waitAlgo{
+	mutex: &sync.Mutex{},
+	base:  2 * time.Second,
+	cap:   30 * time.Second,
+}
+
+// authenticateWithBackoff retries the login throttled by Snowflake with backoff as long as the login timeout
+// allows. The last LoginThrottledError is returned if the login is still throttled.
+//
+// If LoginAttemptTimeout is set, a login attempt that times out is retried with the timeout doubled, and a
+// LoginTimeoutError is returned if no attempt completes within the login timeout.
+func authenticateWithBackoff(ctx context.Context, sc *snowflakeConn) (*authResponseMain, error) {
+	start := time.Now()
+	deadline := start.Add(sc.cfg.LoginTimeout)
+	var sleep time.Duration
+	var timedOut []LoginAttempt
+	for attempt := 0; ; attempt++ {
+		var authData *authResponseMain
+		var err error
+		if timeout := loginAttemptTimeout(sc.cfg, len(timedOut), deadline); timeout > 0 {
+			var la *LoginAttempt
+			authData, la, err = authenticateWithTimeout(ctx, sc, timeout)
+			if la != nil {
+				timedOut = append(timedOut, *la)
+				if time.Now().Before(deadline) {
+					glog.V(1).Infof("login attempt timed out after %v. retrying", la.Elapsed)
+					continue
+				}
+				return nil, newLoginTimeoutError(time.Since(start), timedOut)
+			}
+		} else if len(timedOut) > 0 {
+			return nil, newLoginTimeoutError(time.Since(start), timedOut)
+		} else {
+			authData, err = authenticateWithCredentials(ctx, sc)
+		}
+		throttled, ok := err.(*LoginThrottledError)
+		if !ok {
+			return authData, err
+		}
+		sleep = loginThrottleWaitAlgo.decorr(attempt, sleep)
+		if throttled.RetryAfter > sleep {
+			sleep = throttled.RetryAfter
+		}
+		if time.Now().Add(sleep).After(deadline) {
+			return nil, err
+		}
+		glog.V(1).Infof("login was throttled. retrying in %v. err: %v", sleep, err)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(sleep):
+		}
+	}
+}
+
+// loginAttemptTimeout returns the timeout of the login attempt after the number of attempts timed out, which
+// is LoginAttemptTimeout doubled for each of them up to the deadline. It is zero if the login attempts have no
+// timeout of their own or the deadline has passed.
+func loginAttemptTimeout(cfg *Config, timedOut int, deadline time.Time) time.Duration {
+	if cfg.LoginAttemptTimeout <= 0 {
+		return 0
+	}
+	remaining := time.Until(deadline)
+	if remaining <= 0 {
+		return 0
+	}
+	timeout := cfg.LoginAttemptTimeout
+	for i := 0; i < timedOut && timeout < remaining; i++ {
+		timeout *= 2
+	}
+	return durationMin(timeout, remaining)
+}
+
+// authenticateWithTimeout makes a login attempt that is abandoned after the timeout. The timing of the attempt
+// is returned if it timed out.
+func authenticateWithTimeout(ctx context.Context, sc *snowflakeConn, timeout time.Duration) (*authResponseMain, *LoginAttempt, error) {
+	la := &LoginAttempt{Timeout: timeout}
+	var mu sync.Mutex
+	var handshakeStart time.Time
+	trace := &httptrace.ClientTrace{
+		TLSHandshakeStart: func() {
+			mu.Lock()
+			defer mu.Unlock()
+			handshakeStart = time.Now()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			la.TLSHandshake += time.Since(handshakeStart)
+			handshakeStart = time.Time{}
+		},
+	}
+	attemptCtx, cancel := context.WithTimeout(httptrace.WithClientTrace(ctx, trace), timeout)
+	defer cancel()
+	start := time.Now()
+	authData, err := authenticateWithCredentials(attemptCtx, sc)
+	if err == nil || ctx.Err() != nil || attemptCtx.Err() != context.DeadlineExceeded {
+		return authData, nil, err
+	}
+	la.Elapsed = time.Since(start)
+	mu.Lock()
+	defer mu.Unlock()
+	if !handshakeStart.IsZero() {
+		// abandoned in the middle of the handshake
+		la.TLSHandshake += time.Since(handshakeStart)
+	}
+	return nil, la, err
+}
+
+func newLoginTimeoutError(elapsed time.Duration, attempts []LoginAttempt) *LoginTimeoutError {
+	var handshake time.Duration
+	for _, la := range attempts {
+		handshake += la.TLSHandshake
+	}
+	return &LoginTimeoutError{
+		SnowflakeError: SnowflakeError{
+			Number:      ErrLoginTimeout,
+			SQLState:    SQLStateConnectionWasNotEstablished,
+			Message:     errMsgLoginTimeout,
+			MessageArgs: []interface{}{elapsed, len(attempts), handshake},
+		},
+		Elapsed:  elapsed,
+		Attempts: attempts,
+	}
+}
+
 // Used to authenticate the user with Snowflake.
 func authenticate(
 	ctx context.Context,
@@ -268,12 +456,13 @@ func authenticate(
 	proofKey []byte,
 ) (resp *authResponseMain, err error) {
 
-	headers := getHeaders()
+	headers := getHeaders(sc.rest)
 	clientEnvironment := authRequestClientEnvironment{
 		Application: sc.cfg.Application,
 		Os:          operatingSystem,
 		OsVersion:   platform,
 		OCSPMode:    sc.cfg.ocspMode(),
+		Extra:       sc.cfg.ClientEnvironment,
 	}
 
 	sessionParameters := make(map[string]interface{})
@@ -282,7 +471,7 @@ func authenticate(
 		sessionParameters[strings.ToUpper(k)] = *v
 	}
 
-	sessionParameters[sessionClientValidateDefaultParameters] = sc.cfg.ValidateDefaultParameters != ConfigBoolFalse
+	sessionParameters[sessionClientValidateDefaultParameters] = sc.cfg.ValidateDefaultParameters != ConfigBoolFalse && !sc.cfg.FetchOnly
 
 	requestMain := authRequestData{
 		ClientAppID:       clientType,
@@ -302,6 +491,10 @@ func authenticate(
 		requestMain.LoginName = sc.cfg.User
 		requestMain.Authenticator = AuthTypeOAuth.String()
 		requestMain.Token = sc.cfg.Token
+	case AuthTypeAzureAD:
+		requestMain.LoginName = sc.cfg.User
+		requestMain.Authenticator = AuthTypeOAuth.String()
+		requestMain.Token = string(samlResponse)
 	case AuthTypeOkta:
 		requestMain.RawSAMLResponse = string(samlResponse)
 	case AuthTypeJwt:
@@ -329,14 +522,19 @@ func authenticate(
 		Data: requestMain,
 	}
 	params := &url.Values{}
-	if sc.cfg.Database != "" {
-		params.Add("databaseName", sc.cfg.Database)
-	}
-	if sc.cfg.Schema != "" {
-		params.Add("schemaName", sc.cfg.Schema)
-	}
-	if sc.cfg.Warehouse != "" {
-		params.Add("warehouse", sc.cfg.Warehouse)
+	if sc.cfg.FetchOnly {
+		// no database, schema or warehouse is resolved for a fetch-only connection
+		glog.V(2).Info("fetch-only connection")
+	} else {
+		if sc.cfg.Database != "" {
+			params.Add("databaseName", sc.cfg.Database)
+		}
+		if sc.cfg.Schema != "" {
+			params.Add("schemaName", sc.cfg.Schema)
+		}
+		if sc.cfg.Warehouse != "" {
+			params.Add("warehouse", sc.cfg.Warehouse)
+		}
 	}
 	if sc.cfg.Role != "" {
 		params.Add("roleName", sc.cfg.Role)
@@ -354,6 +552,11 @@ func authenticate(
 	if err != nil {
 		return nil, err
 	}
+	if respd.Data.NextAction == nextActionPasswordChange {
+		if respd, err = changePassword(ctx, sc, params, headers, authRequest, respd); err != nil {
+			return nil, err
+		}
+	}
 	if !respd.Success {
 		glog.V(1).Infoln("Authentication FAILED")
 		glog.Flush()
@@ -375,9 +578,55 @@ func authenticate(
 	sc.rest.Token = respd.Data.Token
 	sc.rest.MasterToken = respd.Data.MasterToken
 	sc.rest.SessionID = respd.Data.SessionID
+	sc.rest.setTokenExpiry(respd.Data.Validity, respd.Data.MasterValidity)
 	return &respd.Data, nil
 }
 
+// changePassword completes the login rejected because the password has expired by sending the login again with
+// Config.NewPassword and the in-flight context of the rejected login. The connections of a Connector opened
+// afterwards log in with the new password, but any other Config must be updated by the caller.
+func changePassword(
+	ctx context.Context,
+	sc *snowflakeConn,
+	params *url.Values,
+	headers map[string]string,
+	req authRequest,
+	respd *authResponse) (
+	*authResponse, error) {
+	if sc.cfg.NewPassword == "" || sc.cfg.Authenticator != AuthTypeSnowflake {
+		glog.V(1).Infof("the password has expired. user: %v", sc.cfg.User)
+		return nil, &PasswordExpiredError{
+			SnowflakeError: SnowflakeError{
+				Number:      ErrCodePasswordExpired,
+				SQLState:    SQLStateConnectionRejected,
+				Message:     errMsgPasswordExpired,
+				MessageArgs: []interface{}{sc.cfg.User, respd.Message},
+			},
+			User: sc.cfg.User,
+		}
+	}
+	glog.V(2).Infof("the password has expired. changing it. user: %v", sc.cfg.User)
+	req.InFlightCtx = respd.Data.InFlightCtx
+	req.Data.ChosenNewPassword = sc.cfg.NewPassword
+	jsonBody, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	respd, err = sc.rest.FuncPostAuth(ctx, sc.rest, params, headers, jsonBody, sc.rest.LoginTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if respd.Success {
+		// the renewals and the re-logins of the connection, and the logins of the following connections of the
+		// Connector, use the new password
+		sc.cfg.Password = sc.cfg.NewPassword
+		if sc.password != nil {
+			sc.password.set(sc.cfg.NewPassword)
+		}
+	}
+	return respd, nil
+}
+
 // Generate a JWT token in string given the configuration
 func prepareJWTToken(config *Config) (string, error) {
 	pubBytes, err := x509.MarshalPKIXPublicKey(config.PrivateKey.Public())
@@ -10,7 +10,9 @@ import (
 	"errors"
 	"fmt"
 	"github.com/dgrijalva/jwt-go/v4"
+	"net/http"
 	"net/url"
+	"strconv"
 	"testing"
 	"time"
 )
@@ -225,6 +227,57 @@ func postAuthCheckJWTToken(_ context.Context, _ *snowflakeRestful, _ *url.Values
 	}, nil
 }
 
+// Checks that no database, schema or warehouse is set up when authenticating a fetch-only connection.
+func postAuthCheckFetchOnly(_ context.Context, _ *snowflakeRestful, params *url.Values, _ map[string]string, jsonBody []byte, _ time.Duration) (*authResponse, error) {
+	var ar authRequest
+	if err := json.Unmarshal(jsonBody, &ar); err != nil {
+		return nil, err
+	}
+	for _, k := range []string{"databaseName", "schemaName", "warehouse"} {
+		if params.Get(k) != "" {
+			return nil, fmt.Errorf("%v must not be set for a fetch-only connection", k)
+		}
+	}
+	if params.Get("roleName") == "" {
+		return nil, errors.New("role name is empty")
+	}
+	if ar.Data.SessionParameters[sessionClientValidateDefaultParameters] != false {
+		return nil, errors.New("default parameters must not be validated for a fetch-only connection")
+	}
+	return &authResponse{
+		Success: true,
+		Data: authResponseMain{
+			Token:       "t",
+			MasterToken: "m",
+		},
+	}, nil
+}
+
+func postAuthCheckClientEnvironment(_ context.Context, _ *snowflakeRestful, _ *url.Values, headers map[string]string, jsonBody []byte, _ time.Duration) (*authResponse, error) {
+	var ar struct {
+		Data struct {
+			ClientEnvironment map[string]string `json:"CLIENT_ENVIRONMENT"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(jsonBody, &ar); err != nil {
+		return nil, err
+	}
+	env := ar.Data.ClientEnvironment
+	if env["APPLICATION"] != "testapp" || env["OS"] != operatingSystem || env["TEAM"] != "analytics" {
+		return nil, fmt.Errorf("unexpected client environment: %v", env)
+	}
+	if ua := headers["User-Agent"]; ua != userAgent+" (testapp)" {
+		return nil, fmt.Errorf("unexpected User-Agent: %v", ua)
+	}
+	return &authResponse{
+		Success: true,
+		Data: authResponseMain{
+			Token:       "t",
+			MasterToken: "m",
+		},
+	}, nil
+}
+
 func getDefaultSnowflakeConn() *snowflakeConn {
 	cfg := Config{
 		Account:            "a",
@@ -344,6 +397,41 @@ func TestUnitAuthenticateOAuth(t *testing.T) {
 	}
 }
 
+func TestUnitAuthenticateFetchOnly(t *testing.T) {
+	sr := &snowflakeRestful{
+		FuncPostAuth: postAuthCheckFetchOnly,
+	}
+	sc := getDefaultSnowflakeConn()
+	sc.cfg.FetchOnly = true
+	sc.rest = sr
+	if _, err := authenticate(context.TODO(), sc, []byte{}, []byte{}); err != nil {
+		t.Fatalf("failed to run. err: %v", err)
+	}
+}
+
+func TestUnitAuthenticateClientEnvironment(t *testing.T) {
+	sc := getDefaultSnowflakeConn()
+	sc.cfg.Application = "testapp"
+	// the fixed entries cannot be overridden
+	sc.cfg.ClientEnvironment = map[string]string{"team": "analytics", "OS": "overridden"}
+	sc.rest = &snowflakeRestful{
+		UserAgent:    userAgentFor(sc.cfg),
+		FuncPostAuth: postAuthCheckClientEnvironment,
+	}
+	if _, err := authenticate(context.TODO(), sc, []byte{}, []byte{}); err != nil {
+		t.Fatalf("failed to run. err: %v", err)
+	}
+}
+
+func TestUserAgentFor(t *testing.T) {
+	if ua := userAgentFor(&Config{Application: clientType}); ua != userAgent {
+		t.Fatalf("unexpected User-Agent: %v", ua)
+	}
+	if ua := userAgentFor(&Config{Application: "app"}); ua != userAgent+" (app)" {
+		t.Fatalf("unexpected User-Agent: %v", ua)
+	}
+}
+
 func TestUnitAuthenticatePasscode(t *testing.T) {
 	var err error
 	sr := &snowflakeRestful{
@@ -393,3 +481,208 @@ func TestUnitAuthenticateJWT(t *testing.T) {
 		t.Fatalf("invalid token passed")
 	}
 }
+
+func postTestTooManyRequests(_ context.Context, _ *snowflakeRestful, _ *url.URL, _ map[string]string, _ []byte, _ time.Duration, _ bool) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header:     http.Header{"Retry-After": []string{"3"}},
+		Body:       &fakeResponseBody{body: []byte{}},
+	}, nil
+}
+
+func TestUnitPostAuthThrottled(t *testing.T) {
+	sr := &snowflakeRestful{
+		FuncPost: postTestTooManyRequests,
+	}
+	_, err := postAuth(context.TODO(), sr, &url.Values{}, make(map[string]string), []byte{}, 0)
+	throttled, ok := err.(*LoginThrottledError)
+	if !ok {
+		t.Fatalf("should have failed with LoginThrottledError. err: %v", err)
+	}
+	if throttled.Number != ErrCodeLoginThrottled || throttled.RetryAfter != 3*time.Second {
+		t.Fatalf("unexpected error: %+v", throttled)
+	}
+}
+
+var loginAttempts int
+
+func postAuthThrottledOnce(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, _ []byte, _ time.Duration) (*authResponse, error) {
+	loginAttempts++
+	if loginAttempts == 1 {
+		return nil, &LoginThrottledError{
+			SnowflakeError: SnowflakeError{
+				Number:  ErrCodeLoginThrottled,
+				Message: errMsgLoginThrottled,
+			},
+		}
+	}
+	return &authResponse{
+		Success: true,
+		Data: authResponseMain{
+			Token:       "t",
+			MasterToken: "m",
+		},
+	}, nil
+}
+
+func postAuthLocked(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, _ []byte, _ time.Duration) (*authResponse, error) {
+	loginAttempts++
+	return &authResponse{
+		Success: false,
+		Code:    strconv.Itoa(ErrUserTemporarilyLocked),
+		Message: "User temporarily locked.",
+	}, nil
+}
+
+func TestUnitAuthenticateWithBackoff(t *testing.T) {
+	loginAttempts = 0
+	sc := getDefaultSnowflakeConn()
+	sc.cfg.LoginTimeout = 10 * time.Second
+	sc.rest = &snowflakeRestful{
+		FuncPostAuth: postAuthThrottledOnce,
+	}
+	if _, err := authenticateWithBackoff(context.TODO(), sc); err != nil {
+		t.Fatalf("failed to authenticate. err: %v", err)
+	}
+	if loginAttempts != 2 {
+		t.Fatalf("the login should have been retried once. attempts: %v", loginAttempts)
+	}
+
+	loginAttempts = 0
+	sc.cfg.LoginTimeout = 0
+	_, err := authenticateWithBackoff(context.TODO(), sc)
+	if throttled, ok := err.(*LoginThrottledError); !ok || throttled.Number != ErrCodeLoginThrottled {
+		t.Fatalf("should have failed with LoginThrottledError. err: %v", err)
+	}
+	if loginAttempts != 1 {
+		t.Fatalf("the login should not have been retried beyond the login timeout. attempts: %v", loginAttempts)
+	}
+
+	// a locked user is not throttling, and the login is not retried
+	loginAttempts = 0
+	sc.cfg.LoginTimeout = 10 * time.Second
+	sc.rest.FuncPostAuth = postAuthLocked
+	_, err = authenticateWithBackoff(context.TODO(), sc)
+	if driverErr, ok := err.(*SnowflakeError); !ok || driverErr.Number != ErrUserTemporarilyLocked {
+		t.Fatalf("should have failed with ErrUserTemporarilyLocked. err: %v", err)
+	}
+	if loginAttempts != 1 {
+		t.Fatalf("the login should not have been retried. attempts: %v", loginAttempts)
+	}
+}
+
+func TestUnitAuthenticateWithAttemptTimeout(t *testing.T) {
+	loginAttempts = 0
+	sc := getDefaultSnowflakeConn()
+	sc.cfg.LoginTimeout = 10 * time.Second
+	sc.cfg.LoginAttemptTimeout = 20 * time.Millisecond
+	sc.rest = &snowflakeRestful{
+		FuncPostAuth: func(ctx context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, _ []byte, _ time.Duration) (*authResponse, error) {
+			loginAttempts++
+			if loginAttempts == 1 {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}
+			return &authResponse{Success: true, Data: authResponseMain{Token: "t", MasterToken: "m"}}, nil
+		},
+	}
+	if _, err := authenticateWithBackoff(context.TODO(), sc); err != nil {
+		t.Fatalf("failed to authenticate. err: %v", err)
+	}
+	if loginAttempts != 2 {
+		t.Fatalf("the login should have been retried once. attempts: %v", loginAttempts)
+	}
+
+	loginAttempts = 0
+	sc.cfg.LoginTimeout = 100 * time.Millisecond
+	sc.rest.FuncPostAuth = func(ctx context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, _ []byte, _ time.Duration) (*authResponse, error) {
+		loginAttempts++
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	_, err := authenticateWithBackoff(context.TODO(), sc)
+	le, ok := err.(*LoginTimeoutError)
+	if !ok || le.Number != ErrLoginTimeout {
+		t.Fatalf("should have failed with LoginTimeoutError. err: %v", err)
+	}
+	// 20ms, 40ms and the remaining 40ms
+	if len(le.Attempts) != 3 || loginAttempts != 3 {
+		t.Fatalf("number of attempts didn't match. expected: 3, got: %v, %v", len(le.Attempts), loginAttempts)
+	}
+	if le.Attempts[0].Timeout != 20*time.Millisecond || le.Attempts[1].Timeout != 40*time.Millisecond {
+		t.Fatalf("unexpected attempt timeouts: %+v", le.Attempts)
+	}
+}
+
+func TestLoginAttemptTimeout(t *testing.T) {
+	cfg := &Config{LoginAttemptTimeout: 5 * time.Second}
+	deadline := time.Now().Add(time.Minute)
+	if timeout := loginAttemptTimeout(cfg, 0, deadline); timeout != 5*time.Second {
+		t.Fatalf("unexpected timeout: %v", timeout)
+	}
+	if timeout := loginAttemptTimeout(cfg, 2, deadline); timeout != 20*time.Second {
+		t.Fatalf("unexpected timeout: %v", timeout)
+	}
+	if timeout := loginAttemptTimeout(cfg, 10, deadline); timeout > time.Minute || timeout < 59*time.Second {
+		t.Fatalf("the timeout should be capped by the deadline: %v", timeout)
+	}
+	if timeout := loginAttemptTimeout(cfg, 0, time.Now().Add(-time.Second)); timeout != 0 {
+		t.Fatalf("no timeout should be set after the deadline: %v", timeout)
+	}
+	if timeout := loginAttemptTimeout(&Config{}, 0, deadline); timeout != 0 {
+		t.Fatalf("no timeout should be set by default: %v", timeout)
+	}
+}
+
+func TestUnitAuthenticatePasswordExpired(t *testing.T) {
+	var requests []authRequest
+	sc := getDefaultSnowflakeConn()
+	sc.rest = &snowflakeRestful{
+		FuncPostAuth: func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, jsonBody []byte, _ time.Duration) (*authResponse, error) {
+			var ar authRequest
+			if err := json.Unmarshal(jsonBody, &ar); err != nil {
+				return nil, err
+			}
+			requests = append(requests, ar)
+			if ar.Data.ChosenNewPassword == "" {
+				return &authResponse{
+					Data:    authResponseMain{NextAction: nextActionPasswordChange, InFlightCtx: "ctx-1"},
+					Message: "Specified password has expired.  Password must be changed.",
+					Code:    "390106",
+				}, nil
+			}
+			return &authResponse{
+				Data:    authResponseMain{Token: "t", MasterToken: "m", SessionID: 1},
+				Success: true,
+			}, nil
+		},
+	}
+	_, err := authenticate(context.TODO(), sc, []byte{}, []byte{})
+	expired, ok := err.(*PasswordExpiredError)
+	if !ok || expired.Number != ErrCodePasswordExpired || expired.User != "u" {
+		t.Fatalf("should fail with PasswordExpiredError. err: %v", err)
+	}
+	if !isLoginRejected(err) {
+		t.Fatal("the expired password should reject the login")
+	}
+	if len(requests) != 1 {
+		t.Fatalf("the login should not be sent again. requests: %v", len(requests))
+	}
+
+	requests = nil
+	sc.cfg.NewPassword = "p2"
+	sc.password = &changedPassword{}
+	if _, err = authenticate(context.TODO(), sc, []byte{}, []byte{}); err != nil {
+		t.Fatalf("failed to change the password. err: %v", err)
+	}
+	if len(requests) != 2 || requests[1].InFlightCtx != "ctx-1" || requests[1].Data.Password != "p" ||
+		requests[1].Data.ChosenNewPassword != "p2" {
+		t.Fatalf("the password should be changed with the in-flight context. requests: %+v", requests)
+	}
+	if sc.rest.Token != "t" || sc.cfg.Password != "p2" {
+		t.Fatalf("the connection should be logged in with the new password. token: %v, password: %v", sc.rest.Token, sc.cfg.Password)
+	}
+	if password := sc.password.get(); password != "p2" {
+		t.Fatalf("the following connections of the Connector should log in with the new password: %v", password)
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+var (
+	// azureIMDSTokenURL is the token endpoint of the Azure Instance Metadata Service for managed identities.
+	azureIMDSTokenURL = "http://169.254.169.254/metadata/identity/oauth2/token"
+	// azureDefaultAuthorityHost is the Azure AD endpoint used for workload identity federation.
+	azureDefaultAuthorityHost = "https://login.microsoftonline.com/"
+)
+
+const (
+	azureIMDSAPIVersion            = "2018-02-01"
+	azureAppServiceAPIVersion      = "2019-08-01"
+	azureClientAssertionType       = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+	envAzureFederatedTokenFile     = "AZURE_FEDERATED_TOKEN_FILE"
+	envAzureClientID               = "AZURE_CLIENT_ID"
+	envAzureTenantID               = "AZURE_TENANT_ID"
+	envAzureAuthorityHost          = "AZURE_AUTHORITY_HOST"
+	envAzureIdentityEndpoint       = "IDENTITY_ENDPOINT"
+	envAzureIdentityHeader         = "IDENTITY_HEADER"
+	headerAzureMetadata            = "Metadata"
+	headerAzureIdentityHeader      = "X-IDENTITY-HEADER"
+	headerContentTypeFormURLEncode = "application/x-www-form-urlencoded"
+)
+
+type azureADTokenResponse struct {
+	AccessToken string `json:"access_token"`
+}
+
+/*
+getAzureADToken acquires an Azure AD access token for the Snowflake External OAuth integration identified by
+resource from the environment:
+ 1. workload identity federation, e.g., on AKS, if AZURE_FEDERATED_TOKEN_FILE, AZURE_CLIENT_ID and
+    AZURE_TENANT_ID are set. The federated OIDC token in the file is exchanged for an Azure AD token.
+ 2. the managed identity endpoint of App Service and Functions if IDENTITY_ENDPOINT and IDENTITY_HEADER are set.
+ 3. the managed identity endpoint of the Instance Metadata Service otherwise.
+
+clientID selects a user-assigned managed identity and overrides AZURE_CLIENT_ID. It is optional.
+*/
+func getAzureADToken(ctx context.Context, sr *snowflakeRestful, resource string, clientID string) (string, error) {
+	if tokenFile := os.Getenv(envAzureFederatedTokenFile); tokenFile != "" &&
+		os.Getenv(envAzureTenantID) != "" && (clientID != "" || os.Getenv(envAzureClientID) != "") {
+		if clientID == "" {
+			clientID = os.Getenv(envAzureClientID)
+		}
+		return getAzureADTokenByFederation(ctx, sr, resource, clientID, tokenFile)
+	}
+	headers := make(map[string]string)
+	params := &url.Values{}
+	var tokenURL string
+	if endpoint, secret := os.Getenv(envAzureIdentityEndpoint), os.Getenv(envAzureIdentityHeader); endpoint != "" && secret != "" {
+		tokenURL = endpoint
+		params.Add("api-version", azureAppServiceAPIVersion)
+		headers[headerAzureIdentityHeader] = secret
+	} else {
+		tokenURL = azureIMDSTokenURL
+		params.Add("api-version", azureIMDSAPIVersion)
+		headers[headerAzureMetadata] = "true"
+	}
+	params.Add("resource", resource)
+	if clientID != "" {
+		params.Add("client_id", clientID)
+	}
+	fullURL, err := url.Parse(tokenURL)
+	if err != nil {
+		return "", err
+	}
+	fullURL.RawQuery = params.Encode()
+	resp, err := sr.FuncGet(ctx, sr, fullURL, headers, sr.LoginTimeout)
+	if err != nil {
+		return "", err
+	}
+	return parseAzureADTokenResponse(resp, fullURL)
+}
+
+// getAzureADTokenByFederation exchanges the federated OIDC token in tokenFile for an Azure AD access token.
+func getAzureADTokenByFederation(ctx context.Context, sr *snowflakeRestful, resource string, clientID string, tokenFile string) (string, error) {
+	assertion, err := ioutil.ReadFile(tokenFile)
+	if err != nil {
+		return "", err
+	}
+	authorityHost := os.Getenv(envAzureAuthorityHost)
+	if authorityHost == "" {
+		authorityHost = azureDefaultAuthorityHost
+	}
+	if !strings.HasSuffix(authorityHost, "/") {
+		authorityHost += "/"
+	}
+	fullURL, err := url.Parse(authorityHost + url.PathEscape(os.Getenv(envAzureTenantID)) + "/oauth2/v2.0/token")
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{}
+	form.Add("grant_type", "client_credentials")
+	form.Add("client_id", clientID)
+	form.Add("client_assertion_type", azureClientAssertionType)
+	form.Add("client_assertion", strings.TrimSpace(string(assertion)))
+	form.Add("scope", strings.TrimSuffix(resource, "/")+"/.default")
+	headers := map[string]string{
+		"Content-Type": headerContentTypeFormURLEncode,
+		"accept":       headerContentTypeApplicationJSON,
+	}
+	resp, err := sr.FuncPost(ctx, sr, fullURL, headers, []byte(form.Encode()), sr.LoginTimeout, true)
+	if err != nil {
+		return "", err
+	}
+	return parseAzureADTokenResponse(resp, fullURL)
+}
+
+func parseAzureADTokenResponse(resp *http.Response, fullURL *url.URL) (string, error) {
+	defer drainAndClose(resp.Body)
+	if resp.StatusCode == http.StatusOK {
+		var respd azureADTokenResponse
+		if err := decodeJSONBody(resp.Body, &respd); err != nil {
+			glog.V(1).Infof("failed to decode JSON. err: %v", err)
+			glog.Flush()
+			return "", err
+		}
+		if respd.AccessToken != "" {
+			return respd.AccessToken, nil
+		}
+	}
+	glog.V(1).Infof("HTTP: %v, URL: %v", resp.StatusCode, fullURL)
+	glog.Flush()
+	return "", &SnowflakeError{
+		Number:      ErrFailedToGetAzureADToken,
+		SQLState:    SQLStateConnectionRejected,
+		Message:     errMsgFailedToGetAzureADToken,
+		MessageArgs: []interface{}{resp.StatusCode, fullURL.Scheme + "://" + fullURL.Host + fullURL.Path},
+	}
+}
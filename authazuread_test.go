@@ -0,0 +1,138 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+)
+
+func getAzureIMDSTokenMock(_ context.Context, _ *snowflakeRestful, fullURL *url.URL, headers map[string]string, _ time.Duration) (*http.Response, error) {
+	q := fullURL.Query()
+	if fullURL.Host != "169.254.169.254" || headers[headerAzureMetadata] != "true" ||
+		q.Get("resource") != "api://snowflake" || q.Get("client_id") != "cid" {
+		return nil, fmt.Errorf("unexpected request. URL: %v, headers: %v", fullURL, headers)
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       &fakeResponseBody{body: []byte(`{"access_token":"imds-token","token_type":"Bearer"}`)},
+	}, nil
+}
+
+func getAzureAppServiceTokenMock(_ context.Context, _ *snowflakeRestful, fullURL *url.URL, headers map[string]string, _ time.Duration) (*http.Response, error) {
+	if fullURL.Host != "localhost:8081" || headers[headerAzureIdentityHeader] != "secret" ||
+		fullURL.Query().Get("api-version") != azureAppServiceAPIVersion {
+		return nil, fmt.Errorf("unexpected request. URL: %v, headers: %v", fullURL, headers)
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       &fakeResponseBody{body: []byte(`{"access_token":"app-service-token"}`)},
+	}, nil
+}
+
+func getAzureTokenErrorMock(_ context.Context, _ *snowflakeRestful, _ *url.URL, _ map[string]string, _ time.Duration) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusBadRequest,
+		Body:       &fakeResponseBody{body: []byte(`{"error":"invalid_request"}`)},
+	}, nil
+}
+
+func postAzureFederatedTokenMock(_ context.Context, _ *snowflakeRestful, fullURL *url.URL, _ map[string]string, body []byte, _ time.Duration, _ bool) (*http.Response, error) {
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, err
+	}
+	if fullURL.String() != "https://login.microsoftonline.com/tenant/oauth2/v2.0/token" ||
+		form.Get("client_assertion") != "oidc-token" || form.Get("client_id") != "env-client" ||
+		form.Get("scope") != "api://snowflake/.default" {
+		return nil, fmt.Errorf("unexpected request. URL: %v, form: %v", fullURL, form)
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       &fakeResponseBody{body: []byte(`{"access_token":"federated-token"}`)},
+	}, nil
+}
+
+func TestGetAzureADToken(t *testing.T) {
+	sr := &snowflakeRestful{FuncGet: getAzureIMDSTokenMock}
+	token, err := getAzureADToken(context.Background(), sr, "api://snowflake", "cid")
+	if err != nil || token != "imds-token" {
+		t.Fatalf("failed to get a token from IMDS. token: %v, err: %v", token, err)
+	}
+
+	os.Setenv(envAzureIdentityEndpoint, "http://localhost:8081/msi/token")
+	os.Setenv(envAzureIdentityHeader, "secret")
+	defer os.Unsetenv(envAzureIdentityEndpoint)
+	defer os.Unsetenv(envAzureIdentityHeader)
+	sr.FuncGet = getAzureAppServiceTokenMock
+	token, err = getAzureADToken(context.Background(), sr, "api://snowflake", "")
+	if err != nil || token != "app-service-token" {
+		t.Fatalf("failed to get a token from App Service. token: %v, err: %v", token, err)
+	}
+
+	sr.FuncGet = getAzureTokenErrorMock
+	_, err = getAzureADToken(context.Background(), sr, "api://snowflake", "")
+	if driverErr, ok := err.(*SnowflakeError); !ok || driverErr.Number != ErrFailedToGetAzureADToken {
+		t.Fatalf("should have failed with ErrFailedToGetAzureADToken. err: %v", err)
+	}
+}
+
+func TestGetAzureADTokenByFederation(t *testing.T) {
+	f, err := ioutil.TempFile("", "azure-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("oidc-token\n")
+	f.Close()
+	os.Setenv(envAzureFederatedTokenFile, f.Name())
+	os.Setenv(envAzureTenantID, "tenant")
+	os.Setenv(envAzureClientID, "env-client")
+	defer os.Unsetenv(envAzureFederatedTokenFile)
+	defer os.Unsetenv(envAzureTenantID)
+	defer os.Unsetenv(envAzureClientID)
+
+	sr := &snowflakeRestful{FuncPost: postAzureFederatedTokenMock}
+	token, err := getAzureADToken(context.Background(), sr, "api://snowflake/", "")
+	if err != nil || token != "federated-token" {
+		t.Fatalf("failed to exchange the federated token. token: %v, err: %v", token, err)
+	}
+}
+
+func postAuthCheckAzureAD(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, jsonBody []byte, _ time.Duration) (*authResponse, error) {
+	var ar authRequest
+	if err := json.Unmarshal(jsonBody, &ar); err != nil {
+		return nil, err
+	}
+	if ar.Data.Authenticator != AuthTypeOAuth.String() || ar.Data.Token != "imds-token" {
+		return nil, fmt.Errorf("unexpected auth request: %+v", ar.Data)
+	}
+	return &authResponse{
+		Success: true,
+		Data: authResponseMain{
+			Token:       "t",
+			MasterToken: "m",
+		},
+	}, nil
+}
+
+func TestLoginAzureAD(t *testing.T) {
+	sc := getDefaultSnowflakeConn()
+	sc.cfg.Authenticator = AuthTypeAzureAD
+	sc.cfg.AzureADResource = "api://snowflake"
+	sc.cfg.AzureADClientID = "cid"
+	sc.rest = &snowflakeRestful{
+		FuncGet:      getAzureIMDSTokenMock,
+		FuncPostAuth: postAuthCheckAzureAD,
+	}
+	if _, err := sc.login(context.Background()); err != nil {
+		t.Fatalf("failed to log in. err: %v", err)
+	}
+}
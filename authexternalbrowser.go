@@ -92,7 +92,7 @@ func getIdpURLProofKey(
 	headers := make(map[string]string)
 	headers["Content-Type"] = headerContentTypeApplicationJSON
 	headers["accept"] = headerContentTypeApplicationJSON
-	headers["User-Agent"] = userAgent
+	headers["User-Agent"] = sr.getUserAgent()
 
 	clientEnvironment := authRequestClientEnvironment{
 		Application: application,
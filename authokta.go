@@ -22,8 +22,59 @@ type authOKTARequest struct {
 	Password string `json:"password"`
 }
 
+// authOKTAVerifyRequest is the request to verify an MFA factor or to poll the verification of a push factor.
+type authOKTAVerifyRequest struct {
+	StateToken string `json:"stateToken"`
+	PassCode   string `json:"passCode,omitempty"`
+}
+
+// Okta authentication statuses and MFA factors
+const (
+	oktaStatusSuccess      = "SUCCESS"
+	oktaStatusMFARequired  = "MFA_REQUIRED"
+	oktaStatusMFAChallenge = "MFA_CHALLENGE"
+	oktaFactorPush         = "push"
+	oktaFactorTOTP         = "token:software:totp"
+	oktaFactorResultWait   = "WAITING"
+)
+
+// oktaMFAPollInterval is the interval of polling Okta for the verification of a push factor.
+var oktaMFAPollInterval = 2 * time.Second
+
+type oktaLink struct {
+	Href string `json:"href"`
+}
+
+type oktaFactor struct {
+	ID         string `json:"id"`
+	FactorType string `json:"factorType"`
+	Provider   string `json:"provider"`
+	Links      struct {
+		Verify oktaLink `json:"verify"`
+	} `json:"_links"`
+}
+
 type authOKTAResponse struct {
-	CookieToken string `json:"cookieToken"`
+	CookieToken  string `json:"cookieToken"`
+	SessionToken string `json:"sessionToken"`
+	Status       string `json:"status"`
+	StateToken   string `json:"stateToken"`
+	FactorResult string `json:"factorResult"`
+	Embedded     struct {
+		Factors []oktaFactor `json:"factors"`
+	} `json:"_embedded"`
+	Links struct {
+		Next oktaLink `json:"next"`
+	} `json:"_links"`
+}
+
+// oneTimeToken returns the token exchanged for the SAML response. Okta returns it as sessionToken and, for
+// compatibility, as cookieToken.
+func (r *authOKTAResponse) oneTimeToken() string {
+	if r.SessionToken != "" {
+		return r.SessionToken
+	}
+	return r.CookieToken
 }
 
 /*
@@ -58,12 +109,13 @@ func authenticateBySAML(
 	account string,
 	user string,
 	password string,
+	passcode string,
 ) (samlResponse []byte, err error) {
 	glog.V(2).Info("step 1: query GS to obtain IDP token and SSO url")
 	headers := make(map[string]string)
 	headers["Content-Type"] = headerContentTypeApplicationJSON
 	headers["accept"] = headerContentTypeApplicationJSON
-	headers["User-Agent"] = userAgent
+	headers["User-Agent"] = sr.getUserAgent()
 
 	clientEnvironment := authRequestClientEnvironment{
 		Application: application,
@@ -136,11 +188,17 @@ func authenticateBySAML(
 	if err != nil {
 		return nil, err
 	}
+	if respa.Status == oktaStatusMFARequired {
+		glog.V(2).Info("step 3a: verify the MFA factor")
+		if respa, err = verifyOktaMFA(ctx, sr, headers, oktaURL, respa, passcode); err != nil {
+			return nil, err
+		}
+	}
 
 	glog.V(2).Info("step 4: query IDP URL snowflake app to get SAML response")
 	params = &url.Values{}
 	params.Add("RelayState", "/some/deep/link")
-	params.Add("onetimetoken", respa.CookieToken)
+	params.Add("onetimetoken", respa.oneTimeToken())
 
 	headers = make(map[string]string)
 	headers["accept"] = "*/*"
@@ -167,6 +225,83 @@ func authenticateBySAML(
 	return bd, nil
 }
 
+// verifyOktaMFA verifies the MFA factor of the user with Okta, without a browser: the TOTP factor with the
+// passcode if given, or else the push factor, whose verification is polled until the user approves it in Okta
+// Verify, rejects it or it times out.
+func verifyOktaMFA(
+	ctx context.Context,
+	sr *snowflakeRestful,
+	headers map[string]string,
+	oktaURL *url.URL,
+	respa *authOKTAResponse,
+	passcode string) (
+	*authOKTAResponse, error) {
+	factorType := oktaFactorPush
+	if passcode != "" {
+		factorType = oktaFactorTOTP
+	}
+	var factor *oktaFactor
+	for i, f := range respa.Embedded.Factors {
+		if f.FactorType == factorType {
+			factor = &respa.Embedded.Factors[i]
+			break
+		}
+	}
+	if factor == nil {
+		return nil, errOktaMFA(factorType, "the factor is not enrolled")
+	}
+	req := authOKTAVerifyRequest{StateToken: respa.StateToken, PassCode: passcode}
+	href := factor.Links.Verify.Href
+	for {
+		// the state token is sent only to the IdP that authenticated the user
+		target, err := url.Parse(href)
+		if err != nil || !isPrefixEqual(oktaURL, target) {
+			return nil, &SnowflakeError{
+				Number:      ErrCodeIdpConnectionError,
+				SQLState:    SQLStateConnectionRejected,
+				Message:     errMsgIdpConnectionError,
+				MessageArgs: []interface{}{oktaURL, href, ""},
+			}
+		}
+		jsonBody, err := json.Marshal(req)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := sr.FuncPostAuthOKTA(ctx, sr, headers, jsonBody, href, sr.LoginTimeout)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case resp.Status == oktaStatusSuccess:
+			return resp, nil
+		case resp.Status == oktaStatusMFAChallenge && resp.FactorResult == oktaFactorResultWait:
+			glog.V(2).Infof("waiting for the push factor to be verified")
+			href = resp.Links.Next.Href
+			if resp.StateToken != "" {
+				req.StateToken = resp.StateToken
+			}
+		default:
+			return nil, errOktaMFA(factorType, fmt.Sprintf("status: %v, factor result: %v", resp.Status, resp.FactorResult))
+		}
+		await := time.NewTimer(oktaMFAPollInterval)
+		select {
+		case <-await.C:
+		case <-ctx.Done():
+			await.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func errOktaMFA(factorType string, reason string) *SnowflakeError {
+	return &SnowflakeError{
+		Number:      ErrCodeOktaMFAFailed,
+		SQLState:    SQLStateConnectionRejected,
+		Message:     errMsgOktaMFAFailed,
+		MessageArgs: []interface{}{factorType, reason},
+	}
+}
+
 func postBackURL(htmlData []byte) (url *url.URL, err error) {
 	idx0 := bytes.Index(htmlData, []byte("<form"))
 	if idx0 < 0 {
@@ -216,7 +351,7 @@ func postAuthSAML(
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	defer drainAndClose(resp.Body)
 	if resp.StatusCode == http.StatusOK {
 		var respd authResponse
 		err = json.NewDecoder(resp.Body).Decode(&respd)
@@ -277,7 +412,7 @@ func postAuthOKTA(
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	defer drainAndClose(resp.Body)
 	if resp.StatusCode == http.StatusOK {
 		var respd authOKTAResponse
 		err = json.NewDecoder(resp.Body).Decode(&respd)
@@ -323,7 +458,7 @@ func getSSO(
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	defer drainAndClose(resp.Body)
 	b, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		glog.V(1).Infof("failed to extract HTTP response body. err: %v", err)
@@ -4,6 +4,7 @@ package gosnowflake
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/url"
@@ -190,17 +191,17 @@ func TestUnitAuthenticateBySAML(t *testing.T) {
 		FuncPostAuthSAML: postAuthSAMLError,
 	}
 	var err error
-	_, err = authenticateBySAML(context.TODO(), sr, authenticator, application, account, user, password)
+	_, err = authenticateBySAML(context.TODO(), sr, authenticator, application, account, user, password, "")
 	if err == nil {
 		t.Fatal("should have failed.")
 	}
 	sr.FuncPostAuthSAML = postAuthSAMLAuthFail
-	_, err = authenticateBySAML(context.TODO(), sr, authenticator, application, account, user, password)
+	_, err = authenticateBySAML(context.TODO(), sr, authenticator, application, account, user, password, "")
 	if err == nil {
 		t.Fatal("should have failed.")
 	}
 	sr.FuncPostAuthSAML = postAuthSAMLAuthSuccessButInvalidURL
-	_, err = authenticateBySAML(context.TODO(), sr, authenticator, application, account, user, password)
+	_, err = authenticateBySAML(context.TODO(), sr, authenticator, application, account, user, password, "")
 	if err == nil {
 		t.Fatal("should have failed.")
 	}
@@ -213,24 +214,103 @@ func TestUnitAuthenticateBySAML(t *testing.T) {
 	}
 	sr.FuncPostAuthSAML = postAuthSAMLAuthSuccess
 	sr.FuncPostAuthOKTA = postAuthOKTAError
-	_, err = authenticateBySAML(context.TODO(), sr, authenticator, application, account, user, password)
+	_, err = authenticateBySAML(context.TODO(), sr, authenticator, application, account, user, password, "")
 	if err == nil {
 		t.Fatal("should have failed.")
 	}
 	sr.FuncPostAuthOKTA = postAuthOKTASuccess
 	sr.FuncGetSSO = getSSOError
-	_, err = authenticateBySAML(context.TODO(), sr, authenticator, application, account, user, password)
+	_, err = authenticateBySAML(context.TODO(), sr, authenticator, application, account, user, password, "")
 	if err == nil {
 		t.Fatal("should have failed.")
 	}
 	sr.FuncGetSSO = getSSOSuccessButInvalidURL
-	_, err = authenticateBySAML(context.TODO(), sr, authenticator, application, account, user, password)
+	_, err = authenticateBySAML(context.TODO(), sr, authenticator, application, account, user, password, "")
 	if err == nil {
 		t.Fatal("should have failed.")
 	}
 	sr.FuncGetSSO = getSSOSuccess
-	_, err = authenticateBySAML(context.TODO(), sr, authenticator, application, account, user, password)
+	_, err = authenticateBySAML(context.TODO(), sr, authenticator, application, account, user, password, "")
 	if err != nil {
 		t.Fatalf("failed. err: %v", err)
 	}
 }
+
+func TestUnitAuthenticateBySAMLOktaMFA(t *testing.T) {
+	orig := oktaMFAPollInterval
+	oktaMFAPollInterval = time.Millisecond
+	defer func() { oktaMFAPollInterval = orig }()
+
+	authenticator := &url.URL{Scheme: "https", Host: "abc.com"}
+	var posted []string
+	var verified authOKTAVerifyRequest
+	polls := 0
+	factors := []oktaFactor{{ID: "f1", FactorType: oktaFactorPush}, {ID: "f2", FactorType: oktaFactorTOTP}}
+	factors[0].Links.Verify.Href = "https://abc.com/api/v1/authn/factors/f1/verify"
+	factors[1].Links.Verify.Href = "https://abc.com/api/v1/authn/factors/f2/verify"
+	var onetimetoken string
+	sr := &snowflakeRestful{
+		Protocol:         "https",
+		Host:             "abc.com",
+		Port:             443,
+		FuncPostAuthSAML: postAuthSAMLAuthSuccess,
+		FuncPostAuthOKTA: func(_ context.Context, _ *snowflakeRestful, _ map[string]string, body []byte, fullURL string, _ time.Duration) (*authOKTAResponse, error) {
+			posted = append(posted, fullURL)
+			resp := &authOKTAResponse{}
+			switch fullURL {
+			case "https://abc.com/token":
+				resp.Status = oktaStatusMFARequired
+				resp.StateToken = "state"
+				resp.Embedded.Factors = factors
+			case factors[0].Links.Verify.Href, "https://abc.com/api/v1/authn/factors/f1/verify/poll":
+				if polls++; polls < 3 {
+					resp.Status = oktaStatusMFAChallenge
+					resp.FactorResult = oktaFactorResultWait
+					resp.Links.Next.Href = "https://abc.com/api/v1/authn/factors/f1/verify/poll"
+				} else {
+					resp.Status = oktaStatusSuccess
+					resp.SessionToken = "push-token"
+				}
+			case factors[1].Links.Verify.Href:
+				if err := json.Unmarshal(body, &verified); err != nil {
+					return nil, err
+				}
+				resp.Status = oktaStatusSuccess
+				resp.SessionToken = "totp-token"
+			default:
+				return nil, errors.New("unexpected URL: " + fullURL)
+			}
+			return resp, nil
+		},
+		FuncGetSSO: func(ctx context.Context, sr *snowflakeRestful, params *url.Values, headers map[string]string, ssoURL string, timeout time.Duration) ([]byte, error) {
+			onetimetoken = params.Get("onetimetoken")
+			return getSSOSuccess(ctx, sr, params, headers, ssoURL, timeout)
+		},
+	}
+	if _, err := authenticateBySAML(context.TODO(), sr, authenticator, "testapp", "testaccount", "u", "p", ""); err != nil {
+		t.Fatalf("failed to verify the push factor. err: %v", err)
+	}
+	if len(posted) != 4 || onetimetoken != "push-token" {
+		t.Fatalf("the push factor should be polled until verified. posted: %v, token: %v", posted, onetimetoken)
+	}
+
+	posted = nil
+	if _, err := authenticateBySAML(context.TODO(), sr, authenticator, "testapp", "testaccount", "u", "p", "123456"); err != nil {
+		t.Fatalf("failed to verify the TOTP factor. err: %v", err)
+	}
+	if len(posted) != 2 || onetimetoken != "totp-token" || verified.PassCode != "123456" || verified.StateToken != "state" {
+		t.Fatalf("the TOTP factor should be verified with the passcode. posted: %v, request: %+v", posted, verified)
+	}
+
+	factors = factors[1:]
+	_, err := authenticateBySAML(context.TODO(), sr, authenticator, "testapp", "testaccount", "u", "p", "")
+	if driverErr, ok := err.(*SnowflakeError); !ok || driverErr.Number != ErrCodeOktaMFAFailed {
+		t.Fatalf("should fail without the push factor enrolled. err: %v", err)
+	}
+
+	factors[0].Links.Verify.Href = "https://evil.com/api/v1/authn/factors/f2/verify"
+	_, err = authenticateBySAML(context.TODO(), sr, authenticator, "testapp", "testaccount", "u", "p", "123456")
+	if driverErr, ok := err.(*SnowflakeError); !ok || driverErr.Number != ErrCodeIdpConnectionError {
+		t.Fatalf("should not send the state token to another host. err: %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"net/http"
+)
+
+// RequestAuthorizer authorizes the HTTP requests of the connections, e.g., to sign them for a gateway that
+// fronts Snowflake with its own authentication, such as a JWT exchange or SigV4 to a proxy.
+type RequestAuthorizer interface {
+	// Authorize is called before every attempt of a request, including the retries, with the headers set by
+	// the driver, e.g., the Authorization header with the session token, which it may replace. The request is a
+	// copy that may be modified; its body is available from GetBody. The requests to the cloud storage of the
+	// results are authorized too and may be told apart by their host. If Authorize returns an error, the
+	// request fails with ErrCodeRequestAuthorizationFailed without being sent.
+	Authorize(req *http.Request) error
+}
+
+// RequestAuthorizerFunc adapts a function to a RequestAuthorizer.
+type RequestAuthorizerFunc func(req *http.Request) error
+
+// Authorize calls f(req).
+func (f RequestAuthorizerFunc) Authorize(req *http.Request) error {
+	return f(req)
+}
+
+// authorizerTransport authorizes the requests with the RequestAuthorizer before sending them through base.
+type authorizerTransport struct {
+	authorizer RequestAuthorizer
+	base       http.RoundTripper
+}
+
+func (t *authorizerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// a RoundTripper must not modify the request of the caller
+	authorized := req.Clone(req.Context())
+	if err := t.authorizer.Authorize(authorized); err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, &SnowflakeError{
+			Number:      ErrCodeRequestAuthorizationFailed,
+			SQLState:    SQLStateConnectionRejected,
+			Message:     errMsgRequestAuthorizationFailed,
+			MessageArgs: []interface{}{req.URL.Host, err},
+		}
+	}
+	return t.base.RoundTrip(authorized)
+}
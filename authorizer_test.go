@@ -0,0 +1,64 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestRequestAuthorizer(t *testing.T) {
+	var authorization, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get(headerAuthorizationKey)
+	}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := newHTTPClient(&Config{
+		RequestAuthorizer: RequestAuthorizerFunc(func(req *http.Request) error {
+			b, err := req.GetBody()
+			if err != nil {
+				return err
+			}
+			signed, err := ioutil.ReadAll(b)
+			body = string(signed)
+			req.Header.Set(headerAuthorizationKey, "Gateway "+req.Header.Get(headerAuthorizationKey))
+			return err
+		}),
+	})
+	headers := map[string]string{headerAuthorizationKey: "Snowflake Token=\"t\""}
+	resp, err := newRetryHTTP(context.Background(), client, http.NewRequest, u, headers, 10*time.Second).
+		doPost().setBody([]byte("{}")).execute()
+	if err != nil {
+		t.Fatalf("failed to post. err: %v", err)
+	}
+	resp.Body.Close()
+	if authorization != "Gateway Snowflake Token=\"t\"" || body != "{}" {
+		t.Fatalf("the request should have been authorized. authorization: %v, body: %v", authorization, body)
+	}
+	if headers[headerAuthorizationKey] != "Snowflake Token=\"t\"" {
+		t.Fatal("the headers of the driver should not be modified")
+	}
+
+	client = newHTTPClient(&Config{
+		RequestAuthorizer: RequestAuthorizerFunc(func(*http.Request) error { return errors.New("no credentials") }),
+	})
+	start := time.Now()
+	_, err = newRetryHTTP(context.Background(), client, http.NewRequest, u, headers, 10*time.Second).execute()
+	if driverErr, ok := err.(*SnowflakeError); !ok || driverErr.Number != ErrCodeRequestAuthorizationFailed {
+		t.Fatalf("should have failed with ErrCodeRequestAuthorizationFailed. err: %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatal("the request should not be retried")
+	}
+}
@@ -0,0 +1,304 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultBatchMaxRows is the number of buffered rows that triggers a flush if BatchWriterOptions doesn't set it.
+	defaultBatchMaxRows = 10000
+	// defaultBatchMaxBytes is the approximate size of the buffered rows that triggers a flush by default.
+	defaultBatchMaxBytes = 16 << 20
+	// defaultBatchMaxRetries is the number of times the rows of a failed flush are retried by default.
+	defaultBatchMaxRetries = 3
+	// batchRetryBackoff is the backoff after a failed flush during which the writes don't retry the buffered rows.
+	// It doubles with every failed retry up to maxBatchRetryBackoff.
+	batchRetryBackoff    = time.Second
+	maxBatchRetryBackoff = time.Minute
+)
+
+// BatchWriterOptions controls when a BatchWriter flushes the buffered rows and how.
+type BatchWriterOptions struct {
+	// MaxRows is the number of buffered rows that triggers a flush. It is 10000 by default.
+	MaxRows int
+	// MaxBytes is the approximate size of the buffered values in bytes that triggers a flush. It is 16 MB by
+	// default.
+	MaxBytes int
+	// FlushInterval flushes the buffered rows periodically in the background if it is positive.
+	FlushInterval time.Duration
+	// StageRows is the number of rows from which a flush loads the rows with a staged COPY INTO, as
+	// Client.IngestRows, instead of an INSERT with array binds. The staged COPY is not used if it is zero.
+	StageRows int
+	// IngestOptions are the options of the staged COPY INTO.
+	IngestOptions *IngestOptions
+	// MaxRetries is the number of times the next flushes retry the rows of a failed flush before the rows are
+	// dropped and handed to OnFlush in BatchFlush.Dropped. It is 3 by default. The writes that reach MaxRows or
+	// MaxBytes retry the rows only after a backoff, which grows with the failed retries.
+	MaxRetries int
+	// OnFlush is called after every flush, including the failed ones. It is called without holding the lock of the
+	// BatchWriter, so that it may call the BatchWriter, and the calls of concurrent flushes may be out of order.
+	OnFlush func(BatchFlush)
+}
+
+// BatchFlush describes a flush of a BatchWriter.
+type BatchFlush struct {
+	QueryID  string // the query ID of INSERT or COPY INTO
+	Rows     int
+	Bytes    int  // approximate size of the values
+	Staged   bool // true if the rows were loaded with a staged COPY INTO
+	Duration time.Duration
+	Err      error
+	// Dropped are the rows dropped after the flush failed, and MaxRetries flushes failed to retry them, if any, so
+	// that they can be written elsewhere.
+	Dropped [][]interface{}
+}
+
+// BatchWriterMetrics are the counters of a BatchWriter.
+type BatchWriterMetrics struct {
+	Flushes       int64
+	FailedFlushes int64
+	RowsWritten   int64
+	BufferedRows  int64
+	DroppedRows   int64
+	LastFlush     time.Time
+}
+
+// BatchWriter buffers the rows written to a table and writes them in batches, each of which is a single
+// statement and so either fully written or not at all. A failed flush keeps the rows buffered, so that the next
+// flush retries them, after a backoff if the flush is triggered by a write, up to MaxRetries times. The rows are
+// then dropped and handed to OnFlush, so that the buffer doesn't grow while the flushes keep failing.
+//
+// A BatchWriter is safe for concurrent use. It runs the statements on its Client, which must not be used otherwise
+// until the BatchWriter is closed if FlushInterval is set.
+type BatchWriter struct {
+	client  *Client
+	table   string
+	columns []string
+	opts    BatchWriterOptions
+
+	mu      sync.Mutex
+	rows    [][]interface{}
+	bytes   int
+	retries int           // the number of failed flushes of the buffered rows in a row
+	backoff time.Duration // the backoff after the last failed flush
+	retryAt time.Time     // the time before which the writes don't retry the rows of a failed flush
+	err     error         // the error of the last background flush, returned by the next call
+	metrics BatchWriterMetrics
+	closed  bool
+
+	shutdownChan chan struct{}
+	wg           sync.WaitGroup
+}
+
+// NewBatchWriter returns the BatchWriter of the columns of the table. The table and the columns are not escaped.
+func (c *Client) NewBatchWriter(table string, columns []string, opts *BatchWriterOptions) *BatchWriter {
+	w := &BatchWriter{client: c, table: table, columns: columns}
+	if opts != nil {
+		w.opts = *opts
+	}
+	if w.opts.MaxRows <= 0 {
+		w.opts.MaxRows = defaultBatchMaxRows
+	}
+	if w.opts.MaxBytes <= 0 {
+		w.opts.MaxBytes = defaultBatchMaxBytes
+	}
+	if w.opts.MaxRetries <= 0 {
+		w.opts.MaxRetries = defaultBatchMaxRetries
+	}
+	if w.opts.FlushInterval > 0 {
+		w.shutdownChan = make(chan struct{})
+		w.wg.Add(1)
+		go w.run(c.sc.backgroundContext())
+	}
+	return w
+}
+
+// run flushes the rows every FlushInterval until the BatchWriter is closed or the background context of the
+// connection is done.
+func (w *BatchWriter) run(bgCtx context.Context) {
+	defer w.wg.Done()
+	ticker := time.NewTicker(w.opts.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.mu.Lock()
+			f, err := w.flush(bgCtx)
+			if err != nil {
+				glog.V(1).Infof("failed to flush the rows of %v. err: %v", w.table, err)
+				w.err = err
+			}
+			w.mu.Unlock()
+			w.notify(f)
+		case <-w.shutdownChan:
+			return
+		case <-bgCtx.Done():
+			return
+		}
+	}
+}
+
+// Write buffers a row of the values of the columns, and flushes the buffered rows if they reach MaxRows or
+// MaxBytes, unless a flush has failed within its backoff. It returns the error of the flush, or that of the last
+// background flush.
+func (w *BatchWriter) Write(ctx context.Context, values ...interface{}) error {
+	if len(values) != len(w.columns) {
+		return fmt.Errorf("a row has %v values for %v columns", len(values), len(w.columns))
+	}
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return fmt.Errorf("the batch writer of %v is closed", w.table)
+	}
+	w.rows = append(w.rows, values)
+	for _, v := range values {
+		w.bytes += batchValueSize(v)
+	}
+	w.metrics.BufferedRows = int64(len(w.rows))
+	var f *BatchFlush
+	var err error
+	if (len(w.rows) >= w.opts.MaxRows || w.bytes >= w.opts.MaxBytes) && !time.Now().Before(w.retryAt) {
+		f, err = w.flush(ctx)
+	} else {
+		err = w.err
+		w.err = nil
+	}
+	w.mu.Unlock()
+	w.notify(f)
+	return err
+}
+
+// Flush writes the buffered rows, retrying those of a failed flush at once.
+func (w *BatchWriter) Flush(ctx context.Context) error {
+	w.mu.Lock()
+	f, err := w.flush(ctx)
+	w.mu.Unlock()
+	w.notify(f)
+	return err
+}
+
+// Close stops the background flushes and writes the buffered rows. The Client is not closed.
+func (w *BatchWriter) Close(ctx context.Context) error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.mu.Unlock()
+	if w.shutdownChan != nil {
+		close(w.shutdownChan)
+		w.wg.Wait()
+	}
+	return w.Flush(ctx)
+}
+
+// Metrics returns the counters of the BatchWriter.
+func (w *BatchWriter) Metrics() BatchWriterMetrics {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.metrics
+}
+
+// flush writes the buffered rows in a statement, and returns the flush for OnFlush, or nil if there was nothing to
+// flush. The caller holds the lock, and notifies OnFlush once it has released it.
+func (w *BatchWriter) flush(ctx context.Context) (*BatchFlush, error) {
+	if len(w.rows) == 0 {
+		err := w.err
+		w.err = nil
+		return nil, err
+	}
+	f := BatchFlush{Rows: len(w.rows), Bytes: w.bytes}
+	start := time.Now()
+	if w.opts.StageRows > 0 && len(w.rows) >= w.opts.StageRows {
+		f.Staged = true
+		f.QueryID, f.Err = w.copyRows(ctx)
+	} else {
+		f.QueryID, f.Err = w.insertRows(ctx)
+	}
+	f.Duration = time.Since(start)
+	w.metrics.Flushes++
+	w.metrics.LastFlush = start
+	if f.Err != nil {
+		w.metrics.FailedFlushes++
+		if w.retries++; w.retries > w.opts.MaxRetries {
+			glog.V(1).Infof("dropping %v rows of %v after %v failed flushes", len(w.rows), w.table, w.retries)
+			f.Dropped = w.rows
+			w.metrics.DroppedRows += int64(len(w.rows))
+			w.rows, w.bytes, w.retries, w.backoff, w.retryAt = nil, 0, 0, 0, time.Time{}
+			w.metrics.BufferedRows = 0
+		} else {
+			if w.backoff *= 2; w.backoff == 0 {
+				w.backoff = batchRetryBackoff
+			} else if w.backoff > maxBatchRetryBackoff {
+				w.backoff = maxBatchRetryBackoff
+			}
+			w.retryAt = time.Now().Add(w.backoff)
+		}
+	} else {
+		w.metrics.RowsWritten += int64(f.Rows)
+		w.rows, w.bytes, w.retries, w.backoff, w.retryAt = nil, 0, 0, 0, time.Time{}
+		w.metrics.BufferedRows = 0
+		w.err = nil
+	}
+	return &f, f.Err
+}
+
+// notify calls OnFlush with the flush unless it is nil.
+func (w *BatchWriter) notify(f *BatchFlush) {
+	if f != nil && w.opts.OnFlush != nil {
+		w.opts.OnFlush(*f)
+	}
+}
+
+// insertRows inserts the rows with an array binding of every column.
+func (w *BatchWriter) insertRows(ctx context.Context) (string, error) {
+	args := make([]interface{}, len(w.columns))
+	for i := range args {
+		col := make([]interface{}, len(w.rows))
+		for j, row := range w.rows {
+			col[j] = row[i]
+		}
+		args[i] = col
+	}
+	query := fmt.Sprintf("INSERT INTO %v (%v) VALUES (%v)", w.table, strings.Join(w.columns, ", "),
+		strings.TrimSuffix(strings.Repeat("?, ", len(w.columns)), ", "))
+	res, err := w.client.Exec(ctx, query, args...)
+	if err != nil {
+		return "", err
+	}
+	return res.QueryID(), nil
+}
+
+// copyRows loads the rows with Client.IngestRows.
+func (w *BatchWriter) copyRows(ctx context.Context) (string, error) {
+	rows := make(chan []interface{}, len(w.rows))
+	for _, row := range w.rows {
+		rows <- row
+	}
+	close(rows)
+	res, err := w.client.IngestRows(ctx, w.table, w.columns, rows, w.opts.IngestOptions)
+	if err != nil {
+		return "", err
+	}
+	return res.QueryID, nil
+}
+
+// batchValueSize returns the approximate size of a buffered value.
+func batchValueSize(v interface{}) int {
+	switch v := v.(type) {
+	case nil:
+		return 0
+	case string:
+		return len(v)
+	case []byte:
+		return len(v)
+	}
+	return 8
+}
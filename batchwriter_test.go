@@ -0,0 +1,254 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// batchTestClient returns the Client whose statements insert 3 rows, failing them while fail is set.
+func batchTestClient(fail *bool) *fakeQueryClient {
+	c := newFakeQueryClient(nil)
+	c.respond = func(req execRequest) (*execResponse, error) {
+		if *fail {
+			return nil, errors.New("network down")
+		}
+		resp := fakeQueryResponse("qid"+string(rune('0'+len(c.requests))),
+			[]execResponseRowType{{Name: "number of rows inserted", Type: "fixed"}}, []string{"3"})
+		resp.Data.StatementTypeID = statementTypeIDInsert
+		return resp, nil
+	}
+	return c
+}
+
+func TestBatchWriter(t *testing.T) {
+	fail := false
+	client := batchTestClient(&fail)
+	var flushes []BatchFlush
+	w := client.NewBatchWriter("t", []string{"id", "name"}, &BatchWriterOptions{
+		MaxRows: 3,
+		OnFlush: func(f BatchFlush) { flushes = append(flushes, f) },
+	})
+	ctx := context.Background()
+	for _, row := range [][]interface{}{{1, "a"}, {2, nil}} {
+		if err := w.Write(ctx, row...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if reqs := client.recorded(); len(reqs) != 0 || w.Metrics().BufferedRows != 2 {
+		t.Fatalf("the rows should be buffered. statements: %v, metrics: %+v", len(reqs), w.Metrics())
+	}
+	if err := w.Write(ctx, int64(3), "c"); err != nil {
+		t.Fatal(err)
+	}
+	reqs := client.recorded()
+	if len(reqs) != 1 || reqs[0].SQLText != "INSERT INTO t (id, name) VALUES (?, ?)" {
+		t.Fatalf("the rows should be inserted when they reach MaxRows. statements: %+v", reqs)
+	}
+	b, _ := json.Marshal(reqs[0].Bindings)
+	if expected := `{"1":{"type":"FIXED","value":["1","2","3"]},"2":{"type":"TEXT","value":["a",null,"c"]}}`; string(b) != expected {
+		t.Fatalf("every column should be an array binding. expected: %v, got: %s", expected, b)
+	}
+	if len(flushes) != 1 || flushes[0].Rows != 3 || flushes[0].Bytes != 26 || flushes[0].QueryID != "qid1" ||
+		flushes[0].Staged || flushes[0].Err != nil {
+		t.Fatalf("unexpected flushes: %+v", flushes)
+	}
+
+	if err := w.Write(ctx, 4, "d"); err != nil {
+		t.Fatal(err)
+	}
+	fail = true
+	if err := w.Flush(ctx); err == nil {
+		t.Fatal("the flush should fail")
+	}
+	if m := w.Metrics(); m.Flushes != 2 || m.FailedFlushes != 1 || m.RowsWritten != 3 || m.BufferedRows != 1 {
+		t.Fatalf("the failed rows should stay buffered. metrics: %+v", m)
+	}
+	fail = false
+	if err := w.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if m, reqs := w.Metrics(), client.recorded(); len(reqs) != 3 || m.RowsWritten != 4 || m.BufferedRows != 0 || len(flushes) != 3 {
+		t.Fatalf("the rows should be retried on close. statements: %v, metrics: %+v", len(reqs), m)
+	}
+	if err := w.Write(ctx, 5, "e"); err == nil {
+		t.Fatal("a closed batch writer should fail")
+	}
+	if err := w.Write(ctx, 5); err == nil {
+		t.Fatal("a row with fewer values than the columns should fail")
+	}
+}
+
+func TestBatchWriterFlushInterval(t *testing.T) {
+	fail := true
+	client := batchTestClient(&fail)
+	w := client.NewBatchWriter("t", []string{"id"},
+		&BatchWriterOptions{FlushInterval: 10 * time.Millisecond, MaxRetries: 1 << 20})
+	defer w.Close(context.Background())
+	if err := w.Write(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	for w.Metrics().FailedFlushes == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if err := w.Write(context.Background(), 2); err == nil || err.Error() != "network down" {
+		t.Fatalf("the next write should return the error of the background flush. err: %v", err)
+	}
+	client.mu.Lock()
+	fail = false
+	client.mu.Unlock()
+	for w.Metrics().RowsWritten != 2 {
+		time.Sleep(time.Millisecond)
+	}
+	if err := w.Write(context.Background(), 3); err != nil {
+		t.Fatalf("the error should be returned once. err: %v", err)
+	}
+}
+
+func TestBatchWriterMaxRetries(t *testing.T) {
+	fail := true
+	client := batchTestClient(&fail)
+	var flushes []BatchFlush
+	w := client.NewBatchWriter("t", []string{"id"}, &BatchWriterOptions{
+		MaxRetries: 2,
+		OnFlush:    func(f BatchFlush) { flushes = append(flushes, f) },
+	})
+	ctx := context.Background()
+	w.Write(ctx, 1)
+	for i := 0; i < 2; i++ {
+		if err := w.Flush(ctx); err == nil {
+			t.Fatal("the flush should fail")
+		}
+		if m := w.Metrics(); m.BufferedRows != 1 || flushes[i].Dropped != nil {
+			t.Fatalf("the rows should be retried. metrics: %+v, flush: %+v", m, flushes[i])
+		}
+	}
+	w.Write(ctx, 2)
+	if err := w.Flush(ctx); err == nil {
+		t.Fatal("the flush should fail")
+	}
+	if m := w.Metrics(); m.BufferedRows != 0 || m.DroppedRows != 2 || m.FailedFlushes != 3 {
+		t.Fatalf("the rows should be dropped after the retries. metrics: %+v", m)
+	}
+	if dropped := flushes[2].Dropped; len(dropped) != 2 || dropped[0][0] != 1 || dropped[1][0] != 2 {
+		t.Fatalf("the dropped rows should be handed to OnFlush. flush: %+v", flushes[2])
+	}
+
+	fail = false
+	w.Write(ctx, 3)
+	if err := w.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if m := w.Metrics(); m.RowsWritten != 1 || m.DroppedRows != 2 {
+		t.Fatalf("the rows after the dropped ones should be written. metrics: %+v", m)
+	}
+}
+
+func TestBatchWriterRetryBackoff(t *testing.T) {
+	fail := true
+	client := batchTestClient(&fail)
+	var w *BatchWriter
+	var metrics []BatchWriterMetrics
+	w = client.NewBatchWriter("t", []string{"id"}, &BatchWriterOptions{
+		MaxRows:    1,
+		MaxRetries: 1,
+		// the BatchWriter is not locked while OnFlush is called
+		OnFlush: func(f BatchFlush) { metrics = append(metrics, w.Metrics()) },
+	})
+	ctx := context.Background()
+	if err := w.Write(ctx, 1); err == nil {
+		t.Fatal("the flush of the write should fail")
+	}
+	for i := 2; i < 5; i++ {
+		if err := w.Write(ctx, i); err != nil {
+			t.Fatalf("the writes should not retry the rows within the backoff. err: %v", err)
+		}
+	}
+	if n := len(client.queries()); n != 1 || len(metrics) != 1 || metrics[0].BufferedRows != 1 {
+		t.Fatalf("the rows should stay buffered. statements: %v, metrics: %+v", n, metrics)
+	}
+	fail = false
+	if err := w.Flush(ctx); err != nil {
+		t.Fatalf("Flush should retry the rows at once. err: %v", err)
+	}
+	if m := w.Metrics(); m.RowsWritten != 4 || m.DroppedRows != 0 || len(metrics) != 2 {
+		t.Fatalf("the rows should be written. metrics: %+v", m)
+	}
+}
+
+func TestBatchWriterStaged(t *testing.T) {
+	stageDir, cleanup := ingestStageDir(t)
+	defer cleanup()
+	client := ingestTestClient(stageDir)
+	var flushes []BatchFlush
+	w := client.NewBatchWriter("t", []string{"id", "name"}, &BatchWriterOptions{
+		StageRows:     2,
+		IngestOptions: &IngestOptions{CopyOptions: "ON_ERROR = ABORT_STATEMENT"},
+		OnFlush:       func(f BatchFlush) { flushes = append(flushes, f) },
+	})
+	w.Write(context.Background(), 1, "a")
+	w.Write(context.Background(), 2, "b")
+	if err := w.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(flushes) != 1 || !flushes[0].Staged || flushes[0].QueryID != "qid" {
+		t.Fatalf("the rows should be loaded with COPY INTO. flushes: %+v", flushes)
+	}
+	if stmts := client.queries(); len(stmts) != 4 || !strings.HasPrefix(stmts[2], "COPY INTO t FROM @SF_GO_INGEST_") ||
+		!strings.HasSuffix(stmts[2], "ON_ERROR = ABORT_STATEMENT") {
+		t.Fatalf("unexpected statements: %v", stmts)
+	}
+	expected := map[string][]interface{}{"id": {[]byte("1"), []byte("2")}, "name": {[]byte("a"), []byte("b")}}
+	if f := stagedFiles(t, stageDir)["data_0.parquet"]; f == nil || !reflect.DeepEqual(f.columns, expected) {
+		t.Fatalf("unexpected content: %+v", f)
+	}
+}
+
+func TestBatchWriterStagedFailedPut(t *testing.T) {
+	stageDir, cleanup := ingestStageDir(t)
+	defer cleanup()
+	client := ingestTestClient(stageDir)
+	fail := true
+	respond := client.respond
+	client.respond = func(req execRequest) (*execResponse, error) {
+		if fail && strings.HasPrefix(req.SQLText, "PUT ") {
+			return &execResponse{Data: execResponseData{QueryID: "qid"}, Code: "2003", Message: "stage does not exist"}, nil
+		}
+		return respond(req)
+	}
+	var flushes []BatchFlush
+	w := client.NewBatchWriter("t", []string{"id"}, &BatchWriterOptions{
+		StageRows:  1,
+		MaxRetries: 1,
+		OnFlush:    func(f BatchFlush) { flushes = append(flushes, f) },
+	})
+	ctx := context.Background()
+	w.Write(ctx, 1)
+	if err := w.Flush(ctx); err == nil {
+		t.Fatal("the staged flush should fail with the PUT")
+	}
+	if m := w.Metrics(); m.BufferedRows != 1 || !flushes[0].Staged || flushes[0].Dropped != nil {
+		t.Fatalf("the rows of the failed PUT should be retried. metrics: %+v, flush: %+v", m, flushes[0])
+	}
+	if err := w.Flush(ctx); err == nil {
+		t.Fatal("the retry should fail with the PUT")
+	}
+	if dropped := flushes[1].Dropped; len(dropped) != 1 || dropped[0][0] != 1 {
+		t.Fatalf("the rows should be dropped after the retries. flush: %+v", flushes[1])
+	}
+
+	fail = false
+	w.Write(ctx, 2)
+	if err := w.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if m := w.Metrics(); m.RowsWritten != 1 || m.DroppedRows != 1 {
+		t.Fatalf("the rows after the dropped ones should be staged. metrics: %+v", m)
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// bindStageName is the temporary stage of the session the bindings of stage binding are uploaded to.
+const bindStageName = "SYSTEM$BIND"
+
+// bindStageFileName is the file of the bindings of a query in the stage.
+const bindStageFileName = "bindings.csv.gz"
+
+// createBindStage creates the temporary stage of the bindings, whose files are read with its file format.
+const createBindStage = "CREATE TEMPORARY STAGE IF NOT EXISTS " + bindStageName +
+	` FILE_FORMAT = (TYPE = CSV FIELD_OPTIONALLY_ENCLOSED_BY = '"')`
+
+// stageBindings uploads the bindings as a gzipped CSV file to the temporary stage of the bindings, and returns the
+// stage location the query binds from. Every binding is a column of the file, and the array bindings have a row
+// per element.
+func (sc *snowflakeConn) stageBindings(ctx context.Context, bindings map[string]execBindParameter) (string, error) {
+	content, err := bindingsCSV(bindings)
+	if err != nil {
+		return "", err
+	}
+	// the statements of the stage binding are run even if the query is only described
+	ctx = context.WithValue(ctx, describeOnly, false)
+	if _, err = sc.exec(ctx, createBindStage, false, true, nil); err != nil {
+		return "", err
+	}
+	location := "@" + bindStageName + "/" + uuid.New().String()
+	stage, err := sc.uploadStage(ctx, []*PutFileResult{{Source: bindStageFileName}}, location)
+	if err != nil {
+		return "", err
+	}
+	digest := sha256.Sum256(content)
+	if err = stage.upload(ctx, bindStageFileName, bytes.NewReader(content), int64(len(content)),
+		base64.StdEncoding.EncodeToString(digest[:])); err != nil {
+		return "", err
+	}
+	return location, nil
+}
+
+// bindingsCSV returns the gzipped CSV of the bindings, whose values are enclosed in double quotes unless they are
+// NULL. The bindings are either all arrays of the same length or none.
+func bindingsCSV(bindings map[string]execBindParameter) ([]byte, error) {
+	columns := make([][]*string, len(bindings))
+	rows, arrays := 0, false
+	for i := range columns {
+		b, ok := bindings[strconv.Itoa(i+1)]
+		if !ok {
+			return nil, fmt.Errorf("no binding %v", i+1)
+		}
+		values, array := b.Value.([]*string)
+		if !array {
+			s, _ := b.Value.(*string)
+			values = []*string{s}
+		}
+		if i == 0 {
+			rows, arrays = len(values), array
+		} else if array != arrays || len(values) != rows {
+			return nil, fmt.Errorf("the binding %v has %v values, not %v as the others", i+1, len(values), rows)
+		}
+		columns[i] = make([]*string, len(values))
+		for j, s := range values {
+			if s == nil {
+				continue
+			}
+			text, err := stageBindText(b.Type, *s)
+			if err != nil {
+				return nil, fmt.Errorf("the binding %v: %v", i+1, err)
+			}
+			columns[i][j] = &text
+		}
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	for row := 0; row < rows; row++ {
+		for i, values := range columns {
+			if i > 0 {
+				zw.Write([]byte{','})
+			}
+			if s := values[row]; s != nil {
+				zw.Write([]byte(`"` + strings.Replace(*s, `"`, `""`, -1) + `"`))
+			}
+		}
+		zw.Write([]byte{'\n'})
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// stageBindText converts the value of a binding of the type, as valueToString formats it for a query request, to
+// the text that COPY parses with the default formats, e.g., the epoch nanoseconds of a TIMESTAMP_NTZ to
+// 2006-01-02 15:04:05.999999999.
+func stageBindText(typ string, s string) (string, error) {
+	switch typ {
+	case "DATE":
+		ms, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return "", err
+		}
+		return time.Unix(0, ms*int64(time.Millisecond)).UTC().Format("2006-01-02"), nil
+	case "TIME":
+		ns, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return "", err
+		}
+		return time.Unix(0, ns).UTC().Format("15:04:05.999999999"), nil
+	case "TIMESTAMP_NTZ", "TIMESTAMP_LTZ":
+		ns, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return "", err
+		}
+		if typ == "TIMESTAMP_LTZ" {
+			return time.Unix(0, ns).UTC().Format("2006-01-02 15:04:05.999999999 -07:00"), nil
+		}
+		return time.Unix(0, ns).UTC().Format("2006-01-02 15:04:05.999999999"), nil
+	case "TIMESTAMP_TZ":
+		// the epoch nanoseconds and the offset in minutes plus 1440
+		fields := strings.Fields(s)
+		if len(fields) != 2 {
+			return "", fmt.Errorf("invalid TIMESTAMP_TZ: %v", s)
+		}
+		ns, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return "", err
+		}
+		offset, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return "", err
+		}
+		loc := time.FixedZone("", (offset-1440)*60)
+		return time.Unix(0, ns).In(loc).Format("2006-01-02 15:04:05.999999999 -07:00"), nil
+	}
+	return s, nil
+}
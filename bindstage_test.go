@@ -0,0 +1,61 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestStageBindText(t *testing.T) {
+	at := time.Date(2020, 9, 1, 10, 11, 12, 5000000, time.FixedZone("", -7*60*60))
+	testcases := []struct {
+		typ      string
+		value    interface{}
+		expected string
+	}{
+		{"DATE", at, "2020-09-01"},
+		{"TIME", at, "10:11:12.005"},
+		{"TIMESTAMP_NTZ", at, "2020-09-01 17:11:12.005"},
+		{"TIMESTAMP_LTZ", at, "2020-09-01 17:11:12.005 +00:00"},
+		{"TIMESTAMP_TZ", at, "2020-09-01 10:11:12.005 -07:00"},
+		{"FIXED", int64(-3), "-3"},
+		{"BINARY", []byte{0xca, 0xfe}, "cafe"},
+	}
+	for _, tc := range testcases {
+		s, err := valueToString(tc.value, tc.typ)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if text, err := stageBindText(tc.typ, *s); err != nil || text != tc.expected {
+			t.Errorf("%v: expected: %v, got: %v, err: %v", tc.typ, tc.expected, text, err)
+		}
+	}
+}
+
+func TestBindingsCSV(t *testing.T) {
+	a, b := "a", "b"
+	content, err := bindingsCSV(map[string]execBindParameter{
+		"1": {Type: "FIXED", Value: []*string{&a, nil}},
+		"2": {Type: "TEXT", Value: []*string{&b, &a}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if csv, err := ioutil.ReadAll(zr); err != nil || string(csv) != "\"a\",\"b\"\n,\"a\"\n" {
+		t.Fatalf("the array bindings should have a row per element: %q, err: %v", csv, err)
+	}
+	if _, err = bindingsCSV(map[string]execBindParameter{
+		"1": {Type: "TEXT", Value: []*string{&a, &b}},
+		"2": {Type: "TEXT", Value: []*string{&a}},
+	}); err == nil {
+		t.Fatal("the arrays of different lengths should fail")
+	}
+}
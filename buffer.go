@@ -0,0 +1,54 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"sync"
+)
+
+const (
+	// maxPooledBufferSize is the capacity above which a buffer is not returned to the pool
+	// so that a single large request doesn't pin the memory.
+	maxPooledBufferSize = 1 << 20 // 1MB
+	// maxDrainBytes is the number of bytes read from a response body before closing it. The transport
+	// reuses the connection only if the body is read to the end.
+	maxDrainBytes = 64 << 10 // 64KB
+)
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	buf.Reset()
+	bufferPool.Put(buf)
+}
+
+// drainAndClose reads the rest of the response body and closes it so that the connection can be reused.
+func drainAndClose(body io.ReadCloser) error {
+	io.Copy(ioutil.Discard, io.LimitReader(body, maxDrainBytes))
+	return body.Close()
+}
+
+// decodeJSONBody reads the whole response body into a pooled buffer and decodes it into v.
+func decodeJSONBody(body io.Reader, v interface{}) error {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if _, err := buf.ReadFrom(body); err != nil {
+		return err
+	}
+	return json.Unmarshal(buf.Bytes(), v)
+}
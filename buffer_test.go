@@ -0,0 +1,57 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+type trackedBody struct {
+	*strings.Reader
+	closed bool
+}
+
+func (b *trackedBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestDrainAndClose(t *testing.T) {
+	body := &trackedBody{Reader: strings.NewReader(`{"code":"0"}   trailing data`)}
+	if err := drainAndClose(body); err != nil {
+		t.Fatalf("failed to drain. err: %v", err)
+	}
+	if body.Len() != 0 || !body.closed {
+		t.Fatalf("body should have been read to the end and closed. remaining: %v, closed: %v", body.Len(), body.closed)
+	}
+	large := &trackedBody{Reader: strings.NewReader(strings.Repeat("x", maxDrainBytes+10))}
+	drainAndClose(large)
+	if large.Len() != 10 || !large.closed {
+		t.Fatalf("drain should stop at the limit. remaining: %v, closed: %v", large.Len(), large.closed)
+	}
+}
+
+func TestDecodeJSONBody(t *testing.T) {
+	var respd execResponse
+	if err := decodeJSONBody(strings.NewReader(`{"code":"123","success":true}`), &respd); err != nil {
+		t.Fatalf("failed to decode. err: %v", err)
+	}
+	if respd.Code != "123" || !respd.Success {
+		t.Fatalf("unexpected response: %+v", respd)
+	}
+	if err := decodeJSONBody(strings.NewReader(`{"code":`), &respd); err == nil {
+		t.Fatal("should have failed to decode a truncated body")
+	}
+}
+
+func TestPutBufferDropsLargeBuffers(t *testing.T) {
+	buf := bytes.NewBuffer(make([]byte, 0, maxPooledBufferSize+1))
+	putBuffer(buf)
+	buf = getBuffer()
+	defer putBuffer(buf)
+	if buf.Cap() > maxPooledBufferSize {
+		t.Fatalf("large buffer should not have been pooled. capacity: %v", buf.Cap())
+	}
+}
@@ -0,0 +1,294 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// CassetteMode is whether a Cassette records or replays the HTTP exchanges.
+type CassetteMode int
+
+const (
+	// CassetteRecord sends the requests to Snowflake and records the exchanges.
+	CassetteRecord CassetteMode = iota
+	// CassetteReplay answers the requests with the recorded responses without any network access.
+	CassetteReplay
+)
+
+// scrubbed replaces the secrets in the recorded exchanges.
+const scrubbed = "<scrubbed>"
+
+// cassetteQueryParams are the query parameters kept in the recorded URLs. The others, e.g., the request IDs
+// and the signatures of presigned URLs, vary per request or are secrets.
+var cassetteQueryParams = map[string]bool{
+	"databaseName": true,
+	"schemaName":   true,
+	"warehouse":    true,
+	"roleName":     true,
+	"delete":       true,
+}
+
+// CassetteRequest is a recorded request.
+type CassetteRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+// CassetteResponse is a recorded response. Body is base64 encoded if Base64 is true.
+type CassetteResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	Base64     bool        `json:"base64,omitempty"`
+}
+
+// CassetteInteraction is a recorded exchange.
+type CassetteInteraction struct {
+	Request  CassetteRequest  `json:"request"`
+	Response CassetteResponse `json:"response"`
+	used     bool
+}
+
+// Cassette records the HTTP exchanges of the connections with Snowflake to a file, or replays them from the
+// file, so that tests of the code using the driver run deterministically and offline. The passwords, tokens,
+// keys and signatures are scrubbed from the recorded exchanges. A Cassette is safe for concurrent use and may be
+// shared by many connections through Config.Cassette.
+//
+// In replay mode, a request is answered with the first unused exchange that has the same method, URL path,
+// query parameters and SQL text. A request without one fails.
+type Cassette struct {
+	mu           sync.Mutex
+	path         string
+	mode         CassetteMode
+	interactions []*CassetteInteraction
+}
+
+// NewCassette creates a Cassette of the file. In replay mode the file is loaded.
+func NewCassette(path string, mode CassetteMode) (*Cassette, error) {
+	c := &Cassette{path: path, mode: mode}
+	if mode != CassetteReplay {
+		return c, nil
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(b, &c.interactions); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Mode returns the mode of the cassette.
+func (c *Cassette) Mode() CassetteMode {
+	return c.mode
+}
+
+// Interactions returns the recorded exchanges.
+func (c *Cassette) Interactions() []CassetteInteraction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ret := make([]CassetteInteraction, len(c.interactions))
+	for i, in := range c.interactions {
+		ret[i] = *in
+	}
+	return ret
+}
+
+// Save writes the recorded exchanges to the file. It does nothing in replay mode.
+func (c *Cassette) Save() error {
+	if c.mode == CassetteReplay {
+		return nil
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	c.mu.Lock()
+	err := enc.Encode(c.interactions)
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.path, buf.Bytes(), 0600)
+}
+
+// transport returns the transport that records the exchanges through base, or replays them.
+func (c *Cassette) transport(base http.RoundTripper) http.RoundTripper {
+	return &cassetteTransport{cassette: c, base: base}
+}
+
+type cassetteTransport struct {
+	cassette *Cassette
+	base     http.RoundTripper
+}
+
+func (t *cassetteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	recorded := CassetteRequest{
+		Method: req.Method,
+		URL:    scrubCassetteURL(req.URL.String()),
+		Body:   scrubCassetteBody(body),
+	}
+	if t.cassette.mode == CassetteReplay {
+		return t.cassette.replay(req, recorded)
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+	in := &CassetteInteraction{
+		Request:  recorded,
+		Response: CassetteResponse{StatusCode: resp.StatusCode, Header: resp.Header.Clone()},
+	}
+	in.Response.Header.Del("Set-Cookie")
+	in.Response.Header.Del("Content-Length")
+	if utf8.Valid(respBody) {
+		in.Response.Body = scrubCassetteBody(respBody)
+	} else {
+		in.Response.Body = base64.StdEncoding.EncodeToString(respBody)
+		in.Response.Base64 = true
+	}
+	t.cassette.mu.Lock()
+	t.cassette.interactions = append(t.cassette.interactions, in)
+	t.cassette.mu.Unlock()
+	return resp, nil
+}
+
+// replay returns the response of the first unused exchange that matches the request.
+func (c *Cassette) replay(req *http.Request, recorded CassetteRequest) (*http.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, in := range c.interactions {
+		if in.used || !in.Request.matches(recorded) {
+			continue
+		}
+		in.used = true
+		body := []byte(in.Response.Body)
+		if in.Response.Base64 {
+			var err error
+			if body, err = base64.StdEncoding.DecodeString(in.Response.Body); err != nil {
+				return nil, err
+			}
+		}
+		return &http.Response{
+			Status:        http.StatusText(in.Response.StatusCode),
+			StatusCode:    in.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        in.Response.Header.Clone(),
+			Body:          ioutil.NopCloser(bytes.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, &SnowflakeError{
+		Number:      ErrCodeCassetteNotRecorded,
+		Message:     errMsgCassetteNotRecorded,
+		MessageArgs: []interface{}{recorded.Method, recorded.URL},
+	}
+}
+
+func (r CassetteRequest) matches(o CassetteRequest) bool {
+	if r.Method != o.Method {
+		return false
+	}
+	u1, err1 := url.Parse(r.URL)
+	u2, err2 := url.Parse(o.URL)
+	if err1 != nil || err2 != nil || u1.Path != u2.Path || u1.Query().Encode() != u2.Query().Encode() {
+		return false
+	}
+	return cassetteSQLText(r.Body) == cassetteSQLText(o.Body)
+}
+
+// cassetteSQLText returns the SQL text of the query request body, or empty.
+func cassetteSQLText(body string) string {
+	var req struct {
+		SQLText string `json:"sqlText"`
+	}
+	if json.Unmarshal([]byte(body), &req) != nil {
+		return ""
+	}
+	return req.SQLText
+}
+
+// scrubCassetteURL removes the query parameters other than cassetteQueryParams from the URL.
+func scrubCassetteURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.RawQuery == "" {
+		return s
+	}
+	q := u.Query()
+	for k := range q {
+		if !cassetteQueryParams[k] {
+			q.Del(k)
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// scrubCassetteBody scrubs the secrets of the JSON body. A body that isn't JSON is returned as is.
+func scrubCassetteBody(body []byte) string {
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(body))
+	d.UseNumber()
+	if len(body) == 0 || d.Decode(&v) != nil {
+		return string(body)
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if enc.Encode(scrubCassetteValue(v)) != nil {
+		return string(body)
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func scrubCassetteValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		for k, e := range x {
+			if isSecretField(k) && e != nil {
+				x[k] = scrubbed
+			} else {
+				x[k] = scrubCassetteValue(e)
+			}
+		}
+	case []interface{}:
+		for i, e := range x {
+			x[i] = scrubCassetteValue(e)
+		}
+	case string:
+		// presigned URLs of the result chunks
+		if strings.HasPrefix(x, "https://") || strings.HasPrefix(x, "http://") {
+			return scrubCassetteURL(x)
+		}
+	}
+	return v
+}
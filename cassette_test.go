@@ -0,0 +1,75 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestCassetteRecordReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	rec, err := NewCassette(path, CassetteRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		body := `{"data":{"token":"secret-token","chunks":[{"url":"https://stage.s3.amazonaws.com/c0?X-Amz-Signature=abc"}]},"success":true}`
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Set-Cookie": {"id=1"}, "Content-Type": {"application/json"}},
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+		}, nil
+	})
+	client := &http.Client{Transport: rec.transport(base)}
+	query := `{"sqlText":"SELECT 1","sequenceId":1}`
+	resp, err := client.Post("https://acct.snowflakecomputing.com/queries/v1/query-request?requestId=1&request_guid=2",
+		"application/json", strings.NewReader(query))
+	if err != nil {
+		t.Fatal(err)
+	}
+	live, _ := ioutil.ReadAll(resp.Body)
+	if !strings.Contains(string(live), "secret-token") {
+		t.Fatalf("the live response should not be scrubbed: %v", string(live))
+	}
+	if err = rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+	saved, _ := ioutil.ReadFile(path)
+	for _, secret := range []string{"secret-token", "X-Amz-Signature", "requestId", "Set-Cookie"} {
+		if bytes.Contains(saved, []byte(secret)) {
+			t.Fatalf("%v should be scrubbed from the cassette: %v", secret, string(saved))
+		}
+	}
+
+	rep, err := NewCassette(path, CassetteReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = &http.Client{Transport: rep.transport(nil)}
+	resp, err = client.Post("https://other.snowflakecomputing.com/queries/v1/query-request?requestId=3",
+		"application/json", strings.NewReader(`{"sqlText":"SELECT 1","sequenceId":7}`))
+	if err != nil {
+		t.Fatalf("failed to replay. err: %v", err)
+	}
+	replayed, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(replayed), `"token":"<scrubbed>"`) {
+		t.Fatalf("unexpected response. status: %v, body: %v", resp.StatusCode, string(replayed))
+	}
+	// each exchange is replayed once
+	_, err = client.Post("https://other.snowflakecomputing.com/queries/v1/query-request",
+		"application/json", strings.NewReader(`{"sqlText":"SELECT 1"}`))
+	if err == nil || !strings.Contains(err.Error(), "260021") {
+		t.Fatalf("should fail for a request that isn't recorded. err: %v", err)
+	}
+}
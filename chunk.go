@@ -2,6 +2,7 @@ package gosnowflake
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 
@@ -50,6 +51,29 @@ func decodeLargeChunk(r io.Reader, rowCount int, cellCount int) ([][]*string, er
 	return rows, nil
 }
 
+// decodeChunkRows parses a JSON chunk a row at a time, so that only a row is buffered by the decoder
+// instead of the whole chunk body.
+func decodeChunkRows(r io.Reader, rowCount int) ([]chunkRowType, error) {
+	dec := json.NewDecoder(r)
+	if t, err := dec.Token(); err != nil {
+		return nil, err
+	} else if t != json.Delim('[') {
+		return nil, fmt.Errorf("corrupt chunk: expected chunk to begin with '['")
+	}
+	rows := make([]chunkRowType, 0, rowCount)
+	for dec.More() {
+		var row []*string
+		if err := dec.Decode(&row); err != nil {
+			return nil, err
+		}
+		rows = append(rows, chunkRowType{RowSet: row})
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
 func (lcd *largeChunkDecoder) mkError(s string) error {
 	return fmt.Errorf("corrupt chunk: %s", s)
 }
@@ -258,6 +282,10 @@ func (lcd *largeChunkDecoder) nextByteNonWhitespace() byte {
 }
 
 func (lcd *largeChunkDecoder) rewind(n int) {
+	if lcd.ptr < n {
+		// nothing was read at the end of the stream
+		return
+	}
 	lcd.ptr -= n
 	lcd.rem += n
 }
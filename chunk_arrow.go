@@ -5,6 +5,7 @@ package gosnowflake
 import (
 	"bytes"
 	"encoding/base64"
+	"fmt"
 	"github.com/apache/arrow/go/arrow/ipc"
 	"github.com/apache/arrow/go/arrow/memory"
 	"io"
@@ -17,8 +18,14 @@ type arrowResultChunk struct {
 	allocator        memory.Allocator
 }
 
-func (arc *arrowResultChunk) decodeArrowChunk(rowType []execResponseRowType) ([]chunkRowType, error) {
+func (arc *arrowResultChunk) decodeArrowChunk(rowType []execResponseRowType, raw bool) (rows []chunkRowType, err error) {
 	glog.V(2).Info("Arrow Decoder")
+	defer func() {
+		// the arrow library panics on the data that doesn't match its type or the type of the column
+		if r := recover(); r != nil {
+			rows, err = nil, fmt.Errorf("corrupt arrow chunk: %v", r)
+		}
+	}()
 
 	var chunkRows []chunkRowType
 
@@ -32,12 +39,22 @@ func (arc *arrowResultChunk) decodeArrowChunk(rowType []execResponseRowType) ([]
 
 		numRows := int(record.NumRows())
 		columns := record.Columns()
+		if !raw && len(columns) > len(rowType) {
+			return nil, fmt.Errorf("corrupt arrow chunk: %v columns, %v in the row type", len(columns), len(rowType))
+		}
 		tmpRows := make([]chunkRowType, numRows)
 
 		for colIdx, col := range columns {
 			destcol := make([]snowflakeValue, numRows)
-			err := arrowToValue(&destcol, rowType[colIdx], col)
-			if err != nil {
+			var err error
+			if raw {
+				err = arrowToRawValue(&destcol, col)
+			} else {
+				err = arrowToValue(&destcol, rowType[colIdx], col)
+			}
+			if err != nil && colIdx < len(rowType) {
+				return nil, newConversionError(rowType[colIdx], -1, nil, err)
+			} else if err != nil {
 				return nil, err
 			}
 
@@ -80,6 +80,16 @@ func TestValidChunkData(t *testing.T) {
 	testDecodeOk(t, `[["𐍈"]]`)            // "𐍈"
 }
 
+func TestBadChunkDataStreaming(t *testing.T) {
+	for _, s := range []string{
+		"", "42", "{}", "[[]", `[[hello world]]`, `[["hello world"]`, `[["hello world"`, `[["\uQQQQ"]]`,
+	} {
+		if _, err := decodeChunkRows(strings.NewReader(s), 0); err == nil {
+			t.Fatalf("expected streaming decode to fail for input: %s", s)
+		}
+	}
+}
+
 func TestSmallBufferChunkData(t *testing.T) {
 	r := strings.NewReader(`[
 	  [null,"hello world"],
@@ -139,6 +149,20 @@ func testDecodeOk(t *testing.T, s string) {
 		t.Fatalf("expected decode to succeed: %s", err)
 	}
 
+	compareDecodedRows(t, rows, expect)
+
+	chunkRows, err := decodeChunkRows(strings.NewReader(s), 0)
+	if err != nil {
+		t.Fatalf("expected streaming decode to succeed: %s", err)
+	}
+	rows = make([][]*string, len(chunkRows))
+	for i, row := range chunkRows {
+		rows[i] = row.RowSet
+	}
+	compareDecodedRows(t, rows, expect)
+}
+
+func compareDecodedRows(t *testing.T, rows [][]*string, expect []byte) {
 	actual, err := json.Marshal(rows)
 	if err != nil {
 		t.Fatalf("json marshal failed: %s", err)
@@ -0,0 +1,172 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ChunkCacheStats is a snapshot of the counters of a ChunkCache.
+type ChunkCacheStats struct {
+	Hits      int64 // number of chunks read from the cache, or from the download of another fetch
+	Misses    int64 // number of chunks downloaded
+	Evictions int64 // number of chunks evicted to make room for new ones
+	Entries   int   // number of chunks in the cache
+	Bytes     int64 // size of the chunks in the cache
+}
+
+// ChunkCache is a client side cache of the downloaded result chunks, keyed by a hash of the URL of the chunk and
+// the key it is encrypted with, so that the workers fetching the result of the same query ID with
+// WithFetchResultByID download every chunk once. The chunks of the other queries are not cached. A chunk being
+// downloaded for a fetch is waited for by the others instead of being downloaded again. The chunks are cached as
+// they are downloaded, before they are decoded, and the least recently used ones are evicted when the cache
+// exceeds its size. A ChunkCache is safe for concurrent use and may be shared by many connections through
+// Config.ChunkCache.
+//
+// The signature of the URL, which changes every time a result is fetched, is not part of the key.
+type ChunkCache struct {
+	mu       sync.Mutex
+	maxBytes int64
+	bytes    int64
+	entries  map[string]*list.Element
+	lru      *list.List
+	loading  map[string]*chunkCacheCall
+	stats    ChunkCacheStats
+}
+
+type chunkCacheEntry struct {
+	key  string
+	data []byte
+}
+
+// chunkCacheCall is the download of a chunk the other fetches of the chunk wait for.
+type chunkCacheCall struct {
+	done chan struct{}
+	data []byte
+	err  error
+}
+
+// NewChunkCache creates a ChunkCache that holds up to maxBytes bytes of chunks. A chunk larger than maxBytes is
+// not cached, and is decoded as it is downloaded.
+func NewChunkCache(maxBytes int64) *ChunkCache {
+	return &ChunkCache{
+		maxBytes: maxBytes,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+		loading:  make(map[string]*chunkCacheCall),
+	}
+}
+
+// Stats returns the counters of the cache.
+func (cc *ChunkCache) Stats() ChunkCacheStats {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	stats := cc.stats
+	stats.Entries = cc.lru.Len()
+	stats.Bytes = cc.bytes
+	return stats
+}
+
+// Purge removes all chunks from the cache.
+func (cc *ChunkCache) Purge() {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.entries = make(map[string]*list.Element)
+	cc.lru.Init()
+	cc.bytes = 0
+}
+
+// load returns the chunk of the key from the cache, or from the download of the chunk in progress, or downloads it
+// and caches it. If the download in progress fails, e.g., because its fetch is canceled, the chunk is downloaded
+// again.
+func (cc *ChunkCache) load(ctx context.Context, key string, download func() ([]byte, error)) ([]byte, error) {
+	for {
+		cc.mu.Lock()
+		if elem, ok := cc.entries[key]; ok {
+			cc.lru.MoveToFront(elem)
+			cc.stats.Hits++
+			cc.mu.Unlock()
+			return elem.Value.(*chunkCacheEntry).data, nil
+		}
+		call, ok := cc.loading[key]
+		if !ok {
+			break
+		}
+		cc.mu.Unlock()
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if call.err == nil {
+			cc.mu.Lock()
+			cc.stats.Hits++
+			cc.mu.Unlock()
+			return call.data, nil
+		}
+	}
+	call := &chunkCacheCall{done: make(chan struct{})}
+	cc.loading[key] = call
+	cc.stats.Misses++
+	cc.mu.Unlock()
+
+	call.data, call.err = download()
+	cc.mu.Lock()
+	delete(cc.loading, key)
+	if call.err == nil {
+		cc.add(key, call.data)
+	}
+	cc.mu.Unlock()
+	close(call.done)
+	return call.data, call.err
+}
+
+// add caches the chunk and evicts the least recently used ones beyond the size of the cache. The caller must hold
+// the lock.
+func (cc *ChunkCache) add(key string, data []byte) {
+	size := int64(len(data))
+	if size > cc.maxBytes {
+		return
+	}
+	if elem, ok := cc.entries[key]; ok {
+		cc.bytes -= int64(len(elem.Value.(*chunkCacheEntry).data))
+		cc.lru.Remove(elem)
+	}
+	cc.entries[key] = cc.lru.PushFront(&chunkCacheEntry{key: key, data: data})
+	cc.bytes += size
+	for cc.bytes > cc.maxBytes {
+		oldest := cc.lru.Back()
+		entry := oldest.Value.(*chunkCacheEntry)
+		cc.lru.Remove(oldest)
+		delete(cc.entries, entry.key)
+		cc.bytes -= int64(len(entry.data))
+		cc.stats.Evictions++
+	}
+}
+
+// chunkCacheKey returns the key of the chunk of the URL downloaded with the headers, which have the key the chunk
+// is encrypted with, i.e., the QRMK or the chunk headers of the result. The query string of the URL is dropped as
+// it has the signature of the URL. The key is a SHA-256 hash so that the cache doesn't hold the encryption keys.
+func chunkCacheKey(chunkURL string, headers map[string]string) string {
+	if i := strings.IndexByte(chunkURL, '?'); i >= 0 {
+		chunkURL = chunkURL[:i]
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	h := sha256.New()
+	io.WriteString(h, chunkURL)
+	for _, name := range names {
+		io.WriteString(h, "|"+strings.ToLower(name)+":"+headers[name])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
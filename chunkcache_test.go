@@ -0,0 +1,170 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fetchCachedResult reads the result of numChunks chunks with the cache and the context, counting the chunk
+// downloads, and returns the number of rows read.
+func fetchCachedResult(ctx context.Context, t *testing.T, cache *ChunkCache, numChunks int, qrmk string, downloads *int32) int {
+	cm := make([]execResponseChunk, numChunks)
+	for i := range cm {
+		cm[i] = execResponseChunk{URL: fmt.Sprintf("https://stage/results/qid/chunk%v?X-Amz-Signature=%v", i, time.Now().UnixNano()),
+			RowCount: 3, CompressedSize: int64(len(progressChunkBody))}
+	}
+	v1, v2 := "0", "z"
+	rows := new(snowflakeRows)
+	rows.RowType = []execResponseRowType{{Name: "c1", Type: "fixed"}, {Name: "c2", Type: "text"}}
+	rows.ChunkDownloader = &snowflakeChunkDownloader{
+		sc: &snowflakeConn{
+			cfg:  &Config{ChunkCache: cache},
+			rest: &snowflakeRestful{RequestTimeout: defaultRequestTimeout},
+		},
+		ctx:                ctx,
+		Total:              int64(1 + numChunks*3),
+		ChunkMetas:         cm,
+		TotalRowIndex:      int64(-1),
+		CellCount:          2,
+		Qrmk:               qrmk,
+		FuncDownload:       downloadChunk,
+		FuncDownloadHelper: downloadChunkHelper,
+		FuncGet: func(ctx context.Context, scd *snowflakeChunkDownloader, u string, h map[string]string, d time.Duration) (*http.Response, error) {
+			atomic.AddInt32(downloads, 1)
+			return getChunkTestProgress(ctx, scd, u, h, d)
+		},
+		RowSet: rowSetType{JSON: [][]*string{{&v1, &v2}}},
+	}
+	rows.ChunkDownloader.start()
+	defer rows.Close()
+	dest := make([]driver.Value, 2)
+	cnt := 0
+	for {
+		if err := rows.Next(dest); err == io.EOF {
+			return cnt
+		} else if err != nil {
+			t.Errorf("failed to get value. err: %v", err)
+			return cnt
+		}
+		cnt++
+	}
+}
+
+func TestChunkCacheSharedFetches(t *testing.T) {
+	cache := NewChunkCache(1 << 20)
+	ctx := WithFetchResultByID(context.Background(), "1234-5678")
+	var downloads int32
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if cnt := fetchCachedResult(ctx, t, cache, 3, "key1", &downloads); cnt != 10 {
+				t.Errorf("wrong number of rows. expected: 10, got: %v", cnt)
+			}
+		}()
+	}
+	wg.Wait()
+	if downloads != 3 {
+		t.Fatalf("every chunk should be downloaded once. downloads: %v", downloads)
+	}
+	stats := cache.Stats()
+	if stats.Misses != 3 || stats.Hits != 9 || stats.Entries != 3 || stats.Bytes != int64(3*len(progressChunkBody)) {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	fetchCachedResult(ctx, t, cache, 3, "key2", &downloads)
+	if downloads != 6 {
+		t.Fatalf("the chunks encrypted with another key should be downloaded. downloads: %v", downloads)
+	}
+}
+
+func TestChunkCacheBypass(t *testing.T) {
+	cache := NewChunkCache(1 << 20)
+	var downloads int32
+	for i := 0; i < 2; i++ {
+		fetchCachedResult(context.Background(), t, cache, 3, "key1", &downloads)
+	}
+	if downloads != 6 {
+		t.Fatalf("the chunks of a query should not be cached. downloads: %v", downloads)
+	}
+	if stats := cache.Stats(); stats.Misses != 0 || stats.Entries != 0 {
+		t.Fatalf("the cache should not be used. stats: %+v", stats)
+	}
+
+	small := NewChunkCache(int64(len(progressChunkBody) - 1))
+	ctx := WithFetchResultByID(context.Background(), "1234-5678")
+	downloads = 0
+	for i := 0; i < 2; i++ {
+		if cnt := fetchCachedResult(ctx, t, small, 3, "key1", &downloads); cnt != 10 {
+			t.Fatalf("wrong number of rows. expected: 10, got: %v", cnt)
+		}
+	}
+	if downloads != 6 {
+		t.Fatalf("the chunks larger than the cache should be downloaded every time. downloads: %v", downloads)
+	}
+	if stats := small.Stats(); stats.Misses != 0 || stats.Entries != 0 {
+		t.Fatalf("the chunks larger than the cache should be streamed. stats: %+v", stats)
+	}
+}
+
+func TestChunkCacheEviction(t *testing.T) {
+	cache := NewChunkCache(10)
+	ctx := context.Background()
+	load := func(key string, size int) {
+		if _, err := cache.load(ctx, key, func() ([]byte, error) { return make([]byte, size), nil }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	load("a", 4)
+	load("b", 4)
+	load("a", 4)
+	load("c", 4)
+	if stats := cache.Stats(); stats.Entries != 2 || stats.Bytes != 8 || stats.Evictions != 1 || stats.Hits != 1 {
+		t.Fatalf("the least recently used chunk should be evicted. stats: %+v", cache.Stats())
+	}
+	load("b", 4)
+	if stats := cache.Stats(); stats.Misses != 4 {
+		t.Fatalf("the evicted chunk should be downloaded again. stats: %+v", stats)
+	}
+	load("d", 11)
+	if stats := cache.Stats(); stats.Entries != 2 || stats.Bytes != 8 {
+		t.Fatalf("a chunk larger than the cache should not be cached. stats: %+v", stats)
+	}
+
+	failed := errors.New("failed")
+	if _, err := cache.load(ctx, "e", func() ([]byte, error) { return nil, failed }); err != failed {
+		t.Fatalf("the error of the download should be returned. err: %v", err)
+	}
+	cache.Purge()
+	if stats := cache.Stats(); stats.Entries != 0 || stats.Bytes != 0 {
+		t.Fatalf("the cache should be empty. stats: %+v", stats)
+	}
+}
+
+func TestChunkCacheKey(t *testing.T) {
+	headers := map[string]string{headerSseCAlgorithm: headerSseCAes, headerSseCKey: "qrmk"}
+	if chunkCacheKey("https://stage/c0?sig=1", headers) != chunkCacheKey("https://stage/c0?sig=2", headers) {
+		t.Error("the signature should not be part of the key")
+	}
+	if chunkCacheKey("https://stage/c0", headers) == chunkCacheKey("https://stage/c1", headers) {
+		t.Error("the chunks should have different keys")
+	}
+	if chunkCacheKey("https://stage/c0", headers) == chunkCacheKey("https://stage/c0", map[string]string{headerSseCKey: "other"}) {
+		t.Error("the encryption key should be part of the key")
+	}
+	if key := chunkCacheKey("https://stage/c0", headers); strings.Contains(key, "qrmk") || len(key) != 64 {
+		t.Errorf("the key should be a hash. key: %v", key)
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// resultSpill holds the result chunks of a chunk downloader that are spilled to temporary files. The fields
+// are guarded by the ChunksMutex of the chunk downloader.
+type resultSpill struct {
+	dir      string
+	maxBytes int64
+	files    map[int]string
+	sizes    map[int]int64
+	bytes    int64
+	closed   bool
+}
+
+// newResultSpill returns the resultSpill of the Config, or nil if the chunks are kept in memory.
+func newResultSpill(sc *snowflakeConn) *resultSpill {
+	if sc == nil || sc.cfg == nil || sc.cfg.ResultSpillDir == "" {
+		return nil
+	}
+	return &resultSpill{
+		dir:      sc.cfg.ResultSpillDir,
+		maxBytes: sc.cfg.ResultSpillMaxBytes,
+		files:    make(map[int]string),
+		sizes:    make(map[int]int64),
+	}
+}
+
+// spilled returns true if the chunk is in a temporary file. The caller must hold ChunksMutex.
+func (scd *snowflakeChunkDownloader) spilled(idx int) bool {
+	return scd.spill != nil && scd.spill.files[idx] != ""
+}
+
+// spillChunk writes the chunk, as downloaded, to a temporary file in the spill directory.
+func (scd *snowflakeChunkDownloader) spillChunk(idx int, r io.Reader) error {
+	f, err := ioutil.TempFile(scd.spill.dir, "gosnowflake-chunk-")
+	if err != nil {
+		return err
+	}
+	src := r
+	if scd.spill.maxBytes > 0 {
+		// a byte over the limit is enough to tell that the chunk doesn't fit
+		src = io.LimitReader(r, scd.spill.maxBytes+1)
+	}
+	n, err := io.Copy(f, src)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	scd.ChunksMutex.Lock()
+	defer scd.ChunksMutex.Unlock()
+	if scd.spill.closed {
+		// the rows were closed while the chunk was downloaded
+		os.Remove(f.Name())
+		return nil
+	}
+	if scd.spill.maxBytes > 0 && scd.spill.bytes+n > scd.spill.maxBytes {
+		os.Remove(f.Name())
+		return &SnowflakeError{
+			Number:      ErrCodeResultSpillLimitExceeded,
+			Message:     errMsgResultSpillLimitExceeded,
+			MessageArgs: []interface{}{scd.spill.dir, scd.spill.maxBytes},
+		}
+	}
+	scd.spill.files[idx] = f.Name()
+	scd.spill.sizes[idx] = n
+	scd.spill.bytes += n
+	glog.V(2).Infof("spilled chunk %v to %v. bytes: %v", idx+1, f.Name(), n)
+	return nil
+}
+
+// readSpilledChunk decodes the chunk from its temporary file, which is removed afterwards.
+func (scd *snowflakeChunkDownloader) readSpilledChunk(idx int) error {
+	scd.ChunksMutex.Lock()
+	name := scd.spill.files[idx]
+	scd.ChunksMutex.Unlock()
+	defer scd.removeSpilledChunk(idx)
+
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return decodeChunk(scd, idx, bufio.NewReader(f))
+}
+
+// removeSpilledChunk removes the temporary file of the chunk, if any.
+func (scd *snowflakeChunkDownloader) removeSpilledChunk(idx int) {
+	scd.ChunksMutex.Lock()
+	defer scd.ChunksMutex.Unlock()
+	if name := scd.spill.files[idx]; name != "" {
+		if err := os.Remove(name); err != nil {
+			glog.V(1).Infof("failed to remove the spilled chunk %v. err: %v", name, err)
+		}
+		scd.spill.bytes -= scd.spill.sizes[idx]
+		delete(scd.spill.files, idx)
+		delete(scd.spill.sizes, idx)
+	}
+}
+
+// closeSpill removes the temporary files of the chunks that have not been read, as well as those of the chunks
+// downloaded later.
+func (scd *snowflakeChunkDownloader) closeSpill() {
+	if scd.spill == nil {
+		return
+	}
+	scd.ChunksMutex.Lock()
+	scd.spill.closed = true
+	indexes := make([]int, 0, len(scd.spill.files))
+	for idx := range scd.spill.files {
+		indexes = append(indexes, idx)
+	}
+	scd.ChunksMutex.Unlock()
+	for _, idx := range indexes {
+		scd.removeSpilledChunk(idx)
+	}
+}
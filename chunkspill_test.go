@@ -0,0 +1,133 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func newSpillTestRows(dir string, maxBytes int64, numChunks int) *snowflakeRows {
+	cm := make([]execResponseChunk, 0)
+	for i := 0; i < numChunks; i++ {
+		cm = append(cm, execResponseChunk{URL: fmt.Sprintf("dummyURL%v", i+1), RowCount: 3})
+	}
+	rows := new(snowflakeRows)
+	rows.RowType = []execResponseRowType{
+		{Name: "c1", Type: "fixed"},
+		{Name: "c2", Type: "text"},
+	}
+	rows.ChunkDownloader = &snowflakeChunkDownloader{
+		sc: &snowflakeConn{
+			cfg:  &Config{ResultSpillDir: dir, ResultSpillMaxBytes: maxBytes},
+			rest: &snowflakeRestful{RequestTimeout: defaultRequestTimeout},
+		},
+		ctx:                context.Background(),
+		Total:              int64(numChunks * 3),
+		ChunkMetas:         cm,
+		TotalRowIndex:      int64(-1),
+		CellCount:          2,
+		FuncDownload:       downloadChunk,
+		FuncDownloadHelper: downloadChunkHelper,
+		FuncGet:            getChunkTestProgress,
+	}
+	return rows
+}
+
+func spilledFiles(t *testing.T, dir string) int {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(files)
+}
+
+func TestResultSpill(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spill")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	numChunks := 4
+	rows := newSpillTestRows(dir, int64(2*len(progressChunkBody)), numChunks)
+	backupMaxChunkDownloadWorkers := MaxChunkDownloadWorkers
+	MaxChunkDownloadWorkers = 1
+	defer func() { MaxChunkDownloadWorkers = backupMaxChunkDownloadWorkers }()
+	if err = rows.ChunkDownloader.start(); err != nil {
+		t.Fatal(err)
+	}
+	dest := make([]driver.Value, 2)
+	cnt := 0
+	for {
+		if err = rows.Next(dest); err != nil {
+			break
+		}
+		if dest[0] != fmt.Sprint(cnt%3+1) {
+			t.Fatalf("unexpected row %v: %v", cnt, dest)
+		}
+		cnt++
+	}
+	if err != io.EOF {
+		t.Fatalf("failed to read the spilled chunks. err: %v", err)
+	}
+	if cnt != numChunks*3 {
+		t.Fatalf("wrong number of rows. expected: %v, got: %v", numChunks*3, cnt)
+	}
+	if n := spilledFiles(t, dir); n != 0 {
+		t.Fatalf("the spilled chunks should have been removed once read. files: %v", n)
+	}
+}
+
+func TestResultSpillClose(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spill")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rows := newSpillTestRows(dir, 0, 4)
+	if err = rows.ChunkDownloader.start(); err != nil {
+		t.Fatal(err)
+	}
+	dest := make([]driver.Value, 2)
+	if err = rows.Next(dest); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; spilledFiles(t, dir) < 3; i++ {
+		if i > 100 {
+			t.Fatal("the remaining chunks should have been spilled")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err = rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := spilledFiles(t, dir); n != 0 {
+		t.Fatalf("the spilled chunks should have been removed on close. files: %v", n)
+	}
+}
+
+func TestResultSpillLimitExceeded(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spill")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rows := newSpillTestRows(dir, int64(len(progressChunkBody)-1), 2)
+	if err = rows.ChunkDownloader.start(); err != nil {
+		t.Fatal(err)
+	}
+	dest := make([]driver.Value, 2)
+	err = rows.Next(dest)
+	if driverErr, ok := err.(*SnowflakeError); !ok || driverErr.Number != ErrCodeResultSpillLimitExceeded {
+		t.Fatalf("should have failed with ErrCodeResultSpillLimitExceeded. err: %v", err)
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+)
+
+// ExecResult is the result of Client.Exec.
+type ExecResult interface {
+	driver.Result
+	SnowflakeDMLResult
+}
+
+// Client runs statements on one Snowflake session without the database/sql package, so that there is no
+// connection pool and the query IDs and metadata of the results are directly available.
+//
+// A Client is not safe for concurrent use, the same as a connection of database/sql. The arguments of the
+// statements are bound by position, and are converted the same way as by database/sql.
+type Client struct {
+	sc *snowflakeConn
+}
+
+// NewClient logs in to Snowflake with the Config and returns a Client for the session.
+func NewClient(ctx context.Context, config Config) (*Client, error) {
+	conn, err := SnowflakeDriver{}.OpenWithConfig(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{sc: conn.(*snowflakeConn)}, nil
+}
+
+// Query runs the query and returns its result set.
+func (c *Client) Query(ctx context.Context, query string, args ...interface{}) (ResultSet, error) {
+	nvs, err := c.namedValues(args)
+	if err != nil {
+		return nil, err
+	}
+	return QueryResultSet(ctx, c.sc, query, nvs...)
+}
+
+// Exec runs the statement and returns its result.
+func (c *Client) Exec(ctx context.Context, query string, args ...interface{}) (ExecResult, error) {
+	nvs, err := c.namedValues(args)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.sc.ExecContext(ctx, query, nvs)
+	if err != nil {
+		return nil, err
+	}
+	return c.execResult(res), nil
+}
+
+// noRowsResult is the ExecResult of a statement whose driver result is driver.ResultNoRows, e.g., a query.
+type noRowsResult struct {
+	driver.Result
+	queryID string
+}
+
+func (res noRowsResult) QueryID() string {
+	return res.queryID
+}
+
+func (res noRowsResult) RowCounts() DMLRowCounts {
+	return DMLRowCounts{}
+}
+
+// execResult returns the driver result of ExecContext as an ExecResult.
+func (c *Client) execResult(res driver.Result) ExecResult {
+	if er, ok := res.(ExecResult); ok {
+		return er
+	}
+	return noRowsResult{Result: res, queryID: c.sc.QueryID}
+}
+
+// SubmitAsync submits the statement and returns its query ID as soon as Snowflake accepts it, without
+// waiting for it to complete. The result is fetched by FetchResult, and the status by QueryStatus.
+func (c *Client) SubmitAsync(ctx context.Context, query string, args ...interface{}) (string, error) {
+	nvs, err := c.namedValues(args)
+	if err != nil {
+		return "", err
+	}
+	res, err := c.sc.ExecContext(WithAsyncCompletion(ctx, nil), query, nvs)
+	if err != nil {
+		return "", err
+	}
+	return res.(SnowflakeResult).QueryID(), nil
+}
+
+// FetchResult returns the result set of the query, waiting for the query to complete if it is still running.
+func (c *Client) FetchResult(ctx context.Context, queryID string) (ResultSet, error) {
+	return QueryResultSet(WithFetchResultByID(ctx, queryID), c.sc, "")
+}
+
+// QueryStatus returns the status of the query.
+func (c *Client) QueryStatus(ctx context.Context, queryID string) (*QueryStatus, error) {
+	return c.sc.QueryStatus(ctx, queryID)
+}
+
+// PutFile uploads the local file to the stage location, e.g., @orders_stage/2020, as PutFiles does. localPath may
+// be a pattern, and the error is that of the first file that failed.
+func (c *Client) PutFile(ctx context.Context, localPath string, stageLocation string) error {
+	results, err := c.PutFiles(ctx, []string{localPath}, stageLocation, nil)
+	if err != nil {
+		return err
+	}
+	for _, r := range results {
+		if r.Status == PutFileFailed {
+			return r.Err
+		}
+	}
+	return nil
+}
+
+// GetFile downloads the files of the stage location, e.g., @orders_stage/2020/a.csv.gz, or every file in
+// @orders_stage/2020, to the local directory, as GET does.
+func (c *Client) GetFile(ctx context.Context, stageLocation string, localDir string) error {
+	return c.getFiles(ctx, stageLocation, localDir)
+}
+
+// ConnectionInfo returns the information of the session.
+func (c *Client) ConnectionInfo() ConnectionInfo {
+	return c.sc.ConnectionInfo()
+}
+
+// CurrentServiceName returns the service name that Snowflake assigned to the session.
+func (c *Client) CurrentServiceName() string {
+	return c.sc.CurrentServiceName()
+}
+
+// AbortAll aborts every query the Client has submitted and that has not returned yet. Unlike the other
+// methods, it may be called while the Client runs queries, e.g., from another goroutine on shutdown.
+func (c *Client) AbortAll(ctx context.Context) error {
+	return c.sc.AbortAll(ctx)
+}
+
+// Close logs out of the session.
+func (c *Client) Close() error {
+	return c.sc.Close()
+}
+
+// namedValues converts the arguments the same way as database/sql, including the arrays of
+// Array, and numbers them by position.
+func (c *Client) namedValues(args []interface{}) ([]driver.NamedValue, error) {
+	nvs := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		nv := driver.NamedValue{Ordinal: i + 1, Value: arg}
+		if err := c.sc.CheckNamedValue(&nv); err == nil {
+			nvs[i] = nv
+			continue
+		}
+		v, err := driver.DefaultParameterConverter.ConvertValue(arg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert argument %d: %v", i+1, err)
+		}
+		nv.Value = v
+		nvs[i] = nv
+	}
+	return nvs, nil
+}
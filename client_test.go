@@ -0,0 +1,136 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// fakeQueryClient is a Client whose statements are answered by respond instead of Snowflake, recording their
+// requests.
+type fakeQueryClient struct {
+	*Client
+	mu       sync.Mutex
+	requests []execRequest
+	// respond answers the request with mu held. It returns the rows of newFakeQueryClient by default.
+	respond func(req execRequest) (*execResponse, error)
+}
+
+// newFakeQueryClient returns the fakeQueryClient whose statements return the rows of the columns as JSON.
+func newFakeQueryClient(rowType []execResponseRowType, rows ...[]string) *fakeQueryClient {
+	c := &fakeQueryClient{}
+	c.respond = func(execRequest) (*execResponse, error) {
+		return fakeQueryResponse("qid", rowType, rows...), nil
+	}
+	c.Client = &Client{sc: &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{
+			FuncPostQuery: func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte, _ time.Duration, _ *uuid.UUID) (*execResponse, error) {
+				var req execRequest
+				if err := json.Unmarshal(body, &req); err != nil {
+					return nil, err
+				}
+				c.mu.Lock()
+				defer c.mu.Unlock()
+				c.requests = append(c.requests, req)
+				return c.respond(req)
+			},
+		},
+	}}
+	return c
+}
+
+// fakeQueryResponse returns the successful response of the query whose result is the rows of the columns.
+func fakeQueryResponse(qid string, rowType []execResponseRowType, rows ...[]string) *execResponse {
+	data := execResponseData{QueryID: qid, QueryResultFormat: jsonFormat, RowType: rowType, Total: int64(len(rows))}
+	for _, row := range rows {
+		values := make([]*string, len(row))
+		for i := range row {
+			values[i] = &row[i]
+		}
+		data.RowSet = append(data.RowSet, values)
+	}
+	return &execResponse{Data: data, Code: "0", Success: true}
+}
+
+// recorded returns the requests of the statements run so far.
+func (c *fakeQueryClient) recorded() []execRequest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]execRequest(nil), c.requests...)
+}
+
+// queries returns the SQL texts of the statements run so far.
+func (c *fakeQueryClient) queries() []string {
+	var queries []string
+	for _, req := range c.recorded() {
+		queries = append(queries, req.SQLText)
+	}
+	return queries
+}
+
+func TestClient(t *testing.T) {
+	var req execRequest
+	c := &Client{sc: &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{
+			FuncPostQuery: func(ctx context.Context, sr *snowflakeRestful, params *url.Values, headers map[string]string, body []byte, timeout time.Duration, requestID *uuid.UUID) (*execResponse, error) {
+				req = execRequest{}
+				if err := json.Unmarshal(body, &req); err != nil {
+					return nil, err
+				}
+				if req.AsyncExec {
+					return postAsyncQueryMock(ctx, sr, params, headers, body, timeout, requestID)
+				}
+				one := "1"
+				return &execResponse{
+					Data: execResponseData{
+						QueryID:         "sync-1",
+						RowType:         []execResponseRowType{{Name: "number of rows inserted", Type: "fixed"}},
+						RowSet:          [][]*string{{&one}},
+						Total:           1,
+						StatementTypeID: statementTypeIDInsert,
+					},
+					Code:    "0",
+					Success: true,
+				}, nil
+			},
+			FuncGet: getQueryResultMock,
+		},
+	}}
+
+	res, err := c.Exec(context.Background(), "INSERT INTO t VALUES(?, ?)", 1, []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("failed to execute the statement. err: %v", err)
+	}
+	if res.QueryID() != "sync-1" || res.RowCounts().Inserted != 1 {
+		t.Fatalf("unexpected result. query ID: %v, counts: %+v", res.QueryID(), res.RowCounts())
+	}
+	if b := req.Bindings["1"]; b.Type != "FIXED" || b.Value != "1" {
+		t.Fatalf("unexpected binding: %+v", b)
+	}
+	if b := req.Bindings["2"]; b.Type != "TEXT" {
+		t.Fatalf("unexpected array binding: %+v", b)
+	}
+
+	qid, err := c.SubmitAsync(context.Background(), "INSERT INTO t VALUES(1)")
+	if err != nil || qid != "async-1" {
+		t.Fatalf("failed to submit the statement. query ID: %v, err: %v", qid, err)
+	}
+
+	rs, err := c.FetchResult(context.Background(), "1234-5678")
+	if err != nil {
+		t.Fatalf("failed to fetch the result. err: %v", err)
+	}
+	defer rs.Close()
+	if rs.QueryID() != "1234-5678" {
+		t.Fatalf("unexpected query ID: %v", rs.QueryID())
+	}
+}
@@ -0,0 +1,289 @@
+// sfsql runs SQL statements on Snowflake from the command line, e.g., to try out a DSN or as a smoke test
+// of the driver.
+//
+// The DSN is given by -dsn or the SNOWFLAKE_DSN environment variable. A statement given as an argument is run
+// and its result printed, and sfsql exits with 1 if it fails:
+//
+//	sfsql -format csv "SELECT * FROM orders LIMIT 10"
+//
+// Without a statement, sfsql reads the statements terminated by ';' at the end of a line from the standard
+// input, interactively on a terminal. Ctrl+C cancels the running statement.
+//
+// -async submits the statement and prints its query ID without waiting for the result, and -poll waits for
+// the query with the ID and prints its result. PUT and GET, without options, transfer the files with PutFile and
+// GetFile of the Client:
+//
+//	sfsql "PUT 'file:///data/orders 2020.csv' @orders_stage"
+//	sfsql "GET '@orders_stage/orders 2020.csv.gz' file:///data/downloaded"
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"text/tabwriter"
+
+	sf "github.com/snowflakedb/gosnowflake"
+)
+
+var (
+	dsnFlag    = flag.String("dsn", "", "DSN of the connection. $SNOWFLAKE_DSN by default")
+	formatFlag = flag.String("format", "table", "output format: table, csv or json")
+	asyncFlag  = flag.Bool("async", false, "submit the statement and print its query ID")
+	pollFlag   = flag.String("poll", "", "wait for the query with the ID and print its result")
+)
+
+// withInterrupt returns a context canceled by Ctrl+C, until stop is called.
+func withInterrupt(parent context.Context) (ctx context.Context, stop func()) {
+	ctx, cancel := context.WithCancel(parent)
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	go func() {
+		select {
+		case <-c:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(c)
+		cancel()
+	}
+}
+
+// printResult prints the result set in the format of the -format flag.
+func printResult(w io.Writer, rs sf.ResultSet) error {
+	defer rs.Close()
+	switch *formatFlag {
+	case "csv":
+		_, err := rs.WriteCSV(w, &sf.ExportOptions{Header: true})
+		return err
+	case "json":
+		_, err := rs.WriteJSON(w, nil)
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(rs.Columns(), "\t"))
+	n := 0
+	for {
+		rows, err := rs.NextBatch()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			values := make([]string, len(row))
+			for i, v := range row {
+				if v == nil {
+					values[i] = "NULL"
+				} else {
+					values[i] = fmt.Sprint(v)
+				}
+			}
+			fmt.Fprintln(tw, strings.Join(values, "\t"))
+		}
+		n += len(rows)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%v rows. query ID: %v\n", n, rs.QueryID())
+	return nil
+}
+
+// fileTransfer is a PUT or GET statement, run by the Client with the local file or directory.
+type fileTransfer struct {
+	command string // PUT or GET
+	local   string
+	stage   string
+}
+
+// fileClient transfers the files of the PUT and GET statements, as the Client does.
+type fileClient interface {
+	PutFile(ctx context.Context, localPath string, stageLocation string) error
+	GetFile(ctx context.Context, stageLocation string, localDir string) error
+}
+
+// run uploads the local file to the stage for PUT, or downloads the files of the stage to the local directory for
+// GET.
+func (ft *fileTransfer) run(ctx context.Context, client fileClient) error {
+	if ft.command == "PUT" {
+		return client.PutFile(ctx, ft.local, ft.stage)
+	}
+	return client.GetFile(ctx, ft.stage, ft.local)
+}
+
+// splitStatement splits the statement into its words, keeping the words in single quotes, e.g., the paths with
+// spaces, as one without the quotes.
+func splitStatement(stmt string) []string {
+	var words []string
+	var word strings.Builder
+	inWord, quoted := false, false
+	for _, r := range stmt {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+			inWord = true
+		case !quoted && (r == ' ' || r == '\t' || r == '\n' || r == '\r'):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words
+}
+
+// parseFileTransfer returns the file transfer of the statement, PUT file://<local> <stage> or
+// GET <stage> file://<local>, and false if it is neither PUT nor GET. A PUT or GET with options fails, as
+// PutFile and GetFile take none.
+func parseFileTransfer(stmt string) (*fileTransfer, bool, error) {
+	words := splitStatement(stmt)
+	if len(words) == 0 || !strings.EqualFold(words[0], "PUT") && !strings.EqualFold(words[0], "GET") {
+		return nil, false, nil
+	}
+	ft := &fileTransfer{command: strings.ToUpper(words[0])}
+	if len(words) != 3 {
+		return nil, true, fmt.Errorf("%v takes a file and a stage without options: %v", ft.command, stmt)
+	}
+	local := words[1]
+	ft.stage = words[2]
+	if ft.command == "GET" {
+		local, ft.stage = ft.stage, local
+	}
+	if !strings.HasPrefix(strings.ToLower(local), "file://") {
+		return nil, true, fmt.Errorf("the local path of %v should start with file://: %v", ft.command, local)
+	}
+	ft.local = local[len("file://"):]
+	return ft, true, nil
+}
+
+// run runs the statement and prints its result.
+func run(ctx context.Context, client *sf.Client, stmt string) error {
+	ctx, stop := withInterrupt(ctx)
+	defer stop()
+	ft, ok, err := parseFileTransfer(stmt)
+	switch {
+	case err != nil:
+		return err
+	case ok:
+		return ft.run(ctx, client)
+	case *asyncFlag:
+		qid, err := client.SubmitAsync(ctx, stmt)
+		if err != nil {
+			return err
+		}
+		fmt.Println(qid)
+		return nil
+	}
+	rs, err := client.Query(ctx, stmt)
+	if err != nil {
+		return err
+	}
+	return printResult(os.Stdout, rs)
+}
+
+// poll waits for the query to complete and prints its result.
+func poll(ctx context.Context, client *sf.Client, qid string) error {
+	ctx, stop := withInterrupt(ctx)
+	defer stop()
+	status, err := client.QueryStatus(ctx, qid)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "query %v: %v\n", qid, status.Status)
+	rs, err := client.FetchResult(ctx, qid)
+	if err != nil {
+		return err
+	}
+	return printResult(os.Stdout, rs)
+}
+
+// interact runs the statements read from r, which are terminated by ';' at the end of a line. The errors are
+// printed and the next statements are run.
+func interact(ctx context.Context, client *sf.Client, r io.Reader, prompt bool) {
+	scanner := bufio.NewScanner(r)
+	var stmt strings.Builder
+	for {
+		if prompt {
+			if stmt.Len() == 0 {
+				fmt.Print("sfsql> ")
+			} else {
+				fmt.Print("    -> ")
+			}
+		}
+		if !scanner.Scan() {
+			break
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if stmt.Len() == 0 && (line == "" || strings.HasPrefix(line, "--")) {
+			continue
+		}
+		stmt.WriteString(line)
+		if !strings.HasSuffix(line, ";") {
+			stmt.WriteString("\n")
+			continue
+		}
+		if err := run(ctx, client, strings.TrimSuffix(stmt.String(), ";")); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		}
+		stmt.Reset()
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatalf("failed to read the statements. err: %v", err)
+	}
+}
+
+func main() {
+	if !flag.Parsed() {
+		// enable glog for Go Snowflake Driver
+		flag.Parse()
+	}
+
+	dsn := *dsnFlag
+	if dsn == "" {
+		dsn = os.Getenv("SNOWFLAKE_DSN")
+	}
+	if dsn == "" {
+		log.Fatal("the DSN is given by -dsn or the SNOWFLAKE_DSN environment variable.")
+	}
+	cfg, err := sf.ParseDSN(dsn)
+	if err != nil {
+		log.Fatalf("failed to parse the DSN. err: %v", err)
+	}
+	ctx := context.Background()
+	client, err := sf.NewClient(ctx, *cfg)
+	if err != nil {
+		log.Fatalf("failed to connect. err: %v", err)
+	}
+	defer client.Close()
+
+	switch {
+	case *pollFlag != "":
+		err = poll(ctx, client, *pollFlag)
+	case flag.NArg() > 0:
+		err = run(ctx, client, strings.Join(flag.Args(), " "))
+	default:
+		fi, _ := os.Stdin.Stat()
+		interact(ctx, client, os.Stdin, fi != nil && fi.Mode()&os.ModeCharDevice != 0)
+	}
+	if err != nil {
+		client.Close()
+		log.Fatalf("ERROR: %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestParseFileTransfer(t *testing.T) {
+	testcases := []struct {
+		stmt     string
+		expected *fileTransfer
+		ok       bool
+		fails    bool
+	}{
+		{"PUT file:///data/a.csv @s", &fileTransfer{"PUT", "/data/a.csv", "@s"}, true, false},
+		{"put 'file:///data/a b.csv'\n  '@s/2020'", &fileTransfer{"PUT", "/data/a b.csv", "@s/2020"}, true, false},
+		{"GET @s/a.csv.gz file:///data/downloaded", &fileTransfer{"GET", "/data/downloaded", "@s/a.csv.gz"}, true,
+			false},
+		{"get '@s/a b.csv.gz' 'FILE://C:/data'", &fileTransfer{"GET", "C:/data", "@s/a b.csv.gz"}, true, false},
+		{"PUT file:///data/a.csv @s AUTO_COMPRESS = FALSE", nil, true, true},
+		{"GET @s", nil, true, true},
+		{"PUT /data/a.csv @s", nil, true, true},
+		{"SELECT 'PUT file:///data/a.csv @s'", nil, false, false},
+		{"PUTS file:///data/a.csv @s", nil, false, false},
+		{"", nil, false, false},
+	}
+	for _, tc := range testcases {
+		ft, ok, err := parseFileTransfer(tc.stmt)
+		if ok != tc.ok || (err != nil) != tc.fails {
+			t.Errorf("%q: unexpected file transfer. ok: %v, err: %v", tc.stmt, ok, err)
+			continue
+		}
+		if tc.expected != nil && (ft == nil || *ft != *tc.expected) {
+			t.Errorf("%q: expected: %+v, got: %+v", tc.stmt, tc.expected, ft)
+		}
+	}
+}
+
+// recordingFileClient records the file transfers instead of running them.
+type recordingFileClient struct {
+	calls []string
+}
+
+func (c *recordingFileClient) PutFile(_ context.Context, localPath string, stageLocation string) error {
+	c.calls = append(c.calls, "PutFile "+localPath+" "+stageLocation)
+	return nil
+}
+
+func (c *recordingFileClient) GetFile(_ context.Context, stageLocation string, localDir string) error {
+	c.calls = append(c.calls, "GetFile "+stageLocation+" "+localDir)
+	return nil
+}
+
+func TestFileTransferRun(t *testing.T) {
+	client := &recordingFileClient{}
+	for _, stmt := range []string{
+		"PUT 'file:///data/orders 2020.csv' @orders_stage",
+		"GET '@orders_stage/orders 2020.csv.gz' file:///data/downloaded",
+	} {
+		ft, ok, err := parseFileTransfer(stmt)
+		if !ok || err != nil {
+			t.Fatalf("%q: should be a file transfer. err: %v", stmt, err)
+		}
+		if err = ft.run(context.Background(), client); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := []string{
+		"PutFile /data/orders 2020.csv @orders_stage",
+		"GetFile @orders_stage/orders 2020.csv.gz /data/downloaded",
+	}
+	if !reflect.DeepEqual(client.calls, expected) {
+		t.Fatalf("unexpected file transfers. expected: %q, got: %q", expected, client.calls)
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The compressions of the files uploaded by PUT, as in its SOURCE_COMPRESSION option and in its result.
+const (
+	CompressionAutoDetect = "AUTO_DETECT"
+	CompressionGzip       = "GZIP"
+	CompressionBz2        = "BZ2"
+	CompressionBrotli     = "BROTLI"
+	CompressionZstd       = "ZSTD"
+	CompressionDeflate    = "DEFLATE"
+	CompressionRawDeflate = "RAW_DEFLATE"
+	CompressionNone       = "NONE"
+	// CompressionParquet and CompressionORC are detected for the Parquet and ORC files, which are compressed
+	// internally and are uploaded as they are.
+	CompressionParquet = "PARQUET"
+	CompressionORC     = "ORC"
+)
+
+// compressionMagics are the first bytes of the files of the compressions that have them.
+var compressionMagics = []struct {
+	magic       []byte
+	compression string
+}{
+	{[]byte{0x1f, 0x8b}, CompressionGzip},
+	{[]byte("BZh"), CompressionBz2},
+	{[]byte{0x28, 0xb5, 0x2f, 0xfd}, CompressionZstd},
+	{[]byte("PAR1"), CompressionParquet},
+	{[]byte("ORC"), CompressionORC},
+}
+
+// compressionExtensions are the file extensions of the compressions, which are detected by the extension if the
+// file doesn't start with a magic, e.g., Brotli.
+var compressionExtensions = map[string]string{
+	".gz":          CompressionGzip,
+	".bz2":         CompressionBz2,
+	".br":          CompressionBrotli,
+	".zst":         CompressionZstd,
+	".deflate":     CompressionDeflate,
+	".raw_deflate": CompressionRawDeflate,
+	".parquet":     CompressionParquet,
+	".orc":         CompressionORC,
+}
+
+// parseSourceCompression returns the SOURCE_COMPRESSION option of PUT in upper case, AUTO_DETECT if it is empty.
+func parseSourceCompression(option string) (string, error) {
+	switch option = strings.ToUpper(option); option {
+	case "":
+		return CompressionAutoDetect, nil
+	case CompressionAutoDetect, CompressionGzip, CompressionBz2, CompressionBrotli, CompressionZstd,
+		CompressionDeflate, CompressionRawDeflate, CompressionNone:
+		return option, nil
+	}
+	return "", fmt.Errorf("invalid source compression: %v", option)
+}
+
+// sourceCompression returns the compression of the file, detected from its first bytes and its extension if
+// the SOURCE_COMPRESSION option is AUTO_DETECT.
+func sourceCompression(path string, option string) (string, error) {
+	if option != CompressionAutoDetect {
+		return option, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	head := make([]byte, 4)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	for _, m := range compressionMagics {
+		if bytes.HasPrefix(head[:n], m.magic) {
+			return m.compression, nil
+		}
+	}
+	if compression, ok := compressionExtensions[strings.ToLower(filepath.Ext(path))]; ok {
+		return compression, nil
+	}
+	return CompressionNone, nil
+}
+
+// openUploadContent returns the content of the file to upload, gzipped while it is read if the file is
+// compressed by PUT. The compressed content is the same every time, so that its digest is that of the upload.
+func openUploadContent(file *PutFileResult) (io.ReadCloser, error) {
+	f, err := os.Open(file.Source)
+	if err != nil {
+		return nil, err
+	}
+	if file.SourceCompression == file.TargetCompression {
+		return f, nil
+	}
+	r, w := io.Pipe()
+	go func() {
+		defer f.Close()
+		// the header has no name nor modification time
+		zw := gzip.NewWriter(w)
+		_, err := io.Copy(zw, f)
+		if cerr := zw.Close(); err == nil {
+			err = cerr
+		}
+		w.CloseWithError(err)
+	}()
+	return r, nil
+}
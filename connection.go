@@ -10,9 +10,9 @@ import (
 	"fmt"
 	"github.com/google/uuid"
 	"net/url"
-	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 )
@@ -26,12 +26,18 @@ const (
 	statementTypeIDDelete           = statementTypeIDDml + int64(0x300)
 	statementTypeIDMerge            = statementTypeIDDml + int64(0x400)
 	statementTypeIDMultiTableInsert = statementTypeIDDml + int64(0x500)
+	statementTypeIDUnload           = statementTypeIDDml + int64(0x700)
+
+	// statementTypeIDDDL is the first of the range of DDL statements, including CREATE TABLE AS SELECT
+	statementTypeIDDDL = int64(0x6000)
 )
 
 const (
-	sessionClientSessionKeepAlive          = "client_session_keep_alive"
-	sessionClientValidateDefaultParameters = "CLIENT_VALIDATE_DEFAULT_PARAMETERS"
-	serviceName                            = "service_name"
+	sessionClientSessionKeepAlive                   = "client_session_keep_alive"
+	sessionClientSessionKeepAliveHeartbeatFrequency = "client_session_keep_alive_heartbeat_frequency"
+	sessionClientTelemetryEnabled                   = "client_telemetry_enabled"
+	sessionClientValidateDefaultParameters          = "CLIENT_VALIDATE_DEFAULT_PARAMETERS"
+	serviceName                                     = "service_name"
 )
 
 type snowflakeConn struct {
@@ -40,6 +46,17 @@ type snowflakeConn struct {
 	SequenceCounter uint64
 	QueryID         string
 	SQLState        string
+	info            ConnectionInfo
+	keepSession     bool         // the session is not deleted when the connection is closed
+	heartBeatMu     sync.Mutex   // serializes starting and stopping the heartbeat
+	service         atomic.Value // string. the service name sent in the X-Snowflake-Service header
+
+	queryContextCache queryContextCache
+	describeCache     *describeCache // statement descriptions. created on the first PrepareContext
+	inFlight          inFlightQueries
+	sqlAPI            *sqlAPIClient    // submits the statements if Config.UseSQLAPI is set
+	openConns         *openConns       // the open connections of the Connector that opened the connection, if any
+	password          *changedPassword // the password of the Connector that opened the connection, if any
 }
 
 // isDml returns true if the statement type code is in the range of DML.
@@ -47,19 +64,59 @@ func (sc *snowflakeConn) isDml(v int64) bool {
 	switch v {
 	case statementTypeIDDml, statementTypeIDInsert,
 		statementTypeIDUpdate, statementTypeIDDelete,
-		statementTypeIDMerge, statementTypeIDMultiTableInsert:
+		statementTypeIDMerge, statementTypeIDMultiTableInsert,
+		statementTypeIDUnload:
 		return true
 	}
 	return false
 }
 
+// isDDL returns true if the statement type code is in the range of DDL.
+func (sc *snowflakeConn) isDDL(v int64) bool {
+	return v&^0xfff == statementTypeIDDDL
+}
+
 // isMultiStmt returns true if the statement type code is of type multistatement
 // Note that the statement type code is also equivalent to type INSERT, so an additional check of the name is required
 func (sc *snowflakeConn) isMultiStmt(data execResponseData) bool {
-	return data.StatementTypeID == statementTypeIDMulti && data.RowType[0].Name == "multiple statement execution"
+	return data.StatementTypeID == statementTypeIDMulti && len(data.RowType) > 0 &&
+		data.RowType[0].Name == "multiple statement execution"
 }
 
+// exec runs the query. If the query fails because of a retryable error, it is re-submitted up to
+// MaxQueryRetries times. If the query fails because the warehouse is suspended and AutoResumeWarehouse is set,
+// the warehouse is resumed and the query is retried once.
 func (sc *snowflakeConn) exec(
+	ctx context.Context,
+	query string,
+	noResult bool,
+	isInternal bool,
+	bindings []driver.NamedValue) (
+	*execResponse, error) {
+	data, err := sc.execOnce(ctx, query, noResult, isInternal, bindings)
+	for attempt := 0; err != nil && attempt < sc.cfg.MaxQueryRetries &&
+		sc.canRetryQuery(ctx, query, isInternal, err); attempt++ {
+		glog.V(1).Infof("query failed with a retryable error. re-submitting. attempt: %v, %v, err: %v",
+			attempt+1, queryLogFrom(ctx), err)
+		if !waitQueryRetry(ctx, attempt) {
+			break
+		}
+		// execOnce generates a new request ID, so that the query is not taken for the failed one, unless the
+		// context has an idempotency key
+		data, err = sc.execOnce(ctx, query, noResult, isInternal, bindings)
+	}
+	if err == nil || isInternal || !sc.cfg.AutoResumeWarehouse || !IsWarehouseSuspended(err) {
+		return data, err
+	}
+	glog.V(1).Infof("warehouse %v is suspended. resuming it. err: %v", sc.cfg.Warehouse, err)
+	if rerr := sc.WarmUpWarehouse(ctx); rerr != nil {
+		glog.V(1).Infof("failed to resume the warehouse. err: %v", rerr)
+		return data, err
+	}
+	return sc.execOnce(ctx, query, noResult, isInternal, bindings)
+}
+
+func (sc *snowflakeConn) execOnce(
 	ctx context.Context,
 	query string,
 	noResult bool,
@@ -75,8 +132,20 @@ func (sc *snowflakeConn) exec(
 		SequenceID: counter,
 	}
 	req.IsInternal = isInternal
+	req.DescribeOnly, _ = ctx.Value(describeOnly).(bool)
+	if isInternal || req.DescribeOnly {
+		ctx = withoutIdempotencyKey(ctx)
+	}
+	multiCount := ctx.Value(MultiStatementCount)
+	if multiCount != nil && multiCount != 1 && len(bindings) > 0 {
+		// Snowflake doesn't define which statement of the batch a binding applies to
+		return nil, errMultiStatementBindings(len(bindings))
+	}
 	tsmode := "TIMESTAMP_NTZ"
 	idx := 1
+	bindSize := 0
+	stageBinding := false
+	rejectNonFinite := rejectsNonFiniteFloats(ctx)
 	if len(bindings) > 0 {
 		req.Bindings = make(map[string]execBindParameter, len(bindings))
 		for i, n := 0, len(bindings); i < n; i++ {
@@ -88,15 +157,26 @@ func (sc *snowflakeConn) exec(
 					return nil, err
 				}
 			} else {
+				if rejectNonFinite {
+					if err = checkFiniteBind(bindings[i].Value, idx); err != nil {
+						return nil, err
+					}
+				}
 				var v1 interface{}
 				if t == "ARRAY" {
-					t, v1 = arrayToString(bindings[i].Value)
+					t, v1, err = arrayToString(bindings[i].Value, tsmode)
 				} else {
 					v1, err = valueToString(bindings[i].Value, tsmode)
 				}
 				if err != nil {
 					return nil, err
 				}
+				size := bindValueSize(v1)
+				if limit := sc.cfg.MaxInlineBindSize; limit > 0 && size > limit {
+					// larger values are uploaded to a stage and bound from there
+					stageBinding = true
+				}
+				bindSize += size
 				req.Bindings[strconv.Itoa(idx)] = execBindParameter{
 					Type:  t,
 					Value: v1,
@@ -105,33 +185,79 @@ func (sc *snowflakeConn) exec(
 			}
 		}
 	}
-	multiCount := ctx.Value(MultiStatementCount)
+	if stageBinding {
+		if req.BindStage, err = sc.stageBindings(ctx, req.Bindings); err != nil {
+			return nil, err
+		}
+		req.Bindings, bindSize = nil, 0
+	}
 	if multiCount != nil {
 		req.Parameters = map[string]interface{}{string(MultiStatementCount): multiCount}
 	}
+	tag, err := queryTag(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if tag != "" {
+		if req.Parameters == nil {
+			req.Parameters = make(map[string]interface{})
+		}
+		req.Parameters["QUERY_TAG"] = tag
+	}
+	if isRawResults(ctx) {
+		if req.Parameters == nil {
+			req.Parameters = make(map[string]interface{})
+		}
+		// the strings of the JSON format need no conversion
+		req.Parameters["GO_QUERY_RESULT_FORMAT"] = jsonFormat
+	}
+	req.QueryContext = sc.queryContextCache.get()
 	glog.V(2).Infof("bindings: %v", req.Bindings)
 	glog.V(2).Infof("parameters: %v", req.Parameters)
 
 	headers := make(map[string]string)
 	headers["Content-Type"] = headerContentTypeApplicationJSON
 	headers["accept"] = headerAcceptTypeApplicationSnowflake // TODO v1.1: change to JSON in case of PUT/GET
-	headers["User-Agent"] = userAgent
-	if serviceName, ok := sc.cfg.Params[serviceName]; ok {
-		headers["X-Snowflake-Service"] = *serviceName
+	headers["User-Agent"] = sc.rest.getUserAgent()
+	if name := sc.CurrentServiceName(); name != "" {
+		headers["X-Snowflake-Service"] = name
+	}
+	if extra, ok := ctx.Value(httpHeaders).(map[string]string); ok {
+		for name, value := range extra {
+			headers[name] = value
+		}
 	}
 
-	jsonBody, err := json.Marshal(req)
-	if err != nil {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	// a multi-MB binding is encoded without growing the buffer step by step
+	buf.Grow(len(query) + bindSize + 1024)
+	if err := json.NewEncoder(buf).Encode(req); err != nil {
 		return nil, err
 	}
+	jsonBody := buf.Bytes()
+	if limit := sc.cfg.MaxRequestBodySize; limit > 0 && len(jsonBody) > limit {
+		return nil, errRequestTooLarge(len(jsonBody), fmt.Sprintf("%v bytes", limit))
+	}
 
 	var data *execResponse
 
-	requestID := uuid.New()
+	if err = sc.rest.waitRateLimit(ctx); err != nil {
+		return nil, err
+	}
+	requestID := newRequestID(ctx)
+	ql := queryLogFrom(ctx)
+	ql.setRequestID(requestID)
+	sc.inFlight.add(requestID)
 	data, err = sc.rest.FuncPostQuery(ctx, sc.rest, &url.Values{}, headers, jsonBody, sc.rest.RequestTimeout, &requestID)
+	sc.inFlight.remove(requestID)
 	if err != nil {
+		glog.V(1).Infof("failed to post the query. %v, err: %v", ql, err)
 		return data, err
 	}
+	ql.setQueryID(data.Data.QueryID)
+	sendQueryID(ctx, data.Data.QueryID)
+	sc.queryContextCache.merge(data.Data.QueryContext, sc.queryContextCacheSize())
 	var code int
 	if data.Code != "" {
 		code, err = strconv.Atoi(data.Code)
@@ -142,7 +268,7 @@ func (sc *snowflakeConn) exec(
 	} else {
 		code = -1
 	}
-	glog.V(2).Infof("Success: %v, Code: %v", data.Success, code)
+	glog.V(2).Infof("Success: %v, Code: %v. %v", data.Success, code, ql)
 	if !data.Success {
 		return nil, &SnowflakeError{
 			Number:   code,
@@ -151,7 +277,10 @@ func (sc *snowflakeConn) exec(
 			QueryID:  data.Data.QueryID,
 		}
 	}
-	glog.V(2).Info("Exec/Query SUCCESS")
+	if err = sc.checkResultShape(data.Data); err != nil {
+		return nil, err
+	}
+	glog.V(2).Infof("Exec/Query SUCCESS. %v", ql)
 	sc.cfg.Database = data.Data.FinalDatabaseName
 	sc.cfg.Schema = data.Data.FinalSchemaName
 	sc.cfg.Role = data.Data.FinalRoleName
@@ -185,7 +314,10 @@ func (sc *snowflakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (dr
 	if sc.rest == nil {
 		return nil, driver.ErrBadConn
 	}
-	_, err := sc.exec(ctx, "BEGIN", false, false, nil)
+	if sc.cfg.FetchOnly {
+		return nil, errFetchOnlyConnection()
+	}
+	_, err := sc.exec(withoutIdempotencyKey(ctx), "BEGIN", false, false, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -200,11 +332,20 @@ func (sc *snowflakeConn) cleanup() {
 
 func (sc *snowflakeConn) Close() (err error) {
 	glog.V(2).Infoln("Close")
+	if sc.openConns != nil {
+		sc.openConns.remove(sc)
+	}
+	if sc.cfg.AbortOnClose {
+		sc.abortOnClose()
+	}
+	sc.runCloseHook()
 	sc.stopHeartBeat()
 
-	err = sc.rest.FuncCloseSession(context.TODO(), sc.rest, sc.rest.RequestTimeout)
-	if err != nil {
-		glog.V(2).Info(err)
+	if !sc.keepSession {
+		err = sc.rest.FuncCloseSession(context.TODO(), sc.rest, sc.rest.RequestTimeout)
+		if err != nil {
+			glog.V(2).Info(err)
+		}
 	}
 	sc.cleanup()
 	return nil
@@ -219,6 +360,13 @@ func (sc *snowflakeConn) PrepareContext(ctx context.Context, query string) (driv
 		sc:    sc,
 		query: query,
 	}
+	if sc.cfg.DescribeCacheSize > 0 && !sc.cfg.FetchOnly {
+		desc, err := sc.describe(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		stmt.desc = desc
+	}
 	return stmt, nil
 }
 
@@ -227,14 +375,32 @@ func (sc *snowflakeConn) Prepare(query string) (driver.Stmt, error) {
 }
 
 func (sc *snowflakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	defer sc.inFlight.begin()()
+	ctx, ql := withQueryLog(ctx)
+	if sc.cfg.QueryJournal == nil {
+		res, err := sc.execContext(ctx, query, args)
+		return res, ql.annotate(err)
+	}
+	start := time.Now()
+	res, err := sc.execContext(ctx, query, args)
+	err = ql.annotate(err)
+	sc.journalExec(ctx, start, query, res, err)
+	return res, err
+}
+
+func (sc *snowflakeConn) execContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
 	glog.V(2).Infof("Exec: %#v, %v", query, args)
 	if sc.rest == nil {
 		return nil, driver.ErrBadConn
 	}
-	// TODO: handle noResult and isInternal
-	data, err := sc.exec(ctx, query, false, false, args)
+	if sc.cfg.FetchOnly {
+		return nil, errFetchOnlyConnection()
+	}
+	// TODO: handle isInternal
+	async := isAsyncMode(ctx)
+	data, err := sc.exec(ctx, query, async, false, args)
 	if err != nil {
-		glog.V(2).Infof("error: %v", err)
+		glog.V(2).Infof("error: %v. %v", err, queryLogFrom(ctx))
 		if data != nil {
 			code, err := strconv.Atoi(data.Code)
 			if err != nil {
@@ -248,80 +414,116 @@ func (sc *snowflakeConn) ExecContext(ctx context.Context, query string, args []d
 		}
 		return nil, err
 	}
+	if async {
+		if bgCtx := sc.backgroundContext(); asyncNotifier(ctx, bgCtx) != nil {
+			go sc.watchAsyncQuery(ctx, bgCtx, data.Data.QueryID)
+		}
+		return &snowflakeResult{
+			affectedRows: -1,
+			insertID:     -1,
+			queryID:      data.Data.QueryID,
+		}, nil
+	}
 
-	var updatedRows int64
+	var counts DMLRowCounts
 	if sc.isDml(data.Data.StatementTypeID) {
-		// collects all values from the returned row sets
-		updatedRows, err = updateRows(data.Data)
+		counts, err = dmlRowCounts(data.Data)
 		if err != nil {
 			return nil, err
 		}
-		glog.V(2).Infof("number of updated rows: %#v", updatedRows)
+		glog.V(2).Infof("number of updated rows: %#v", counts)
 		return &snowflakeResult{
-			affectedRows: updatedRows,
+			affectedRows: counts.Total(),
 			insertID:     -1,
 			queryID:      sc.QueryID,
+			rowCounts:    counts,
+			extensions:   data.Data.Extensions,
 		}, nil // last insert id is not supported by Snowflake
 	} else if sc.isMultiStmt(data.Data) {
 		childResults := getChildResults(data.Data.ResultIDs, data.Data.ResultTypes)
-		for _, child := range childResults {
-			resultPath := fmt.Sprintf("/queries/%s/result", child.id)
-			childData, err := sc.getQueryResult(ctx, resultPath)
+		childResps, childErrs := sc.fetchChildResults(ctx, childResults)
+		var childErrors []*ChildStatementError
+		children := make([]childStatementResult, len(childResults))
+		for i, child := range childResults {
+			children[i].queryID = child.id
+			childData, err := childResps[i], childErrs[i]
+			if err == nil && !childData.Success {
+				err = childDataError(childData, child.id)
+			}
 			if err != nil {
 				glog.V(2).Infof("error: %v", err)
-				code, err := strconv.Atoi(childData.Code)
-				if err != nil {
-					return nil, err
-				}
-				if childData != nil {
-					return nil, &SnowflakeError{
-						Number:   code,
-						SQLState: childData.Data.SQLState,
-						Message:  err.Error(),
-						QueryID:  childData.Data.QueryID}
-				}
-				return nil, err
+				childErrors = append(childErrors, &ChildStatementError{Index: i, QueryID: child.id, Err: err})
+				continue
 			}
 			if sc.isDml(childData.Data.StatementTypeID) {
-				count, err := updateRows(childData.Data)
+				childCounts, err := dmlRowCounts(childData.Data)
 				if err != nil {
 					glog.V(2).Infof("error: %v", err)
-					if childData != nil {
-						code, err := strconv.Atoi(childData.Code)
-						if err != nil {
-							return nil, err
-						}
-						return nil, &SnowflakeError{
-							Number:   code,
-							SQLState: childData.Data.SQLState,
-							Message:  err.Error(),
-							QueryID:  childData.Data.QueryID}
-					}
-					return nil, err
+					childErrors = append(childErrors, &ChildStatementError{Index: i, QueryID: child.id, Err: err})
+					continue
 				}
-				updatedRows += count
+				counts = counts.add(childCounts)
+				children[i].rowCounts = childCounts
 			}
 		}
-		glog.V(2).Infof("number of updated rows: %#v", updatedRows)
+		if len(childErrors) > 0 {
+			return nil, &MultiStatementError{QueryID: data.Data.QueryID, Errors: childErrors, children: children}
+		}
+		glog.V(2).Infof("number of updated rows: %#v", counts)
 		return &snowflakeResult{
-			affectedRows: updatedRows,
+			affectedRows: counts.Total(),
 			insertID:     -1,
 			queryID:      sc.QueryID,
+			rowCounts:    counts,
+			extensions:   data.Data.Extensions,
+			children:     children,
 		}, nil
 	}
-	glog.V(2).Info("DDL")
+	if sc.isDDL(data.Data.StatementTypeID) {
+		glog.V(2).Info("DDL")
+		return newDDLResult(data.Data), nil
+	}
 	return driver.ResultNoRows, nil
 }
 
 func (sc *snowflakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	defer sc.inFlight.begin()()
+	ctx, ql := withQueryLog(ctx)
+	qid, _ := ctx.Value(fetchResultByID).(string)
+	if sc.cfg.QueryJournal == nil || qid != "" {
+		rows, err := sc.queryContext(ctx, query, args)
+		return rows, ql.annotate(err)
+	}
+	start := time.Now()
+	rows, err := sc.queryContext(ctx, query, args)
+	err = ql.annotate(err)
+	sc.journalQuery(ctx, start, query, rows, err)
+	return rows, err
+}
+
+func (sc *snowflakeConn) queryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
 	glog.V(2).Infof("Query: %#v, %v", query, args)
 	if sc.rest == nil {
 		return nil, driver.ErrBadConn
 	}
+	if qid, ok := ctx.Value(fetchResultByID).(string); ok && qid != "" {
+		return sc.fetchResultByQueryID(ctx, qid)
+	}
+	if sc.cfg.FetchOnly {
+		return nil, errFetchOnlyConnection()
+	}
+	var cacheKey string
+	if rc := sc.cfg.ResultCache; rc != nil && ctx.Value(MultiStatementCount) == nil {
+		cacheKey = resultCacheKey(sc, query, args)
+		if cached, ok := rc.get(cacheKey); ok {
+			glog.V(2).Infof("result cache hit. query ID: %v", cached.QueryID)
+			return sc.cachedRows(ctx, cached)
+		}
+	}
 	// TODO: handle noResult and isInternal
 	data, err := sc.exec(ctx, query, false, false, args)
 	if err != nil {
-		glog.V(2).Infof("error: %v", err)
+		glog.V(2).Infof("error: %v. %v", err, queryLogFrom(ctx))
 		if data != nil {
 			code, err := strconv.Atoi(data.Code)
 			if err != nil {
@@ -335,6 +537,9 @@ func (sc *snowflakeConn) QueryContext(ctx context.Context, query string, args []
 		}
 		return nil, err
 	}
+	if cacheKey != "" {
+		sc.cfg.ResultCache.put(cacheKey, &data.Data)
+	}
 
 	rows := new(snowflakeRows)
 	rows.sc = sc
@@ -342,6 +547,7 @@ func (sc *snowflakeConn) QueryContext(ctx context.Context, query string, args []
 	rows.ChunkDownloader = &snowflakeChunkDownloader{
 		sc:                 sc,
 		ctx:                ctx,
+		queryID:            data.Data.QueryID,
 		CurrentChunk:       make([]chunkRowType, len(data.Data.RowSet)),
 		ChunkMetas:         data.Data.Chunks,
 		Total:              data.Data.Total,
@@ -352,22 +558,23 @@ func (sc *snowflakeConn) QueryContext(ctx context.Context, query string, args []
 		ChunkHeader:        data.Data.ChunkHeaders,
 		FuncDownload:       downloadChunk,
 		FuncDownloadHelper: downloadChunkHelper,
-		FuncGet:            getChunk,
+		FuncGet:            sc.getChunkFunc(),
 		RowSet: rowSetType{RowType: data.Data.RowType,
 			JSON:         data.Data.RowSet,
 			RowSetBase64: data.Data.RowSetBase64,
 		},
 	}
 	rows.queryID = sc.QueryID
+	rows.extensions = data.Data.Extensions
 
 	if sc.isMultiStmt(data.Data) {
 		childResults := getChildResults(data.Data.ResultIDs, data.Data.ResultTypes)
 		var nextChunkDownloader *snowflakeChunkDownloader
 		firstResultSet := false
 
-		for _, child := range childResults {
-			resultPath := fmt.Sprintf("/queries/%s/result", child.id)
-			childData, err := sc.getQueryResult(ctx, resultPath)
+		childResps, childErrs := sc.fetchChildResults(ctx, childResults)
+		for i := range childResults {
+			childData, err := childResps[i], childErrs[i]
 			if err != nil {
 				glog.V(2).Infof("error: %v", err)
 				if childData != nil {
@@ -395,8 +602,10 @@ func (sc *snowflakeConn) QueryContext(ctx context.Context, query string, args []
 		}
 	}
 
-	rows.ChunkDownloader.start()
-	return rows, err
+	if err = rows.ChunkDownloader.start(); err != nil {
+		return nil, err
+	}
+	return rows, nil
 }
 
 func (sc *snowflakeConn) Exec(
@@ -413,89 +622,236 @@ func (sc *snowflakeConn) Query(
 	return sc.QueryContext(context.TODO(), query, toNamedValues(args))
 }
 
-func (sc *snowflakeConn) Ping(ctx context.Context) error {
-	glog.V(2).Infoln("Ping")
-	if sc.rest == nil {
-		return driver.ErrBadConn
+func (sc *snowflakeConn) CheckNamedValue(nv *driver.NamedValue) error {
+	// a Valuer is resolved once, and its value is checked as any other value
+	valuer := false
+	if vr, ok := nv.Value.(driver.Valuer); ok {
+		v, err := valuerValue(vr)
+		if err != nil {
+			return err
+		}
+		nv.Value = v
+		valuer = true
 	}
-	// TODO: handle noResult and isInternal
-	_, err := sc.exec(ctx, "SELECT 1", false, false, []driver.NamedValue{})
-	return err
+	if _, ok := nv.Value.(json.RawMessage); ok {
+		return nil
+	}
+	if isArrayBind(nv.Value) || isObjectBind(nv.Value) {
+		return nil
+	}
+	if s, ok := uuidBindValue(nv.Value); ok {
+		nv.Value = s
+		return nil
+	}
+	if valuer {
+		// the other values of a Valuer are converted as database/sql does
+		var err error
+		nv.Value, err = driver.DefaultParameterConverter.ConvertValue(nv.Value)
+		return err
+	}
+	return driver.ErrSkip
 }
 
-func (sc *snowflakeConn) CheckNamedValue(nv *driver.NamedValue) error {
-	switch reflect.TypeOf(nv.Value) {
-	case reflect.TypeOf([]int{0}), reflect.TypeOf([]int64{0}), reflect.TypeOf([]float64{0}),
-		reflect.TypeOf([]bool{false}), reflect.TypeOf([]string{""}):
-		return nil
-	default:
-		return driver.ErrSkip
+// parameterValueString converts a parameter value in a response to a string.
+func parameterValueString(value interface{}) string {
+	switch vv := value.(type) {
+	case int64:
+		return strconv.FormatInt(vv, 10)
+	case float64:
+		return strconv.FormatFloat(vv, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(vv)
+	case string:
+		return vv
 	}
+	return ""
 }
 
 func (sc *snowflakeConn) populateSessionParameters(parameters []nameValueParameter) {
 	// other session parameters (not all)
 	glog.V(2).Infof("params: %#v", parameters)
+	listener := sc.cfg.SessionParameterListener
+	keepAliveChanged := false
 	for _, param := range parameters {
-		v := ""
-		switch param.Value.(type) {
-		case int64:
-			if vv, ok := param.Value.(int64); ok {
-				v = strconv.FormatInt(vv, 10)
-			}
-		case float64:
-			if vv, ok := param.Value.(float64); ok {
-				v = strconv.FormatFloat(vv, 'g', -1, 64)
-			}
-		case bool:
-			if vv, ok := param.Value.(bool); ok {
-				v = strconv.FormatBool(vv)
-			}
-		default:
-			if vv, ok := param.Value.(string); ok {
-				v = vv
-			}
-		}
+		v := parameterValueString(param.Value)
 		glog.V(3).Infof("parameter. name: %v, value: %v", param.Name, v)
-		sc.cfg.Params[strings.ToLower(param.Name)] = &v
+		name := strings.ToLower(param.Name)
+		if name == serviceName {
+			sc.service.Store(v)
+		}
+		old, ok := sc.cfg.Params[name]
+		sc.cfg.Params[name] = &v
+		if ok && old != nil && *old == v {
+			continue
+		}
+		if name == sessionClientSessionKeepAlive || name == sessionClientSessionKeepAliveHeartbeatFrequency {
+			keepAliveChanged = true
+		}
+		if listener == nil {
+			continue
+		}
+		change := SessionParameterChange{
+			SessionID: sc.rest.SessionID,
+			Name:      strings.ToUpper(param.Name),
+			NewValue:  v,
+			Added:     !ok || old == nil,
+		}
+		if old != nil {
+			change.OldValue = *old
+		}
+		listener(change)
+	}
+	if keepAliveChanged {
+		// e.g., ALTER SESSION SET CLIENT_SESSION_KEEP_ALIVE = TRUE in the middle of the session
+		sc.startHeartBeat()
 	}
 }
 
+// isClientSessionKeepAliveEnabled returns true if CLIENT_SESSION_KEEP_ALIVE is set, either by the Config or by
+// Snowflake, e.g., by the user or account level parameter returned at login.
 func (sc *snowflakeConn) isClientSessionKeepAliveEnabled() bool {
 	v, ok := sc.cfg.Params[sessionClientSessionKeepAlive]
-	if !ok {
+	if !ok || v == nil {
 		return false
 	}
-	return strings.Compare(*v, "true") == 0
+	enabled, err := strconv.ParseBool(*v)
+	return err == nil && enabled
+}
+
+// heartBeatFrequency returns the interval of the heartbeats from CLIENT_SESSION_KEEP_ALIVE_HEARTBEAT_FREQUENCY,
+// set either by the Config or by Snowflake, within the range Snowflake allows.
+func (sc *snowflakeConn) heartBeatFrequency() time.Duration {
+	v, ok := sc.cfg.Params[sessionClientSessionKeepAliveHeartbeatFrequency]
+	if !ok || v == nil {
+		return heartBeatInterval
+	}
+	sec, err := strconv.Atoi(*v)
+	if err != nil {
+		glog.V(1).Infof("invalid heartbeat frequency: %v", *v)
+		return heartBeatInterval
+	}
+	return durationMax(minHeartBeatInterval, durationMin(heartBeatInterval, time.Duration(sec)*time.Second))
 }
 
+// startHeartBeat starts, restarts or stops the heartbeat to follow the current values of
+// CLIENT_SESSION_KEEP_ALIVE and CLIENT_SESSION_KEEP_ALIVE_HEARTBEAT_FREQUENCY. It is a no-op if the heartbeat
+// already follows them. Config.DisableHeartbeat keeps it stopped.
 func (sc *snowflakeConn) startHeartBeat() {
-	if !sc.isClientSessionKeepAliveEnabled() {
+	sc.heartBeatMu.Lock()
+	defer sc.heartBeatMu.Unlock()
+	if sc.rest == nil {
+		return
+	}
+	hb := sc.rest.HeartBeat
+	if !sc.isClientSessionKeepAliveEnabled() || sc.cfg.DisableHeartbeat {
+		if hb != nil {
+			hb.stop()
+			sc.rest.HeartBeat = nil
+		}
 		return
 	}
+	interval := sc.heartBeatFrequency()
+	if hb != nil {
+		if hb.interval == interval {
+			return
+		}
+		hb.stop()
+	}
 	sc.rest.HeartBeat = &heartbeat{
-		restful: sc.rest,
+		restful:  sc.rest,
+		interval: interval,
+		ctx:      sc.backgroundContext(),
 	}
 	sc.rest.HeartBeat.start()
 }
 
+// backgroundContext returns the context of the operations the connection runs in the background.
+func (sc *snowflakeConn) backgroundContext() context.Context {
+	if sc.cfg == nil || sc.cfg.BackgroundContext == nil {
+		return context.Background()
+	}
+	return sc.cfg.BackgroundContext
+}
+
 func (sc *snowflakeConn) stopHeartBeat() {
-	if !sc.isClientSessionKeepAliveEnabled() {
+	sc.heartBeatMu.Lock()
+	defer sc.heartBeatMu.Unlock()
+	if sc.rest == nil || sc.rest.HeartBeat == nil {
 		return
 	}
 	sc.rest.HeartBeat.stop()
+	sc.rest.HeartBeat = nil
 }
 
-func updateRows(data execResponseData) (int64, error) {
-	var count int64
-	for i, n := 0, len(data.RowType); i < n; i++ {
+// dmlRowCounts parses the result of a DML statement. The result has a single row with a column for each
+// type of change, e.g., "number of rows inserted", or "rows_unloaded" and "output_bytes" for COPY INTO
+// location. A multi-table insert has a column per target table, all of which count inserted rows. The
+// "number of multi-joined rows updated" column of an UPDATE is not added as the rows are already counted in
+// "number of rows updated".
+func dmlRowCounts(data execResponseData) (DMLRowCounts, error) {
+	var counts DMLRowCounts
+	if len(data.RowSet) == 0 {
+		return counts, nil
+	}
+	for i, n := 0, len(data.RowType); i < n && i < len(data.RowSet[0]); i++ {
+		if data.RowSet[0][i] == nil {
+			continue
+		}
 		v, err := strconv.ParseInt(*data.RowSet[0][i], 10, 64)
 		if err != nil {
-			return -1, err
+			return DMLRowCounts{}, err
+		}
+		switch name := strings.ToLower(data.RowType[i].Name); {
+		case strings.HasPrefix(name, "number of rows inserted"):
+			counts.Inserted += v
+		case strings.HasPrefix(name, "number of rows updated"):
+			counts.Updated += v
+		case strings.HasPrefix(name, "number of rows deleted"):
+			counts.Deleted += v
+		case name == "rows_unloaded":
+			counts.Unloaded += v
+		case name == "output_bytes":
+			counts.UnloadedBytes += v
+		case strings.HasPrefix(name, "number of multi-joined rows updated"), name == "input_bytes":
+		case data.StatementTypeID == statementTypeIDMultiTableInsert:
+			counts.Inserted += v
+		default:
+			glog.V(2).Infof("unknown DML result column: %v", data.RowType[i].Name)
+		}
+	}
+	return counts, nil
+}
+
+// checkResultShape returns an error if the result in the response doesn't have the shape the driver reads it
+// in, so that an unexpected response fails the query instead of the driver panicking while reading it.
+func (sc *snowflakeConn) checkResultShape(data execResponseData) error {
+	for i, row := range data.RowSet {
+		if len(row) != len(data.RowType) {
+			return errUnexpectedResultShape(data.QueryID,
+				fmt.Sprintf("row %v has %v values for %v columns", i+1, len(row), len(data.RowType)))
 		}
-		count += v
 	}
-	return count, nil
+	if sc.isDml(data.StatementTypeID) && len(data.RowType) == 0 {
+		return errUnexpectedResultShape(data.QueryID, "no row count column in the result of a DML statement")
+	}
+	if data.StatementTypeID == statementTypeIDMulti && data.ResultIDs != "" {
+		ids, types := strings.Split(data.ResultIDs, ","), strings.Split(data.ResultTypes, ",")
+		if len(ids) != len(types) {
+			return errUnexpectedResultShape(data.QueryID,
+				fmt.Sprintf("%v statement results with %v statement types", len(ids), len(types)))
+		}
+	}
+	return nil
+}
+
+func errUnexpectedResultShape(queryID, reason string) *SnowflakeError {
+	return &SnowflakeError{
+		Number:      ErrUnexpectedResultShape,
+		SQLState:    SQLStateConnectionFailure,
+		Message:     errMsgUnexpectedResultShape,
+		MessageArgs: []interface{}{reason},
+		QueryID:     queryID,
+	}
 }
 
 type childResult struct {
@@ -511,18 +867,47 @@ func getChildResults(IDs string, types string) []childResult {
 	resultTypes := strings.Split(types, ",")
 	res := make([]childResult, len(queryIDs))
 	for i, id := range queryIDs {
-		res[i] = childResult{id, resultTypes[i]}
+		res[i] = childResult{id: id}
+		if i < len(resultTypes) {
+			res[i].typ = resultTypes[i]
+		}
 	}
 	return res
 }
 
+// fetchChildResults gets the results of the statements in a multi-statement query, up to
+// MaxChildResultFetchWorkers at a time. The results and errors are in the order of the statements.
+func (sc *snowflakeConn) fetchChildResults(ctx context.Context, children []childResult) ([]*execResponse, []error) {
+	data := make([]*execResponse, len(children))
+	errs := make([]error, len(children))
+	workers := intMin(MaxChildResultFetchWorkers, len(children))
+	if workers < 1 {
+		workers = 1
+	}
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i, child := range children {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, id string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			data[i], errs[i] = sc.getQueryResult(ctx, fmt.Sprintf("/queries/%s/result", id))
+		}(i, child.id)
+	}
+	wg.Wait()
+	return data, errs
+}
+
 func (sc *snowflakeConn) getQueryResult(ctx context.Context, resultPath string) (*execResponse, error) {
 	headers := make(map[string]string)
 	headers["Content-Type"] = headerContentTypeApplicationJSON
 	headers["accept"] = headerAcceptTypeApplicationSnowflake
-	headers["User-Agent"] = userAgent
-	if serviceName, ok := sc.cfg.Params[serviceName]; ok {
-		headers["X-Snowflake-Service"] = *serviceName
+	headers["User-Agent"] = sc.rest.getUserAgent()
+	if name := sc.CurrentServiceName(); name != "" {
+		headers["X-Snowflake-Service"] = name
 	}
 	param := make(url.Values)
 	param.Add(requestIDKey, uuid.New().String())
@@ -531,27 +916,194 @@ func (sc *snowflakeConn) getQueryResult(ctx context.Context, resultPath string)
 	if sc.rest.Token != "" {
 		headers[headerAuthorizationKey] = fmt.Sprintf(headerSnowflakeToken, sc.rest.Token)
 	}
-	url := sc.rest.getFullURL(resultPath, &param)
+	url, err := sc.rest.getResultURL(resultPath, &param)
+	if err != nil {
+		return nil, err
+	}
+	if err := sc.rest.waitRateLimit(ctx); err != nil {
+		return nil, err
+	}
 	res, err := sc.rest.FuncGet(ctx, sc.rest, url, headers, sc.rest.RequestTimeout)
 	if err != nil {
-		glog.V(1).Infof("failed to get response. err: %v", err)
+		glog.V(1).Infof("failed to get response. %v, err: %v", queryLogFrom(ctx), err)
 		glog.Flush()
 		return nil, err
 	}
-	var respd *execResponse
-	err = json.NewDecoder(res.Body).Decode(&respd)
+	defer drainAndClose(res.Body)
+	var respd execResponse
+	err = decodeExecResponse(ctx, res.Body, &respd)
 	if err != nil {
-		glog.V(1).Infof("failed to decode JSON. err: %v", err)
+		glog.V(1).Infof("failed to decode JSON. %v, err: %v", queryLogFrom(ctx), err)
 		glog.Flush()
 		return nil, err
 	}
-	return respd, nil
+	if respd.Success {
+		if err = sc.checkResultShape(respd.Data); err != nil {
+			return nil, err
+		}
+	}
+	return &respd, nil
+}
+
+// fetchResultPollInterval is the wait before polling again the result of a query that is still running. It
+// backs off like queryPollInterval.
+var fetchResultPollInterval = 500 * time.Millisecond
+
+// fetchResultByQueryID builds the rows from the result of the query that has already run. If the query is
+// still running, the result is polled until the query completes or the context is canceled.
+func (sc *snowflakeConn) fetchResultByQueryID(ctx context.Context, qid string) (driver.Rows, error) {
+	queryLogFrom(ctx).setQueryID(qid)
+	data, err := sc.waitQueryResult(ctx, qid)
+	if err != nil {
+		glog.V(2).Infof("error: %v. %v", err, queryLogFrom(ctx))
+		return nil, err
+	}
+	if !data.Success {
+		return nil, childDataError(data, qid)
+	}
+	data.Data.QueryResultFormat, err = resultFormat(data.Data, qid)
+	if err != nil {
+		return nil, err
+	}
+	rows := new(snowflakeRows)
+	rows.sc = sc
+	rows.RowType = data.Data.RowType
+	rows.ChunkDownloader = populateChunkDownloader(ctx, sc, data.Data)
+	rows.queryID = qid
+	rows.extensions = data.Data.Extensions
+	if err = rows.ChunkDownloader.start(); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// cachedRows builds the rows from a result in the result cache.
+func (sc *snowflakeConn) cachedRows(ctx context.Context, data *execResponseData) (driver.Rows, error) {
+	rows := new(snowflakeRows)
+	rows.sc = sc
+	rows.RowType = data.RowType
+	rows.ChunkDownloader = populateChunkDownloader(ctx, sc, *data)
+	rows.queryID = data.QueryID
+	rows.extensions = data.Extensions
+	if err := rows.ChunkDownloader.start(); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// waitQueryResult gets the result of the query, polling it while the query is in progress.
+func (sc *snowflakeConn) waitQueryResult(ctx context.Context, qid string) (*execResponse, error) {
+	resultPath := fmt.Sprintf("/queries/%s/result", qid)
+	defer sc.rest.startPolling(qid)()
+	poller := newQueryPoller(fetchResultPollInterval)
+	for {
+		data, err := sc.getQueryResult(ctx, resultPath)
+		if err != nil {
+			return nil, err
+		}
+		switch data.Code {
+		case sessionExpiredCode:
+			if err = sc.rest.FuncRenewSession(ctx, sc.rest, sc.rest.RequestTimeout); err != nil {
+				return nil, err
+			}
+			continue
+		case queryInProgressCode, queryInProgressAsyncCode:
+			glog.V(2).Infof("query %v is still in progress. %v", qid, queryLogFrom(ctx))
+		default:
+			return data, nil
+		}
+		if err = poller.wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// resultFormat returns the format of the result. The server may omit the format of the result fetched by
+// query ID, in which case the format is detected from the first row set.
+func resultFormat(data execResponseData, qid string) (string, error) {
+	switch data.QueryResultFormat {
+	case arrowFormat, jsonFormat:
+		return data.QueryResultFormat, nil
+	case "":
+		if data.RowSetBase64 != "" {
+			return arrowFormat, nil
+		}
+		return jsonFormat, nil
+	}
+	return "", &SnowflakeError{
+		Number:      ErrUnsupportedResultFormat,
+		SQLState:    SQLStateConnectionFailure,
+		Message:     errMsgUnsupportedResultFormat,
+		MessageArgs: []interface{}{data.QueryResultFormat, qid},
+		QueryID:     qid,
+	}
+}
+
+// childDataError converts the failed result of a query fetched by ID, e.g., a child statement, into a
+// SnowflakeError.
+func childDataError(childData *execResponse, qid string) *SnowflakeError {
+	code, err := strconv.Atoi(childData.Code)
+	if err != nil {
+		code = -1
+	}
+	return &SnowflakeError{
+		Number:   code,
+		SQLState: childData.Data.SQLState,
+		Message:  childData.Message,
+		QueryID:  qid,
+	}
+}
+
+func errFetchOnlyConnection() *SnowflakeError {
+	return &SnowflakeError{
+		Number:   ErrCodeFetchOnlyConnection,
+		SQLState: SQLStateFeatureNotSupported,
+		Message:  errMsgFetchOnlyConnection,
+	}
+}
+
+// bindValueSize returns the number of bytes of a converted binding, which is a string or an array of them.
+func bindValueSize(v interface{}) int {
+	switch vv := v.(type) {
+	case *string:
+		if vv != nil {
+			return len(*vv)
+		}
+	case []*string:
+		n := 0
+		for _, s := range vv {
+			if s != nil {
+				n += len(*s)
+			}
+		}
+		return n
+	}
+	return 0
+}
+
+func errRequestTooLarge(size int, limit string) *SnowflakeError {
+	return &SnowflakeError{
+		Number:      ErrCodeRequestTooLarge,
+		SQLState:    SQLStateProgramLimitExceeded,
+		Message:     errMsgRequestTooLarge,
+		MessageArgs: []interface{}{size, limit},
+	}
+}
+
+func errMultiStatementBindings(n int) *SnowflakeError {
+	return &SnowflakeError{
+		Number:      ErrCodeMultiStatementBindings,
+		SQLState:    SQLStateFeatureNotSupported,
+		Message:     errMsgMultiStatementBindings,
+		MessageArgs: []interface{}{n},
+	}
 }
 
 func populateChunkDownloader(ctx context.Context, sc *snowflakeConn, data execResponseData) *snowflakeChunkDownloader {
 	return &snowflakeChunkDownloader{
 		sc:                 sc,
 		ctx:                ctx,
+		queryID:            data.QueryID,
 		CurrentChunk:       make([]chunkRowType, len(data.RowSet)),
 		ChunkMetas:         data.Chunks,
 		Total:              data.Total,
@@ -562,7 +1114,7 @@ func populateChunkDownloader(ctx context.Context, sc *snowflakeConn, data execRe
 		ChunkHeader:        data.ChunkHeaders,
 		FuncDownload:       downloadChunk,
 		FuncDownloadHelper: downloadChunkHelper,
-		FuncGet:            getChunk,
+		FuncGet:            sc.getChunkFunc(),
 		RowSet: rowSetType{RowType: data.RowType,
 			JSON:         data.RowSet,
 			RowSetBase64: data.RowSetBase64,
@@ -1,9 +1,22 @@
 package gosnowflake
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"github.com/google/uuid"
+	"io"
+	"io/ioutil"
+	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -57,11 +70,27 @@ func TestServiceName(t *testing.T) {
 		} else {
 			t.Error("No service name in the response")
 		}
+		if name := sc.CurrentServiceName(); name != expectServiceName {
+			t.Errorf("current service name mis-match. expected %v, actual %v", expectServiceName, name)
+		}
 
 		expectServiceName += serviceNameAppend
 	}
 }
 
+func BenchmarkExec(b *testing.B) {
+	sc := &snowflakeConn{
+		cfg:  &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{FuncPostQuery: postQueryMock},
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := sc.exec(context.Background(), "SELECT 1", false, false, nil); err != nil {
+			b.Fatalf("failed to exec. err: %v", err)
+		}
+	}
+}
+
 func closeSessionMock(_ context.Context, _ *snowflakeRestful, _ time.Duration) error {
 	return &SnowflakeError{
 		Number: ErrSessionGone,
@@ -81,3 +110,695 @@ func TestCloseIgnoreSessionGone(t *testing.T) {
 		t.Error("Close should let go session gone error")
 	}
 }
+
+func getQueryResultMock(_ context.Context, _ *snowflakeRestful, fullURL *url.URL, _ map[string]string, _ time.Duration) (*http.Response, error) {
+	if fullURL.Path != "/queries/1234-5678/result" {
+		return nil, fmt.Errorf("unexpected path: %v", fullURL.Path)
+	}
+	er := &execResponse{
+		Data: execResponseData{
+			QueryID: "1234-5678",
+			RowType: []execResponseRowType{{Name: "C1", Type: "fixed"}},
+			RowSet:  [][]*string{{&[]string{"1"}[0]}, {&[]string{"2"}[0]}},
+			Total:   2,
+		},
+		Code:    "0",
+		Success: true,
+	}
+	ba, err := json.Marshal(er)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(bytes.NewReader(ba)),
+	}, nil
+}
+
+func TestFetchOnlyConnection(t *testing.T) {
+	sr := &snowflakeRestful{
+		FuncPostQuery: postQueryMock,
+		FuncGet:       getQueryResultMock,
+	}
+	sc := &snowflakeConn{
+		cfg:  &Config{Params: map[string]*string{}, FetchOnly: true},
+		rest: sr,
+	}
+	if _, err := sc.ExecContext(context.Background(), "INSERT INTO t VALUES(1)", nil); err == nil {
+		t.Fatal("should have failed to execute a statement on a fetch-only connection")
+	} else if driverErr, ok := err.(*SnowflakeError); !ok || driverErr.Number != ErrCodeFetchOnlyConnection {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := sc.QueryContext(context.Background(), "SELECT 1", nil); err == nil {
+		t.Fatal("should have failed to run a query on a fetch-only connection")
+	}
+	if _, err := sc.BeginTx(context.Background(), driver.TxOptions{}); err == nil {
+		t.Fatal("should have failed to begin a transaction on a fetch-only connection")
+	}
+
+	rows, err := sc.QueryContext(WithFetchResultByID(context.Background(), "1234-5678"), "", nil)
+	if err != nil {
+		t.Fatalf("failed to fetch the result. err: %v", err)
+	}
+	defer rows.Close()
+	if qid := rows.(SnowflakeResult).QueryID(); qid != "1234-5678" {
+		t.Fatalf("unexpected query ID: %v", qid)
+	}
+	dest := make([]driver.Value, 1)
+	var cnt int
+	for rows.Next(dest) != io.EOF {
+		cnt++
+		if dest[0] != strconv.Itoa(cnt) {
+			t.Fatalf("unexpected value: %v", dest[0])
+		}
+	}
+	if cnt != 2 {
+		t.Fatalf("number of rows didn't match. expected: 2, got: %v", cnt)
+	}
+}
+
+func postMultiStatementMock(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, _ []byte, _ time.Duration, _ *uuid.UUID) (*execResponse, error) {
+	return &execResponse{
+		Data: execResponseData{
+			QueryID:         "parent",
+			StatementTypeID: statementTypeIDMulti,
+			RowType:         []execResponseRowType{{Name: "multiple statement execution"}},
+			ResultIDs:       "child-0,child-1,child-2",
+			ResultTypes:     "12544,12544,12544",
+		},
+		Code:    "0",
+		Success: true,
+	}, nil
+}
+
+func getMultiStatementChildMock(_ context.Context, _ *snowflakeRestful, fullURL *url.URL, _ map[string]string, _ time.Duration) (*http.Response, error) {
+	var er *execResponse
+	switch fullURL.Path {
+	case "/queries/child-0/result":
+		er = &execResponse{
+			Data: execResponseData{
+				QueryID:         "child-0",
+				StatementTypeID: statementTypeIDInsert,
+				RowType:         []execResponseRowType{{Name: "number of rows inserted", Type: "fixed"}},
+				RowSet:          [][]*string{{&[]string{"3"}[0]}},
+			},
+			Code:    "0",
+			Success: true,
+		}
+	case "/queries/child-1/result":
+		er = &execResponse{
+			Data:    execResponseData{SQLState: "42S02"},
+			Code:    "002003",
+			Message: "Table 'T2' does not exist",
+		}
+	default:
+		return nil, fmt.Errorf("unexpected path: %v", fullURL.Path)
+	}
+	ba, err := json.Marshal(er)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(bytes.NewReader(ba)),
+	}, nil
+}
+
+func TestMultiStatementExecChildErrors(t *testing.T) {
+	sr := &snowflakeRestful{
+		FuncPostQuery: postMultiStatementMock,
+		FuncGet:       getMultiStatementChildMock,
+	}
+	sc := &snowflakeConn{
+		cfg:  &Config{Params: map[string]*string{}},
+		rest: sr,
+	}
+	_, err := sc.ExecContext(context.Background(), "INSERT INTO t1 VALUES(1); INSERT INTO t2 VALUES(1); SELECT 1", nil)
+	if err == nil {
+		t.Fatal("should have failed")
+	}
+	me, ok := err.(*MultiStatementError)
+	if !ok {
+		t.Fatalf("should be MultiStatementError. err: %v", err)
+	}
+	if me.QueryID != "parent" {
+		t.Fatalf("unexpected query ID: %v", me.QueryID)
+	}
+	if len(me.Errors) != 2 {
+		t.Fatalf("number of child errors didn't match. expected: 2, got: %v", len(me.Errors))
+	}
+	if me.Errors[0].Index != 1 || me.Errors[0].QueryID != "child-1" {
+		t.Fatalf("unexpected child error: %v", me.Errors[0])
+	}
+	driverErr, ok := me.Errors[0].Err.(*SnowflakeError)
+	if !ok || driverErr.Number != 2003 || driverErr.SQLState != "42S02" || driverErr.QueryID != "child-1" {
+		t.Fatalf("unexpected child error: %v", me.Errors[0].Err)
+	}
+	if me.Errors[1].Index != 2 || me.Errors[1].QueryID != "child-2" {
+		t.Fatalf("unexpected child error: %v", me.Errors[1])
+	}
+}
+
+func TestFetchChildResultsInParallel(t *testing.T) {
+	backupMaxChildResultFetchWorkers := MaxChildResultFetchWorkers
+	MaxChildResultFetchWorkers = 2
+	defer func() { MaxChildResultFetchWorkers = backupMaxChildResultFetchWorkers }()
+
+	var running, maxRunning int32
+	sr := &snowflakeRestful{
+		FuncGet: func(_ context.Context, _ *snowflakeRestful, fullURL *url.URL, _ map[string]string, _ time.Duration) (*http.Response, error) {
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				m := atomic.LoadInt32(&maxRunning)
+				if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+					break
+				}
+			}
+			// the earlier statements complete later
+			id := strings.Split(fullURL.Path, "/")[2]
+			i, _ := strconv.Atoi(strings.TrimPrefix(id, "child-"))
+			time.Sleep(time.Duration(5-i) * 10 * time.Millisecond)
+			ba, err := json.Marshal(&execResponse{Data: execResponseData{QueryID: id}, Code: "0", Success: true})
+			if err != nil {
+				return nil, err
+			}
+			return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(bytes.NewReader(ba))}, nil
+		},
+	}
+	sc := &snowflakeConn{
+		cfg:  &Config{Params: map[string]*string{}},
+		rest: sr,
+	}
+	children := getChildResults("child-0,child-1,child-2,child-3,child-4", "12544,12544,12544,12544,12544")
+	data, errs := sc.fetchChildResults(context.Background(), children)
+	for i := range children {
+		if errs[i] != nil {
+			t.Fatalf("failed to get the result of %v. err: %v", children[i].id, errs[i])
+		}
+		if data[i].Data.QueryID != children[i].id {
+			t.Fatalf("result order didn't match. expected: %v, got: %v", children[i].id, data[i].Data.QueryID)
+		}
+	}
+	if maxRunning != 2 {
+		t.Fatalf("number of parallel fetches didn't match. expected: 2, got: %v", maxRunning)
+	}
+}
+
+func TestMultiStatementBindings(t *testing.T) {
+	posted := false
+	sc := &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{
+			FuncPostQuery: func(context.Context, *snowflakeRestful, *url.Values, map[string]string, []byte, time.Duration, *uuid.UUID) (*execResponse, error) {
+				posted = true
+				return nil, fmt.Errorf("should not be posted")
+			},
+		},
+	}
+	ctx, _ := WithMultiStatement(context.Background(), 2)
+	_, err := sc.ExecContext(ctx, "INSERT INTO t1 VALUES(?); INSERT INTO t2 VALUES(?)", toNamedValues([]driver.Value{int64(1), int64(2)}))
+	driverErr, ok := err.(*SnowflakeError)
+	if !ok || driverErr.Number != ErrCodeMultiStatementBindings {
+		t.Fatalf("should fail with ErrCodeMultiStatementBindings. err: %v", err)
+	}
+	if posted {
+		t.Fatal("the query should not be posted")
+	}
+}
+
+func TestMaxInlineBindSize(t *testing.T) {
+	stageDir, cleanup := ingestStageDir(t)
+	defer cleanup()
+	var requests []execRequest
+	sc := &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}, MaxInlineBindSize: 16},
+		rest: &snowflakeRestful{
+			FuncPostQuery: func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte, _ time.Duration, _ *uuid.UUID) (*execResponse, error) {
+				var req execRequest
+				if err := json.Unmarshal(body, &req); err != nil {
+					t.Fatal(err)
+				}
+				requests = append(requests, req)
+				data := execResponseData{QueryID: "q"}
+				if strings.HasPrefix(req.SQLText, "PUT ") {
+					data.Command = "UPLOAD"
+					data.StageInfo = &execResponseStageInfo{LocationType: "LOCAL_FS", Location: stageDir}
+				}
+				return &execResponse{Data: data, Code: "0", Success: true}, nil
+			},
+		},
+	}
+	if _, err := sc.ExecContext(context.Background(), "INSERT INTO t VALUES(?)", toNamedValues([]driver.Value{"small"})); err != nil {
+		t.Fatalf("failed to exec. err: %v", err)
+	}
+	if len(requests) != 1 || requests[0].Bindings["1"].Value != "small" || requests[0].BindStage != "" {
+		t.Fatalf("the binding should be inlined: %+v", requests)
+	}
+
+	requests = nil
+	large := strings.Repeat("x", 16) + `"y"`
+	at := time.Date(2020, 9, 1, 10, 11, 12, 5, time.UTC)
+	if _, err := sc.ExecContext(context.Background(), "INSERT INTO t VALUES(?, ?, ?, ?)",
+		toNamedValues([]driver.Value{int64(1), large, nil, at})); err != nil {
+		t.Fatalf("failed to exec. err: %v", err)
+	}
+	if len(requests) != 3 || requests[0].SQLText != createBindStage || !requests[0].IsInternal ||
+		!strings.HasPrefix(requests[1].SQLText, "PUT 'file://bindings.csv.gz' '@SYSTEM$BIND/") {
+		t.Fatalf("the bindings should be uploaded to the stage of the bindings: %+v", requests)
+	}
+	if insert := requests[2]; insert.Bindings != nil || !strings.HasPrefix(insert.BindStage, "@SYSTEM$BIND/") ||
+		!strings.Contains(requests[1].SQLText, insert.BindStage) {
+		t.Fatalf("the query should bind from the stage: %+v", insert)
+	}
+	f, err := os.Open(filepath.Join(stageDir, bindStageFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, err := ioutil.ReadAll(zr); err != nil ||
+		string(b) != `"1","xxxxxxxxxxxxxxxx""y""",,"2020-09-01 10:11:12.000000005"`+"\n" {
+		t.Fatalf("unexpected bindings in the stage: %q, err: %v", b, err)
+	}
+
+	requests = nil
+	if _, err = sc.ExecContext(context.Background(), "INSERT INTO t VALUES(?, ?)",
+		toNamedValues([]driver.Value{[]int64{1, 2}, large})); err == nil {
+		t.Fatal("an array binding with a binding that is not an array should fail")
+	}
+	if len(requests) != 0 {
+		t.Fatalf("nothing should be staged: %+v", requests)
+	}
+
+	var posted []byte
+	sc.rest.FuncPostQuery = func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte, _ time.Duration, _ *uuid.UUID) (*execResponse, error) {
+		posted = body
+		return &execResponse{Data: execResponseData{QueryID: "q"}, Code: "0", Success: true}, nil
+	}
+	sc.cfg.MaxInlineBindSize = 0
+	sc.cfg.MaxRequestBodySize = 64
+	_, err = sc.ExecContext(context.Background(), "INSERT INTO t VALUES(?)", toNamedValues([]driver.Value{strings.Repeat("x", 17)}))
+	if driverErr, ok := err.(*SnowflakeError); !ok || driverErr.Number != ErrCodeRequestTooLarge {
+		t.Fatalf("should fail with ErrCodeRequestTooLarge. err: %v", err)
+	}
+	if posted != nil {
+		t.Fatal("the query should not be posted")
+	}
+}
+
+func TestDMLRowCounts(t *testing.T) {
+	testcases := []struct {
+		typ     int64
+		columns []string
+		values  []string
+		counts  DMLRowCounts
+	}{
+		{statementTypeIDInsert, []string{"number of rows inserted"}, []string{"3"}, DMLRowCounts{Inserted: 3}},
+		{statementTypeIDUpdate, []string{"number of rows updated", "number of multi-joined rows updated"}, []string{"5", "2"}, DMLRowCounts{Updated: 5}},
+		{statementTypeIDDelete, []string{"number of rows deleted"}, []string{"4"}, DMLRowCounts{Deleted: 4}},
+		{statementTypeIDMerge, []string{"number of rows inserted", "number of rows updated", "number of rows deleted"}, []string{"1", "2", "3"}, DMLRowCounts{Inserted: 1, Updated: 2, Deleted: 3}},
+		{statementTypeIDMultiTableInsert, []string{"number of rows inserted into T1", "T2"}, []string{"6", "7"}, DMLRowCounts{Inserted: 13}},
+		{statementTypeIDUnload, []string{"rows_unloaded", "input_bytes", "output_bytes"}, []string{"10", "400", "120"}, DMLRowCounts{Unloaded: 10, UnloadedBytes: 120}},
+	}
+	for _, tc := range testcases {
+		data := execResponseData{StatementTypeID: tc.typ, RowSet: [][]*string{make([]*string, len(tc.values))}}
+		for i, name := range tc.columns {
+			data.RowType = append(data.RowType, execResponseRowType{Name: name, Type: "fixed"})
+			data.RowSet[0][i] = &tc.values[i]
+		}
+		counts, err := dmlRowCounts(data)
+		if err != nil {
+			t.Fatalf("failed to parse the DML result. err: %v", err)
+		}
+		if counts != tc.counts {
+			t.Errorf("unexpected counts for %v. expected: %+v, got: %+v", tc.columns, tc.counts, counts)
+		}
+	}
+	if counts, err := dmlRowCounts(execResponseData{StatementTypeID: statementTypeIDInsert}); err != nil || counts.Total() != 0 {
+		t.Errorf("an empty result should have no rows. counts: %+v, err: %v", counts, err)
+	}
+}
+
+func TestUnexpectedResultShape(t *testing.T) {
+	one := "1"
+	testcases := []execResponseData{
+		{RowType: []execResponseRowType{{Name: "c1"}, {Name: "c2"}}, RowSet: [][]*string{{&one, &one}, {&one}}},
+		{StatementTypeID: statementTypeIDInsert},
+		{StatementTypeID: statementTypeIDMulti, RowType: []execResponseRowType{{Name: "multiple statement execution"}},
+			ResultIDs: "q1,q2", ResultTypes: "4096"},
+	}
+	for _, data := range testcases {
+		sc := &snowflakeConn{
+			cfg: &Config{Params: map[string]*string{}},
+			rest: &snowflakeRestful{
+				FuncPostQuery: func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, _ []byte, _ time.Duration, _ *uuid.UUID) (*execResponse, error) {
+					return &execResponse{Data: data, Code: "0", Success: true}, nil
+				},
+			},
+		}
+		_, err := sc.ExecContext(context.Background(), "SELECT 1", nil)
+		if driverErr, ok := err.(*SnowflakeError); !ok || driverErr.Number != ErrUnexpectedResultShape {
+			t.Errorf("should have failed with ErrUnexpectedResultShape. data: %+v, err: %v", data, err)
+		}
+	}
+	if (&snowflakeConn{}).isMultiStmt(execResponseData{StatementTypeID: statementTypeIDMulti}) {
+		t.Error("a result without columns is not of a multi-statement query")
+	}
+}
+
+func TestDDLResult(t *testing.T) {
+	created, inserted := "Table T2 successfully created.", "42"
+	testcases := []struct {
+		data     execResponseData
+		status   string
+		inserted int64
+		counted  bool
+	}{
+		{execResponseData{RowType: []execResponseRowType{{Name: "status"}}, RowSet: [][]*string{{&created}}},
+			created, 0, false},
+		{execResponseData{RowType: []execResponseRowType{{Name: "status"}, {Name: "number of rows inserted"}},
+			RowSet: [][]*string{{&created, &inserted}}}, created, 42, true},
+		{execResponseData{}, "", 0, false},
+	}
+	for _, tc := range testcases {
+		data := tc.data
+		data.QueryID, data.StatementTypeID = "qid", 0x6000
+		c := &Client{sc: &snowflakeConn{
+			cfg: &Config{Params: map[string]*string{}},
+			rest: &snowflakeRestful{
+				FuncPostQuery: func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, _ []byte, _ time.Duration, _ *uuid.UUID) (*execResponse, error) {
+					return &execResponse{Data: data, Code: "0", Success: true}, nil
+				},
+			},
+		}}
+		res, err := c.Exec(context.Background(), "CREATE TABLE t2 AS SELECT * FROM t1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ddl, ok := res.(SnowflakeDDLResult)
+		if !ok || ddl.QueryID() != "qid" || ddl.Status() != tc.status || ddl.RowCounts().Inserted != tc.inserted {
+			t.Fatalf("unexpected result of %+v: %+v", data, res)
+		}
+		n, err := res.RowsAffected()
+		if tc.counted && (err != nil || n != tc.inserted) || !tc.counted && err == nil {
+			t.Fatalf("unexpected rows affected of %+v: %v, err: %v", data, n, err)
+		}
+		if _, err = res.LastInsertId(); err == nil {
+			t.Fatal("should have failed to get LastInsertID")
+		}
+	}
+
+	// a query run with Exec has no status row even if it has a column named status
+	status := "ok"
+	c := &Client{sc: &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{
+			FuncPostQuery: func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, _ []byte, _ time.Duration, _ *uuid.UUID) (*execResponse, error) {
+				return &execResponse{Data: execResponseData{
+					QueryID:         "qid",
+					StatementTypeID: statementTypeIDMulti,
+					RowType:         []execResponseRowType{{Name: "status"}},
+					RowSet:          [][]*string{{&status}},
+				}, Code: "0", Success: true}, nil
+			},
+		},
+	}}
+	res, err := c.sc.ExecContext(context.Background(), "SELECT status FROM t", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != driver.ResultNoRows {
+		t.Fatalf("the result of a query should be driver.ResultNoRows: %+v", res)
+	}
+	er, err := c.Exec(context.Background(), "SELECT status FROM t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := er.(SnowflakeDDLResult); ok || er.QueryID() != "qid" {
+		t.Fatalf("unexpected result of a query: %+v", er)
+	}
+	if _, err = er.RowsAffected(); err == nil {
+		t.Fatal("a query should have no rows affected")
+	}
+}
+
+func TestFetchResultByIDInProgress(t *testing.T) {
+	origInterval := fetchResultPollInterval
+	fetchResultPollInterval = time.Millisecond
+	defer func() { fetchResultPollInterval = origInterval }()
+
+	var polled int
+	sr := &snowflakeRestful{
+		FuncGet: func(ctx context.Context, sr *snowflakeRestful, fullURL *url.URL, headers map[string]string, timeout time.Duration) (*http.Response, error) {
+			polled++
+			if polled < 3 {
+				ba, err := json.Marshal(&execResponse{Code: queryInProgressAsyncCode, Success: true})
+				if err != nil {
+					return nil, err
+				}
+				return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(bytes.NewReader(ba))}, nil
+			}
+			return getQueryResultMock(ctx, sr, fullURL, headers, timeout)
+		},
+	}
+	sc := &snowflakeConn{
+		cfg:  &Config{Params: map[string]*string{}, FetchOnly: true},
+		rest: sr,
+	}
+	rows, err := sc.QueryContext(WithFetchResultByID(context.Background(), "1234-5678"), "", nil)
+	if err != nil {
+		t.Fatalf("failed to fetch the result. err: %v", err)
+	}
+	defer rows.Close()
+	if polled != 3 {
+		t.Fatalf("the result should have been polled 3 times. got: %v", polled)
+	}
+	if format := rows.(*snowflakeRows).ChunkDownloader.QueryResultFormat; format != jsonFormat {
+		t.Fatalf("unexpected result format: %v", format)
+	}
+
+	polled = -100
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err = sc.QueryContext(WithFetchResultByID(ctx, "1234-5678"), "", nil); err != context.DeadlineExceeded {
+		t.Fatalf("should have timed out while the query is in progress. err: %v", err)
+	}
+}
+
+func TestResultFormat(t *testing.T) {
+	testcases := []struct {
+		data   execResponseData
+		format string
+	}{
+		{execResponseData{QueryResultFormat: arrowFormat}, arrowFormat},
+		{execResponseData{QueryResultFormat: jsonFormat}, jsonFormat},
+		{execResponseData{RowSetBase64: "/////w=="}, arrowFormat},
+		{execResponseData{}, jsonFormat},
+	}
+	for _, tc := range testcases {
+		format, err := resultFormat(tc.data, "1234")
+		if err != nil {
+			t.Fatalf("failed to get the result format. err: %v", err)
+		}
+		if format != tc.format {
+			t.Errorf("unexpected result format. expected: %v, got: %v", tc.format, format)
+		}
+	}
+	_, err := resultFormat(execResponseData{QueryResultFormat: "parquet"}, "1234")
+	if driverErr, ok := err.(*SnowflakeError); !ok || driverErr.Number != ErrUnsupportedResultFormat {
+		t.Fatalf("should have failed with an unsupported format. err: %v", err)
+	}
+}
+
+func TestRawResults(t *testing.T) {
+	var body []byte
+	sr := &snowflakeRestful{
+		FuncPostQuery: func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, b []byte, _ time.Duration, _ *uuid.UUID) (*execResponse, error) {
+			body = b
+			num, ts := "1.50", "1600000000.123000000"
+			return &execResponse{
+				Data: execResponseData{
+					QueryID: "1234-5678",
+					RowType: []execResponseRowType{
+						{Name: "N", Type: "fixed", Scale: 2},
+						{Name: "S", Type: "text"},
+						{Name: "T", Type: "timestamp_ntz", Scale: 9},
+					},
+					RowSet:            [][]*string{{&num, nil, &ts}},
+					Total:             1,
+					QueryResultFormat: jsonFormat,
+				},
+				Code:    "0",
+				Success: true,
+			}, nil
+		},
+	}
+	sc := &snowflakeConn{
+		cfg:  &Config{Params: map[string]*string{}},
+		rest: sr,
+	}
+	rows, err := sc.QueryContext(WithRawResults(context.Background()), "SELECT 1", nil)
+	if err != nil {
+		t.Fatalf("failed to query. err: %v", err)
+	}
+	defer rows.Close()
+	if !strings.Contains(string(body), `"GO_QUERY_RESULT_FORMAT":"json"`) {
+		t.Fatalf("the result should be requested in the JSON format. body: %v", string(body))
+	}
+	dest := make([]driver.Value, 3)
+	if err = rows.Next(dest); err != nil {
+		t.Fatalf("failed to get the row. err: %v", err)
+	}
+	if dest[0] != "1.50" || dest[1] != nil || dest[2] != "1600000000.123000000" {
+		t.Fatalf("the values should be the strings on the wire. got: %#v", dest)
+	}
+}
+
+func TestWithHTTPHeaders(t *testing.T) {
+	var headers map[string]string
+	sc := &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{
+			FuncPostQuery: func(ctx context.Context, sr *snowflakeRestful, params *url.Values, h map[string]string, body []byte, timeout time.Duration, requestID *uuid.UUID) (*execResponse, error) {
+				headers = h
+				return postQueryMock(ctx, sr, params, h, body, timeout, requestID)
+			},
+		},
+	}
+	ctx, err := WithHTTPHeaders(context.Background(), map[string]string{
+		"x-correlation-id": "c-1",
+		"X-Tenant-Name":    "acme",
+		"traceparent":      "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+	})
+	if err != nil {
+		t.Fatalf("failed to set the headers. err: %v", err)
+	}
+	if _, err = sc.ExecContext(ctx, "SELECT 1", nil); err != nil {
+		t.Fatalf("failed to execute. err: %v", err)
+	}
+	if headers["X-Correlation-Id"] != "c-1" || headers["X-Tenant-Name"] != "acme" || headers["Traceparent"] == "" {
+		t.Fatalf("the headers should be sent. headers: %v", headers)
+	}
+
+	for _, h := range []map[string]string{
+		{"Authorization": "Snowflake Token=\"x\""},
+		{"X-Snowflake-Service": "svc"},
+		{"Traceparents": "x"},
+		{"X-Request-Id": "a\r\nInjected: b"},
+		{"X-Request-Id": strings.Repeat("a", maxHTTPHeaderValueLength+1)},
+	} {
+		_, err = WithHTTPHeaders(context.Background(), h)
+		if e, ok := err.(*SnowflakeError); !ok || e.Number != ErrCodeInvalidHTTPHeader {
+			t.Errorf("the headers should be rejected. headers: %v, err: %v", h, err)
+		}
+	}
+}
+
+func TestHeartBeatFromServerParameters(t *testing.T) {
+	sc := &snowflakeConn{
+		cfg:  &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{},
+	}
+	sc.startHeartBeat()
+	if sc.rest.HeartBeat != nil {
+		t.Fatal("no heartbeat should be started without CLIENT_SESSION_KEEP_ALIVE")
+	}
+	// the parameters returned at login, e.g., set at the user level
+	sc.populateSessionParameters([]nameValueParameter{
+		{Name: "CLIENT_SESSION_KEEP_ALIVE", Value: true},
+		{Name: "CLIENT_SESSION_KEEP_ALIVE_HEARTBEAT_FREQUENCY", Value: float64(1200)},
+	})
+	sc.startHeartBeat()
+	if sc.rest.HeartBeat == nil || sc.rest.HeartBeat.interval != 1200*time.Second {
+		t.Fatalf("the heartbeat should be started with the frequency of Snowflake: %+v", sc.rest.HeartBeat)
+	}
+	sc.cfg.DisableHeartbeat = true
+	sc.startHeartBeat()
+	if sc.rest.HeartBeat != nil {
+		t.Fatal("the heartbeat should be stopped by DisableHeartbeat")
+	}
+	sc.cfg.DisableHeartbeat = false
+	sc.startHeartBeat()
+	sc.stopHeartBeat()
+	if sc.rest.HeartBeat != nil {
+		t.Fatal("the heartbeat should be stopped")
+	}
+	sc.stopHeartBeat()
+
+	testcases := []struct {
+		value    string
+		interval time.Duration
+	}{
+		{"60", minHeartBeatInterval},
+		{"7200", heartBeatInterval},
+		{"invalid", heartBeatInterval},
+	}
+	for _, tc := range testcases {
+		v := tc.value
+		sc.cfg.Params[sessionClientSessionKeepAliveHeartbeatFrequency] = &v
+		if interval := sc.heartBeatFrequency(); interval != tc.interval {
+			t.Errorf("%v: expected %v, got %v", tc.value, tc.interval, interval)
+		}
+	}
+}
+
+func TestHeartBeatLiveParameterUpdates(t *testing.T) {
+	sc := &snowflakeConn{
+		cfg:  &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{},
+	}
+	sc.startHeartBeat()
+	// ALTER SESSION SET CLIENT_SESSION_KEEP_ALIVE = TRUE
+	sc.populateSessionParameters([]nameValueParameter{{Name: "CLIENT_SESSION_KEEP_ALIVE", Value: "true"}})
+	hb := sc.rest.HeartBeat
+	if hb == nil || hb.interval != heartBeatInterval {
+		t.Fatalf("the heartbeat should be started: %+v", hb)
+	}
+	// unchanged values returned by the following queries keep the heartbeat
+	sc.populateSessionParameters([]nameValueParameter{{Name: "CLIENT_SESSION_KEEP_ALIVE", Value: "true"}})
+	if sc.rest.HeartBeat != hb {
+		t.Fatal("the heartbeat should not be restarted")
+	}
+	sc.populateSessionParameters([]nameValueParameter{{Name: "CLIENT_SESSION_KEEP_ALIVE_HEARTBEAT_FREQUENCY", Value: "900"}})
+	if sc.rest.HeartBeat == hb || sc.rest.HeartBeat.interval != 900*time.Second {
+		t.Fatalf("the heartbeat should be restarted with the new frequency: %+v", sc.rest.HeartBeat)
+	}
+	sc.populateSessionParameters([]nameValueParameter{{Name: "CLIENT_SESSION_KEEP_ALIVE", Value: "false"}})
+	if sc.rest.HeartBeat != nil {
+		t.Fatal("the heartbeat should be stopped")
+	}
+	// turned off mid-session, Close doesn't stop the heartbeat again
+	sc.stopHeartBeat()
+}
+
+func TestHeartBeatBackgroundContext(t *testing.T) {
+	bgCtx, cancel := context.WithCancel(context.Background())
+	keepAlive := "true"
+	sc := &snowflakeConn{
+		cfg: &Config{
+			Params:            map[string]*string{sessionClientSessionKeepAlive: &keepAlive},
+			BackgroundContext: bgCtx,
+		},
+		rest: &snowflakeRestful{},
+	}
+	sc.startHeartBeat()
+	hb := sc.rest.HeartBeat
+	if hb == nil || hb.ctx != bgCtx {
+		t.Fatalf("the heartbeat should run with the background context: %+v", hb)
+	}
+	cancel()
+	select {
+	case <-hb.doneChan:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the heartbeat should stop with the background context")
+	}
+	// stopping the stopped heartbeat doesn't block
+	sc.stopHeartBeat()
+	if sc.rest.HeartBeat != nil {
+		t.Fatal("the heartbeat should be removed")
+	}
+}
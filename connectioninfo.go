@@ -0,0 +1,67 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"strings"
+)
+
+// SnowflakeConnection is the interface of the driver connection to get the session information.
+// The driver connection is given to the function passed to sql.Conn.Raw.
+type SnowflakeConnection interface {
+	ConnectionInfo() ConnectionInfo
+	ExportSession() ExportedSession
+	WarmUpWarehouse(ctx context.Context) error
+	QueryStatus(ctx context.Context, queryID string) (*QueryStatus, error)
+	CurrentServiceName() string
+	AbortAll(ctx context.Context) error
+}
+
+// ConnectionInfo is the session information returned by Snowflake at login.
+type ConnectionInfo struct {
+	SessionID       int
+	ServerVersion   string
+	DisplayUserName string
+	Database        string
+	Schema          string
+	Warehouse       string
+	Role            string
+	// Parameters are the session parameters returned at login, keyed by upper case names.
+	Parameters map[string]string
+}
+
+func newConnectionInfo(data *authResponseMain) ConnectionInfo {
+	params := make(map[string]string, len(data.Parameters))
+	for _, param := range data.Parameters {
+		params[strings.ToUpper(param.Name)] = parameterValueString(param.Value)
+	}
+	return ConnectionInfo{
+		SessionID:       data.SessionID,
+		ServerVersion:   data.ServerVersion,
+		DisplayUserName: data.DisplayUserName,
+		Database:        data.SessionInfo.DatabaseName,
+		Schema:          data.SessionInfo.SchemaName,
+		Warehouse:       data.SessionInfo.WarehouseName,
+		Role:            data.SessionInfo.RoleName,
+		Parameters:      params,
+	}
+}
+
+// ConnectionInfo returns the session information. The returned value is a copy.
+func (sc *snowflakeConn) ConnectionInfo() ConnectionInfo {
+	info := sc.info
+	info.Parameters = make(map[string]string, len(sc.info.Parameters))
+	for k, v := range sc.info.Parameters {
+		info.Parameters[k] = v
+	}
+	return info
+}
+
+// CurrentServiceName returns the service name that Snowflake assigned to the session, which is sent back in the
+// X-Snowflake-Service header of the requests. It is updated from every response, and is empty until Snowflake
+// assigns one. It is safe to call while the connection is in use.
+func (sc *snowflakeConn) CurrentServiceName() string {
+	name, _ := sc.service.Load().(string)
+	return name
+}
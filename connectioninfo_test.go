@@ -0,0 +1,48 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"testing"
+)
+
+func TestConnectionInfo(t *testing.T) {
+	data := &authResponseMain{
+		SessionID:       1234,
+		ServerVersion:   "4.20.1",
+		DisplayUserName: "TESTUSER",
+		Parameters: []nameValueParameter{
+			{Name: "timezone", Value: "UTC"},
+			{Name: "CLIENT_SESSION_KEEP_ALIVE", Value: false},
+			{Name: "CLIENT_PREFETCH_THREADS", Value: float64(4)},
+		},
+		SessionInfo: authResponseSessionInfo{
+			DatabaseName:  "TESTDB",
+			SchemaName:    "PUBLIC",
+			WarehouseName: "WH",
+			RoleName:      "SYSADMIN",
+		},
+	}
+	var conn SnowflakeConnection = &snowflakeConn{info: newConnectionInfo(data)}
+	info := conn.ConnectionInfo()
+	if info.SessionID != 1234 || info.ServerVersion != "4.20.1" || info.DisplayUserName != "TESTUSER" {
+		t.Fatalf("unexpected connection info: %+v", info)
+	}
+	if info.Database != "TESTDB" || info.Schema != "PUBLIC" || info.Warehouse != "WH" || info.Role != "SYSADMIN" {
+		t.Fatalf("unexpected session info: %+v", info)
+	}
+	expected := map[string]string{
+		"TIMEZONE":                  "UTC",
+		"CLIENT_SESSION_KEEP_ALIVE": "false",
+		"CLIENT_PREFETCH_THREADS":   "4",
+	}
+	for k, v := range expected {
+		if info.Parameters[k] != v {
+			t.Fatalf("unexpected parameter %v. expected: %v, got: %v", k, v, info.Parameters[k])
+		}
+	}
+	info.Parameters["TIMEZONE"] = "changed"
+	if conn.ConnectionInfo().Parameters["TIMEZONE"] != "UTC" {
+		t.Fatal("the parameters must be copied")
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"database/sql/driver"
+	"net/http"
+	"sync"
+)
+
+// Connector creates connections with a Config instead of a DSN, so that the options that cannot be given in
+// a DSN, such as CredentialsProvider, can be used with sql.OpenDB. The connections created by a Connector share
+// one HTTP client, so that they reuse the idle HTTP connections, and the TLS sessions of them, of each other
+// instead of handshaking per connection. The HTTP client, and the password changed at login if any, are all they
+// share: every connection logs in to its own session, and the OCSP response cache is shared by all the connections
+// of the process, not per Connector.
+type Connector struct {
+	driver   SnowflakeDriver
+	cfg      Config
+	client   *http.Client
+	conns    *openConns
+	password *changedPassword
+}
+
+// NewConnector creates a new Connector for the driver and the Config.
+func NewConnector(driver SnowflakeDriver, config Config) Connector {
+	return Connector{driver, config, newHTTPClient(&config), &openConns{}, &changedPassword{}}
+}
+
+// Connect creates a new connection. Once a connection has changed the expired password at login with
+// Config.NewPassword, the following connections log in with the new password.
+func (t Connector) Connect(ctx context.Context) (driver.Conn, error) {
+	cfg := t.cfg
+	if t.password != nil {
+		if password := t.password.get(); password != "" {
+			cfg.Password = password
+		}
+	}
+	conn, err := t.driver.openWithClient(ctx, cfg, t.client, t.password)
+	if err != nil {
+		return nil, err
+	}
+	if sc, ok := conn.(*snowflakeConn); ok && t.conns != nil {
+		t.conns.add(sc)
+	}
+	return conn, nil
+}
+
+// AbortAll aborts every query the open connections of the Connector have submitted and that has not returned
+// yet, e.g., for a fast shutdown of the workers of the sql.DB opened with the Connector. database/sql holds a
+// connection while it runs a query, so that the AbortAll of a connection given to sql.Conn.Raw cannot reach the
+// queries of the others. It returns the first error of the aborts, if any.
+func (t Connector) AbortAll(ctx context.Context) error {
+	if t.conns == nil {
+		return nil
+	}
+	return t.conns.abortAll(ctx)
+}
+
+// Close aborts the queries in flight of the open connections of the Connector if Config.AbortOnClose is set.
+// sql.DB.Close calls it as of Go 1.17, so that the queries running when the sql.DB is closed are aborted instead
+// of running to the end before their connections are closed.
+func (t Connector) Close() error {
+	if !t.cfg.AbortOnClose {
+		return nil
+	}
+	ctx := context.Background()
+	if t.cfg.RequestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.cfg.RequestTimeout)
+		defer cancel()
+	}
+	return t.AbortAll(ctx)
+}
+
+// Driver creates a new driver.
+func (t Connector) Driver() driver.Driver {
+	return t.driver
+}
+
+// changedPassword is the password a connection of a Connector has changed at login, shared by the connections of
+// the Connector. It is empty until a password is changed.
+type changedPassword struct {
+	mu       sync.Mutex
+	password string
+}
+
+func (p *changedPassword) get() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.password
+}
+
+func (p *changedPassword) set(password string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.password = password
+}
@@ -5,6 +5,7 @@ package gosnowflake
 import (
 	"database/sql/driver"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"github.com/apache/arrow/go/arrow"
 	"github.com/apache/arrow/go/arrow/array"
@@ -40,14 +41,44 @@ func goTypeToSnowflake(v driver.Value, tsmode string) string {
 			return "TEXT" // not supported dataType
 		}
 		return "CHANGE_TYPE"
-	case []int, []int64, []float64, []bool, []string:
-		return "ARRAY"
 	case time.Time:
 		return tsmode
+	case json.RawMessage:
+		return "VARIANT"
+	}
+	if isArrayBind(v) {
+		return "ARRAY"
+	}
+	if isObjectBind(v) {
+		return "OBJECT"
 	}
 	return "TEXT"
 }
 
+// valuerValue returns the value of the driver.Valuer, or nil for a nil pointer whose element type implements
+// driver.Valuer, as database/sql does.
+func valuerValue(vr driver.Valuer) (driver.Value, error) {
+	if rv := reflect.ValueOf(vr); rv.Kind() == reflect.Ptr && rv.IsNil() && rv.Type().Elem().Implements(valuerType) {
+		return nil, nil
+	}
+	return vr.Value()
+}
+
+var valuerType = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+
+// isArrayBind returns true if the value is bound as an array of values, which is a slice other than []byte
+// that doesn't implement driver.Valuer.
+func isArrayBind(v driver.Value) bool {
+	if v == nil {
+		return false
+	}
+	if _, ok := v.(driver.Valuer); ok {
+		return false
+	}
+	t := reflect.TypeOf(v)
+	return t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8
+}
+
 // snowflakeTypeToGo translates Snowflake data type to Go data type.
 func snowflakeTypeToGo(dbtype string, scale int64) reflect.Type {
 	switch dbtype {
@@ -79,6 +110,17 @@ func valueToString(v driver.Value, tsmode string) (*string, error) {
 	if v == nil {
 		return nil, nil
 	}
+	if isObjectBind(v) {
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Map && rv.IsNil() {
+			return nil, nil
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		s := string(b)
+		return &s, nil
+	}
 	v1 := reflect.ValueOf(v)
 	switch v1.Kind() {
 	case reflect.Bool:
@@ -88,7 +130,7 @@ func valueToString(v driver.Value, tsmode string) (*string, error) {
 		s := strconv.FormatInt(v1.Int(), 10)
 		return &s, nil
 	case reflect.Float64:
-		s := strconv.FormatFloat(v1.Float(), 'g', -1, 32)
+		s := formatFloat(v1.Float(), 64)
 		return &s, nil
 	case reflect.String:
 		s := v1.String()
@@ -97,6 +139,10 @@ func valueToString(v driver.Value, tsmode string) (*string, error) {
 		if v1.IsNil() {
 			return nil, nil
 		}
+		if raw, ok := v.(json.RawMessage); ok {
+			s := string(raw)
+			return &s, nil
+		}
 		if bd, ok := v.([]byte); ok {
 			if tsmode == "BINARY" {
 				s := hex.EncodeToString(bd)
@@ -153,10 +199,18 @@ func extractTimestamp(srcValue *string) (sec int64, nsec int64, err error) {
 		nsec = 0
 	} else {
 		s := (*srcValue)[i+1:]
+		if len(s) > 9 {
+			// beyond the nanosecond precision
+			s = s[:9]
+		}
 		nsec, err = strconv.ParseInt(s+strings.Repeat("0", 9-len(s)), 10, 64)
 		if err != nil {
 			return 0, 0, err
 		}
+		if strings.HasPrefix(*srcValue, "-") {
+			// the fraction of a time before the epoch is negative too, e.g., -1.5 is 1.5 seconds before
+			nsec = -nsec
+		}
 	}
 	glog.V(2).Infof("sec: %v, nsec: %v", sec, nsec)
 	return sec, nsec, nil
@@ -247,43 +301,126 @@ func stringToValue(dest *driver.Value, srcColumnMeta execResponseRowType, srcVal
 	return nil
 }
 
-func arrayToString(v driver.Value) (string, []string) {
-	var t string
-	var arr []string
-	switch a := v.(type) {
-	case []int:
-		t = "FIXED"
-		for _, x := range a {
-			arr = append(arr, strconv.Itoa(x))
-		}
-	case []int64:
-		t = "FIXED"
-		for _, x := range a {
-			arr = append(arr, strconv.Itoa(int(x)))
-		}
-	case []float64:
-		t = "REAL"
-		for _, x := range a {
-			arr = append(arr, fmt.Sprintf("%g", x))
-		}
-	case []bool:
-		t = "BOOLEAN"
-		for _, x := range a {
-			arr = append(arr, strconv.FormatBool(x))
-		}
-	case []string:
-		t = "TEXT"
-		arr = a
+// isObjectBind returns true if the value is bound as an OBJECT, which is a struct other than time.Time, a map
+// or a non-nil pointer to either of them that doesn't implement driver.Valuer.
+func isObjectBind(v driver.Value) bool {
+	if v == nil {
+		return false
+	}
+	if _, ok := v.(driver.Valuer); ok {
+		return false
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return false
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Struct:
+		return rv.Type() != reflect.TypeOf(time.Time{})
+	case reflect.Map:
+		return true
+	}
+	return false
+}
+
+// arrayToString converts the elements of an array binding to strings, and returns them with the Snowflake type
+// of the elements. nil elements, including nil pointers, are bound as NULL. The elements of []interface{} and
+// pointer slices, e.g., []*int64, must be of the same type. time.Time elements are bound in the timestamp mode,
+// and nested slices and maps as JSON text, which PARSE_JSON turns into ARRAY, OBJECT or VARIANT values.
+func arrayToString(v driver.Value, tsmode string) (string, []*string, error) {
+	a := reflect.ValueOf(v)
+	var typ string
+	arr := make([]*string, a.Len())
+	for i := range arr {
+		e := a.Index(i)
+		if vr, ok := e.Interface().(driver.Valuer); ok {
+			v, err := valuerValue(vr)
+			if err != nil {
+				return "", nil, err
+			}
+			if v == nil {
+				continue
+			}
+			e = reflect.ValueOf(v)
+		}
+		for (e.Kind() == reflect.Ptr || e.Kind() == reflect.Interface) && !e.IsNil() {
+			e = e.Elem()
+		}
+		switch e.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+			if e.IsNil() {
+				continue
+			}
+		}
+		t, s, err := arrayElementToString(e, tsmode)
+		if err != nil {
+			return "", nil, err
+		}
+		if typ == "" {
+			typ = t
+		} else if typ != t {
+			return "", nil, fmt.Errorf("array binding has elements of different types: %v and %v", typ, t)
+		}
+		arr[i] = &s
+	}
+	if typ == "" {
+		// empty or all NULL
+		typ = "TEXT"
+	}
+	return typ, arr, nil
+}
+
+// arrayElementToString converts an element of an array binding to a string with its Snowflake type.
+func arrayElementToString(e reflect.Value, tsmode string) (string, string, error) {
+	if s, ok := uuidBindValue(e.Interface()); ok {
+		return "TEXT", s, nil
+	}
+	switch e.Kind() {
+	case reflect.Bool:
+		return "BOOLEAN", strconv.FormatBool(e.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "FIXED", strconv.FormatInt(e.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "FIXED", strconv.FormatUint(e.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return "REAL", formatFloat(e.Float(), 64), nil
+	case reflect.String:
+		return "TEXT", e.String(), nil
+	case reflect.Struct:
+		if tm, ok := e.Interface().(time.Time); ok {
+			s, err := valueToString(tm, tsmode)
+			if err != nil {
+				return "", "", err
+			}
+			return tsmode, *s, nil
+		}
+	case reflect.Slice, reflect.Array, reflect.Map:
+		if raw, ok := e.Interface().(json.RawMessage); ok {
+			return "VARIANT", string(raw), nil
+		}
+		if b, ok := e.Interface().([]byte); ok {
+			return "BINARY", hex.EncodeToString(b), nil
+		}
+		b, err := json.Marshal(e.Interface())
+		if err != nil {
+			return "", "", err
+		}
+		return "TEXT", string(b), nil
 	}
-	return t, arr
+	return "", "", fmt.Errorf("unsupported type in array binding: %v", e.Type())
 }
 
 var decimalShift = new(big.Int).Exp(big.NewInt(2), big.NewInt(64), nil)
 
+// decimalFloatPrec is the precision of the big.Float values of the FIXED columns with a scale. It holds the 38
+// digits of a NUMBER, so that formatting the value with its scale gives back the exact number.
+const decimalFloatPrec = 128
+
 func intToBigFloat(val int64, scale int64) *big.Float {
-	f := new(big.Float).SetInt64(val)
-	s := new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(scale), nil))
-	return new(big.Float).Quo(f, s)
+	return ratToBigFloat(new(big.Rat).SetFrac(big.NewInt(val), pow10BigInt(scale)))
 }
 
 func decimalToBigInt(num decimal128.Num) *big.Int {
@@ -292,10 +429,22 @@ func decimalToBigInt(num decimal128.Num) *big.Int {
 	return new(big.Int).Add(new(big.Int).Mul(high, decimalShift), low)
 }
 
+// decimalToBigRat returns the exact value of the decimal with the scale.
+func decimalToBigRat(num decimal128.Num, scale int64) *big.Rat {
+	return new(big.Rat).SetFrac(decimalToBigInt(num), pow10BigInt(scale))
+}
+
 func decimalToBigFloat(num decimal128.Num, scale int64) *big.Float {
-	f := new(big.Float).SetInt(decimalToBigInt(num))
-	s := new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(scale), nil))
-	return new(big.Float).Quo(f, s)
+	return ratToBigFloat(decimalToBigRat(num, scale))
+}
+
+// ratToBigFloat returns the value rounded to the nearest big.Float of decimalFloatPrec.
+func ratToBigFloat(r *big.Rat) *big.Float {
+	return new(big.Float).SetPrec(decimalFloatPrec).SetRat(r)
+}
+
+func pow10BigInt(n int64) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(n), nil)
 }
 
 func stringIntToDecimal(src string) (decimal128.Num, bool) {
@@ -303,25 +452,41 @@ func stringIntToDecimal(src string) (decimal128.Num, bool) {
 	if !ok {
 		return decimal128.Num{}, ok
 	}
-	var high, low big.Int
-	high.QuoRem(b, decimalShift, &low)
-	return decimal128.New(high.Int64(), low.Uint64()), ok
+	return bigIntToDecimal(b), ok
 }
 
 func stringFloatToDecimal(src string, scale int64) (decimal128.Num, bool) {
-	b, ok := new(big.Float).SetString(src)
+	b, ok := new(big.Rat).SetString(src)
 	if !ok {
 		return decimal128.Num{}, ok
 	}
-	s := new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(scale), nil))
-	n := new(big.Float).Mul(b, s)
+	n := new(big.Rat).Mul(b, new(big.Rat).SetInt(pow10BigInt(scale)))
 	if !n.IsInt() {
 		return decimal128.Num{}, false
 	}
-	var high, low, z big.Int
-	n.Int(&z)
-	high.QuoRem(&z, decimalShift, &low)
-	return decimal128.New(high.Int64(), low.Uint64()), ok
+	return bigIntToDecimal(n.Num()), ok
+}
+
+func bigIntToDecimal(b *big.Int) decimal128.Num {
+	var high, low big.Int
+	high.QuoRem(b, decimalShift, &low)
+	if low.Sign() < 0 {
+		// the low bits are unsigned
+		high.Sub(&high, big.NewInt(1))
+		low.Add(&low, decimalShift)
+	}
+	return decimal128.New(high.Int64(), low.Uint64())
+}
+
+// scaledToEpoch splits a time in units of 10^-scale seconds since the epoch into seconds and nanoseconds.
+func scaledToEpoch(v int64, scale int64) (sec int64, nsec int64) {
+	pow := int64(math.Pow10(int(scale)))
+	sec, frac := v/pow, v%pow
+	if frac < 0 {
+		sec--
+		frac += pow
+	}
+	return sec, frac * int64(math.Pow10(9-int(scale)))
 }
 
 // Arrow Interface (Column) converter. This is called when Arrow chunks are downloaded to convert to the corresponding
@@ -434,10 +599,10 @@ func arrowToValue(destcol *[]snowflakeValue, srcColumnMeta execResponseRowType,
 		return err
 	case "TIME":
 		if srcValue.DataType().ID() == arrow.INT64 {
-			for i, int64 := range array.NewInt64Data(data).Int64Values() {
+			for i, t := range array.NewInt64Data(data).Int64Values() {
 				if !srcValue.IsNull(i) {
 					t0 := time.Time{}
-					(*destcol)[i] = t0.Add(time.Duration(int64))
+					(*destcol)[i] = t0.Add(time.Duration(t * int64(math.Pow10(9-int(srcColumnMeta.Scale)))))
 				}
 			}
 		} else {
@@ -462,7 +627,7 @@ func arrowToValue(destcol *[]snowflakeValue, srcColumnMeta execResponseRowType,
 		} else {
 			for i, t := range array.NewInt64Data(data).Int64Values() {
 				if !srcValue.IsNull(i) {
-					(*destcol)[i] = time.Unix(0, t*int64(math.Pow10(9-int(srcColumnMeta.Scale)))).UTC()
+					(*destcol)[i] = time.Unix(scaledToEpoch(t, srcColumnMeta.Scale)).UTC()
 				}
 			}
 		}
@@ -480,9 +645,7 @@ func arrowToValue(destcol *[]snowflakeValue, srcColumnMeta execResponseRowType,
 		} else {
 			for i, t := range array.NewInt64Data(data).Int64Values() {
 				if !srcValue.IsNull(i) {
-					q := t / int64(math.Pow10(int(srcColumnMeta.Scale)))
-					r := t % int64(math.Pow10(int(srcColumnMeta.Scale)))
-					(*destcol)[i] = time.Unix(q, r)
+					(*destcol)[i] = time.Unix(scaledToEpoch(t, srcColumnMeta.Scale))
 				}
 			}
 		}
@@ -495,7 +658,7 @@ func arrowToValue(destcol *[]snowflakeValue, srcColumnMeta execResponseRowType,
 			for i := range *destcol {
 				if !srcValue.IsNull(i) {
 					loc := Location(int(timezone[i]) - 1440)
-					tt := time.Unix(epoch[i], 0)
+					tt := time.Unix(scaledToEpoch(epoch[i], srcColumnMeta.Scale))
 					(*destcol)[i] = tt.In(loc)
 				}
 			}
@@ -517,3 +680,55 @@ func arrowToValue(destcol *[]snowflakeValue, srcColumnMeta execResponseRowType,
 	err = fmt.Errorf("unsupported data type")
 	return err
 }
+
+// arrowToRawValue copies the Arrow scalars of the column without converting them to the Snowflake data type.
+// This is used by WithRawResults.
+func arrowToRawValue(destcol *[]snowflakeValue, srcValue array.Interface) error {
+	for i := range *destcol {
+		if srcValue.IsNull(i) {
+			continue
+		}
+		v, err := arrowScalar(srcValue, i)
+		if err != nil {
+			return err
+		}
+		(*destcol)[i] = v
+	}
+	return nil
+}
+
+func arrowScalar(col array.Interface, i int) (snowflakeValue, error) {
+	switch c := col.(type) {
+	case *array.Int8:
+		return c.Value(i), nil
+	case *array.Int16:
+		return c.Value(i), nil
+	case *array.Int32:
+		return c.Value(i), nil
+	case *array.Int64:
+		return c.Value(i), nil
+	case *array.Decimal128:
+		return c.Value(i), nil
+	case *array.Float64:
+		return c.Value(i), nil
+	case *array.Boolean:
+		return c.Value(i), nil
+	case *array.String:
+		return c.Value(i), nil
+	case *array.Binary:
+		return c.Value(i), nil
+	case *array.Date32:
+		return int32(c.Value(i)), nil
+	case *array.Struct:
+		fields := make([]interface{}, c.NumField())
+		for f := range fields {
+			v, err := arrowScalar(c.Field(f), i)
+			if err != nil {
+				return nil, err
+			}
+			fields[f] = v
+		}
+		return fields, nil
+	}
+	return nil, fmt.Errorf("unsupported arrow data type: %v", col.DataType())
+}
@@ -3,14 +3,21 @@
 package gosnowflake
 
 import (
+	"database/sql"
 	"database/sql/driver"
+	"encoding/json"
 	"fmt"
 	"github.com/apache/arrow/go/arrow"
 	"github.com/apache/arrow/go/arrow/array"
 	"github.com/apache/arrow/go/arrow/memory"
+	"math"
 	"math/big"
 	"math/cmplx"
+	"math/rand"
+	"os"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -115,6 +122,38 @@ func TestValueToString(t *testing.T) {
 	}
 }
 
+func TestObjectBind(t *testing.T) {
+	type address struct {
+		City string `json:"city"`
+		Zip  string `json:"zip,omitempty"`
+	}
+	testcases := []struct {
+		in  driver.Value
+		out string
+	}{
+		{address{City: "San Mateo"}, `{"city":"San Mateo"}`},
+		{&address{City: "Bellevue", Zip: "98004"}, `{"city":"Bellevue","zip":"98004"}`},
+		{map[string]interface{}{"a": []int{1, 2}}, `{"a":[1,2]}`},
+	}
+	for _, tc := range testcases {
+		if typ := goTypeToSnowflake(tc.in, "TIMESTAMP_NTZ"); typ != "OBJECT" {
+			t.Errorf("%T should be bound as OBJECT. got: %v", tc.in, typ)
+		}
+		s, err := valueToString(tc.in, "TIMESTAMP_NTZ")
+		if err != nil || s == nil || *s != tc.out {
+			t.Errorf("failed to marshal %v. expected: %v, got: %v, err: %v", tc.in, tc.out, s, err)
+		}
+	}
+	for _, v := range []driver.Value{time.Now(), (*address)(nil), sql.NullString{}, "a"} {
+		if isObjectBind(v) {
+			t.Errorf("%T should not be bound as OBJECT", v)
+		}
+	}
+	if s, err := valueToString(map[string]string(nil), ""); err != nil || s != nil {
+		t.Errorf("a nil map should be bound as NULL. got: %v, err: %v", s, err)
+	}
+}
+
 func TestExtractTimestamp(t *testing.T) {
 	s := "1234abcdef"
 	_, _, err := extractTimestamp(&s)
@@ -131,6 +170,32 @@ func TestExtractTimestamp(t *testing.T) {
 	if err == nil {
 		t.Errorf("should raise error: %v", s)
 	}
+	s = "1234.1234567891234"
+	sec, nsec, err := extractTimestamp(&s)
+	if err != nil || sec != 1234 || nsec != 123456789 {
+		t.Errorf("failed to extract %v. sec: %v, nsec: %v, err: %v", s, sec, nsec, err)
+	}
+}
+
+func TestFormatTime(t *testing.T) {
+	var dest driver.Value
+	source := "45296.123456789" // 12:34:56.123456789
+	if err := stringToValue(&dest, execResponseRowType{Type: "time", Scale: 9}, &source); err != nil {
+		t.Fatalf("failed to convert. err: %v", err)
+	}
+	d := 12*time.Hour + 34*time.Minute + 56*time.Second + 123456789*time.Nanosecond
+	if v := formatTime(dest, TimeFormatZeroDate); v != (time.Time{}).Add(d) {
+		t.Fatalf("unexpected value: %v", v)
+	}
+	if v := formatTime(dest, TimeFormatEpochDate); v != time.Unix(0, int64(d)).UTC() {
+		t.Fatalf("unexpected value: %v", v)
+	}
+	if v := formatTime(dest, TimeFormatDuration); v != d {
+		t.Fatalf("unexpected value: %v", v)
+	}
+	if v := formatTime(nil, TimeFormatDuration); v != nil {
+		t.Fatalf("NULL should stay nil: %v", v)
+	}
 }
 
 func TestStringToValue(t *testing.T) {
@@ -188,28 +253,126 @@ func TestStringToValue(t *testing.T) {
 type tcArrayToString struct {
 	in  interface{}
 	typ string
-	out []string
+	out []*string
 }
 
 func TestArrayToString(t *testing.T) {
+	str := func(s string) *string { return &s }
+	one, two := int64(1), int64(2)
+	tm := time.Unix(1600000000, 123)
 	testcases := []tcArrayToString{
-		{in: []int{1, 2}, typ: "FIXED", out: []string{"1", "2"}},
-		{in: []int64{3, 4, 5}, typ: "FIXED", out: []string{"3", "4", "5"}},
-		{in: []float64{6.7}, typ: "REAL", out: []string{"6.7"}},
-		{in: []bool{true, false}, typ: "BOOLEAN", out: []string{"true", "false"}},
-		{in: []string{"foo", "bar", "baz"}, typ: "TEXT", out: []string{"foo", "bar", "baz"}},
+		{in: []int{1, 2}, typ: "FIXED", out: []*string{str("1"), str("2")}},
+		{in: []int64{3, 4, 5}, typ: "FIXED", out: []*string{str("3"), str("4"), str("5")}},
+		{in: []float64{6.7}, typ: "REAL", out: []*string{str("6.7")}},
+		{in: []bool{true, false}, typ: "BOOLEAN", out: []*string{str("true"), str("false")}},
+		{in: []string{"foo", "bar", "baz"}, typ: "TEXT", out: []*string{str("foo"), str("bar"), str("baz")}},
+		{in: []*int64{&one, nil, &two}, typ: "FIXED", out: []*string{str("1"), nil, str("2")}},
+		{in: []interface{}{true, nil, false}, typ: "BOOLEAN", out: []*string{str("true"), nil, str("false")}},
+		{in: []time.Time{tm}, typ: "TIMESTAMP_NTZ", out: []*string{str("1600000000000000123")}},
+		{in: [][]int{{1, 2}, nil}, typ: "TEXT", out: []*string{str("[1,2]"), nil}},
+		{in: []interface{}{map[string]interface{}{"a": 1}, []string{"x"}}, typ: "TEXT", out: []*string{str(`{"a":1}`), str(`["x"]`)}},
+		{in: []interface{}{nil}, typ: "TEXT", out: []*string{nil}},
 	}
 	for _, test := range testcases {
-		s, a := arrayToString(test.in)
+		s, a, err := arrayToString(test.in, "TIMESTAMP_NTZ")
+		if err != nil {
+			t.Errorf("failed. in: %v, err: %v", test.in, err)
+			continue
+		}
 		if s != test.typ {
 			t.Errorf("failed. in: %v, expected: %v, got: %v", test.in, test.typ, s)
 		}
-		for i, v := range a {
-			if v != test.out[i] {
-				t.Errorf("failed. in: %v, expected: %v, got: %v", test.in, test.out[i], a)
-			}
+		if !reflect.DeepEqual(a, test.out) {
+			t.Errorf("failed. in: %v, expected: %v, got: %v", test.in, test.out, a)
 		}
 	}
+	if _, _, err := arrayToString([]interface{}{1, "a"}, "TIMESTAMP_NTZ"); err == nil {
+		t.Error("should fail for the elements of different types")
+	}
+	if _, _, err := arrayToString([]interface{}{struct{}{}}, "TIMESTAMP_NTZ"); err == nil {
+		t.Error("should fail for an unsupported element type")
+	}
+}
+
+func TestCheckNamedValueArrays(t *testing.T) {
+	sc := &snowflakeConn{}
+	for _, v := range []driver.Value{[]int{1}, []interface{}{1}, []*string{nil}, []time.Time{}, [][]int{{1}}} {
+		if err := sc.CheckNamedValue(&driver.NamedValue{Value: v}); err != nil {
+			t.Errorf("%T should be bound as an array. err: %v", v, err)
+		}
+	}
+	for _, v := range []driver.Value{[]byte{1}, "a", int64(1), nil} {
+		if err := sc.CheckNamedValue(&driver.NamedValue{Value: v}); err != driver.ErrSkip {
+			t.Errorf("%T should not be bound as an array. err: %v", v, err)
+		}
+	}
+}
+
+type testValuer struct {
+	v driver.Value
+}
+
+func (tv testValuer) Value() (driver.Value, error) {
+	return tv.v, nil
+}
+
+// loopValuer is a Valuer whose value is a Valuer again.
+type loopValuer struct{}
+
+func (lv loopValuer) Value() (driver.Value, error) {
+	return lv, nil
+}
+
+func TestCheckNamedValueValuer(t *testing.T) {
+	sc := &snowflakeConn{}
+	raw := json.RawMessage(`{"a":1}`)
+	for _, test := range []struct {
+		in  driver.Value
+		out driver.Value
+	}{
+		{testValuer{int64(1)}, int64(1)},
+		{testValuer{1}, int64(1)},
+		{testValuer{raw}, raw},
+		{testValuer{[]int{1}}, []int{1}},
+		{(*testValuer)(nil), nil},
+		{sql.NullString{}, nil},
+		{raw, raw},
+	} {
+		nv := driver.NamedValue{Value: test.in}
+		if err := sc.CheckNamedValue(&nv); err != nil {
+			t.Fatalf("failed to check %#v. err: %v", test.in, err)
+		}
+		if !reflect.DeepEqual(nv.Value, test.out) {
+			t.Errorf("unexpected value of %#v. expected: %#v, got: %#v", test.in, test.out, nv.Value)
+		}
+	}
+	if err := sc.CheckNamedValue(&driver.NamedValue{Value: testValuer{make(chan int)}}); err == nil {
+		t.Error("an unsupported value of a Valuer should fail")
+	}
+	if err := sc.CheckNamedValue(&driver.NamedValue{Value: loopValuer{}}); err == nil {
+		t.Error("a Valuer returning a Valuer should fail")
+	}
+}
+
+func TestBindRawMessage(t *testing.T) {
+	raw := json.RawMessage(`{"a":[1,2]}`)
+	if typ := goTypeToSnowflake(raw, "TIMESTAMP_NTZ"); typ != "VARIANT" {
+		t.Fatalf("json.RawMessage should be bound as VARIANT. got: %v", typ)
+	}
+	if s, err := valueToString(raw, "TIMESTAMP_NTZ"); err != nil || *s != string(raw) {
+		t.Fatalf("json.RawMessage should be bound as is. got: %v, err: %v", s, err)
+	}
+	if s, err := valueToString(json.RawMessage(nil), "TIMESTAMP_NTZ"); err != nil || s != nil {
+		t.Fatalf("nil json.RawMessage should be NULL. got: %v, err: %v", s, err)
+	}
+	typ, arr, err := arrayToString([]json.RawMessage{raw, nil}, "TIMESTAMP_NTZ")
+	if err != nil || typ != "VARIANT" || *arr[0] != string(raw) || arr[1] != nil {
+		t.Fatalf("unexpected array binding: %v %v, err: %v", typ, arr, err)
+	}
+	typ, arr, err = arrayToString([]sql.NullInt64{{Int64: 3, Valid: true}, {}}, "TIMESTAMP_NTZ")
+	if err != nil || typ != "FIXED" || *arr[0] != "3" || arr[1] != nil {
+		t.Fatalf("the Valuer elements should be bound by their values: %v %v, err: %v", typ, arr, err)
+	}
 }
 
 func TestArrowToValue(t *testing.T) {
@@ -554,3 +717,208 @@ func TestArrowToValue(t *testing.T) {
 
 	}
 }
+
+// scaledString formats v in units of 10^-scale the way the JSON result format does.
+func scaledString(v *big.Int, scale int) string {
+	digits := new(big.Int).Abs(v).String()
+	if scale > 0 {
+		if len(digits) <= scale {
+			digits = strings.Repeat("0", scale-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+	}
+	if v.Sign() < 0 {
+		digits = "-" + digits
+	}
+	return digits
+}
+
+// arrowJSONParity converts values in both the Arrow and JSON result formats and checks that they are the same.
+type arrowJSONParity struct {
+	t    testing.TB
+	pool memory.Allocator
+}
+
+var (
+	parityTimestampStruct = arrow.StructOf(
+		arrow.Field{Name: "epoch", Type: &arrow.Int64Type{}},
+		arrow.Field{Name: "fraction", Type: &arrow.Int32Type{}})
+	parityTimezoneStruct = arrow.StructOf(
+		arrow.Field{Name: "epoch", Type: &arrow.Int64Type{}},
+		arrow.Field{Name: "timezone", Type: &arrow.Int32Type{}})
+)
+
+func (p *arrowJSONParity) convert(meta execResponseRowType, arr array.Interface, jsonValue string) (snowflakeValue, driver.Value) {
+	defer arr.Release()
+	dest := make([]snowflakeValue, 1)
+	if err := arrowToValue(&dest, meta, arr); err != nil {
+		p.t.Fatalf("failed to convert arrow %v. err: %v", meta.Type, err)
+	}
+	var v driver.Value
+	if err := stringToValue(&v, meta, &jsonValue); err != nil {
+		p.t.Fatalf("failed to convert JSON %v %v. err: %v", meta.Type, jsonValue, err)
+	}
+	return dest[0], v
+}
+
+// sameNumber checks that the Arrow value is exactly the number of the JSON text. A value with a scale is the
+// big.Float nearest to the number, whose digits up to the scale are the number.
+func (p *arrowJSONParity) sameNumber(meta execResponseRowType, a snowflakeValue, j driver.Value) {
+	exact, ok := new(big.Rat).SetString(j.(string))
+	if !ok {
+		p.t.Fatalf("%v(%v): JSON %v is not a number", meta.Type, meta.Scale, j)
+	}
+	var got *big.Rat
+	switch x := a.(type) {
+	case *big.Int:
+		got = new(big.Rat).SetInt(x)
+	case int64:
+		got = new(big.Rat).SetInt64(x)
+	case *big.Float:
+		if x.Cmp(ratToBigFloat(exact)) != 0 {
+			p.t.Fatalf("%v(%v): arrow %v is not the nearest float of JSON %v", meta.Type, meta.Scale, x, j)
+		}
+		got, _ = new(big.Rat).SetString(x.Text('f', int(meta.Scale)))
+	default:
+		p.t.Fatalf("%v(%v): unexpected arrow value %T", meta.Type, meta.Scale, a)
+	}
+	if got.Cmp(exact) != 0 {
+		p.t.Fatalf("%v(%v): arrow %v != JSON %v", meta.Type, meta.Scale, got.RatString(), j)
+	}
+}
+
+func (p *arrowJSONParity) sameTime(meta execResponseRowType, jsonValue string, a snowflakeValue, j driver.Value) {
+	at, jt := a.(time.Time), j.(time.Time)
+	_, aOffset := at.Zone()
+	_, jOffset := jt.Zone()
+	if !at.Equal(jt) || aOffset != jOffset {
+		p.t.Fatalf("%v(%v) %v: arrow %v != JSON %v", meta.Type, meta.Scale, jsonValue, at, jt)
+	}
+}
+
+// decimal checks a NUMBER(38, scale) as a decimal.
+func (p *arrowJSONParity) decimal(num *big.Int, scale int) {
+	meta := execResponseRowType{Type: "fixed", Scale: int64(scale)}
+	b := array.NewDecimal128Builder(p.pool, &arrow.Decimal128Type{Precision: 38, Scale: int32(scale)})
+	b.Append(bigIntToDecimal(num))
+	a, j := p.convert(meta, b.NewArray(), scaledString(num, scale))
+	p.sameNumber(meta, a, j)
+}
+
+// int64Number checks a NUMBER(18, scale) as an INT64.
+func (p *arrowJSONParity) int64Number(n int64, scale int) {
+	meta := execResponseRowType{Type: "fixed", Scale: int64(scale)}
+	b := array.NewInt64Builder(p.pool)
+	b.Append(n)
+	a, j := p.convert(meta, b.NewArray(), scaledString(big.NewInt(n), scale))
+	p.sameNumber(meta, a, j)
+}
+
+// timestamp checks a TIMESTAMP_NTZ and a TIMESTAMP_LTZ of the nanoseconds since the epoch as an INT64 and as a
+// struct.
+func (p *arrowJSONParity) timestamp(nanos int64, scale int) {
+	v := nanos / int64(math.Pow10(9-scale))
+	jsonValue := scaledString(big.NewInt(v), scale)
+	for _, typ := range []string{"timestamp_ntz", "timestamp_ltz"} {
+		meta := execResponseRowType{Type: typ, Scale: int64(scale)}
+		ib := array.NewInt64Builder(p.pool)
+		ib.Append(v)
+		a, j := p.convert(meta, ib.NewArray(), jsonValue)
+		p.sameTime(meta, jsonValue, a, j)
+
+		sec, nsec := scaledToEpoch(v, int64(scale))
+		sb := array.NewStructBuilder(p.pool, parityTimestampStruct)
+		sb.Append(true)
+		sb.FieldBuilder(0).(*array.Int64Builder).Append(sec)
+		sb.FieldBuilder(1).(*array.Int32Builder).Append(int32(nsec))
+		a, j = p.convert(meta, sb.NewArray(), jsonValue)
+		p.sameTime(meta, jsonValue, a, j)
+	}
+}
+
+// timestampTZ checks a TIMESTAMP_TZ as a struct of the epoch and the time zone, which is the offset in minutes
+// plus 1440.
+func (p *arrowJSONParity) timestampTZ(nanos int64, scale int, tz int) {
+	v := nanos / int64(math.Pow10(9-scale))
+	meta := execResponseRowType{Type: "timestamp_tz", Scale: int64(scale)}
+	jsonValue := scaledString(big.NewInt(v), scale) + " " + fmt.Sprint(tz)
+	sb := array.NewStructBuilder(p.pool, parityTimezoneStruct)
+	sb.Append(true)
+	sb.FieldBuilder(0).(*array.Int64Builder).Append(v)
+	sb.FieldBuilder(1).(*array.Int32Builder).Append(int32(tz))
+	a, j := p.convert(meta, sb.NewArray(), jsonValue)
+	p.sameTime(meta, jsonValue, a, j)
+}
+
+// timeOfDay checks a TIME of v units of 10^-scale seconds since midnight as an INT64.
+func (p *arrowJSONParity) timeOfDay(v int64, scale int) {
+	meta := execResponseRowType{Type: "time", Scale: int64(scale)}
+	jsonValue := scaledString(big.NewInt(v), scale)
+	ib := array.NewInt64Builder(p.pool)
+	ib.Append(v)
+	a, j := p.convert(meta, ib.NewArray(), jsonValue)
+	p.sameTime(meta, jsonValue, a, j)
+}
+
+// TestArrowJSONParity converts random values in both the Arrow and JSON result formats and checks that they
+// are the same. The seed is logged to reproduce a failure with SF_PARITY_SEED.
+func TestArrowJSONParity(t *testing.T) {
+	seed := time.Now().UnixNano()
+	if s := os.Getenv("SF_PARITY_SEED"); s != "" {
+		var err error
+		if seed, err = strconv.ParseInt(s, 10, 64); err != nil {
+			t.Fatalf("invalid SF_PARITY_SEED: %v", s)
+		}
+	}
+	t.Logf("seed: %v", seed)
+	r := rand.New(rand.NewSource(seed))
+	p := &arrowJSONParity{t: t, pool: memory.NewGoAllocator()}
+	maxNumber := new(big.Int).Exp(big.NewInt(10), big.NewInt(38), nil)
+	for i := 0; i < 1000; i++ {
+		num := new(big.Int).Rand(r, maxNumber)
+		if r.Intn(2) == 0 {
+			num.Neg(num)
+		}
+		p.decimal(num, r.Intn(38))
+		p.int64Number(r.Int63n(1e18)-r.Int63n(1e18), r.Intn(19))
+		nanos := r.Int63n(2e18) - 1e18
+		p.timestamp(nanos, r.Intn(10))
+		p.timestampTZ(nanos, r.Intn(4), r.Intn(2881))
+		scale := r.Intn(10)
+		p.timeOfDay(r.Int63n(86400*int64(math.Pow10(scale))), scale)
+	}
+}
+
+func TestArrowToRawValue(t *testing.T) {
+	pool := memory.NewGoAllocator()
+	num, _ := stringFloatToDecimal("1.50", 2)
+	db := array.NewDecimal128Builder(pool, &arrow.Decimal128Type{Precision: 38, Scale: 2})
+	db.Append(num)
+	db.AppendNull()
+	sb := array.NewStructBuilder(pool, arrow.StructOf(
+		arrow.Field{Name: "epoch", Type: &arrow.Int64Type{}},
+		arrow.Field{Name: "fraction", Type: &arrow.Int32Type{}}))
+	sb.Append(true)
+	sb.FieldBuilder(0).(*array.Int64Builder).Append(1600000000)
+	sb.FieldBuilder(1).(*array.Int32Builder).Append(123)
+	sb.Append(true)
+	sb.FieldBuilder(0).(*array.Int64Builder).Append(0)
+	sb.FieldBuilder(1).(*array.Int32Builder).Append(0)
+
+	for _, tc := range []struct {
+		arr      array.Interface
+		expected []snowflakeValue
+	}{
+		{db.NewArray(), []snowflakeValue{num, nil}},
+		{sb.NewArray(), []snowflakeValue{[]interface{}{int64(1600000000), int32(123)}, []interface{}{int64(0), int32(0)}}},
+	} {
+		dest := make([]snowflakeValue, 2)
+		if err := arrowToRawValue(&dest, tc.arr); err != nil {
+			t.Fatalf("failed to copy the values. err: %v", err)
+		}
+		if !reflect.DeepEqual(dest, tc.expected) {
+			t.Fatalf("unexpected values. expected: %#v, got: %#v", tc.expected, dest)
+		}
+		tc.arr.Release()
+	}
+}
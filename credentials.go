@@ -0,0 +1,74 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"crypto/rsa"
+)
+
+// Credentials are the secrets used to log in. Empty fields leave the ones in the Config as they are.
+type Credentials struct {
+	Password   string
+	Token      string
+	PrivateKey *rsa.PrivateKey
+}
+
+// CredentialsProvider supplies the credentials at connect time, e.g., from a secret manager, so that they
+// don't have to be in the DSN or the Config.
+type CredentialsProvider interface {
+	// Credentials returns the credentials used for the next login.
+	Credentials(ctx context.Context) (*Credentials, error)
+	// Refresh is called when the login failed with the credentials, e.g., because they were rotated.
+	// The login is retried once with the credentials returned after Refresh.
+	Refresh(ctx context.Context) error
+}
+
+func applyCredentials(ctx context.Context, cfg *Config) error {
+	creds, err := cfg.CredentialsProvider.Credentials(ctx)
+	if err != nil {
+		return err
+	}
+	if creds == nil {
+		return nil
+	}
+	if creds.Password != "" {
+		cfg.Password = creds.Password
+	}
+	if creds.Token != "" {
+		cfg.Token = creds.Token
+	}
+	if creds.PrivateKey != nil {
+		cfg.PrivateKey = creds.PrivateKey
+	}
+	return nil
+}
+
+// isLoginRejected returns true if the server rejected the credentials, including an expired password.
+func isLoginRejected(err error) bool {
+	e, ok := asSnowflakeError(err)
+	return ok && e.SQLState == SQLStateConnectionRejected
+}
+
+// authenticateWithCredentials logs in with the credentials from the CredentialsProvider if any, and retries
+// once with refreshed credentials if the login is rejected.
+func authenticateWithCredentials(ctx context.Context, sc *snowflakeConn) (*authResponseMain, error) {
+	if sc.cfg.CredentialsProvider == nil {
+		return sc.login(ctx)
+	}
+	if err := applyCredentials(ctx, sc.cfg); err != nil {
+		return nil, err
+	}
+	authData, err := sc.login(ctx)
+	if err == nil || !isLoginRejected(err) {
+		return authData, err
+	}
+	glog.V(1).Infof("login was rejected. refreshing the credentials. err: %v", err)
+	if err = sc.cfg.CredentialsProvider.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	if err = applyCredentials(ctx, sc.cfg); err != nil {
+		return nil, err
+	}
+	return sc.login(ctx)
+}
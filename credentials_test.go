@@ -0,0 +1,93 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type testCredentialsProvider struct {
+	passwords []string
+	refreshed int
+}
+
+func (p *testCredentialsProvider) Credentials(_ context.Context) (*Credentials, error) {
+	return &Credentials{Password: p.passwords[p.refreshed]}, nil
+}
+
+func (p *testCredentialsProvider) Refresh(_ context.Context) error {
+	if p.refreshed+1 >= len(p.passwords) {
+		return errors.New("no more passwords")
+	}
+	p.refreshed++
+	return nil
+}
+
+func postAuthCheckRotatedPassword(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, jsonBody []byte, _ time.Duration) (*authResponse, error) {
+	var ar authRequest
+	if err := json.Unmarshal(jsonBody, &ar); err != nil {
+		return nil, err
+	}
+	if ar.Data.Password != "rotated" {
+		return &authResponse{
+			Success: false,
+			Code:    "390100",
+			Message: "Incorrect username or password was specified.",
+		}, nil
+	}
+	return &authResponse{
+		Success: true,
+		Data: authResponseMain{
+			Token:       "t",
+			MasterToken: "m",
+		},
+	}, nil
+}
+
+func TestAuthenticateWithCredentialsProvider(t *testing.T) {
+	sc := getDefaultSnowflakeConn()
+	sc.cfg.Password = ""
+	provider := &testCredentialsProvider{passwords: []string{"expired", "rotated"}}
+	sc.cfg.CredentialsProvider = provider
+	sc.rest = &snowflakeRestful{
+		FuncPostAuth: postAuthCheckRotatedPassword,
+	}
+	if _, err := authenticateWithCredentials(context.Background(), sc); err != nil {
+		t.Fatalf("failed to authenticate. err: %v", err)
+	}
+	if provider.refreshed != 1 || sc.cfg.Password != "rotated" {
+		t.Fatalf("the credentials should have been refreshed once. refreshed: %v", provider.refreshed)
+	}
+
+	sc.cfg.CredentialsProvider = &testCredentialsProvider{passwords: []string{"expired", "expired"}}
+	_, err := authenticateWithCredentials(context.Background(), sc)
+	if driverErr, ok := err.(*SnowflakeError); !ok || driverErr.Number != 390100 {
+		t.Fatalf("should have failed to authenticate. err: %v", err)
+	}
+}
+
+func TestFillMissingConfigParametersWithCredentialsProvider(t *testing.T) {
+	cfg := &Config{Account: "a", User: "u"}
+	if err := fillMissingConfigParameters(cfg); err != ErrEmptyPassword {
+		t.Fatalf("should have failed with ErrEmptyPassword. err: %v", err)
+	}
+	cfg.CredentialsProvider = &testCredentialsProvider{passwords: []string{"p"}}
+	if err := fillMissingConfigParameters(cfg); err != nil {
+		t.Fatalf("the password should not be required with a credentials provider. err: %v", err)
+	}
+}
+
+func TestConnectorInvalidConfig(t *testing.T) {
+	connector := NewConnector(SnowflakeDriver{}, Config{User: "u", Password: "p"})
+	if _, ok := connector.Driver().(SnowflakeDriver); !ok {
+		t.Fatal("unexpected driver")
+	}
+	if _, err := connector.Connect(context.Background()); err != ErrEmptyAccount {
+		t.Fatalf("should have failed with ErrEmptyAccount. err: %v", err)
+	}
+}
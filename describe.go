@@ -0,0 +1,106 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// statementDescription is the metadata of a statement described by Snowflake without running it.
+type statementDescription struct {
+	numberOfBinds int
+}
+
+// describeCache is an LRU cache of the statement descriptions of a connection, keyed by the query text. The
+// descriptions depend on the session context, so the cache is purged when the database, the schema or the role
+// of the session changes.
+type describeCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	lru     *list.List
+	// the session context the descriptions were made in
+	database string
+	schema   string
+	role     string
+}
+
+type describeCacheEntry struct {
+	query string
+	desc  *statementDescription
+}
+
+func newDescribeCache(size int) *describeCache {
+	return &describeCache{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+func (dc *describeCache) get(query string) (*statementDescription, bool) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	elem, ok := dc.entries[query]
+	if !ok {
+		return nil, false
+	}
+	dc.lru.MoveToFront(elem)
+	return elem.Value.(*describeCacheEntry).desc, true
+}
+
+func (dc *describeCache) add(query string, desc *statementDescription) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	if elem, ok := dc.entries[query]; ok {
+		elem.Value.(*describeCacheEntry).desc = desc
+		dc.lru.MoveToFront(elem)
+		return
+	}
+	dc.entries[query] = dc.lru.PushFront(&describeCacheEntry{query: query, desc: desc})
+	for dc.lru.Len() > dc.size {
+		oldest := dc.lru.Back()
+		dc.lru.Remove(oldest)
+		delete(dc.entries, oldest.Value.(*describeCacheEntry).query)
+	}
+}
+
+// setSessionContext purges the cache if the session context differs from the one the cached descriptions were
+// made in.
+func (dc *describeCache) setSessionContext(database, schema, role string) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	if database == dc.database && schema == dc.schema && role == dc.role {
+		return
+	}
+	if dc.lru.Len() > 0 {
+		glog.V(2).Infof("session context changed. purging %v statement descriptions", dc.lru.Len())
+	}
+	dc.database, dc.schema, dc.role = database, schema, role
+	dc.entries = make(map[string]*list.Element)
+	dc.lru.Init()
+}
+
+// describe returns the description of the query, from the cache of the connection if it was described in the
+// same session context before.
+func (sc *snowflakeConn) describe(ctx context.Context, query string) (*statementDescription, error) {
+	if sc.describeCache == nil {
+		sc.describeCache = newDescribeCache(sc.cfg.DescribeCacheSize)
+	}
+	sc.describeCache.setSessionContext(sc.cfg.Database, sc.cfg.Schema, sc.cfg.Role)
+	if desc, ok := sc.describeCache.get(query); ok {
+		glog.V(2).Infof("statement description cache hit: %v", query)
+		return desc, nil
+	}
+	data, err := sc.exec(context.WithValue(ctx, describeOnly, true), query, false, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	desc := &statementDescription{numberOfBinds: data.Data.NumberOfBinds}
+	// the first response of the session sets the session context
+	sc.describeCache.setSessionContext(sc.cfg.Database, sc.cfg.Schema, sc.cfg.Role)
+	sc.describeCache.add(query, desc)
+	return desc, nil
+}
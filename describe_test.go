@@ -0,0 +1,77 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestDescribeCache(t *testing.T) {
+	var described []string
+	schema := "S1"
+	sc := &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}, DescribeCacheSize: 2},
+		rest: &snowflakeRestful{
+			FuncPostQuery: func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte, _ time.Duration, _ *uuid.UUID) (*execResponse, error) {
+				var req execRequest
+				if err := json.Unmarshal(body, &req); err != nil {
+					return nil, err
+				}
+				if req.SQLText == "USE SCHEMA S2" {
+					schema = "S2"
+				}
+				if req.DescribeOnly {
+					described = append(described, req.SQLText)
+				}
+				return &execResponse{
+					Data:    execResponseData{QueryID: "q", NumberOfBinds: 2, FinalSchemaName: schema},
+					Code:    "0",
+					Success: true,
+				}, nil
+			},
+		},
+	}
+	ctx := context.Background()
+	prepare := func(query string) {
+		stmt, err := sc.PrepareContext(ctx, query)
+		if err != nil {
+			t.Fatalf("failed to prepare. err: %v", err)
+		}
+		if n := stmt.NumInput(); n != 2 {
+			t.Fatalf("unexpected number of inputs: %v", n)
+		}
+	}
+	prepare("SELECT ?, ?")
+	prepare("SELECT ?, ?")
+	if len(described) != 1 {
+		t.Fatalf("the description should have been cached. described: %v", described)
+	}
+	prepare("SELECT 2, ?, ?")
+	prepare("SELECT 3, ?, ?")
+	prepare("SELECT ?, ?")
+	if len(described) != 4 {
+		t.Fatalf("the least recently used description should have been evicted. described: %v", described)
+	}
+	if _, err := sc.ExecContext(ctx, "USE SCHEMA S2", nil); err != nil {
+		t.Fatal(err)
+	}
+	prepare("SELECT ?, ?")
+	if len(described) != 5 {
+		t.Fatalf("the cache should have been purged on the schema change. described: %v", described)
+	}
+
+	sc.cfg.DescribeCacheSize = 0
+	stmt, err := sc.PrepareContext(ctx, "SELECT ?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := stmt.NumInput(); n != -1 || len(described) != 5 {
+		t.Fatalf("the statement should not be described. inputs: %v, described: %v", n, described)
+	}
+}
@@ -32,6 +32,11 @@ The Go Snowflake Driver supports the following connection syntaxes (or data sour
 
 where all parameters must be escaped or use `Config` and `DSN` to construct a DSN string.
 
+The username and password end at the last '@', so a password may include '@', ':', '/' and '?' as is. They may
+also be percent-encoded, e.g., %40 for '@', in which case a '+' must be encoded as %2B. A password that is not a
+valid encoding, e.g., one including '%' not followed by two hexadecimal digits, is taken as is. An IPv6 host must
+be enclosed in square brackets, e.g., [::1]:8080. If a parameter is repeated, the last value is taken.
+
 The following example opens a database handle with the Snowflake account
 myaccount where the username is jsmith, password is mypassword, database is
 mydb, schema is testschema, and warehouse is mywh:
@@ -77,7 +82,8 @@ The following connection parameters are supported:
 	* role: Specifies the role to use by default for accessing Snowflake
 		objects in the client session (can be changed after login).
 
-	* passcode: Specifies the passcode provided by Duo when using multi-factor authentication (MFA) for login.
+	* passcode: Specifies the passcode provided by Duo, or by the TOTP factor of Okta, when using multi-factor
+		authentication (MFA) for login.
 
 	* passcodeInPassword: false by default. Set to true if the MFA passcode is
 		embedded in the login password. Appends the MFA passcode to the end of the
@@ -87,34 +93,125 @@ The following connection parameters are supported:
 		is 60 seconds. The login request gives up after the timeout length if the
 		HTTP response is success.
 
+	* loginAttemptTimeout: Specifies the timeout, in seconds, of the first login attempt. A login attempt
+		that times out, e.g., because the TLS handshake and OCSP check are slow, is retried with the timeout
+		doubled as long as loginTimeout allows. If every attempt times out, the login fails with a
+		*LoginTimeoutError that has the timing of each attempt. Not set by default.
+
 	* authenticator: Specifies the authenticator to use for authenticating user credentials:
 		- To use the internal Snowflake authenticator, specify snowflake (Default).
 		- To authenticate through Okta, specify https://<okta_account_name>.okta.com (URL prefix for Okta).
+		  If Okta requires MFA, the driver verifies the TOTP factor with the passcode parameter, or else sends
+		  an Okta Verify push and waits for the user to approve it.
 		- To authenticate using your IDP via a browser, specify externalbrowser.
 		- To authenticate via OAuth, specify oauth and provide an OAuth Access Token (see the token parameter below).
+		- To authenticate via External OAuth with an Azure AD token acquired from the managed identity or the
+		  workload identity of the environment, specify azure_ad and provide the azureADResource parameter.
+
+	* azureADResource: The application ID URI of the Azure AD application of the External OAuth security
+		integration, e.g., api://<application_id>. Required for the azure_ad authenticator.
 
-	* application: Identifies your application to Snowflake Support.
+	* azureADClientID: Optional. The client ID of the user-assigned managed identity or the workload identity.
+		Defaults to the system-assigned managed identity or AZURE_CLIENT_ID.
+
+	* application: Identifies your application to Snowflake Support. The name is also sent in the User-Agent
+	  header and in the client environment at login so that queries can be attributed to the application.
 
 	* insecureMode: false by default. Set to true to bypass the Online
 		Certificate Status Protocol (OCSP) certificate revocation check.
 		IMPORTANT: Change the default value for testing or emergency situations only.
+		insecureModeOCSP is the same parameter under the name of the other Snowflake drivers. Setting both to
+		different values fails with ErrCodeConflictingParameters.
 
 	* token: a token that can be used to authenticate. Should be used in conjunction with the "oauth" authenticator.
 
 	* client_session_keep_alive: Set to true have a heartbeat in the background every hour to keep the connection alive
 		such that the connection session will never expire. Care should be taken in using this option as it opens up
 		the access forever as long as the process is alive.
+		The parameter set for the user or the account is honored as well, as returned by Snowflake at login. The
+		interval of the heartbeats is CLIENT_SESSION_KEEP_ALIVE_HEARTBEAT_FREQUENCY, from 900 to 3600 seconds,
+		when set either way. Changing either parameter by ALTER SESSION starts, restarts or stops the heartbeat.
+
+	* disableHeartbeat: false by default. Set to true to never heartbeat the session, even if
+		client_session_keep_alive is set for the user or the account. Setting client_session_keep_alive=true in the
+		same DSN fails with ErrCodeConflictingParameters.
+
+	* disableTelemetry: false by default. Set to true to set CLIENT_TELEMETRY_ENABLED to false for the session.
+		Setting client_telemetry_enabled=true in the same DSN fails with ErrCodeConflictingParameters.
 
 	* ocspFailOpen: true by default. Set to false to make OCSP check fail closed mode.
 
 	* validateDefaultParameters: true by default. Set to false to disable checks on existence and privileges check for
 								 Database, Schema, Warehouse and Role when setting up the connection
 
+	* maxIdleConns: Specifies the maximum number of idle HTTP connections kept across all Snowflake endpoints.
+
+	* maxIdleConnsPerHost: Specifies the maximum number of idle HTTP connections kept per endpoint.
+
+	* maxConnsPerHost: Specifies the maximum number of HTTP connections per endpoint, including those in use.
+		0 means no limit.
+
+	* idleConnTimeout: Specifies the timeout, in seconds, after which an idle HTTP connection is closed.
+
+	* enableHTTP2: false by default. Set to true to attempt HTTP/2 with the Snowflake endpoints.
+		The connections opened with the same connection pool parameters share one HTTP transport and its
+		pool of idle connections.
+
+	* params: Specifies many session parameters at once as a JSON object, URL-escaped or base64 encoded,
+		e.g., {"TIMEZONE":"UTC","STATEMENT_TIMEOUT_IN_SECONDS":60}. A session parameter given by its own
+		parameter after params overrides the value in params.
+
+	* autoResumeWarehouse: false by default. Set to true to resume the warehouse and retry the statement once if
+		it fails because the warehouse is suspended and doesn't resume automatically.
+
+	* maxQueryRetries: 0 by default. Specifies the number of times a query that fails because of an internal
+		error of Snowflake is re-submitted. See Retrying Queries below.
+
+	* requestRateLimit: Specifies the average number of query submissions and result polls per second of a
+		connection, allowing a second's worth of requests at once. Not limited by default. See Rate Limiting below.
+
+	* failoverURLs: Specifies a comma separated list of account URLs to log in to, in order, if the account URL
+		of the DSN is unreachable. See Multi-Region Failover below.
+
+	* fetchOnly: false by default. Set to true to open a connection that can only fetch results by query ID
+		(see WithFetchResultByID). No database, schema or warehouse is set up at login, so the connection doesn't
+		resume a warehouse.
+
+	* maxInlineBindSize: Specifies the largest binding, in bytes, inlined in a query request. Not set by default.
+		See Large Bindings below.
+
+	* maxRequestBodySize: Specifies the largest query request, in bytes. Not set by default. See Large Bindings below.
+
+	* pingMode: query by default. Specifies how Ping checks a connection: query runs SELECT 1, heartbeat sends a
+		heartbeat of the session, which needs no warehouse, and none sends nothing. See Pinging Connections below.
+
+	* pingTimeout: Specifies the timeout of Ping in seconds, apart from the deadline of its context. Not set by
+		default.
+
+	* abortOnClose: false by default. Set to true to abort the queries in flight when a connection is closed. See
+		Aborting Queries below.
+
+	* describeCacheSize: Specifies the number of statement descriptions cached per connection. Not set by default,
+		so statements are not described when they are prepared. See Describing Prepared Statements below.
+
+	* resultSpillDir: Specifies the directory to spill the downloaded result chunks to. Not set by default, so the
+		chunks are kept in memory. See Spilling Results to Disk below.
+
+	* resultSpillMaxBytes: Specifies the limit of the bytes spilled by a result at once. Not limited by default.
+
 All other parameters are interpreted as session parameters (https://docs.snowflake.com/en/sql-reference/parameters.html).
 For example, the TIMESTAMP_OUTPUT_FORMAT session parameter can be set by adding:
 
 	...&TIMESTAMP_OUTPUT_FORMAT=MM-DD-YYYY...
 
+The names of session parameters are case-insensitive. The values of commonly used session parameters, e.g.,
+CLIENT_SESSION_KEEP_ALIVE and STATEMENT_TIMEOUT_IN_SECONDS, are validated when the connection string is parsed.
+When the Config is built in code, these parameters can be set with the typed Config.SessionParams instead of
+Config.Params.
+
+Config.SessionParameterListener is notified when a response from Snowflake changes a session parameter of a
+connection, e.g., after ALTER SESSION SET TIMEZONE, so that connection wrappers can adjust to the new value.
+
 A complete connection string looks similar to the following:
 
 	my_user_name:my_password@ac123456/my_database/my_schema?my_warehouse=inventory_warehouse&role=my_user_role&DATE_OUTPUT_FORMAT=YYYY-MM-DD
@@ -271,6 +368,17 @@ Footnotes:
 
 Note: SQL NULL values are converted to Golang nil values, and vice-versa.
 
+In the Arrow data format, FIXED columns with a scale are returned as *big.Float with 128 bits of precision, so
+that formatting the value with the scale of the column, e.g., f.Text('f', 2) for NUMBER(38, 2), gives back the
+exact number in Snowflake. FIXED columns that don't fit in int64 are returned as *big.Int. Times and timestamps
+keep their nanoseconds and time zones, and are the same as in the JSON data format.
+
+TIME values are returned as time.Time on January 1, year 1, UTC by default. A context created by WithTimeFormat
+returns them as time.Time on January 1, 1970, UTC with TimeFormatEpochDate, or as time.Duration since midnight
+with TimeFormatDuration, with the nanoseconds kept in both data formats:
+
+	rows, err := db.QueryContext(sf.WithTimeFormat(ctx, sf.TimeFormatDuration), "SELECT start_time FROM shifts")
+
 Binding Parameters to Array Variables For Batch Inserts
 
 Version 1.3.9 (and later) of the Go Snowflake Driver supports the ability to bind an array variable to a parameter in an SQL
@@ -291,9 +399,51 @@ binds arrays to the parameters in the INSERT statement.
 	// Insert the data from the arrays into the table.
 	_, err = db.Exec("insert into my_table values (?, ?, ?, ?)", intArray, fltArray, boolArray, strArray)
 
+Slices of other element types are also bound as arrays: the integer and float types, time.Time, which is bound
+in the timestamp mode of the statement, and pointers, e.g., []*int64, whose nil elements are bound as NULL. The
+elements of []interface{} must be of the same type or nil. Nested slices and maps are bound as JSON text, which
+PARSE_JSON turns into ARRAY, OBJECT or VARIANT values:
+
+	ids := []*int64{&id1, nil, &id2}
+	tags := [][]string{{"a", "b"}, nil, {"c"}}
+	_, err = db.Exec("insert into events(id, tags_json) values (?, ?)", ids, tags)
+
 Note: For alternative ways to load data into the Snowflake database (including bulk loading using the COPY command), see
 Loading Data Into Snowflake (https://docs.snowflake.com/en/user-guide-data-load.html).
 
+Binding Structs and Maps to OBJECT Values
+
+A struct other than time.Time, a map, or a pointer to either of them is marshaled with encoding/json and bound as
+an OBJECT, so that semi-structured values are written without assembling JSON strings for PARSE_JSON. A value that
+implements driver.Valuer is converted by its Value method instead:
+
+	type Address struct {
+		City string `json:"city"`
+		Zip  string `json:"zip"`
+	}
+	_, err = db.Exec("insert into customers(id, address) select ?, ?", 1, Address{City: "San Mateo", Zip: "94401"})
+
+A json.RawMessage is bound as is as a VARIANT, for JSON that is already encoded:
+
+	_, err = db.Exec("insert into events(payload) select ?", json.RawMessage(body))
+
+The value of a driver.Valuer is bound as any other value, so that a Valuer may return a json.RawMessage, an array
+or a struct, and the elements of an array binding that implement driver.Valuer, e.g., sql.NullInt64, are bound by
+their values.
+
+Large Bindings
+
+Bindings are inlined in the query requests, whose buffers are sized for the bindings up front, so that multi-MB
+strings and []byte values are encoded without reallocations. Snowflake limits the size of a request, so
+Config.MaxInlineBindSize (maxInlineBindSize in the DSN) can cap the size of a binding. If a binding is larger, the
+bindings of the query are uploaded instead as a CSV file to the temporary stage SYSTEM$BIND of the session, as with
+PutFiles, and the query binds from the file, with a row per element of the array bindings. Snowflake binds from a
+stage for the INSERT statements.
+
+Likewise, Config.MaxRequestBodySize (maxRequestBodySize in the DSN) caps the size of a whole query request. A
+larger request fails with the code ErrCodeRequestTooLarge before it is sent. A request rejected by Snowflake with
+HTTP 413 is not retried and fails with the same code.
+
 Binding a Parameter to a Time Type
 
 Go's database/sql package supports the ability to bind a parameter in an SQL statement to a time.Time variable.
@@ -346,6 +496,37 @@ example, sf is an alias for the gosnowflake package:
 	var b = []byte{0x01, 0x02, 0x03}
 	_, err = stmt.Exec(sf.DataTypeBinary, b)
 
+NULL Values
+
+The NULL values of the results are always nil, and an empty string is "", so that NULL is told from the empty
+string by scanning a nullable column into sql.NullString, a pointer or interface{}. database/sql fails to scan
+NULL into a string rather than returning "":
+
+	var name sql.NullString
+	err = rows.Scan(&name)
+	if !name.Valid {
+		// NULL
+	}
+
+NaN and Infinity
+
+The float values NaN, +Inf and -Inf are bound as 'NaN', 'inf' and '-inf', which Snowflake reads as the special
+FLOAT values, and the special values of the results are scanned into float64 as such. A context created by
+WithRejectNonFiniteFloats makes a query fail with the error code ErrNonFiniteFloat instead, if a bind variable or
+a FLOAT value of the result is one of them:
+
+	rows, err := db.QueryContext(sf.WithRejectNonFiniteFloats(ctx), "SELECT score FROM t WHERE w > ?", weight)
+
+UUIDs
+
+A uuid.UUID of github.com/google/uuid, or a [16]byte, is bound as the text of the UUID, also as an element of an
+array binding. A TEXT column of UUIDs is scanned into a uuid.UUID as it is an sql.Scanner. The result sets of
+QueryResultSet and Client queried with a context created by WithUUIDResults return the values of the named TEXT
+columns that are UUIDs as uuid.UUID instead of string, e.g., for the rows read with ResultSet.NextBatch. database/sql
+always receives the UUIDs as string, as uuid.UUID is not a driver.Value:
+
+	rs, err := client.Query(sf.WithUUIDResults(ctx, "ORDER_ID"), "SELECT * FROM orders")
+
 Maximum number of Result Set Chunk Downloader
 
 The driver directly downloads a result set from the cloud storage if the size is large. It is
@@ -361,6 +542,16 @@ memory footprint by itself. Consider Custom JSON Decoder.
 	)
 	sf.MaxChunkDownloadWorkers = 2
 
+The chunks are decoded concurrently as they are downloaded, while the rows are returned in order. Since the
+decoding is bound by CPU, a chunk is downloaded in full before it is decoded, and MaxChunkDecodeWorkers chunks of
+a result are decoded at once, GOMAXPROCS by default:
+
+	sf.MaxChunkDecodeWorkers = 4
+
+Closing the rows before reading them to the end cancels the chunk downloads in flight and releases the downloaded
+chunks at once, so that leaving a large result early frees its connections and memory without waiting for the
+downloads.
+
 
 Experimental: Custom JSON Decoder for parsing Result Set
 
@@ -505,6 +696,15 @@ update 10 rows. If one UPDATE statement updated 15 rows and the other UPDATE sta
 rows, the total would still be 20. You would see no indication that the UPDATES had not functioned as
 expected.
 
+If any of the statements fails, ExecContext() returns a *MultiStatementError that has an entry for each failed
+statement, with the index of the statement in the query, its query ID and the error:
+
+	if me, ok := err.(*sf.MultiStatementError); ok {
+		for _, ce := range me.Errors {
+			fmt.Printf("statement %v (%v) failed: %v\n", ce.Index, ce.QueryID, ce.Err)
+		}
+	}
+
 
 The ExecContext() function does not return an error if passed a query (e.g. a SELECT statement). However, it
 still returns only a single value, not a result set, so using it to execute queries (or a mix of queries and non-query
@@ -541,11 +741,838 @@ example:
 		Fatalf("failed to query multiple statements: %v", err)
 	}
 
-Preparing statements and using bind variables are also not supported for multi-statement queries.
+Preparing statements and using bind variables are also not supported for multi-statement queries. A multi-statement
+query with bind variables fails with the error code ErrCodeMultiStatementBindings before it is sent to Snowflake,
+because Snowflake doesn't define the statement a binding applies to.
+
+The results of the statements are fetched in parallel, up to MaxChildResultFetchWorkers (8 by default) at a time,
+and are returned in the order of the statements.
+
+
+Fetching Results by Query ID
+
+The result of a query that has already run can be fetched by its query ID, for example, in a worker other than
+the one that issued the query. Pass a context created by WithFetchResultByID to QueryContext; the query text is
+ignored.
+
+	ctx := sf.WithFetchResultByID(context.Background(), queryID)
+	rows, err := db.QueryContext(ctx, "")
+
+If the query is still running, QueryContext polls the result until the query completes, so pass a context with a
+deadline to bound the wait. Both the JSON and Arrow result formats are read, whichever the query produced.
+
+Workers that only fetch results may set the connection parameter fetchOnly=true so that the login doesn't set up
+a database, schema or warehouse. Executing any other statement on such a connection returns an error.
+
+Processing Results a Chunk at a Time
+
+Applications that process large results in batches can read them without the database/sql package. QueryResultSet
+runs a query on a driver connection and returns a ResultSet. In addition to Next and Scan, NextBatch returns the rows
+up to the end of the result chunk currently being read, and io.EOF at the end of the result.
+
+	err = conn.Raw(func(x interface{}) error {
+		rs, err := sf.QueryResultSet(ctx, x.(driver.Conn), "SELECT * FROM big_table")
+		if err != nil {
+			return err
+		}
+		defer rs.Close()
+		for {
+			batch, err := rs.NextBatch()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			process(batch)
+		}
+	})
+
+Client
+
+Client runs statements on one Snowflake session without the database/sql package, for applications that don't
+want a connection pool and need the query IDs and metadata of every statement:
+
+	client, err := sf.NewClient(ctx, cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Close()
+	res, err := client.Exec(ctx, "INSERT INTO t VALUES(?)", 1)
+	qid, err := client.SubmitAsync(ctx, "CALL long_running_procedure()")
+	rs, err := client.FetchResult(ctx, qid) // waits for the query to complete
+	defer rs.Close()
+
+Query and FetchResult return a ResultSet. A Client is not safe for concurrent use. PutFile uploads a file to a
+stage as PutFiles below, and GetFile downloads the files of a stage location to a local directory as GET does,
+directly from the storage of the stage, decrypting them if the stage is client-side encrypted. The files are
+downloaded as they are stored, e.g., gzipped:
+
+	err = client.PutFile(ctx, "/data/orders.csv", "@orders_stage/2020")
+	err = client.GetFile(ctx, "@orders_stage/2020/orders.csv.gz", "/data/downloaded")
+
+PutFiles uploads the files matching glob patterns to a stage with a number of files uploaded at the same time,
+skipping the files the stage already has with the same digest, and returns the result of every file: uploaded,
+skipped or failed with the reason. A pattern that matches no file, and a file with the same name as another one,
+which would overwrite it in the stage, have failed results. The files are uploaded directly to the storage of the
+stage, S3, Azure or GCS, with the temporary credentials of the response of a PUT, and are encrypted with a key of
+their own if the stage is client-side encrypted, as the internal stages are. As with AUTO_COMPRESS = TRUE of PUT, the
+files that are not compressed are gzipped while they are uploaded and get the .gz extension in the stage. The
+compression of every file is detected from its first bytes and its extension unless SourceCompression is given, and
+the files compressed with, e.g., gzip or zstd, and the Parquet and ORC files, are uploaded as they are.
+DisableAutoCompress uploads every file as it is:
+
+	results, err := client.PutFiles(ctx, []string{"/data/orders_*.csv"}, "@orders_stage/2020",
+		&sf.PutFilesOptions{Parallelism: 8})
+	for _, r := range results {
+		fmt.Println(r.Source, r.Status, r.Err)
+	}
+
+The file of a client-side encrypted stage is encrypted with a random key, which is stored with the file wrapped
+with the master key of the stage. Config.FileKeyWrapper wraps the keys of the uploaded files and unwraps those of
+the downloaded ones instead, e.g., with a KMS. Snowflake unwraps the file keys with the master key of the stage, so
+the files whose keys are wrapped otherwise cannot be loaded with COPY, and are only downloaded by GetFile with the
+same wrapper:
+
+	cfg.FileKeyWrapper = kmsWrapper // implements WrapKey and UnwrapKey
+
+ListStage and RemoveStageFiles run LIST and REMOVE on a stage location with a PATTERN regular expression, and
+return the files as StageFile and RemovedStageFile structs:
+
+	files, err := client.ListStage(ctx, "@orders_stage/2020", `.*\.csv\.gz`)
+	for _, f := range files {
+		fmt.Println(f.Name, f.Size, f.LastModified)
+	}
+	removed, err := client.RemoveStageFiles(ctx, "@orders_stage/2020", `.*\.tmp`)
+
+Client.ExportQuery is the fastest way to extract a large result from Snowflake. It unloads the result with COPY INTO
+a temporary stage, and downloads the unloaded files in parallel through presigned URLs, without GET:
+
+	res, err := client.ExportQuery(ctx, "SELECT * FROM big_table", sf.ExportToDir("/data/export"),
+		&sf.UnloadOptions{FileFormat: "TYPE = PARQUET"})
+
+An ExportDestination function may instead open an io.WriteCloser per file, e.g., to upload the files elsewhere.
+
+Client.IngestRecords and Client.IngestRows are the write path the other way around. They write the Arrow records, or
+the rows, read from a channel until it is closed to local Parquet files of up to MaxFileRows rows, upload the files
+to a temporary stage with PutFiles, and load them into the table with COPY INTO, matching the columns of the files
+with those of the table by their names:
+
+	records := make(chan array.Record)
+	go produce(records) // sends the records and closes the channel
+	res, err := client.IngestRecords(ctx, "orders", records, &sf.IngestOptions{CopyOptions: "ON_ERROR = CONTINUE"})
+	fmt.Println(res.QueryID, res.Rows)
+
+The columns of the files have the Parquet types of the Arrow types of the fields, e.g., DATE for Date32 and
+DECIMAL for Decimal128. The values of IngestRows are written as strings, which COPY INTO converts to the types of
+the columns of the table.
+
+A BatchWriter buffers the rows written to a table, e.g., by a streaming consumer, and flushes them when they reach
+MaxRows or MaxBytes, every FlushInterval, and on Close. A flush is a single INSERT with an array binding of every
+column, or a staged COPY INTO as IngestRows from StageRows rows, so a batch is written entirely or not at all. The
+rows of a failed flush stay buffered for the next one, up to MaxRetries times, 3 by default, and the writes retry
+them only after a backoff. The rows are then dropped and handed to OnFlush in BatchFlush.Dropped, so that the buffer
+doesn't grow while Snowflake is unreachable.
+OnFlush and Metrics report the flushes and their errors, and the error of a background flush is returned by the next
+Write:
+
+	w := client.NewBatchWriter("events", []string{"id", "payload"},
+		&sf.BatchWriterOptions{MaxRows: 5000, FlushInterval: 10 * time.Second})
+	for msg := range messages {
+		if err := w.Write(ctx, msg.ID, msg.Payload); err != nil {
+			log.Print(err)
+		}
+	}
+	err = w.Close(ctx)
+
+Running SQL Scripts
+
+Client.ExecScript runs the statements of a SQL script, e.g., a migration in a .sql file, in order. The script is
+read from an io.Reader one statement at a time, so that a script of any size can be run, and is split at the
+semicolons outside of the string literals, the $$ literals, the quoted identifiers and the line and block comments.
+A Snowflake Scripting block must be enclosed in $$. The result of every statement has its position and
+line in the script, its query ID, the number of rows it changed, its duration and its error:
+
+	f, err := os.Open("migrations/0042_orders.sql")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	results, err := client.ExecScript(ctx, f, &sf.ScriptOptions{
+		OnResult: func(r sf.StatementResult) { log.Printf("line %v: %v rows in %v", r.Line, r.RowsAffected, r.Duration) },
+	})
+
+ExecScript stops at the first failure unless ScriptOptions.ContinueOnError is set. With ScriptOptions.BatchSize, the
+statements are run that many at a time in multi-statement queries, which saves round trips but gives every
+statement of a batch the duration of the whole query. If a statement of a batch fails, the others still have their
+query IDs and row counts, unless the query failed as a whole: then the first statement has the error and the others
+are marked NotRun. SplitStatements splits a script the same way without running it.
+
+Schema Migrations
+
+Snowflake has no advisory locks, which the schema migration tools, e.g., golang-migrate or goose, use so that a
+migration runs at most once at a time. Client.NewMigrationLock emulates one with a row of a lock table, which Lock
+creates if it doesn't exist. The lock is held by its owner until Unlock or until its TTL expires, so that the lock of
+a crashed migration is taken over, and Refresh extends it during a long migration. Lock fails with the code
+ErrMigrationLocked if another owner holds the lock past MigrationLockOptions.Timeout, and Refresh and Unlock fail
+with ErrMigrationLockLost if the owner no longer holds it:
+
+	lock := client.NewMigrationLock("migrations.public.schema_lock", "analytics",
+		&sf.MigrationLockOptions{TTL: 30 * time.Minute, Timeout: 5 * time.Minute})
+	if err := lock.Lock(ctx); err != nil {
+		log.Fatal(err)
+	}
+	defer lock.Unlock(ctx)
+	res, err := client.ExecDDL(ctx, "CREATE TABLE IF NOT EXISTS orders (id INT)")
+	fmt.Println(res.QueryID, res.Status) // e.g., ORDERS already exists, statement succeeded.
+
+Client.ExecDDL runs a DDL statement and returns the status message of Snowflake with its query ID and duration.
+
+Exporting Results
+
+ResultSet.WriteCSV and ResultSet.WriteJSON stream the rest of a result set to an io.Writer a chunk at a time, e.g.,
+for CLI tools and export jobs. The values are formatted as Snowflake outputs them, following the output format
+parameters of the session such as TIMESTAMP_OUTPUT_FORMAT, DATE_OUTPUT_FORMAT and BINARY_OUTPUT_FORMAT:
+
+	err = conn.Raw(func(x interface{}) error {
+		rs, err := sf.QueryResultSet(ctx, x.(driver.Conn), "SELECT * FROM big_table")
+		if err != nil {
+			return err
+		}
+		defer rs.Close()
+		_, err = rs.WriteCSV(os.Stdout, &sf.ExportOptions{Header: true})
+		return err
+	})
+
+WriteJSON writes a JSON array of objects keyed by the column names, or JSON Lines with ExportOptions.JSONLines.
+Numbers, booleans and semi-structured data are written as JSON values, and the other types as strings.
+
+Result Download Progress
+
+The download progress of a large result can be observed by passing a context created by WithResultProgress to
+QueryContext. The callback receives the number of rows, chunks and bytes downloaded so far along with the totals
+expected, once when the query returns and once for every result chunk downloaded.
+
+	ctx := sf.WithResultProgress(context.Background(), func(p sf.ResultProgress) {
+		fmt.Printf("%d/%d rows\n", p.RowsDownloaded, p.TotalRows)
+	})
+	rows, err := db.QueryContext(ctx, "SELECT * FROM big_table")
+
+Result Row Counts
+
+The number of rows of a result is known when the query returns, before the rows are read, e.g., for a paginating
+UI to show the size of the result up front. The rows returned by QueryContext implement SnowflakeRows, whose
+TotalRows returns the number of rows of the result set and ChunkRowCounts the number of rows of every chunk:
+
+	err = conn.Raw(func(x interface{}) error {
+		rows, err := x.(driver.QueryerContext).QueryContext(ctx, "SELECT * FROM big_table", nil)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		fmt.Printf("%d rows\n", rows.(sf.SnowflakeRows).TotalRows())
+		...
+	})
+
+Limiting Results
+
+WithMaxResultRows and WithMaxResultBytes protect the application from unexpectedly large results. Once the result
+exceeds the limit, reading the next row returns an error with the code ErrResultTruncated and no further chunk is
+downloaded.
+
+	ctx := sf.WithMaxResultRows(context.Background(), 100000)
+	rows, err := db.QueryContext(ctx, "SELECT * FROM big_table")
+
+Spilling Results to Disk
+
+On memory-constrained hosts, Config.ResultSpillDir (resultSpillDir in the DSN) has the chunk downloader write
+the downloaded chunks of a result to temporary files in the directory as they arrive, and decode each chunk only
+when its rows are read. Only the chunk being read is kept in memory. The file of a chunk is removed once the chunk
+is read, or when the rows are closed.
+
+Config.ResultSpillMaxBytes (resultSpillMaxBytes in the DSN) caps the bytes on disk for a result at once. A chunk
+that doesn't fit fails the download with the code ErrCodeResultSpillLimitExceeded, so allow for about
+MaxChunkDownloadWorkers compressed chunks.
+
+	...&resultSpillDir=%2Fvar%2Ftmp%2Fsnowflake&resultSpillMaxBytes=1073741824...
+
+Raw Results
+
+Tools that re-serialize results, e.g., CSV exporters and proxies, can skip the conversion of the values to Go
+types with a context created by WithRawResults. The queries run with it request the JSON result format and
+return every value as the string on the wire, or nil for NULL:
+
+	rows, err := db.QueryContext(sf.WithRawResults(ctx), "SELECT * FROM big_table")
+
+A result in the Arrow format, e.g., fetched by query ID, returns the Arrow scalars instead. See WithRawResults.
+
+Conversion Errors
+
+If a value of a result cannot be converted to a Go value, Rows.Next returns a *ConversionError with the column
+name, the Snowflake type, the row number and the value, which wraps the error of the conversion:
+
+	var ce *sf.ConversionError
+	if errors.As(err, &ce) {
+		log.Printf("bad value %q in column %v of row %v", ce.Value, ce.Column, ce.Row)
+	}
+
+Scanning a NULL into a non-pointer destination, e.g., an int instead of an *int or sql.NullInt64, fails in
+database/sql with an error that names the column.
+
+ResultSet.Scan of the result sets queried with a context created by WithStrictScan fails with a *ConversionError
+wrapping the error code ErrLossyScan, instead of converting a value with a loss: a NUMBER that overflows the integer
+destination or has a fraction, a negative NUMBER scanned into an unsigned integer, a NUMBER that the float64 or
+float32 doesn't represent exactly, a FLOAT that a float32 doesn't represent exactly, or a date or a time scanned into
+a string. database/sql converts the values into the destinations of Rows.Scan itself, so the strict mode doesn't apply
+to it.
+
+	rs, err := client.Query(sf.WithStrictScan(ctx), "SELECT id, amount FROM orders")
+
+Extra HTTP Headers
+
+Multi-tenant applications can attach extra HTTP headers, e.g., correlation IDs and tenant tags for audit trails,
+to the query requests with a context created by WithHTTPHeaders:
+
+	ctx, err := sf.WithHTTPHeaders(ctx, map[string]string{"X-Correlation-ID": requestID, "X-Tenant-ID": tenant})
+	...
+	rows, err := db.QueryContext(ctx, "SELECT * FROM orders")
+
+Only the headers in an allowlist, i.e., X-Correlation-*, X-Request-*, X-Tenant-*, X-Trace-*, X-Audit-*,
+Traceparent and Tracestate, with printable ASCII values are allowed, so that the headers of the driver and
+Snowflake can't be overridden.
+
+Skipping Bad Chunks
+
+By default, a result chunk that fails to be downloaded or decoded after the retries fails the iteration of the rows.
+For best-effort exports, a context created by WithSkipBadChunks skips such a chunk and continues with the next one.
+The callback is told which chunk was skipped and how many rows were lost:
+
+	ctx := sf.WithSkipBadChunks(context.Background(), func(c sf.SkippedChunk) {
+		log.Printf("skipped %v rows of query %v: %v", c.Rows, c.QueryID, c.Err)
+	})
+	rows, err := db.QueryContext(ctx, "SELECT * FROM big_table")
+
+Canceling the context still stops the iteration.
+
+Client Environment
+
+Config.ClientEnvironment adds entries to the client environment sent at login, for example the name of the team or
+the service running the application. The entries are upper cased and cannot override the ones set by the driver such
+as APPLICATION and OS. The field cannot be given in the DSN. Use a Connector instead.
+
+	cfg := sf.Config{
+		...
+		Application:       "inventory",
+		ClientEnvironment: map[string]string{"SERVICE": "inventory-api"},
+	}
+
+Connection Information
+
+The session information returned at login, such as the session ID and the server version, is available from the
+driver connection through the SnowflakeConnection interface.
+
+	err = conn.Raw(func(x interface{}) error {
+		info := x.(sf.SnowflakeConnection).ConnectionInfo()
+		log.Printf("session: %v, server version: %v", info.SessionID, info.ServerVersion)
+		return nil
+	})
+
+CurrentServiceName returns the service name that Snowflake assigned to the session. The driver tracks it per
+connection from every response and sends it back in the X-Snowflake-Service header of the following requests.
+
+Session Expiration
+
+The driver tracks the expirations of the session token and the master token returned by Snowflake. Before
+database/sql reuses a pooled connection, the tokens that expire within a minute are renewed, and a connection whose
+session has expired or fails to be renewed is discarded, so that the next query runs on a new connection instead of
+failing on the expired session.
+
+Connector and Credentials Provider
+
+NewConnector creates a driver.Connector from a Config, which is passed to sql.OpenDB. It allows the options that
+cannot be given in a DSN.
+
+	db := sql.OpenDB(sf.NewConnector(sf.SnowflakeDriver{}, cfg))
+
+The connections created by a Connector share one HTTP client, so that a large pool reuses the idle HTTP
+connections and TLS sessions across its connections instead of handshaking per connection. The HTTP client is the
+only state a Connector shares: every connection logs in to its own session with its own session token and
+parameters. The OCSP response cache is not per Connector but shared by all the connections of the process, and
+so is the OCSP fail open mode, which the connection opened last sets.
+
+Config.BackgroundContext governs what the connections do in the background: the heartbeats, which renew the
+session when it has expired, the watches of the asynchronous queries and the background flushes of a
+BatchWriter. They stop as soon as the context is done, e.g., when the application shuts down, instead of running
+until the connections are closed:
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel() // on shutdown
+	cfg.BackgroundContext = ctx
+	db := sql.OpenDB(sf.NewConnector(sf.SnowflakeDriver{}, cfg))
+
+Config.CredentialsProvider supplies the password, OAuth token or private key when a connection is opened, so that
+the secrets can be fetched from a secret manager instead of being kept in the DSN. If the login is rejected,
+the driver calls Refresh on the provider and retries the login once with the new credentials.
+
+For the key pair authentication, NewPrivateKeyProvider loads the PEM encoded private key at every login from
+a function supplied by the application, e.g., one that fetches the key from AWS Secrets Manager, so that the key
+isn't stored on disk and rotated keys are picked up by new connections.
+
+	cfg.Authenticator = sf.AuthTypeJwt
+	cfg.CredentialsProvider = sf.NewPrivateKeyProvider(func(ctx context.Context) (io.Reader, error) {
+		secret, err := fetchSecret(ctx, "snowflake/private-key")
+		if err != nil {
+			return nil, err
+		}
+		return strings.NewReader(secret), nil
+	})
+
+Session Handoff
+
+Short-lived processes, such as command line tools, can reuse an authenticated session instead of logging in on
+every run. ExportSession on the driver connection returns the session tokens, which the application stores
+securely, and SnowflakeDriver.ResumeSession creates a connection with them. Exported and resumed sessions are
+not deleted when the connections are closed.
+
+	err = conn.Raw(func(x interface{}) error {
+		session = x.(sf.SnowflakeConnection).ExportSession()
+		return nil
+	})
+	...
+	conn, err := sf.SnowflakeDriver{}.ResumeSession(ctx, cfg, session)
+
+Connection Hooks
+
+Config.OnOpen is called after a connection has logged in and before database/sql hands it out, and
+Config.OnClose before the connection is closed, while the session is still usable. The hooks are given the
+connection, which runs statements in its session and returns the session information, e.g., for fleet-level
+bookkeeping or custom warm-up statements. If OnOpen returns an error, the session is closed and opening the
+connection fails with the error. OnOpen is not called for resumed sessions.
+
+	cfg.OnOpen = func(ctx context.Context, conn sf.HookConn) error {
+		_, err := conn.ExecContext(ctx, "ALTER SESSION SET QUERY_TAG = 'billing-service'", nil)
+		return err
+	}
+	cfg.OnClose = func(ctx context.Context, conn sf.HookConn) {
+		inventory.Remove(conn.ConnectionInfo().SessionID)
+	}
+
+Expired Passwords
+
+When the password of the user has expired and must be changed, the login fails with *PasswordExpiredError. With
+Config.NewPassword, the driver changes the password at login instead, and the connection uses the new password
+from then on. The following connections of the same Connector log in with the new password too, but any other
+Config, e.g., the DSN of sql.Open, must be updated with the new password.
+
+	if _, ok := err.(*sf.PasswordExpiredError); ok {
+		cfg.NewPassword = newPassword // rotated, e.g., by the secret manager
+	}
+
+Login Throttling
+
+When Snowflake throttles the login because of too many login attempts, the driver retries the login with backoff
+as long as the login timeout allows. If the login is still throttled, a *LoginThrottledError is returned so that
+the application can tell throttling from bad credentials. A user temporarily locked after too many failed logins
+is not throttled, and the login fails at once with a *SnowflakeError numbered ErrUserTemporarilyLocked.
+
+	if _, ok := err.(*sf.LoginThrottledError); ok {
+		// wait before opening a new connection
+	}
+
+If loginAttemptTimeout (Config.LoginAttemptTimeout) is set, a login attempt that doesn't complete in time is
+abandoned and retried with the timeout doubled. A *LoginTimeoutError is returned if no attempt completes within
+the login timeout. Its Attempts has the time each attempt spent in the TLS handshakes, including the OCSP checks.
+
+Rows Changed by DML Statements
+
+RowsAffected returns the total number of rows inserted, updated and deleted by a DML statement. The driver result
+also implements SnowflakeDMLResult, whose RowCounts method returns the counts per type of change, e.g., for a
+MERGE statement. For COPY INTO location, RowsAffected returns the number of rows unloaded and RowCounts also has
+the number of bytes unloaded. The driver result is available when the statement is executed on the driver connection:
+
+	err = conn.Raw(func(x interface{}) error {
+		res, err := x.(driver.ExecerContext).ExecContext(ctx, mergeQuery, nil)
+		if err != nil {
+			return err
+		}
+		counts := res.(sf.SnowflakeDMLResult).RowCounts()
+		fmt.Printf("inserted: %v, updated: %v, deleted: %v\n", counts.Inserted, counts.Updated, counts.Deleted)
+		return nil
+	})
+
+The driver result of a DDL statement, e.g., CREATE TABLE AS SELECT, implements SnowflakeDDLResult, whose Status
+method returns the status message of Snowflake, e.g., "Table T2 successfully created.". If its status row has the
+number of rows inserted, RowCounts returns it and RowsAffected their total. Otherwise RowsAffected fails as for any
+DDL statement. The result of a query run with ExecContext is driver.ResultNoRows:
+
+	res, err := x.(driver.ExecerContext).ExecContext(ctx, "CREATE TABLE t2 AS SELECT * FROM t1", nil)
+	fmt.Println(res.(sf.SnowflakeDDLResult).Status())
+
+Asynchronous Queries
+
+A statement executed with ExecContext and a context created by WithAsyncCompletion or WithAsyncCompletionChan
+runs asynchronously. ExecContext returns as soon as Snowflake accepts the statement, and the driver polls the
+status of the query and delivers its completion, with the query ID, the final status and the error if any:
+
+	ch := make(chan sf.AsyncQueryResult, 1)
+	res, err := db.ExecContext(sf.WithAsyncCompletionChan(ctx, ch), "INSERT INTO t SELECT * FROM big_table")
+	...
+	completion := <-ch
+	if completion.Err != nil {
+		...
+	}
+
+The result of an asynchronous query can be fetched with WithFetchResultByID once it completes.
+
+The status of any query of the user can be checked with QueryStatus of the driver connection. While the query waits
+on an overloaded warehouse, the status reports the time spent in the queues, which autoscaling controllers can use
+to add clusters:
+
+	err = conn.Raw(func(x interface{}) error {
+		status, err := x.(sf.SnowflakeConnection).QueryStatus(ctx, queryID)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%v queued for %v\n", status.Status, status.QueuedTime())
+		return nil
+	})
+
+The status also has the total elapsed time, the bytes and micro-partitions scanned and the warehouse size, if
+Snowflake reports them. EstimatedCredits approximates the credits the query has consumed from the size of the
+warehouse, the time the query ran out of the queues and its share of the warehouse load, so that cost-aware
+schedulers can compare queries. Snowflake bills the warehouses rather than the queries, so that the estimates
+don't add up to the bill.
+
+A synchronous query that runs longer than Snowflake holds the submission is reported in progress, and the driver
+polls its result until it completes, backing off progressively from 100ms to 2s between the polls that return
+early. Canceling the context stops the polling and aborts the query. While the connection polls the result,
+QueryStatus.PollingTime reports how long it has been polling.
+
+Query IDs on Submission
+
+The query ID of a result is known only once the query completes. To monitor or cancel a long-running query by its
+ID while it runs, pass a context created by WithQueryIDChan. The driver sends the query ID to the channel as soon
+as Snowflake assigns it, including while it polls the result of a query in progress. The query waits until the ID
+is received, so the channel should be buffered or read concurrently:
+
+	ch := make(chan string, 1)
+	go func() {
+		qid := <-ch
+		...
+	}()
+	rows, err := db.QueryContext(sf.WithQueryIDChan(ctx, ch), "SELECT * FROM big_table")
+
+Pinging Connections
+
+By default, Ping, which database/sql calls, e.g., to check the connections of the pool, runs SELECT 1. The query
+checks the warehouse as well, but resumes it if it is suspended, which consumes credits. Config.PingMode
+(pingMode in the DSN) selects a lighter check: PingModeHeartbeat sends a heartbeat of the session, which fails if
+the session no longer exists without using a warehouse, and PingModeNone sends nothing. Config.PingTimeout
+(pingTimeout in the DSN) bounds Ping apart from the deadline of its context:
+
+	db, err := sql.Open("snowflake", "user:password@myaccount/mydb?pingMode=heartbeat&pingTimeout=5")
+
+Aborting Queries
+
+AbortAll of the driver connection or of a Client aborts every query the connection has submitted and that has not
+returned yet, by the request IDs of the queries, e.g., for a fast shutdown of a batch worker. The aborted queries
+return an error. Unlike the other methods, it may be called while the connection runs queries:
+
+	client, err := sf.NewClient(ctx, cfg)
+	...
+	go func() {
+		<-shutdown
+		client.AbortAll(context.Background())
+	}()
+
+database/sql holds a connection while it runs a query, so that sql.Conn.Raw cannot reach the AbortAll of a
+connection running a query. AbortAll of a Connector aborts the queries of all the open connections of the sql.DB
+opened with it instead:
+
+	connector := sf.NewConnector(sf.SnowflakeDriver{}, cfg)
+	db := sql.OpenDB(connector)
+	...
+	go func() {
+		<-shutdown
+		connector.AbortAll(context.Background())
+	}()
+
+With Config.AbortOnClose (abortOnClose in the DSN) set, closing a connection aborts its queries in flight and
+waits until they return before the session is closed. database/sql closes a connection only once its query
+returns, so that closing the sql.DB aborts the queries in flight through Connector.Close instead, which sql.DB.Close
+calls as of Go 1.17. The asynchronous queries, which return once Snowflake accepts them, are not aborted.
+
+SQL API
+
+With Config.UseSQLAPI (useSQLAPI in the DSN) set, a connection submits its statements to the SQL API,
+/api/v2/statements, instead of the internal endpoints of Snowflake, e.g., where the internal endpoints are
+blocked by a proxy. The SQL API requires the JWT or the OAuth authenticator:
+
+	db, err := sql.Open("snowflake", "user@myaccount/mydb/myschema?warehouse=mywh&authenticator=oauth&token=<token>&useSQLAPI=true")
+
+The SQL API is stateless: there is no session to log in to or keep alive, and every statement runs in the
+database, the schema, the warehouse and the role of the Config, so USE and ALTER SESSION don't affect the next
+statements. The results larger than a partition are downloaded partition by partition as chunks. Multi-statement
+queries, asynchronous queries, prepared statement descriptions and PingModeHeartbeat are not supported and fail
+with ErrCodeSQLAPINotSupported, and the features that use the internal endpoints, e.g., QueryStatus, don't work.
+
+Authorizing Requests
+
+Config.RequestAuthorizer is called before every HTTP request of the connections is sent, including the retries,
+e.g., to sign the requests for a gateway that fronts Snowflake with its own authentication. It may replace the
+Authorization header set by the driver or add its own headers:
+
+	cfg.RequestAuthorizer = sf.RequestAuthorizerFunc(func(req *http.Request) error {
+		token, err := gateway.Token(req.Context())
+		if err != nil {
+			return err
+		}
+		req.Header.Set("X-Gateway-Authorization", token)
+		return nil
+	})
+
+The requests to the cloud storage of the results are authorized too, and may be told apart by req.URL.Host. If
+the RequestAuthorizer returns an error, the request fails with ErrCodeRequestAuthorizationFailed without being
+sent or retried.
+
+Result Cache
+
+Applications that run the same small queries repeatedly, e.g., dashboard backends, can cache the results on the
+client side. Set Config.ResultCache to a ResultCache shared by the connections opened with the Config. The results
+of SELECT statements are cached by the query text, the bind parameters and the session context, including the
+warehouse and the session parameters, for the TTL, and Stats reports the hits, misses and evictions.
+
+	cfg.ResultCache = sf.NewResultCache(100, 30*time.Second, 1000)
+	db := sql.OpenDB(sf.NewConnector(sf.SnowflakeDriver{}, *cfg))
+
+The cached results are not invalidated when the data change.
+
+The large results are downloaded in chunks, which Config.ChunkCache caches across the connections when many
+workers fetch the result of the same query ID with WithFetchResultByID. The chunks of the other queries are not
+cached. The chunks are keyed by a hash of their URLs, without the signatures, and the keys they are encrypted with.
+A chunk is downloaded once while it stays in the cache, and a fetch of a chunk being downloaded waits for the
+download. The least recently used chunks are evicted beyond the size of the cache, and a chunk larger than the
+cache is decoded as it is downloaded without being cached:
+
+	cfg.ChunkCache = sf.NewChunkCache(512 << 20) // 512 MB
+
+Response Extensions
+
+Snowflake may add metadata to the query response that this version of the driver doesn't know. Run the query with
+a context created by WithResponseExtensions to capture the unknown fields of the response as raw JSON. The rows,
+and the results of DML statements, implement ResponseExtensions:
+
+	err = conn.Raw(func(x interface{}) error {
+		rows, err := x.(driver.QueryerContext).QueryContext(sf.WithResponseExtensions(ctx), query, nil)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		raw := rows.(sf.ResponseExtensions).Extensions()["newField"]
+		...
+	})
+
+Request IDs
+
+Snowflake support finds a query by its query ID, the request ID of the query request and the request GUID of
+the HTTP request. The log lines of the driver about a query, from its submission and the polling of its result to
+the download of the result chunks, end with the IDs known so far, and the SnowflakeError of a query has them in
+QueryID, RequestID and RequestGUID:
+
+	var se *sf.SnowflakeError
+	if errors.As(err, &se) {
+		log.Printf("query failed. query ID: %v, request ID: %v, request GUID: %v", se.QueryID, se.RequestID,
+			se.RequestGUID)
+	}
+
+RequestGUID is that of the last request sent for the query, as every retry of a request has a new one. The other
+errors, e.g., context.Canceled, are returned as is.
+
+Wire Trace
+
+To diagnose an issue of the protocol, a context created by WithWireTrace writes a trace of every HTTP request
+sent for the operations run with it, e.g., a query, the polling of its result and the download of the result
+chunks, to a writer: the method and the URL, the headers and the first 4 KB of the body of the request and of the
+response, and the time until the response:
+
+	ctx := sf.WithWireTrace(context.Background(), os.Stderr)
+	rows, err := db.QueryContext(ctx, "SELECT * FROM orders")
+
+The credentials, e.g., the password, the tokens, the session cookies and the signatures of the presigned URLs,
+are replaced with REDACTED, but the SQL text, the bindings and the data of the results are not. A trace may
+therefore have confidential data. The login of a connection is traced with the context given to Conn or
+Connector.Connect.
+
+Error Codes
+
+ErrorCodes returns the catalog of the numbers the driver returns in SnowflakeError.Number, with the name of the
+constant and the category of each, e.g., ErrorCategoryAuth or ErrorCategoryNetwork, and LookupErrorCode looks up a
+number. Rather than matching on the messages, applications test the errors with the predicates, which find the
+SnowflakeError in the chain of a wrapped error:
+
+	switch {
+	case sf.IsAuthError(err):
+		// the credentials are rejected or the user is locked; retrying doesn't help
+	case sf.IsNetworkError(err):
+		// Snowflake is unreachable; retry later
+	case sf.IsObjectNotFound(err):
+		// the table doesn't exist or the role has no privilege on it
+	}
+
+ErrorCategoryOf returns the category of an error. The numbers of the errors of the statements are those of
+Snowflake, and most of them are not in the catalog.
+
+Suspended Warehouses
+
+IsWarehouseSuspended reports whether an error was returned because the warehouse of the session is suspended and
+doesn't resume automatically. With the connection parameter autoResumeWarehouse=true, the driver resumes the
+warehouse and retries the statement. Latency-critical services can resume the warehouse before taking traffic:
+
+	err = conn.Raw(func(x interface{}) error {
+		return x.(sf.SnowflakeConnection).WarmUpWarehouse(ctx)
+	})
+
+Retrying Queries
+
+A query may fail because of an internal error of Snowflake, for which an incident is recorded, and succeed if it
+is submitted again. IsRetryableQueryError reports such errors. With the connection parameter maxQueryRetries, the
+driver re-submits the query, with a new request ID, up to the number of times, waiting one second before the first
+attempt and twice as long before every next one. Only the queries that change no data, e.g., SELECT and SHOW, are
+re-submitted, since a failed DML statement may have been applied in part. An idempotent DML statement or
+multi-statement query is re-submitted with a context created by WithRetryableDML:
+
+	_, err = db.ExecContext(sf.WithRetryableDML(ctx), "MERGE INTO t USING s ON t.id = s.id ...")
+
+A statement run with a context created by WithIdempotencyKey is submitted with a request ID derived from the key
+rather than a random one. Snowflake takes a request with the request ID of an earlier one for a retry, and returns
+the result of the earlier statement instead of running it again, so that an INSERT or MERGE submitted again after a
+timeout, e.g., by a job retried with the same key, is applied once. With the SQL API, the statement is submitted with
+retry=true. The key must identify the statement, e.g., by the ID of the message it writes, since every statement
+run with the same key gets the result of the first one. IdempotentRequestID returns the request ID of a key:
+
+	ctx = sf.WithIdempotencyKey(ctx, "order-"+orderID)
+	_, err = db.ExecContext(ctx, "INSERT INTO orders SELECT ...")
+
+Rate Limiting
+
+Many clients submitting queries at once, e.g., in a fan-out of microservices, may be throttled by Snowflake
+with HTTP 429. A RateLimiter, a token bucket created by NewRateLimiter, limits the query submissions and the
+result polls on the client side instead. The requests wait for the RateLimiter, or fail with the context error
+if the context is done first. Setting Config.RateLimiter of a Connector shares the RateLimiter among the
+connections of the Connector, while the connection parameter requestRateLimit limits every connection opened
+with the DSN separately:
+
+	cfg.RateLimiter = sf.NewRateLimiter(50, 10) // 50 requests per second on average, up to 10 at once
+	db := sql.OpenDB(sf.NewConnector(sf.SnowflakeDriver{}, *cfg))
+
+The logins, the heartbeats and the result chunk downloads are not limited.
+
+Client Redirect
+
+When Snowflake redirects the login to another deployment, e.g., the primary deployment after a failover with
+Client Redirect, the driver logs in to the new deployment and uses it for the connection. Only HTTPS redirects to
+Snowflake hosts are followed, as the login carries the credentials. Config.ClientRedirectListener is notified of
+every redirect that is followed.
+
+The result URL of a query is usually relative to the deployment of the session, and an absolute one is followed
+only on the host of the session, as the session token is sent with it. The result URL of another deployment fails
+with the code ErrCodeInvalidResultURL, and the redirects of a result to another host are followed without the
+session token.
+
+Multi-Region Failover
+
+For a replicated account, Config.FailoverURLs (or the connection parameter failoverURLs) lists the account URLs
+of the replicas in the order of preference:
+
+	cfg.FailoverURLs = []string{"https://myorg-acct2.snowflakecomputing.com"}
+
+When the account URL of the connection is unreachable, times out or is unavailable at login, the driver logs in
+to the next account URL in the list. The account name is taken from the host of the account URL. A login that is
+rejected, e.g., for wrong credentials, doesn't fail over. Statements don't fail over after the login.
+
+Typed SHOW Results
+
+The sfshow subpackage parses the results of SHOW WAREHOUSES, DATABASES, SCHEMAS, TABLES, USERS and ROLES into
+typed Go structs for admin automation tools, tolerating the columns added and renamed across Snowflake versions:
+
+	whs, err := sfshow.Warehouses(ctx, db, &sfshow.Options{Like: "ETL%"})
+
+sfshow.Scan parses the result of any other SHOW command into a slice of a struct with show tags.
+
+Account Usage
+
+The sfusage subpackage queries the QUERY_HISTORY and WAREHOUSE_METERING_HISTORY views of
+SNOWFLAKE.ACCOUNT_USAGE into typed Go structs for FinOps tooling, a page at a time:
+
+	page, err := sfusage.QueryHistory(ctx, db, &sfusage.QueryHistoryFilter{Start: since, PageSize: 500})
+	...
+	// the next page
+	page, err = sfusage.QueryHistory(ctx, db, &sfusage.QueryHistoryFilter{Start: since, PageSize: 500, After: page.Next})
+
+Query Journal
+
+Config.QueryJournal is called with a QueryJournalEntry for every statement run on the connections, including the
+failed statements: the query ID, the SQL text with the string literals redacted, the duration, the number of rows
+and the error. The bindings are not recorded, but the message of the error is not redacted and may have values of
+the statement. NewQueryJournalWriter writes the entries to an io.Writer as JSON lines, e.g., for compliance
+audits:
+
+	cfg.QueryJournal = sf.NewQueryJournalWriter(auditLog)
+
+Describing Prepared Statements
+
+With Config.DescribeCacheSize (describeCacheSize in the DSN) set, Prepare has Snowflake describe the statement
+without running it, so that a statement with an error fails before it runs and Stmt.NumInput returns the number
+of bind variables, which database/sql checks the arguments against. The descriptions are cached per connection
+by the query text, so that preparing the same statement again sends no request. The cache is emptied when the
+database, the schema or the role of the session changes.
+
+	db, err := sql.Open("snowflake", "user:password@myaccount/mydb?describeCacheSize=100")
+	stmt, err := db.PrepareContext(ctx, "SELECT * FROM t WHERE id = ?")
+
+Query Tags and Labels
+
+WithQueryTag sets QUERY_TAG of the queries run with the context, instead of the QUERY_TAG of the session, and
+WithQueryLabels sets it to a JSON object of labels, so that the queries can be attributed to teams, jobs or
+tenants for workload management and chargeback, e.g., by PARSE_JSON(QUERY_TAG):team in QUERY_HISTORY. The tag
+of WithQueryTag is added to the labels as "tag". The QUERY_TAG is recorded in the QueryJournalEntry as well. A
+QUERY_TAG longer than 2000 characters fails the query with the code ErrCodeInvalidQueryTag before it is sent.
+
+	ctx = sf.WithQueryLabels(ctx, map[string]string{"team": "billing", "job": "nightly-rollup"})
+	rows, err := db.QueryContext(ctx, "SELECT ...") // QUERY_TAG: {"job":"nightly-rollup","team":"billing"}
+
+Testing Without Snowflake
+
+The sfmock subpackage is an in-process fake of the Snowflake REST API for unit testing applications. A
+sfmock.Server answers the statements with the canned responses of the expectations whose patterns match them,
+and records the statements and their bindings:
+
+	srv := sfmock.NewServer()
+	defer srv.Close()
+	srv.Expect(`^INSERT INTO users`).Return(sfmock.Inserted(1))
+	db := sql.OpenDB(sf.NewConnector(sf.SnowflakeDriver{}, srv.Config()))
+
+Config.Cassette records the HTTP exchanges of the connections with Snowflake to a file, or replays them from the
+file, so that CI suites run the tests that depend on the driver deterministically and offline. The passwords,
+tokens, keys and the signatures of presigned URLs are scrubbed from the recorded exchanges:
+
+	mode := sf.CassetteReplay
+	if os.Getenv("RECORD") != "" {
+		mode = sf.CassetteRecord
+	}
+	cassette, err := sf.NewCassette("testdata/orders.json", mode)
+	...
+	cfg.Cassette = cassette
+	db := sql.OpenDB(sf.NewConnector(sf.SnowflakeDriver{}, cfg))
+	...
+	err = cassette.Save() // writes the file in record mode
 
+In replay mode, a request is answered with the first exchange not replayed yet that has the same method, URL
+and SQL text, and the account, user and password of the Config are only used for the validation of the Config.
 
 Limitations
 
-GET and PUT operations are unsupported.
+GET and PUT statements run with database/sql don't transfer the files, as the driver has no access to the local
+files there. Client.PutFile, PutFiles and GetFile transfer them instead.
 */
 package gosnowflake
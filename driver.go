@@ -15,21 +15,69 @@ type SnowflakeDriver struct{}
 // Open creates a new connection.
 func (d SnowflakeDriver) Open(dsn string) (driver.Conn, error) {
 	glog.V(2).Info("Open")
-	var err error
-	sc := &snowflakeConn{
-		SequenceCounter: 0,
-	}
 	ctx := context.TODO()
-	sc.cfg, err = ParseDSN(dsn)
+	cfg, err := ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return d.OpenWithConfig(ctx, *cfg)
+}
+
+// OpenWithConfig creates a new connection with the given Config.
+func (d SnowflakeDriver) OpenWithConfig(ctx context.Context, config Config) (driver.Conn, error) {
+	glog.V(2).Info("OpenWithConfig")
+	return d.openWithClient(ctx, config, nil, nil)
+}
+
+// openWithClient creates a new connection that sends the requests with the HTTP client, or with a new one if nil.
+// The password changed at login, if any, is set to the password of the Connector unless nil.
+func (d SnowflakeDriver) openWithClient(
+	ctx context.Context, config Config, client *http.Client, password *changedPassword) (driver.Conn, error) {
+	sc, err := newSnowflakeConn(config, true, client)
+	if err != nil {
+		return nil, err
+	}
+	sc.password = password
+	if sc.cfg.UseSQLAPI {
+		return openSQLAPI(ctx, sc)
+	}
+	authData, err := sc.loginWithFailover(ctx)
 	if err != nil {
 		sc.cleanup()
 		return nil, err
 	}
-	st := SnowflakeTransport
-	if sc.cfg.InsecureMode {
-		// no revocation check with OCSP. Think twice when you want to enable this option.
-		st = snowflakeInsecureTransport
-	} else {
+
+	sc.info = newConnectionInfo(authData)
+	sc.populateSessionParameters(authData.Parameters)
+	if err = sc.runOpenHook(ctx); err != nil {
+		sc.stopHeartBeat()
+		sc.cleanup()
+		return nil, err
+	}
+	sc.startHeartBeat()
+	return sc, nil
+}
+
+// newSnowflakeConn creates a connection that is not logged in yet. The credentials are not required
+// for a connection that resumes a session. The connection sends the requests with the HTTP client, which is
+// shared with the other connections of a Connector, or with a new one if nil.
+func newSnowflakeConn(config Config, login bool, client *http.Client) (*snowflakeConn, error) {
+	if err := fillMissingParameters(&config, login); err != nil {
+		return nil, err
+	}
+	// the session parameters are updated per connection
+	config.Params = normalizeSessionParams(&config)
+	sc := &snowflakeConn{
+		SequenceCounter: 0,
+		cfg:             &config,
+	}
+	if name, ok := config.Params[serviceName]; ok && name != nil {
+		sc.service.Store(*name)
+	}
+	if client == nil {
+		client = newHTTPClient(sc.cfg)
+	}
+	if !sc.cfg.InsecureMode {
 		// set OCSP fail open mode
 		ocspResponseCacheLock.Lock()
 		ocspFailOpen = sc.cfg.OCSPFailOpen
@@ -37,16 +85,14 @@ func (d SnowflakeDriver) Open(dsn string) (driver.Conn, error) {
 	}
 	// authenticate
 	sc.rest = &snowflakeRestful{
-		Host:     sc.cfg.Host,
-		Port:     sc.cfg.Port,
-		Protocol: sc.cfg.Protocol,
-		Client: &http.Client{
-			// request timeout including reading response body
-			Timeout:   defaultClientTimeout,
-			Transport: st,
-		},
+		Host:                sc.cfg.Host,
+		Port:                sc.cfg.Port,
+		Protocol:            sc.cfg.Protocol,
+		Client:              client,
+		UserAgent:           userAgentFor(sc.cfg),
 		LoginTimeout:        sc.cfg.LoginTimeout,
 		RequestTimeout:      sc.cfg.RequestTimeout,
+		RateLimiter:         sc.cfg.RateLimiter,
 		FuncPost:            postRestful,
 		FuncGet:             getRestful,
 		FuncPostQuery:       postRestfulQuery,
@@ -59,7 +105,12 @@ func (d SnowflakeDriver) Open(dsn string) (driver.Conn, error) {
 		FuncPostAuthOKTA:    postAuthOKTA,
 		FuncGetSSO:          getSSO,
 	}
-	var authData *authResponseMain
+	return sc, nil
+}
+
+// login authenticates the user with the authenticator in the Config.
+func (sc *snowflakeConn) login(ctx context.Context) (*authResponseMain, error) {
+	var err error
 	var samlResponse []byte
 	var proofKey []byte
 
@@ -75,7 +126,6 @@ func (d SnowflakeDriver) Open(dsn string) (driver.Conn, error) {
 			sc.cfg.User,
 			sc.cfg.Password)
 		if err != nil {
-			sc.cleanup()
 			return nil, err
 		}
 	case AuthTypeOkta:
@@ -86,25 +136,24 @@ func (d SnowflakeDriver) Open(dsn string) (driver.Conn, error) {
 			sc.cfg.Application,
 			sc.cfg.Account,
 			sc.cfg.User,
-			sc.cfg.Password)
+			sc.cfg.Password,
+			sc.cfg.Passcode)
 		if err != nil {
-			sc.cleanup()
 			return nil, err
 		}
+	case AuthTypeAzureAD:
+		var token string
+		token, err = getAzureADToken(ctx, sc.rest, sc.cfg.AzureADResource, sc.cfg.AzureADClientID)
+		if err != nil {
+			return nil, err
+		}
+		samlResponse = []byte(token)
 	}
-	authData, err = authenticate(
+	return authenticate(
 		ctx,
 		sc,
 		samlResponse,
 		proofKey)
-	if err != nil {
-		sc.cleanup()
-		return nil, err
-	}
-
-	sc.populateSessionParameters(authData.Parameters)
-	sc.startHeartBeat()
-	return sc, nil
 }
 
 func init() {
@@ -3,9 +3,13 @@
 package gosnowflake
 
 import (
+	"bytes"
+	"context"
 	"crypto/rsa"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"math"
 	"net/url"
 	"strconv"
 	"strings"
@@ -57,19 +61,122 @@ type Config struct {
 	Passcode           string
 	PasscodeInPassword bool
 
+	// NewPassword changes the password at login if it has expired. Otherwise the login fails with
+	// *PasswordExpiredError (optional)
+	NewPassword string
+
 	OktaURL *url.URL
 
-	LoginTimeout     time.Duration // Login retry timeout EXCLUDING network roundtrip and read out http response
-	RequestTimeout   time.Duration // request retry timeout EXCLUDING network roundtrip and read out http response
-	JWTExpireTimeout time.Duration // JWT expire after timeout
+	LoginTimeout time.Duration // Login retry timeout EXCLUDING network roundtrip and read out http response
+	// LoginAttemptTimeout is the timeout of the first login attempt. A login attempt that times out is retried
+	// with the timeout doubled, as long as LoginTimeout allows (optional)
+	LoginAttemptTimeout time.Duration
+	RequestTimeout      time.Duration // request retry timeout EXCLUDING network roundtrip and read out http response
+	JWTExpireTimeout    time.Duration // JWT expire after timeout
 
 	Application  string           // application name.
 	InsecureMode bool             // driver doesn't check certificate revocation status
 	OCSPFailOpen OCSPFailOpenMode // OCSP Fail Open
 
+	// DisableHeartbeat never heartbeats the session, even if CLIENT_SESSION_KEEP_ALIVE is set for the user or the
+	// account, so that an idle session expires (optional)
+	DisableHeartbeat bool
+	// DisableTelemetry sets CLIENT_TELEMETRY_ENABLED to false for the session, so that Snowflake doesn't have the
+	// client send telemetry (optional)
+	DisableTelemetry bool
+
+	ClientEnvironment map[string]string // additional client environment information sent at login
+
 	Token string // Token to use for OAuth other forms of token based auth
 
 	PrivateKey *rsa.PrivateKey // Private key used to sign JWT
+
+	CredentialsProvider CredentialsProvider // supplies the password, token or private key at connect time
+
+	AzureADResource string // application ID URI of the External OAuth integration for AZURE_AD authenticator
+	AzureADClientID string // client ID of the user-assigned managed identity for AZURE_AD authenticator (optional)
+
+	MaxIdleConns        int           // maximum number of idle HTTP connections across all hosts (optional)
+	MaxIdleConnsPerHost int           // maximum number of idle HTTP connections per host (optional)
+	MaxConnsPerHost     int           // maximum number of HTTP connections per host (optional)
+	IdleConnTimeout     time.Duration // idle HTTP connection timeout (optional)
+	EnableHTTP2         bool          // attempt HTTP/2 with the Snowflake endpoints
+
+	// FetchOnly makes the connection only fetch results by query ID. No database, schema
+	// or warehouse is set up at login so that no warehouse is resumed for the connection.
+	FetchOnly bool
+
+	// AutoResumeWarehouse resumes the warehouse and retries the statement once if it fails because the
+	// warehouse is suspended, for warehouses that don't resume automatically.
+	AutoResumeWarehouse bool
+
+	// MaxQueryRetries is the number of times a query that fails because of an internal error of Snowflake is
+	// re-submitted. The statements that change data are re-submitted only with WithRetryableDML (optional)
+	MaxQueryRetries int
+
+	SessionParams SessionParams // commonly used session parameters. the others are set by Params
+
+	SessionParameterListener SessionParameterListener // notified of the changes of the session parameters (optional)
+
+	ClientRedirectListener ClientRedirectListener // notified when the login is redirected to another deployment (optional)
+
+	OnOpen  ConnectionOpenHook  // called after a connection has logged in (optional)
+	OnClose ConnectionCloseHook // called before a connection is closed (optional)
+
+	AbortOnClose bool // aborts the queries in flight when a connection is closed (optional)
+
+	PingMode    PingMode      // how Ping checks a connection. SELECT 1 by default (optional)
+	PingTimeout time.Duration // timeout of Ping, apart from the deadline of its context (optional)
+
+	// UseSQLAPI submits the statements to the SQL API, /api/v2/statements, instead of the internal endpoints
+	// of Snowflake. It requires the JWT or the OAuth authenticator (optional)
+	UseSQLAPI bool
+
+	// FailoverURLs is the ordered list of the account URLs, e.g., https://myorg-acct2.snowflakecomputing.com,
+	// to log in to if the account URL in Host is unreachable (optional)
+	FailoverURLs []string
+
+	ResultCache *ResultCache // caches small query results across the connections (optional)
+	ChunkCache  *ChunkCache  // caches the downloaded result chunks across the connections (optional)
+
+	RateLimiter *RateLimiter // limits the query submissions and the result polls, per connection or shared (optional)
+
+	// FileKeyWrapper wraps the keys of the files uploaded to the client-side encrypted stages, e.g., with a KMS, and
+	// unwraps those of the files downloaded. They are wrapped with the master key of the stage by default (optional)
+	FileKeyWrapper FileKeyWrapper
+
+	// MaxInlineBindSize is the largest binding, in bytes after conversion, inlined in a query request. The
+	// bindings of a query with a larger one are uploaded to a temporary stage and bound from there (optional)
+	MaxInlineBindSize int
+
+	// MaxRequestBodySize is the largest query request, in bytes, sent to Snowflake. A larger request fails with
+	// ErrCodeRequestTooLarge before it is sent (optional)
+	MaxRequestBodySize int
+
+	// ResultSpillDir is the directory to which the downloaded result chunks are spilled, to be decoded only when
+	// the rows are read, so that a large result takes little memory. The chunks are kept in memory if empty
+	// (optional)
+	ResultSpillDir string
+
+	// ResultSpillMaxBytes is the limit of the bytes spilled to ResultSpillDir at once by a result. A download
+	// exceeding the limit fails with ErrCodeResultSpillLimitExceeded. Not limited if 0 (optional)
+	ResultSpillMaxBytes int64
+
+	// DescribeCacheSize is the number of statement descriptions cached per connection. If set, PrepareContext
+	// has Snowflake describe the statement, so that the statements with errors fail before they run and
+	// NumInput returns the number of bind variables (optional)
+	DescribeCacheSize int
+
+	QueryJournal QueryJournal // records every statement run on the connections, e.g., for audits (optional)
+
+	Cassette *Cassette // records the HTTP exchanges with Snowflake, or replays them for offline tests (optional)
+
+	RequestAuthorizer RequestAuthorizer // authorizes every HTTP request, e.g., for a gateway (optional)
+
+	// BackgroundContext governs the operations the connections run in the background, i.e., the heartbeats with
+	// the session renewals, the watches of the asynchronous queries and the background flushes of BatchWriter.
+	// They stop when it is done, e.g., on application shutdown. context.Background() if nil (optional)
+	BackgroundContext context.Context
 }
 
 // ocspMode returns the OCSP mode in string INSECURE, FAIL_OPEN, FAIL_CLOSED
@@ -146,6 +253,9 @@ func DSN(cfg *Config) (dsn string, err error) {
 	if cfg.LoginTimeout != defaultLoginTimeout {
 		params.Add("loginTimeout", strconv.FormatInt(int64(cfg.LoginTimeout/time.Second), 10))
 	}
+	if cfg.LoginAttemptTimeout != 0 {
+		params.Add("loginAttemptTimeout", strconv.FormatInt(int64(cfg.LoginAttemptTimeout/time.Second), 10))
+	}
 	if cfg.RequestTimeout != defaultRequestTimeout {
 		params.Add("requestTimeout", strconv.FormatInt(int64(cfg.RequestTimeout/time.Second), 10))
 	}
@@ -161,11 +271,24 @@ func DSN(cfg *Config) (dsn string, err error) {
 	if cfg.Token != "" {
 		params.Add("token", cfg.Token)
 	}
+	if cfg.AzureADResource != "" {
+		params.Add("azureADResource", cfg.AzureADResource)
+	}
+	if cfg.AzureADClientID != "" {
+		params.Add("azureADClientID", cfg.AzureADClientID)
+	}
+	sessionParams := cfg.SessionParams.values()
 	if cfg.Params != nil {
 		for k, v := range cfg.Params {
+			if _, ok := sessionParams[strings.ToLower(k)]; ok {
+				continue
+			}
 			params.Add(k, *v)
 		}
 	}
+	for k, v := range sessionParams {
+		params.Add(k, v)
+	}
 	if cfg.PrivateKey != nil {
 		privateKeyInBytes, err := marshalPKCS8PrivateKey(cfg.PrivateKey)
 		if err != nil {
@@ -177,6 +300,70 @@ func DSN(cfg *Config) (dsn string, err error) {
 	if cfg.InsecureMode {
 		params.Add("insecureMode", strconv.FormatBool(cfg.InsecureMode))
 	}
+	if cfg.DisableHeartbeat {
+		params.Add("disableHeartbeat", strconv.FormatBool(cfg.DisableHeartbeat))
+	}
+	if cfg.DisableTelemetry {
+		params.Add("disableTelemetry", strconv.FormatBool(cfg.DisableTelemetry))
+	}
+
+	if cfg.MaxIdleConns != 0 {
+		params.Add("maxIdleConns", strconv.Itoa(cfg.MaxIdleConns))
+	}
+	if cfg.MaxIdleConnsPerHost != 0 {
+		params.Add("maxIdleConnsPerHost", strconv.Itoa(cfg.MaxIdleConnsPerHost))
+	}
+	if cfg.MaxConnsPerHost != 0 {
+		params.Add("maxConnsPerHost", strconv.Itoa(cfg.MaxConnsPerHost))
+	}
+	if cfg.IdleConnTimeout != 0 {
+		params.Add("idleConnTimeout", strconv.FormatInt(int64(cfg.IdleConnTimeout/time.Second), 10))
+	}
+	if cfg.EnableHTTP2 {
+		params.Add("enableHTTP2", strconv.FormatBool(cfg.EnableHTTP2))
+	}
+	if cfg.MaxRequestBodySize != 0 {
+		params.Add("maxRequestBodySize", strconv.Itoa(cfg.MaxRequestBodySize))
+	}
+	if cfg.MaxInlineBindSize != 0 {
+		params.Add("maxInlineBindSize", strconv.Itoa(cfg.MaxInlineBindSize))
+	}
+	if cfg.PingMode != PingModeQuery {
+		params.Add("pingMode", cfg.PingMode.String())
+	}
+	if cfg.PingTimeout != 0 {
+		params.Add("pingTimeout", strconv.FormatInt(int64(cfg.PingTimeout/time.Second), 10))
+	}
+	if cfg.AbortOnClose {
+		params.Add("abortOnClose", strconv.FormatBool(cfg.AbortOnClose))
+	}
+	if cfg.UseSQLAPI {
+		params.Add("useSQLAPI", strconv.FormatBool(cfg.UseSQLAPI))
+	}
+	if cfg.DescribeCacheSize != 0 {
+		params.Add("describeCacheSize", strconv.Itoa(cfg.DescribeCacheSize))
+	}
+	if cfg.ResultSpillDir != "" {
+		params.Add("resultSpillDir", cfg.ResultSpillDir)
+	}
+	if cfg.ResultSpillMaxBytes != 0 {
+		params.Add("resultSpillMaxBytes", strconv.FormatInt(cfg.ResultSpillMaxBytes, 10))
+	}
+	if cfg.FetchOnly {
+		params.Add("fetchOnly", strconv.FormatBool(cfg.FetchOnly))
+	}
+	if cfg.AutoResumeWarehouse {
+		params.Add("autoResumeWarehouse", strconv.FormatBool(cfg.AutoResumeWarehouse))
+	}
+	if cfg.MaxQueryRetries != 0 {
+		params.Add("maxQueryRetries", strconv.Itoa(cfg.MaxQueryRetries))
+	}
+	if cfg.RateLimiter != nil {
+		params.Add("requestRateLimit", strconv.FormatFloat(cfg.RateLimiter.Rate(), 'f', -1, 64))
+	}
+	if len(cfg.FailoverURLs) > 0 {
+		params.Add("failoverURLs", strings.Join(cfg.FailoverURLs, ","))
+	}
 
 	params.Add("ocspFailOpen", strconv.FormatBool(cfg.OCSPFailOpen != OCSPFailOpenFalse))
 
@@ -204,85 +391,45 @@ func ParseDSN(dsn string) (cfg *Config, err error) {
 	// user[:password]@host:port/database/schema?account=user_account[?param1=value1&paramN=valueN]
 	// or
 	// host:port/database/schema?account=user_account[?param1=value1&paramN=valueN]
-
-	foundSlash := false
-	secondSlash := false
-	done := false
-	var i int
-	posQuestion := len(dsn)
-	for i = len(dsn) - 1; i >= 0; i-- {
-		switch {
-		case dsn[i] == '/':
-			foundSlash = true
-
-			// left part is empty if i <= 0
-			var j int
-			posSecondSlash := i
-			if i > 0 {
-				for j = i - 1; j >= 0; j-- {
-					switch {
-					case dsn[j] == '/':
-						// second slash
-						secondSlash = true
-						posSecondSlash = j
-					case dsn[j] == '@':
-						// username[:password]@...
-						cfg.User, cfg.Password = parseUserPassword(j, dsn)
-					}
-					if dsn[j] == '@' {
-						break
-					}
-				}
-
-				// account or host:port
-				err = parseAccountHostPort(cfg, j, posSecondSlash, dsn)
-				if err != nil {
-					return nil, err
-				}
-			}
-			// [?param1=value1&...&paramN=valueN]
-			// Find the first '?' in dsn[i+1:]
-			err = parseParams(cfg, i, dsn)
-			if err != nil {
-				return
-			}
-			if secondSlash {
-				cfg.Database = dsn[posSecondSlash+1 : i]
-				cfg.Schema = dsn[i+1 : posQuestion]
-			} else {
-				cfg.Database = dsn[posSecondSlash+1 : posQuestion]
-			}
-			done = true
-		case dsn[i] == '?':
-			posQuestion = i
-		}
-		if done {
-			break
+	//
+	// The credentials end at the last '@' before the parameters so that the password may include '@', ':', '/' and
+	// '?', and the parameters '@'. The parameters start at the first '?' after an '@', so a '?' that follows an '@'
+	// in the password must be escaped.
+	end := len(dsn)
+	if posAt := strings.Index(dsn, "@"); posAt >= 0 {
+		if posQuestion := strings.Index(dsn[posAt:], "?"); posQuestion >= 0 {
+			end = posAt + posQuestion
 		}
 	}
-	if !foundSlash {
-		// no db or schema is specified
-		var j int
-		for j = len(dsn) - 1; j >= 0; j-- {
-			switch {
-			case dsn[j] == '@':
-				cfg.User, cfg.Password = parseUserPassword(j, dsn)
-			case dsn[j] == '?':
-				posQuestion = j
-			}
-			if dsn[j] == '@' {
-				break
-			}
-		}
-		err = parseAccountHostPort(cfg, j, posQuestion, dsn)
-		if err != nil {
+	posAt := strings.LastIndex(dsn[:end], "@")
+	if posAt >= 0 {
+		cfg.User, cfg.Password = parseUserPassword(posAt, dsn)
+	}
+	rest := dsn[posAt+1:]
+	var query string
+	if posQuestion := strings.Index(rest, "?"); posQuestion >= 0 {
+		query = rest[posQuestion+1:]
+		rest = rest[:posQuestion]
+	}
+	// account or host:port, followed by the optional database and schema
+	paths := strings.SplitN(rest, "/", 3)
+	if err = parseAccountHostPort(cfg, paths[0]); err != nil {
+		return nil, err
+	}
+	if len(paths) > 1 {
+		if cfg.Database, err = url.QueryUnescape(paths[1]); err != nil {
 			return nil, err
 		}
-		err = parseParams(cfg, posQuestion-1, dsn)
-		if err != nil {
-			return
+	}
+	if len(paths) > 2 {
+		if cfg.Schema, err = url.QueryUnescape(paths[2]); err != nil {
+			return nil, err
 		}
 	}
+	// [?param1=value1&...&paramN=valueN]
+	if err = parseDSNParams(cfg, query); err != nil {
+		return nil, err
+	}
 	if cfg.Account == "" && strings.HasSuffix(cfg.Host, defaultDomain) {
 		posDot := strings.Index(cfg.Host, ".")
 		if posDot > 0 {
@@ -298,43 +445,16 @@ func ParseDSN(dsn string) (cfg *Config, err error) {
 	if err != nil {
 		return nil, err
 	}
-
-	// unescape parameters
-	var s string
-	s, err = url.QueryUnescape(cfg.User)
-	if err != nil {
-		return nil, err
-	}
-	cfg.User = s
-	s, err = url.QueryUnescape(cfg.Password)
-	if err != nil {
-		return nil, err
-	}
-	cfg.Password = s
-	s, err = url.QueryUnescape(cfg.Database)
-	if err != nil {
-		return nil, err
-	}
-	cfg.Database = s
-	s, err = url.QueryUnescape(cfg.Schema)
-	if err != nil {
-		return nil, err
-	}
-	cfg.Schema = s
-	s, err = url.QueryUnescape(cfg.Role)
-	if err != nil {
-		return nil, err
-	}
-	cfg.Role = s
-	s, err = url.QueryUnescape(cfg.Warehouse)
-	if err != nil {
-		return nil, err
-	}
-	cfg.Warehouse = s
 	return cfg, nil
 }
 
 func fillMissingConfigParameters(cfg *Config) error {
+	return fillMissingParameters(cfg, true)
+}
+
+// fillMissingParameters fills the default parameters. The user name and password are checked only if
+// requireCredentials is true, i.e., the connection logs in.
+func fillMissingParameters(cfg *Config, requireCredentials bool) error {
 	posDash := strings.LastIndex(cfg.Account, "-")
 	if posDash > 0 {
 		if strings.Contains(cfg.Host, ".global.") {
@@ -345,16 +465,19 @@ func fillMissingConfigParameters(cfg *Config) error {
 		return ErrEmptyAccount
 	}
 
-	if cfg.Authenticator != AuthTypeOAuth && strings.Trim(cfg.User, " ") == "" {
+	if requireCredentials && cfg.Authenticator != AuthTypeOAuth && cfg.Authenticator != AuthTypeAzureAD && strings.Trim(cfg.User, " ") == "" {
 		// oauth does not require a username
 		return ErrEmptyUsername
 	}
 
-	if cfg.Authenticator != AuthTypeExternalBrowser &&
+	if requireCredentials &&
+		cfg.Authenticator != AuthTypeExternalBrowser &&
 		cfg.Authenticator != AuthTypeOAuth &&
 		cfg.Authenticator != AuthTypeJwt &&
+		cfg.Authenticator != AuthTypeAzureAD &&
+		cfg.CredentialsProvider == nil &&
 		strings.Trim(cfg.Password, " ") == "" {
-		// no password parameter is required for EXTERNALBROWSER, OAUTH or JWT.
+		// no password parameter is required for EXTERNALBROWSER, OAUTH, JWT, AZURE_AD or a credentials provider.
 		return ErrEmptyPassword
 	}
 	if strings.Trim(cfg.Protocol, " ") == "" {
@@ -410,9 +533,45 @@ func fillMissingConfigParameters(cfg *Config) error {
 			MessageArgs: []interface{}{cfg.Host},
 		}
 	}
+	for _, u := range cfg.FailoverURLs {
+		if _, err := parseFailoverURL(u); err != nil {
+			return err
+		}
+	}
+	if err := validateDisabledFeatures(cfg); err != nil {
+		return err
+	}
+	return validateSessionParams(cfg)
+}
+
+// validateDisabledFeatures checks that the session parameters of the config don't enable the features it disables.
+func validateDisabledFeatures(cfg *Config) error {
+	params := normalizeSessionParams(cfg)
+	enabled := func(name string) bool {
+		v, ok := params[name]
+		if !ok || v == nil {
+			return false
+		}
+		b, err := strconv.ParseBool(*v)
+		return err == nil && b
+	}
+	if cfg.DisableHeartbeat && enabled(sessionClientSessionKeepAlive) {
+		return errConflictingParameters("disableHeartbeat", strings.ToUpper(sessionClientSessionKeepAlive))
+	}
+	if cfg.DisableTelemetry && enabled(sessionClientTelemetryEnabled) {
+		return errConflictingParameters("disableTelemetry", strings.ToUpper(sessionClientTelemetryEnabled))
+	}
 	return nil
 }
 
+func errConflictingParameters(a string, b string) *SnowflakeError {
+	return &SnowflakeError{
+		Number:      ErrCodeConflictingParameters,
+		Message:     errMsgConflictingParameters,
+		MessageArgs: []interface{}{a, b},
+	}
+}
+
 // transformAccountToHost transforms host to accout name
 func transformAccountToHost(cfg *Config) (err error) {
 	if cfg.Port == 0 && !strings.HasSuffix(cfg.Host, defaultDomain) && cfg.Host != "" {
@@ -429,57 +588,56 @@ func transformAccountToHost(cfg *Config) (err error) {
 	return nil
 }
 
-// parseAccountHostPort parses the DSN string to attempt to get account or host and port.
-func parseAccountHostPort(cfg *Config, posAt, posSlash int, dsn string) (err error) {
-	// account or host:port
-	var k int
-	for k = posAt + 1; k < posSlash; k++ {
-		if dsn[k] == ':' {
-			cfg.Port, err = strconv.Atoi(dsn[k+1 : posSlash])
-			if err != nil {
-				err = &SnowflakeError{
-					Number:      ErrCodeFailedToParsePort,
-					Message:     errMsgFailedToParsePort,
-					MessageArgs: []interface{}{dsn[k+1 : posSlash]},
-				}
-				return
+// parseAccountHostPort parses the account or host and port part of the DSN. An IPv6 host must be enclosed
+// in square brackets, e.g., [::1]:8080.
+func parseAccountHostPort(cfg *Config, hostPort string) (err error) {
+	host, port := hostPort, ""
+	if strings.HasPrefix(hostPort, "[") {
+		if posBracket := strings.Index(hostPort, "]"); posBracket > 0 {
+			host = hostPort[:posBracket+1]
+			port = strings.TrimPrefix(hostPort[posBracket+1:], ":")
+		}
+	} else if posColon := strings.Index(hostPort, ":"); posColon >= 0 {
+		host, port = hostPort[:posColon], hostPort[posColon+1:]
+	}
+	if host != hostPort {
+		cfg.Port, err = strconv.Atoi(port)
+		if err != nil {
+			return &SnowflakeError{
+				Number:      ErrCodeFailedToParsePort,
+				Message:     errMsgFailedToParsePort,
+				MessageArgs: []interface{}{port},
 			}
-			break
 		}
 	}
-	cfg.Host = dsn[posAt+1 : k]
+	cfg.Host = host
 	return transformAccountToHost(cfg)
 }
 
-// parseUserPassword parses the DSN string for username and password
+// parseUserPassword parses the DSN string for username and password. They are unescaped if they are
+// percent-encoded. A value that is not a valid encoding, e.g., a password including '%', is taken as is.
 func parseUserPassword(posAt int, dsn string) (user, password string) {
-	var k int
-	for k = 0; k < posAt; k++ {
-		if dsn[k] == ':' {
-			password = dsn[k+1 : posAt]
-			break
-		}
+	user = dsn[:posAt]
+	if posColon := strings.Index(user, ":"); posColon >= 0 {
+		user, password = user[:posColon], user[posColon+1:]
 	}
-	user = dsn[:k]
-	return
+	return unescapeLenient(user), unescapeLenient(password)
 }
 
-// parseParams parse parameters
-func parseParams(cfg *Config, posQuestion int, dsn string) (err error) {
-	for j := posQuestion + 1; j < len(dsn); j++ {
-		if dsn[j] == '?' {
-			if err = parseDSNParams(cfg, dsn[j+1:]); err != nil {
-				return
-			}
-			break
-		}
+// unescapeLenient unescapes the percent-encoded value, or returns the value as is if it is not a valid
+// encoding.
+func unescapeLenient(value string) string {
+	if s, err := url.QueryUnescape(value); err == nil {
+		return s
 	}
-	return
+	return value
 }
 
-// parseDSNParams parses the DSN "query string". Values must be url.QueryEscape'ed
+// parseDSNParams parses the DSN "query string". Values must be url.QueryEscape'ed. If a parameter is
+// repeated, the last value is taken.
 func parseDSNParams(cfg *Config, params string) (err error) {
 	glog.V(2).Infof("Query String: %v\n", params)
+	insecureModeParams := make(map[string]bool) // the last values of insecureMode and insecureModeOCSP
 	for _, v := range strings.Split(params, "&") {
 		param := strings.SplitN(v, "=", 2)
 		if len(param) != 2 {
@@ -520,6 +678,11 @@ func parseDSNParams(cfg *Config, params string) (err error) {
 			if err != nil {
 				return
 			}
+		case "loginAttemptTimeout":
+			cfg.LoginAttemptTimeout, err = parseTimeout(value)
+			if err != nil {
+				return
+			}
 		case "requestTimeout":
 			cfg.RequestTimeout, err = parseTimeout(value)
 			if err != nil {
@@ -537,13 +700,25 @@ func parseDSNParams(cfg *Config, params string) (err error) {
 			if err != nil {
 				return err
 			}
-		case "insecureMode":
+		case "insecureMode", "insecureModeOCSP":
+			// insecureModeOCSP is the name of insecureMode in the other Snowflake drivers
 			var vv bool
 			vv, err = strconv.ParseBool(value)
 			if err != nil {
 				return
 			}
+			insecureModeParams[param[0]] = vv
 			cfg.InsecureMode = vv
+		case "disableHeartbeat":
+			cfg.DisableHeartbeat, err = strconv.ParseBool(value)
+			if err != nil {
+				return
+			}
+		case "disableTelemetry":
+			cfg.DisableTelemetry, err = strconv.ParseBool(value)
+			if err != nil {
+				return
+			}
 		case "ocspFailOpen":
 			var vv bool
 			vv, err = strconv.ParseBool(value)
@@ -558,6 +733,10 @@ func parseDSNParams(cfg *Config, params string) (err error) {
 
 		case "token":
 			cfg.Token = value
+		case "azureADResource":
+			cfg.AzureADResource = value
+		case "azureADClientID":
+			cfg.AzureADClientID = value
 		case "privateKey":
 			var decodeErr error
 			block, decodeErr := base64.URLEncoding.DecodeString(value)
@@ -583,6 +762,111 @@ func parseDSNParams(cfg *Config, params string) (err error) {
 			} else {
 				cfg.ValidateDefaultParameters = ConfigBoolFalse
 			}
+		case "maxIdleConns":
+			cfg.MaxIdleConns, err = strconv.Atoi(value)
+			if err != nil {
+				return
+			}
+		case "maxIdleConnsPerHost":
+			cfg.MaxIdleConnsPerHost, err = strconv.Atoi(value)
+			if err != nil {
+				return
+			}
+		case "maxConnsPerHost":
+			cfg.MaxConnsPerHost, err = strconv.Atoi(value)
+			if err != nil {
+				return
+			}
+		case "maxRequestBodySize":
+			cfg.MaxRequestBodySize, err = strconv.Atoi(value)
+			if err != nil {
+				return
+			}
+		case "maxInlineBindSize":
+			cfg.MaxInlineBindSize, err = strconv.Atoi(value)
+			if err != nil {
+				return
+			}
+		case "pingMode":
+			cfg.PingMode, err = parsePingMode(value)
+			if err != nil {
+				return
+			}
+		case "pingTimeout":
+			cfg.PingTimeout, err = parseTimeout(value)
+			if err != nil {
+				return
+			}
+		case "abortOnClose":
+			var vv bool
+			vv, err = strconv.ParseBool(value)
+			if err != nil {
+				return
+			}
+			cfg.AbortOnClose = vv
+		case "useSQLAPI":
+			var vv bool
+			vv, err = strconv.ParseBool(value)
+			if err != nil {
+				return
+			}
+			cfg.UseSQLAPI = vv
+		case "describeCacheSize":
+			cfg.DescribeCacheSize, err = strconv.Atoi(value)
+			if err != nil {
+				return
+			}
+		case "resultSpillDir":
+			cfg.ResultSpillDir = value
+		case "resultSpillMaxBytes":
+			cfg.ResultSpillMaxBytes, err = strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return
+			}
+		case "idleConnTimeout":
+			cfg.IdleConnTimeout, err = parseTimeout(value)
+			if err != nil {
+				return
+			}
+		case "enableHTTP2":
+			var vv bool
+			vv, err = strconv.ParseBool(value)
+			if err != nil {
+				return
+			}
+			cfg.EnableHTTP2 = vv
+		case "fetchOnly":
+			var vv bool
+			vv, err = strconv.ParseBool(value)
+			if err != nil {
+				return
+			}
+			cfg.FetchOnly = vv
+		case "params":
+			if err = parseParamsBlob(cfg, value); err != nil {
+				return
+			}
+		case "autoResumeWarehouse":
+			var vv bool
+			vv, err = strconv.ParseBool(value)
+			if err != nil {
+				return
+			}
+			cfg.AutoResumeWarehouse = vv
+		case "maxQueryRetries":
+			cfg.MaxQueryRetries, err = strconv.Atoi(value)
+			if err != nil {
+				return
+			}
+		case "requestRateLimit":
+			var rate float64
+			if rate, err = strconv.ParseFloat(value, 64); err != nil {
+				return
+			}
+			// allows a second's worth of requests at once
+			cfg.RateLimiter = NewRateLimiter(rate, int(math.Ceil(rate)))
+		case "failoverURLs":
+			cfg.FailoverURLs = strings.Split(value, ",")
 		default:
 			if cfg.Params == nil {
 				cfg.Params = make(map[string]*string)
@@ -590,9 +874,73 @@ func parseDSNParams(cfg *Config, params string) (err error) {
 			cfg.Params[param[0]] = &value
 		}
 	}
+	if v, ok := insecureModeParams["insecureMode"]; ok {
+		if vOCSP, ok := insecureModeParams["insecureModeOCSP"]; ok && v != vOCSP {
+			return errConflictingParameters("insecureMode", "insecureModeOCSP")
+		}
+	}
 	return
 }
 
+// parseParamsBlob parses the session parameters given at once by the params parameter, as a JSON object or
+// a base64 encoded JSON object, e.g., {"TIMEZONE":"UTC","STATEMENT_TIMEOUT_IN_SECONDS":60}.
+func parseParamsBlob(cfg *Config, blob string) error {
+	blob = strings.TrimSpace(blob)
+	data := []byte(blob)
+	if !strings.HasPrefix(blob, "{") {
+		var err error
+		if data, err = decodeBase64Lenient(blob); err != nil {
+			return &SnowflakeError{
+				Number:      ErrCodeInvalidParamsBlob,
+				Message:     errMsgInvalidParamsBlob,
+				MessageArgs: []interface{}{err},
+			}
+		}
+	}
+	var values map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&values); err != nil {
+		return &SnowflakeError{
+			Number:      ErrCodeInvalidParamsBlob,
+			Message:     errMsgInvalidParamsBlob,
+			MessageArgs: []interface{}{err},
+		}
+	}
+	if cfg.Params == nil {
+		cfg.Params = make(map[string]*string)
+	}
+	for k, v := range values {
+		var value string
+		switch vv := v.(type) {
+		case nil:
+			continue
+		case json.Number:
+			value = vv.String()
+		case string, bool:
+			value = parameterValueString(vv)
+		default:
+			return &SnowflakeError{
+				Number:      ErrCodeInvalidParamsBlob,
+				Message:     errMsgInvalidParamsBlob,
+				MessageArgs: []interface{}{fmt.Sprintf("unsupported value of %v: %v", k, v)},
+			}
+		}
+		cfg.Params[k] = &value
+	}
+	return nil
+}
+
+// decodeBase64Lenient decodes the standard or URL base64 encoding, with or without padding. A '+' of the
+// standard encoding that was unescaped to a space in the query string is restored.
+func decodeBase64Lenient(s string) ([]byte, error) {
+	s = strings.TrimRight(strings.Replace(s, " ", "+", -1), "=")
+	if strings.ContainsAny(s, "-_") {
+		return base64.RawURLEncoding.DecodeString(s)
+	}
+	return base64.RawStdEncoding.DecodeString(s)
+}
+
 func parseTimeout(value string) (time.Duration, error) {
 	var vv int64
 	var err error
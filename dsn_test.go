@@ -1,9 +1,12 @@
 package gosnowflake
 
 import (
+	"encoding/base64"
 	"fmt"
 	"net/url"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -445,6 +448,46 @@ func TestParseDSN(t *testing.T) {
 			ocspMode: ocspModeFailOpen,
 			err:      nil,
 		},
+		{
+			dsn: "u:p@a.r.c.snowflakecomputing.com/db/s?account=a.r.c&maxIdleConns=100&maxIdleConnsPerHost=20&maxConnsPerHost=50&idleConnTimeout=90&enableHTTP2=true",
+			config: &Config{
+				Account: "a", User: "u", Password: "p",
+				Protocol: "https", Host: "a.r.c.snowflakecomputing.com", Port: 443,
+				Database: "db", Schema: "s", ValidateDefaultParameters: ConfigBoolTrue, OCSPFailOpen: OCSPFailOpenTrue,
+				MaxIdleConns: 100, MaxIdleConnsPerHost: 20, MaxConnsPerHost: 50, IdleConnTimeout: 90 * time.Second,
+				EnableHTTP2: true,
+			},
+			ocspMode: ocspModeFailOpen,
+			err:      nil,
+		},
+		{
+			dsn: "@a?authenticator=azure_ad&azureADResource=api%3A%2F%2Fsnowflake&azureADClientID=cid",
+			config: &Config{
+				Account: "a", Authenticator: AuthTypeAzureAD,
+				Protocol: "https", Host: "a.snowflakecomputing.com", Port: 443,
+				AzureADResource: "api://snowflake", AzureADClientID: "cid",
+				OCSPFailOpen:              OCSPFailOpenTrue,
+				ValidateDefaultParameters: ConfigBoolTrue,
+			},
+			ocspMode: ocspModeFailOpen,
+			err:      nil,
+		},
+		{
+			dsn: "u:p@a.r.c.snowflakecomputing.com/db/s?account=a.r.c&loginAttemptTimeout=5",
+			config: &Config{
+				Account: "a", User: "u", Password: "p",
+				Protocol: "https", Host: "a.r.c.snowflakecomputing.com", Port: 443,
+				Database: "db", Schema: "s", ValidateDefaultParameters: ConfigBoolTrue, OCSPFailOpen: OCSPFailOpenTrue,
+				LoginAttemptTimeout: 5 * time.Second,
+			},
+			ocspMode: ocspModeFailOpen,
+			err:      nil,
+		},
+		{
+			dsn:    "u:p@a.r.c.snowflakecomputing.com/db/s?account=a.r.c&maxConnsPerHost=many",
+			config: &Config{},
+			err:    &strconv.NumError{},
+		},
 	}
 
 	for i, test := range testcases {
@@ -523,6 +566,23 @@ func TestParseDSN(t *testing.T) {
 				t.Fatalf("%d: Failed to match ValidateDefaultParameters. expected: %v, got: %v",
 					i, test.config.ValidateDefaultParameters, cfg.ValidateDefaultParameters)
 			}
+			if test.config.MaxIdleConns != cfg.MaxIdleConns ||
+				test.config.MaxIdleConnsPerHost != cfg.MaxIdleConnsPerHost ||
+				test.config.MaxConnsPerHost != cfg.MaxConnsPerHost ||
+				test.config.IdleConnTimeout != cfg.IdleConnTimeout ||
+				test.config.EnableHTTP2 != cfg.EnableHTTP2 {
+				t.Fatalf("%d: Failed to match connection pool parameters. expected: %v, got: %v",
+					i, test.config, cfg)
+			}
+			if test.config.LoginAttemptTimeout != cfg.LoginAttemptTimeout {
+				t.Fatalf("%d: Failed to match LoginAttemptTimeout. expected: %v, got: %v",
+					i, test.config.LoginAttemptTimeout, cfg.LoginAttemptTimeout)
+			}
+			if test.config.AzureADResource != cfg.AzureADResource ||
+				test.config.AzureADClientID != cfg.AzureADClientID {
+				t.Fatalf("%d: Failed to match Azure AD parameters. expected: %v, got: %v",
+					i, test.config, cfg)
+			}
 		case test.err != nil:
 			driverErrE, okE := test.err.(*SnowflakeError)
 			driverErrG, okG := err.(*SnowflakeError)
@@ -771,6 +831,128 @@ func TestDSN(t *testing.T) {
 			},
 			dsn: "u:p@a.snowflakecomputing.com:443?insecureMode=true&ocspFailOpen=true&validateDefaultParameters=true",
 		},
+		{
+			cfg: &Config{
+				User:                "u",
+				Password:            "p",
+				Account:             "a",
+				MaxIdleConns:        100,
+				MaxIdleConnsPerHost: 20,
+				MaxConnsPerHost:     50,
+				IdleConnTimeout:     90 * time.Second,
+				EnableHTTP2:         true,
+			},
+			dsn: "u:p@a.snowflakecomputing.com:443?enableHTTP2=true&idleConnTimeout=90&maxConnsPerHost=50&maxIdleConns=100&maxIdleConnsPerHost=20&ocspFailOpen=true&validateDefaultParameters=true",
+		},
+		{
+			cfg: &Config{
+				User:      "u",
+				Password:  "p",
+				Account:   "a",
+				FetchOnly: true,
+			},
+			dsn: "u:p@a.snowflakecomputing.com:443?fetchOnly=true&ocspFailOpen=true&validateDefaultParameters=true",
+		},
+		{
+			cfg: &Config{
+				User:                "u",
+				Password:            "p",
+				Account:             "a",
+				LoginAttemptTimeout: 10 * time.Second,
+			},
+			dsn: "u:p@a.snowflakecomputing.com:443?loginAttemptTimeout=10&ocspFailOpen=true&validateDefaultParameters=true",
+		},
+		{
+			cfg: &Config{
+				User:              "u",
+				Password:          "p",
+				Account:           "a",
+				MaxInlineBindSize: 1048576,
+			},
+			dsn: "u:p@a.snowflakecomputing.com:443?maxInlineBindSize=1048576&ocspFailOpen=true&validateDefaultParameters=true",
+		},
+		{
+			cfg: &Config{
+				User:            "u",
+				Password:        "p",
+				Account:         "a",
+				MaxQueryRetries: 3,
+			},
+			dsn: "u:p@a.snowflakecomputing.com:443?maxQueryRetries=3&ocspFailOpen=true&validateDefaultParameters=true",
+		},
+		{
+			cfg: &Config{
+				User:        "u",
+				Password:    "p",
+				Account:     "a",
+				RateLimiter: NewRateLimiter(2.5, 3),
+			},
+			dsn: "u:p@a.snowflakecomputing.com:443?ocspFailOpen=true&requestRateLimit=2.5&validateDefaultParameters=true",
+		},
+		{
+			cfg: &Config{
+				User:               "u",
+				Password:           "p",
+				Account:            "a",
+				MaxRequestBodySize: 10485760,
+			},
+			dsn: "u:p@a.snowflakecomputing.com:443?maxRequestBodySize=10485760&ocspFailOpen=true&validateDefaultParameters=true",
+		},
+		{
+			cfg: &Config{
+				User:                "u",
+				Password:            "p",
+				Account:             "a",
+				ResultSpillDir:      "/tmp",
+				ResultSpillMaxBytes: 1073741824,
+			},
+			dsn: "u:p@a.snowflakecomputing.com:443?ocspFailOpen=true&resultSpillDir=%2Ftmp&resultSpillMaxBytes=1073741824&validateDefaultParameters=true",
+		},
+		{
+			cfg: &Config{
+				User:              "u",
+				Password:          "p",
+				Account:           "a",
+				DescribeCacheSize: 100,
+			},
+			dsn: "u:p@a.snowflakecomputing.com:443?describeCacheSize=100&ocspFailOpen=true&validateDefaultParameters=true",
+		},
+		{
+			cfg: &Config{
+				User:         "u",
+				Password:     "p",
+				Account:      "a",
+				AbortOnClose: true,
+			},
+			dsn: "u:p@a.snowflakecomputing.com:443?abortOnClose=true&ocspFailOpen=true&validateDefaultParameters=true",
+		},
+		{
+			cfg: &Config{
+				User:        "u",
+				Password:    "p",
+				Account:     "a",
+				PingMode:    PingModeHeartbeat,
+				PingTimeout: 5 * time.Second,
+			},
+			dsn: "u:p@a.snowflakecomputing.com:443?ocspFailOpen=true&pingMode=heartbeat&pingTimeout=5&validateDefaultParameters=true",
+		},
+		{
+			cfg: &Config{
+				User:      "u",
+				Password:  "p",
+				Account:   "a",
+				UseSQLAPI: true,
+			},
+			dsn: "u:p@a.snowflakecomputing.com:443?ocspFailOpen=true&useSQLAPI=true&validateDefaultParameters=true",
+		},
+		{
+			cfg: &Config{
+				Account:         "a",
+				Authenticator:   AuthTypeAzureAD,
+				AzureADResource: "api://snowflake",
+			},
+			dsn: ":@a.snowflakecomputing.com:443?authenticator=azure_ad&azureADResource=api%3A%2F%2Fsnowflake&ocspFailOpen=true&validateDefaultParameters=true",
+		},
 		{
 			cfg: &Config{
 				User:     "u",
@@ -820,3 +1002,147 @@ func TestDSN(t *testing.T) {
 		}
 	}
 }
+
+func TestParseDSNSpecialCharacters(t *testing.T) {
+	testcases := []struct {
+		dsn      string
+		user     string
+		password string
+		host     string
+		port     int
+		database string
+		schema   string
+		role     string
+	}{
+		{dsn: "u:p%40ss%3Aw%2Frd@a.snowflakecomputing.com:443/db", user: "u", password: "p@ss:w/rd", host: "a.snowflakecomputing.com", port: 443, database: "db"},
+		{dsn: "u:p@ss:w@a/db/sc", user: "u", password: "p@ss:w", host: "a.snowflakecomputing.com", port: 443, database: "db", schema: "sc"},
+		{dsn: "u:p/ss?w@a", user: "u", password: "p/ss?w", host: "a.snowflakecomputing.com", port: 443},
+		{dsn: "u:100%pure@a", user: "u", password: "100%pure", host: "a.snowflakecomputing.com", port: 443},
+		{dsn: "u%3Ax:p@a", user: "u:x", password: "p", host: "a.snowflakecomputing.com", port: 443},
+		{dsn: "u:p@[::1]:8080/db/sc?account=a&protocol=http", user: "u", password: "p", host: "[::1]", port: 8080, database: "db", schema: "sc"},
+		{dsn: "u:p@a/my%20db/sc?role=r1&role=r2", user: "u", password: "p", host: "a.snowflakecomputing.com", port: 443, database: "my db", schema: "sc", role: "r2"},
+		{dsn: "u:p@a?role=r%2541", user: "u", password: "p", host: "a.snowflakecomputing.com", port: 443, role: "r%41"},
+		{dsn: "u:p@ss@a/db?query_tag=a@b&role=r", user: "u", password: "p@ss", host: "a.snowflakecomputing.com", port: 443, database: "db", role: "r"},
+		{dsn: "u:p?w@a?query_tag=a@b", user: "u", password: "p?w", host: "a.snowflakecomputing.com", port: 443},
+	}
+	for _, tc := range testcases {
+		cfg, err := ParseDSN(tc.dsn)
+		if err != nil {
+			t.Errorf("failed to parse %v. err: %v", tc.dsn, err)
+			continue
+		}
+		if cfg.User != tc.user || cfg.Password != tc.password {
+			t.Errorf("unexpected credentials for %v. user: %v, password: %v", tc.dsn, cfg.User, cfg.Password)
+		}
+		if cfg.Host != tc.host || cfg.Port != tc.port {
+			t.Errorf("unexpected host for %v. host: %v, port: %v", tc.dsn, cfg.Host, cfg.Port)
+		}
+		if cfg.Database != tc.database || cfg.Schema != tc.schema || cfg.Role != tc.role {
+			t.Errorf("unexpected database, schema or role for %v. got: %v, %v, %v", tc.dsn, cfg.Database, cfg.Schema, cfg.Role)
+		}
+		dsn, err := DSN(cfg)
+		if err != nil {
+			t.Errorf("failed to build the DSN from %v. err: %v", tc.dsn, err)
+			continue
+		}
+		parsed, err := ParseDSN(dsn)
+		if err != nil {
+			t.Errorf("failed to parse %v. err: %v", dsn, err)
+			continue
+		}
+		if parsed.User != cfg.User || parsed.Password != cfg.Password || parsed.Host != cfg.Host || parsed.Port != cfg.Port {
+			t.Errorf("the DSN %v didn't round trip. got: %+v", dsn, parsed)
+		}
+	}
+	if _, err := ParseDSN("u:p@[::1]:port/db?account=a"); err == nil {
+		t.Error("should have failed to parse an invalid port")
+	}
+	if cfg, err := ParseDSN("u:p@a?query_tag=a@b"); err != nil || cfg.Params["query_tag"] == nil ||
+		*cfg.Params["query_tag"] != "a@b" {
+		t.Errorf("the '@' of a parameter should be kept in the parameter. cfg: %+v, err: %v", cfg, err)
+	}
+}
+
+func TestParseDSNParamsBlob(t *testing.T) {
+	blob := `{"TIMEZONE":"UTC","STATEMENT_TIMEOUT_IN_SECONDS":60,"CLIENT_SESSION_KEEP_ALIVE":true,"QUERY_TAG":null}`
+	for _, encoded := range []string{
+		url.QueryEscape(blob),
+		base64.URLEncoding.EncodeToString([]byte(blob)),
+		base64.RawURLEncoding.EncodeToString([]byte(blob)),
+		base64.StdEncoding.EncodeToString([]byte(blob)),
+	} {
+		cfg, err := ParseDSN("u:p@a?TIMEZONE=America/Los_Angeles&params=" + encoded)
+		if err != nil {
+			t.Errorf("failed to parse the params %v. err: %v", encoded, err)
+			continue
+		}
+		expected := map[string]string{
+			"TIMEZONE":                     "UTC",
+			"STATEMENT_TIMEOUT_IN_SECONDS": "60",
+			"CLIENT_SESSION_KEEP_ALIVE":    "true",
+		}
+		if len(cfg.Params) != len(expected) {
+			t.Errorf("unexpected params for %v: %v", encoded, cfg.Params)
+		}
+		for k, v := range expected {
+			if cfg.Params[k] == nil || *cfg.Params[k] != v {
+				t.Errorf("unexpected value of %v for %v: %v", k, encoded, cfg.Params[k])
+			}
+		}
+	}
+	for _, invalid := range []string{"%7Bnot-json", "!!!", url.QueryEscape(`{"A":[1]}`)} {
+		_, err := ParseDSN("u:p@a?params=" + invalid)
+		if driverErr, ok := err.(*SnowflakeError); !ok || driverErr.Number != ErrCodeInvalidParamsBlob {
+			t.Errorf("should have failed to parse the params %v. err: %v", invalid, err)
+		}
+	}
+}
+
+func TestParseDSNDisableFeatures(t *testing.T) {
+	cfg, err := ParseDSN("u:p@a?disableHeartbeat=true&disableTelemetry=true&insecureModeOCSP=true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.DisableHeartbeat || !cfg.DisableTelemetry || !cfg.InsecureMode || cfg.ocspMode() != ocspModeInsecure {
+		t.Fatalf("the features should be disabled: %+v", cfg)
+	}
+	if v := normalizeSessionParams(cfg)[sessionClientTelemetryEnabled]; v == nil || *v != "false" {
+		t.Fatalf("CLIENT_TELEMETRY_ENABLED should be false: %v", v)
+	}
+	dsn, err := DSN(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, param := range []string{"disableHeartbeat=true", "disableTelemetry=true", "insecureMode=true"} {
+		if !strings.Contains(dsn, param) {
+			t.Errorf("the DSN should have %v: %v", param, dsn)
+		}
+	}
+	if cfg, err = ParseDSN("u:p@a?insecureMode=false&insecureModeOCSP=false"); err != nil || cfg.InsecureMode {
+		t.Fatalf("the same values should not conflict. cfg: %+v, err: %v", cfg, err)
+	}
+	if cfg, err = ParseDSN("u:p@a?insecureMode=true&insecureMode=false"); err != nil || cfg.InsecureMode {
+		t.Fatalf("the last value of a repeated parameter should be taken. cfg: %+v, err: %v", cfg, err)
+	}
+
+	for _, dsn := range []string{
+		"u:p@a?insecureMode=true&insecureModeOCSP=false",
+		"u:p@a?insecureModeOCSP=false&insecureMode=false&insecureMode=true",
+		"u:p@a?disableHeartbeat=true&CLIENT_SESSION_KEEP_ALIVE=true",
+		"u:p@a?disableTelemetry=true&client_telemetry_enabled=true",
+	} {
+		_, err = ParseDSN(dsn)
+		if driverErr, ok := err.(*SnowflakeError); !ok || driverErr.Number != ErrCodeConflictingParameters {
+			t.Errorf("%v should have conflicting parameters. err: %v", dsn, err)
+		}
+	}
+	for _, dsn := range []string{
+		"u:p@a?disableHeartbeat=maybe",
+		"u:p@a?disableTelemetry=1x",
+		"u:p@a?insecureModeOCSP=yes",
+	} {
+		if _, err = ParseDSN(dsn); err == nil {
+			t.Errorf("%v should fail with an invalid boolean", dsn)
+		}
+	}
+}
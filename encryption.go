@@ -0,0 +1,259 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// The client-side encryption of the files of the internal stages. Every file is encrypted with AES-CBC with its
+// own random key, the file key, which is wrapped by a FileKeyWrapper and stored with the file in its metadata.
+// By default the file key is wrapped with the master key of the stage the PUT response carries, with AES-ECB.
+// PutFiles encrypts the files of the stages that have isClientSideEncrypted in the PUT response with encryptFile,
+// and GetFile decrypts the encrypted files with decryptFile, with the FileKeyWrapper of the Config.
+
+// EncryptionMaterial is the encryption material of a stage in the response of PUT and GET.
+type EncryptionMaterial struct {
+	QueryStageMasterKey string `json:"queryStageMasterKey"` // base64 encoded AES key of 128, 192 or 256 bits
+	QueryID             string `json:"queryId"`             // the query ID of the PUT or GET
+	SMKID               int64  `json:"smkId"`               // the ID of the master key
+}
+
+// encryptionMaterials are the encryption materials of the response of PUT, which has one for all the files, and of
+// GET, which has one per file in the order of the files, null for a file that is not encrypted.
+type encryptionMaterials []*EncryptionMaterial
+
+func (m *encryptionMaterials) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if len(b) > 0 && b[0] == '[' {
+		return json.Unmarshal(b, (*[]*EncryptionMaterial)(m))
+	}
+	var material *EncryptionMaterial
+	if err := json.Unmarshal(b, &material); err != nil {
+		return err
+	}
+	*m = nil
+	if material != nil {
+		*m = encryptionMaterials{material}
+	}
+	return nil
+}
+
+// materialDescriptor identifies the master key that wrapped the file key, stored as the matdesc metadata of the
+// file.
+type materialDescriptor struct {
+	SMKID   string `json:"smkId"`
+	QueryID string `json:"queryId"`
+	KeySize string `json:"keySize"` // in bits
+}
+
+// encryptionMetadata is the metadata of an encrypted file, stored with the file in the stage.
+type encryptionMetadata struct {
+	key     string // base64 encoded wrapped file key
+	iv      string // base64 encoded initialization vector of AES-CBC
+	matdesc string // JSON encoded materialDescriptor
+}
+
+// FileKeyWrapper wraps the file keys of the files uploaded to a client-side encrypted stage, e.g., with a KMS that
+// escrows them, and unwraps those of the files downloaded. It is set by Config.FileKeyWrapper, and the file keys are
+// wrapped with the master key of the stage in the material by default. Snowflake unwraps the file keys with the
+// master key to read the files, e.g., in COPY INTO, so the files of another wrapper are only read back by GetFile
+// with the same wrapper.
+type FileKeyWrapper interface {
+	WrapKey(ctx context.Context, fileKey []byte, material *EncryptionMaterial) ([]byte, error)
+	UnwrapKey(ctx context.Context, wrappedKey []byte, material *EncryptionMaterial) ([]byte, error)
+}
+
+// masterKeyWrapper wraps the file keys with the master key of the stage, as the other Snowflake clients do.
+type masterKeyWrapper struct{}
+
+func (masterKeyWrapper) WrapKey(_ context.Context, fileKey []byte, material *EncryptionMaterial) ([]byte, error) {
+	block, err := masterKeyCipher(material)
+	if err != nil {
+		return nil, err
+	}
+	wrapped := pkcs7Pad(fileKey, aes.BlockSize)
+	for i := 0; i < len(wrapped); i += aes.BlockSize {
+		block.Encrypt(wrapped[i:i+aes.BlockSize], wrapped[i:i+aes.BlockSize])
+	}
+	return wrapped, nil
+}
+
+func (masterKeyWrapper) UnwrapKey(_ context.Context, wrappedKey []byte, material *EncryptionMaterial) ([]byte,
+	error) {
+	block, err := masterKeyCipher(material)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrappedKey) == 0 || len(wrappedKey)%aes.BlockSize != 0 {
+		return nil, errors.New("invalid length of the wrapped file key")
+	}
+	fileKey := make([]byte, len(wrappedKey))
+	for i := 0; i < len(wrappedKey); i += aes.BlockSize {
+		block.Decrypt(fileKey[i:i+aes.BlockSize], wrappedKey[i:i+aes.BlockSize])
+	}
+	return pkcs7Unpad(fileKey, aes.BlockSize)
+}
+
+func masterKeyCipher(material *EncryptionMaterial) (cipher.Block, error) {
+	masterKey, err := base64.StdEncoding.DecodeString(material.QueryStageMasterKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid master key of the stage: %v", err)
+	}
+	return aes.NewCipher(masterKey)
+}
+
+// encryptFile returns the content read from r encrypted with a new file key, and the metadata of the encrypted
+// file with the file key wrapped by the wrapper. The file key has the size of the master key of the stage. The
+// content is encrypted while it is read, and its length is that of the plain content rounded up to the next
+// multiple of the block size, since the last block is always padded.
+func encryptFile(ctx context.Context, r io.Reader, material *EncryptionMaterial, wrapper FileKeyWrapper) (
+	io.Reader, *encryptionMetadata, error) {
+	masterKey, err := base64.StdEncoding.DecodeString(material.QueryStageMasterKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid master key of the stage: %v", err)
+	}
+	fileKey := make([]byte, len(masterKey))
+	iv := make([]byte, aes.BlockSize)
+	if _, err = rand.Read(fileKey); err != nil {
+		return nil, nil, err
+	}
+	if _, err = rand.Read(iv); err != nil {
+		return nil, nil, err
+	}
+	block, err := aes.NewCipher(fileKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	wrappedKey, err := wrapper.WrapKey(ctx, fileKey, material)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to wrap the file key: %v", err)
+	}
+	matdesc, err := json.Marshal(materialDescriptor{
+		SMKID:   strconv.FormatInt(material.SMKID, 10),
+		QueryID: material.QueryID,
+		KeySize: strconv.Itoa(len(masterKey) * 8),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &cbcEncryptReader{r: r, mode: cipher.NewCBCEncrypter(block, iv), chunk: make([]byte, 64*1024)},
+		&encryptionMetadata{
+			key:     base64.StdEncoding.EncodeToString(wrappedKey),
+			iv:      base64.StdEncoding.EncodeToString(iv),
+			matdesc: string(matdesc),
+		}, nil
+}
+
+// encryptedSize returns the length of the content of size bytes encrypted by encryptFile.
+func encryptedSize(size int64) int64 {
+	return size + aes.BlockSize - size%aes.BlockSize
+}
+
+// cbcEncryptReader encrypts the content of r with AES-CBC while it is read.
+type cbcEncryptReader struct {
+	r       io.Reader
+	mode    cipher.BlockMode
+	chunk   []byte
+	pending []byte // the encrypted bytes not read yet
+	eof     bool
+}
+
+func (e *cbcEncryptReader) Read(p []byte) (int, error) {
+	for len(e.pending) == 0 {
+		if e.eof {
+			return 0, io.EOF
+		}
+		n, err := io.ReadFull(e.r, e.chunk)
+		blocks := e.chunk[:n]
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			// the last block is padded, also if the content is a multiple of the block size
+			blocks = pkcs7Pad(blocks, aes.BlockSize)
+			e.eof = true
+		} else if err != nil {
+			return 0, err
+		}
+		e.mode.CryptBlocks(blocks, blocks)
+		e.pending = blocks
+	}
+	n := copy(p, e.pending)
+	e.pending = e.pending[n:]
+	return n, nil
+}
+
+// decryptFile decrypts the content of the file encrypted by encryptFile, or by another Snowflake client, read
+// from r to w.
+func decryptFile(ctx context.Context, r io.Reader, w io.Writer, material *EncryptionMaterial,
+	metadata *encryptionMetadata, wrapper FileKeyWrapper) error {
+	wrappedKey, err := base64.StdEncoding.DecodeString(metadata.key)
+	if err != nil {
+		return fmt.Errorf("invalid file key: %v", err)
+	}
+	iv, err := base64.StdEncoding.DecodeString(metadata.iv)
+	if err != nil || len(iv) != aes.BlockSize {
+		return fmt.Errorf("invalid initialization vector: %v", metadata.iv)
+	}
+	fileKey, err := wrapper.UnwrapKey(ctx, wrappedKey, material)
+	if err != nil {
+		return fmt.Errorf("failed to unwrap the file key: %v", err)
+	}
+	block, err := aes.NewCipher(fileKey)
+	if err != nil {
+		return err
+	}
+	mode := cipher.NewCBCDecrypter(block, iv)
+	// the last block is held back until the end of the content to remove the padding
+	buf := make([]byte, 64*1024+aes.BlockSize)
+	held := 0
+	for {
+		n, err := io.ReadFull(r, buf[held:])
+		n += held
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			if n == 0 || n%aes.BlockSize != 0 {
+				return errors.New("invalid length of the encrypted file")
+			}
+			mode.CryptBlocks(buf[:n], buf[:n])
+			last, err := pkcs7Unpad(buf[:n], aes.BlockSize)
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(last)
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		mode.CryptBlocks(buf[:n-aes.BlockSize], buf[:n-aes.BlockSize])
+		if _, err = w.Write(buf[:n-aes.BlockSize]); err != nil {
+			return err
+		}
+		copy(buf, buf[n-aes.BlockSize:n])
+		held = aes.BlockSize
+	}
+}
+
+func pkcs7Pad(b []byte, blockSize int) []byte {
+	padding := blockSize - len(b)%blockSize
+	return append(append(make([]byte, 0, len(b)+padding), b...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+}
+
+func pkcs7Unpad(b []byte, blockSize int) ([]byte, error) {
+	if len(b) == 0 || len(b)%blockSize != 0 {
+		return nil, errors.New("invalid padding")
+	}
+	padding := int(b[len(b)-1])
+	if padding == 0 || padding > blockSize || !bytes.Equal(b[len(b)-padding:], bytes.Repeat(b[len(b)-1:], padding)) {
+		return nil, errors.New("invalid padding")
+	}
+	return b[:len(b)-padding], nil
+}
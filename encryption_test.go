@@ -0,0 +1,118 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"sync"
+	"testing"
+)
+
+// reversingKeyWrapper stands for a KMS wrapping the file keys.
+type reversingKeyWrapper struct {
+	mu        sync.Mutex
+	wrapped   int
+	unwrapped int
+}
+
+func reverseKey(key []byte) []byte {
+	reversed := make([]byte, len(key))
+	for i, b := range key {
+		reversed[len(key)-1-i] = b
+	}
+	return reversed
+}
+
+func (w *reversingKeyWrapper) WrapKey(_ context.Context, fileKey []byte, _ *EncryptionMaterial) ([]byte, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.wrapped++
+	return reverseKey(fileKey), nil
+}
+
+func (w *reversingKeyWrapper) UnwrapKey(_ context.Context, wrappedKey []byte, _ *EncryptionMaterial) ([]byte, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.unwrapped++
+	return reverseKey(wrappedKey), nil
+}
+
+func (w *reversingKeyWrapper) counts() (wrapped, unwrapped int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.wrapped, w.unwrapped
+}
+
+// encryptTestFile returns the content encrypted by encryptFile and its metadata.
+func encryptTestFile(t *testing.T, content []byte, material *EncryptionMaterial, wrapper FileKeyWrapper) (
+	*bytes.Buffer, *encryptionMetadata) {
+	r, metadata, err := encryptFile(context.Background(), bytes.NewReader(content), material, wrapper)
+	if err != nil {
+		t.Fatalf("failed to encrypt. size: %v, err: %v", len(content), err)
+	}
+	var encrypted bytes.Buffer
+	if _, err = encrypted.ReadFrom(r); err != nil {
+		t.Fatalf("failed to encrypt. size: %v, err: %v", len(content), err)
+	}
+	return &encrypted, metadata
+}
+
+func TestEncryptFile(t *testing.T) {
+	masterKey := make([]byte, 32)
+	rand.Read(masterKey)
+	material := &EncryptionMaterial{
+		QueryStageMasterKey: base64.StdEncoding.EncodeToString(masterKey),
+		QueryID:             "q1",
+		SMKID:               1234,
+	}
+	for _, size := range []int{0, 1, 16, 64 * 1024, 64*1024 + 17, 300 * 1024} {
+		content := make([]byte, size)
+		rand.Read(content)
+		for _, wrapper := range []FileKeyWrapper{masterKeyWrapper{}, &reversingKeyWrapper{}} {
+			encrypted, metadata := encryptTestFile(t, content, material, wrapper)
+			if encrypted.Len() != size+16-size%16 || size >= 16 && bytes.Contains(encrypted.Bytes(), content) {
+				t.Fatalf("the content should be encrypted. size: %v, encrypted: %v", size, encrypted.Len())
+			}
+			var decrypted bytes.Buffer
+			if err := decryptFile(context.Background(), encrypted, &decrypted, material, metadata, wrapper); err != nil {
+				t.Fatalf("failed to decrypt. size: %v, err: %v", size, err)
+			}
+			if !bytes.Equal(decrypted.Bytes(), content) {
+				t.Fatalf("the decrypted content should be the original one. size: %v", size)
+			}
+		}
+	}
+
+	encrypted, metadata := encryptTestFile(t, []byte("data"), material, masterKeyWrapper{})
+	var desc materialDescriptor
+	if err := json.Unmarshal([]byte(metadata.matdesc), &desc); err != nil ||
+		desc != (materialDescriptor{SMKID: "1234", QueryID: "q1", KeySize: "256"}) {
+		t.Fatalf("unexpected material descriptor: %v, err: %v", metadata.matdesc, err)
+	}
+	if key, _ := base64.StdEncoding.DecodeString(metadata.key); len(key) != 48 {
+		t.Fatalf("the file key should be wrapped with its padding. length: %v", len(key))
+	}
+	rand.Read(masterKey)
+	other := &EncryptionMaterial{QueryStageMasterKey: base64.StdEncoding.EncodeToString(masterKey)}
+	err := decryptFile(context.Background(), encrypted, &bytes.Buffer{}, other, metadata, masterKeyWrapper{})
+	if err == nil {
+		t.Fatal("the file should not be decrypted with another master key")
+	}
+}
+
+func TestEncryptFileWithKeyWrapper(t *testing.T) {
+	material := &EncryptionMaterial{QueryStageMasterKey: base64.StdEncoding.EncodeToString(make([]byte, 16))}
+	wrapper := &reversingKeyWrapper{}
+	encrypted, metadata := encryptTestFile(t, []byte("data"), material, wrapper)
+	if wrapped, _ := wrapper.counts(); wrapped != 1 {
+		t.Fatal("the file key should be wrapped by the wrapper")
+	}
+	err := decryptFile(context.Background(), encrypted, &bytes.Buffer{}, material, metadata, masterKeyWrapper{})
+	if err == nil {
+		t.Fatal("the file key should not be unwrapped with the master key")
+	}
+}
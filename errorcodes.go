@@ -0,0 +1,241 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"errors"
+	"net"
+)
+
+// ErrorCategory is the kind of failure an error number of the driver stands for.
+type ErrorCategory int
+
+const (
+	// ErrorCategoryUnknown is the category of the errors not in the catalog, e.g., the SQL errors of Snowflake
+	// the driver doesn't handle.
+	ErrorCategoryUnknown ErrorCategory = iota
+	// ErrorCategoryConfig is the category of the errors of an invalid Config or DSN, or of a feature that is not
+	// supported with the Config.
+	ErrorCategoryConfig
+	// ErrorCategoryAuth is the category of the errors of the login and the authorization of the requests.
+	ErrorCategoryAuth
+	// ErrorCategoryNetwork is the category of the failed requests to Snowflake.
+	ErrorCategoryNetwork
+	// ErrorCategoryResult is the category of the errors of reading the results.
+	ErrorCategoryResult
+	// ErrorCategoryTransaction is the category of the unsupported transaction options and of the migration locks
+	// that are not held.
+	ErrorCategoryTransaction
+	// ErrorCategoryConversion is the category of the values of the results that cannot be converted.
+	ErrorCategoryConversion
+	// ErrorCategoryOCSP is the category of the failed revocation checks of the certificates.
+	ErrorCategoryOCSP
+	// ErrorCategorySQL is the category of the errors of the statements reported by Snowflake.
+	ErrorCategorySQL
+	// ErrorCategorySession is the category of the errors of a session that no longer exists.
+	ErrorCategorySession
+)
+
+func (c ErrorCategory) String() string {
+	switch c {
+	case ErrorCategoryConfig:
+		return "config"
+	case ErrorCategoryAuth:
+		return "auth"
+	case ErrorCategoryNetwork:
+		return "network"
+	case ErrorCategoryResult:
+		return "result"
+	case ErrorCategoryTransaction:
+		return "transaction"
+	case ErrorCategoryConversion:
+		return "conversion"
+	case ErrorCategoryOCSP:
+		return "ocsp"
+	case ErrorCategorySQL:
+		return "sql"
+	case ErrorCategorySession:
+		return "session"
+	default:
+		return "unknown"
+	}
+}
+
+// ErrorCode describes an error number the driver returns in SnowflakeError.Number.
+type ErrorCode struct {
+	Number   int
+	Name     string // the name of the constant, e.g., ErrFailedToPostQuery
+	Category ErrorCategory
+}
+
+// errorCatalog has every error number defined by the driver, and the numbers of the errors of Snowflake the
+// driver handles.
+var errorCatalog = []ErrorCode{
+	{ErrCodeEmptyAccountCode, "ErrCodeEmptyAccountCode", ErrorCategoryConfig},
+	{ErrCodeEmptyUsernameCode, "ErrCodeEmptyUsernameCode", ErrorCategoryConfig},
+	{ErrCodeEmptyPasswordCode, "ErrCodeEmptyPasswordCode", ErrorCategoryConfig},
+	{ErrCodeFailedToParseHost, "ErrCodeFailedToParseHost", ErrorCategoryConfig},
+	{ErrCodeFailedToParsePort, "ErrCodeFailedToParsePort", ErrorCategoryConfig},
+	{ErrCodeIdpConnectionError, "ErrCodeIdpConnectionError", ErrorCategoryAuth},
+	{ErrCodeSSOURLNotMatch, "ErrCodeSSOURLNotMatch", ErrorCategoryAuth},
+	{ErrCodeServiceUnavailable, "ErrCodeServiceUnavailable", ErrorCategoryNetwork},
+	{ErrCodeFailedToConnect, "ErrCodeFailedToConnect", ErrorCategoryNetwork},
+	{ErrCodeRegionOverlap, "ErrCodeRegionOverlap", ErrorCategoryConfig},
+	{ErrCodePrivateKeyParseError, "ErrCodePrivateKeyParseError", ErrorCategoryAuth},
+	{ErrCodeFailedToParseAuthenticator, "ErrCodeFailedToParseAuthenticator", ErrorCategoryConfig},
+	{ErrCodeFetchOnlyConnection, "ErrCodeFetchOnlyConnection", ErrorCategoryConfig},
+	{ErrCodeInvalidExportedSession, "ErrCodeInvalidExportedSession", ErrorCategoryConfig},
+	{ErrCodeLoginThrottled, "ErrCodeLoginThrottled", ErrorCategoryAuth},
+	{ErrCodeInvalidSessionParameter, "ErrCodeInvalidSessionParameter", ErrorCategoryConfig},
+	{ErrCodeNoWarehouse, "ErrCodeNoWarehouse", ErrorCategoryConfig},
+	{ErrCodeInvalidParamsBlob, "ErrCodeInvalidParamsBlob", ErrorCategoryConfig},
+	{ErrCodeInvalidClientRedirect, "ErrCodeInvalidClientRedirect", ErrorCategoryNetwork},
+	{ErrCodeInvalidFailoverURL, "ErrCodeInvalidFailoverURL", ErrorCategoryConfig},
+	{ErrCodeFileTransferNotSupported, "ErrCodeFileTransferNotSupported", ErrorCategoryConfig},
+	{ErrCodeCassetteNotRecorded, "ErrCodeCassetteNotRecorded", ErrorCategoryNetwork},
+	{ErrCodeInvalidHTTPHeader, "ErrCodeInvalidHTTPHeader", ErrorCategoryConfig},
+	{ErrCodeMultiStatementBindings, "ErrCodeMultiStatementBindings", ErrorCategoryConfig},
+	{ErrCodeRequestTooLarge, "ErrCodeRequestTooLarge", ErrorCategoryConfig},
+	{ErrCodePasswordExpired, "ErrCodePasswordExpired", ErrorCategoryAuth},
+	{ErrCodeOktaMFAFailed, "ErrCodeOktaMFAFailed", ErrorCategoryAuth},
+	{ErrCodeInvalidQueryTag, "ErrCodeInvalidQueryTag", ErrorCategoryConfig},
+	{ErrCodeResultSpillLimitExceeded, "ErrCodeResultSpillLimitExceeded", ErrorCategoryResult},
+	{ErrCodeInvalidResultURL, "ErrCodeInvalidResultURL", ErrorCategoryNetwork},
+	{ErrCodeSQLAPINotSupported, "ErrCodeSQLAPINotSupported", ErrorCategoryConfig},
+	{ErrCodeRequestAuthorizationFailed, "ErrCodeRequestAuthorizationFailed", ErrorCategoryAuth},
+	{ErrCodeConflictingParameters, "ErrCodeConflictingParameters", ErrorCategoryConfig},
+
+	{ErrFailedToPostQuery, "ErrFailedToPostQuery", ErrorCategoryNetwork},
+	{ErrFailedToRenewSession, "ErrFailedToRenewSession", ErrorCategoryNetwork},
+	{ErrFailedToCancelQuery, "ErrFailedToCancelQuery", ErrorCategoryNetwork},
+	{ErrFailedToCloseSession, "ErrFailedToCloseSession", ErrorCategoryNetwork},
+	{ErrFailedToAuth, "ErrFailedToAuth", ErrorCategoryAuth},
+	{ErrFailedToAuthSAML, "ErrFailedToAuthSAML", ErrorCategoryAuth},
+	{ErrFailedToAuthOKTA, "ErrFailedToAuthOKTA", ErrorCategoryAuth},
+	{ErrFailedToGetSSO, "ErrFailedToGetSSO", ErrorCategoryAuth},
+	{ErrFailedToParseResponse, "ErrFailedToParseResponse", ErrorCategoryAuth},
+	{ErrFailedToGetExternalBrowserResponse, "ErrFailedToGetExternalBrowserResponse", ErrorCategoryAuth},
+	{ErrFailedToHeartbeat, "ErrFailedToHeartbeat", ErrorCategoryNetwork},
+	{ErrFailedToGetAzureADToken, "ErrFailedToGetAzureADToken", ErrorCategoryAuth},
+	{ErrFailedToGetQueryStatus, "ErrFailedToGetQueryStatus", ErrorCategoryNetwork},
+	{ErrFailedToDownloadFile, "ErrFailedToDownloadFile", ErrorCategoryNetwork},
+	{ErrLoginTimeout, "ErrLoginTimeout", ErrorCategoryNetwork},
+	{ErrFailedToUploadFile, "ErrFailedToUploadFile", ErrorCategoryNetwork},
+
+	{ErrFailedToGetChunk, "ErrFailedToGetChunk", ErrorCategoryNetwork},
+	{ErrResultTruncated, "ErrResultTruncated", ErrorCategoryResult},
+	{ErrUnsupportedResultFormat, "ErrUnsupportedResultFormat", ErrorCategoryResult},
+	{ErrUnexpectedResultShape, "ErrUnexpectedResultShape", ErrorCategoryResult},
+
+	{ErrNoReadOnlyTransaction, "ErrNoReadOnlyTransaction", ErrorCategoryTransaction},
+	{ErrNoDefaultTransactionIsolationLevel, "ErrNoDefaultTransactionIsolationLevel", ErrorCategoryTransaction},
+	{ErrMigrationLocked, "ErrMigrationLocked", ErrorCategoryTransaction},
+	{ErrMigrationLockLost, "ErrMigrationLockLost", ErrorCategoryTransaction},
+
+	{ErrInvalidTimestampTz, "ErrInvalidTimestampTz", ErrorCategoryConversion},
+	{ErrInvalidOffsetStr, "ErrInvalidOffsetStr", ErrorCategoryConversion},
+	{ErrInvalidBinaryHexForm, "ErrInvalidBinaryHexForm", ErrorCategoryConversion},
+	{ErrNonFiniteFloat, "ErrNonFiniteFloat", ErrorCategoryConversion},
+	{ErrLossyScan, "ErrLossyScan", ErrorCategoryConversion},
+
+	{ErrOCSPStatusRevoked, "ErrOCSPStatusRevoked", ErrorCategoryOCSP},
+	{ErrOCSPStatusUnknown, "ErrOCSPStatusUnknown", ErrorCategoryOCSP},
+	{ErrOCSPInvalidValidity, "ErrOCSPInvalidValidity", ErrorCategoryOCSP},
+	{ErrOCSPNoOCSPResponderURL, "ErrOCSPNoOCSPResponderURL", ErrorCategoryOCSP},
+
+	{ErrInternalError, "ErrInternalError", ErrorCategorySQL},
+	{ErrNoActiveWarehouse, "ErrNoActiveWarehouse", ErrorCategorySQL},
+	{ErrObjectNotExist, "ErrObjectNotExist", ErrorCategorySQL},
+
+	{ErrSessionGone, "ErrSessionGone", ErrorCategorySession},
+	{ErrUserTemporarilyLocked, "ErrUserTemporarilyLocked", ErrorCategoryAuth},
+	{ErrRoleNotExist, "ErrRoleNotExist", ErrorCategoryAuth},
+	{ErrObjectNotExistOrAuthorized, "ErrObjectNotExistOrAuthorized", ErrorCategorySQL},
+}
+
+var errorCatalogByNumber = func() map[int]ErrorCode {
+	m := make(map[int]ErrorCode, len(errorCatalog))
+	for _, code := range errorCatalog {
+		m[code.Number] = code
+	}
+	return m
+}()
+
+// ErrorCodes returns the catalog of the error numbers the driver returns in SnowflakeError.Number: the numbers
+// defined by the driver, and those of the errors of Snowflake the driver handles. Snowflake returns many other
+// numbers for the errors of the statements.
+func ErrorCodes() []ErrorCode {
+	return append([]ErrorCode(nil), errorCatalog...)
+}
+
+// LookupErrorCode returns the ErrorCode of the error number, or false if it is not in the catalog.
+func LookupErrorCode(number int) (ErrorCode, bool) {
+	code, ok := errorCatalogByNumber[number]
+	return code, ok
+}
+
+// asSnowflakeError returns the SnowflakeError in the chain of the error, including those embedded in the errors
+// of the login.
+func asSnowflakeError(err error) (*SnowflakeError, bool) {
+	var se *SnowflakeError
+	if errors.As(err, &se) {
+		return se, true
+	}
+	var throttled *LoginThrottledError
+	if errors.As(err, &throttled) {
+		return &throttled.SnowflakeError, true
+	}
+	var expired *PasswordExpiredError
+	if errors.As(err, &expired) {
+		return &expired.SnowflakeError, true
+	}
+	var timeout *LoginTimeoutError
+	if errors.As(err, &timeout) {
+		return &timeout.SnowflakeError, true
+	}
+	return nil, false
+}
+
+// ErrorCategoryOf returns the category of the error number of the SnowflakeError in the chain of the error, or
+// ErrorCategoryUnknown if there is none or the number is not in the catalog.
+func ErrorCategoryOf(err error) ErrorCategory {
+	se, ok := asSnowflakeError(err)
+	if !ok {
+		return ErrorCategoryUnknown
+	}
+	return errorCatalogByNumber[se.Number].Category
+}
+
+// IsAuthError returns true if the error is of the login or of the authorization of a request, e.g., the
+// credentials are rejected or the user is locked.
+func IsAuthError(err error) bool {
+	if ErrorCategoryOf(err) == ErrorCategoryAuth {
+		return true
+	}
+	se, ok := asSnowflakeError(err)
+	return ok && se.SQLState == SQLStateConnectionRejected
+}
+
+// IsNetworkError returns true if the error is of a request to Snowflake or to the cloud storage of the results
+// that failed, e.g., because Snowflake is unreachable.
+func IsNetworkError(err error) bool {
+	if ErrorCategoryOf(err) == ErrorCategoryNetwork {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// IsObjectNotFound returns true if the error is of an object that does not exist or is not authorized, e.g., a
+// table of a statement, or the database or the role of the session.
+func IsObjectNotFound(err error) bool {
+	se, ok := asSnowflakeError(err)
+	if !ok {
+		return false
+	}
+	switch se.Number {
+	case ErrObjectNotExist, ErrObjectNotExistOrAuthorized, ErrRoleNotExist:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestErrorCodesCatalog(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "errors.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	numbers := make(map[int]bool)
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			vs := spec.(*ast.ValueSpec)
+			for i, name := range vs.Names {
+				lit, ok := vs.Values[i].(*ast.BasicLit)
+				if !ok || lit.Kind != token.INT || !strings.HasPrefix(name.Name, "Err") {
+					continue
+				}
+				n, _ := strconv.Atoi(lit.Value)
+				code, ok := LookupErrorCode(n)
+				if !ok || code.Name != name.Name {
+					t.Errorf("%v (%v) should be in the catalog. got: %+v", name.Name, n, code)
+				}
+				numbers[n] = true
+			}
+		}
+	}
+	codes := ErrorCodes()
+	if len(codes) != len(numbers) {
+		t.Errorf("every number should be in the catalog once. catalog: %v, errors.go: %v", len(codes), len(numbers))
+	}
+	for _, code := range codes {
+		if code.Category == ErrorCategoryUnknown {
+			t.Errorf("%v should have a category", code.Name)
+		}
+	}
+	codes[0].Name = "modified"
+	if ErrorCodes()[0].Name == "modified" {
+		t.Error("the catalog should not be modified by the caller")
+	}
+}
+
+func TestErrorPredicates(t *testing.T) {
+	testcases := []struct {
+		err      error
+		auth     bool
+		network  bool
+		notFound bool
+		category ErrorCategory
+	}{
+		{err: &SnowflakeError{Number: ErrFailedToAuth}, auth: true, category: ErrorCategoryAuth},
+		{err: &SnowflakeError{Number: 390100, SQLState: SQLStateConnectionRejected}, auth: true},
+		{err: &LoginThrottledError{SnowflakeError: SnowflakeError{Number: ErrCodeLoginThrottled}}, auth: true, category: ErrorCategoryAuth},
+		{err: &LoginTimeoutError{SnowflakeError: SnowflakeError{Number: ErrLoginTimeout}}, network: true, category: ErrorCategoryNetwork},
+		{err: fmt.Errorf("query: %w", &SnowflakeError{Number: ErrFailedToPostQuery}), network: true, category: ErrorCategoryNetwork},
+		{err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}, network: true},
+		{err: fmt.Errorf("select: %w", &SnowflakeError{Number: ErrObjectNotExist}), notFound: true, category: ErrorCategorySQL},
+		{err: &SnowflakeError{Number: ErrRoleNotExist}, auth: true, notFound: true, category: ErrorCategoryAuth},
+		{err: &SnowflakeError{Number: 1003}},
+		{err: errors.New("failed")},
+		{err: nil},
+	}
+	for _, tc := range testcases {
+		t.Run(fmt.Sprint(tc.err), func(t *testing.T) {
+			if IsAuthError(tc.err) != tc.auth || IsNetworkError(tc.err) != tc.network ||
+				IsObjectNotFound(tc.err) != tc.notFound || ErrorCategoryOf(tc.err) != tc.category {
+				t.Fatalf("unexpected result. auth: %v, network: %v, not found: %v, category: %v",
+					IsAuthError(tc.err), IsNetworkError(tc.err), IsObjectNotFound(tc.err), ErrorCategoryOf(tc.err))
+			}
+		})
+	}
+}
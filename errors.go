@@ -4,6 +4,8 @@ package gosnowflake
 
 import (
 	"fmt"
+	"strings"
+	"time"
 )
 
 // SnowflakeError is a error type including various Snowflake specific information.
@@ -14,6 +16,10 @@ type SnowflakeError struct {
 	Message        string
 	MessageArgs    []interface{}
 	IncludeQueryID bool // TODO: populate this in connection
+	// RequestID and RequestGUID identify the request of the query for Snowflake support, together with QueryID.
+	// RequestGUID is that of the last HTTP request sent for the query.
+	RequestID   string
+	RequestGUID string
 }
 
 func (se *SnowflakeError) Error() string {
@@ -33,6 +39,120 @@ func (se *SnowflakeError) Error() string {
 	return fmt.Sprintf("%06d: %s", se.Number, message)
 }
 
+// LoginThrottledError is returned when Snowflake rejects the login because of too many login attempts,
+// so that the caller can tell throttling from bad credentials. RetryAfter is the wait time suggested by
+// the server, if any.
+type LoginThrottledError struct {
+	SnowflakeError
+	RetryAfter time.Duration
+}
+
+// PasswordExpiredError is returned when Snowflake rejects the login because the password of the user has expired
+// and must be changed, and no Config.NewPassword is given to change it at login.
+type PasswordExpiredError struct {
+	SnowflakeError
+	User string
+}
+
+// LoginTimeoutError is returned when every login attempt timed out within the login timeout. Attempts has
+// the timing of each attempt, so that a slow TLS handshake, which includes the OCSP check, can be told from a
+// slow login endpoint.
+type LoginTimeoutError struct {
+	SnowflakeError
+	Elapsed  time.Duration
+	Attempts []LoginAttempt
+}
+
+// LoginAttempt is the timing of a login attempt that timed out.
+type LoginAttempt struct {
+	Timeout      time.Duration // timeout of the attempt
+	Elapsed      time.Duration // time until the attempt was abandoned
+	TLSHandshake time.Duration // time spent in the TLS handshakes including the OCSP checks
+}
+
+// ConversionError is returned by Rows.Next when a value of a result cannot be converted to a Go value. Row
+// is the zero-based index of the row in the result, or -1 if the value was converted with its whole chunk,
+// as in the Arrow format. Value is the value sent by Snowflake, truncated to maxConversionErrorValueLen bytes.
+type ConversionError struct {
+	Column string
+	Type   string
+	Row    int64
+	Value  string
+	Err    error
+}
+
+func (ce *ConversionError) Error() string {
+	row := "unknown row"
+	if ce.Row >= 0 {
+		row = fmt.Sprintf("row %d", ce.Row)
+	}
+	if ce.Value == "" {
+		return fmt.Sprintf("failed to convert column %v (%v) of %v: %v", ce.Column, ce.Type, row, ce.Err)
+	}
+	return fmt.Sprintf("failed to convert column %v (%v) of %v, value %q: %v", ce.Column, ce.Type, row, ce.Value, ce.Err)
+}
+
+// Unwrap returns the error of the conversion.
+func (ce *ConversionError) Unwrap() error {
+	return ce.Err
+}
+
+// maxConversionErrorValueLen is the maximum length of the value in a ConversionError.
+const maxConversionErrorValueLen = 64
+
+func newConversionError(rowType execResponseRowType, row int64, value *string, err error) *ConversionError {
+	ce := &ConversionError{
+		Column: rowType.Name,
+		Type:   strings.ToUpper(rowType.Type),
+		Row:    row,
+		Err:    err,
+	}
+	if value != nil {
+		ce.Value = *value
+		if len(ce.Value) > maxConversionErrorValueLen {
+			ce.Value = ce.Value[:maxConversionErrorValueLen] + "..."
+		}
+	}
+	return ce
+}
+
+// ChildStatementError is the error of a single statement in a multi-statement query. Index is the
+// zero-based position of the statement in the query.
+type ChildStatementError struct {
+	Index   int
+	QueryID string
+	Err     error
+}
+
+func (ce *ChildStatementError) Error() string {
+	return fmt.Sprintf("statement %d (query ID: %s): %v", ce.Index, ce.QueryID, ce.Err)
+}
+
+// Unwrap returns the underlying error of the statement.
+func (ce *ChildStatementError) Unwrap() error {
+	return ce.Err
+}
+
+// MultiStatementError is returned when one or more statements in a multi-statement query failed.
+// QueryID is the ID of the parent query and Errors has an entry for each failed statement.
+type MultiStatementError struct {
+	QueryID  string
+	Errors   []*ChildStatementError
+	children []childStatementResult // the results of all the statements, empty for those that failed
+}
+
+func (me *MultiStatementError) Error() string {
+	if len(me.Errors) == 1 {
+		return fmt.Sprintf("1 statement failed in multi-statement query %s: %v", me.QueryID, me.Errors[0])
+	}
+	msgs := make([]string, len(me.Errors))
+	for i, ce := range me.Errors {
+		msgs[i] = ce.Error()
+	}
+	return fmt.Sprintf("%d statements failed in multi-statement query %s: %s",
+		len(me.Errors), me.QueryID, strings.Join(msgs, "; "))
+}
+
 const (
 	/* connection */
 
@@ -60,6 +180,57 @@ const (
 	ErrCodePrivateKeyParseError = 260010
 	// ErrCodeFailedToParseAuthenticator is an error code for the case where a DNS includes an invalid authenticator
 	ErrCodeFailedToParseAuthenticator = 260011
+	// ErrCodeFetchOnlyConnection is an error code for the case where a statement is executed on a fetch-only connection
+	ErrCodeFetchOnlyConnection = 260012
+	// ErrCodeInvalidExportedSession is an error code for the case where an exported session has no token
+	ErrCodeInvalidExportedSession = 260013
+	// ErrCodeLoginThrottled is an error code for the case where the login is throttled by Snowflake
+	ErrCodeLoginThrottled = 260014
+	// ErrCodeInvalidSessionParameter is an error code for the case where a known session parameter has an invalid value
+	ErrCodeInvalidSessionParameter = 260015
+	// ErrCodeNoWarehouse is an error code for the case where a warehouse is required but not set for the connection
+	ErrCodeNoWarehouse = 260016
+	// ErrCodeInvalidParamsBlob is an error code for the case where the params parameter of a DSN is not a valid JSON object
+	ErrCodeInvalidParamsBlob = 260017
+	// ErrCodeInvalidClientRedirect is an error code for the case where a redirect of the login is not followed
+	ErrCodeInvalidClientRedirect = 260018
+	// ErrCodeInvalidFailoverURL is an error code for the case where a failover URL is not a valid account URL
+	ErrCodeInvalidFailoverURL = 260019
+	// ErrCodeFileTransferNotSupported was the error code of the file transfers before they were supported, and is no
+	// longer returned
+	ErrCodeFileTransferNotSupported = 260020
+	// ErrCodeCassetteNotRecorded is an error code for the case where a Cassette in replay mode has no exchange for a request
+	ErrCodeCassetteNotRecorded = 260021
+	// ErrCodeInvalidHTTPHeader is an error code for the case where an extra HTTP header is not allowed or has an invalid value
+	ErrCodeInvalidHTTPHeader = 260022
+	// ErrCodeMultiStatementBindings is an error code for the case where a multi-statement query has bind variables
+	ErrCodeMultiStatementBindings = 260023
+	// ErrCodeRequestTooLarge is an error code for the case where a query request is larger than the limit of the
+	// Config or Snowflake
+	ErrCodeRequestTooLarge = 260024
+	// ErrCodePasswordExpired is an error code for the case where the password of the user has expired and
+	// Config.NewPassword is not set
+	ErrCodePasswordExpired = 260025
+	// ErrCodeOktaMFAFailed is an error code for the case where the MFA factor of the user is not verified by Okta
+	ErrCodeOktaMFAFailed = 260026
+	// ErrCodeInvalidQueryTag is an error code for the case where the QUERY_TAG of WithQueryTag and WithQueryLabels
+	// is too long
+	ErrCodeInvalidQueryTag = 260027
+	// ErrCodeResultSpillLimitExceeded is an error code for the case where the result chunks spilled to
+	// Config.ResultSpillDir exceed Config.ResultSpillMaxBytes
+	ErrCodeResultSpillLimitExceeded = 260028
+	// ErrCodeInvalidResultURL is an error code for the case where the result URL of a query is not of the host of
+	// the session
+	ErrCodeInvalidResultURL = 260029
+	// ErrCodeSQLAPINotSupported is an error code for the case where a feature requires the internal endpoints of
+	// Snowflake but the connection uses the SQL API
+	ErrCodeSQLAPINotSupported = 260030
+	// ErrCodeRequestAuthorizationFailed is an error code for the case where the RequestAuthorizer of the Config
+	// fails to authorize a request
+	ErrCodeRequestAuthorizationFailed = 260031
+	// ErrCodeConflictingParameters is an error code for the case where two connection parameters contradict each
+	// other, e.g., disableHeartbeat and CLIENT_SESSION_KEEP_ALIVE
+	ErrCodeConflictingParameters = 260032
 
 	/* network */
 
@@ -85,11 +256,30 @@ const (
 	ErrFailedToGetExternalBrowserResponse = 261009
 	// ErrFailedToHeartbeat is an error code when a heartbeat fails.
 	ErrFailedToHeartbeat = 261010
+	// ErrFailedToGetAzureADToken is an error code for the case where an Azure AD token cannot be acquired.
+	ErrFailedToGetAzureADToken = 261011
+	// ErrFailedToGetQueryStatus is an error code for the case where the status of a query cannot be retrieved.
+	ErrFailedToGetQueryStatus = 261012
+	// ErrFailedToDownloadFile is an error code for the case where an unloaded file, or a file of GET, cannot be
+	// downloaded.
+	ErrFailedToDownloadFile = 261013
+	// ErrLoginTimeout is an error code for the case where every login attempt timed out within the login timeout.
+	ErrLoginTimeout = 261014
+	// ErrFailedToUploadFile is an error code for the case where a file cannot be uploaded to the storage of a stage.
+	ErrFailedToUploadFile = 261015
 
 	/* rows */
 
 	// ErrFailedToGetChunk is an error code for the case where it failed to get chunk of result set
 	ErrFailedToGetChunk = 262000
+	// ErrResultTruncated is an error code for the case where the result exceeded the limit set by
+	// WithMaxResultRows or WithMaxResultBytes
+	ErrResultTruncated = 262001
+	// ErrUnsupportedResultFormat is an error code for the case where the result is in a format the driver doesn't read
+	ErrUnsupportedResultFormat = 262002
+	// ErrUnexpectedResultShape is an error code for the case where the result of a query in a response of Snowflake
+	// doesn't have the expected shape, e.g., a row has fewer values than the result has columns
+	ErrUnexpectedResultShape = 262003
 
 	/* transaction*/
 
@@ -97,6 +287,11 @@ const (
 	ErrNoReadOnlyTransaction = 263000
 	// ErrNoDefaultTransactionIsolationLevel is an error code for the case where non default isolation level is specified.
 	ErrNoDefaultTransactionIsolationLevel = 263001
+	// ErrMigrationLocked is an error code for the case where a MigrationLock is held by another owner
+	ErrMigrationLocked = 263002
+	// ErrMigrationLockLost is an error code for the case where the owner of a MigrationLock no longer holds it,
+	// e.g., because it expired and another owner acquired it
+	ErrMigrationLockLost = 263003
 
 	/* converter */
 
@@ -107,6 +302,12 @@ const (
 	ErrInvalidOffsetStr = 268001
 	// ErrInvalidBinaryHexForm is an error code for the case where a binary data in hex form is invalid.
 	ErrInvalidBinaryHexForm = 268002
+	// ErrNonFiniteFloat is an error code for the case where a bind variable or a result value is NaN or infinite
+	// and the query runs with a context created by WithRejectNonFiniteFloats
+	ErrNonFiniteFloat = 268003
+	// ErrLossyScan is an error code for the case where ResultSet.Scan with a context created by WithStrictScan would
+	// lose a part of the value, e.g., a NUMBER overflowing an int
+	ErrLossyScan = 268004
 
 	/* OCSP */
 
@@ -119,10 +320,27 @@ const (
 	// ErrOCSPNoOCSPResponderURL is an error code for the case where the OCSP responder URL is not attached.
 	ErrOCSPNoOCSPResponderURL = 269004
 
+	/* SQL error code */
+
+	// ErrInternalError is a SQL error code for the case that the query failed because of an internal error of
+	// Snowflake, for which an incident is recorded
+	ErrInternalError = 603
+
+	// ErrNoActiveWarehouse is a SQL error code for the case that the session has no running warehouse,
+	// e.g., the warehouse is suspended and doesn't resume automatically
+	ErrNoActiveWarehouse = 606
+
+	// ErrObjectNotExist is a SQL error code for the case that an object of the statement does not exist or is
+	// not authorized, e.g., a table
+	ErrObjectNotExist = 2003
+
 	/* GS error code */
 
 	// ErrSessionGone is an GS error code for the case that session is already closed
 	ErrSessionGone = 390111
+	// ErrUserTemporarilyLocked is a GS error code for the case that the user is temporarily locked after too many
+	// failed login attempts
+	ErrUserTemporarilyLocked = 390102
 	// ErrRoleNotExist is a GS error code for the case that the role specified does not exist
 	ErrRoleNotExist = 390189
 	// ErrObjectNotExistOrAuthorized is a GS error code for the case that the server-side object specified does not exist
@@ -133,11 +351,36 @@ const (
 	errMsgFailedToParseHost                  = "failed to parse a host name. host: %v"
 	errMsgFailedToParsePort                  = "failed to parse a port number. port: %v"
 	errMsgFailedToParseAuthenticator         = "failed to parse an authenticator: %v"
+	errMsgFetchOnlyConnection                = "the connection is fetch-only. only results can be fetched by query ID"
+	errMsgInvalidExportedSession             = "the exported session must have a session token and a master token"
+	errMsgLoginThrottled                     = "too many login attempts. HTTP: %v, URL: %v"
+	errMsgInvalidSessionParameter            = "invalid value of session parameter %v: %v"
+	errMsgNoWarehouse                        = "no warehouse is set for the connection"
+	errMsgInvalidParamsBlob                  = "failed to parse the params parameter. %v"
+	errMsgInvalidClientRedirect              = "login redirect to %v was not followed: %v"
+	errMsgInvalidFailoverURL                 = "invalid failover URL %v: %v"
+	errMsgCassetteNotRecorded                = "no recorded exchange for the request. method: %v, URL: %v"
+	errMsgInvalidHTTPHeader                  = "invalid HTTP header %v: %v"
+	errMsgMultiStatementBindings             = "bind variables are not supported for multi-statement queries. number of bindings: %v"
+	errMsgRequestTooLarge                    = "the query request is too large. size: %v bytes, limit: %v"
+	errMsgPasswordExpired                    = "the password of the user %v has expired and must be changed. %v"
+	errMsgOktaMFAFailed                      = "failed to verify the MFA factor %v with Okta. %v"
+	errMsgInvalidQueryTag                    = "the query tag is too long. length: %v, limit: %v"
+	errMsgResultSpillLimitExceeded           = "the result chunks spilled to %v exceed the limit of %v bytes"
+	errMsgInvalidResultURL                   = "invalid result URL %v: %v"
+	errMsgSQLAPINotSupported                 = "not supported with the SQL API: %v"
+	errMsgRequestAuthorizationFailed         = "failed to authorize the request to %v: %v"
+	errMsgConflictingParameters              = "conflicting connection parameters: %v and %v"
 	errMsgInvalidOffsetStr                   = "offset must be a string consist of sHHMI where one sign character '+'/'-' followed by zero filled hours and minutes: %v"
 	errMsgInvalidByteArray                   = "invalid byte array: %v"
+	errMsgNonFiniteFloat                     = "non-finite float value %v of %v"
+	errMsgLossyScan                          = "scanning into %v would lose a part of the value: %v"
 	errMsgIdpConnectionError                 = "failed to verify URLs. authenticator: %v, token URL:%v, SSO URL:%v"
 	errMsgSSOURLNotMatch                     = "SSO URL didn't match. expected: %v, got: %v"
 	errMsgFailedToGetChunk                   = "failed to get a chunk of result sets. idx: %v"
+	errMsgUnexpectedResultShape              = "unexpected shape of the result: %v"
+	errMsgResultTruncated                    = "the result was truncated as it exceeded the limit of %v %v"
+	errMsgUnsupportedResultFormat            = "unsupported result format: %v. query ID: %v"
 	errMsgFailedToPostQuery                  = "failed to POST. HTTP: %v, URL: %v"
 	errMsgFailedToRenew                      = "failed to renew session. HTTP: %v, URL: %v"
 	errMsgFailedToCancelQuery                = "failed to cancel query. HTTP: %v, URL: %v"
@@ -148,8 +391,15 @@ const (
 	errMsgFailedToGetSSO                     = "failed to auth via OKTA for unknown reason. HTTP: %v, URL: %v"
 	errMsgFailedToParseResponse              = "failed to parse a response from Snowflake. Response: %v"
 	errMsgFailedToGetExternalBrowserResponse = "failed to get an external browser response from Snowflake, err: %s"
+	errMsgFailedToGetAzureADToken            = "failed to get an Azure AD token. HTTP: %v, URL: %v"
+	errMsgFailedToGetQueryStatus             = "failed to get query status. HTTP: %v, URL: %v"
+	errMsgFailedToDownloadFile               = "failed to download the file %v. HTTP: %v"
+	errMsgLoginTimeout                       = "login timed out after %v in %v attempts. TLS handshake including OCSP: %v"
+	errMsgFailedToUploadFile                 = "failed to upload the file %v. HTTP: %v"
 	errMsgNoReadOnlyTransaction              = "no readonly mode is supported"
 	errMsgNoDefaultTransactionIsolationLevel = "no default isolation transaction level is supported"
+	errMsgMigrationLocked                    = "the migration lock %v in %v is held by another owner than %v"
+	errMsgMigrationLockLost                  = "the migration lock %v in %v is no longer held by %v"
 	errMsgServiceUnavailable                 = "service is unavailable. check your connectivity. you may need a proxy server. HTTP: %v, URL: %v"
 	errMsgFailedToConnect                    = "failed to connect to db. verify account name is correct. HTTP: %v, URL: %v"
 	errMsgOCSPStatusRevoked                  = "OCSP revoked: reason:%v, at:%v"
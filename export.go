@@ -0,0 +1,322 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"bufio"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExportOptions controls how ResultSet.WriteCSV and ResultSet.WriteJSON write a result.
+type ExportOptions struct {
+	// Header writes the column names as the first CSV record.
+	Header bool
+	// Comma is the CSV field delimiter. It is ',' if zero.
+	Comma rune
+	// NullString is written for NULL in CSV. It is empty by default.
+	NullString string
+	// JSONLines writes one JSON object per line instead of a JSON array of the objects.
+	JSONLines bool
+}
+
+func (rs *snowflakeResultSet) WriteCSV(w io.Writer, opts *ExportOptions) (int64, error) {
+	if opts == nil {
+		opts = &ExportOptions{}
+	}
+	f := newValueFormatter(rs.rows)
+	cw := csv.NewWriter(w)
+	if opts.Comma != 0 {
+		cw.Comma = opts.Comma
+	}
+	if opts.Header {
+		if err := cw.Write(rs.Columns()); err != nil {
+			return 0, err
+		}
+	}
+	record := make([]string, len(rs.rows.RowType))
+	n, err := rs.eachBatch(func(_ int64, row Row) error {
+		for i, v := range row {
+			if v == nil {
+				record[i] = opts.NullString
+			} else {
+				record[i] = f.format(i, v)
+			}
+		}
+		return cw.Write(record)
+	}, func() error {
+		cw.Flush()
+		return cw.Error()
+	})
+	return n, err
+}
+
+func (rs *snowflakeResultSet) WriteJSON(w io.Writer, opts *ExportOptions) (int64, error) {
+	if opts == nil {
+		opts = &ExportOptions{}
+	}
+	f := newValueFormatter(rs.rows)
+	bw := bufio.NewWriter(w)
+	keys := make([][]byte, len(rs.rows.RowType))
+	for i, name := range rs.Columns() {
+		key, err := json.Marshal(name)
+		if err != nil {
+			return 0, err
+		}
+		keys[i] = key
+	}
+	if !opts.JSONLines {
+		bw.WriteByte('[')
+	}
+	n, err := rs.eachBatch(func(idx int64, row Row) error {
+		if idx > 0 && opts.JSONLines {
+			bw.WriteByte('\n')
+		} else if idx > 0 {
+			bw.WriteByte(',')
+		}
+		bw.WriteByte('{')
+		for i, v := range row {
+			if i > 0 {
+				bw.WriteByte(',')
+			}
+			bw.Write(keys[i])
+			bw.WriteByte(':')
+			if err := f.writeJSON(bw, i, v); err != nil {
+				return err
+			}
+		}
+		bw.WriteByte('}')
+		return nil
+	}, bw.Flush)
+	if err != nil {
+		return n, err
+	}
+	if opts.JSONLines {
+		if n > 0 {
+			bw.WriteByte('\n')
+		}
+	} else {
+		bw.WriteByte(']')
+	}
+	return n, bw.Flush()
+}
+
+// eachBatch calls fn with the index and the row for the rest of the rows of the result set a chunk at a time,
+// and flush after every chunk. It returns the number of rows.
+func (rs *snowflakeResultSet) eachBatch(fn func(int64, Row) error, flush func() error) (int64, error) {
+	var n int64
+	for {
+		batch, err := rs.NextBatch()
+		if err == io.EOF {
+			return n, flush()
+		}
+		if err != nil {
+			return n, err
+		}
+		for _, row := range batch {
+			if err = fn(n, row); err != nil {
+				return n, err
+			}
+			n++
+		}
+		if err = flush(); err != nil {
+			return n, err
+		}
+	}
+}
+
+// valueFormatter formats the values of the columns as Snowflake outputs them, following the session
+// parameters of the output formats.
+type valueFormatter struct {
+	rowType  []execResponseRowType
+	params   map[string]*string
+	timezone *time.Location
+}
+
+func newValueFormatter(rows *snowflakeRows) *valueFormatter {
+	f := &valueFormatter{rowType: rows.RowType}
+	if rows.sc != nil {
+		f.params = rows.sc.cfg.Params
+	}
+	if tz := f.param("timezone"); tz != "" {
+		if loc, err := time.LoadLocation(tz); err == nil {
+			f.timezone = loc
+		}
+	}
+	return f
+}
+
+func (f *valueFormatter) param(name string) string {
+	if v, ok := f.params[name]; ok && v != nil && !strings.EqualFold(*v, "AUTO") {
+		return *v
+	}
+	return ""
+}
+
+// timeFormat returns the output format of the date, time or timestamp column.
+func (f *valueFormatter) timeFormat(i int) string {
+	typ := strings.ToLower(f.rowType[i].Type)
+	formats := map[string][]string{
+		"date":          {"date_output_format", "YYYY-MM-DD"},
+		"time":          {"time_output_format", "HH24:MI:SS"},
+		"timestamp_ntz": {"timestamp_ntz_output_format", "timestamp_output_format", "YYYY-MM-DD HH24:MI:SS.FF3"},
+		"timestamp_ltz": {"timestamp_ltz_output_format", "timestamp_output_format", "YYYY-MM-DD HH24:MI:SS.FF3 TZHTZM"},
+		"timestamp_tz":  {"timestamp_tz_output_format", "timestamp_output_format", "YYYY-MM-DD HH24:MI:SS.FF3 TZHTZM"},
+	}[typ]
+	if formats == nil {
+		return "YYYY-MM-DD HH24:MI:SS.FF9 TZHTZM"
+	}
+	for _, name := range formats[:len(formats)-1] {
+		if v := f.param(name); v != "" {
+			return v
+		}
+	}
+	return formats[len(formats)-1]
+}
+
+// format returns the string of the non-NULL value of the column.
+func (f *valueFormatter) format(i int, v driver.Value) string {
+	switch x := v.(type) {
+	case string:
+		if strings.EqualFold(f.rowType[i].Type, "boolean") {
+			if b, err := strconv.ParseBool(x); err == nil {
+				return strconv.FormatBool(b)
+			}
+		}
+		return x
+	case time.Time:
+		if f.timezone != nil && strings.EqualFold(f.rowType[i].Type, "timestamp_ltz") {
+			x = x.In(f.timezone)
+		}
+		return formatSnowflakeTime(x, f.timeFormat(i))
+	case []byte:
+		if strings.EqualFold(f.param("binary_output_format"), "BASE64") {
+			return base64.StdEncoding.EncodeToString(x)
+		}
+		return strings.ToUpper(hex.EncodeToString(x))
+	case *big.Float:
+		return x.Text('f', int(f.rowType[i].Scale))
+	case *big.Int:
+		return x.String()
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case float64:
+		return formatFloat(x, 64)
+	case bool:
+		return strconv.FormatBool(x)
+	}
+	return fmt.Sprint(v)
+}
+
+// writeJSON writes the value of the column as a JSON value. Numbers and booleans are written as JSON
+// numbers and booleans, and semi-structured data as JSON.
+func (f *valueFormatter) writeJSON(w *bufio.Writer, i int, v driver.Value) error {
+	if v == nil {
+		_, err := w.WriteString("null")
+		return err
+	}
+	s := f.format(i, v)
+	switch strings.ToLower(f.rowType[i].Type) {
+	case "fixed", "real", "boolean", "variant", "object", "array":
+		// not NaN nor Infinity, which JSON has no numbers for
+		if json.Valid([]byte(s)) {
+			_, err := w.WriteString(s)
+			return err
+		}
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// formatSnowflakeTime formats the time with a Snowflake date and time format, e.g., YYYY-MM-DD HH24:MI:SS.FF3.
+// Text in double quotes is written as is.
+func formatSnowflakeTime(t time.Time, format string) string {
+	var sb strings.Builder
+	for i := 0; i < len(format); {
+		rest := strings.ToUpper(format[i:])
+		if format[i] == '"' {
+			end := strings.IndexByte(format[i+1:], '"')
+			if end < 0 {
+				sb.WriteString(format[i+1:])
+				break
+			}
+			sb.WriteString(format[i+1 : i+1+end])
+			i += end + 2
+			continue
+		}
+		token, value := timeFormatToken(t, rest)
+		if token == "" {
+			sb.WriteByte(format[i])
+			i++
+			continue
+		}
+		sb.WriteString(value)
+		i += len(token)
+	}
+	return sb.String()
+}
+
+// timeFormatToken returns the format element at the start of the upper case format and its value.
+func timeFormatToken(t time.Time, format string) (string, string) {
+	_, offset := t.Zone()
+	sign := '+'
+	if offset < 0 {
+		sign = '-'
+		offset = -offset
+	}
+	tzh := fmt.Sprintf("%c%02d", sign, offset/3600)
+	tzm := fmt.Sprintf("%02d", offset%3600/60)
+	hour12 := t.Hour() % 12
+	if hour12 == 0 {
+		hour12 = 12
+	}
+	if strings.HasPrefix(format, "FF") {
+		digits := 9
+		token := "FF"
+		if len(format) > 2 && format[2] >= '0' && format[2] <= '9' {
+			digits = int(format[2] - '0')
+			token = format[:3]
+		}
+		return token, fmt.Sprintf("%09d", t.Nanosecond())[:digits]
+	}
+	for _, e := range []struct {
+		token string
+		value func() string
+	}{
+		{"YYYY", func() string { return fmt.Sprintf("%04d", t.Year()) }},
+		{"YY", func() string { return fmt.Sprintf("%02d", t.Year()%100) }},
+		{"MMMM", func() string { return t.Month().String() }},
+		{"MON", func() string { return t.Month().String()[:3] }},
+		{"MM", func() string { return fmt.Sprintf("%02d", int(t.Month())) }},
+		{"DD", func() string { return fmt.Sprintf("%02d", t.Day()) }},
+		{"DY", func() string { return t.Weekday().String()[:3] }},
+		{"HH24", func() string { return fmt.Sprintf("%02d", t.Hour()) }},
+		{"HH12", func() string { return fmt.Sprintf("%02d", hour12) }},
+		{"HH", func() string { return fmt.Sprintf("%02d", t.Hour()) }},
+		{"AM", func() string { return t.Format("PM") }},
+		{"PM", func() string { return t.Format("PM") }},
+		{"MI", func() string { return fmt.Sprintf("%02d", t.Minute()) }},
+		{"SS", func() string { return fmt.Sprintf("%02d", t.Second()) }},
+		{"TZHTZM", func() string { return tzh + tzm }},
+		{"TZH", func() string { return tzh }},
+		{"TZM", func() string { return tzm }},
+	} {
+		if strings.HasPrefix(format, e.token) {
+			return e.token, e.value()
+		}
+	}
+	return "", ""
+}
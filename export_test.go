@@ -0,0 +1,117 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResultSetWriteCSV(t *testing.T) {
+	numChunks := 3
+	rs, firstChunkSize := newResultSetTest(numChunks)
+	var buf bytes.Buffer
+	n, err := rs.WriteCSV(&buf, &ExportOptions{Header: true, Comma: '|'})
+	if err != nil {
+		t.Fatalf("failed to write CSV. err: %v", err)
+	}
+	if expected := int64(firstChunkSize + numChunks*rowsInChunk); n != expected {
+		t.Fatalf("wrong number of rows. expected: %v, got: %v", expected, n)
+	}
+	last := (numChunks-1)*1000 + rowsInChunk - 1
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != int(n)+1 || lines[0] != "c1|c2" || lines[1] != "0|Test0" ||
+		lines[len(lines)-1] != fmt.Sprintf("%v|testchunk%v", last, last) {
+		t.Fatalf("unexpected CSV. lines: %v, first: %q, last: %q", len(lines), lines[:2], lines[len(lines)-1])
+	}
+}
+
+func TestResultSetWriteJSON(t *testing.T) {
+	num, ts, variant, flag, text := "1.50", "1600000000.123456789", `{"a":[1,2]}`, "1", `say "hi"`
+	rows := &snowflakeRows{
+		sc: &snowflakeConn{cfg: &Config{Params: map[string]*string{}}},
+		RowType: []execResponseRowType{
+			{Name: "N", Type: "fixed", Scale: 2},
+			{Name: "T", Type: "timestamp_ntz", Scale: 9},
+			{Name: "V", Type: "variant"},
+			{Name: "B", Type: "boolean"},
+			{Name: "S", Type: "text"},
+			{Name: "X", Type: "text"},
+		},
+	}
+	format := "YYYY-MM-DD\"T\"HH24:MI:SS.FF6"
+	rows.sc.cfg.Params["timestamp_ntz_output_format"] = &format
+	rows.ChunkDownloader = &snowflakeChunkDownloader{
+		ctx:           context.Background(),
+		Total:         2,
+		TotalRowIndex: int64(-1),
+		RowSet: rowSetType{JSON: [][]*string{
+			{&num, &ts, &variant, &flag, &text, nil},
+			{nil, nil, nil, nil, nil, nil},
+		}},
+	}
+	if err := rows.ChunkDownloader.start(); err != nil {
+		t.Fatal(err)
+	}
+	rs := &snowflakeResultSet{rows: rows}
+	var buf bytes.Buffer
+	n, err := rs.WriteJSON(&buf, nil)
+	if err != nil || n != 2 {
+		t.Fatalf("failed to write JSON. n: %v, err: %v", n, err)
+	}
+	expected := `[{"N":1.50,"T":"2020-09-13T12:26:40.123456","V":{"a":[1,2]},"B":true,"S":"say \"hi\"","X":null},` +
+		`{"N":null,"T":null,"V":null,"B":null,"S":null,"X":null}]`
+	if buf.String() != expected {
+		t.Fatalf("unexpected JSON.\nexpected: %v\ngot:      %v", expected, buf.String())
+	}
+	var v []map[string]interface{}
+	if err = json.Unmarshal(buf.Bytes(), &v); err != nil {
+		t.Fatalf("should be valid JSON. err: %v", err)
+	}
+}
+
+func TestFormatSnowflakeTime(t *testing.T) {
+	tm := time.Date(2020, 3, 7, 15, 4, 5, 123456789, time.FixedZone("", -(7*3600+30*60)))
+	for _, tc := range []struct {
+		format   string
+		expected string
+	}{
+		{"YYYY-MM-DD HH24:MI:SS.FF3 TZHTZM", "2020-03-07 15:04:05.123 -0730"},
+		{"YYYY-MM-DD HH24:MI:SS.FF TZH:TZM", "2020-03-07 15:04:05.123456789 -07:30"},
+		{"dd mon yy hh12:mi am", "07 Mar 20 03:04 PM"},
+		{"DY, MMMM DD \"at\" HH24", "Sat, March 07 at 15"},
+		{"YYYY-MM-DD\"T\"HH24:MI:SS.FF0", "2020-03-07T15:04:05."},
+	} {
+		if got := formatSnowflakeTime(tm, tc.format); got != tc.expected {
+			t.Errorf("format: %v. expected: %v, got: %v", tc.format, tc.expected, got)
+		}
+	}
+}
+
+func TestValueFormatter(t *testing.T) {
+	base64Format := "BASE64"
+	rows := &snowflakeRows{
+		sc: &snowflakeConn{cfg: &Config{Params: map[string]*string{"binary_output_format": &base64Format}}},
+		RowType: []execResponseRowType{
+			{Type: "fixed", Scale: 3},
+			{Type: "binary"},
+			{Type: "real"},
+		},
+	}
+	f := newValueFormatter(rows)
+	if s := f.format(0, new(big.Float).SetFloat64(1.5)); s != "1.500" {
+		t.Fatalf("unexpected number: %v", s)
+	}
+	if s := f.format(1, []byte("abc")); s != "YWJj" {
+		t.Fatalf("unexpected binary: %v", s)
+	}
+	if s := f.format(2, 0.25); s != "0.25" {
+		t.Fatalf("unexpected real: %v", s)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// ResponseExtensions provides the fields of the query response data that the driver doesn't know, e.g.,
+// metadata added to Snowflake after the driver was released. The rows returned by QueryContext and the
+// results of DML statements returned by ExecContext implement it when the query runs with a context created
+// by WithResponseExtensions.
+type ResponseExtensions interface {
+	// Extensions returns the raw JSON of the unknown fields by the field name. It is nil unless the query
+	// runs with a context created by WithResponseExtensions.
+	Extensions() map[string]json.RawMessage
+}
+
+// WithResponseExtensions returns a context that captures the unknown fields of the responses of the queries
+// run with it. Capturing them parses the response twice, so it is not enabled by default.
+func WithResponseExtensions(ctx context.Context) context.Context {
+	return context.WithValue(ctx, responseExtensions, true)
+}
+
+// knownExecResponseDataFields is the set of the JSON field names of execResponseData in lower case.
+var knownExecResponseDataFields = jsonFieldNames(reflect.TypeOf(execResponseData{}))
+
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	names := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		names[strings.ToLower(name)] = true
+	}
+	return names
+}
+
+// decodeExecResponse decodes the query response from body into respd. If the context was created by
+// WithResponseExtensions, the unknown fields of the response data are captured in respd.Data.Extensions.
+func decodeExecResponse(ctx context.Context, body io.Reader, respd *execResponse) error {
+	if capture, _ := ctx.Value(responseExtensions).(bool); !capture {
+		return decodeJSONBody(body, respd)
+	}
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if _, err := buf.ReadFrom(body); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(buf.Bytes(), respd); err != nil {
+		return err
+	}
+	var raw struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &raw); err != nil {
+		return err
+	}
+	for name, value := range raw.Data {
+		if knownExecResponseDataFields[strings.ToLower(name)] {
+			continue
+		}
+		if respd.Data.Extensions == nil {
+			respd.Data.Extensions = make(map[string]json.RawMessage)
+		}
+		// copy the value out of the pooled buffer
+		respd.Data.Extensions[name] = append(json.RawMessage(nil), value...)
+	}
+	return nil
+}
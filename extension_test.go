@@ -0,0 +1,68 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+const responseWithUnknownFields = `{"data":{"queryId":"1234-5678","rowtype":[{"name":"C1","type":"fixed"}],` +
+	`"rowset":[["1"]],"total":1,"queryResultFormat":"json","newMetadata":{"a":1},"newFlag":true},` +
+	`"code":"0","success":true}`
+
+func TestDecodeExecResponseExtensions(t *testing.T) {
+	var respd execResponse
+	if err := decodeExecResponse(context.Background(), strings.NewReader(responseWithUnknownFields), &respd); err != nil {
+		t.Fatalf("failed to decode. err: %v", err)
+	}
+	if respd.Data.Extensions != nil {
+		t.Fatalf("the unknown fields should not be captured by default. got: %v", respd.Data.Extensions)
+	}
+
+	respd = execResponse{}
+	ctx := WithResponseExtensions(context.Background())
+	if err := decodeExecResponse(ctx, strings.NewReader(responseWithUnknownFields), &respd); err != nil {
+		t.Fatalf("failed to decode. err: %v", err)
+	}
+	if respd.Data.QueryID != "1234-5678" || respd.Data.Total != 1 {
+		t.Fatalf("the known fields should be decoded. got: %+v", respd.Data)
+	}
+	if len(respd.Data.Extensions) != 2 {
+		t.Fatalf("unexpected extensions: %v", respd.Data.Extensions)
+	}
+	if v := string(respd.Data.Extensions["newMetadata"]); v != `{"a":1}` {
+		t.Fatalf("unexpected value of newMetadata: %v", v)
+	}
+	if v := string(respd.Data.Extensions["newFlag"]); v != "true" {
+		t.Fatalf("unexpected value of newFlag: %v", v)
+	}
+}
+
+func TestFetchResultWithExtensions(t *testing.T) {
+	sc := &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{
+			FuncGet: func(_ context.Context, _ *snowflakeRestful, _ *url.URL, _ map[string]string, _ time.Duration) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       ioutil.NopCloser(strings.NewReader(responseWithUnknownFields)),
+				}, nil
+			},
+		},
+	}
+	ctx := WithResponseExtensions(WithFetchResultByID(context.Background(), "1234-5678"))
+	rows, err := sc.QueryContext(ctx, "", nil)
+	if err != nil {
+		t.Fatalf("failed to fetch the result. err: %v", err)
+	}
+	defer rows.Close()
+	if v := string(rows.(ResponseExtensions).Extensions()["newFlag"]); v != "true" {
+		t.Fatalf("unexpected value of newFlag: %v", v)
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// parseFailoverURL parses an account URL of Config.FailoverURLs. The protocol defaults to HTTPS and
+// the port to 443.
+func parseFailoverURL(s string) (*url.URL, error) {
+	invalid := func(reason string) error {
+		return &SnowflakeError{
+			Number:      ErrCodeInvalidFailoverURL,
+			Message:     errMsgInvalidFailoverURL,
+			MessageArgs: []interface{}{s, reason},
+		}
+	}
+	raw := strings.TrimSpace(s)
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, invalid(err.Error())
+	}
+	if u.Hostname() == "" {
+		return nil, invalid("no host")
+	}
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return nil, invalid("the protocol is not HTTP or HTTPS")
+	}
+	if u.Port() != "" {
+		if _, err = strconv.Atoi(u.Port()); err != nil {
+			return nil, invalid("invalid port")
+		}
+	}
+	return u, nil
+}
+
+// isFailoverError returns true if the login failed because the deployment is unreachable or unavailable,
+// but not if it rejected the login, in which case the other deployments would reject it as well.
+func isFailoverError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	switch e := err.(type) {
+	case *SnowflakeError:
+		return e.Number == ErrCodeServiceUnavailable
+	case *url.Error, net.Error:
+		return true
+	}
+	return err == context.DeadlineExceeded
+}
+
+// loginWithFailover logs in to the account URL in the Config and, if it is unreachable, to the failover
+// URLs in order. The connection uses the account URL that accepted the login afterwards.
+func (sc *snowflakeConn) loginWithFailover(ctx context.Context) (*authResponseMain, error) {
+	authData, err := sc.loginFollowingRedirects(ctx)
+	for _, s := range sc.cfg.FailoverURLs {
+		if err == nil || !isFailoverError(ctx, err) {
+			break
+		}
+		u, perr := parseFailoverURL(s)
+		if perr != nil {
+			return nil, perr
+		}
+		glog.V(1).Infof("failed to log in to %v. failing over to %v. err: %v", sc.rest.getURL(), u, err)
+		sc.failover(u)
+		authData, err = sc.loginFollowingRedirects(ctx)
+	}
+	return authData, err
+}
+
+// failover points the connection to the account URL. The account name is taken from the host of
+// the Snowflake domain, as the replicas of an account have their own account names.
+func (sc *snowflakeConn) failover(u *url.URL) {
+	host := u.Hostname()
+	port := 443
+	if u.Port() != "" {
+		port, _ = strconv.Atoi(u.Port())
+	} else if u.Scheme == "http" {
+		port = 80
+	}
+	if strings.HasSuffix(host, defaultDomain) {
+		sc.cfg.Account = strings.Split(host, ".")[0]
+	}
+	sc.cfg.Host, sc.cfg.Port, sc.cfg.Protocol = host, port, u.Scheme
+	sc.rest.Host, sc.rest.Port, sc.rest.Protocol = host, port, u.Scheme
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func getFailoverTestConn(hosts *[]string, fail func(host string) error) *snowflakeConn {
+	sc := getDefaultSnowflakeConn()
+	sc.cfg.Account = "acct1"
+	sc.cfg.Host, sc.cfg.Port, sc.cfg.Protocol = "acct1.snowflakecomputing.com", 443, "https"
+	sc.cfg.FailoverURLs = []string{"https://acct2.snowflakecomputing.com", "acct3.snowflakecomputing.com:8443"}
+	sc.rest = &snowflakeRestful{
+		Host:     sc.cfg.Host,
+		Port:     sc.cfg.Port,
+		Protocol: sc.cfg.Protocol,
+		FuncPostAuth: func(_ context.Context, sr *snowflakeRestful, _ *url.Values, _ map[string]string, _ []byte, _ time.Duration) (*authResponse, error) {
+			*hosts = append(*hosts, sr.Host)
+			if err := fail(sr.Host); err != nil {
+				return nil, err
+			}
+			return &authResponse{
+				Success: true,
+				Data:    authResponseMain{Token: "t", MasterToken: "m"},
+			}, nil
+		},
+	}
+	return sc
+}
+
+func TestUnitLoginWithFailover(t *testing.T) {
+	var hosts []string
+	sc := getFailoverTestConn(&hosts, func(host string) error {
+		switch host {
+		case "acct1.snowflakecomputing.com":
+			return &SnowflakeError{Number: ErrCodeServiceUnavailable}
+		case "acct2.snowflakecomputing.com":
+			return &url.Error{Op: "Post", URL: host, Err: context.DeadlineExceeded}
+		}
+		return nil
+	})
+	if _, err := sc.loginWithFailover(context.TODO()); err != nil {
+		t.Fatalf("failed to log in. err: %v", err)
+	}
+	if len(hosts) != 3 || hosts[2] != "acct3.snowflakecomputing.com" {
+		t.Fatalf("the login should have failed over to the last URL. hosts: %v", hosts)
+	}
+	if sc.cfg.Account != "acct3" || sc.cfg.Host != "acct3.snowflakecomputing.com" || sc.rest.Port != 8443 {
+		t.Fatalf("the connection should use the last URL. account: %v, url: %v", sc.cfg.Account, sc.rest.getURL())
+	}
+}
+
+func TestUnitLoginNoFailoverOnRejection(t *testing.T) {
+	var hosts []string
+	sc := getFailoverTestConn(&hosts, func(host string) error {
+		return &SnowflakeError{Number: 390100, SQLState: SQLStateConnectionRejected}
+	})
+	if _, err := sc.loginWithFailover(context.TODO()); err == nil {
+		t.Fatal("should have failed to log in.")
+	}
+	if len(hosts) != 1 || sc.cfg.Host != "acct1.snowflakecomputing.com" {
+		t.Fatalf("a rejected login should not fail over. hosts: %v", hosts)
+	}
+}
+
+func TestParseFailoverURL(t *testing.T) {
+	for _, s := range []string{"acct2.snowflakecomputing.com", "https://acct2.snowflakecomputing.com:443"} {
+		u, err := parseFailoverURL(s)
+		if err != nil {
+			t.Fatalf("failed to parse %v. err: %v", s, err)
+		}
+		if u.Scheme != "https" || u.Hostname() != "acct2.snowflakecomputing.com" {
+			t.Fatalf("unexpected URL: %v", u)
+		}
+	}
+	for _, s := range []string{"", "ftp://acct2.snowflakecomputing.com", "acct2.snowflakecomputing.com:port"} {
+		_, err := parseFailoverURL(s)
+		if e, ok := err.(*SnowflakeError); !ok || e.Number != ErrCodeInvalidFailoverURL {
+			t.Fatalf("should have failed to parse %q. err: %v", s, err)
+		}
+	}
+}
+
+func TestParseDSNFailoverURLs(t *testing.T) {
+	cfg, err := ParseDSN("u:p@acct1/db?failoverURLs=acct2.snowflakecomputing.com,https%3A%2F%2Facct3.snowflakecomputing.com")
+	if err != nil {
+		t.Fatalf("failed to parse dsn. err: %v", err)
+	}
+	if len(cfg.FailoverURLs) != 2 || cfg.FailoverURLs[1] != "https://acct3.snowflakecomputing.com" {
+		t.Fatalf("unexpected failover URLs: %v", cfg.FailoverURLs)
+	}
+	dsn, err := DSN(cfg)
+	if err != nil {
+		t.Fatalf("failed to construct dsn. err: %v", err)
+	}
+	if cfg, err = ParseDSN(dsn); err != nil || len(cfg.FailoverURLs) != 2 {
+		t.Fatalf("failover URLs should round trip. dsn: %v, err: %v", dsn, err)
+	}
+	if _, err = ParseDSN("u:p@acct1/db?failoverURLs=ftp%3A%2F%2Facct2"); err == nil {
+		t.Fatal("should have failed to parse an invalid failover URL.")
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// WithRejectNonFiniteFloats returns a context whose queries fail with ErrNonFiniteFloat on the float values NaN,
+// +Inf and -Inf, in the bind variables as well as in the FLOAT columns of the results, e.g., for an application
+// that can't store them. By default, they are bound as 'NaN', 'inf' and '-inf', as Snowflake spells them, and
+// the results have them as the Go float values or the strings Snowflake returns.
+func WithRejectNonFiniteFloats(ctx context.Context) context.Context {
+	return context.WithValue(ctx, rejectNonFiniteFloats, true)
+}
+
+func rejectsNonFiniteFloats(ctx context.Context) bool {
+	reject, _ := ctx.Value(rejectNonFiniteFloats).(bool)
+	return reject
+}
+
+// formatFloat formats the float as Snowflake reads it, which spells the special values 'NaN', 'inf' and '-inf'
+// instead of NaN, +Inf and -Inf.
+func formatFloat(f float64, bitSize int) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, bitSize)
+}
+
+// isNonFiniteFloat returns true if the value is a NaN or infinite float.
+func isNonFiniteFloat(v reflect.Value) bool {
+	if v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return false
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return math.IsNaN(v.Float()) || math.IsInf(v.Float(), 0)
+	}
+	return false
+}
+
+// checkFiniteBind returns ErrNonFiniteFloat if the bind variable, or an element of an array bind, is a NaN or
+// infinite float.
+func checkFiniteBind(v driver.Value, idx int) error {
+	rv := reflect.ValueOf(v)
+	if isNonFiniteFloat(rv) {
+		return errNonFiniteFloat(v, fmt.Sprintf("bind variable %v", idx))
+	}
+	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		for i := 0; i < rv.Len(); i++ {
+			if e := rv.Index(i); isNonFiniteFloat(e) {
+				return errNonFiniteFloat(e.Interface(), fmt.Sprintf("bind variable %v", idx))
+			}
+		}
+	}
+	return nil
+}
+
+// checkFiniteRow returns ErrNonFiniteFloat if a FLOAT column of the row is NaN or infinite, either as a float or
+// as the string Snowflake returns in JSON.
+func checkFiniteRow(rowType []execResponseRowType, dest []driver.Value) error {
+	for i, v := range dest {
+		if i >= len(rowType) || !strings.EqualFold(rowType[i].Type, "real") {
+			continue
+		}
+		nonFinite := isNonFiniteFloat(reflect.ValueOf(v))
+		if s, ok := v.(string); ok {
+			f, err := strconv.ParseFloat(s, 64)
+			nonFinite = err == nil && (math.IsNaN(f) || math.IsInf(f, 0))
+		}
+		if nonFinite {
+			return errNonFiniteFloat(v, fmt.Sprintf("column %v", rowType[i].Name))
+		}
+	}
+	return nil
+}
+
+func errNonFiniteFloat(v interface{}, of string) *SnowflakeError {
+	return &SnowflakeError{
+		Number:      ErrNonFiniteFloat,
+		SQLState:    SQLStateNumericValueOutOfRange,
+		Message:     errMsgNonFiniteFloat,
+		MessageArgs: []interface{}{v, of},
+	}
+}
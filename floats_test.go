@@ -0,0 +1,90 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"strconv"
+	"testing"
+)
+
+// floatTestClient returns the Client whose result is a FLOAT column of the values.
+func floatTestClient(values ...string) *fakeQueryClient {
+	rows := make([][]string, len(values))
+	for i, v := range values {
+		rows[i] = []string{v}
+	}
+	return newFakeQueryClient([]execResponseRowType{{Name: "F", Type: "real"}}, rows...)
+}
+
+func TestNonFiniteFloatBinds(t *testing.T) {
+	c := floatTestClient("inf", "-inf", "NaN", "1.5")
+	rs, err := c.Query(context.Background(), "SELECT ?, ?", math.Inf(1), []interface{}{math.NaN(), math.Inf(-1), 2.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Close()
+	b, _ := json.Marshal(c.recorded()[0].Bindings)
+	if expected := `{"1":{"type":"REAL","value":"inf"},"2":{"type":"REAL","value":["NaN","-inf","2.5"]}}`; string(b) != expected {
+		t.Fatalf("the special values should be bound as Snowflake spells them. expected: %v, got: %s", expected, b)
+	}
+	var got []float64
+	for rs.Next() {
+		var f float64
+		if err = rs.Scan(&f); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, f)
+	}
+	if err = rs.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 4 || !math.IsInf(got[0], 1) || !math.IsInf(got[1], -1) || !math.IsNaN(got[2]) || got[3] != 1.5 {
+		t.Fatalf("the special values should be scanned into floats. got: %v", got)
+	}
+}
+
+func TestFloatBindPrecision(t *testing.T) {
+	// 0.1 + 0.2 and 16777217 have no exact float32 representation
+	for _, f := range []float64{0.1 + 0.2, 16777217} {
+		s, err := valueToString(f, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed, err := strconv.ParseFloat(*s, 64); err != nil || parsed != f {
+			t.Errorf("the float should be bound without loss of precision. value: %v, bound: %v", f, *s)
+		}
+	}
+}
+
+func TestRejectNonFiniteFloats(t *testing.T) {
+	c := floatTestClient("1.5", "-inf")
+	ctx := WithRejectNonFiniteFloats(context.Background())
+	for _, arg := range []interface{}{math.NaN(), []float64{1, math.Inf(1)}, []interface{}{nil, float32(math.Inf(-1))}} {
+		_, err := c.Query(ctx, "SELECT ?", arg)
+		if driverErr, ok := err.(*SnowflakeError); !ok || driverErr.Number != ErrNonFiniteFloat {
+			t.Fatalf("the bind variable %v should fail with ErrNonFiniteFloat. err: %v", arg, err)
+		}
+	}
+	if queries := c.queries(); len(queries) != 0 {
+		t.Fatalf("the statements should not be submitted. queries: %q", queries)
+	}
+
+	rs, err := c.Query(ctx, "SELECT ?", 1.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Close()
+	var f float64
+	if !rs.Next() || rs.Scan(&f) != nil || f != 1.5 {
+		t.Fatalf("the finite value should be returned. got: %v, err: %v", f, rs.Err())
+	}
+	if rs.Next() {
+		t.Fatal("the infinite value should fail")
+	}
+	if driverErr, ok := rs.Err().(*SnowflakeError); !ok || driverErr.Number != ErrNonFiniteFloat {
+		t.Fatalf("the infinite value should fail with ErrNonFiniteFloat. err: %v", rs.Err())
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+//go:build go1.18
+// +build go1.18
+
+package gosnowflake
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow/go/arrow"
+	"github.com/apache/arrow/go/arrow/array"
+	"github.com/apache/arrow/go/arrow/ipc"
+	"github.com/apache/arrow/go/arrow/memory"
+)
+
+// The fuzzers check that the parsers of the responses of Snowflake and of the DSN return errors rather than
+// panic on unexpected input, and that the Arrow and JSON result formats convert the values alike. They run on
+// their seeds with go test, and are fuzzed with, e.g., go test -run '^$' -fuzz FuzzExecResponse.
+
+func FuzzExecResponse(f *testing.F) {
+	f.Add(`{"data":{"rowtype":[{"name":"multiple statement execution","type":"text"}],"rowset":[["x"]],` +
+		`"statementTypeId":4096,"resultIds":"q1,q2","resultTypes":"4096,12544"},"code":"0","success":true}`)
+	f.Add(`{"data":{"rowtype":[{"name":"number of rows inserted","type":"fixed"}],"rowset":[["3"]],` +
+		`"statementTypeId":12544},"success":true}`)
+	f.Add(`{"data":{"statementTypeId":4096},"success":true}`)
+	f.Fuzz(func(t *testing.T, body string) {
+		var respd execResponse
+		if err := decodeExecResponse(WithResponseExtensions(context.Background()), strings.NewReader(body), &respd); err != nil {
+			return
+		}
+		sc := &snowflakeConn{}
+		if sc.isMultiStmt(respd.Data) {
+			getChildResults(respd.Data.ResultIDs, respd.Data.ResultTypes)
+		}
+		if sc.isDml(respd.Data.StatementTypeID) {
+			dmlRowCounts(respd.Data)
+		}
+	})
+}
+
+func FuzzChunkJSON(f *testing.F) {
+	f.Add(`["1","a"],["2",null]`, 2)
+	f.Add(`["é\n"]`, 1)
+	f.Add(``, 0)
+	f.Fuzz(func(t *testing.T, chunk string, cellCount int) {
+		if cellCount < 0 || cellCount > 1000 {
+			return
+		}
+		decodeChunkRows(&largeResultSetReader{body: strings.NewReader(chunk)}, 0)
+		decodeLargeChunk(&largeResultSetReader{body: strings.NewReader(chunk)}, 0, cellCount)
+	})
+}
+
+func FuzzChunkArrow(f *testing.F) {
+	pool := memory.NewGoAllocator()
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "c1", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+		{Name: "c2", Type: arrow.BinaryTypes.String, Nullable: true},
+	}, nil)
+	b := array.NewRecordBuilder(pool, schema)
+	defer b.Release()
+	b.Field(0).(*array.Int64Builder).AppendValues([]int64{1, 2}, []bool{true, false})
+	b.Field(1).(*array.StringBuilder).AppendValues([]string{"a", "b"}, nil)
+	record := b.NewRecord()
+	defer record.Release()
+	var buf bytes.Buffer
+	w := ipc.NewWriter(&buf, ipc.WithSchema(schema), ipc.WithAllocator(pool))
+	if err := w.Write(record); err != nil {
+		f.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		f.Fatal(err)
+	}
+	f.Add(buf.Bytes(), "fixed", "text")
+	f.Add(buf.Bytes(), "text", "timestamp_ntz")
+	f.Fuzz(func(t *testing.T, chunk []byte, type1 string, type2 string) {
+		reader, err := ipc.NewReader(bytes.NewReader(chunk))
+		if err != nil {
+			return
+		}
+		arc := arrowResultChunk{*reader, 0, len(chunk), memory.NewGoAllocator()}
+		arc.decodeArrowChunk([]execResponseRowType{{Name: "c1", Type: type1}, {Name: "c2", Type: type2}}, false)
+	})
+}
+
+func FuzzParseDSN(f *testing.F) {
+	f.Add("u:p@a.snowflakecomputing.com:443/db/schema?warehouse=wh&role=r&loginTimeout=10")
+	f.Add("u:p@a.b.c/db?authenticator=oauth&token=t&queryTag=%7B%7D")
+	f.Add("u@host:8080?account=a&protocol=http&failoverURLs=https%3A%2F%2Fb.snowflakecomputing.com")
+	f.Fuzz(func(t *testing.T, dsn string) {
+		cfg, err := ParseDSN(dsn)
+		if err != nil {
+			return
+		}
+		DSN(cfg)
+	})
+}
+
+// FuzzArrowJSONParity checks that the values converted in the Arrow and JSON result formats are the same.
+func FuzzArrowJSONParity(f *testing.F) {
+	f.Add([]byte{0x01}, false, uint8(0), int64(0), uint16(1440), int64(0))
+	f.Add([]byte{0x4b, 0x3b, 0x4c, 0xa8, 0x5a, 0x86, 0xc4, 0x7a, 0x09, 0x8a, 0x22, 0x3f, 0xff, 0xff, 0xff, 0xff},
+		true, uint8(37), int64(-1e18), uint16(0), int64(86399999999999))
+	f.Add([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, false, uint8(18), int64(1598955072123456789),
+		uint16(2880), int64(43200))
+	maxNumber := new(big.Int).Exp(big.NewInt(10), big.NewInt(38), nil)
+	f.Fuzz(func(t *testing.T, digits []byte, neg bool, scale uint8, nanos int64, tz uint16, timeOfDay int64) {
+		p := &arrowJSONParity{t: t, pool: memory.NewGoAllocator()}
+		num := new(big.Int).Mod(new(big.Int).SetBytes(digits), maxNumber)
+		if neg {
+			num.Neg(num)
+		}
+		p.decimal(num, int(scale%38))
+		p.int64Number(nanos%1e18, int(scale%19))
+		nanos %= 1e18
+		p.timestamp(nanos, int(scale%10))
+		p.timestampTZ(nanos, int(scale%4), int(tz%2881))
+		timeScale := int(scale % 10)
+		day := 86400 * int64(math.Pow10(timeScale))
+		p.timeOfDay((timeOfDay%day+day)%day, timeScale)
+	})
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+)
+
+// getFiles runs a GET of the stage location and downloads the files of its response from the storage of the stage
+// to the local directory, parallel ones at a time as the response says. The files are stored as they are in the
+// stage, e.g., gzipped, and are decrypted if they are encrypted. The first error cancels the rest of the downloads.
+func (c *Client) getFiles(ctx context.Context, stageLocation string, localDir string) error {
+	data, err := c.sc.fileTransferData(ctx, "GET "+quoteFileTransferPath(stageLocation)+" "+
+		quoteFileTransferPath("file://"+filepath.ToSlash(localDir)), "DOWNLOAD")
+	if err != nil {
+		return err
+	}
+	storage, err := newStageStorage(c.sc.rest.Client, data.StageInfo)
+	if err != nil {
+		return err
+	}
+	if cs, ok := storage.(*cloudStorage); ok && cs.needsPresignedURLs() {
+		if len(data.PresignedURLs) != len(data.SrcLocations) {
+			return fmt.Errorf("the response of GET has %v presigned URLs for %v files", len(data.PresignedURLs),
+				len(data.SrcLocations))
+		}
+		for i, name := range data.SrcLocations {
+			cs.presignedURLs[name] = data.PresignedURLs[i]
+		}
+	}
+	if err = os.MkdirAll(localDir, 0700); err != nil {
+		return err
+	}
+	wrapper := c.sc.fileKeyWrapper()
+	parallelism := int(data.Parallel)
+	if parallelism <= 0 {
+		parallelism = defaultPutParallelism
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	for i, name := range data.SrcLocations {
+		sem <- struct{}{}
+		if ctx.Err() != nil {
+			break
+		}
+		var material *EncryptionMaterial
+		if i < len(data.EncryptionMaterial) {
+			material = data.EncryptionMaterial[i]
+		}
+		wg.Add(1)
+		go func(name string, material *EncryptionMaterial) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := getFile(ctx, storage, name, material, wrapper, localDir); err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(name, material)
+	}
+	wg.Wait()
+	if firstErr == nil {
+		// the context of the caller was canceled before every file was downloaded
+		firstErr = ctx.Err()
+	}
+	return firstErr
+}
+
+// getFile downloads the file of the stage to the local directory, decrypting it with the material and the wrapper if
+// it is encrypted. A file that fails to be downloaded is removed.
+func getFile(ctx context.Context, storage stageStorage, name string, material *EncryptionMaterial,
+	wrapper FileKeyWrapper, localDir string) error {
+	content, meta, err := storage.download(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+	localPath := filepath.Join(localDir, path.Base(name))
+	f, err := os.Create(localPath)
+	if err != nil {
+		return err
+	}
+	switch {
+	case meta.encryption == nil:
+		_, err = io.Copy(f, content)
+	case material == nil:
+		err = fmt.Errorf("no encryption material of the encrypted file %v", name)
+	default:
+		err = decryptFile(ctx, content, f, material, meta.encryption, wrapper)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(localPath)
+	}
+	return err
+}
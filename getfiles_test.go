@@ -0,0 +1,123 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestClientPutGetFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "getfiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"a.csv", "b.csv"} {
+		if err = ioutil.WriteFile(filepath.Join(dir, name), []byte("content of "+name), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server := newFakeStageStorage()
+	defer server.Close()
+	material := testEncryptionMaterial()
+	client := newFakeQueryClient(nil)
+	client.sc.rest.Client = server.Client()
+	client.answerFileTransfers(server.stageInfo("AZURE"), material, "a.csv", "b.csv")
+
+	// the files are uploaded as they are, so that they are downloaded as the local ones
+	for _, name := range []string{"a.csv", "b.csv"} {
+		results, err := client.PutFiles(context.Background(), []string{filepath.Join(dir, name)}, "@s",
+			&PutFilesOptions{DisableAutoCompress: true})
+		if err != nil || results[0].Status != PutFileUploaded {
+			t.Fatalf("failed to upload %v. results: %+v, err: %v", name, results, err)
+		}
+	}
+	if err = client.PutFile(context.Background(), filepath.Join(dir, "missing.csv"), "@s"); err == nil {
+		t.Fatal("PutFile of a missing file should fail")
+	}
+	if err = client.PutFile(context.Background(), filepath.Join(dir, "a.csv"), "@s"); err != nil {
+		t.Fatal(err)
+	}
+
+	localDir := filepath.Join(dir, "downloaded")
+	if err = client.GetFile(context.Background(), "@s", localDir); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.csv", "b.csv"} {
+		if b, err := ioutil.ReadFile(filepath.Join(localDir, name)); err != nil || string(b) != "content of "+name {
+			t.Fatalf("unexpected content of %v: %q, err: %v", name, b, err)
+		}
+	}
+	stmts := client.queries()
+	if get := stmts[len(stmts)-1]; get != "GET '@s' 'file://"+filepath.ToSlash(localDir)+"'" {
+		t.Fatalf("unexpected GET: %v", get)
+	}
+
+	// a file that fails to be downloaded is not left in the directory
+	client.answerFileTransfers(server.stageInfo("AZURE"), nil, "a.csv")
+	localDir = filepath.Join(dir, "unencrypted")
+	if err = client.GetFile(context.Background(), "@s", localDir); err == nil ||
+		!strings.Contains(err.Error(), "no encryption material") {
+		t.Fatalf("an encrypted file without its material should fail. err: %v", err)
+	}
+	if _, err = os.Stat(filepath.Join(localDir, "a.csv")); !os.IsNotExist(err) {
+		t.Fatalf("the failed file should be removed. err: %v", err)
+	}
+	client.answerFileTransfers(server.stageInfo("AZURE"), material, "missing.csv")
+	if err = client.GetFile(context.Background(), "@s", localDir); err == nil {
+		t.Fatal("a missing file should fail")
+	} else if driverErr, ok := err.(*SnowflakeError); !ok || driverErr.Number != ErrFailedToDownloadFile {
+		t.Fatalf("the download should fail with ErrFailedToDownloadFile. err: %v", err)
+	}
+}
+
+func TestClientFileKeyWrapper(t *testing.T) {
+	dir, err := ioutil.TempDir("", "getfiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err = ioutil.WriteFile(filepath.Join(dir, "a.csv"), []byte("content"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	server := newFakeStageStorage()
+	defer server.Close()
+	material := testEncryptionMaterial()
+	wrapper := &reversingKeyWrapper{}
+	client := newFakeQueryClient(nil)
+	client.sc.rest.Client = server.Client()
+	client.sc.cfg.FileKeyWrapper = wrapper
+	client.answerFileTransfers(server.stageInfo("S3"), material, "a.csv")
+
+	if err = client.PutFile(context.Background(), filepath.Join(dir, "a.csv"), "@s"); err != nil {
+		t.Fatal(err)
+	}
+	file, _ := server.file("/bucket/stage/a.csv.gz")
+	storage, _ := newStageStorage(server.Client(), &execResponseStageInfo{LocationType: "S3"})
+	meta, err := storage.(*cloudStorage).parseMeta(file.header)
+	if err != nil || meta.encryption == nil {
+		t.Fatalf("the file should be encrypted. meta: %+v, err: %v", meta, err)
+	}
+	if err = decryptFile(context.Background(), bytes.NewReader(file.content), &bytes.Buffer{}, material,
+		meta.encryption, masterKeyWrapper{}); err == nil {
+		t.Fatal("the file key should be wrapped by the FileKeyWrapper of the Config, not with the master key")
+	}
+
+	client.answerFileTransfers(server.stageInfo("S3"), material, "a.csv.gz")
+	if err = client.GetFile(context.Background(), "@s", dir); err != nil {
+		t.Fatal(err)
+	}
+	if wrapped, unwrapped := wrapper.counts(); wrapped != 1 || unwrapped != 1 {
+		t.Fatalf("the FileKeyWrapper should wrap the key on PUT and unwrap it on GET. wrapped: %v, unwrapped: %v",
+			wrapped, unwrapped)
+	}
+	if b, err := ioutil.ReadFile(filepath.Join(dir, "a.csv.gz")); err != nil || len(b) == 0 {
+		t.Fatalf("the file should be downloaded. err: %v", err)
+	}
+}
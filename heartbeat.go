@@ -9,6 +9,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,15 +18,26 @@ import (
 const (
 	// One hour interval should be good enough to renew tokens for four hours master token validity
 	heartBeatInterval = 3600 * time.Second
+	// the shortest interval of CLIENT_SESSION_KEEP_ALIVE_HEARTBEAT_FREQUENCY Snowflake allows
+	minHeartBeatInterval = 900 * time.Second
 )
 
 type heartbeat struct {
 	restful      *snowflakeRestful
+	interval     time.Duration
+	ctx          context.Context // stops the heartbeat when it is done
 	shutdownChan chan bool
+	doneChan     chan bool
 }
 
 func (hc *heartbeat) run() {
-	hbTicker := time.NewTicker(heartBeatInterval)
+	defer close(hc.doneChan)
+	interval := hc.interval
+	if interval <= 0 {
+		interval = heartBeatInterval
+	}
+	glog.V(2).Infof("heartbeat interval: %v", interval)
+	hbTicker := time.NewTicker(interval)
 	defer hbTicker.Stop()
 	for {
 		select {
@@ -37,23 +49,36 @@ func (hc *heartbeat) run() {
 		case <-hc.shutdownChan:
 			glog.V(2).Info("stopping heartbeat")
 			return
+		case <-hc.ctx.Done():
+			glog.V(2).Info("stopping heartbeat as the background context is done")
+			return
 		}
 	}
 }
 
 func (hc *heartbeat) start() {
+	if hc.ctx == nil {
+		hc.ctx = context.Background()
+	}
 	hc.shutdownChan = make(chan bool)
+	hc.doneChan = make(chan bool)
 	go hc.run()
 	glog.V(2).Info("heartbeat started")
 }
 
+// stop stops the heartbeat and waits for it, which may have stopped already with its context.
 func (hc *heartbeat) stop() {
-	hc.shutdownChan <- true
 	close(hc.shutdownChan)
+	<-hc.doneChan
 	glog.V(2).Info("heartbeat stopped")
 }
 
 func (hc *heartbeat) heartbeatMain() error {
+	return heartbeatSession(hc.ctx, hc.restful)
+}
+
+// heartbeatSession sends a heartbeat of the session, renewing the session if it has expired.
+func heartbeatSession(ctx context.Context, sr *snowflakeRestful) error {
 	glog.V(2).Info("Heartbeating!")
 	params := &url.Values{}
 	params.Add(requestIDKey, uuid.New().String())
@@ -61,16 +86,16 @@ func (hc *heartbeat) heartbeatMain() error {
 	headers := make(map[string]string)
 	headers["Content-Type"] = headerContentTypeApplicationJSON
 	headers["accept"] = headerAcceptTypeApplicationSnowflake
-	headers["User-Agent"] = userAgent
-	headers[headerAuthorizationKey] = fmt.Sprintf(headerSnowflakeToken, hc.restful.Token)
+	headers["User-Agent"] = sr.getUserAgent()
+	headers[headerAuthorizationKey] = fmt.Sprintf(headerSnowflakeToken, sr.Token)
 
-	fullURL := hc.restful.getFullURL(heartBeatPath, params)
-	timeout := hc.restful.RequestTimeout
-	resp, err := hc.restful.FuncPost(context.Background(), hc.restful, fullURL, headers, nil, timeout, false)
+	fullURL := sr.getFullURL(heartBeatPath, params)
+	timeout := sr.RequestTimeout
+	resp, err := sr.FuncPost(ctx, sr, fullURL, headers, nil, timeout, false)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
+	defer drainAndClose(resp.Body)
 	if resp.StatusCode == http.StatusOK {
 		glog.V(2).Infof("heartbeatMain: resp: %v", resp)
 		var respd execResponse
@@ -81,9 +106,17 @@ func (hc *heartbeat) heartbeatMain() error {
 			return err
 		}
 		if respd.Code == sessionExpiredCode {
-			err = hc.restful.FuncRenewSession(context.TODO(), hc.restful, timeout)
+			return sr.FuncRenewSession(ctx, sr, timeout)
+		}
+		if !respd.Success {
+			code, err := strconv.Atoi(respd.Code)
 			if err != nil {
-				return err
+				code = ErrFailedToHeartbeat
+			}
+			return &SnowflakeError{
+				Number:   code,
+				SQLState: SQLStateConnectionFailure,
+				Message:  respd.Message,
 			}
 		}
 		return nil
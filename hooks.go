@@ -0,0 +1,55 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"database/sql/driver"
+)
+
+// HookConn is the connection given to the ConnectionOpenHook and the ConnectionCloseHook. The statements run on
+// it are run in the session of the connection.
+type HookConn interface {
+	SnowflakeConnection
+	driver.ExecerContext
+	driver.QueryerContext
+}
+
+// ConnectionOpenHook is called after a connection has logged in and before it is handed out, e.g., to tag the
+// session by ALTER SESSION SET QUERY_TAG, to record the session in a fleet inventory from conn.ConnectionInfo(),
+// or to run warm-up statements. If it returns an error, the session is closed and the error is returned instead
+// of the connection.
+type ConnectionOpenHook func(ctx context.Context, conn HookConn) error
+
+// ConnectionCloseHook is called before a connection is closed, while the session is still usable, e.g., to
+// remove the session from a fleet inventory. It is called with a context timing out after Config.RequestTimeout.
+type ConnectionCloseHook func(ctx context.Context, conn HookConn)
+
+// runOpenHook runs the ConnectionOpenHook of the Config, if any, on the connection that has just logged in.
+func (sc *snowflakeConn) runOpenHook(ctx context.Context) error {
+	if sc.cfg.OnOpen == nil {
+		return nil
+	}
+	if err := sc.cfg.OnOpen(ctx, sc); err != nil {
+		glog.V(1).Infof("the connection open hook failed. closing the session. err: %v", err)
+		if cerr := sc.rest.FuncCloseSession(ctx, sc.rest, sc.rest.RequestTimeout); cerr != nil {
+			glog.V(2).Info(cerr)
+		}
+		return err
+	}
+	return nil
+}
+
+// runCloseHook runs the ConnectionCloseHook of the Config, if any, on the connection that is being closed.
+func (sc *snowflakeConn) runCloseHook() {
+	if sc.cfg.OnClose == nil {
+		return
+	}
+	ctx := context.Background()
+	if sc.rest.RequestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, sc.rest.RequestTimeout)
+		defer cancel()
+	}
+	sc.cfg.OnClose(ctx, sc)
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestConnectionHooks(t *testing.T) {
+	var queries []string
+	closed := false
+	sc := &snowflakeConn{
+		cfg: &Config{
+			Params: map[string]*string{},
+			OnOpen: func(ctx context.Context, conn HookConn) error {
+				_, err := conn.ExecContext(ctx, "ALTER SESSION SET QUERY_TAG = 'fleet'", nil)
+				return err
+			},
+			OnClose: func(ctx context.Context, conn HookConn) {
+				if conn.ConnectionInfo().SessionID != 123 {
+					t.Errorf("unexpected session ID: %v", conn.ConnectionInfo().SessionID)
+				}
+				if _, err := conn.ExecContext(ctx, "SELECT 1", nil); err != nil {
+					t.Errorf("the session should be usable in the close hook. err: %v", err)
+				}
+			},
+		},
+		info: ConnectionInfo{SessionID: 123},
+		rest: &snowflakeRestful{
+			FuncPostQuery: func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte, _ time.Duration, _ *uuid.UUID) (*execResponse, error) {
+				var req execRequest
+				if err := json.Unmarshal(body, &req); err != nil {
+					return nil, err
+				}
+				if closed {
+					t.Errorf("the query should run before the session is closed: %v", req.SQLText)
+				}
+				queries = append(queries, req.SQLText)
+				return &execResponse{Data: execResponseData{QueryID: "q"}, Code: "0", Success: true}, nil
+			},
+			FuncCloseSession: func(_ context.Context, _ *snowflakeRestful, _ time.Duration) error {
+				closed = true
+				return nil
+			},
+		},
+	}
+	if err := sc.runOpenHook(context.Background()); err != nil {
+		t.Fatalf("failed to run the open hook. err: %v", err)
+	}
+	if len(queries) != 1 || queries[0] != "ALTER SESSION SET QUERY_TAG = 'fleet'" || closed {
+		t.Fatalf("the open hook should have run on the open session. queries: %v", queries)
+	}
+	rest := sc.rest
+	if err := sc.Close(); err != nil {
+		t.Fatalf("failed to close. err: %v", err)
+	}
+	if len(queries) != 2 || !closed {
+		t.Fatalf("the close hook should have run before the session was closed. queries: %v", queries)
+	}
+
+	closed = false
+	hookErr := errors.New("tagging failed")
+	sc.cfg = &Config{
+		Params: map[string]*string{},
+		OnOpen: func(context.Context, HookConn) error { return hookErr },
+	}
+	sc.rest = rest
+	if err := sc.runOpenHook(context.Background()); err != hookErr {
+		t.Fatalf("should return the error of the hook. err: %v", err)
+	}
+	if !closed {
+		t.Fatal("the session should be closed if the open hook fails")
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// idempotencyNamespace is the namespace of the request IDs derived from the idempotency keys, so that the same key
+// gives the same request ID in every process.
+var idempotencyNamespace = uuid.MustParse("5f0c2a39-3d3e-4c8e-9a57-6b1f0c2d8e41")
+
+// WithIdempotencyKey returns a context whose statements are submitted with a request ID derived from the key,
+// instead of a random one. Snowflake takes a query request with the request ID of an earlier one for a retry of
+// it, and returns the result of the earlier statement instead of running it again, so that an INSERT or a MERGE
+// submitted again after a timeout, e.g., by a retried job, is applied once. Use a key per statement, e.g., the ID of
+// the message that the statement writes: another statement run with the same key gets the result of the first one.
+// The statements that the driver runs on its own with the context, e.g., BEGIN of BeginTx or the description of
+// PrepareContext, get random request IDs. An empty key is ignored.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	if key == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, idempotencyKey, key)
+}
+
+// IdempotentRequestID returns the request ID of the statements run with the idempotency key, e.g., to find them in
+// the query history.
+func IdempotentRequestID(key string) string {
+	return uuid.NewSHA1(idempotencyNamespace, []byte(key)).String()
+}
+
+// isIdempotent returns true if the context has an idempotency key.
+func isIdempotent(ctx context.Context) bool {
+	_, ok := ctx.Value(idempotencyKey).(string)
+	return ok
+}
+
+// withoutIdempotencyKey returns the context without the idempotency key, for the statements that the driver runs
+// on its own, e.g., BEGIN or the description of a prepared statement, which must not take the request ID of the
+// statement of the application.
+func withoutIdempotencyKey(ctx context.Context) context.Context {
+	if !isIdempotent(ctx) {
+		return ctx
+	}
+	return context.WithValue(ctx, idempotencyKey, nil)
+}
+
+// newRequestID returns the request ID of a statement, derived from the idempotency key of the context if it has
+// one, or a random one.
+func newRequestID(ctx context.Context) uuid.UUID {
+	if key, ok := ctx.Value(idempotencyKey).(string); ok {
+		return uuid.NewSHA1(idempotencyNamespace, []byte(key))
+	}
+	return uuid.New()
+}
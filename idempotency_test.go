@@ -0,0 +1,117 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestWithIdempotencyKey(t *testing.T) {
+	var requestIDs []string
+	c := &Client{sc: &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{
+			FuncPostQuery: func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, _ []byte, _ time.Duration, requestID *uuid.UUID) (*execResponse, error) {
+				requestIDs = append(requestIDs, requestID.String())
+				one := "1"
+				return &execResponse{
+					Data: execResponseData{
+						QueryID:         "qid",
+						RowType:         []execResponseRowType{{Name: "number of rows inserted", Type: "fixed"}},
+						RowSet:          [][]*string{{&one}},
+						Total:           1,
+						StatementTypeID: statementTypeIDInsert,
+					},
+					Code:    "0",
+					Success: true,
+				}, nil
+			},
+		},
+	}}
+
+	for _, ctx := range []context.Context{
+		WithIdempotencyKey(context.Background(), "order-1"),
+		WithIdempotencyKey(context.Background(), "order-1"),
+		WithIdempotencyKey(context.Background(), "order-2"),
+		context.Background(),
+		WithIdempotencyKey(context.Background(), ""),
+	} {
+		if _, err := c.Exec(ctx, "INSERT INTO t VALUES(1)"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if requestIDs[0] != IdempotentRequestID("order-1") || requestIDs[1] != requestIDs[0] {
+		t.Fatalf("the statements of the same key should have the same request ID. request IDs: %v", requestIDs)
+	}
+	if requestIDs[2] != IdempotentRequestID("order-2") || requestIDs[2] == requestIDs[0] {
+		t.Fatalf("another key should have another request ID. request IDs: %v", requestIDs)
+	}
+	if requestIDs[3] == requestIDs[4] || requestIDs[3] == requestIDs[0] || requestIDs[4] == IdempotentRequestID("") {
+		t.Fatalf("the statements without a key should have random request IDs. request IDs: %v", requestIDs)
+	}
+	if id := IdempotentRequestID("order-1"); uuid.MustParse(id).Version() != 5 {
+		t.Fatalf("the request ID should be a name-based UUID: %v", id)
+	}
+}
+
+func TestIdempotencyKeyInternalStatements(t *testing.T) {
+	requestIDs := make(map[string]string)
+	sc := &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}, DescribeCacheSize: 1},
+		rest: &snowflakeRestful{
+			FuncPostQuery: func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte, _ time.Duration, requestID *uuid.UUID) (*execResponse, error) {
+				var req execRequest
+				if err := json.Unmarshal(body, &req); err != nil {
+					return nil, err
+				}
+				statement := req.SQLText
+				if req.DescribeOnly {
+					statement = "DESCRIBE " + statement
+				}
+				requestIDs[statement] = requestID.String()
+				one := "1"
+				return &execResponse{
+					Data: execResponseData{
+						QueryID:         "qid",
+						RowType:         []execResponseRowType{{Name: "number of rows inserted", Type: "fixed"}},
+						RowSet:          [][]*string{{&one}},
+						Total:           1,
+						StatementTypeID: statementTypeIDInsert,
+					},
+					Code:    "0",
+					Success: true,
+				}, nil
+			},
+		},
+	}
+	ctx := WithIdempotencyKey(context.Background(), "order-1")
+	stmt, err := sc.PrepareContext(ctx, "INSERT INTO t VALUES(1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = stmt.(driver.StmtExecContext).ExecContext(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = sc.BeginTx(ctx, driver.TxOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = sc.ExecContext(ctx, "INSERT INTO t VALUES(2)", nil); err != nil {
+		t.Fatal(err)
+	}
+	key := IdempotentRequestID("order-1")
+	if requestIDs["INSERT INTO t VALUES(1)"] != key || requestIDs["INSERT INTO t VALUES(2)"] != key {
+		t.Fatalf("the statements should have the request ID of the key. request IDs: %v", requestIDs)
+	}
+	for _, statement := range []string{"DESCRIBE INSERT INTO t VALUES(1)", "BEGIN"} {
+		if id, ok := requestIDs[statement]; !ok || id == key {
+			t.Errorf("%v should have a random request ID. request IDs: %v", statement, requestIDs)
+		}
+	}
+}
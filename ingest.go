@@ -0,0 +1,397 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/arrow"
+	"github.com/apache/arrow/go/arrow/array"
+	"github.com/apache/arrow/go/arrow/decimal128"
+	"github.com/google/uuid"
+)
+
+// defaultIngestFileRows is the number of rows of a staged file if IngestOptions doesn't set it.
+const defaultIngestFileRows = 500000
+
+// ingestFileFormat is the file format of the staged files, whose columns are loaded into the columns of the table
+// with the same names.
+const ingestFileFormat = `FILE_FORMAT = (TYPE = PARQUET) MATCH_BY_COLUMN_NAME = CASE_INSENSITIVE`
+
+// IngestOptions controls how Client.IngestRecords and Client.IngestRows load the data.
+type IngestOptions struct {
+	// MaxFileRows is the maximum number of rows of a staged file. It is 500000 by default.
+	MaxFileRows int
+	// Parallelism is the number of the files uploaded at a time, as that of PutFilesOptions.
+	Parallelism int
+	// CopyOptions are appended to COPY INTO, e.g., ON_ERROR = CONTINUE.
+	CopyOptions string
+}
+
+// IngestResult is the result of Client.IngestRecords and Client.IngestRows.
+type IngestResult struct {
+	QueryID string // the query ID of COPY INTO
+	Rows    int64  // number of loaded rows
+	Files   []PutFileResult
+}
+
+// IngestRecords writes the Arrow records to local Parquet files, uploads them to a temporary stage and loads them
+// into the table with COPY INTO, until the channel is closed. The columns of the files are the fields of the schema
+// of the first record, and every record must have the same field names and types. The columns are loaded into the
+// columns of the table with the same names, case-insensitively, and the other columns of the table get their
+// defaults. Each record is released after it is written. The table is not escaped.
+func (c *Client) IngestRecords(ctx context.Context, table string, records <-chan array.Record, opts *IngestOptions) (*IngestResult, error) {
+	return c.ingest(ctx, table, opts, func(w *ingestWriter) error {
+		for {
+			var record array.Record
+			var ok bool
+			select {
+			case record, ok = <-records:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			if !ok {
+				return nil
+			}
+			err := w.writeRecord(record)
+			record.Release()
+			if err != nil {
+				return err
+			}
+		}
+	})
+}
+
+// IngestRows is IngestRecords for the rows of the values of the columns. A value is nil for NULL, or a string,
+// []byte, bool, an integer, a float, time.Time or any value formatted by fmt.Sprint. The values are written as
+// strings, e.g., hex for []byte, which COPY INTO converts to the types of the columns of the table.
+func (c *Client) IngestRows(ctx context.Context, table string, columns []string, rows <-chan []interface{}, opts *IngestOptions) (*IngestResult, error) {
+	return c.ingest(ctx, table, opts, func(w *ingestWriter) error {
+		for _, name := range columns {
+			w.columns = append(w.columns, &parquetColumn{name: name, typ: parquetByteArray, converted: parquetUTF8})
+		}
+		for {
+			var row []interface{}
+			var ok bool
+			select {
+			case row, ok = <-rows:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			if !ok {
+				return nil
+			}
+			if len(row) != len(columns) {
+				return fmt.Errorf("a row has %v values for %v columns", len(row), len(columns))
+			}
+			cols, err := w.row()
+			if err != nil {
+				return err
+			}
+			for i, v := range row {
+				if s, null := formatIngestValue(v); null {
+					cols[i].appendNull()
+				} else {
+					cols[i].appendBytes([]byte(s))
+				}
+			}
+			if err = w.endRow(); err != nil {
+				return err
+			}
+		}
+	})
+}
+
+// ingest writes the files with write, and stages and loads them if there are any.
+func (c *Client) ingest(ctx context.Context, table string, opts *IngestOptions, write func(w *ingestWriter) error) (*IngestResult, error) {
+	if opts == nil {
+		opts = &IngestOptions{}
+	}
+	dir, err := ioutil.TempDir("", "sf_go_ingest")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	w := &ingestWriter{dir: dir, maxRows: opts.MaxFileRows}
+	if w.maxRows <= 0 {
+		w.maxRows = defaultIngestFileRows
+	}
+	err = write(w)
+	if cerr := w.close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	ret := &IngestResult{}
+	if len(w.files) == 0 {
+		return ret, nil
+	}
+
+	stage := "SF_GO_INGEST_" + strings.ToUpper(strings.Replace(uuid.New().String(), "-", "", -1))
+	if _, err = c.sc.ExecContext(withoutIdempotencyKey(ctx), "CREATE TEMPORARY STAGE "+stage, nil); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if _, err := c.sc.ExecContext(context.Background(), "DROP STAGE IF EXISTS "+stage, nil); err != nil {
+			glog.V(1).Infof("failed to drop the stage %v. err: %v", stage, err)
+		}
+	}()
+	if ret.Files, err = c.PutFiles(ctx, w.files, "@"+stage, &PutFilesOptions{Parallelism: opts.Parallelism}); err != nil {
+		return nil, err
+	}
+	for _, f := range ret.Files {
+		if f.Status == PutFileFailed {
+			return nil, f.Err
+		}
+	}
+
+	copyInto := fmt.Sprintf("COPY INTO %v FROM @%v %v PURGE = TRUE", table, stage, ingestFileFormat)
+	if opts.CopyOptions != "" {
+		copyInto += " " + opts.CopyOptions
+	}
+	rows, err := c.queryValues(ctx, copyInto, &ret.QueryID)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		// file, status, rows_parsed, rows_loaded, ...
+		if len(row) < 4 {
+			continue
+		}
+		if n, err := strconv.ParseInt(fmt.Sprint(row[3]), 10, 64); err == nil {
+			ret.Rows += n
+		}
+	}
+	return ret, nil
+}
+
+// ingestWriter writes the rows to Parquet files of up to maxRows rows in dir.
+type ingestWriter struct {
+	dir     string
+	maxRows int
+	files   []string
+	// columns are the columns of the files, set by IngestRows or by the first record of IngestRecords
+	columns []*parquetColumn
+	f       *os.File
+	w       *bufio.Writer
+	pw      *parquetWriter
+	rows    int
+}
+
+// row returns the columns the values of the next row are appended to, creating a file if there is none.
+func (w *ingestWriter) row() ([]*parquetColumn, error) {
+	if w.pw == nil {
+		path := filepath.Join(w.dir, fmt.Sprintf("data_%d.parquet", len(w.files)))
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		w.files = append(w.files, path)
+		columns := make([]*parquetColumn, len(w.columns))
+		for i, c := range w.columns {
+			columns[i] = &parquetColumn{name: c.name, typ: c.typ, converted: c.converted, typeLength: c.typeLength,
+				scale: c.scale, precision: c.precision, timeUnit: c.timeUnit}
+		}
+		w.f, w.w = f, bufio.NewWriter(f)
+		if w.pw, err = newParquetWriter(w.w, columns); err != nil {
+			return nil, err
+		}
+	}
+	return w.pw.columns, nil
+}
+
+// endRow ends the current row, and the file if it has maxRows rows.
+func (w *ingestWriter) endRow() error {
+	if err := w.pw.endRow(); err != nil {
+		return err
+	}
+	if w.rows++; w.rows >= w.maxRows {
+		return w.close()
+	}
+	return nil
+}
+
+func (w *ingestWriter) close() error {
+	if w.f == nil {
+		return nil
+	}
+	err := w.pw.close()
+	if ferr := w.w.Flush(); err == nil {
+		err = ferr
+	}
+	if cerr := w.f.Close(); err == nil {
+		err = cerr
+	}
+	w.f, w.w, w.pw, w.rows = nil, nil, nil, 0
+	return err
+}
+
+// writeRecord writes the rows of the record. The columns are set to the fields of the first record, and the
+// fields of the other records must have the same names and types.
+func (w *ingestWriter) writeRecord(record array.Record) error {
+	fields := record.Schema().Fields()
+	if w.columns != nil && len(fields) != len(w.columns) {
+		return fmt.Errorf("a record has %v columns, not %v", len(fields), len(w.columns))
+	}
+	recordColumns := make([]*parquetColumn, len(fields))
+	appenders := make([]func(*parquetColumn, int), len(fields))
+	for i, f := range fields {
+		var err error
+		if recordColumns[i], appenders[i], err = arrowIngestColumn(f.Name, record.Column(i)); err != nil {
+			return fmt.Errorf("the column %v: %v", f.Name, err)
+		}
+		if w.columns != nil && !recordColumns[i].sameType(w.columns[i]) {
+			return fmt.Errorf("the column %v of a record is %v %v, not the one of the first record", i, f.Name,
+				f.Type)
+		}
+	}
+	if w.columns == nil {
+		w.columns = recordColumns
+	}
+	for row := 0; row < int(record.NumRows()); row++ {
+		cols, err := w.row()
+		if err != nil {
+			return err
+		}
+		for i, appendValue := range appenders {
+			if record.Column(i).IsNull(row) {
+				cols[i].appendNull()
+			} else {
+				appendValue(cols[i], row)
+			}
+		}
+		if err = w.endRow(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// arrowIngestColumn returns the Parquet column of the Arrow column, and the function appending the value of the
+// column at an index to it. The unsigned integers are widened, or are a DECIMAL(20, 0) for uint64, the dates of
+// Date64 are truncated to the day and the timestamps of seconds are written in milliseconds.
+func arrowIngestColumn(name string, col array.Interface) (*parquetColumn, func(*parquetColumn, int), error) {
+	c := &parquetColumn{name: name, converted: parquetNoConvertedType}
+	var appendValue func(*parquetColumn, int)
+	switch col := col.(type) {
+	case *array.Null:
+		c.typ = parquetBoolean
+		appendValue = func(*parquetColumn, int) {}
+	case *array.Boolean:
+		c.typ = parquetBoolean
+		appendValue = func(p *parquetColumn, i int) { p.appendBool(col.Value(i)) }
+	case *array.Int8:
+		c.typ = parquetInt32
+		appendValue = func(p *parquetColumn, i int) { p.appendInt32(int32(col.Value(i))) }
+	case *array.Int16:
+		c.typ = parquetInt32
+		appendValue = func(p *parquetColumn, i int) { p.appendInt32(int32(col.Value(i))) }
+	case *array.Int32:
+		c.typ = parquetInt32
+		appendValue = func(p *parquetColumn, i int) { p.appendInt32(col.Value(i)) }
+	case *array.Int64:
+		c.typ = parquetInt64
+		appendValue = func(p *parquetColumn, i int) { p.appendInt64(col.Value(i)) }
+	case *array.Uint8:
+		c.typ = parquetInt32
+		appendValue = func(p *parquetColumn, i int) { p.appendInt32(int32(col.Value(i))) }
+	case *array.Uint16:
+		c.typ = parquetInt32
+		appendValue = func(p *parquetColumn, i int) { p.appendInt32(int32(col.Value(i))) }
+	case *array.Uint32:
+		c.typ = parquetInt64
+		appendValue = func(p *parquetColumn, i int) { p.appendInt64(int64(col.Value(i))) }
+	case *array.Uint64:
+		c.typ, c.converted, c.typeLength, c.precision = parquetFixedLenByteArray, parquetDecimal, 16, 20
+		appendValue = func(p *parquetColumn, i int) { p.appendBytes(decimalBytes(decimal128.FromU64(col.Value(i)))) }
+	case *array.Float32:
+		c.typ = parquetFloat
+		appendValue = func(p *parquetColumn, i int) { p.appendFloat(col.Value(i)) }
+	case *array.Float64:
+		c.typ = parquetDouble
+		appendValue = func(p *parquetColumn, i int) { p.appendDouble(col.Value(i)) }
+	case *array.String:
+		c.typ, c.converted = parquetByteArray, parquetUTF8
+		appendValue = func(p *parquetColumn, i int) { p.appendBytes([]byte(col.Value(i))) }
+	case *array.Binary:
+		c.typ = parquetByteArray
+		appendValue = func(p *parquetColumn, i int) { p.appendBytes(col.Value(i)) }
+	case *array.Date32:
+		c.typ, c.converted = parquetInt32, parquetDate
+		appendValue = func(p *parquetColumn, i int) { p.appendInt32(int32(col.Value(i))) }
+	case *array.Date64:
+		c.typ, c.converted = parquetInt32, parquetDate
+		appendValue = func(p *parquetColumn, i int) {
+			ms := int64(col.Value(i))
+			days := ms / (24 * 60 * 60 * 1000)
+			if ms < 0 && ms%(24*60*60*1000) != 0 {
+				days--
+			}
+			p.appendInt32(int32(days))
+		}
+	case *array.Timestamp:
+		c.typ = parquetInt64
+		scale := int64(1)
+		switch col.DataType().(*arrow.TimestampType).Unit {
+		case arrow.Second:
+			c.converted, c.timeUnit, scale = parquetTimestampMillis, parquetMillis, 1000
+		case arrow.Millisecond:
+			c.converted, c.timeUnit = parquetTimestampMillis, parquetMillis
+		case arrow.Microsecond:
+			c.converted, c.timeUnit = parquetTimestampMicros, parquetMicros
+		default:
+			c.timeUnit = parquetNanos
+		}
+		appendValue = func(p *parquetColumn, i int) { p.appendInt64(int64(col.Value(i)) * scale) }
+	case *array.Decimal128:
+		typ := col.DataType().(*arrow.Decimal128Type)
+		c.typ, c.converted, c.typeLength = parquetFixedLenByteArray, parquetDecimal, 16
+		c.precision, c.scale = typ.Precision, typ.Scale
+		appendValue = func(p *parquetColumn, i int) { p.appendBytes(decimalBytes(col.Value(i))) }
+	default:
+		return nil, nil, fmt.Errorf("unsupported Arrow type: %v", col.DataType())
+	}
+	return c, appendValue, nil
+}
+
+// decimalBytes returns the 16 bytes of the big-endian two's complement of the decimal.
+func decimalBytes(num decimal128.Num) []byte {
+	b := make([]byte, 16)
+	binary.BigEndian.PutUint64(b, uint64(num.HighBits()))
+	binary.BigEndian.PutUint64(b[8:], num.LowBits())
+	return b
+}
+
+// formatIngestValue formats the value of IngestRows as COPY INTO parses it with the default formats, e.g., hex for
+// a binary value, and returns true if it is NULL.
+func formatIngestValue(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case nil:
+		return "", true
+	case string:
+		return v, false
+	case []byte:
+		if v == nil {
+			return "", true
+		}
+		return hex.EncodeToString(v), false
+	case float32:
+		return formatFloat(float64(v), 32), false
+	case float64:
+		return formatFloat(v, 64), false
+	case time.Time:
+		return v.Format(time.RFC3339Nano), false
+	}
+	return fmt.Sprint(v), false
+}
@@ -0,0 +1,275 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/arrow"
+	"github.com/apache/arrow/go/arrow/array"
+	"github.com/apache/arrow/go/arrow/decimal128"
+	"github.com/apache/arrow/go/arrow/memory"
+)
+
+// ingestTestClient returns the Client whose COPY INTO loads 3 rows of every file, and whose PUT stages the files
+// in stageDir.
+func ingestTestClient(stageDir string) *fakeQueryClient {
+	c := newFakeQueryClient([]execResponseRowType{{Name: "status", Type: "text"}}, []string{"ok"})
+	respond := c.respond
+	c.respond = func(req execRequest) (*execResponse, error) {
+		if !strings.HasPrefix(req.SQLText, "COPY INTO") {
+			return respond(req)
+		}
+		return fakeQueryResponse("qid", []execResponseRowType{{Name: "file", Type: "text"}, {Name: "status", Type: "text"},
+			{Name: "rows_parsed", Type: "fixed"}, {Name: "rows_loaded", Type: "fixed"}},
+			[]string{"data_0.parquet", "LOADED", "3", "3"}, []string{"data_1.parquet", "LOADED", "3", "3"}), nil
+	}
+	c.answerFileTransfers(execResponseStageInfo{LocationType: "LOCAL_FS", Location: stageDir}, nil)
+	return c
+}
+
+// stagedFiles returns the Parquet files in the stage directory by their names.
+func stagedFiles(t *testing.T, stageDir string) map[string]*parquetTestFile {
+	paths, err := filepath.Glob(filepath.Join(stageDir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	staged := make(map[string]*parquetTestFile)
+	for _, path := range paths {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		staged[filepath.Base(path)] = readParquetFile(t, b)
+	}
+	return staged
+}
+
+// ingestStageDir returns a new directory standing for the stage of the ingestion, and the function removing it.
+func ingestStageDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "ingest_stage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func TestIngestRows(t *testing.T) {
+	stageDir, cleanup := ingestStageDir(t)
+	defer cleanup()
+	client := ingestTestClient(stageDir)
+
+	rows := make(chan []interface{}, 4)
+	rows <- []interface{}{1, `say "hi"`, nil}
+	rows <- []interface{}{2.5, "", []byte{0xca, 0xfe}}
+	rows <- []interface{}{true, "a,b\nc", time.Date(2020, 9, 1, 10, 11, 12, 5, time.UTC)}
+	rows <- []interface{}{int64(-4), nil, "x"}
+	close(rows)
+	res, err := client.IngestRows(context.Background(), "t", []string{"a", "b", "c"}, rows,
+		&IngestOptions{MaxFileRows: 3, CopyOptions: "ON_ERROR = CONTINUE"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.QueryID != "qid" || res.Rows != 6 || len(res.Files) != 2 || res.Files[1].Status != PutFileUploaded {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	expected := map[string]map[string][]interface{}{
+		"data_0.parquet": {
+			"a": {[]byte("1"), []byte("2.5"), []byte("true")},
+			"b": {[]byte(`say "hi"`), []byte(""), []byte("a,b\nc")},
+			"c": {nil, []byte("cafe"), []byte("2020-09-01T10:11:12.000000005Z")},
+		},
+		"data_1.parquet": {"a": {[]byte("-4")}, "b": {nil}, "c": {[]byte("x")}},
+	}
+	uploaded := stagedFiles(t, stageDir)
+	if len(uploaded) != len(expected) {
+		t.Fatalf("unexpected files in the stage: %v", uploaded)
+	}
+	for name, columns := range expected {
+		if f := uploaded[name]; f == nil || !reflect.DeepEqual(f.columns, columns) {
+			t.Errorf("unexpected content of %v: %+v", name, f)
+		}
+	}
+	stmts := client.queries()
+	if len(stmts) != 4 || !strings.HasPrefix(stmts[0], "CREATE TEMPORARY STAGE SF_GO_INGEST_") ||
+		!strings.HasPrefix(stmts[1], "PUT 'file://") || !strings.HasPrefix(stmts[3], "DROP STAGE IF EXISTS SF_GO_INGEST_") {
+		t.Fatalf("the files should be loaded from a temporary stage. statements: %v", stmts)
+	}
+	stage := strings.TrimPrefix(stmts[0], "CREATE TEMPORARY STAGE ")
+	if copyInto := "COPY INTO t FROM @" + stage + " FILE_FORMAT = (TYPE = PARQUET) " +
+		"MATCH_BY_COLUMN_NAME = CASE_INSENSITIVE PURGE = TRUE ON_ERROR = CONTINUE"; stmts[2] != copyInto {
+		t.Fatalf("unexpected COPY INTO. expected: %v, got: %v", copyInto, stmts[2])
+	}
+
+	client = ingestTestClient(stageDir)
+	rows = make(chan []interface{})
+	close(rows)
+	if res, err = client.IngestRows(context.Background(), "t", []string{"a"}, rows, nil); err != nil || res.Rows != 0 ||
+		len(client.queries()) != 0 {
+		t.Fatalf("nothing should be loaded without rows. res: %+v, statements: %v, err: %v", res, client.queries(), err)
+	}
+	rows = make(chan []interface{}, 1)
+	rows <- []interface{}{1, 2}
+	if _, err = client.IngestRows(context.Background(), "t", []string{"a"}, rows, nil); err == nil {
+		t.Fatal("a row with more values than the columns should fail")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = client.IngestRows(ctx, "t", []string{"a"}, make(chan []interface{}), nil); err != context.Canceled {
+		t.Fatalf("the canceled context should stop reading the rows. err: %v", err)
+	}
+}
+
+func TestIngestRecords(t *testing.T) {
+	stageDir, cleanup := ingestStageDir(t)
+	defer cleanup()
+	client := ingestTestClient(stageDir)
+
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "day", Type: arrow.FixedWidthTypes.Date32},
+		{Name: "at", Type: arrow.FixedWidthTypes.Timestamp_ms},
+		{Name: "amount", Type: &arrow.Decimal128Type{Precision: 10, Scale: 2}},
+		{Name: "ok", Type: arrow.FixedWidthTypes.Boolean},
+		{Name: "data", Type: arrow.BinaryTypes.Binary},
+	}, nil)
+	// RecordBuilder doesn't build the date, timestamp and decimal columns
+	ids := array.NewInt64Builder(pool)
+	ids.AppendValues([]int64{1, 2}, nil)
+	names := array.NewStringBuilder(pool)
+	names.AppendValues([]string{"a\"b", ""}, []bool{true, false})
+	days := array.NewDate32Builder(pool)
+	days.AppendValues([]arrow.Date32{18506, 0}, nil)
+	ats := array.NewTimestampBuilder(pool, arrow.FixedWidthTypes.Timestamp_ms.(*arrow.TimestampType))
+	ats.AppendValues([]arrow.Timestamp{1598955072123, 0}, nil)
+	amounts := array.NewDecimal128Builder(pool, schema.Field(4).Type.(*arrow.Decimal128Type))
+	amounts.AppendValues([]decimal128.Num{decimal128.FromI64(-12345), decimal128.FromI64(5)}, nil)
+	oks := array.NewBooleanBuilder(pool)
+	oks.AppendValues([]bool{true, false}, nil)
+	data := array.NewBinaryBuilder(pool, arrow.BinaryTypes.Binary)
+	data.AppendValues([][]byte{{0x01, 0xff}, {}}, nil)
+	var cols []array.Interface
+	for _, b := range []array.Builder{ids, names, days, ats, amounts, oks, data} {
+		cols = append(cols, b.NewArray())
+		b.Release()
+	}
+	records := make(chan array.Record, 2)
+	records <- array.NewRecord(schema, cols, 2)
+	for _, col := range cols {
+		col.Release()
+	}
+	close(records)
+	res, err := client.IngestRecords(context.Background(), "db.s.t", records, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Rows != 6 || len(res.Files) != 1 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	negative := make([]byte, 16)
+	for i := range negative {
+		negative[i] = 0xff
+	}
+	negative[14], negative[15] = 0xcf, 0xc7 // -12345
+	expected := map[string][]interface{}{
+		"id":     {int64(1), int64(2)},
+		"name":   {[]byte(`a"b`), nil},
+		"day":    {int32(18506), int32(0)},
+		"at":     {int64(1598955072123), int64(0)},
+		"amount": {negative, append(make([]byte, 15), 5)},
+		"ok":     {true, false},
+		"data":   {[]byte{0x01, 0xff}, []byte{}},
+	}
+	f := stagedFiles(t, stageDir)["data_0.parquet"]
+	if f == nil || !reflect.DeepEqual(f.columns, expected) {
+		t.Fatalf("unexpected content: %+v", f)
+	}
+	types := []struct {
+		typ, converted int32
+	}{{parquetInt64, -1}, {parquetByteArray, parquetUTF8}, {parquetInt32, parquetDate},
+		{parquetInt64, parquetTimestampMillis}, {parquetFixedLenByteArray, parquetDecimal}, {parquetBoolean, -1},
+		{parquetByteArray, -1}}
+	for i, e := range f.schema {
+		converted, ok := e[6].(int64)
+		if !ok {
+			converted = -1
+		}
+		if e[1].(int64) != int64(types[i].typ) || converted != int64(types[i].converted) {
+			t.Errorf("unexpected type of the column %v: %v", i, e)
+		}
+	}
+	if amount := f.schema[4]; amount[7].(int64) != 2 || amount[8].(int64) != 10 {
+		t.Fatalf("the decimal should have the precision and scale of the field: %v", amount)
+	}
+	if stmts := client.queries(); !strings.HasPrefix(stmts[2], "COPY INTO db.s.t FROM @SF_GO_INGEST_") {
+		t.Fatalf("the columns should be matched by their names. got: %v", stmts[2])
+	}
+
+	b := array.NewRecordBuilder(pool, arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil))
+	defer b.Release()
+	other := array.NewRecordBuilder(pool, arrow.NewSchema([]arrow.Field{{Name: "other", Type: arrow.PrimitiveTypes.Int64}}, nil))
+	defer other.Release()
+	records = make(chan array.Record, 2)
+	b.Field(0).AppendNull()
+	records <- b.NewRecord()
+	other.Field(0).AppendNull()
+	records <- other.NewRecord()
+	close(records)
+	if _, err = client.IngestRecords(context.Background(), "t", records, nil); err == nil {
+		t.Fatal("a record with other columns should fail")
+	}
+	other32 := array.NewRecordBuilder(pool, arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int32}}, nil))
+	defer other32.Release()
+	records = make(chan array.Record, 2)
+	b.Field(0).AppendNull()
+	records <- b.NewRecord()
+	other32.Field(0).AppendNull()
+	records <- other32.NewRecord()
+	close(records)
+	if _, err = client.IngestRecords(context.Background(), "t", records, nil); err == nil {
+		t.Fatal("a record with another type of a column should fail")
+	}
+
+	unsupported := array.NewRecordBuilder(pool, arrow.NewSchema([]arrow.Field{
+		{Name: "l", Type: arrow.ListOf(arrow.PrimitiveTypes.Int64)},
+	}, nil))
+	defer unsupported.Release()
+	unsupported.Field(0).AppendNull()
+	records = make(chan array.Record, 1)
+	records <- unsupported.NewRecord()
+	close(records)
+	if _, err = client.IngestRecords(context.Background(), "t", records, nil); err == nil {
+		t.Fatal("an unsupported type should fail")
+	}
+}
+
+func TestIngestFailedPut(t *testing.T) {
+	client := ingestTestClient("")
+	respond := client.respond
+	client.respond = func(req execRequest) (*execResponse, error) {
+		if strings.HasPrefix(req.SQLText, "PUT ") {
+			return &execResponse{Data: execResponseData{QueryID: "qid"}, Code: "2003", Message: "stage does not exist"}, nil
+		}
+		return respond(req)
+	}
+	rows := make(chan []interface{}, 1)
+	rows <- []interface{}{1}
+	close(rows)
+	_, err := client.IngestRows(context.Background(), "t", []string{"a"}, rows, nil)
+	if driverErr, ok := err.(*SnowflakeError); !ok || driverErr.Number != 2003 {
+		t.Fatalf("the ingestion should fail with the error of the PUT. err: %v", err)
+	}
+	if stmts := client.queries(); len(stmts) != 3 || !strings.HasPrefix(stmts[2], "DROP STAGE") {
+		t.Fatalf("the stage should be dropped. statements: %v", stmts)
+	}
+}
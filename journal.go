@@ -0,0 +1,168 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// QueryJournalEntry is the record of a statement run on a connection.
+type QueryJournalEntry struct {
+	Time     time.Time     // when the statement started
+	QueryID  string        // empty if the statement failed before Snowflake assigned a query ID
+	SQLText  string        // the statement with the string literals redacted
+	QueryTag string        // QUERY_TAG set by WithQueryTag or WithQueryLabels. empty if not set
+	Duration time.Duration // until the result was returned, not including fetching the rows
+	Rows     int64         // affected rows of a DML statement or rows of a query result. -1 if unknown
+	// Err is nil if the statement succeeded. It is not redacted, and its message may have values of the statement,
+	// e.g., a literal that failed to convert.
+	Err error
+}
+
+// QueryJournal is called with the record of every statement run by ExecContext and QueryContext of a
+// connection, including multi-statement queries as a whole and the failed statements. The statements the
+// driver runs internally are not recorded. It is called in the goroutine that runs the statement, so it
+// should return quickly.
+type QueryJournal func(QueryJournalEntry)
+
+// NewQueryJournalWriter returns the QueryJournal that writes the entries to w as JSON lines. The writes
+// are serialized, so that the connections can share the QueryJournal. The messages of the errors are written as
+// they are, without redaction.
+func NewQueryJournalWriter(w io.Writer) QueryJournal {
+	var mu sync.Mutex
+	enc := json.NewEncoder(w)
+	return func(e QueryJournalEntry) {
+		line := struct {
+			Time       time.Time `json:"time"`
+			QueryID    string    `json:"queryId,omitempty"`
+			SQLText    string    `json:"sqlText"`
+			QueryTag   string    `json:"queryTag,omitempty"`
+			DurationMs int64     `json:"durationMs"`
+			Rows       int64     `json:"rows"`
+			Error      string    `json:"error,omitempty"`
+		}{
+			Time:       e.Time,
+			QueryID:    e.QueryID,
+			SQLText:    e.SQLText,
+			QueryTag:   e.QueryTag,
+			DurationMs: int64(e.Duration / time.Millisecond),
+			Rows:       e.Rows,
+		}
+		if e.Err != nil {
+			line.Error = e.Err.Error()
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if err := enc.Encode(&line); err != nil {
+			glog.V(1).Infof("failed to write the query journal. err: %v", err)
+		}
+	}
+}
+
+// journalExec records the statement run by ExecContext.
+func (sc *snowflakeConn) journalExec(ctx context.Context, start time.Time, query string, res driver.Result, err error) {
+	e := newQueryJournalEntry(ctx, start, query, err)
+	if r, ok := res.(SnowflakeResult); ok {
+		e.QueryID = r.QueryID()
+	}
+	if res != nil {
+		if n, rerr := res.RowsAffected(); rerr == nil {
+			e.Rows = n
+		}
+	}
+	sc.cfg.QueryJournal(e)
+}
+
+// journalQuery records the statement run by QueryContext.
+func (sc *snowflakeConn) journalQuery(ctx context.Context, start time.Time, query string, rows driver.Rows, err error) {
+	e := newQueryJournalEntry(ctx, start, query, err)
+	if r, ok := rows.(*snowflakeRows); ok {
+		e.QueryID = r.queryID
+		if r.ChunkDownloader != nil {
+			e.Rows = r.ChunkDownloader.Total
+		}
+	}
+	sc.cfg.QueryJournal(e)
+}
+
+func newQueryJournalEntry(ctx context.Context, start time.Time, query string, err error) QueryJournalEntry {
+	tag, _ := queryTag(ctx)
+	e := QueryJournalEntry{
+		Time:     start,
+		SQLText:  redactSQL(query),
+		QueryTag: tag,
+		Duration: time.Since(start),
+		Rows:     -1,
+		Err:      err,
+	}
+	switch qe := err.(type) {
+	case *SnowflakeError:
+		e.QueryID = qe.QueryID
+	case *MultiStatementError:
+		e.QueryID = qe.QueryID
+	}
+	return e
+}
+
+// redactSQL replaces the string literals, quoted by single quotes or $$, in the query with '***', so that
+// no secrets or personal data in the literals are recorded. The quoted identifiers and comments are kept.
+func redactSQL(query string) string {
+	var b strings.Builder
+	for i := 0; i < len(query); {
+		switch {
+		case query[i] == '\'':
+			// '' is an escaped quote in a literal
+			j := i + 1
+			for j < len(query) {
+				if query[j] == '\\' {
+					j += 2
+					continue
+				}
+				if query[j] == '\'' {
+					if j+1 < len(query) && query[j+1] == '\'' {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			b.WriteString("'***'")
+			i = j + 1
+		case strings.HasPrefix(query[i:], "$$"):
+			end := strings.Index(query[i+2:], "$$")
+			b.WriteString("'***'")
+			if end < 0 {
+				return b.String()
+			}
+			i += end + 4
+		case query[i] == '"', strings.HasPrefix(query[i:], "--"), strings.HasPrefix(query[i:], "/*"):
+			// the closing is searched for after the opening, so that "/*/" doesn't end the comment
+			opening, closing := 1, `"`
+			switch query[i] {
+			case '-':
+				opening, closing = 2, "\n"
+			case '/':
+				opening, closing = 2, "*/"
+			}
+			end := strings.Index(query[i+opening:], closing)
+			if end < 0 {
+				b.WriteString(query[i:])
+				return b.String()
+			}
+			n := end + opening + len(closing)
+			b.WriteString(query[i : i+n])
+			i += n
+		default:
+			b.WriteByte(query[i])
+			i++
+		}
+	}
+	return b.String()
+}
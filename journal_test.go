@@ -0,0 +1,98 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestRedactSQL(t *testing.T) {
+	testcases := []struct {
+		query    string
+		redacted string
+	}{
+		{"SELECT 1", "SELECT 1"},
+		{"ALTER USER u SET PASSWORD = 'secret'", "ALTER USER u SET PASSWORD = '***'"},
+		{"SELECT 'it''s', 'a\\'b' FROM t", "SELECT '***', '***' FROM t"},
+		{"SELECT $$multi\nline$$", "SELECT '***'"},
+		{`SELECT "it's" FROM t -- don't`, `SELECT "it's" FROM t -- don't`},
+		{"SELECT /* it's */ 'x'", "SELECT /* it's */ '***'"},
+		{"SELECT /*/ it's */ 'x'", "SELECT /*/ it's */ '***'"},
+		{"SELECT 'unterminated", "SELECT '***'"},
+	}
+	for _, tc := range testcases {
+		if redacted := redactSQL(tc.query); redacted != tc.redacted {
+			t.Errorf("failed to redact %q. expected: %q, got: %q", tc.query, tc.redacted, redacted)
+		}
+	}
+}
+
+func TestQueryJournal(t *testing.T) {
+	var buf bytes.Buffer
+	var entries []QueryJournalEntry
+	writer := NewQueryJournalWriter(&buf)
+	sc := &snowflakeConn{
+		cfg: &Config{
+			Params: map[string]*string{},
+			QueryJournal: func(e QueryJournalEntry) {
+				entries = append(entries, e)
+				writer(e)
+			},
+		},
+		rest: &snowflakeRestful{
+			FuncPostQuery: func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte, _ time.Duration, _ *uuid.UUID) (*execResponse, error) {
+				var req execRequest
+				if err := json.Unmarshal(body, &req); err != nil {
+					return nil, err
+				}
+				if req.SQLText == "SELECT * FROM missing" {
+					return &execResponse{Data: execResponseData{QueryID: "q2", SQLState: "42S02"}, Code: "002003", Message: "does not exist"}, nil
+				}
+				return &execResponse{
+					Data: execResponseData{
+						QueryID:         "q1",
+						StatementTypeID: statementTypeIDInsert,
+						RowType:         []execResponseRowType{{Name: "number of rows inserted", Type: "fixed"}},
+						RowSet:          [][]*string{{&[]string{"3"}[0]}},
+					},
+					Code:    "0",
+					Success: true,
+				}, nil
+			},
+		},
+	}
+	if _, err := sc.ExecContext(context.Background(), "INSERT INTO t VALUES('a'), ('b'), ('c')", nil); err != nil {
+		t.Fatalf("failed to exec. err: %v", err)
+	}
+	if _, err := sc.QueryContext(WithQueryTag(context.Background(), "etl"), "SELECT * FROM missing", nil); err == nil {
+		t.Fatal("should have failed")
+	}
+	if len(entries) != 2 {
+		t.Fatalf("number of entries didn't match. expected: 2, got: %v", len(entries))
+	}
+	if e := entries[0]; e.QueryID != "q1" || e.Rows != 3 || e.Err != nil || e.SQLText != "INSERT INTO t VALUES('***'), ('***'), ('***')" {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+	if e := entries[1]; e.QueryID != "q2" || e.Rows != -1 || e.Err == nil || e.QueryTag != "etl" {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+
+	dec := json.NewDecoder(&buf)
+	for i := 0; i < 2; i++ {
+		var line map[string]interface{}
+		if err := dec.Decode(&line); err != nil {
+			t.Fatalf("failed to decode the journal. err: %v", err)
+		}
+		if line["queryId"] != entries[i].QueryID || line["sqlText"] != entries[i].SQLText ||
+			i == 1 && line["queryTag"] != "etl" {
+			t.Fatalf("unexpected journal line: %v", line)
+		}
+	}
+}
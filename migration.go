@@ -0,0 +1,167 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// defaultMigrationLockTTL is the time a migration lock is held without being refreshed by default.
+	defaultMigrationLockTTL = 15 * time.Minute
+	// defaultMigrationLockPollInterval is the time between the attempts to acquire a migration lock by default.
+	defaultMigrationLockPollInterval = time.Second
+)
+
+// DDLResult is the result of a DDL statement run by ExecDDL.
+type DDLResult struct {
+	QueryID string
+	// Status is the message Snowflake returns for the statement, e.g., "Table T successfully created." or
+	// "T already exists, statement succeeded."
+	Status   string
+	Duration time.Duration
+}
+
+// ExecDDL runs the DDL statement, e.g., CREATE TABLE, and returns its query ID and the status message of
+// Snowflake, e.g., for a migration tool to record what every statement did.
+func (c *Client) ExecDDL(ctx context.Context, statement string, args ...interface{}) (*DDLResult, error) {
+	start := time.Now()
+	res, err := c.Exec(ctx, statement, args...)
+	if err != nil {
+		return nil, err
+	}
+	ddl := &DDLResult{QueryID: res.QueryID(), Duration: time.Since(start)}
+	if sr, ok := res.(SnowflakeDDLResult); ok {
+		ddl.Status = sr.Status()
+	}
+	return ddl, nil
+}
+
+// MigrationLockOptions are the options of NewMigrationLock.
+type MigrationLockOptions struct {
+	// Owner identifies the holder of the lock, e.g., the host name and the process ID. A random UUID by default.
+	Owner string
+	// TTL is the time the lock is held after it is acquired or refreshed. Another owner may take over a lock that
+	// has expired, e.g., because its holder crashed. 15 minutes by default.
+	TTL time.Duration
+	// Timeout is the time Lock waits for another owner to release the lock. By default, Lock fails at once with
+	// ErrMigrationLocked if another owner holds it.
+	Timeout time.Duration
+	// PollInterval is the time between the attempts of Lock to acquire the lock. 1 second by default.
+	PollInterval time.Duration
+}
+
+// MigrationLock is an advisory lock emulated with a row of a lock table, since Snowflake has no advisory locks,
+// for the schema migration tools, e.g., golang-migrate or goose, to run a migration at most once at a time. The
+// lock is held until it is unlocked or its TTL expires, and is not tied to the session, so that it is held across
+// reconnections.
+type MigrationLock struct {
+	c     *Client
+	table string
+	name  string
+	opts  MigrationLockOptions
+}
+
+// NewMigrationLock returns the lock of the name, e.g., the name of the database being migrated, in the lock
+// table, e.g., migrations.public.schema_lock. The table is created by Lock if it doesn't exist.
+func (c *Client) NewMigrationLock(table string, name string, opts *MigrationLockOptions) *MigrationLock {
+	l := &MigrationLock{c: c, table: table, name: name}
+	if opts != nil {
+		l.opts = *opts
+	}
+	if l.opts.Owner == "" {
+		l.opts.Owner = uuid.New().String()
+	}
+	if l.opts.TTL <= 0 {
+		l.opts.TTL = defaultMigrationLockTTL
+	}
+	if l.opts.PollInterval <= 0 {
+		l.opts.PollInterval = defaultMigrationLockPollInterval
+	}
+	return l
+}
+
+// Owner returns the owner the lock is acquired for.
+func (l *MigrationLock) Owner() string {
+	return l.opts.Owner
+}
+
+// Lock acquires the lock, waiting up to the Timeout of the options for another owner to release it, and fails with
+// ErrMigrationLocked if it doesn't. Acquiring a lock the owner already holds extends it as Refresh does.
+func (l *MigrationLock) Lock(ctx context.Context) error {
+	if _, err := l.c.ExecDDL(ctx, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %v (lock_name STRING NOT NULL PRIMARY KEY, "+
+		"owner STRING NOT NULL, acquired_at TIMESTAMP_LTZ NOT NULL, expires_at TIMESTAMP_LTZ NOT NULL)", l.table)); err != nil {
+		return err
+	}
+	deadline := time.Now().Add(l.opts.Timeout)
+	for {
+		acquired, err := l.tryLock(ctx)
+		if err != nil || acquired {
+			return err
+		}
+		if time.Now().Add(l.opts.PollInterval).After(deadline) {
+			return l.errLock(ErrMigrationLocked, errMsgMigrationLocked)
+		}
+		select {
+		case <-time.After(l.opts.PollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// tryLock acquires the lock if no owner holds it, it has expired or the owner already holds it. A MERGE runs
+// atomically on the table, so that only one owner acquires the lock.
+func (l *MigrationLock) tryLock(ctx context.Context) (bool, error) {
+	ttl := l.opts.TTL.Milliseconds()
+	res, err := l.c.Exec(ctx, fmt.Sprintf("MERGE INTO %v t USING (SELECT ? AS lock_name) s ON t.lock_name = s.lock_name "+
+		"WHEN MATCHED AND (t.expires_at < CURRENT_TIMESTAMP() OR t.owner = ?) THEN UPDATE SET owner = ?, "+
+		"acquired_at = CURRENT_TIMESTAMP(), expires_at = DATEADD(millisecond, ?, CURRENT_TIMESTAMP()) "+
+		"WHEN NOT MATCHED THEN INSERT (lock_name, owner, acquired_at, expires_at) "+
+		"VALUES (s.lock_name, ?, CURRENT_TIMESTAMP(), DATEADD(millisecond, ?, CURRENT_TIMESTAMP()))", l.table),
+		l.name, l.opts.Owner, l.opts.Owner, ttl, l.opts.Owner, ttl)
+	if err != nil {
+		return false, err
+	}
+	return res.RowCounts().Total() > 0, nil
+}
+
+// Refresh extends the lock by its TTL, e.g., periodically during a long migration. It fails with
+// ErrMigrationLockLost if the owner no longer holds the lock.
+func (l *MigrationLock) Refresh(ctx context.Context) error {
+	res, err := l.c.Exec(ctx, fmt.Sprintf("UPDATE %v SET expires_at = DATEADD(millisecond, ?, CURRENT_TIMESTAMP()) "+
+		"WHERE lock_name = ? AND owner = ?", l.table), l.opts.TTL.Milliseconds(), l.name, l.opts.Owner)
+	if err != nil {
+		return err
+	}
+	if res.RowCounts().Total() == 0 {
+		return l.errLock(ErrMigrationLockLost, errMsgMigrationLockLost)
+	}
+	return nil
+}
+
+// Unlock releases the lock. It fails with ErrMigrationLockLost if the owner no longer holds the lock, e.g., because
+// it expired and another owner acquired it, in which case the migration may have run concurrently.
+func (l *MigrationLock) Unlock(ctx context.Context) error {
+	res, err := l.c.Exec(ctx, fmt.Sprintf("DELETE FROM %v WHERE lock_name = ? AND owner = ?", l.table),
+		l.name, l.opts.Owner)
+	if err != nil {
+		return err
+	}
+	if res.RowCounts().Total() == 0 {
+		return l.errLock(ErrMigrationLockLost, errMsgMigrationLockLost)
+	}
+	return nil
+}
+
+func (l *MigrationLock) errLock(number int, message string) *SnowflakeError {
+	return &SnowflakeError{
+		Number:      number,
+		Message:     message,
+		MessageArgs: []interface{}{l.name, l.table, l.opts.Owner},
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// lockTable emulates the lock table of MigrationLock with the owner of the lock.
+type lockTable struct {
+	mu    sync.Mutex
+	owner string
+}
+
+// client returns a Client running the statements of MigrationLock on the lock table.
+func (lt *lockTable) client() *Client {
+	return &Client{sc: &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{
+			FuncPostQuery: func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte, _ time.Duration, _ *uuid.UUID) (*execResponse, error) {
+				var req execRequest
+				if err := json.Unmarshal(body, &req); err != nil {
+					return nil, err
+				}
+				lt.mu.Lock()
+				defer lt.mu.Unlock()
+				binding := func(idx string) string { return req.Bindings[idx].Value.(string) }
+				n := "0"
+				data := execResponseData{QueryID: "qid", StatementTypeID: statementTypeIDMerge}
+				switch {
+				case strings.HasPrefix(req.SQLText, "CREATE"):
+					status := "LOCKS already exists, statement succeeded."
+					data = execResponseData{QueryID: "qid", QueryResultFormat: jsonFormat, StatementTypeID: 0x6000,
+						RowType: []execResponseRowType{{Name: "status", Type: "text"}}, RowSet: [][]*string{{&status}}, Total: 1}
+					return &execResponse{Data: data, Code: "0", Success: true}, nil
+				case strings.HasPrefix(req.SQLText, "MERGE"):
+					if lt.owner == "" || lt.owner == binding("2") {
+						lt.owner, n = binding("3"), "1"
+					}
+				case strings.HasPrefix(req.SQLText, "UPDATE"):
+					if lt.owner == binding("3") {
+						n = "1"
+					}
+				case strings.HasPrefix(req.SQLText, "DELETE"):
+					if lt.owner == binding("2") {
+						lt.owner, n = "", "1"
+					}
+				}
+				data.RowType = []execResponseRowType{{Name: "number of rows inserted", Type: "fixed"}}
+				data.RowSet = [][]*string{{&n}}
+				return &execResponse{Data: data, Code: "0", Success: true}, nil
+			},
+		},
+	}}
+}
+
+func TestMigrationLock(t *testing.T) {
+	lt := &lockTable{}
+	ctx := context.Background()
+	a := lt.client().NewMigrationLock("locks", "db", nil)
+	b := lt.client().NewMigrationLock("locks", "db", &MigrationLockOptions{Owner: "b", PollInterval: time.Millisecond})
+	if a.Owner() == "" || b.Owner() != "b" {
+		t.Fatalf("unexpected owners: %v, %v", a.Owner(), b.Owner())
+	}
+	if err := a.Lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Lock(ctx); err != nil {
+		t.Fatalf("the owner should acquire the lock again. err: %v", err)
+	}
+	var se *SnowflakeError
+	if err := b.Lock(ctx); !errors.As(err, &se) || se.Number != ErrMigrationLocked {
+		t.Fatalf("the lock should be held by another owner. err: %v", err)
+	}
+	if err := b.Refresh(ctx); !errors.As(err, &se) || se.Number != ErrMigrationLockLost {
+		t.Fatalf("the lock should not be refreshed by another owner. err: %v", err)
+	}
+	if err := a.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	b.opts.Timeout = time.Minute
+	locked := make(chan error)
+	go func() {
+		locked <- b.Lock(ctx)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	if err := a.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-locked; err != nil {
+		t.Fatalf("the lock should be acquired once it is released. err: %v", err)
+	}
+	if err := a.Unlock(ctx); !errors.As(err, &se) || se.Number != ErrMigrationLockLost {
+		t.Fatalf("the lock should no longer be held. err: %v", err)
+	}
+	if err := b.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExecDDL(t *testing.T) {
+	res, err := (&lockTable{}).client().ExecDDL(context.Background(), "CREATE TABLE IF NOT EXISTS locks (i INT)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.QueryID != "qid" || res.Status != "LOCKS already exists, statement succeeded." {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+}
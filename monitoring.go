@@ -0,0 +1,153 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const monitoringQueryPath = "/monitoring/queries/%s"
+
+// statuses of a query reported by the monitoring endpoint
+const (
+	queryStatusRunning                  = "RUNNING"
+	queryStatusResumingWarehouse        = "RESUMING_WAREHOUSE"
+	queryStatusQueued                   = "QUEUED"
+	queryStatusQueuedRepairingWarehouse = "QUEUED_REPARING_WAREHOUSE"
+	queryStatusBlocked                  = "BLOCKED"
+	queryStatusNoData                   = "NO_DATA"
+	queryStatusSuccess                  = "SUCCESS"
+)
+
+type monitoringQueryStats struct {
+	QueuedProvisioningTime int64 `json:"queuedProvisioningTime"` // milliseconds
+	QueuedRepairTime       int64 `json:"queuedRepairTime"`       // milliseconds
+	QueuedOverloadTime     int64 `json:"queuedOverloadTime"`     // milliseconds
+	ScanBytes              int64 `json:"scanBytes"`
+	ProducedRows           int64 `json:"producedRows"`
+	ScanAssignedPartitions int64 `json:"scanAssignedPartitions"`
+	ScanOriginalPartitions int64 `json:"scanOriginalPartitions"`
+}
+
+type monitoringQuery struct {
+	ID               string               `json:"id"`
+	Status           string               `json:"status"`
+	ErrorCode        string               `json:"errorCode"`
+	ErrorMessage     string               `json:"errorMessage"`
+	SQLText          string               `json:"sqlText"`
+	StartTime        int64                `json:"startTime"` // epoch milliseconds
+	EndTime          int64                `json:"endTime"`   // epoch milliseconds
+	TotalDuration    int64                `json:"totalDuration"`
+	WarehouseName    string               `json:"warehouseName"`
+	WarehouseSize    string               `json:"warehouseExternalSize"` // e.g., X-Small
+	QueryLoadPercent int64                `json:"queryLoadPercent"`
+	Stats            monitoringQueryStats `json:"stats"`
+}
+
+type monitoringResponseData struct {
+	Queries []monitoringQuery `json:"queries"`
+}
+
+type monitoringResponse struct {
+	Data    monitoringResponseData `json:"data"`
+	Message string                 `json:"message"`
+	Code    string                 `json:"code"`
+	Success bool                   `json:"success"`
+}
+
+// isQueryRunning returns true if the query with the status has not completed yet. NO_DATA is returned
+// until the query is registered on the monitoring endpoint.
+func isQueryRunning(status string) bool {
+	switch status {
+	case queryStatusRunning, queryStatusResumingWarehouse, queryStatusQueued,
+		queryStatusQueuedRepairingWarehouse, queryStatusBlocked, queryStatusNoData:
+		return true
+	}
+	return false
+}
+
+// err returns the error of the query if it didn't succeed.
+func (mq *monitoringQuery) err() error {
+	if mq.Status == queryStatusSuccess {
+		return nil
+	}
+	code, err := strconv.Atoi(mq.ErrorCode)
+	if err != nil {
+		code = -1
+	}
+	message := mq.ErrorMessage
+	if message == "" {
+		message = fmt.Sprintf("query finished with status %v", mq.Status)
+	}
+	return &SnowflakeError{
+		Number:  code,
+		Message: message,
+		QueryID: mq.ID,
+	}
+}
+
+// getQueryStatus gets the status of the query from the monitoring endpoint.
+func (sc *snowflakeConn) getQueryStatus(ctx context.Context, qid string) (*monitoringQuery, error) {
+	headers := make(map[string]string)
+	headers["Content-Type"] = headerContentTypeApplicationJSON
+	headers["accept"] = headerContentTypeApplicationJSON
+	headers["User-Agent"] = sc.rest.getUserAgent()
+	if name := sc.CurrentServiceName(); name != "" {
+		headers["X-Snowflake-Service"] = name
+	}
+	if sc.rest.Token != "" {
+		headers[headerAuthorizationKey] = fmt.Sprintf(headerSnowflakeToken, sc.rest.Token)
+	}
+	param := make(url.Values)
+	param.Add(requestIDKey, uuid.New().String())
+	param.Add("clientStartTime", strconv.FormatInt(time.Now().Unix(), 10))
+	param.Add(requestGUIDKey, uuid.New().String())
+	fullURL := sc.rest.getFullURL(fmt.Sprintf(monitoringQueryPath, qid), &param)
+	if err := sc.rest.waitRateLimit(ctx); err != nil {
+		return nil, err
+	}
+	res, err := sc.rest.FuncGet(ctx, sc.rest, fullURL, headers, sc.rest.RequestTimeout)
+	if err != nil {
+		glog.V(1).Infof("failed to get response. err: %v", err)
+		glog.Flush()
+		return nil, err
+	}
+	defer drainAndClose(res.Body)
+	if res.StatusCode != http.StatusOK {
+		return nil, &SnowflakeError{
+			Number:      ErrFailedToGetQueryStatus,
+			SQLState:    SQLStateConnectionFailure,
+			Message:     errMsgFailedToGetQueryStatus,
+			MessageArgs: []interface{}{res.StatusCode, fullURL},
+			QueryID:     qid,
+		}
+	}
+	var respd monitoringResponse
+	if err = decodeJSONBody(res.Body, &respd); err != nil {
+		glog.V(1).Infof("failed to decode JSON. err: %v", err)
+		glog.Flush()
+		return nil, err
+	}
+	if !respd.Success {
+		code, err := strconv.Atoi(respd.Code)
+		if err != nil {
+			code = -1
+		}
+		return nil, &SnowflakeError{
+			Number:  code,
+			Message: respd.Message,
+			QueryID: qid,
+		}
+	}
+	if len(respd.Data.Queries) == 0 {
+		return &monitoringQuery{ID: qid, Status: queryStatusNoData}, nil
+	}
+	return &respd.Data.Queries[0], nil
+}
@@ -369,7 +369,7 @@ func checkOCSPCacheServer(
 			err:  err,
 		}
 	}
-	defer res.Body.Close()
+	defer drainAndClose(res.Body)
 	glog.V(2).Infof("StatusCode from OCSP Cache Server: %v\n", res.StatusCode)
 	if res.StatusCode != http.StatusOK {
 		return nil, &ocspStatus{
@@ -423,7 +423,7 @@ func retryOCSP(
 			err:  err,
 		}
 	}
-	defer res.Body.Close()
+	defer drainAndClose(res.Body)
 	glog.V(2).Infof("StatusCode from OCSP Server: %v\n", res.StatusCode)
 	if res.StatusCode != http.StatusOK {
 		return ocspRes, ocspResBytes, &ocspStatus{
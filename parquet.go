@@ -0,0 +1,455 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+// This file writes the Parquet files of the ingestion, whose columns are flat and optional. Every column chunk of a
+// row group has a single data page of PLAIN values, compressed with gzip, and the metadata is encoded with the
+// compact protocol of Thrift as parquet.thrift of the Parquet format defines it.
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// parquetMagic starts and ends a Parquet file.
+const parquetMagic = "PAR1"
+
+// defaultParquetRowGroupRows is the number of rows of a row group, whose values are buffered until it is written.
+const defaultParquetRowGroupRows = 100000
+
+// the physical types
+const (
+	parquetBoolean           int32 = 0
+	parquetInt32             int32 = 1
+	parquetInt64             int32 = 2
+	parquetFloat             int32 = 4
+	parquetDouble            int32 = 5
+	parquetByteArray         int32 = 6
+	parquetFixedLenByteArray int32 = 7
+)
+
+// the converted types
+const (
+	parquetNoConvertedType int32 = -1
+	parquetUTF8            int32 = 0
+	parquetDecimal         int32 = 5
+	parquetDate            int32 = 6
+	parquetTimestampMillis int32 = 9
+	parquetTimestampMicros int32 = 10
+)
+
+// the time units of the TIMESTAMP logical type, which are the field IDs of the TimeUnit union
+const (
+	parquetMillis = 1
+	parquetMicros = 2
+	parquetNanos  = 3
+)
+
+const (
+	parquetEncodingPlain = 0
+	parquetEncodingRLE   = 3
+	parquetCodecGzip     = 2
+	parquetDataPage      = 0
+	parquetOptional      = 1
+)
+
+// parquetColumn is an optional column of a Parquet file, and buffers the values of the current row group.
+type parquetColumn struct {
+	name       string
+	typ        int32 // the physical type
+	converted  int32 // the converted type, or parquetNoConvertedType
+	typeLength int32 // the length of a FIXED_LEN_BYTE_ARRAY
+	scale      int32
+	precision  int32
+	timeUnit   int // the unit of a TIMESTAMP, or 0
+
+	defLevels []bool // false for a NULL
+	values    bytes.Buffer
+	bools     []bool
+}
+
+// sameType returns true if the columns have the same name and type.
+func (c *parquetColumn) sameType(other *parquetColumn) bool {
+	return c.name == other.name && c.typ == other.typ && c.converted == other.converted &&
+		c.typeLength == other.typeLength && c.scale == other.scale && c.precision == other.precision &&
+		c.timeUnit == other.timeUnit
+}
+
+func (c *parquetColumn) appendNull() {
+	c.defLevels = append(c.defLevels, false)
+}
+
+func (c *parquetColumn) appendBool(v bool) {
+	c.defLevels = append(c.defLevels, true)
+	c.bools = append(c.bools, v)
+}
+
+func (c *parquetColumn) appendInt32(v int32) {
+	c.defLevels = append(c.defLevels, true)
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], uint32(v))
+	c.values.Write(b[:])
+}
+
+func (c *parquetColumn) appendInt64(v int64) {
+	c.defLevels = append(c.defLevels, true)
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], uint64(v))
+	c.values.Write(b[:])
+}
+
+func (c *parquetColumn) appendFloat(v float32) {
+	c.defLevels = append(c.defLevels, true)
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], math.Float32bits(v))
+	c.values.Write(b[:])
+}
+
+func (c *parquetColumn) appendDouble(v float64) {
+	c.defLevels = append(c.defLevels, true)
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], math.Float64bits(v))
+	c.values.Write(b[:])
+}
+
+// appendBytes appends a BYTE_ARRAY value, or a FIXED_LEN_BYTE_ARRAY one of typeLength bytes.
+func (c *parquetColumn) appendBytes(v []byte) {
+	c.defLevels = append(c.defLevels, true)
+	if c.typ == parquetByteArray {
+		var b [4]byte
+		binary.LittleEndian.PutUint32(b[:], uint32(len(v)))
+		c.values.Write(b[:])
+	}
+	c.values.Write(v)
+}
+
+// pageData returns the definition levels and the values of the row group, and resets them.
+func (c *parquetColumn) pageData() []byte {
+	levels := encodeParquetLevels(c.defLevels)
+	var data bytes.Buffer
+	var n [4]byte
+	binary.LittleEndian.PutUint32(n[:], uint32(len(levels)))
+	data.Write(n[:])
+	data.Write(levels)
+	if c.typ == parquetBoolean {
+		packed := make([]byte, (len(c.bools)+7)/8)
+		for i, v := range c.bools {
+			if v {
+				packed[i/8] |= 1 << uint(i%8)
+			}
+		}
+		data.Write(packed)
+	} else {
+		data.Write(c.values.Bytes())
+	}
+	c.defLevels, c.bools = c.defLevels[:0], c.bools[:0]
+	c.values.Reset()
+	return data.Bytes()
+}
+
+// encodeParquetLevels encodes the definition levels, 0 or 1, with the runs of the RLE encoding.
+func encodeParquetLevels(levels []bool) []byte {
+	var b []byte
+	for i := 0; i < len(levels); {
+		j := i + 1
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+		b = appendUvarint(b, uint64(j-i)<<1)
+		if levels[i] {
+			b = append(b, 1)
+		} else {
+			b = append(b, 0)
+		}
+		i = j
+	}
+	return b
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+// parquetChunk is the metadata of a column chunk.
+type parquetChunk struct {
+	dataPageOffset   int64
+	uncompressedSize int64
+	compressedSize   int64
+	numValues        int64
+}
+
+type parquetRowGroup struct {
+	chunks        []parquetChunk
+	totalByteSize int64
+	numRows       int64
+}
+
+// parquetWriter writes the rows of the columns to a Parquet file. The values of a row are appended to the
+// columns before endRow is called.
+type parquetWriter struct {
+	w            io.Writer
+	offset       int64
+	columns      []*parquetColumn
+	rowGroupRows int
+	rows         int // rows of the current row group
+	rowGroups    []parquetRowGroup
+}
+
+func newParquetWriter(w io.Writer, columns []*parquetColumn) (*parquetWriter, error) {
+	pw := &parquetWriter{w: w, columns: columns, rowGroupRows: defaultParquetRowGroupRows}
+	if err := pw.write([]byte(parquetMagic)); err != nil {
+		return nil, err
+	}
+	return pw, nil
+}
+
+func (pw *parquetWriter) write(b []byte) error {
+	n, err := pw.w.Write(b)
+	pw.offset += int64(n)
+	return err
+}
+
+// endRow ends the current row, and writes the row group if it has rowGroupRows rows.
+func (pw *parquetWriter) endRow() error {
+	if pw.rows++; pw.rows >= pw.rowGroupRows {
+		return pw.writeRowGroup()
+	}
+	return nil
+}
+
+func (pw *parquetWriter) writeRowGroup() error {
+	rg := parquetRowGroup{numRows: int64(pw.rows)}
+	for _, c := range pw.columns {
+		data := c.pageData()
+		var compressed bytes.Buffer
+		zw := gzip.NewWriter(&compressed)
+		if _, err := zw.Write(data); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		t := &thriftWriter{}
+		t.i32(1, parquetDataPage)
+		t.i32(2, int32(len(data)))
+		t.i32(3, int32(compressed.Len()))
+		t.beginStruct(5) // DataPageHeader
+		t.i32(1, int32(pw.rows))
+		t.i32(2, parquetEncodingPlain)
+		t.i32(3, parquetEncodingRLE)
+		t.i32(4, parquetEncodingRLE)
+		t.endStruct()
+		header := t.end()
+
+		chunk := parquetChunk{
+			dataPageOffset:   pw.offset,
+			uncompressedSize: int64(len(header) + len(data)),
+			compressedSize:   int64(len(header) + compressed.Len()),
+			numValues:        int64(pw.rows),
+		}
+		if err := pw.write(header); err != nil {
+			return err
+		}
+		if err := pw.write(compressed.Bytes()); err != nil {
+			return err
+		}
+		rg.chunks = append(rg.chunks, chunk)
+		rg.totalByteSize += chunk.uncompressedSize
+	}
+	pw.rowGroups = append(pw.rowGroups, rg)
+	pw.rows = 0
+	return nil
+}
+
+// close writes the last row group and the metadata of the file.
+func (pw *parquetWriter) close() error {
+	if pw.rows > 0 {
+		if err := pw.writeRowGroup(); err != nil {
+			return err
+		}
+	}
+	var numRows int64
+	for _, rg := range pw.rowGroups {
+		numRows += rg.numRows
+	}
+	t := &thriftWriter{}
+	t.i32(1, 1) // version
+	t.listHeader(2, thriftStruct, len(pw.columns)+1)
+	t.beginElem()
+	t.binary(4, []byte("schema"))
+	t.i32(5, int32(len(pw.columns)))
+	t.endElem()
+	for _, c := range pw.columns {
+		t.beginElem()
+		t.i32(1, c.typ)
+		if c.typ == parquetFixedLenByteArray {
+			t.i32(2, c.typeLength)
+		}
+		t.i32(3, parquetOptional)
+		t.binary(4, []byte(c.name))
+		if c.converted != parquetNoConvertedType {
+			t.i32(6, c.converted)
+		}
+		if c.converted == parquetDecimal {
+			t.i32(7, c.scale)
+			t.i32(8, c.precision)
+		}
+		if c.timeUnit != 0 {
+			t.beginStruct(10) // LogicalType
+			t.beginStruct(8)  // TIMESTAMP
+			t.bool(1, true)   // isAdjustedToUTC
+			t.beginStruct(2)  // unit
+			t.beginStruct(int16(c.timeUnit))
+			t.endStruct()
+			t.endStruct()
+			t.endStruct()
+			t.endStruct()
+		}
+		t.endElem()
+	}
+	t.i64(3, numRows)
+	t.listHeader(4, thriftStruct, len(pw.rowGroups))
+	for _, rg := range pw.rowGroups {
+		t.beginElem()
+		t.listHeader(1, thriftStruct, len(rg.chunks))
+		for i, chunk := range rg.chunks {
+			c := pw.columns[i]
+			t.beginElem()
+			t.i64(2, chunk.dataPageOffset)
+			t.beginStruct(3) // ColumnMetaData
+			t.i32(1, c.typ)
+			t.listHeader(2, thriftI32, 2)
+			t.elemI32(parquetEncodingPlain)
+			t.elemI32(parquetEncodingRLE)
+			t.listHeader(3, thriftBinary, 1)
+			t.elemBinary([]byte(c.name))
+			t.i32(4, parquetCodecGzip)
+			t.i64(5, chunk.numValues)
+			t.i64(6, chunk.uncompressedSize)
+			t.i64(7, chunk.compressedSize)
+			t.i64(9, chunk.dataPageOffset)
+			t.endStruct()
+			t.endElem()
+		}
+		t.i64(2, rg.totalByteSize)
+		t.i64(3, rg.numRows)
+		t.endElem()
+	}
+	t.binary(6, []byte("gosnowflake"))
+	meta := t.end()
+	var n [4]byte
+	binary.LittleEndian.PutUint32(n[:], uint32(len(meta)))
+	for _, b := range [][]byte{meta, n[:], []byte(parquetMagic)} {
+		if err := pw.write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// the types of the compact protocol of Thrift
+const (
+	thriftBooleanTrue  = 1
+	thriftBooleanFalse = 2
+	thriftI32          = 5
+	thriftI64          = 6
+	thriftBinary       = 8
+	thriftList         = 9
+	thriftStruct       = 12
+)
+
+// thriftWriter encodes a struct with the compact protocol of Thrift. The fields of a struct are written in the
+// order of their IDs.
+type thriftWriter struct {
+	buf     bytes.Buffer
+	lastID  int16
+	lastIDs []int16 // of the enclosing structs
+}
+
+func (t *thriftWriter) fieldHeader(id int16, typ byte) {
+	if delta := id - t.lastID; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.varint(int64(id))
+	}
+	t.lastID = id
+}
+
+// varint writes the zigzag varint of an integer.
+func (t *thriftWriter) varint(v int64) {
+	t.buf.Write(appendUvarint(nil, uint64(v<<1^v>>63)))
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.fieldHeader(id, thriftI32)
+	t.varint(int64(v))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.fieldHeader(id, thriftI64)
+	t.varint(v)
+}
+
+func (t *thriftWriter) bool(id int16, v bool) {
+	if v {
+		t.fieldHeader(id, thriftBooleanTrue)
+	} else {
+		t.fieldHeader(id, thriftBooleanFalse)
+	}
+}
+
+func (t *thriftWriter) binary(id int16, v []byte) {
+	t.fieldHeader(id, thriftBinary)
+	t.elemBinary(v)
+}
+
+func (t *thriftWriter) beginStruct(id int16) {
+	t.fieldHeader(id, thriftStruct)
+	t.beginElem()
+}
+
+func (t *thriftWriter) endStruct() {
+	t.endElem()
+}
+
+func (t *thriftWriter) listHeader(id int16, elemType byte, size int) {
+	t.fieldHeader(id, thriftList)
+	if size < 15 {
+		t.buf.WriteByte(byte(size)<<4 | elemType)
+	} else {
+		t.buf.WriteByte(0xf0 | elemType)
+		t.buf.Write(appendUvarint(nil, uint64(size)))
+	}
+}
+
+// beginElem begins a struct that is an element of a list.
+func (t *thriftWriter) beginElem() {
+	t.lastIDs = append(t.lastIDs, t.lastID)
+	t.lastID = 0
+}
+
+func (t *thriftWriter) endElem() {
+	t.buf.WriteByte(0) // stop
+	t.lastID = t.lastIDs[len(t.lastIDs)-1]
+	t.lastIDs = t.lastIDs[:len(t.lastIDs)-1]
+}
+
+func (t *thriftWriter) elemI32(v int32) {
+	t.varint(int64(v))
+}
+
+func (t *thriftWriter) elemBinary(v []byte) {
+	t.buf.Write(appendUvarint(nil, uint64(len(v))))
+	t.buf.Write(v)
+}
+
+// end ends the top-level struct and returns its encoding.
+func (t *thriftWriter) end() []byte {
+	t.buf.WriteByte(0)
+	return t.buf.Bytes()
+}
@@ -0,0 +1,266 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// thriftReader decodes the structs of the compact protocol of Thrift into the maps of their fields by their IDs.
+// The integers are int64, the binaries []byte, the lists []interface{} and the structs map[int16]interface{}.
+type thriftReader struct {
+	b   []byte
+	pos int
+}
+
+func (r *thriftReader) byte() byte {
+	b := r.b[r.pos]
+	r.pos++
+	return b
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.b[r.pos:])
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) varint() int64 {
+	v := r.uvarint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *thriftReader) value(typ byte) interface{} {
+	switch typ {
+	case thriftBooleanTrue:
+		return true
+	case thriftBooleanFalse:
+		return false
+	case thriftI32, thriftI64:
+		return r.varint()
+	case thriftBinary:
+		n := int(r.uvarint())
+		r.pos += n
+		return r.b[r.pos-n : r.pos]
+	case thriftList:
+		header := r.byte()
+		size := int(header >> 4)
+		if size == 15 {
+			size = int(r.uvarint())
+		}
+		list := make([]interface{}, size)
+		for i := range list {
+			if header&0x0f == thriftBooleanTrue {
+				list[i] = r.byte() == thriftBooleanTrue
+			} else {
+				list[i] = r.value(header & 0x0f)
+			}
+		}
+		return list
+	case thriftStruct:
+		return r.readStruct()
+	}
+	panic(fmt.Sprintf("unexpected thrift type %v", typ))
+}
+
+func (r *thriftReader) readStruct() map[int16]interface{} {
+	fields := make(map[int16]interface{})
+	var id int16
+	for {
+		header := r.byte()
+		if header == 0 {
+			return fields
+		}
+		if delta := header >> 4; delta != 0 {
+			id += int16(delta)
+		} else {
+			id = int16(r.varint())
+		}
+		fields[id] = r.value(header & 0x0f)
+	}
+}
+
+// parquetTestFile is a Parquet file read by readParquetFile.
+type parquetTestFile struct {
+	schema  []map[int16]interface{} // the schema elements of the columns
+	columns map[string][]interface{}
+	numRows int64
+	groups  int
+}
+
+// readParquetFile reads the Parquet file written by parquetWriter. The values are nil for NULL, or bool, int32,
+// int64, float32, float64 or []byte.
+func readParquetFile(t *testing.T, b []byte) *parquetTestFile {
+	if len(b) < 12 || string(b[:4]) != parquetMagic || string(b[len(b)-4:]) != parquetMagic {
+		t.Fatal("the file should start and end with PAR1")
+	}
+	n := int(binary.LittleEndian.Uint32(b[len(b)-8:]))
+	meta := (&thriftReader{b: b[len(b)-8-n : len(b)-8]}).readStruct()
+	f := &parquetTestFile{columns: make(map[string][]interface{}), numRows: meta[3].(int64)}
+	schema := meta[2].([]interface{})
+	if root := schema[0].(map[int16]interface{}); root[5].(int64) != int64(len(schema)-1) {
+		t.Fatalf("unexpected root of the schema: %v", root)
+	}
+	for _, e := range schema[1:] {
+		f.schema = append(f.schema, e.(map[int16]interface{}))
+	}
+	var rows int64
+	for _, g := range meta[4].([]interface{}) {
+		group := g.(map[int16]interface{})
+		rows += group[3].(int64)
+		f.groups++
+		for i, c := range group[1].([]interface{}) {
+			element := f.schema[i]
+			name := string(element[4].([]byte))
+			colMeta := c.(map[int16]interface{})[3].(map[int16]interface{})
+			if colMeta[4].(int64) != parquetCodecGzip || colMeta[5].(int64) != group[3].(int64) {
+				t.Fatalf("unexpected metadata of the column chunk of %v: %v", name, colMeta)
+			}
+			r := &thriftReader{b: b, pos: int(colMeta[9].(int64))}
+			header := r.readStruct()
+			if int64(r.pos)+int64(header[3].(int64)) != colMeta[9].(int64)+colMeta[7].(int64) {
+				t.Fatalf("the compressed size of the column chunk of %v should include the page", name)
+			}
+			zr, err := gzip.NewReader(bytes.NewReader(b[r.pos : r.pos+int(header[3].(int64))]))
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := ioutil.ReadAll(zr)
+			if err != nil || int64(len(data)) != header[2].(int64) {
+				t.Fatalf("failed to decompress the page of %v. err: %v", name, err)
+			}
+			numValues := int(header[5].(map[int16]interface{})[1].(int64))
+			f.columns[name] = append(f.columns[name], readParquetPage(t, data, numValues, element)...)
+		}
+	}
+	if rows != f.numRows {
+		t.Fatalf("the rows of the row groups should be those of the file. %v != %v", rows, f.numRows)
+	}
+	return f
+}
+
+// readParquetPage decodes the RLE definition levels and the PLAIN values of a data page.
+func readParquetPage(t *testing.T, data []byte, numValues int, element map[int16]interface{}) []interface{} {
+	n := int(binary.LittleEndian.Uint32(data))
+	levels := &thriftReader{b: data[4 : 4+n]}
+	var defined []bool
+	for levels.pos < len(levels.b) {
+		header := levels.uvarint()
+		if header&1 != 0 {
+			t.Fatal("the definition levels should be RLE runs")
+		}
+		v := levels.byte() == 1
+		for i := 0; i < int(header>>1); i++ {
+			defined = append(defined, v)
+		}
+	}
+	if len(defined) != numValues {
+		t.Fatalf("%v definition levels for %v values", len(defined), numValues)
+	}
+	values := data[4+n:]
+	var bit int
+	ret := make([]interface{}, numValues)
+	for i, ok := range defined {
+		if !ok {
+			continue
+		}
+		switch element[1].(int64) {
+		case int64(parquetBoolean):
+			ret[i] = values[bit/8]&(1<<uint(bit%8)) != 0
+			bit++
+		case int64(parquetInt32):
+			ret[i] = int32(binary.LittleEndian.Uint32(values))
+			values = values[4:]
+		case int64(parquetInt64):
+			ret[i] = int64(binary.LittleEndian.Uint64(values))
+			values = values[8:]
+		case int64(parquetFloat):
+			ret[i] = math.Float32frombits(binary.LittleEndian.Uint32(values))
+			values = values[4:]
+		case int64(parquetDouble):
+			ret[i] = math.Float64frombits(binary.LittleEndian.Uint64(values))
+			values = values[8:]
+		case int64(parquetByteArray):
+			size := int(binary.LittleEndian.Uint32(values))
+			ret[i] = values[4 : 4+size]
+			values = values[4+size:]
+		case int64(parquetFixedLenByteArray):
+			size := int(element[2].(int64))
+			ret[i] = values[:size]
+			values = values[size:]
+		}
+	}
+	return ret
+}
+
+func TestParquetWriter(t *testing.T) {
+	columns := []*parquetColumn{
+		{name: "b", typ: parquetBoolean, converted: parquetNoConvertedType},
+		{name: "i", typ: parquetInt32, converted: parquetNoConvertedType},
+		{name: "l", typ: parquetInt64, converted: parquetTimestampMicros, timeUnit: parquetMicros},
+		{name: "f", typ: parquetFloat, converted: parquetNoConvertedType},
+		{name: "d", typ: parquetDouble, converted: parquetNoConvertedType},
+		{name: "s", typ: parquetByteArray, converted: parquetUTF8},
+		{name: "x", typ: parquetFixedLenByteArray, converted: parquetDecimal, typeLength: 2, scale: 1, precision: 4},
+	}
+	var buf bytes.Buffer
+	w, err := newParquetWriter(&buf, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.rowGroupRows = 2
+	for i := 0; i < 5; i++ {
+		if i == 1 {
+			for _, c := range columns {
+				c.appendNull()
+			}
+		} else {
+			columns[0].appendBool(i%2 == 0)
+			columns[1].appendInt32(int32(-i))
+			columns[2].appendInt64(int64(i) << 40)
+			columns[3].appendFloat(float32(i) / 2)
+			columns[4].appendDouble(float64(i) / 4)
+			columns[5].appendBytes([]byte(fmt.Sprint("s", i)))
+			columns[6].appendBytes([]byte{0, byte(i)})
+		}
+		if err = w.endRow(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = w.close(); err != nil {
+		t.Fatal(err)
+	}
+	f := readParquetFile(t, buf.Bytes())
+	if f.numRows != 5 || f.groups != 3 {
+		t.Fatalf("5 rows should be written in 3 row groups. rows: %v, groups: %v", f.numRows, f.groups)
+	}
+	expected := map[string][]interface{}{
+		"b": {true, nil, true, false, true},
+		"i": {int32(0), nil, int32(-2), int32(-3), int32(-4)},
+		"l": {int64(0), nil, int64(2) << 40, int64(3) << 40, int64(4) << 40},
+		"f": {float32(0), nil, float32(1), float32(1.5), float32(2)},
+		"d": {float64(0), nil, 0.5, 0.75, float64(1)},
+		"s": {[]byte("s0"), nil, []byte("s2"), []byte("s3"), []byte("s4")},
+		"x": {[]byte{0, 0}, nil, []byte{0, 2}, []byte{0, 3}, []byte{0, 4}},
+	}
+	for name, values := range expected {
+		if !reflect.DeepEqual(f.columns[name], values) {
+			t.Errorf("unexpected values of %v: %v", name, f.columns[name])
+		}
+	}
+	if x := f.schema[6]; x[2].(int64) != 2 || x[6].(int64) != int64(parquetDecimal) || x[7].(int64) != 1 ||
+		x[8].(int64) != 4 {
+		t.Fatalf("unexpected schema of the decimal: %v", x)
+	}
+	unit := f.schema[2][10].(map[int16]interface{})[8].(map[int16]interface{})
+	if unit[1] != true || unit[2].(map[int16]interface{})[parquetMicros] == nil {
+		t.Fatalf("unexpected logical type of the timestamp: %v", unit)
+	}
+}
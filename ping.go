@@ -0,0 +1,69 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+)
+
+// PingMode is how Ping checks the connection.
+type PingMode int
+
+const (
+	// PingModeQuery runs SELECT 1, which checks the warehouse as well, but resumes it if suspended and may
+	// consume credits. This is the default.
+	PingModeQuery PingMode = iota
+	// PingModeHeartbeat sends a heartbeat of the session, which checks the session without a warehouse.
+	PingModeHeartbeat
+	// PingModeNone sends nothing, so that Ping only fails if the connection is closed.
+	PingModeNone
+)
+
+var pingModeNames = map[PingMode]string{
+	PingModeQuery:     "query",
+	PingModeHeartbeat: "heartbeat",
+	PingModeNone:      "none",
+}
+
+func (m PingMode) String() string {
+	if name, ok := pingModeNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("PingMode(%d)", int(m))
+}
+
+// parsePingMode parses the pingMode parameter of a DSN.
+func parsePingMode(value string) (PingMode, error) {
+	for mode, name := range pingModeNames {
+		if strings.EqualFold(value, name) {
+			return mode, nil
+		}
+	}
+	return PingModeQuery, fmt.Errorf("invalid ping mode: %v", value)
+}
+
+// Ping checks the connection in the PingMode of the Config, within the PingTimeout of the Config if set.
+func (sc *snowflakeConn) Ping(ctx context.Context) error {
+	glog.V(2).Infoln("Ping")
+	if sc.rest == nil {
+		return driver.ErrBadConn
+	}
+	if sc.cfg.PingTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, sc.cfg.PingTimeout)
+		defer cancel()
+	}
+	switch sc.cfg.PingMode {
+	case PingModeNone:
+		return nil
+	case PingModeHeartbeat:
+		return heartbeatSession(ctx, sc.rest)
+	default:
+		// TODO: handle noResult and isInternal
+		_, err := sc.exec(withoutIdempotencyKey(ctx), "SELECT 1", false, false, []driver.NamedValue{})
+		return err
+	}
+}
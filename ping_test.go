@@ -0,0 +1,58 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestPingMode(t *testing.T) {
+	var queries, heartbeats int
+	var deadline bool
+	heartbeatBody := `{"code":null,"success":true}`
+	sc := &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{
+			FuncPostQuery: func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, _ []byte, _ time.Duration, _ *uuid.UUID) (*execResponse, error) {
+				queries++
+				return &execResponse{Data: execResponseData{QueryID: "q"}, Code: "0", Success: true}, nil
+			},
+			FuncPost: func(ctx context.Context, _ *snowflakeRestful, u *url.URL, _ map[string]string, _ []byte, _ time.Duration, _ bool) (*http.Response, error) {
+				if u.Path != heartBeatPath {
+					t.Errorf("unexpected path: %v", u.Path)
+				}
+				heartbeats++
+				_, deadline = ctx.Deadline()
+				return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(heartbeatBody))}, nil
+			},
+		},
+	}
+	ctx := context.Background()
+	if err := sc.Ping(ctx); err != nil || queries != 1 || heartbeats != 0 {
+		t.Fatalf("should ping by a query by default. queries: %v, heartbeats: %v, err: %v", queries, heartbeats, err)
+	}
+	sc.cfg.PingMode = PingModeHeartbeat
+	if err := sc.Ping(ctx); err != nil || queries != 1 || heartbeats != 1 || deadline {
+		t.Fatalf("should ping by a heartbeat. queries: %v, heartbeats: %v, err: %v", queries, heartbeats, err)
+	}
+	sc.cfg.PingTimeout = time.Second
+	if err := sc.Ping(ctx); err != nil || !deadline {
+		t.Fatalf("the ping should have the deadline of PingTimeout. err: %v", err)
+	}
+	heartbeatBody = `{"code":"390111","message":"Session no longer exists.","success":false}`
+	if err := sc.Ping(ctx); err == nil || err.(*SnowflakeError).Number != 390111 {
+		t.Fatalf("should fail if the session no longer exists. err: %v", err)
+	}
+	sc.cfg.PingMode = PingModeNone
+	if err := sc.Ping(ctx); err != nil || queries != 1 || heartbeats != 3 {
+		t.Fatalf("should send nothing. queries: %v, heartbeats: %v, err: %v", queries, heartbeats, err)
+	}
+}
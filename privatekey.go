@@ -0,0 +1,92 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+)
+
+// PrivateKeySource returns the PEM encoded private key for the key pair authentication, e.g., read from
+// a file or fetched from AWS Secrets Manager or KMS by the application.
+type PrivateKeySource func(ctx context.Context) (io.Reader, error)
+
+// NewPrivateKeyProvider returns a CredentialsProvider that loads the private key from source at every login,
+// so that the key doesn't have to be stored on disk and a rotated key is picked up by new connections.
+func NewPrivateKeyProvider(source PrivateKeySource) CredentialsProvider {
+	return &privateKeyProvider{source: source}
+}
+
+type privateKeyProvider struct {
+	source PrivateKeySource
+}
+
+func (p *privateKeyProvider) Credentials(ctx context.Context) (*Credentials, error) {
+	r, err := p.source(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if c, ok := r.(io.Closer); ok {
+		defer c.Close()
+	}
+	key, err := ParsePrivateKeyPEM(r)
+	if err != nil {
+		return nil, err
+	}
+	return &Credentials{PrivateKey: key}, nil
+}
+
+// Refresh does nothing as the key is loaded from the source again for the retry.
+func (p *privateKeyProvider) Refresh(_ context.Context) error {
+	return nil
+}
+
+// ParsePrivateKeyPEM parses an unencrypted RSA private key in PKCS#8 or PKCS#1 PEM format.
+func ParsePrivateKeyPEM(r io.Reader) (*rsa.PrivateKey, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, &SnowflakeError{
+			Number:  ErrCodePrivateKeyParseError,
+			Message: "no PEM encoded private key is found",
+		}
+	}
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, &SnowflakeError{
+				Number:  ErrCodePrivateKeyParseError,
+				Message: "Error decoding private key using PKCS8.",
+			}
+		}
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, &SnowflakeError{
+				Number:  ErrCodePrivateKeyParseError,
+				Message: "the private key is not an RSA key",
+			}
+		}
+		return rsaKey, nil
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, &SnowflakeError{
+				Number:  ErrCodePrivateKeyParseError,
+				Message: "Error decoding private key using PKCS1.",
+			}
+		}
+		return key, nil
+	}
+	return nil, &SnowflakeError{
+		Number:  ErrCodePrivateKeyParseError,
+		Message: "unsupported PEM block type: " + block.Type,
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"strings"
+	"testing"
+)
+
+func encodePrivateKeyPEM(t *testing.T, key *rsa.PrivateKey, pkcs8 bool) []byte {
+	if !pkcs8 {
+		return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	}
+	b, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: b})
+}
+
+func TestParsePrivateKeyPEM(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pkcs8 := range []bool{true, false} {
+		parsed, err := ParsePrivateKeyPEM(bytes.NewReader(encodePrivateKeyPEM(t, key, pkcs8)))
+		if err != nil {
+			t.Fatalf("failed to parse the key. pkcs8: %v, err: %v", pkcs8, err)
+		}
+		if !parsed.Equal(key) {
+			t.Fatalf("the parsed key doesn't match. pkcs8: %v", pkcs8)
+		}
+	}
+	for _, s := range []string{"", "not a key", "-----BEGIN CERTIFICATE-----\nAAAA\n-----END CERTIFICATE-----\n"} {
+		_, err = ParsePrivateKeyPEM(strings.NewReader(s))
+		if driverErr, ok := err.(*SnowflakeError); !ok || driverErr.Number != ErrCodePrivateKeyParseError {
+			t.Fatalf("should have failed with ErrCodePrivateKeyParseError. input: %q, err: %v", s, err)
+		}
+	}
+}
+
+func TestPrivateKeyProviderRotation(t *testing.T) {
+	keys := make([]*rsa.PrivateKey, 2)
+	for i := range keys {
+		var err error
+		if keys[i], err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+			t.Fatal(err)
+		}
+	}
+	current := 0
+	provider := NewPrivateKeyProvider(func(_ context.Context) (io.Reader, error) {
+		return bytes.NewReader(encodePrivateKeyPEM(t, keys[current], true)), nil
+	})
+	for i := range keys {
+		current = i
+		creds, err := provider.Credentials(context.Background())
+		if err != nil {
+			t.Fatalf("failed to load the key. err: %v", err)
+		}
+		if !creds.PrivateKey.Equal(keys[i]) {
+			t.Fatalf("the rotated key should have been loaded. index: %v", i)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// ResultProgress is a snapshot of the download progress of a query result.
+type ResultProgress struct {
+	// RowsDownloaded is the number of rows received so far, including the rows in the query response.
+	RowsDownloaded int64
+	// TotalRows is the number of rows in the result.
+	TotalRows int64
+	// ChunksDownloaded is the number of result chunks downloaded so far.
+	ChunksDownloaded int
+	// TotalChunks is the number of result chunks to download.
+	TotalChunks int
+	// BytesDownloaded is the number of bytes of the result chunks transferred so far.
+	BytesDownloaded int64
+	// TotalBytes is the expected number of bytes of the result chunks, as reported by the server.
+	TotalBytes int64
+}
+
+// ResultProgressFunc is called with the download progress of a query result. It is called from the chunk
+// downloader goroutines one at a time, so it should return quickly.
+type ResultProgressFunc func(ResultProgress)
+
+// WithResultProgress returns a context that reports the download progress of the results of the queries
+// run with it to fn. fn is called once when the query returns and once for every downloaded chunk.
+func WithResultProgress(ctx context.Context, fn ResultProgressFunc) context.Context {
+	return context.WithValue(ctx, resultProgress, fn)
+}
+
+type resultProgressTracker struct {
+	mu       sync.Mutex
+	fn       ResultProgressFunc
+	progress ResultProgress
+}
+
+func newResultProgressTracker(ctx context.Context, scd *snowflakeChunkDownloader) *resultProgressTracker {
+	fn, ok := ctx.Value(resultProgress).(ResultProgressFunc)
+	if !ok || fn == nil {
+		return nil
+	}
+	t := &resultProgressTracker{
+		fn: fn,
+		progress: ResultProgress{
+			TotalRows:   scd.Total,
+			TotalChunks: len(scd.ChunkMetas),
+		},
+	}
+	for _, c := range scd.ChunkMetas {
+		t.progress.TotalBytes += c.CompressedSize
+	}
+	return t
+}
+
+// report adds the rows and bytes of a chunk to the progress and calls the callback.
+// chunks is zero for the rows included in the query response.
+func (t *resultProgressTracker) report(rows int64, chunks int, bytes int64) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.progress.RowsDownloaded += rows
+	t.progress.ChunksDownloaded += chunks
+	t.progress.BytesDownloaded += bytes
+	t.fn(t.progress)
+}
+
+// countingReader counts the number of bytes read from the underlying reader.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (n int, err error) {
+	n, err = c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
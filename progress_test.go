@@ -0,0 +1,94 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+var progressChunkBody = []byte(`["1","a"],["2","b"],["3","c"]`)
+
+func getChunkTestProgress(_ context.Context, _ *snowflakeChunkDownloader, _ string, _ map[string]string, _ time.Duration) (
+	*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       &fakeResponseBody{body: progressChunkBody},
+	}, nil
+}
+
+func TestResultProgress(t *testing.T) {
+	numChunks := 4
+	cm := make([]execResponseChunk, 0)
+	for i := 0; i < numChunks; i++ {
+		cm = append(cm, execResponseChunk{
+			URL: fmt.Sprintf("dummyURL%v", i+1), RowCount: 3, CompressedSize: int64(len(progressChunkBody))})
+	}
+	v1, v2 := "0", "z"
+	var mu sync.Mutex
+	var reports []ResultProgress
+	ctx := WithResultProgress(context.Background(), func(p ResultProgress) {
+		mu.Lock()
+		defer mu.Unlock()
+		reports = append(reports, p)
+	})
+	rows := new(snowflakeRows)
+	rows.RowType = []execResponseRowType{
+		{Name: "c1", Type: "fixed"},
+		{Name: "c2", Type: "text"},
+	}
+	rows.ChunkDownloader = &snowflakeChunkDownloader{
+		sc: &snowflakeConn{
+			rest: &snowflakeRestful{RequestTimeout: defaultRequestTimeout},
+		},
+		ctx:                ctx,
+		Total:              int64(1 + numChunks*3),
+		ChunkMetas:         cm,
+		TotalRowIndex:      int64(-1),
+		CellCount:          2,
+		FuncDownload:       downloadChunk,
+		FuncDownloadHelper: downloadChunkHelper,
+		FuncGet:            getChunkTestProgress,
+		RowSet:             rowSetType{JSON: [][]*string{{&v1, &v2}}},
+	}
+	rows.ChunkDownloader.start()
+	dest := make([]driver.Value, 2)
+	cnt := 0
+	for {
+		if err := rows.Next(dest); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("failed to get value. err: %v", err)
+		}
+		cnt++
+	}
+	if cnt != 1+numChunks*3 {
+		t.Fatalf("wrong number of rows. expected: %v, got: %v", 1+numChunks*3, cnt)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reports) != numChunks+1 {
+		t.Fatalf("wrong number of progress reports. expected: %v, got: %v", numChunks+1, len(reports))
+	}
+	if reports[0].RowsDownloaded != 1 || reports[0].ChunksDownloaded != 0 {
+		t.Fatalf("unexpected initial progress: %+v", reports[0])
+	}
+	last := reports[len(reports)-1]
+	expected := ResultProgress{
+		RowsDownloaded:   int64(1 + numChunks*3),
+		TotalRows:        int64(1 + numChunks*3),
+		ChunksDownloaded: numChunks,
+		TotalChunks:      numChunks,
+		BytesDownloaded:  int64(numChunks * len(progressChunkBody)),
+		TotalBytes:       int64(numChunks * len(progressChunkBody)),
+	}
+	if last != expected {
+		t.Fatalf("unexpected final progress. expected: %+v, got: %+v", expected, last)
+	}
+}
@@ -0,0 +1,292 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// defaultPutParallelism is the number of files uploaded at the same time by PutFiles by default.
+const defaultPutParallelism = 4
+
+// PutFileStatus is the outcome of the upload of a file by PutFiles.
+type PutFileStatus int
+
+const (
+	// PutFileUploaded is the status of a file uploaded to the stage.
+	PutFileUploaded PutFileStatus = iota
+	// PutFileSkipped is the status of a file not uploaded because the stage has a file of the same name and
+	// digest.
+	PutFileSkipped
+	// PutFileFailed is the status of a file that failed to be uploaded.
+	PutFileFailed
+)
+
+func (s PutFileStatus) String() string {
+	switch s {
+	case PutFileUploaded:
+		return "UPLOADED"
+	case PutFileSkipped:
+		return "SKIPPED"
+	default:
+		return "FAILED"
+	}
+}
+
+// PutFileResult is the result of the upload of a file by PutFiles.
+type PutFileResult struct {
+	Source            string // local path of the file
+	Target            string // location of the file in the stage, with the .gz extension if it is compressed
+	SourceCompression string // e.g., CompressionNone or CompressionGzip
+	TargetCompression string // CompressionGzip if the file is compressed by PutFiles
+	Size              int64  // size of the local file
+	Digest            string // base64 encoded SHA-256 of the uploaded content
+	Status            PutFileStatus
+	Err               error // the reason of the failure, if the Status is PutFileFailed
+}
+
+// PutFilesOptions are the options of PutFiles.
+type PutFilesOptions struct {
+	// Parallelism is the number of files uploaded at the same time. 4 by default.
+	Parallelism int
+	// Overwrite uploads the files that the stage already has with the same digest.
+	Overwrite bool
+	// DisableAutoCompress uploads the files that are not compressed as they are, as AUTO_COMPRESS = FALSE of PUT.
+	// By default they are gzipped while they are uploaded.
+	DisableAutoCompress bool
+	// SourceCompression is the compression of the files, as SOURCE_COMPRESSION of PUT, e.g., CompressionNone.
+	// CompressionAutoDetect by default detects it from the first bytes and the extension of every file. The
+	// compressed files, and the Parquet and ORC files, are not compressed again.
+	SourceCompression string
+}
+
+// uploadStage is the stage of the files uploaded by PutFiles, from the response of PUT.
+type uploadStage struct {
+	storage  stageStorage
+	material *EncryptionMaterial // nil unless the stage is client-side encrypted
+	wrapper  FileKeyWrapper
+}
+
+// PutFiles uploads the local files matching the patterns, e.g., /data/orders_*.csv, to the stage location, e.g.,
+// @orders_stage/2020. The files are uploaded in parallel directly to the storage of the stage, e.g., an S3 bucket,
+// with the temporary credentials of the response of a PUT, and are encrypted if the stage is client-side encrypted.
+// Those the stage already has with the same digest are skipped unless the options have Overwrite. It returns the
+// result of every file, and an error if a pattern is malformed or the PUT fails, e.g., because the stage doesn't
+// exist. A pattern that matches no file has a failed result.
+func (c *Client) PutFiles(ctx context.Context, patterns []string, stageLocation string, opts *PutFilesOptions) (
+	[]PutFileResult, error) {
+	if opts == nil {
+		opts = &PutFilesOptions{}
+	}
+	results, err := stageFileResults(patterns, stageLocation, opts)
+	if err != nil {
+		return nil, err
+	}
+	var files []*PutFileResult
+	for i := range results {
+		if results[i].Status != PutFileFailed {
+			files = append(files, &results[i])
+		}
+	}
+	if len(files) == 0 {
+		return results, nil
+	}
+	stage, err := c.sc.uploadStage(ctx, files, stageLocation)
+	if err != nil {
+		return nil, err
+	}
+	parallelism := opts.Parallelism
+	if parallelism <= 0 {
+		parallelism = defaultPutParallelism
+	}
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for _, file := range files {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			file.Status, file.Err = PutFileFailed, ctx.Err()
+			continue
+		}
+		wg.Add(1)
+		go func(file *PutFileResult) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			putFile(ctx, stage, file, opts.Overwrite)
+		}(file)
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// uploadStage runs a PUT of the first file to the stage location and returns the stage of its response. The files
+// are compressed by PutFiles, so the PUT doesn't compress them, and the stage has them by their names.
+func (sc *snowflakeConn) uploadStage(ctx context.Context, files []*PutFileResult, stageLocation string) (*uploadStage, error) {
+	command := func(source string) string {
+		return "PUT " + quoteFileTransferPath("file://"+filepath.ToSlash(source)) + " " +
+			quoteFileTransferPath(stageLocation) + " AUTO_COMPRESS = FALSE"
+	}
+	data, err := sc.fileTransferData(ctx, command(files[0].Source), "UPLOAD")
+	if err != nil {
+		return nil, err
+	}
+	storage, err := newStageStorage(sc.rest.Client, data.StageInfo)
+	if err != nil {
+		return nil, err
+	}
+	stage := &uploadStage{storage: storage, wrapper: sc.fileKeyWrapper()}
+	if data.StageInfo.IsClientSideEncrypted {
+		if len(data.EncryptionMaterial) == 0 || data.EncryptionMaterial[0] == nil {
+			return nil, fmt.Errorf("no encryption material of the client-side encrypted stage %v", stageLocation)
+		}
+		stage.material = data.EncryptionMaterial[0]
+	}
+	if cs, ok := storage.(*cloudStorage); ok && cs.needsPresignedURLs() {
+		// the presigned URL of the response is that of the file of the PUT, so every file has its own PUT
+		for _, file := range files {
+			name := path.Base(file.Target)
+			data, err := sc.fileTransferData(ctx, command(name), "UPLOAD")
+			if err != nil {
+				return nil, err
+			}
+			cs.presignedURLs[name] = data.StageInfo.PresignedURL
+		}
+	}
+	return stage, nil
+}
+
+// stageFileResults returns the results of the files matching the patterns, each file once, before they are
+// uploaded. The files with the same name as another one are failed, since they would overwrite it in the stage.
+func stageFileResults(patterns []string, stageLocation string, opts *PutFilesOptions) ([]PutFileResult, error) {
+	compression, err := parseSourceCompression(opts.SourceCompression)
+	if err != nil {
+		return nil, err
+	}
+	stageLocation = strings.TrimSuffix(stageLocation, "/")
+	var results []PutFileResult
+	sources := make(map[string]bool)
+	targets := make(map[string]string)
+	for _, pattern := range patterns {
+		paths, err := filepath.Glob(strings.TrimPrefix(pattern, "file://"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %v: %v", pattern, err)
+		}
+		files := 0
+		for _, path := range paths {
+			fi, err := os.Stat(path)
+			if err == nil && fi.IsDir() {
+				continue
+			}
+			files++
+			if sources[path] {
+				continue
+			}
+			sources[path] = true
+			result := PutFileResult{Source: path, Target: stageLocation + "/" + filepath.Base(path), Err: err}
+			if err == nil {
+				result.Size = fi.Size()
+				result.SourceCompression, result.Err = sourceCompression(path, compression)
+				result.TargetCompression = result.SourceCompression
+				if result.SourceCompression == CompressionNone && !opts.DisableAutoCompress {
+					result.TargetCompression = CompressionGzip
+					result.Target += ".gz"
+				}
+			}
+			if result.Err == nil {
+				if other, ok := targets[result.Target]; ok {
+					result.Err = fmt.Errorf("%v has the same name in the stage", other)
+				}
+				targets[result.Target] = path
+			}
+			if result.Err != nil {
+				result.Status = PutFileFailed
+			}
+			results = append(results, result)
+		}
+		if files == 0 {
+			results = append(results, PutFileResult{
+				Source: pattern,
+				Status: PutFileFailed,
+				Err:    fmt.Errorf("no file matches the pattern"),
+			})
+		}
+	}
+	return results, nil
+}
+
+// putFile computes the digest of the content of the file to upload and uploads it, unless the stage has a file of
+// the same name and digest and overwrite is false. A file compressed by PUT is compressed twice, for the digest and
+// for the upload, rather than stored in a temporary file.
+func putFile(ctx context.Context, stage *uploadStage, file *PutFileResult, overwrite bool) {
+	digest, size, err := uploadDigest(file)
+	if err != nil {
+		file.Status, file.Err = PutFileFailed, err
+		return
+	}
+	file.Digest = digest
+	name := path.Base(file.Target)
+	if !overwrite {
+		meta, err := stage.storage.head(ctx, name)
+		if err != nil {
+			file.Status, file.Err = PutFileFailed, err
+			return
+		}
+		if meta != nil && meta.digest == file.Digest {
+			file.Status = PutFileSkipped
+			return
+		}
+	}
+	content, err := openUploadContent(file)
+	if err != nil {
+		file.Status, file.Err = PutFileFailed, err
+		return
+	}
+	defer content.Close()
+	if err = stage.upload(ctx, name, content, size, file.Digest); err != nil {
+		file.Status, file.Err = PutFileFailed, err
+		return
+	}
+	file.Status = PutFileUploaded
+}
+
+// upload uploads the content of the size to the file of the stage with the digest, encrypting it if the stage is
+// client-side encrypted.
+func (s *uploadStage) upload(ctx context.Context, name string, content io.Reader, size int64, digest string) error {
+	r := content
+	meta := &stageFileMeta{digest: digest}
+	if s.material != nil {
+		var err error
+		if r, meta.encryption, err = encryptFile(ctx, content, s.material, s.wrapper); err != nil {
+			return err
+		}
+		size = encryptedSize(size)
+	}
+	return s.storage.upload(ctx, name, r, size, meta)
+}
+
+// uploadDigest returns the base64 encoded SHA-256 of the content of the file to upload, the same as the digest
+// the other Snowflake clients store with the files in the stage, and the size of the content.
+func uploadDigest(file *PutFileResult) (string, int64, error) {
+	content, err := openUploadContent(file)
+	if err != nil {
+		return "", 0, err
+	}
+	defer content.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, content)
+	if err != nil {
+		return "", 0, err
+	}
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)), size, nil
+}
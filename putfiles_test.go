@@ -0,0 +1,246 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPutFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "putfiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err = os.Mkdir(filepath.Join(dir, "sub"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.csv", "b.csv", "c.csv", "staged.csv", "sub/a.csv"} {
+		if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(name), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	server := newFakeStageStorage()
+	defer server.Close()
+	server.uploadDelay = 100 * time.Millisecond
+	material := testEncryptionMaterial()
+	client := newFakeQueryClient(nil)
+	client.sc.rest.Client = server.Client()
+	client.answerFileTransfers(server.stageInfo("S3"), material)
+
+	results, err := client.PutFiles(context.Background(), []string{filepath.Join(dir, "staged.csv")}, "@s/2020",
+		nil)
+	if err != nil || results[0].Status != PutFileUploaded {
+		t.Fatalf("the file should be uploaded. results: %+v, err: %v", results, err)
+	}
+	results, err = client.PutFiles(context.Background(), []string{
+		"file://" + filepath.Join(dir, "*.csv"),
+		filepath.Join(dir, "a.csv"),
+		filepath.Join(dir, "sub", "*"),
+		filepath.Join(dir, "*.json"),
+	}, "@s/2020/", &PutFilesOptions{Parallelism: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []struct {
+		source string
+		status PutFileStatus
+	}{
+		{"a.csv", PutFileUploaded},
+		{"b.csv", PutFileUploaded},
+		{"c.csv", PutFileUploaded},
+		{"staged.csv", PutFileSkipped},
+		{filepath.Join("sub", "a.csv"), PutFileFailed},
+		{"*.json", PutFileFailed},
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("every file should have a result once. results: %+v", results)
+	}
+	for i, e := range expected {
+		r := results[i]
+		if r.Source != filepath.Join(dir, e.source) || r.Status != e.status {
+			t.Errorf("unexpected result. expected: %v %v, got: %+v", e.source, e.status, r)
+		}
+	}
+	if r := results[0]; r.Target != "@s/2020/a.csv.gz" || r.Size != 5 || r.SourceCompression != CompressionNone ||
+		r.TargetCompression != CompressionGzip {
+		t.Fatalf("unexpected result: %+v", r)
+	}
+	if results[4].Err == nil || results[5].Err == nil {
+		t.Fatalf("the failed files should have the reason. results: %+v", results)
+	}
+	server.mu.Lock()
+	maxRunning := server.maxRunning
+	server.mu.Unlock()
+	if maxRunning != 2 {
+		t.Fatalf("the files should be uploaded two at a time. got: %v", maxRunning)
+	}
+	if stmts := client.queries(); len(stmts) != 2 || stmts[1] != "PUT 'file://"+filepath.ToSlash(filepath.Join(dir, "a.csv"))+
+		"' '@s/2020/' AUTO_COMPRESS = FALSE" {
+		t.Fatalf("the stage should be that of a PUT of the first file. statements: %v", stmts)
+	}
+
+	// the file is stored encrypted with the digest of its gzipped content
+	gzipped := decryptStagedFile(t, server, "/bucket/stage/a.csv.gz", material)
+	if digest := sha256.Sum256(gzipped); base64.StdEncoding.EncodeToString(digest[:]) != results[0].Digest {
+		t.Fatal("the digest should be that of the uploaded content")
+	}
+	if file, _ := server.file("/bucket/stage/a.csv.gz"); file.header.Get("X-Amz-Meta-Sfc-Digest") != results[0].Digest {
+		t.Fatalf("the digest should be stored with the file. headers: %v", file.header)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(gzipped))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadAll(zr); string(b) != "a.csv" {
+		t.Fatalf("unexpected content: %s", b)
+	}
+
+	results, err = client.PutFiles(context.Background(), []string{filepath.Join(dir, "staged.csv")}, "@s",
+		&PutFilesOptions{Overwrite: true})
+	if err != nil || results[0].Status != PutFileUploaded || results[0].Target != "@s/staged.csv.gz" {
+		t.Fatalf("the file should be overwritten. results: %+v, err: %v", results, err)
+	}
+	if _, err = client.PutFiles(context.Background(), []string{"[a-"}, "@s", nil); err == nil {
+		t.Fatal("a malformed pattern should fail")
+	}
+
+	client.respond = func(req execRequest) (*execResponse, error) {
+		return &execResponse{Data: execResponseData{QueryID: "qid"}, Code: "2003", Message: "stage does not exist"}, nil
+	}
+	if _, err = client.PutFiles(context.Background(), []string{filepath.Join(dir, "a.csv")}, "@s", nil); err == nil {
+		t.Fatal("the PUT of a stage that doesn't exist should fail")
+	}
+}
+
+// decryptStagedFile returns the decrypted content of the file stored in the path of the server.
+func decryptStagedFile(t *testing.T, server *fakeStageStorage, path string, material *EncryptionMaterial) []byte {
+	file, ok := server.file(path)
+	if !ok {
+		t.Fatalf("%v should be stored", path)
+	}
+	storage, _ := newStageStorage(server.Client(), &execResponseStageInfo{LocationType: "S3"})
+	meta, err := storage.(*cloudStorage).parseMeta(file.header)
+	if err != nil || meta.encryption == nil {
+		t.Fatalf("%v should be encrypted. meta: %+v, err: %v", path, meta, err)
+	}
+	var decrypted bytes.Buffer
+	if err = decryptFile(context.Background(), bytes.NewReader(file.content), &decrypted, material, meta.encryption,
+		masterKeyWrapper{}); err != nil {
+		t.Fatalf("failed to decrypt %v. err: %v", path, err)
+	}
+	return decrypted.Bytes()
+}
+
+func TestPutFilesCompression(t *testing.T) {
+	dir, err := ioutil.TempDir("", "putfiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte("a,b"))
+	zw.Close()
+	files := map[string][]byte{
+		"a.csv":      []byte("a,b"),
+		"b.dat":      gz.Bytes(),
+		"c.parquet":  []byte("PAR1...PAR1"),
+		"d.json.zst": {0x28, 0xb5, 0x2f, 0xfd, 0},
+		"e.json.br":  {0x0b, 0x01, 0x80},
+		"f":          {},
+	}
+	for name, content := range files {
+		if err = ioutil.WriteFile(filepath.Join(dir, name), content, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testcases := []struct {
+		opts     *PutFilesOptions
+		expected map[string]string // source to target compression
+	}{
+		{nil, map[string]string{
+			"a.csv": CompressionGzip, "b.dat": CompressionGzip, "c.parquet": CompressionParquet,
+			"d.json.zst": CompressionZstd, "e.json.br": CompressionBrotli, "f": CompressionGzip,
+		}},
+		{&PutFilesOptions{DisableAutoCompress: true}, map[string]string{
+			"a.csv": CompressionNone, "b.dat": CompressionGzip, "f": CompressionNone,
+		}},
+		{&PutFilesOptions{SourceCompression: "none"}, map[string]string{
+			"b.dat": CompressionGzip, "c.parquet": CompressionGzip,
+		}},
+		{&PutFilesOptions{SourceCompression: CompressionGzip}, map[string]string{
+			"a.csv": CompressionGzip,
+		}},
+	}
+	server := newFakeStageStorage()
+	defer server.Close()
+	material := testEncryptionMaterial()
+	for i, tc := range testcases {
+		// every test case has a client-side encrypted stage of its own
+		info := server.stageInfo("S3")
+		info.Location = fmt.Sprintf("bucket/stage%v/", i)
+		client := newFakeQueryClient(nil)
+		client.sc.rest.Client = server.Client()
+		client.answerFileTransfers(info, material)
+		results, err := client.PutFiles(context.Background(), []string{filepath.Join(dir, "*")}, "@s", tc.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range results {
+			name := filepath.Base(r.Source)
+			expected, ok := tc.expected[name]
+			if !ok {
+				continue
+			}
+			if r.Status != PutFileUploaded || r.TargetCompression != expected {
+				t.Errorf("unexpected result of %v with %+v: %+v", name, tc.opts, r)
+				continue
+			}
+			compressed := r.SourceCompression != r.TargetCompression
+			if compressed != strings.HasSuffix(r.Target, name+".gz") {
+				t.Errorf("the target of %v should have the .gz extension only if it is compressed: %v", name, r.Target)
+			}
+			content := decryptStagedFile(t, server, "/"+info.Location+filepath.Base(r.Target), material)
+			if compressed {
+				zr, err := gzip.NewReader(bytes.NewReader(content))
+				if err != nil {
+					t.Fatalf("%v should be gzipped. err: %v", name, err)
+				}
+				content, _ = ioutil.ReadAll(zr)
+			}
+			if !bytes.Equal(content, files[name]) {
+				t.Errorf("unexpected content of %v: %q", name, content)
+			}
+		}
+
+		// the files compressed by PutFiles have the same digest every time
+		results, err = client.PutFiles(context.Background(), []string{filepath.Join(dir, "*")}, "@s", tc.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range results {
+			if _, ok := tc.expected[filepath.Base(r.Source)]; ok && r.Status != PutFileSkipped {
+				t.Errorf("the file uploaded again should be skipped with %+v: %+v", tc.opts, r)
+			}
+		}
+	}
+
+	if _, err = newFakeQueryClient(nil).PutFiles(context.Background(), []string{filepath.Join(dir, "*")}, "@s",
+		&PutFilesOptions{SourceCompression: "lz4"}); err == nil {
+		t.Fatal("an invalid source compression should fail")
+	}
+}
@@ -3,10 +3,14 @@
 package gosnowflake
 
 import (
+	"encoding/json"
 	"time"
 )
 
-const arrowFormat = "arrow"
+const (
+	arrowFormat = "arrow"
+	jsonFormat  = "json"
+)
 
 type execBindParameter struct {
 	Type  string      `json:"type"`
@@ -14,12 +18,16 @@ type execBindParameter struct {
 }
 
 type execRequest struct {
-	SQLText    string                       `json:"sqlText"`
-	AsyncExec  bool                         `json:"asyncExec"`
-	SequenceID uint64                       `json:"sequenceId"`
-	IsInternal bool                         `json:"isInternal"`
-	Parameters map[string]interface{}       `json:"parameters,omitempty"`
-	Bindings   map[string]execBindParameter `json:"bindings,omitempty"`
+	SQLText      string                       `json:"sqlText"`
+	AsyncExec    bool                         `json:"asyncExec"`
+	SequenceID   uint64                       `json:"sequenceId"`
+	IsInternal   bool                         `json:"isInternal"`
+	DescribeOnly bool                         `json:"describeOnly,omitempty"`
+	Parameters   map[string]interface{}       `json:"parameters,omitempty"`
+	Bindings     map[string]execBindParameter `json:"bindings,omitempty"`
+	BindStage    string                       `json:"bindStage,omitempty"` // the stage location of stage binding
+
+	QueryContext *queryContext `json:"queryContextDTO,omitempty"`
 }
 
 type execResponseRowType struct {
@@ -61,6 +69,7 @@ type execResponseData struct {
 	Chunks             []execResponseChunk   `json:"chunks,omitempty"`
 	Qrmk               string                `json:"qrmk,omitempty"`
 	ChunkHeaders       map[string]string     `json:"chunkHeaders,omitempty"`
+	QueryContext       *queryContext         `json:"queryContext,omitempty"`
 
 	// ping pong response data
 	GetResultURL      string        `json:"getResultUrl,omitempty"`
@@ -69,6 +78,42 @@ type execResponseData struct {
 	ResultIDs         string        `json:"resultIds,omitempty"`
 	ResultTypes       string        `json:"resultTypes,omitempty"`
 	QueryResultFormat string        `json:"queryResultFormat,omitempty"`
+
+	// file transfer response data of PUT and GET
+	Command            string                 `json:"command,omitempty"` // UPLOAD or DOWNLOAD
+	SrcLocations       []string               `json:"src_locations,omitempty"`
+	Parallel           int64                  `json:"parallel,omitempty"`
+	AutoCompress       bool                   `json:"autoCompress,omitempty"`
+	Overwrite          bool                   `json:"overwrite,omitempty"`
+	SourceCompression  string                 `json:"sourceCompression,omitempty"`
+	StageInfo          *execResponseStageInfo `json:"stageInfo,omitempty"`
+	EncryptionMaterial encryptionMaterials    `json:"encryptionMaterial,omitempty"`
+	PresignedURLs      []string               `json:"presignedUrls,omitempty"`
+
+	// Extensions has the raw JSON of the unknown fields, captured if WithResponseExtensions is set
+	Extensions map[string]json.RawMessage `json:"-"`
+}
+
+// execResponseStageInfo is the stage of a PUT or GET, where the files are uploaded to or downloaded from.
+type execResponseStageInfo struct {
+	LocationType          string                  `json:"locationType"` // S3, AZURE, GCS or LOCAL_FS
+	Location              string                  `json:"location"`     // bucket or container, and the path
+	Path                  string                  `json:"path"`
+	Region                string                  `json:"region"`
+	StorageAccount        string                  `json:"storageAccount"` // Azure only
+	IsClientSideEncrypted bool                    `json:"isClientSideEncrypted"`
+	Creds                 execResponseCredentials `json:"creds"`
+	PresignedURL          string                  `json:"presignedUrl"` // GCS only, for the file of the PUT
+	EndPoint              string                  `json:"endPoint"`
+}
+
+// execResponseCredentials are the temporary credentials of the storage of a stage.
+type execResponseCredentials struct {
+	AwsKeyID       string `json:"AWS_KEY_ID"`
+	AwsSecretKey   string `json:"AWS_SECRET_KEY"`
+	AwsToken       string `json:"AWS_TOKEN"`
+	AzureSasToken  string `json:"AZURE_SAS_TOKEN"`
+	GcsAccessToken string `json:"GCS_ACCESS_TOKEN"`
 }
 
 type execResponse struct {
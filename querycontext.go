@@ -0,0 +1,104 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+)
+
+const (
+	queryContextCacheSizeParam   = "query_context_cache_size"
+	defaultQueryContextCacheSize = 5
+)
+
+// queryContextEntry is an opaque context the server returns with a query result and expects back with the
+// subsequent queries, e.g., to guarantee read-after-write consistency of hybrid tables.
+type queryContextEntry struct {
+	ID        int    `json:"id"`
+	Timestamp int64  `json:"timestamp"`
+	Priority  int    `json:"priority"`
+	Context   string `json:"context,omitempty"`
+}
+
+type queryContext struct {
+	Entries []queryContextEntry `json:"entries,omitempty"`
+}
+
+// queryContextCache keeps the latest query context entry per ID, ordered by priority.
+type queryContextCache struct {
+	mu      sync.Mutex
+	entries []queryContextEntry
+}
+
+// merge adds the entries received from the server. An entry replaces the one with the same ID if it is newer
+// and the one with the same priority otherwise. The entries with the lowest priorities are kept up to capacity.
+func (qcc *queryContextCache) merge(qc *queryContext, capacity int) {
+	if qc == nil {
+		return
+	}
+	qcc.mu.Lock()
+	defer qcc.mu.Unlock()
+	for _, e := range qc.Entries {
+		qcc.mergeEntry(e)
+	}
+	sort.SliceStable(qcc.entries, func(i, j int) bool {
+		return qcc.entries[i].Priority < qcc.entries[j].Priority
+	})
+	if capacity >= 0 && len(qcc.entries) > capacity {
+		qcc.entries = qcc.entries[:capacity]
+	}
+}
+
+func (qcc *queryContextCache) mergeEntry(e queryContextEntry) {
+	for i, cur := range qcc.entries {
+		if cur.ID == e.ID {
+			if e.Timestamp > cur.Timestamp {
+				qcc.entries[i] = e
+				qcc.removePriority(e.Priority, i)
+			}
+			return
+		}
+	}
+	for i, cur := range qcc.entries {
+		if cur.Priority == e.Priority {
+			qcc.entries[i] = e
+			return
+		}
+	}
+	qcc.entries = append(qcc.entries, e)
+}
+
+// removePriority removes the entries with the priority except the one at keep.
+func (qcc *queryContextCache) removePriority(priority int, keep int) {
+	entries := qcc.entries[:0]
+	for i, cur := range qcc.entries {
+		if i == keep || cur.Priority != priority {
+			entries = append(entries, cur)
+		}
+	}
+	qcc.entries = entries
+}
+
+// get returns the query context sent with the next query, or nil if the cache is empty.
+func (qcc *queryContextCache) get() *queryContext {
+	qcc.mu.Lock()
+	defer qcc.mu.Unlock()
+	if len(qcc.entries) == 0 {
+		return nil
+	}
+	entries := make([]queryContextEntry, len(qcc.entries))
+	copy(entries, qcc.entries)
+	return &queryContext{Entries: entries}
+}
+
+// queryContextCacheSize returns the capacity of the query context cache given by the session parameter.
+func (sc *snowflakeConn) queryContextCacheSize() int {
+	if v, ok := sc.cfg.Params[queryContextCacheSizeParam]; ok && v != nil {
+		if n, err := strconv.Atoi(*v); err == nil {
+			return n
+		}
+	}
+	return defaultQueryContextCacheSize
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestQueryContextCacheMerge(t *testing.T) {
+	var qcc queryContextCache
+	if qcc.get() != nil {
+		t.Fatal("empty cache should return nil")
+	}
+	qcc.merge(&queryContext{Entries: []queryContextEntry{
+		{ID: 0, Timestamp: 10, Priority: 0, Context: "a"},
+		{ID: 2, Timestamp: 10, Priority: 2, Context: "b"},
+		{ID: 1, Timestamp: 10, Priority: 1, Context: "c"},
+	}}, 5)
+	// older entry for the same ID is ignored, newer entry replaces it,
+	// and a new ID with an existing priority replaces the entry with the priority.
+	qcc.merge(&queryContext{Entries: []queryContextEntry{
+		{ID: 0, Timestamp: 5, Priority: 0, Context: "old"},
+		{ID: 1, Timestamp: 20, Priority: 1, Context: "new"},
+		{ID: 3, Timestamp: 20, Priority: 2, Context: "d"},
+	}}, 5)
+	expected := []queryContextEntry{
+		{ID: 0, Timestamp: 10, Priority: 0, Context: "a"},
+		{ID: 1, Timestamp: 20, Priority: 1, Context: "new"},
+		{ID: 3, Timestamp: 20, Priority: 2, Context: "d"},
+	}
+	if got := qcc.get().Entries; !reflect.DeepEqual(got, expected) {
+		t.Fatalf("unexpected entries. expected: %v, got: %v", expected, got)
+	}
+	qcc.merge(&queryContext{Entries: []queryContextEntry{{ID: 4, Timestamp: 1, Priority: 3}}}, 2)
+	if got := qcc.get().Entries; len(got) != 2 || got[1].ID != 1 {
+		t.Fatalf("the entries with the lowest priorities should be kept. got: %v", got)
+	}
+}
+
+func postQueryMockQueryContext(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte, _ time.Duration, _ *uuid.UUID) (*execResponse, error) {
+	var req execRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+	var ts int64
+	if req.QueryContext != nil {
+		if len(req.QueryContext.Entries) != 1 {
+			return nil, fmt.Errorf("unexpected query context: %v", req.QueryContext)
+		}
+		ts = req.QueryContext.Entries[0].Timestamp
+	}
+	return &execResponse{
+		Data: execResponseData{
+			QueryContext: &queryContext{Entries: []queryContextEntry{{ID: 0, Timestamp: ts + 1, Priority: 0}}},
+		},
+		Code:    "0",
+		Success: true,
+	}, nil
+}
+
+func TestExecQueryContext(t *testing.T) {
+	sc := &snowflakeConn{
+		cfg:  &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{FuncPostQuery: postQueryMockQueryContext},
+	}
+	for i := 1; i <= 3; i++ {
+		if _, err := sc.exec(context.Background(), "SELECT 1", false, false, nil); err != nil {
+			t.Fatalf("failed to exec. err: %v", err)
+		}
+		if ts := sc.queryContextCache.get().Entries[0].Timestamp; ts != int64(i) {
+			t.Fatalf("the query context was not sent back. expected timestamp: %v, got: %v", i, ts)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// queryLog has the IDs by which Snowflake support finds a query in its own logs: the request ID of the query
+// request, the request GUID of the last HTTP request sent for the query, e.g., a poll of its result, and the
+// query ID once Snowflake has returned it. The IDs are added to the log lines and to the errors of the query.
+type queryLog struct {
+	mu          sync.Mutex
+	requestID   string
+	requestGUID string
+	queryID     string
+}
+
+// withQueryLog returns a context with a new queryLog, or the context itself if it already has one, e.g., for the
+// query of a prepared statement.
+func withQueryLog(ctx context.Context) (context.Context, *queryLog) {
+	if l := queryLogFrom(ctx); l != nil {
+		return ctx, l
+	}
+	l := &queryLog{}
+	return context.WithValue(ctx, queryLogKey, l), l
+}
+
+// queryLogFrom returns the queryLog of the context, or nil if there is none. The methods of queryLog may be
+// called on nil.
+func queryLogFrom(ctx context.Context) *queryLog {
+	if ctx == nil {
+		return nil
+	}
+	l, _ := ctx.Value(queryLogKey).(*queryLog)
+	return l
+}
+
+func (l *queryLog) setRequestID(requestID uuid.UUID) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.requestID = requestID.String()
+}
+
+func (l *queryLog) setRequestGUID(requestGUID string) {
+	if l == nil || requestGUID == "" {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.requestGUID = requestGUID
+}
+
+func (l *queryLog) setQueryID(qid string) {
+	if l == nil || qid == "" {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.queryID = qid
+}
+
+// String returns the IDs known so far for the log lines, e.g.,
+// "requestId: 8c9f..., request_guid: 1d2e..., queryId: 01a2...".
+func (l *queryLog) String() string {
+	if l == nil {
+		return "requestId: unknown"
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ids := make([]string, 0, 3)
+	for _, id := range []struct{ key, value string }{
+		{requestIDKey, l.requestID},
+		{requestGUIDKey, l.requestGUID},
+		{"queryId", l.queryID},
+	} {
+		if id.value != "" {
+			ids = append(ids, id.key+": "+id.value)
+		}
+	}
+	if len(ids) == 0 {
+		return "requestId: unknown"
+	}
+	return strings.Join(ids, ", ")
+}
+
+// annotate returns a copy of the SnowflakeError with the IDs of the query, keeping the query ID it already has.
+// The other errors are returned as is, so that they can still be compared, e.g., with context.Canceled.
+func (l *queryLog) annotate(err error) error {
+	se, ok := err.(*SnowflakeError)
+	if l == nil || !ok {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// the error may be shared, e.g., errTooManyRedirects
+	annotated := *se
+	if annotated.QueryID == "" {
+		annotated.QueryID = l.queryID
+	}
+	if annotated.RequestID == "" {
+		annotated.RequestID = l.requestID
+	}
+	if annotated.RequestGUID == "" {
+		annotated.RequestGUID = l.requestGUID
+	}
+	return &annotated
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+func TestQueryLogIDsInErrors(t *testing.T) {
+	origInterval := queryPollInterval
+	queryPollInterval = 0
+	defer func() { queryPollInterval = origInterval }()
+
+	var requestID, requestGUID string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			requestID = r.URL.Query().Get(requestIDKey)
+			requestGUID = r.URL.Query().Get(requestGUIDKey)
+			w.Write([]byte(`{"code":"333333","success":true,"data":{"queryId":"q1","getResultUrl":"/queries/q1/result"}}`))
+			return
+		}
+		w.Write([]byte(`{"code":"002003","success":false,"message":"Object 'T' does not exist or not authorized.",` +
+			`"data":{"queryId":"q1","sqlState":"42S02"}}`))
+	}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, _ := strconv.Atoi(u.Port())
+	sc := &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{
+			Protocol:            "http",
+			Host:                u.Hostname(),
+			Port:                port,
+			Token:               "token",
+			Client:              server.Client(),
+			FuncPost:            postRestful,
+			FuncGet:             getRestful,
+			FuncPostQuery:       postRestfulQuery,
+			FuncPostQueryHelper: postRestfulQueryHelper,
+		},
+	}
+
+	_, err = sc.ExecContext(context.Background(), "SELECT * FROM t", nil)
+	driverErr, ok := err.(*SnowflakeError)
+	if !ok || driverErr.Number != ErrObjectNotExist {
+		t.Fatalf("should have failed with ErrObjectNotExist. err: %v", err)
+	}
+	if driverErr.QueryID != "q1" || driverErr.RequestID == "" || driverErr.RequestID != requestID ||
+		driverErr.RequestGUID == "" || driverErr.RequestGUID != requestGUID {
+		t.Fatalf("the error should have the IDs of the query. error: %+v, requestId: %v, request_guid: %v",
+			driverErr, requestID, requestGUID)
+	}
+}
+
+func TestQueryLog(t *testing.T) {
+	ctx, ql := withQueryLog(context.Background())
+	if ctx2, ql2 := withQueryLog(ctx); ctx2 != ctx || ql2 != ql {
+		t.Fatal("the query log of the context should be reused")
+	}
+	if ql.String() != "requestId: unknown" {
+		t.Fatalf("unexpected IDs: %v", ql)
+	}
+	ql.setQueryID("q1")
+	ql.setRequestGUID("")
+	if ql.String() != "queryId: q1" {
+		t.Fatalf("unexpected IDs: %v", ql)
+	}
+
+	annotated := ql.annotate(errTooManyRedirects).(*SnowflakeError)
+	if annotated.QueryID != "q1" || errTooManyRedirects.QueryID != "" {
+		t.Fatalf("a copy of the error should have the query ID. error: %+v", annotated)
+	}
+	if err := ql.annotate(context.Canceled); err != context.Canceled {
+		t.Fatalf("the other errors should be returned as is. err: %v", err)
+	}
+
+	var nilLog *queryLog
+	nilLog.setQueryID("q1")
+	if nilLog.String() != "requestId: unknown" || !errors.Is(nilLog.annotate(context.Canceled), context.Canceled) {
+		t.Fatal("a nil query log should be usable")
+	}
+	if queryLogFrom(context.Background()) != nil {
+		t.Fatal("the context should have no query log")
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"time"
+)
+
+var (
+	// queryPollInterval is the wait before polling again the result of a query reported in progress. It doubles
+	// for every poll up to queryPollMaxInterval.
+	queryPollInterval = 100 * time.Millisecond
+	// queryPollMaxInterval is the longest wait between the polls of the result of a query.
+	queryPollMaxInterval = 2 * time.Second
+)
+
+// queryPoller backs off progressively between the polls of the result of a query in progress. Snowflake holds
+// every poll until the query completes or its own timeout, so the waits only space out the polls that return
+// early.
+type queryPoller struct {
+	interval time.Duration
+}
+
+func newQueryPoller(interval time.Duration) *queryPoller {
+	return &queryPoller{interval: interval}
+}
+
+// wait waits before the next poll. It returns the context error if the context is done in the meantime.
+func (p *queryPoller) wait(ctx context.Context) error {
+	await := time.NewTimer(p.interval)
+	defer await.Stop()
+	if p.interval *= 2; p.interval > queryPollMaxInterval {
+		p.interval = queryPollMaxInterval
+	}
+	select {
+	case <-await.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// startPolling records that the connection polls the result of the query, until the returned function is
+// called.
+func (sr *snowflakeRestful) startPolling(qid string) func() {
+	if qid == "" {
+		return func() {}
+	}
+	sr.polling.Store(qid, time.Now())
+	return func() { sr.polling.Delete(qid) }
+}
+
+// pollingTime returns how long the connection has been polling the result of the query, or 0 if it doesn't.
+func (sr *snowflakeRestful) pollingTime(qid string) time.Duration {
+	start, ok := sr.polling.Load(qid)
+	if !ok {
+		return 0
+	}
+	return time.Since(start.(time.Time))
+}
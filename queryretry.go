@@ -0,0 +1,95 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// queryRetryInterval is the wait before the first re-submission of a query. It doubles for every attempt.
+var queryRetryInterval = time.Second
+
+// readOnlyKeywords are the leading keywords of the statements that change no data, so that they are
+// re-submitted without WithRetryableDML.
+var readOnlyKeywords = map[string]bool{
+	"SELECT":   true,
+	"WITH":     true,
+	"SHOW":     true,
+	"DESC":     true,
+	"DESCRIBE": true,
+	"EXPLAIN":  true,
+	"LIST":     true,
+	"LS":       true,
+}
+
+// IsRetryableQueryError returns true if the query failed because of an internal error of Snowflake, for which
+// an incident is recorded, and may succeed if it is submitted again.
+func IsRetryableQueryError(err error) bool {
+	se, ok := err.(*SnowflakeError)
+	return ok && se.Number == ErrInternalError
+}
+
+// WithRetryableDML returns a context that allows the statements run with it that change data, e.g., DML and
+// multi-statement queries, to be re-submitted on retryable errors as well. Use it only for the statements
+// that are idempotent, since a statement that failed may have been applied in part.
+func WithRetryableDML(ctx context.Context) context.Context {
+	return context.WithValue(ctx, retryableDML, true)
+}
+
+// canRetryQuery returns true if the query can be re-submitted after the error.
+func (sc *snowflakeConn) canRetryQuery(ctx context.Context, query string, isInternal bool, err error) bool {
+	if isInternal || ctx.Err() != nil || !IsRetryableQueryError(err) {
+		return false
+	}
+	if dml, _ := ctx.Value(retryableDML).(bool); dml {
+		return true
+	}
+	if multiCount := ctx.Value(MultiStatementCount); multiCount != nil && multiCount != 1 {
+		return false
+	}
+	return isReadOnlyQuery(query)
+}
+
+// waitQueryRetry waits before the attempt-th re-submission of a query. It returns false if the context is
+// done in the meantime.
+func waitQueryRetry(ctx context.Context, attempt int) bool {
+	await := time.NewTimer(queryRetryInterval << uint(attempt))
+	defer await.Stop()
+	select {
+	case <-await.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// isReadOnlyQuery returns true if the leading keyword of the query, after the comments and the opening
+// parentheses, is of a statement that changes no data.
+func isReadOnlyQuery(query string) bool {
+	q := query
+	for {
+		q = strings.TrimLeft(q, " \t\r\n(")
+		switch {
+		case strings.HasPrefix(q, "--"):
+			end := strings.Index(q, "\n")
+			if end < 0 {
+				return false
+			}
+			q = q[end+1:]
+		case strings.HasPrefix(q, "/*"):
+			end := strings.Index(q, "*/")
+			if end < 0 {
+				return false
+			}
+			q = q[end+2:]
+		default:
+			end := strings.IndexAny(q, " \t\r\n(;")
+			if end < 0 {
+				end = len(q)
+			}
+			return readOnlyKeywords[strings.ToUpper(q[:end])]
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestQueryRetry(t *testing.T) {
+	orig := queryRetryInterval
+	queryRetryInterval = time.Millisecond
+	defer func() { queryRetryInterval = orig }()
+
+	var requestIDs []uuid.UUID
+	failures := 0
+	sc := &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}, MaxQueryRetries: 2},
+		rest: &snowflakeRestful{
+			FuncPostQuery: func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, _ []byte, _ time.Duration, requestID *uuid.UUID) (*execResponse, error) {
+				requestIDs = append(requestIDs, *requestID)
+				if len(requestIDs) <= failures {
+					return &execResponse{
+						Data:    execResponseData{SQLState: "XX000", QueryID: "q"},
+						Message: "SQL execution internal error: Processing aborted due to error 300002:1234; incident 5678.",
+						Code:    "000603",
+					}, nil
+				}
+				return &execResponse{Data: execResponseData{QueryID: "q"}, Code: "0", Success: true}, nil
+			},
+		},
+	}
+	testcases := []struct {
+		ctx      context.Context
+		query    string
+		failures int
+		attempts int
+		ok       bool
+	}{
+		{context.Background(), "SELECT 1", 2, 3, true},
+		{context.Background(), "/* report */ (SELECT 1)", 1, 2, true},
+		{context.Background(), "SELECT 1", 3, 3, false},
+		{context.Background(), "INSERT INTO t VALUES(1)", 1, 1, false},
+		{WithRetryableDML(context.Background()), "INSERT INTO t VALUES(1)", 1, 2, true},
+	}
+	for _, tc := range testcases {
+		requestIDs = nil
+		failures = tc.failures
+		_, err := sc.ExecContext(tc.ctx, tc.query, nil)
+		if tc.ok && err != nil {
+			t.Fatalf("%v: failed to execute. err: %v", tc.query, err)
+		}
+		if !tc.ok && !IsRetryableQueryError(err) {
+			t.Fatalf("%v: should fail with the internal error. err: %v", tc.query, err)
+		}
+		if len(requestIDs) != tc.attempts {
+			t.Fatalf("%v: unexpected number of attempts. expected: %v, got: %v", tc.query, tc.attempts, len(requestIDs))
+		}
+		if len(requestIDs) > 1 && requestIDs[0] == requestIDs[1] {
+			t.Fatalf("%v: the request ID should be regenerated", tc.query)
+		}
+	}
+}
+
+func TestIsReadOnlyQuery(t *testing.T) {
+	testcases := []struct {
+		query    string
+		readOnly bool
+	}{
+		{"select * from t", true},
+		{"  WITH c AS (SELECT 1) SELECT * FROM c", true},
+		{"-- comment\nSHOW TABLES", true},
+		{"desc table t", true},
+		{"INSERT INTO t SELECT 1", false},
+		{"/* SELECT */ DELETE FROM t", false},
+		{"SELECTED", false},
+		{"-- SELECT", false},
+		{"", false},
+	}
+	for _, tc := range testcases {
+		if readOnly := isReadOnlyQuery(tc.query); readOnly != tc.readOnly {
+			t.Errorf("%q: expected read only: %v, got: %v", tc.query, tc.readOnly, readOnly)
+		}
+	}
+}
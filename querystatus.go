@@ -0,0 +1,138 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"time"
+)
+
+// QueryStatus is the status of a query reported by Snowflake. The queued times tell how long the query waited
+// for the warehouse, so that autoscaling controllers can react when queries queue on an overloaded warehouse.
+type QueryStatus struct {
+	QueryID       string
+	Status        string // e.g., QUEUED, RUNNING, SUCCESS or FAILED_WITH_ERROR
+	SQLText       string
+	WarehouseName string
+	WarehouseSize string // e.g., X-Small, if reported
+	StartTime     time.Time
+	EndTime       time.Time // zero while the query is running
+	// TotalElapsed is the time the query has taken so far, including the time in the queues.
+	TotalElapsed time.Duration
+	// Running is true until the query completes.
+	Running bool
+	// Err is the error of the query if it completed without success.
+	Err error
+	// QueuedProvisioningTime is the time spent waiting for the warehouse to be provisioned, e.g., resumed.
+	QueuedProvisioningTime time.Duration
+	// QueuedRepairTime is the time spent waiting for the warehouse to be repaired.
+	QueuedRepairTime time.Duration
+	// QueuedOverloadTime is the time spent waiting because the warehouse was overloaded by other queries.
+	QueuedOverloadTime time.Duration
+	// LoadPercent is the approximate percentage of the warehouse compute used by the query, if reported.
+	LoadPercent int64
+	// ScanBytes is the number of bytes scanned by the query so far.
+	ScanBytes int64
+	// ProducedRows is the number of rows produced by the query so far.
+	ProducedRows int64
+	// PartitionsScanned is the number of micro-partitions scanned by the query so far.
+	PartitionsScanned int64
+	// PartitionsTotal is the number of micro-partitions of the tables the query scans, before pruning.
+	PartitionsTotal int64
+	// PollingTime is how long the connection has been polling the result of the query, if it waits for the
+	// query to complete. Zero otherwise.
+	PollingTime time.Duration
+}
+
+// QueuedTime returns the total time the query spent in the queues.
+func (qs *QueryStatus) QueuedTime() time.Duration {
+	return qs.QueuedProvisioningTime + qs.QueuedRepairTime + qs.QueuedOverloadTime
+}
+
+// warehouseCreditsPerHour is the number of credits a standard warehouse of a size consumes per hour, by the
+// size in upper case without dashes and spaces.
+var warehouseCreditsPerHour = map[string]float64{
+	"XSMALL":   1,
+	"SMALL":    2,
+	"MEDIUM":   4,
+	"LARGE":    8,
+	"XLARGE":   16,
+	"2XLARGE":  32,
+	"XXLARGE":  32,
+	"3XLARGE":  64,
+	"XXXLARGE": 64,
+	"4XLARGE":  128,
+	"X4LARGE":  128,
+	"5XLARGE":  256,
+	"X5LARGE":  256,
+	"6XLARGE":  512,
+	"X6LARGE":  512,
+}
+
+// EstimatedCredits returns the approximate number of credits the query has consumed so far: the credits per hour
+// of a standard warehouse of WarehouseSize over the time the query ran out of the queues, scaled by LoadPercent if
+// it is reported. It is 0 if the size is unknown, e.g., for a query that ran on no warehouse. Snowflake bills the
+// warehouses, not the queries, so that the estimate is for comparing queries, e.g., in a cost-aware scheduler, and
+// doesn't add up to the bill.
+func (qs *QueryStatus) EstimatedCredits() float64 {
+	size := strings.NewReplacer("-", "", " ", "", "_", "").Replace(strings.ToUpper(qs.WarehouseSize))
+	rate, ok := warehouseCreditsPerHour[size]
+	if !ok {
+		return 0
+	}
+	running := qs.TotalElapsed - qs.QueuedTime()
+	if running <= 0 {
+		return 0
+	}
+	credits := rate * running.Hours()
+	if qs.LoadPercent > 0 {
+		credits *= float64(qs.LoadPercent) / 100
+	}
+	return credits
+}
+
+// QueryStatus returns the status of the query, which may have been run by any session of the user.
+func (sc *snowflakeConn) QueryStatus(ctx context.Context, queryID string) (*QueryStatus, error) {
+	if sc.rest == nil {
+		return nil, driver.ErrBadConn
+	}
+	mq, err := sc.getQueryStatus(ctx, queryID)
+	if err != nil {
+		return nil, err
+	}
+	qs := newQueryStatus(queryID, mq)
+	qs.PollingTime = sc.rest.pollingTime(queryID)
+	return qs, nil
+}
+
+func newQueryStatus(queryID string, mq *monitoringQuery) *QueryStatus {
+	qs := &QueryStatus{
+		QueryID:                queryID,
+		Status:                 mq.Status,
+		SQLText:                mq.SQLText,
+		WarehouseName:          mq.WarehouseName,
+		WarehouseSize:          mq.WarehouseSize,
+		TotalElapsed:           time.Duration(mq.TotalDuration) * time.Millisecond,
+		Running:                isQueryRunning(mq.Status),
+		QueuedProvisioningTime: time.Duration(mq.Stats.QueuedProvisioningTime) * time.Millisecond,
+		QueuedRepairTime:       time.Duration(mq.Stats.QueuedRepairTime) * time.Millisecond,
+		QueuedOverloadTime:     time.Duration(mq.Stats.QueuedOverloadTime) * time.Millisecond,
+		LoadPercent:            mq.QueryLoadPercent,
+		ScanBytes:              mq.Stats.ScanBytes,
+		ProducedRows:           mq.Stats.ProducedRows,
+		PartitionsScanned:      mq.Stats.ScanAssignedPartitions,
+		PartitionsTotal:        mq.Stats.ScanOriginalPartitions,
+	}
+	if mq.StartTime > 0 {
+		qs.StartTime = time.Unix(0, mq.StartTime*int64(time.Millisecond))
+	}
+	if mq.EndTime > 0 {
+		qs.EndTime = time.Unix(0, mq.EndTime*int64(time.Millisecond))
+	}
+	if !qs.Running {
+		qs.Err = mq.err()
+	}
+	return qs
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket limiting the rate of the query submissions and the result polls sent to
+// Snowflake, so that a fleet of clients backs off before Snowflake throttles it with HTTP 429. A RateLimiter is
+// safe for concurrent use and may be shared by many connections through Config.RateLimiter. The logins, the
+// heartbeats and the result chunk downloads are not limited.
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // tokens added per second
+	burst  float64 // capacity of the bucket
+	tokens float64 // negative if the requests are waiting
+	last   time.Time
+}
+
+// NewRateLimiter creates a RateLimiter that allows rate requests per second on average and bursts of up to
+// burst requests. A burst less than 1 is taken as 1. A rate of 0 or less doesn't limit the requests.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	b := math.Max(float64(burst), 1)
+	return &RateLimiter{
+		rate:   rate,
+		burst:  b,
+		tokens: b,
+		last:   time.Now(),
+	}
+}
+
+// Rate returns the average number of requests allowed per second.
+func (l *RateLimiter) Rate() float64 {
+	return l.rate
+}
+
+// Burst returns the maximum number of requests allowed at once.
+func (l *RateLimiter) Burst() int {
+	return int(l.burst)
+}
+
+// Wait blocks until a request is allowed or the context is done, in which case the context error is returned.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if l.rate <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens--
+	if l.tokens >= 0 {
+		l.mu.Unlock()
+		return nil
+	}
+	// the token is reserved, so that the waiting requests are served in order
+	wait := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+
+	await := time.NewTimer(wait)
+	defer await.Stop()
+	select {
+	case <-await.C:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// waitRateLimit waits for the RateLimiter of the connection, if any, before a query submission or a result poll.
+func (sr *snowflakeRestful) waitRateLimit(ctx context.Context) error {
+	if sr.RateLimiter == nil {
+		return nil
+	}
+	return sr.RateLimiter.Wait(ctx)
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := NewRateLimiter(20, 2)
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatalf("failed to wait. err: %v", err)
+		}
+	}
+	// the burst of 2 is immediate and the next 2 wait 50ms each
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond || elapsed > time.Second {
+		t.Fatalf("unexpected wait: %v", elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	l = NewRateLimiter(0.1, 1)
+	if err := l.Wait(ctx); err != nil {
+		t.Fatalf("the first request should be allowed. err: %v", err)
+	}
+	if err := l.Wait(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("should fail with the context error. err: %v", err)
+	}
+	if l.tokens < -1e-3 || l.tokens > 1e-3 {
+		t.Fatalf("the token of the canceled request should be returned. tokens: %v", l.tokens)
+	}
+
+	if err := NewRateLimiter(0, 0).Wait(ctx); err != nil {
+		t.Fatalf("a zero rate should not limit. err: %v", err)
+	}
+}
+
+func TestRateLimitQuerySubmission(t *testing.T) {
+	posted := 0
+	sc := &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{
+			RateLimiter: NewRateLimiter(0.1, 1),
+			FuncPostQuery: func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, _ []byte, _ time.Duration, _ *uuid.UUID) (*execResponse, error) {
+				posted++
+				return &execResponse{Data: execResponseData{QueryID: "q"}, Code: "0", Success: true}, nil
+			},
+		},
+	}
+	if _, err := sc.ExecContext(context.Background(), "SELECT 1", nil); err != nil {
+		t.Fatalf("failed to exec. err: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := sc.ExecContext(ctx, "SELECT 1", nil); err != context.DeadlineExceeded {
+		t.Fatalf("should fail waiting for the rate limiter. err: %v", err)
+	}
+	if posted != 1 {
+		t.Fatalf("the second query should not be posted. posted: %v", posted)
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// maxClientRedirects is the maximum number of redirects followed at login.
+const maxClientRedirects = 3
+
+// ClientRedirect is a redirect of the login to another Snowflake deployment, e.g., the primary deployment
+// after a failover of the account with Client Redirect.
+type ClientRedirect struct {
+	From string // URL the login was sent to
+	To   string // URL the login was redirected to
+}
+
+// ClientRedirectListener is called when the login is redirected to another Snowflake deployment.
+type ClientRedirectListener func(ClientRedirect)
+
+// clientRedirectError is returned by the login when Snowflake redirects it to another deployment.
+type clientRedirectError struct {
+	location *url.URL
+}
+
+func (e *clientRedirectError) Error() string {
+	return "login was redirected to " + e.location.String()
+}
+
+// checkRedirect stops the HTTP client from following the redirects of the login, so that the driver
+// validates the new URL and logs in to it again. Other requests follow redirects as usual.
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if noFollow, _ := req.Context().Value(noFollowRedirect).(bool); noFollow {
+		return http.ErrUseLastResponse
+	}
+	if len(via) >= 10 {
+		return errTooManyRedirects
+	}
+	return nil
+}
+
+var errTooManyRedirects = &SnowflakeError{
+	Number:      ErrCodeInvalidClientRedirect,
+	Message:     errMsgInvalidClientRedirect,
+	MessageArgs: []interface{}{"", "too many redirects"},
+}
+
+func isRedirectStatus(code int) bool {
+	return code == http.StatusTemporaryRedirect || code == http.StatusPermanentRedirect
+}
+
+// newClientRedirectError returns the error for a redirect response of the login.
+func newClientRedirectError(resp *http.Response, fullURL *url.URL) error {
+	location, err := fullURL.Parse(resp.Header.Get("Location"))
+	if err != nil || location.Host == "" {
+		return &SnowflakeError{
+			Number:      ErrCodeInvalidClientRedirect,
+			SQLState:    SQLStateConnectionRejected,
+			Message:     errMsgInvalidClientRedirect,
+			MessageArgs: []interface{}{resp.Header.Get("Location"), "invalid URL"},
+		}
+	}
+	return &clientRedirectError{location: location}
+}
+
+// loginFollowingRedirects logs in and, if Snowflake redirects the login to another deployment, logs in to
+// the new deployment. The connection uses the new deployment afterwards.
+func (sc *snowflakeConn) loginFollowingRedirects(ctx context.Context) (*authResponseMain, error) {
+	for redirects := 0; ; redirects++ {
+		authData, err := authenticateWithBackoff(ctx, sc)
+		re, ok := err.(*clientRedirectError)
+		if !ok {
+			return authData, err
+		}
+		if redirects >= maxClientRedirects {
+			return nil, &SnowflakeError{
+				Number:      ErrCodeInvalidClientRedirect,
+				SQLState:    SQLStateConnectionRejected,
+				Message:     errMsgInvalidClientRedirect,
+				MessageArgs: []interface{}{re.location, "too many redirects"},
+			}
+		}
+		if err = sc.redirect(re.location); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// redirect points the connection to the location. Only the Snowflake domain is accepted, and HTTPS
+// unless the connection already uses HTTP, as the login is sent with the credentials.
+func (sc *snowflakeConn) redirect(location *url.URL) error {
+	reject := func(reason string) error {
+		return &SnowflakeError{
+			Number:      ErrCodeInvalidClientRedirect,
+			SQLState:    SQLStateConnectionRejected,
+			Message:     errMsgInvalidClientRedirect,
+			MessageArgs: []interface{}{location, reason},
+		}
+	}
+	host := location.Hostname()
+	if !strings.HasSuffix(host, defaultDomain) && host != sc.cfg.Host {
+		return reject("the host is not a Snowflake host")
+	}
+	if location.Scheme != "https" && location.Scheme != sc.cfg.Protocol {
+		return reject("the protocol is not HTTPS")
+	}
+	port := 443
+	if location.Port() != "" {
+		var err error
+		if port, err = strconv.Atoi(location.Port()); err != nil {
+			return reject("invalid port")
+		}
+	}
+	from := sc.rest.getURL().String()
+	glog.V(1).Infof("login was redirected from %v to %v", from, location)
+	sc.cfg.Host, sc.cfg.Port, sc.cfg.Protocol = host, port, location.Scheme
+	sc.rest.Host, sc.rest.Port, sc.rest.Protocol = host, port, location.Scheme
+	if fn := sc.cfg.ClientRedirectListener; fn != nil {
+		fn(ClientRedirect{From: from, To: sc.rest.getURL().String()})
+	}
+	return nil
+}
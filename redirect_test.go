@@ -0,0 +1,124 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func postTestRedirect(_ context.Context, _ *snowflakeRestful, _ *url.URL, _ map[string]string, _ []byte, _ time.Duration, _ bool) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusTemporaryRedirect,
+		Header:     http.Header{"Location": []string{"https://b.snowflakecomputing.com:443/session/v1/login-request"}},
+		Body:       &fakeResponseBody{},
+	}, nil
+}
+
+func TestUnitPostAuthRedirect(t *testing.T) {
+	sr := &snowflakeRestful{
+		Protocol: "https",
+		Host:     "a.snowflakecomputing.com",
+		Port:     443,
+		FuncPost: postTestRedirect,
+	}
+	_, err := postAuth(context.TODO(), sr, &url.Values{}, make(map[string]string), []byte{}, 0)
+	re, ok := err.(*clientRedirectError)
+	if !ok {
+		t.Fatalf("should have failed with clientRedirectError. err: %v", err)
+	}
+	if re.location.Host != "b.snowflakecomputing.com:443" {
+		t.Fatalf("unexpected location: %v", re.location)
+	}
+}
+
+func TestUnitLoginFollowingRedirects(t *testing.T) {
+	var hosts []string
+	sc := getDefaultSnowflakeConn()
+	sc.cfg.Host, sc.cfg.Port, sc.cfg.Protocol = "a.snowflakecomputing.com", 443, "https"
+	var redirects []ClientRedirect
+	sc.cfg.ClientRedirectListener = func(r ClientRedirect) {
+		redirects = append(redirects, r)
+	}
+	sc.rest = &snowflakeRestful{
+		Host:     sc.cfg.Host,
+		Port:     sc.cfg.Port,
+		Protocol: sc.cfg.Protocol,
+		FuncPostAuth: func(_ context.Context, sr *snowflakeRestful, _ *url.Values, _ map[string]string, _ []byte, _ time.Duration) (*authResponse, error) {
+			hosts = append(hosts, sr.Host)
+			if sr.Host == "a.snowflakecomputing.com" {
+				location, _ := url.Parse("https://b.snowflakecomputing.com/session/v1/login-request")
+				return nil, &clientRedirectError{location: location}
+			}
+			return &authResponse{
+				Success: true,
+				Data:    authResponseMain{Token: "t", MasterToken: "m"},
+			}, nil
+		},
+	}
+	if _, err := sc.loginFollowingRedirects(context.TODO()); err != nil {
+		t.Fatalf("failed to log in. err: %v", err)
+	}
+	if len(hosts) != 2 || hosts[1] != "b.snowflakecomputing.com" || sc.cfg.Host != "b.snowflakecomputing.com" {
+		t.Fatalf("the login should have been sent to the new host. hosts: %v", hosts)
+	}
+	if len(redirects) != 1 || redirects[0].From != "https://a.snowflakecomputing.com:443" ||
+		redirects[0].To != "https://b.snowflakecomputing.com:443" {
+		t.Fatalf("unexpected redirects: %+v", redirects)
+	}
+}
+
+func TestUnitRedirectRejected(t *testing.T) {
+	sc := getDefaultSnowflakeConn()
+	sc.cfg.Host, sc.cfg.Port, sc.cfg.Protocol = "a.snowflakecomputing.com", 443, "https"
+	sc.rest = &snowflakeRestful{Host: sc.cfg.Host, Port: sc.cfg.Port, Protocol: sc.cfg.Protocol}
+	for _, location := range []string{
+		"https://evil.example.com/session/v1/login-request",
+		"http://b.snowflakecomputing.com/session/v1/login-request",
+	} {
+		u, _ := url.Parse(location)
+		err := sc.redirect(u)
+		if driverErr, ok := err.(*SnowflakeError); !ok || driverErr.Number != ErrCodeInvalidClientRedirect {
+			t.Errorf("the redirect to %v should have been rejected. err: %v", location, err)
+		}
+	}
+	if sc.rest.Host != "a.snowflakecomputing.com" {
+		t.Fatalf("the host should not change. got: %v", sc.rest.Host)
+	}
+}
+
+func TestUnitGetResultURL(t *testing.T) {
+	sr := &snowflakeRestful{Protocol: "https", Host: "a.snowflakecomputing.com", Port: 443}
+	params := &url.Values{}
+	params.Add(requestIDKey, "rid")
+	testcases := []struct {
+		path string
+		url  string
+		ok   bool
+	}{
+		{"/queries/q/result", "https://a.snowflakecomputing.com:443/queries/q/result?requestId=rid", true},
+		{"/api/v2/statements/h?x=1", "https://a.snowflakecomputing.com:443/api/v2/statements/h?requestId=rid&x=1", true},
+		{"https://a.snowflakecomputing.com/queries/q/result?x=1",
+			"https://a.snowflakecomputing.com/queries/q/result?requestId=rid&x=1", true},
+		{"https://a.us-east-1.snowflakecomputing.com/queries/q/result", "", false},
+		{"https://evil.example.com/queries/q/result", "", false},
+		{"http://a.snowflakecomputing.com/queries/q/result", "", false},
+	}
+	for _, tc := range testcases {
+		u, err := sr.getResultURL(tc.path, params)
+		if !tc.ok {
+			if driverErr, ok := err.(*SnowflakeError); !ok || driverErr.Number != ErrCodeInvalidResultURL {
+				t.Errorf("%v: should have been rejected. err: %v", tc.path, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: failed to get the result URL. err: %v", tc.path, err)
+		} else if u.String() != tc.url {
+			t.Errorf("%v: expected: %v, got: %v", tc.path, tc.url, u)
+		}
+	}
+}
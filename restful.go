@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -50,10 +51,17 @@ type snowflakeRestful struct {
 	RequestTimeout time.Duration // request timeout
 
 	Client      *http.Client
+	UserAgent   string
 	Token       string
 	MasterToken string
 	SessionID   int
 	HeartBeat   *heartbeat
+	RateLimiter *RateLimiter // limits the query submissions and the result polls. nil if unlimited
+	polling     sync.Map     // query ID -> time.Time when the connection started to poll the result
+
+	expiryMu          sync.Mutex
+	tokenExpiry       time.Time // when the session token expires. zero if unknown
+	masterTokenExpiry time.Time // when the master token expires, after which the session can't be renewed. zero if unknown
 
 	Connection          *snowflakeConn
 	FuncPostQuery       func(context.Context, *snowflakeRestful, *url.Values, map[string]string, []byte, time.Duration, *uuid.UUID) (*execResponse, error)
@@ -70,6 +78,29 @@ type snowflakeRestful struct {
 	FuncGetSSO       func(context.Context, *snowflakeRestful, *url.Values, map[string]string, string, time.Duration) ([]byte, error)
 }
 
+// setTokenExpiry sets the expirations of the tokens from their validities in seconds returned by Snowflake. A
+// validity of zero leaves the expiration unknown.
+func (sr *snowflakeRestful) setTokenExpiry(validity time.Duration, masterValidity time.Duration) {
+	now := time.Now()
+	sr.expiryMu.Lock()
+	defer sr.expiryMu.Unlock()
+	sr.tokenExpiry = time.Time{}
+	if validity > 0 {
+		sr.tokenExpiry = now.Add(validity * time.Second)
+	}
+	sr.masterTokenExpiry = time.Time{}
+	if masterValidity > 0 {
+		sr.masterTokenExpiry = now.Add(masterValidity * time.Second)
+	}
+}
+
+// getTokenExpiry returns the expirations of the session token and the master token.
+func (sr *snowflakeRestful) getTokenExpiry() (time.Time, time.Time) {
+	sr.expiryMu.Lock()
+	defer sr.expiryMu.Unlock()
+	return sr.tokenExpiry, sr.masterTokenExpiry
+}
+
 func (sr *snowflakeRestful) getURL() *url.URL {
 	return &url.URL{
 		Scheme: sr.Protocol,
@@ -159,6 +190,14 @@ func postRestfulQuery(
 	return nil, ctx.Err()
 }
 
+// getUserAgent returns the User-Agent of the connection.
+func (sr *snowflakeRestful) getUserAgent() string {
+	if sr.UserAgent != "" {
+		return sr.UserAgent
+	}
+	return userAgent
+}
+
 func postRestfulQueryHelper(
 	ctx context.Context,
 	sr *snowflakeRestful,
@@ -168,7 +207,8 @@ func postRestfulQueryHelper(
 	timeout time.Duration,
 	requestID *uuid.UUID) (
 	data *execResponse, err error) {
-	glog.V(2).Infof("params: %v", params)
+	ql := queryLogFrom(ctx)
+	glog.V(2).Infof("params: %v. %v", params, ql)
 	params.Add(requestIDKey, requestID.String())
 	params.Add("clientStartTime", strconv.FormatInt(time.Now().Unix(), 10))
 	params.Add(requestGUIDKey, uuid.New().String())
@@ -180,13 +220,13 @@ func postRestfulQueryHelper(
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	defer drainAndClose(resp.Body)
 	if resp.StatusCode == http.StatusOK {
-		glog.V(2).Infof("postQuery: resp: %v", resp)
+		glog.V(2).Infof("postQuery: resp: %v. %v", resp, ql)
 		var respd execResponse
-		err = json.NewDecoder(resp.Body).Decode(&respd)
+		err = decodeExecResponse(ctx, resp.Body, &respd)
 		if err != nil {
-			glog.V(1).Infof("failed to decode JSON. err: %v", err)
+			glog.V(1).Infof("failed to decode JSON. %v, err: %v", ql, err)
 			glog.Flush()
 			return nil, err
 		}
@@ -198,31 +238,54 @@ func postRestfulQueryHelper(
 			return sr.FuncPostQuery(ctx, sr, params, headers, body, timeout, requestID)
 		}
 
+		if respd.Code == queryInProgressAsyncCode && isAsyncMode(ctx) {
+			// the caller polls the status of the asynchronous query
+			return &respd, nil
+		}
+
 		var resultURL string
 		isSessionRenewed := false
+		if respd.Code == queryInProgressCode || respd.Code == queryInProgressAsyncCode {
+			// the query ID is known before the query completes
+			ql.setQueryID(respd.Data.QueryID)
+			sendQueryID(ctx, respd.Data.QueryID)
+			defer sr.startPolling(respd.Data.QueryID)()
+		}
+		poller := newQueryPoller(queryPollInterval)
 
-		for isSessionRenewed || respd.Code == queryInProgressCode ||
-			respd.Code == queryInProgressAsyncCode {
+		for polls := 0; isSessionRenewed || respd.Code == queryInProgressCode ||
+			respd.Code == queryInProgressAsyncCode; polls++ {
 			if !isSessionRenewed {
 				resultURL = respd.Data.GetResultURL
+				if polls > 0 {
+					if err = poller.wait(ctx); err != nil {
+						return nil, err
+					}
+				}
 			}
 
-			glog.V(2).Info("ping pong")
+			glog.V(2).Infof("ping pong. %v", ql)
 			glog.Flush()
 			headers[headerAuthorizationKey] = fmt.Sprintf(headerSnowflakeToken, sr.Token)
-			fullURL := sr.getFullURL(resultURL, nil)
+			fullURL, err := sr.getResultURL(resultURL, nil)
+			if err != nil {
+				return nil, err
+			}
 
+			if err = sr.waitRateLimit(ctx); err != nil {
+				return nil, err
+			}
 			resp, err = sr.FuncGet(ctx, sr, fullURL, headers, timeout)
 			if err != nil {
-				glog.V(1).Infof("failed to get response. err: %v", err)
+				glog.V(1).Infof("failed to get response. %v, err: %v", ql, err)
 				glog.Flush()
 				return nil, err
 			}
 			respd = execResponse{} // reset the response
-			err = json.NewDecoder(resp.Body).Decode(&respd)
-			resp.Body.Close()
+			err = decodeExecResponse(ctx, resp.Body, &respd)
+			drainAndClose(resp.Body)
 			if err != nil {
-				glog.V(1).Infof("failed to decode JSON. err: %v", err)
+				glog.V(1).Infof("failed to decode JSON. %v, err: %v", ql, err)
 				glog.Flush()
 				return nil, err
 			}
@@ -238,12 +301,15 @@ func postRestfulQueryHelper(
 		}
 		return &respd, nil
 	}
+	if resp.StatusCode == http.StatusRequestEntityTooLarge {
+		return nil, errRequestTooLarge(len(body), "Snowflake's request limit")
+	}
 	b, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		glog.V(1).Infof("failed to extract HTTP response body. err: %v", err)
 		return nil, err
 	}
-	glog.V(1).Infof("HTTP: %v, URL: %v, Body: %v", resp.StatusCode, fullURL, b)
+	glog.V(1).Infof("HTTP: %v, URL: %v, Body: %v. %v", resp.StatusCode, fullURL, b, ql)
 	glog.V(1).Infof("Header: %v", resp.Header)
 	glog.Flush()
 	return nil, &SnowflakeError{
@@ -265,14 +331,14 @@ func closeSession(ctx context.Context, sr *snowflakeRestful, timeout time.Durati
 	headers := make(map[string]string)
 	headers["Content-Type"] = headerContentTypeApplicationJSON
 	headers["accept"] = headerAcceptTypeApplicationSnowflake
-	headers["User-Agent"] = userAgent
+	headers["User-Agent"] = sr.getUserAgent()
 	headers[headerAuthorizationKey] = fmt.Sprintf(headerSnowflakeToken, sr.Token)
 
 	resp, err := sr.FuncPost(ctx, sr, fullURL, headers, nil, 5*time.Second, false)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
+	defer drainAndClose(resp.Body)
 	if resp.StatusCode == http.StatusOK {
 		var respd renewSessionResponse
 		err = json.NewDecoder(resp.Body).Decode(&respd)
@@ -320,7 +386,7 @@ func renewRestfulSession(ctx context.Context, sr *snowflakeRestful, timeout time
 	headers := make(map[string]string)
 	headers["Content-Type"] = headerContentTypeApplicationJSON
 	headers["accept"] = headerAcceptTypeApplicationSnowflake
-	headers["User-Agent"] = userAgent
+	headers["User-Agent"] = sr.getUserAgent()
 	headers[headerAuthorizationKey] = fmt.Sprintf(headerSnowflakeToken, sr.MasterToken)
 
 	body := make(map[string]string)
@@ -337,7 +403,7 @@ func renewRestfulSession(ctx context.Context, sr *snowflakeRestful, timeout time
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
+	defer drainAndClose(resp.Body)
 	if resp.StatusCode == http.StatusOK {
 		var respd renewSessionResponse
 		err = json.NewDecoder(resp.Body).Decode(&respd)
@@ -358,6 +424,7 @@ func renewRestfulSession(ctx context.Context, sr *snowflakeRestful, timeout time
 		}
 		sr.Token = respd.Data.SessionToken
 		sr.MasterToken = respd.Data.MasterToken
+		sr.setTokenExpiry(respd.Data.ValidityInSecondsST, respd.Data.ValidityInSecondsMT)
 		return nil
 	}
 	b, err := ioutil.ReadAll(resp.Body)
@@ -388,7 +455,7 @@ func cancelQuery(ctx context.Context, sr *snowflakeRestful, requestID *uuid.UUID
 	headers := make(map[string]string)
 	headers["Content-Type"] = headerContentTypeApplicationJSON
 	headers["accept"] = headerAcceptTypeApplicationSnowflake
-	headers["User-Agent"] = userAgent
+	headers["User-Agent"] = sr.getUserAgent()
 	headers[headerAuthorizationKey] = fmt.Sprintf(headerSnowflakeToken, sr.Token)
 
 	req := make(map[string]string)
@@ -403,7 +470,7 @@ func cancelQuery(ctx context.Context, sr *snowflakeRestful, requestID *uuid.UUID
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
+	defer drainAndClose(resp.Body)
 	if resp.StatusCode == http.StatusOK {
 		var respd cancelQueryResponse
 		err = json.NewDecoder(resp.Body).Decode(&respd)
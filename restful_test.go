@@ -113,6 +113,95 @@ func TestUnitPostQueryHelperError(t *testing.T) {
 	}
 }
 
+func TestUnitPostQueryHelperTooLarge(t *testing.T) {
+	sr := &snowflakeRestful{
+		Token: "token",
+		FuncPost: func(_ context.Context, _ *snowflakeRestful, _ *url.URL, _ map[string]string, _ []byte, _ time.Duration, _ bool) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusRequestEntityTooLarge,
+				Body:       &fakeResponseBody{body: []byte{0x12, 0x34}},
+			}, nil
+		},
+	}
+	requestID := uuid.New()
+	_, err := postRestfulQueryHelper(context.Background(), sr, &url.Values{}, make(map[string]string), []byte{0x12, 0x34}, 0, &requestID)
+	if driverErr, ok := err.(*SnowflakeError); !ok || driverErr.Number != ErrCodeRequestTooLarge {
+		t.Fatalf("should fail with ErrCodeRequestTooLarge. err: %v", err)
+	}
+}
+
+func TestUnitPostQueryHelperPolling(t *testing.T) {
+	origInterval := queryPollInterval
+	queryPollInterval = time.Millisecond
+	defer func() { queryPollInterval = origInterval }()
+
+	inProgress := []byte(`{"code":"333333","success":true,"data":{"queryId":"q","getResultUrl":"/queries/q/result"}}`)
+	polls := 0
+	var pollingTime time.Duration
+	sr := &snowflakeRestful{
+		Token: "token",
+		FuncPost: func(_ context.Context, _ *snowflakeRestful, _ *url.URL, _ map[string]string, _ []byte, _ time.Duration, _ bool) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Body: &fakeResponseBody{body: inProgress}}, nil
+		},
+	}
+	sr.FuncGet = func(_ context.Context, _ *snowflakeRestful, u *url.URL, _ map[string]string, _ time.Duration) (*http.Response, error) {
+		if u.Path != "/queries/q/result" {
+			t.Errorf("unexpected result path: %v", u.Path)
+		}
+		polls++
+		pollingTime = sr.pollingTime("q")
+		if polls < 4 {
+			return &http.Response{StatusCode: http.StatusOK, Body: &fakeResponseBody{body: inProgress}}, nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: &fakeResponseBody{body: []byte(`{"code":"0","success":true,"data":{"queryId":"q"}}`)}}, nil
+	}
+	requestID := uuid.New()
+	data, err := postRestfulQueryHelper(context.Background(), sr, &url.Values{}, make(map[string]string), []byte{0x12, 0x34}, 0, &requestID)
+	if err != nil || !data.Success {
+		t.Fatalf("failed to poll the result. err: %v", err)
+	}
+	if polls != 4 {
+		t.Fatalf("unexpected number of polls: %v", polls)
+	}
+	// 1ms + 2ms + 4ms of backoff before the last poll
+	if pollingTime < 7*time.Millisecond {
+		t.Fatalf("the polling time should have been reported while polling. got: %v", pollingTime)
+	}
+	if d := sr.pollingTime("q"); d != 0 {
+		t.Fatalf("the polling time should be reset once the result is returned. got: %v", d)
+	}
+
+	polls = 0
+	ctx, cancel := context.WithCancel(context.Background())
+	sr.FuncGet = func(_ context.Context, _ *snowflakeRestful, _ *url.URL, _ map[string]string, _ time.Duration) (*http.Response, error) {
+		if polls++; polls == 2 {
+			cancel()
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: &fakeResponseBody{body: inProgress}}, nil
+	}
+	if _, err = postRestfulQueryHelper(ctx, sr, &url.Values{}, make(map[string]string), []byte{0x12, 0x34}, 0, &requestID); err != context.Canceled {
+		t.Fatalf("the polling should stop when the context is canceled. err: %v", err)
+	}
+	if polls != 2 {
+		t.Fatalf("unexpected number of polls: %v", polls)
+	}
+}
+
+func BenchmarkPostQueryHelper(b *testing.B) {
+	sr := &snowflakeRestful{
+		Token:    "token",
+		FuncPost: postTestAfterRenew,
+	}
+	body := []byte(`{"sqlText":"SELECT 1"}`)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		requestID := uuid.New()
+		if _, err := postRestfulQueryHelper(context.Background(), sr, &url.Values{}, make(map[string]string), body, 0, &requestID); err != nil {
+			b.Fatalf("failed to post. err: %v", err)
+		}
+	}
+}
+
 func renewSessionTest(_ context.Context, _ *snowflakeRestful, _ time.Duration) error {
 	return nil
 }
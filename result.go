@@ -2,15 +2,86 @@
 
 package gosnowflake
 
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"strings"
+)
+
 // SnowflakeResult provides the associated query ID
 type SnowflakeResult interface {
 	QueryID() string
 }
 
+// DMLRowCounts is the number of rows changed by a DML statement, broken down by the type of change.
+// A MERGE statement may insert, update and delete rows at the same time. Unloaded and UnloadedBytes are
+// the number of rows and bytes written by COPY INTO location.
+type DMLRowCounts struct {
+	Inserted      int64
+	Updated       int64
+	Deleted       int64
+	Unloaded      int64
+	UnloadedBytes int64
+}
+
+// Total returns the total number of rows changed or unloaded.
+func (c DMLRowCounts) Total() int64 {
+	return c.Inserted + c.Updated + c.Deleted + c.Unloaded
+}
+
+func (c DMLRowCounts) add(o DMLRowCounts) DMLRowCounts {
+	return DMLRowCounts{
+		Inserted:      c.Inserted + o.Inserted,
+		Updated:       c.Updated + o.Updated,
+		Deleted:       c.Deleted + o.Deleted,
+		Unloaded:      c.Unloaded + o.Unloaded,
+		UnloadedBytes: c.UnloadedBytes + o.UnloadedBytes,
+	}
+}
+
+// SnowflakeDMLResult provides the number of rows changed by a DML statement per type of change,
+// in addition to the associated query ID. The result of ExecContext implements it.
+type SnowflakeDMLResult interface {
+	SnowflakeResult
+	RowCounts() DMLRowCounts
+}
+
+// SnowflakeDDLResult provides the status message Snowflake returns for a statement that is not a DML statement, e.g.,
+// "Table T successfully created." for CREATE TABLE or CREATE TABLE AS SELECT. The result of ExecContext for a DDL
+// statement implements it, while the other statements that are not DML statements, e.g., a query, have
+// driver.ResultNoRows. RowCounts has the rows inserted if the status row has them, in which case RowsAffected
+// returns their total, and otherwise RowsAffected fails as for any DDL statement.
+type SnowflakeDDLResult interface {
+	SnowflakeDMLResult
+	Status() string
+}
+
+// SnowflakeRows provides the number of rows of a query result before the rows are read, e.g., to show the size of
+// the result before paginating through it. The rows returned by QueryContext, and the ResultSet returned by
+// QueryResultSet, implement it. The counts are those of the current result set of a multi-statement query, and are
+// not limited by WithMaxResultRows.
+type SnowflakeRows interface {
+	SnowflakeResult
+	// TotalRows returns the number of rows of the result set.
+	TotalRows() int64
+	// ChunkRowCounts returns the number of rows of every chunk of the result set in order, the first of which
+	// is the rows returned with the query response.
+	ChunkRowCounts() []int
+}
+
 type snowflakeResult struct {
 	affectedRows int64
 	insertID     int64 // Snowflake doesn't support last insert id
 	queryID      string
+	rowCounts    DMLRowCounts
+	extensions   map[string]json.RawMessage
+	children     []childStatementResult // results of the statements of a multi-statement query
+}
+
+// childStatementResult is the result of a statement of a multi-statement query.
+type childStatementResult struct {
+	queryID   string
+	rowCounts DMLRowCounts
 }
 
 func (res *snowflakeResult) LastInsertId() (int64, error) {
@@ -24,3 +95,75 @@ func (res *snowflakeResult) RowsAffected() (int64, error) {
 func (res *snowflakeResult) QueryID() string {
 	return res.queryID
 }
+
+func (res *snowflakeResult) RowCounts() DMLRowCounts {
+	return res.rowCounts
+}
+
+func (res *snowflakeResult) Extensions() map[string]json.RawMessage {
+	return res.extensions
+}
+
+// snowflakeDDLResult is the result of a DDL statement with its status row.
+type snowflakeDDLResult struct {
+	queryID      string
+	status       string
+	rowCounts    DMLRowCounts
+	hasRowCounts bool
+	extensions   map[string]json.RawMessage
+}
+
+// newDDLResult parses the status row of the result, whose "status" column has the message of Snowflake and whose
+// "number of rows ..." columns, if any, have the row counts.
+func newDDLResult(data execResponseData) *snowflakeDDLResult {
+	res := &snowflakeDDLResult{queryID: data.QueryID, extensions: data.Extensions}
+	if len(data.RowSet) == 0 {
+		return res
+	}
+	counts := execResponseData{StatementTypeID: data.StatementTypeID, RowSet: [][]*string{nil}}
+	for i, v := range data.RowSet[0] {
+		if i >= len(data.RowType) || v == nil {
+			continue
+		}
+		switch name := strings.ToLower(data.RowType[i].Name); {
+		case name == "status":
+			res.status = *v
+		case strings.HasPrefix(name, "number of rows "):
+			counts.RowType = append(counts.RowType, data.RowType[i])
+			counts.RowSet[0] = append(counts.RowSet[0], v)
+		}
+	}
+	if len(counts.RowType) > 0 {
+		if rowCounts, err := dmlRowCounts(counts); err == nil {
+			res.rowCounts, res.hasRowCounts = rowCounts, true
+		}
+	}
+	return res
+}
+
+func (res *snowflakeDDLResult) LastInsertId() (int64, error) {
+	return driver.ResultNoRows.LastInsertId()
+}
+
+func (res *snowflakeDDLResult) RowsAffected() (int64, error) {
+	if !res.hasRowCounts {
+		return driver.ResultNoRows.RowsAffected()
+	}
+	return res.rowCounts.Total(), nil
+}
+
+func (res *snowflakeDDLResult) QueryID() string {
+	return res.queryID
+}
+
+func (res *snowflakeDDLResult) RowCounts() DMLRowCounts {
+	return res.rowCounts
+}
+
+func (res *snowflakeDDLResult) Status() string {
+	return res.status
+}
+
+func (res *snowflakeDDLResult) Extensions() map[string]json.RawMessage {
+	return res.extensions
+}
@@ -0,0 +1,165 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"container/list"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ResultCacheStats is a snapshot of the counters of a ResultCache.
+type ResultCacheStats struct {
+	Hits      int64 // number of queries answered from the cache
+	Misses    int64 // number of queries not found in the cache or expired
+	Evictions int64 // number of entries evicted to make room for new ones
+	Entries   int   // number of entries in the cache
+}
+
+// ResultCache is a client side cache of small query results, keyed by the query text, the bind parameters
+// and the session context (account, user, role, warehouse, database, schema and session parameters, e.g.,
+// TIMEZONE). Only the results of SELECT
+// statements that fit in the query response, i.e., have no result chunks, and have at most maxRows rows
+// are cached. A ResultCache is safe for concurrent use and may be shared by many connections through
+// Config.ResultCache.
+//
+// The cached results are not invalidated when the underlying data change, so the TTL should be as short as
+// the application tolerates stale results.
+type ResultCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	maxRows int64
+	entries map[string]*list.Element
+	lru     *list.List
+	stats   ResultCacheStats
+}
+
+type resultCacheEntry struct {
+	key     string
+	data    *execResponseData
+	expires time.Time
+}
+
+// NewResultCache creates a ResultCache that holds up to size results for ttl. Results with more than maxRows
+// rows are not cached.
+func NewResultCache(size int, ttl time.Duration, maxRows int64) *ResultCache {
+	return &ResultCache{
+		size:    size,
+		ttl:     ttl,
+		maxRows: maxRows,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// Stats returns the counters of the cache.
+func (rc *ResultCache) Stats() ResultCacheStats {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	stats := rc.stats
+	stats.Entries = rc.lru.Len()
+	return stats
+}
+
+// Purge removes all entries from the cache.
+func (rc *ResultCache) Purge() {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.entries = make(map[string]*list.Element)
+	rc.lru.Init()
+}
+
+func (rc *ResultCache) get(key string) (*execResponseData, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	elem, ok := rc.entries[key]
+	if !ok {
+		rc.stats.Misses++
+		return nil, false
+	}
+	entry := elem.Value.(*resultCacheEntry)
+	if time.Now().After(entry.expires) {
+		rc.lru.Remove(elem)
+		delete(rc.entries, key)
+		rc.stats.Misses++
+		return nil, false
+	}
+	rc.lru.MoveToFront(elem)
+	rc.stats.Hits++
+	return entry.data, true
+}
+
+func (rc *ResultCache) put(key string, data *execResponseData) {
+	if rc.size <= 0 || !rc.cacheable(data) {
+		return
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	entry := &resultCacheEntry{key: key, data: data, expires: time.Now().Add(rc.ttl)}
+	if elem, ok := rc.entries[key]; ok {
+		elem.Value = entry
+		rc.lru.MoveToFront(elem)
+		return
+	}
+	rc.entries[key] = rc.lru.PushFront(entry)
+	for rc.lru.Len() > rc.size {
+		oldest := rc.lru.Back()
+		rc.lru.Remove(oldest)
+		delete(rc.entries, oldest.Value.(*resultCacheEntry).key)
+		rc.stats.Evictions++
+	}
+}
+
+// cacheable returns true if the result is of a single SELECT statement and is small enough to cache. A SELECT has
+// the statement type of a multi-statement query, which has the result IDs of its statements instead.
+func (rc *ResultCache) cacheable(data *execResponseData) bool {
+	if data.StatementTypeID != statementTypeIDMulti || len(data.Chunks) > 0 || data.ResultIDs != "" {
+		return false
+	}
+	return data.Total <= rc.maxRows
+}
+
+// resultCacheKey returns the key of the result of the query run on the connection with the bind parameters. The
+// session parameters are in the key, as they may change the result, e.g., TIMEZONE.
+func resultCacheKey(sc *snowflakeConn, query string, args []driver.NamedValue) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%q|%q|%q|%q|%q|%q|%q", sc.cfg.Account, sc.cfg.User, sc.cfg.Role, sc.cfg.Warehouse,
+		sc.cfg.Database, sc.cfg.Schema, query)
+	names := make([]string, 0, len(sc.cfg.Params))
+	for name := range sc.cfg.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if v := sc.cfg.Params[name]; v != nil {
+			fmt.Fprintf(&b, "|%q=%q", name, *v)
+		}
+	}
+	for _, arg := range args {
+		v := resultCacheArg(arg.Value)
+		fmt.Fprintf(&b, "|%v:%q:%T:%v", arg.Ordinal, arg.Name, v, v)
+	}
+	return b.String()
+}
+
+// resultCacheArg returns the value a bind parameter points to, so that the parameters are keyed by their values
+// instead of their addresses.
+func resultCacheArg(v interface{}) interface{} {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil
+	}
+	return rv.Interface()
+}
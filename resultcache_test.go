@@ -0,0 +1,143 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestResultCacheLRU(t *testing.T) {
+	rc := NewResultCache(2, time.Minute, 10)
+	for _, key := range []string{"a", "b"} {
+		rc.put(key, &execResponseData{QueryID: key, StatementTypeID: statementTypeIDMulti})
+	}
+	if _, ok := rc.get("a"); !ok {
+		t.Fatal("a should be cached")
+	}
+	rc.put("c", &execResponseData{QueryID: "c", StatementTypeID: statementTypeIDMulti})
+	if _, ok := rc.get("b"); ok {
+		t.Fatal("b should have been evicted as the least recently used")
+	}
+	if data, ok := rc.get("c"); !ok || data.QueryID != "c" {
+		t.Fatalf("c should be cached. data: %v", data)
+	}
+	stats := rc.Stats()
+	if stats.Hits != 2 || stats.Misses != 1 || stats.Evictions != 1 || stats.Entries != 2 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	rc.Purge()
+	if stats = rc.Stats(); stats.Entries != 0 {
+		t.Fatalf("the cache should be empty. stats: %+v", stats)
+	}
+}
+
+func TestResultCacheNotCacheable(t *testing.T) {
+	rc := NewResultCache(10, time.Minute, 10)
+	testcases := []*execResponseData{
+		{StatementTypeID: statementTypeIDInsert},
+		{StatementTypeID: statementTypeIDMulti, Total: 11},
+		{StatementTypeID: statementTypeIDMulti, Chunks: []execResponseChunk{{}}},
+		{StatementTypeID: statementTypeIDMulti, ResultIDs: "1,2"},
+	}
+	for i, data := range testcases {
+		rc.put("key", data)
+		if _, ok := rc.get("key"); ok {
+			t.Errorf("the result %v should not be cached", i)
+		}
+	}
+}
+
+func TestResultCacheExpired(t *testing.T) {
+	rc := NewResultCache(10, time.Millisecond, 10)
+	rc.put("key", &execResponseData{StatementTypeID: statementTypeIDMulti})
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := rc.get("key"); ok {
+		t.Fatal("the result should have expired")
+	}
+}
+
+func TestResultCacheKey(t *testing.T) {
+	tz := "UTC"
+	sc := &snowflakeConn{cfg: &Config{Warehouse: "w1", Params: map[string]*string{"timezone": &tz}}}
+	id1, id2 := int64(1), int64(1)
+	key := resultCacheKey(sc, "SELECT ?", []driver.NamedValue{{Ordinal: 1, Value: &id1}})
+	if k := resultCacheKey(sc, "SELECT ?", []driver.NamedValue{{Ordinal: 1, Value: &id2}}); k != key {
+		t.Fatalf("the pointers to the same value should have the same key. %v != %v", k, key)
+	}
+	if k := resultCacheKey(sc, "SELECT ?", []driver.NamedValue{{Ordinal: 1, Value: int64(1)}}); k != key {
+		t.Fatalf("a pointer should have the key of its value. %v != %v", k, key)
+	}
+	id2 = 2
+	if k := resultCacheKey(sc, "SELECT ?", []driver.NamedValue{{Ordinal: 1, Value: &id2}}); k == key {
+		t.Fatal("the pointers to different values should have different keys")
+	}
+	tz = "America/Los_Angeles"
+	if k := resultCacheKey(sc, "SELECT ?", []driver.NamedValue{{Ordinal: 1, Value: &id1}}); k == key {
+		t.Fatal("the sessions with different time zones should have different keys")
+	}
+	tz = "UTC"
+	sc.cfg.Warehouse = "w2"
+	if k := resultCacheKey(sc, "SELECT ?", []driver.NamedValue{{Ordinal: 1, Value: &id1}}); k == key {
+		t.Fatal("the sessions with different warehouses should have different keys")
+	}
+}
+
+func TestQueryWithResultCache(t *testing.T) {
+	var posted int
+	sc := &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}, ResultCache: NewResultCache(10, time.Minute, 10)},
+		rest: &snowflakeRestful{
+			FuncPostQuery: func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, _ []byte, _ time.Duration, _ *uuid.UUID) (*execResponse, error) {
+				posted++
+				return &execResponse{
+					Data: execResponseData{
+						QueryID:         "cached-1",
+						StatementTypeID: statementTypeIDMulti,
+						RowType:         []execResponseRowType{{Name: "C1", Type: "fixed"}},
+						RowSet:          [][]*string{{&[]string{"1"}[0]}},
+						Total:           1,
+					},
+					Code:    "0",
+					Success: true,
+				}, nil
+			},
+		},
+	}
+	args := []driver.NamedValue{{Ordinal: 1, Value: int64(1)}}
+	for i := 0; i < 3; i++ {
+		rows, err := sc.QueryContext(context.Background(), "SELECT C1 FROM T WHERE ID = ?", args)
+		if err != nil {
+			t.Fatalf("failed to query. err: %v", err)
+		}
+		if qid := rows.(SnowflakeResult).QueryID(); qid != "cached-1" {
+			t.Fatalf("unexpected query ID: %v", qid)
+		}
+		dest := make([]driver.Value, 1)
+		if err = rows.Next(dest); err != nil || dest[0] != "1" {
+			t.Fatalf("unexpected row: %v, err: %v", dest[0], err)
+		}
+		if err = rows.Next(dest); err != io.EOF {
+			t.Fatalf("should have reached the end of the result. err: %v", err)
+		}
+		rows.Close()
+	}
+	if posted != 1 {
+		t.Fatalf("the query should have been posted once. got: %v", posted)
+	}
+	if _, err := sc.QueryContext(context.Background(), "SELECT C1 FROM T WHERE ID = ?", []driver.NamedValue{{Ordinal: 1, Value: int64(2)}}); err != nil {
+		t.Fatalf("failed to query. err: %v", err)
+	}
+	if posted != 2 {
+		t.Fatalf("the query with other parameters should have been posted. got: %v", posted)
+	}
+	if stats := sc.cfg.ResultCache.Stats(); stats.Hits != 2 || stats.Misses != 2 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
@@ -0,0 +1,288 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Row is a row in a result set. The values are the same as the ones database/sql receives from the driver, except
+// the UUIDs of the columns of WithUUIDResults.
+type Row []driver.Value
+
+// ResultSet iterates over the result of a query without the database/sql package.
+//
+// Next and Scan read one row at a time, while NextBatch returns the rows up to the end of the chunk
+// the result set is currently reading, so that the application can process the result a chunk at a time.
+type ResultSet interface {
+	// Columns returns the column names.
+	Columns() []string
+	// Next prepares the next row for Scan. It returns false at the end of the result set or on an error,
+	// which is reported by Err.
+	Next() bool
+	// Scan copies the columns of the current row into the values pointed at by dest.
+	Scan(dest ...interface{}) error
+	// NextBatch returns the next rows up to the end of the current chunk. It returns io.EOF at the end of
+	// the result set.
+	NextBatch() ([]Row, error)
+	// NextResultSet advances to the next result set of a multi-statement query.
+	NextResultSet() bool
+	// Err returns the error encountered during the iteration, if any.
+	Err() error
+	// Close closes the result set.
+	Close() error
+	// QueryID returns the query ID of the result set.
+	QueryID() string
+	// WriteCSV writes the rest of the result set to w as CSV a chunk at a time, and returns the number of
+	// rows written. The values are formatted as Snowflake outputs them, following the output format
+	// parameters of the session, e.g., TIMESTAMP_OUTPUT_FORMAT.
+	WriteCSV(w io.Writer, opts *ExportOptions) (int64, error)
+	// WriteJSON writes the rest of the result set to w as a JSON array of objects keyed by the column names,
+	// or as JSON Lines, a chunk at a time. It returns the number of rows written.
+	WriteJSON(w io.Writer, opts *ExportOptions) (int64, error)
+}
+
+// QueryResultSet runs a query on the driver connection and returns a ResultSet. The connection
+// is either returned by SnowflakeDriver.Open or given to the function passed to sql.Conn.Raw.
+func QueryResultSet(ctx context.Context, conn driver.Conn, query string, args ...driver.NamedValue) (ResultSet, error) {
+	sc, ok := conn.(*snowflakeConn)
+	if !ok {
+		return nil, fmt.Errorf("not a Snowflake connection: %T", conn)
+	}
+	rows, err := sc.QueryContext(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	sr, ok := rows.(*snowflakeRows)
+	if !ok {
+		rows.Close()
+		return nil, fmt.Errorf("not Snowflake rows: %T", rows)
+	}
+	return &snowflakeResultSet{rows: sr}, nil
+}
+
+type snowflakeResultSet struct {
+	rows    *snowflakeRows
+	current Row
+	err     error
+	eof     bool
+	closed  bool
+}
+
+func (rs *snowflakeResultSet) Columns() []string {
+	return rs.rows.Columns()
+}
+
+func (rs *snowflakeResultSet) QueryID() string {
+	return rs.rows.QueryID()
+}
+
+func (rs *snowflakeResultSet) TotalRows() int64 {
+	return rs.rows.TotalRows()
+}
+
+func (rs *snowflakeResultSet) ChunkRowCounts() []int {
+	return rs.rows.ChunkRowCounts()
+}
+
+func (rs *snowflakeResultSet) Next() bool {
+	if rs.closed || rs.eof || rs.err != nil {
+		return false
+	}
+	dest := make(Row, len(rs.rows.RowType))
+	if err := rs.rows.Next(dest); err != nil {
+		if err == io.EOF {
+			rs.eof = true
+		} else {
+			rs.err = err
+		}
+		rs.current = nil
+		return false
+	}
+	rs.convertUUIDs(dest)
+	rs.current = dest
+	return true
+}
+
+func (rs *snowflakeResultSet) NextBatch() ([]Row, error) {
+	if rs.closed || rs.eof {
+		return nil, io.EOF
+	}
+	if rs.err != nil {
+		return nil, rs.err
+	}
+	rs.current = nil
+	batch, err := rs.rows.nextBatch()
+	if err == io.EOF {
+		rs.eof = true
+	} else if err != nil {
+		rs.err = err
+	}
+	for _, row := range batch {
+		rs.convertUUIDs(row)
+	}
+	return batch, err
+}
+
+// convertUUIDs returns the UUIDs of the columns of WithUUIDResults as uuid.UUID.
+func (rs *snowflakeResultSet) convertUUIDs(row Row) {
+	if scd := rs.rows.ChunkDownloader; scd != nil && len(scd.uuidColumns) > 0 && !scd.raw {
+		convertUUIDs(rs.rows.RowType, scd.uuidColumns, row)
+	}
+}
+
+func (rs *snowflakeResultSet) NextResultSet() bool {
+	if rs.closed || rs.err != nil || !rs.rows.HasNextResultSet() {
+		return false
+	}
+	if err := rs.rows.NextResultSet(); err != nil {
+		if err != io.EOF {
+			rs.err = err
+		}
+		return false
+	}
+	rs.current = nil
+	rs.eof = false
+	return true
+}
+
+func (rs *snowflakeResultSet) Scan(dest ...interface{}) error {
+	if rs.closed {
+		return fmt.Errorf("result set is closed")
+	}
+	if rs.current == nil {
+		return fmt.Errorf("Scan called without calling Next")
+	}
+	if len(dest) != len(rs.current) {
+		return fmt.Errorf("expected %d destination arguments in Scan, not %d", len(rs.current), len(dest))
+	}
+	scd := rs.rows.ChunkDownloader
+	for i, v := range rs.current {
+		if scd != nil && scd.strictScan {
+			if err := checkStrictScan(dest[i], v, rs.rows.RowType[i]); err != nil {
+				value := fmt.Sprint(v)
+				return newConversionError(rs.rows.RowType[i], scd.TotalRowIndex, &value, err)
+			}
+		}
+		if err := scanValue(dest[i], v); err != nil {
+			return fmt.Errorf("failed to scan column %d, name %q: %v", i, rs.rows.RowType[i].Name, err)
+		}
+	}
+	return nil
+}
+
+func (rs *snowflakeResultSet) Err() error {
+	return rs.err
+}
+
+func (rs *snowflakeResultSet) Close() error {
+	if rs.closed {
+		return nil
+	}
+	rs.closed = true
+	rs.current = nil
+	return rs.rows.Close()
+}
+
+// scanValue stores a value into the destination with the same conversions as database/sql,
+// which are reached through the sql.Scanner implementations of the sql.Null* types.
+func scanValue(dest interface{}, v driver.Value) error {
+	if u, ok := v.(uuid.UUID); ok {
+		if _, ok = dest.(*interface{}); !ok {
+			// returned by WithUUIDResults, and scanned as the text
+			v = u.String()
+		}
+	}
+	switch d := dest.(type) {
+	case sql.Scanner:
+		return d.Scan(v)
+	case *interface{}:
+		*d = v
+		return nil
+	case *string:
+		var ns sql.NullString
+		if err := ns.Scan(v); err != nil {
+			return err
+		}
+		if !ns.Valid {
+			return errNullValue(dest)
+		}
+		*d = ns.String
+		return nil
+	case *int64:
+		var ni sql.NullInt64
+		if err := ni.Scan(v); err != nil {
+			return err
+		}
+		if !ni.Valid {
+			return errNullValue(dest)
+		}
+		*d = ni.Int64
+		return nil
+	case *int:
+		var ni sql.NullInt64
+		if err := ni.Scan(v); err != nil {
+			return err
+		}
+		if !ni.Valid {
+			return errNullValue(dest)
+		}
+		*d = int(ni.Int64)
+		return nil
+	case *float64:
+		var nf sql.NullFloat64
+		if err := nf.Scan(v); err != nil {
+			return err
+		}
+		if !nf.Valid {
+			return errNullValue(dest)
+		}
+		*d = nf.Float64
+		return nil
+	case *bool:
+		var nb sql.NullBool
+		if err := nb.Scan(v); err != nil {
+			return err
+		}
+		if !nb.Valid {
+			return errNullValue(dest)
+		}
+		*d = nb.Bool
+		return nil
+	case *time.Time:
+		var nt sql.NullTime
+		if err := nt.Scan(v); err != nil {
+			return err
+		}
+		if !nt.Valid {
+			return errNullValue(dest)
+		}
+		*d = nt.Time
+		return nil
+	case *[]byte:
+		switch b := v.(type) {
+		case nil:
+			*d = nil
+		case []byte:
+			*d = append([]byte(nil), b...)
+		case string:
+			*d = []byte(b)
+		default:
+			return fmt.Errorf("unsupported conversion from %T to %T", v, dest)
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported destination type: %v", reflect.TypeOf(dest))
+}
+
+func errNullValue(dest interface{}) error {
+	return fmt.Errorf("converting NULL to %v is unsupported", reflect.TypeOf(dest).Elem())
+}
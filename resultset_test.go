@@ -0,0 +1,130 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func newResultSetTest(numChunks int) (*snowflakeResultSet, int) {
+	cc := make([][]*string, 0)
+	for i := 0; i < 100; i++ {
+		v1 := fmt.Sprintf("%v", i)
+		v2 := fmt.Sprintf("Test%v", i)
+		cc = append(cc, []*string{&v1, &v2})
+	}
+	rt := []execResponseRowType{
+		{Name: "c1", ByteLength: 10, Length: 10, Type: "fixed", Scale: 0, Nullable: true},
+		{Name: "c2", ByteLength: 100000, Length: 100000, Type: "text", Scale: 0, Nullable: false},
+	}
+	cm := make([]execResponseChunk, 0)
+	for i := 0; i < numChunks; i++ {
+		cm = append(cm, execResponseChunk{URL: fmt.Sprintf("dummyURL%v", i+1), RowCount: rowsInChunk})
+	}
+	rows := new(snowflakeRows)
+	rows.RowType = rt
+	rows.ChunkDownloader = &snowflakeChunkDownloader{
+		ctx:           context.Background(),
+		Total:         int64(len(cc) + numChunks*rowsInChunk),
+		ChunkMetas:    cm,
+		TotalRowIndex: int64(-1),
+		FuncDownload:  downloadChunkTest,
+		RowSet:        rowSetType{JSON: cc},
+	}
+	rows.ChunkDownloader.start()
+	return &snowflakeResultSet{rows: rows}, len(cc)
+}
+
+func TestResultSetNextBatch(t *testing.T) {
+	numChunks := 3
+	rs, firstChunkSize := newResultSetTest(numChunks)
+	batch, err := rs.NextBatch()
+	if err != nil {
+		t.Fatalf("failed to get batch. err: %v", err)
+	}
+	if len(batch) != firstChunkSize {
+		t.Fatalf("wrong batch size. expected: %v, got: %v", firstChunkSize, len(batch))
+	}
+	if batch[99][0] != "99" || batch[99][1] != "Test99" {
+		t.Fatalf("unexpected row: %v", batch[99])
+	}
+	// mix Next and NextBatch. the batch returns the rest of the chunk.
+	if !rs.Next() {
+		t.Fatalf("failed to get next row. err: %v", rs.Err())
+	}
+	var c1 int64
+	var c2 string
+	if err = rs.Scan(&c1, &c2); err != nil {
+		t.Fatalf("failed to scan. err: %v", err)
+	}
+	if c1 != 0 || c2 != "testchunk0" {
+		t.Fatalf("unexpected values: %v, %v", c1, c2)
+	}
+	cnt := 0
+	for {
+		batch, err = rs.NextBatch()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to get batch. err: %v", err)
+		}
+		if cnt == 0 && len(batch) != rowsInChunk-1 {
+			t.Fatalf("wrong batch size. expected: %v, got: %v", rowsInChunk-1, len(batch))
+		}
+		cnt += len(batch)
+	}
+	if expected := numChunks*rowsInChunk - 1; cnt != expected {
+		t.Fatalf("failed to get all results. expected: %v, got: %v", expected, cnt)
+	}
+	if rs.Next() {
+		t.Fatal("should have reached the end")
+	}
+	if err = rs.Close(); err != nil {
+		t.Fatalf("failed to close. err: %v", err)
+	}
+}
+
+func TestResultSetScan(t *testing.T) {
+	rs, _ := newResultSetTest(0)
+	var c1 int
+	var c2 string
+	if err := rs.Scan(&c1, &c2); err == nil {
+		t.Fatal("should have failed to scan before Next")
+	}
+	if !rs.Next() {
+		t.Fatalf("failed to get next row. err: %v", rs.Err())
+	}
+	if err := rs.Scan(&c1); err == nil {
+		t.Fatal("should have failed to scan with the wrong number of arguments")
+	}
+	var ns sql.NullString
+	if err := rs.Scan(&c1, &ns); err != nil {
+		t.Fatalf("failed to scan. err: %v", err)
+	}
+	if !ns.Valid || ns.String != "Test0" {
+		t.Fatalf("unexpected value: %v", ns)
+	}
+	var f float64
+	if err := rs.Scan(&f, &c1); err == nil {
+		t.Fatal("should have failed to convert a string to int")
+	}
+	rs.current[0] = nil
+	if err := rs.Scan(&c1, &c2); err == nil {
+		t.Fatal("should have failed to scan NULL into int")
+	}
+	var v interface{}
+	if err := rs.Scan(&v, &c2); err != nil || v != nil {
+		t.Fatalf("failed to scan NULL into interface. err: %v, value: %v", err, v)
+	}
+	if err := rs.Close(); err != nil {
+		t.Fatalf("failed to close. err: %v", err)
+	}
+	if rs.Next() {
+		t.Fatal("Next should return false after Close")
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"net/url"
+)
+
+// getResultURL returns the URL of the result of a query. The result path of a query response is usually
+// relative to the deployment of the session, but may be an absolute URL. As the session token is sent with it, an
+// absolute URL is accepted only if it is of the host of the session and uses HTTPS, or the protocol of the session,
+// so that the result URL of another deployment fails with ErrCodeInvalidResultURL instead of sending the token
+// there.
+func (sr *snowflakeRestful) getResultURL(resultPath string, params *url.Values) (*url.URL, error) {
+	u, err := url.Parse(resultPath)
+	if err != nil || !u.IsAbs() && u.RawQuery == "" {
+		return sr.getFullURL(resultPath, params), nil
+	}
+	if !u.IsAbs() {
+		// the query of the relative path, e.g., the status URL of a statement of the SQL API
+		q := u.Query()
+		if params != nil {
+			for k, v := range *params {
+				q[k] = v
+			}
+		}
+		return sr.getFullURL(u.Path, &q), nil
+	}
+	if u.Hostname() != sr.Host {
+		return nil, errInvalidResultURL(resultPath, "the host is not the host of the session")
+	}
+	if u.Scheme != "https" && u.Scheme != sr.Protocol {
+		return nil, errInvalidResultURL(resultPath, "the protocol is not HTTPS")
+	}
+	if params != nil {
+		q := u.Query()
+		for k, v := range *params {
+			q[k] = v
+		}
+		u.RawQuery = q.Encode()
+	}
+	return u, nil
+}
+
+func errInvalidResultURL(resultPath, reason string) *SnowflakeError {
+	return &SnowflakeError{
+		Number:      ErrCodeInvalidResultURL,
+		SQLState:    SQLStateConnectionRejected,
+		Message:     errMsgInvalidResultURL,
+		MessageArgs: []interface{}{resultPath, reason},
+	}
+}
@@ -172,6 +172,8 @@ type retryHTTP struct {
 	body     []byte
 	timeout  time.Duration
 	raise4XX bool
+	// accepted is a status besides 200 that ends the retries, if not 0
+	accepted int
 }
 
 func newRetryHTTP(ctx context.Context,
@@ -198,6 +200,11 @@ func (r *retryHTTP) doRaise4XX(raise4XX bool) *retryHTTP {
 	return r
 }
 
+func (r *retryHTTP) doAccept(status int) *retryHTTP {
+	r.accepted = status
+	return r
+}
+
 func (r *retryHTTP) doPost() *retryHTTP {
 	r.method = "POST"
 	return r
@@ -216,8 +223,10 @@ func (r *retryHTTP) execute() (res *http.Response, err error) {
 
 	var rIDReplacer requestGUIDReplacer
 	var rUpdater retryCounterUpdater
+	ql := queryLogFrom(r.ctx)
 
 	for {
+		ql.setRequestGUID(r.fullURL.Query().Get(requestGUIDKey))
 		req, err := r.req(r.method, r.fullURL.String(), bytes.NewReader(r.body))
 		if err != nil {
 			return nil, err
@@ -238,18 +247,26 @@ func (r *retryHTTP) execute() (res *http.Response, err error) {
 			}
 			// cannot just return 4xx and 5xx status as the error can be sporadic. run often helps.
 			glog.V(2).Infof(
-				"failed http connection. no response is returned. err: %v. retrying...\n", err)
+				"failed http connection. no response is returned. err: %v. %v. retrying...\n", err, ql)
 		} else {
-			if res.StatusCode == http.StatusOK || r.raise4XX && res != nil && res.StatusCode >= 400 && res.StatusCode < 500 {
+			if res.StatusCode == http.StatusOK || r.accepted != 0 && res.StatusCode == r.accepted ||
+				r.raise4XX && res != nil && res.StatusCode >= 400 && res.StatusCode < 500 ||
+				r.raise4XX && isRedirectStatus(res.StatusCode) || res.StatusCode == http.StatusRequestEntityTooLarge {
 				// exit if success
 				// or
+				// exit if the status is accepted by the caller, e.g., a statement in progress of the SQL API.
+				// or
 				// abort connection if raise4XX flag is enabled and the range of HTTP status code are 4XX.
 				// This is currently used for Snowflake login. The caller must generate an error object based on HTTP status.
+				// or
+				// return the redirect of the login, which is not followed by the HTTP client.
+				// or
+				// return the request rejected as too large, as the same request fails again.
 				break
 			}
 			glog.V(2).Infof(
-				"failed http connection. HTTP Status: %v. retrying...\n", res.StatusCode)
-			res.Body.Close()
+				"failed http connection. HTTP Status: %v. %v. retrying...\n", res.StatusCode, ql)
+			drainAndClose(res.Body)
 		}
 		// uses decorrelated jitter backoff
 		sleepTime = defaultWaitAlgo.decorr(retryCounter, sleepTime)
@@ -303,6 +320,10 @@ func (r *retryHTTP) isRetryableError(err error) (bool, error) {
 			if driverError.Number == ErrOCSPStatusRevoked {
 				return true, err
 			}
+			// the request is not sent without authorization
+			if driverError.Number == ErrCodeRequestAuthorizationFailed {
+				return true, driverError
+			}
 		}
 		if _, ok := urlError.Err.(x509.CertificateInvalidError); ok {
 			// Certificate is invalid
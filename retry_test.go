@@ -261,3 +261,32 @@ func TestRetryLoginRequest(t *testing.T) {
 		t.Fatalf("no retry counter should be attached: %v", retryCounterKey)
 	}
 }
+
+type tooLargeHTTPClient struct {
+	cnt int
+}
+
+func (c *tooLargeHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	c.cnt++
+	return &http.Response{
+		StatusCode: http.StatusRequestEntityTooLarge,
+		Body:       &fakeResponseBody{body: []byte{}},
+	}, nil
+}
+
+func TestRetryRequestTooLarge(t *testing.T) {
+	client := &tooLargeHTTPClient{}
+	urlPtr, err := url.Parse("https://fakeaccountretryfail.snowflakecomputing.com:443/queries/v1/query-request?" + requestIDKey)
+	if err != nil {
+		t.Fatal("failed to parse the test URL")
+	}
+	res, err := newRetryHTTP(context.TODO(),
+		client,
+		fakeRequestFunc, urlPtr, make(map[string]string), 60*time.Second).doPost().setBody([]byte{0}).execute()
+	if err != nil {
+		t.Fatalf("should return the response. err: %v", err)
+	}
+	if res.StatusCode != http.StatusRequestEntityTooLarge || client.cnt != 1 {
+		t.Fatalf("the request should not be retried. status: %v, attempts: %v", res.StatusCode, client.cnt)
+	}
+}
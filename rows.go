@@ -4,6 +4,7 @@ package gosnowflake
 
 import (
 	"bufio"
+	"bytes"
 	"compress/gzip"
 	"context"
 	"database/sql/driver"
@@ -15,8 +16,10 @@ import (
 	"net/http"
 	"net/url"
 	"reflect"
+	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -30,6 +33,15 @@ var (
 	// MaxChunkDownloadWorkers specifies the maximum number of goroutines used to download chunks
 	MaxChunkDownloadWorkers = 10
 
+	// MaxChildResultFetchWorkers specifies the maximum number of goroutines used to get the results of the
+	// statements in a multi-statement query
+	MaxChildResultFetchWorkers = 8
+
+	// MaxChunkDecodeWorkers specifies the maximum number of chunks of a result decoded at once. A chunk is
+	// decoded a row at a time as its body is read, so the body of a chunk waiting for a worker is not read in the
+	// meantime. GOMAXPROCS is used if 0 or less
+	MaxChunkDecodeWorkers = 0
+
 	// CustomJSONDecoderEnabled has the chunk downloader use the custom JSON decoder to reduce memory footprint.
 	CustomJSONDecoderEnabled = false
 )
@@ -43,10 +55,15 @@ type snowflakeRows struct {
 	RowType         []execResponseRowType
 	ChunkDownloader *snowflakeChunkDownloader
 	queryID         string
+	extensions      map[string]json.RawMessage
 }
 
 func (rows *snowflakeRows) Close() (err error) {
 	glog.V(2).Infoln("Rows.Close")
+	if rows.ChunkDownloader != nil {
+		rows.ChunkDownloader.stop()
+		rows.ChunkDownloader.closeSpill()
+	}
 	return nil
 }
 
@@ -71,6 +88,7 @@ type chunkError struct {
 type snowflakeChunkDownloader struct {
 	sc                 *snowflakeConn
 	ctx                context.Context
+	queryID            string
 	Total              int64
 	TotalRowIndex      int64
 	CellCount          int
@@ -83,6 +101,7 @@ type snowflakeChunkDownloader struct {
 	ChunksChan         chan int
 	ChunksError        chan *chunkError
 	ChunksErrorCounter int
+	chunkRetries       map[int]int // the number of retries of each chunk
 	ChunksFinalErrors  []*chunkError
 	Qrmk               string
 	QueryResultFormat  string
@@ -94,6 +113,26 @@ type snowflakeChunkDownloader struct {
 	FuncGet            func(context.Context, *snowflakeChunkDownloader, string, map[string]string, time.Duration) (*http.Response, error)
 	DoneDownloadCond   *sync.Cond
 	NextDownloader     *snowflakeChunkDownloader
+	progress           *resultProgressTracker
+	maxRows            int64
+	maxBytes           int64
+	bytesDownloaded    int64 // accessed atomically
+	truncated          error
+	skipped            SkippedChunkFunc
+	pendingSkips       []SkippedChunk // the skipped chunks to notify once ChunksMutex is released
+	raw                bool
+	timeFormat         TimeFormat
+	rejectNonFinite    bool
+	uuidColumns        []string // the TEXT columns whose UUIDs ResultSet returns as uuid.UUID
+	strictScan         bool
+	fetchedByID        bool // the result is fetched by its query ID, so its chunks may be cached
+	spill              *resultSpill
+	decodeWorkers      chan struct{}
+	downloads          sync.WaitGroup // the running download goroutines
+	stopChan           chan struct{}  // closed when the rows are closed
+	stopOnce           sync.Once
+	cancel             context.CancelFunc // cancels the downloads
+	firstChunkRows     int
 }
 
 // ColumnTypeDatabaseTypeName returns the database column name.
@@ -145,6 +184,10 @@ func (rows *snowflakeRows) Columns() []string {
 }
 
 func (rows *snowflakeRows) ColumnTypeScanType(index int) reflect.Type {
+	if rows.ChunkDownloader != nil && rows.ChunkDownloader.timeFormat == TimeFormatDuration &&
+		strings.EqualFold(rows.RowType[index].Type, "time") {
+		return reflect.TypeOf(time.Duration(0))
+	}
 	return snowflakeTypeToGo(rows.RowType[index].Type, rows.RowType[index].Scale)
 }
 
@@ -152,6 +195,24 @@ func (rows *snowflakeRows) QueryID() string {
 	return rows.queryID
 }
 
+func (rows *snowflakeRows) TotalRows() int64 {
+	if rows.ChunkDownloader == nil {
+		return 0
+	}
+	return rows.ChunkDownloader.Total
+}
+
+func (rows *snowflakeRows) ChunkRowCounts() []int {
+	if rows.ChunkDownloader == nil {
+		return nil
+	}
+	return rows.ChunkDownloader.chunkRowCounts()
+}
+
+func (rows *snowflakeRows) Extensions() map[string]json.RawMessage {
+	return rows.extensions
+}
+
 func (rows *snowflakeRows) Next(dest []driver.Value) (err error) {
 	row, err := rows.ChunkDownloader.Next()
 	if err != nil {
@@ -161,22 +222,74 @@ func (rows *snowflakeRows) Next(dest []driver.Value) (err error) {
 		}
 		return err
 	}
+	return rows.convertRow(row, dest)
+}
 
+// convertRow converts a row of the current chunk to the destination values.
+func (rows *snowflakeRows) convertRow(row chunkRowType, dest []driver.Value) error {
 	if rows.ChunkDownloader.QueryResultFormat == arrowFormat {
 		for i, n := 0, len(row.ArrowRow); i < n; i++ {
 			dest[i] = row.ArrowRow[i]
 		}
+	} else if rows.ChunkDownloader.raw {
+		for i, v := range row.RowSet {
+			if v == nil {
+				dest[i] = nil
+			} else {
+				dest[i] = *v
+			}
+		}
 	} else {
 		for i, n := 0, len(row.RowSet); i < n; i++ {
 			// could move to chunk downloader so that each go routine
 			// can convert data
 			err := stringToValue(&dest[i], rows.RowType[i], row.RowSet[i])
 			if err != nil {
-				return err
+				return newConversionError(rows.RowType[i], rows.ChunkDownloader.TotalRowIndex, row.RowSet[i], err)
 			}
 		}
 	}
-	return err
+	if rows.ChunkDownloader.rejectNonFinite {
+		if err := checkFiniteRow(rows.RowType, dest); err != nil {
+			return err
+		}
+	}
+	if format := rows.ChunkDownloader.timeFormat; format != TimeFormatZeroDate && !rows.ChunkDownloader.raw {
+		for i := range rows.RowType {
+			if i < len(dest) && strings.EqualFold(rows.RowType[i].Type, "time") {
+				dest[i] = formatTime(dest[i], format)
+			}
+		}
+	}
+	return nil
+}
+
+// nextBatch returns the next row and all of the remaining rows in the same chunk.
+func (rows *snowflakeRows) nextBatch() ([]Row, error) {
+	row, err := rows.ChunkDownloader.Next()
+	if err != nil {
+		// includes io.EOF
+		if err == io.EOF {
+			rows.ChunkDownloader.Chunks = nil
+		}
+		return nil, err
+	}
+	scd := rows.ChunkDownloader
+	batch := make([]Row, 0, scd.CurrentChunkSize-scd.CurrentIndex)
+	for {
+		dest := make(Row, len(rows.RowType))
+		if err = rows.convertRow(row, dest); err != nil {
+			return nil, err
+		}
+		batch = append(batch, dest)
+		if scd.CurrentIndex+1 >= scd.CurrentChunkSize || scd.reachedMaxRows() {
+			break
+		}
+		scd.CurrentIndex++
+		scd.TotalRowIndex++
+		row = scd.CurrentChunk[scd.CurrentIndex]
+	}
+	return batch, nil
 }
 
 func (rows *snowflakeRows) HasNextResultSet() bool {
@@ -191,8 +304,11 @@ func (rows *snowflakeRows) NextResultSet() error {
 		if rows.ChunkDownloader.NextDownloader == nil {
 			return io.EOF
 		}
+		rows.ChunkDownloader.stop()
 		rows.ChunkDownloader = rows.ChunkDownloader.NextDownloader
-		rows.ChunkDownloader.start()
+		if err := rows.ChunkDownloader.start(); err != nil {
+			return err
+		}
 	}
 	return rows.ChunkDownloader.nextResultSet()
 }
@@ -204,6 +320,17 @@ func (scd *snowflakeChunkDownloader) totalUncompressedSize() (acc int64) {
 	return
 }
 
+// chunkRowCounts returns the number of rows of the first chunk, which came with the query response, followed by
+// those of the chunks to download.
+func (scd *snowflakeChunkDownloader) chunkRowCounts() []int {
+	counts := make([]int, 0, len(scd.ChunkMetas)+1)
+	counts = append(counts, scd.firstChunkRows)
+	for _, meta := range scd.ChunkMetas {
+		counts = append(counts, meta.RowCount)
+	}
+	return counts
+}
+
 func (scd *snowflakeChunkDownloader) hasNextResultSet() bool {
 	// next result set exists if current chunk has remaining result sets or there is another downloader
 	return scd.CurrentChunkIndex < len(scd.ChunkMetas) || scd.NextDownloader != nil
@@ -221,6 +348,13 @@ func (scd *snowflakeChunkDownloader) start() error {
 	scd.CurrentChunkSize = len(scd.RowSet.JSON) // cache the size
 	scd.CurrentIndex = -1                       // initial chunks idx
 	scd.CurrentChunkIndex = -1                  // initial chunk
+	scd.raw = isRawResults(scd.ctx)
+	scd.timeFormat, _ = scd.ctx.Value(timeFormat).(TimeFormat)
+	scd.rejectNonFinite = rejectsNonFiniteFloats(scd.ctx)
+	scd.uuidColumns, _ = scd.ctx.Value(uuidResults).([]string)
+	scd.strictScan, _ = scd.ctx.Value(strictScan).(bool)
+	qid, _ := scd.ctx.Value(fetchResultByID).(string)
+	scd.fetchedByID = qid != ""
 
 	scd.CurrentChunk = make([]chunkRowType, scd.CurrentChunkSize)
 	populateJSONRowSet(scd.CurrentChunk, scd.RowSet.JSON)
@@ -229,12 +363,18 @@ func (scd *snowflakeChunkDownloader) start() error {
 		// if the rowsetbase64 retrieved from the server is empty, move on to downloading chunks
 		var err error
 		firstArrowChunk := buildFirstArrowChunk(scd.RowSet.RowSetBase64)
-		scd.CurrentChunk, err = firstArrowChunk.decodeArrowChunk(scd.RowSet.RowType)
+		scd.CurrentChunk, err = firstArrowChunk.decodeArrowChunk(scd.RowSet.RowType, scd.raw)
 		scd.CurrentChunkSize = firstArrowChunk.rowCount
 		if err != nil {
 			return err
 		}
 	}
+	scd.firstChunkRows = scd.CurrentChunkSize
+	scd.maxRows, _ = scd.ctx.Value(maxResultRows).(int64)
+	scd.maxBytes, _ = scd.ctx.Value(maxResultBytes).(int64)
+	scd.skipped, _ = scd.ctx.Value(skipBadChunks).(SkippedChunkFunc)
+	scd.progress = newResultProgressTracker(scd.ctx, scd)
+	scd.progress.report(int64(scd.CurrentChunkSize), 0, 0)
 
 	// start downloading chunks if exists
 	chunkMetaLen := len(scd.ChunkMetas)
@@ -244,8 +384,12 @@ func (scd *snowflakeChunkDownloader) start() error {
 		scd.ChunksMutex = &sync.Mutex{}
 		scd.DoneDownloadCond = sync.NewCond(scd.ChunksMutex)
 		scd.Chunks = make(map[int][]chunkRowType)
+		scd.spill = newResultSpill(scd.sc)
+		scd.decodeWorkers = make(chan struct{}, chunkDecodeWorkers())
 		scd.ChunksChan = make(chan int, chunkMetaLen)
 		scd.ChunksError = make(chan *chunkError, MaxChunkDownloadWorkers)
+		scd.stopChan = make(chan struct{})
+		scd.ctx, scd.cancel = context.WithCancel(scd.ctx)
 		for i := 0; i < chunkMetaLen; i++ {
 			glog.V(2).Infof("add chunk to channel ChunksChan: %v", i+1)
 			scd.ChunksChan <- i
@@ -258,25 +402,79 @@ func (scd *snowflakeChunkDownloader) start() error {
 }
 
 func (scd *snowflakeChunkDownloader) schedule() {
+	if scd.stopped() {
+		return
+	}
 	select {
 	case nextIdx := <-scd.ChunksChan:
 		glog.V(2).Infof("schedule chunk: %v", nextIdx+1)
-		go scd.FuncDownload(scd.ctx, scd, nextIdx)
+		scd.download(nextIdx)
 	default:
 		// no more download
 		glog.V(2).Info("no more download")
 	}
 }
 
+// download runs FuncDownload of the chunk in a goroutine tracked by downloads.
+func (scd *snowflakeChunkDownloader) download(idx int) {
+	scd.downloads.Add(1)
+	go func() {
+		defer scd.downloads.Done()
+		scd.FuncDownload(scd.ctx, scd, idx)
+	}()
+}
+
+// stop stops scheduling the downloads of the chunks, cancels the running downloads and waits for them to exit,
+// so that the connections and the chunks are released at once. The chunks downloaded afterwards are dropped.
+func (scd *snowflakeChunkDownloader) stop() {
+	if scd.stopChan == nil {
+		return
+	}
+	scd.stopOnce.Do(func() {
+		close(scd.stopChan)
+		scd.cancel()
+		scd.downloads.Wait()
+		scd.ChunksMutex.Lock()
+		scd.Chunks = nil
+		scd.ChunksMutex.Unlock()
+	})
+}
+
+// stopped returns true if the rows are closed.
+func (scd *snowflakeChunkDownloader) stopped() bool {
+	select {
+	case <-scd.stopChan:
+		return true
+	default:
+		return false
+	}
+}
+
+// reportError passes the error of the download of the chunk to Next, unless the rows are closed and so Next
+// won't receive it.
+func (scd *snowflakeChunkDownloader) reportError(idx int, err error) {
+	select {
+	case scd.ChunksError <- &chunkError{Index: idx, Error: err}:
+	case <-scd.stopChan:
+		glog.V(2).Infof("chunk idx: %v, err: %v. the rows are closed", idx, err)
+	}
+}
+
 func (scd *snowflakeChunkDownloader) checkErrorRetry() (err error) {
 	select {
 	case errc := <-scd.ChunksError:
-		if scd.ChunksErrorCounter < maxChunkDownloaderErrorCounter && errc.Error != context.Canceled {
+		if scd.chunkRetries[errc.Index] < maxChunkDownloaderErrorCounter && errc.Error != context.Canceled {
 			// add the index to the chunks channel so that the download will be retried.
-			go scd.FuncDownload(scd.ctx, scd, errc.Index)
+			scd.download(errc.Index)
+			if scd.chunkRetries == nil {
+				scd.chunkRetries = make(map[int]int)
+			}
+			scd.chunkRetries[errc.Index]++
 			scd.ChunksErrorCounter++
 			glog.V(2).Infof("chunk idx: %v, err: %v. retrying (%v/%v)...",
-				errc.Index, errc.Error, scd.ChunksErrorCounter, maxChunkDownloaderErrorCounter)
+				errc.Index, errc.Error, scd.chunkRetries[errc.Index], maxChunkDownloaderErrorCounter)
+		} else if scd.skipped != nil && errc.Error != context.Canceled && errc.Error != context.DeadlineExceeded {
+			scd.skipChunk(errc)
 		} else {
 			scd.ChunksFinalErrors = append(scd.ChunksFinalErrors, errc)
 			glog.V(2).Infof("chunk idx: %v, err: %v. no further retry", errc.Index, errc.Error)
@@ -287,10 +485,58 @@ func (scd *snowflakeChunkDownloader) checkErrorRetry() (err error) {
 	}
 	return nil
 }
+
+// skipChunk replaces the chunk that failed with an empty chunk so that the iteration continues with the
+// next chunk. The caller must hold ChunksMutex, and call notifySkipped once it is released.
+func (scd *snowflakeChunkDownloader) skipChunk(errc *chunkError) {
+	glog.V(1).Infof("chunk idx: %v, err: %v. skipping the chunk", errc.Index, errc.Error)
+	scd.ChunksFinalErrors = append(scd.ChunksFinalErrors, errc)
+	scd.Chunks[errc.Index] = make([]chunkRowType, 0)
+	scd.pendingSkips = append(scd.pendingSkips, SkippedChunk{
+		QueryID: scd.queryID,
+		Index:   errc.Index,
+		Rows:    scd.ChunkMetas[errc.Index].RowCount,
+		Err:     errc.Error,
+	})
+}
+
+// notifySkipped calls the SkippedChunkFunc with the chunks skipped so far. It is called without holding
+// ChunksMutex so that the callback doesn't block the downloads.
+func (scd *snowflakeChunkDownloader) notifySkipped() {
+	skips := scd.pendingSkips
+	scd.pendingSkips = nil
+	for _, c := range skips {
+		scd.skipped(c)
+	}
+}
+
+// reachedMaxRows returns true if as many rows as the limit set by WithMaxResultRows have been read.
+func (scd *snowflakeChunkDownloader) reachedMaxRows() bool {
+	return scd.maxRows > 0 && scd.TotalRowIndex+1 >= scd.maxRows
+}
+
+// truncate fails the rows with ErrResultTruncated and stops the downloads of the chunks that won't be read.
+func (scd *snowflakeChunkDownloader) truncate(limit int64, unit string) error {
+	scd.truncated = &SnowflakeError{
+		Number:      ErrResultTruncated,
+		Message:     errMsgResultTruncated,
+		MessageArgs: []interface{}{limit, unit},
+	}
+	scd.stop()
+	return scd.truncated
+}
+
 func (scd *snowflakeChunkDownloader) Next() (chunkRowType, error) {
+	if scd.truncated != nil {
+		return chunkRowType{}, scd.truncated
+	}
 	for {
 		scd.CurrentIndex++
 		if scd.CurrentIndex < scd.CurrentChunkSize {
+			if scd.reachedMaxRows() {
+				return chunkRowType{}, scd.truncate(scd.maxRows, "rows")
+			}
+			scd.TotalRowIndex++
 			return scd.CurrentChunk[scd.CurrentIndex], nil
 		}
 		scd.CurrentChunkIndex++ // next chunk
@@ -304,24 +550,42 @@ func (scd *snowflakeChunkDownloader) Next() (chunkRowType, error) {
 			scd.Chunks[scd.CurrentChunkIndex-1] = nil // detach the previously used chunk
 		}
 
-		for scd.Chunks[scd.CurrentChunkIndex] == nil {
+		for scd.Chunks[scd.CurrentChunkIndex] == nil && !scd.spilled(scd.CurrentChunkIndex) {
 			glog.V(2).Infof("waiting for chunk idx: %v/%v",
 				scd.CurrentChunkIndex+1, len(scd.ChunkMetas))
 
 			err := scd.checkErrorRetry()
 			if err != nil {
 				scd.ChunksMutex.Unlock()
+				scd.notifySkipped()
 				return chunkRowType{}, err
 			}
+			if scd.Chunks[scd.CurrentChunkIndex] != nil {
+				// the chunk was skipped
+				break
+			}
 
 			// wait for chunk downloader goroutine to broadcast the event,
 			// 1) one chunk download finishes or 2) an error occurs.
 			scd.DoneDownloadCond.Wait()
 		}
+		if scd.Chunks[scd.CurrentChunkIndex] == nil {
+			// the chunk was spilled
+			scd.ChunksMutex.Unlock()
+			scd.notifySkipped()
+			if err := scd.readSpilledChunk(scd.CurrentChunkIndex); err != nil {
+				return chunkRowType{}, err
+			}
+			scd.ChunksMutex.Lock()
+		}
 		glog.V(2).Infof("ready: chunk %v", scd.CurrentChunkIndex+1)
 		scd.CurrentChunk = scd.Chunks[scd.CurrentChunkIndex]
 		scd.ChunksMutex.Unlock()
+		scd.notifySkipped()
 		scd.CurrentChunkSize = len(scd.CurrentChunk)
+		if scd.maxBytes > 0 && atomic.LoadInt64(&scd.bytesDownloaded) > scd.maxBytes {
+			return chunkRowType{}, scd.truncate(scd.maxBytes, "bytes")
+		}
 
 		// kick off the next download
 		scd.schedule()
@@ -364,14 +628,16 @@ func (r *largeResultSetReader) Read(p []byte) (n int, err error) {
 	if r.status == 1 {
 		var len int
 		len, err = r.body.Read(p)
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
 		if err == io.EOF {
 			r.status = 2
-			return len, nil
 		}
-		if err != nil {
-			return 0, err
+		if len > 0 || err == nil {
+			return len, nil
 		}
-		return len, nil
+		// the tail follows at once, as the readers may take an empty read for the end of the stream
 	}
 	if r.status == 2 {
 		p[0] = 0x5d // tail 0x5d (])
@@ -385,14 +651,18 @@ func (r *largeResultSetReader) Read(p []byte) (n int, err error) {
 func downloadChunk(ctx context.Context, scd *snowflakeChunkDownloader, idx int) {
 	glog.V(2).Infof("download start chunk: %v", idx+1)
 	defer scd.DoneDownloadCond.Broadcast()
+	if scd.stopped() {
+		glog.V(2).Infof("skip chunk: %v. the rows are closed", idx+1)
+		return
+	}
 
 	if err := scd.FuncDownloadHelper(ctx, scd, idx); err != nil {
-		glog.V(1).Infof(
-			"failed to extract HTTP response body. URL: %v, err: %v", scd.ChunkMetas[idx].URL, err)
+		glog.V(1).Infof("failed to extract HTTP response body. URL: %v, query ID: %v, %v, err: %v",
+			scd.ChunkMetas[idx].URL, scd.queryID, queryLogFrom(ctx), err)
 		glog.Flush()
-		scd.ChunksError <- &chunkError{Index: idx, Error: err}
+		scd.reportError(idx, err)
 	} else if scd.ctx.Err() == context.Canceled || scd.ctx.Err() == context.DeadlineExceeded {
-		scd.ChunksError <- &chunkError{Index: idx, Error: scd.ctx.Err()}
+		scd.reportError(idx, scd.ctx.Err())
 	}
 }
 
@@ -407,33 +677,130 @@ func downloadChunkHelper(ctx context.Context, scd *snowflakeChunkDownloader, idx
 		headers[headerSseCAlgorithm] = headerSseCAes
 		headers[headerSseCKey] = scd.Qrmk
 	}
+	if cc := scd.chunkCache(idx); cc != nil {
+		b, err := cc.load(ctx, chunkCacheKey(scd.ChunkMetas[idx].URL, headers), func() ([]byte, error) {
+			var buf bytes.Buffer
+			err := fetchChunk(ctx, scd, idx, headers, func(body io.Reader) error {
+				_, err := buf.ReadFrom(body)
+				return err
+			})
+			return buf.Bytes(), err
+		})
+		if err != nil {
+			return err
+		}
+		return scd.storeChunk(idx, bytes.NewReader(b))
+	}
+	return fetchChunk(ctx, scd, idx, headers, func(body io.Reader) error {
+		return scd.storeChunk(idx, body)
+	})
+}
+
+// chunkCache returns the cache of the chunk, or nil if the chunk is not cached. Only the chunks of the results
+// fetched by their query IDs are cached, and the chunks larger than the cache are streamed to storeChunk instead of
+// being buffered.
+func (scd *snowflakeChunkDownloader) chunkCache(idx int) *ChunkCache {
+	if !scd.fetchedByID || scd.sc.cfg == nil || scd.sc.cfg.ChunkCache == nil {
+		return nil
+	}
+	cc := scd.sc.cfg.ChunkCache
+	size := scd.ChunkMetas[idx].CompressedSize
+	if size <= 0 {
+		size = scd.ChunkMetas[idx].UncompressedSize
+	}
+	if size > cc.maxBytes {
+		return nil
+	}
+	return cc
+}
 
+// fetchChunk downloads the chunk with the headers and passes its body to read.
+func fetchChunk(ctx context.Context, scd *snowflakeChunkDownloader, idx int, headers map[string]string,
+	read func(io.Reader) error) error {
 	resp, err := scd.FuncGet(ctx, scd, scd.ChunkMetas[idx].URL, headers, scd.sc.rest.RequestTimeout)
 	if err != nil {
 		return err
 	}
-	bufStream := bufio.NewReader(resp.Body)
-	defer resp.Body.Close()
+	defer drainAndClose(resp.Body)
 	glog.V(2).Infof("response returned chunk: %v, resp: %v", idx+1, resp)
 	if resp.StatusCode != http.StatusOK {
-		b, err := ioutil.ReadAll(bufStream)
+		b, err := ioutil.ReadAll(resp.Body)
 		if err != nil {
 			return err
 		}
-		glog.V(1).Infof("HTTP: %v, URL: %v, Body: %v", resp.StatusCode, scd.ChunkMetas[idx].URL, b)
+		glog.V(1).Infof("HTTP: %v, URL: %v, Body: %v. query ID: %v, %v",
+			resp.StatusCode, scd.ChunkMetas[idx].URL, b, scd.queryID, queryLogFrom(ctx))
 		glog.V(1).Infof("Header: %v", resp.Header)
 		glog.Flush()
-		return &SnowflakeError{
+		// the query ID is that of the result, which may be a statement of a multi-statement query
+		return queryLogFrom(ctx).annotate(&SnowflakeError{
 			Number:      ErrFailedToGetChunk,
 			SQLState:    SQLStateConnectionFailure,
+			QueryID:     scd.queryID,
 			Message:     errMsgFailedToGetChunk,
 			MessageArgs: []interface{}{idx},
+		})
+	}
+	return read(resp.Body)
+}
+
+// storeChunk decodes the downloaded chunk, or spills it, and reports the progress of the download.
+func (scd *snowflakeChunkDownloader) storeChunk(idx int, r io.Reader) error {
+	body := &countingReader{r: r}
+	bufStream := bufio.NewReader(body)
+	var rows int
+	if scd.spill != nil {
+		if err := scd.spillChunk(idx, bufStream); err != nil {
+			return err
+		}
+		rows = scd.ChunkMetas[idx].RowCount
+	} else {
+		if err := decodeChunk(scd, idx, bufStream); err != nil {
+			return err
 		}
+		scd.ChunksMutex.Lock()
+		rows = len(scd.Chunks[idx])
+		scd.ChunksMutex.Unlock()
+	}
+	atomic.AddInt64(&scd.bytesDownloaded, body.n)
+	scd.progress.report(int64(rows), 1, body.n)
+	return nil
+}
+
+// chunkDecodeWorkers returns the number of chunks of a result decoded at once.
+func chunkDecodeWorkers() int {
+	if MaxChunkDecodeWorkers > 0 {
+		return MaxChunkDecodeWorkers
 	}
-	return decodeChunk(scd, idx, bufStream)
+	return runtime.GOMAXPROCS(0)
 }
 
+// acquireDecodeWorker waits until fewer than chunkDecodeWorkers chunks are decoded. It returns the context
+// error if the context is done in the meantime.
+func (scd *snowflakeChunkDownloader) acquireDecodeWorker() error {
+	if scd.decodeWorkers == nil {
+		return nil
+	}
+	select {
+	case scd.decodeWorkers <- struct{}{}:
+		return nil
+	case <-scd.ctx.Done():
+		return scd.ctx.Err()
+	}
+}
+
+func (scd *snowflakeChunkDownloader) releaseDecodeWorker() {
+	if scd.decodeWorkers != nil {
+		<-scd.decodeWorkers
+	}
+}
+
+// decodeChunk decodes the rows of the chunk as its body is read, once a decode worker is free.
 func decodeChunk(scd *snowflakeChunkDownloader, idx int, bufStream *bufio.Reader) (err error) {
+	if err = scd.acquireDecodeWorker(); err != nil {
+		return err
+	}
+	defer scd.releaseDecodeWorker()
 	gzipMagic, err := bufStream.Peek(2)
 	if err != nil {
 		return err
@@ -457,24 +824,19 @@ func decodeChunk(scd *snowflakeChunkDownloader, idx int, bufStream *bufio.Reader
 	}
 	var respd []chunkRowType
 	if scd.QueryResultFormat != arrowFormat {
-		var decRespd [][]*string
 		if !CustomJSONDecoderEnabled {
-			dec := json.NewDecoder(st)
-			for {
-				if err := dec.Decode(&decRespd); err == io.EOF {
-					break
-				} else if err != nil {
-					return err
-				}
+			respd, err = decodeChunkRows(st, scd.ChunkMetas[idx].RowCount)
+			if err != nil {
+				return err
 			}
 		} else {
-			decRespd, err = decodeLargeChunk(st, scd.ChunkMetas[idx].RowCount, scd.CellCount)
+			decRespd, err := decodeLargeChunk(st, scd.ChunkMetas[idx].RowCount, scd.CellCount)
 			if err != nil {
 				return err
 			}
+			respd = make([]chunkRowType, len(decRespd))
+			populateJSONRowSet(respd, decRespd)
 		}
-		respd = make([]chunkRowType, len(decRespd))
-		populateJSONRowSet(respd, decRespd)
 	} else {
 		ipcReader, err := ipc.NewReader(source)
 		if err != nil {
@@ -486,7 +848,7 @@ func decodeChunk(scd *snowflakeChunkDownloader, idx int, bufStream *bufio.Reader
 			int(scd.totalUncompressedSize()),
 			memory.NewGoAllocator(),
 		}
-		respd, err = arc.decodeArrowChunk(scd.RowSet.RowType)
+		respd, err = arc.decodeArrowChunk(scd.RowSet.RowType, scd.raw)
 		if err != nil {
 			return err
 		}
@@ -500,6 +862,10 @@ func decodeChunk(scd *snowflakeChunkDownloader, idx int, bufStream *bufio.Reader
 
 	scd.ChunksMutex.Lock()
 	defer scd.ChunksMutex.Unlock()
+	if scd.stopped() {
+		// the rows were closed while the chunk was downloaded
+		return nil
+	}
 	scd.Chunks[idx] = respd
 	return nil
 }
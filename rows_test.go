@@ -5,10 +5,16 @@ package gosnowflake
 import (
 	"context"
 	"database/sql/driver"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -82,6 +88,61 @@ func downloadChunkTest(ctx context.Context, scd *snowflakeChunkDownloader, idx i
 	scd.ChunksMutex.Unlock()
 }
 
+func TestRowsConversionError(t *testing.T) {
+	good := "18000"
+	bad := "not a date"
+	rows := new(snowflakeRows)
+	rows.RowType = []execResponseRowType{{Name: "D", Type: "date"}}
+	rows.ChunkDownloader = &snowflakeChunkDownloader{
+		ctx:           context.Background(),
+		Total:         2,
+		TotalRowIndex: int64(-1),
+		RowSet:        rowSetType{JSON: [][]*string{{&good}, {&bad}}},
+	}
+	rows.ChunkDownloader.start()
+	dest := make([]driver.Value, 1)
+	if err := rows.Next(dest); err != nil {
+		t.Fatalf("failed to get value. err: %v", err)
+	}
+	err := rows.Next(dest)
+	ce, ok := err.(*ConversionError)
+	if !ok {
+		t.Fatalf("should be ConversionError. err: %v", err)
+	}
+	if ce.Column != "D" || ce.Type != "DATE" || ce.Row != 1 || ce.Value != bad {
+		t.Fatalf("unexpected conversion error: %+v", ce)
+	}
+	if _, ok := errors.Unwrap(err).(*strconv.NumError); !ok {
+		t.Fatalf("should wrap the strconv error. err: %v", errors.Unwrap(err))
+	}
+	if !strings.Contains(err.Error(), `column D (DATE) of row 1, value "not a date"`) {
+		t.Fatalf("unexpected message: %v", err)
+	}
+}
+
+func TestRowsWithTimeFormat(t *testing.T) {
+	tm := "3600.5"
+	rows := new(snowflakeRows)
+	rows.RowType = []execResponseRowType{{Name: "T", Type: "time", Scale: 9}}
+	rows.ChunkDownloader = &snowflakeChunkDownloader{
+		ctx:           WithTimeFormat(context.Background(), TimeFormatDuration),
+		Total:         1,
+		TotalRowIndex: int64(-1),
+		RowSet:        rowSetType{JSON: [][]*string{{&tm}}},
+	}
+	rows.ChunkDownloader.start()
+	dest := make([]driver.Value, 1)
+	if err := rows.Next(dest); err != nil {
+		t.Fatalf("failed to get value. err: %v", err)
+	}
+	if dest[0] != time.Hour+500*time.Millisecond {
+		t.Fatalf("unexpected value: %v", dest[0])
+	}
+	if typ := rows.ColumnTypeScanType(0); typ != reflect.TypeOf(time.Duration(0)) {
+		t.Fatalf("unexpected scan type: %v", typ)
+	}
+}
+
 func TestRowsWithChunkDownloader(t *testing.T) {
 	numChunks := 12
 	// changed the workers
@@ -385,3 +446,482 @@ func TestDownloadChunkErrorStatus(t *testing.T) {
 		t.Fatal("should have caused an error and queued in scd.ChunksError")
 	}
 }
+
+func TestMaxResultRows(t *testing.T) {
+	numChunks := 3
+	cm := make([]execResponseChunk, 0)
+	for i := 0; i < numChunks; i++ {
+		cm = append(cm, execResponseChunk{URL: fmt.Sprintf("dummyURL%v", i+1), RowCount: rowsInChunk})
+	}
+	v1, v2 := "0", "Test0"
+	maxRows := int64(rowsInChunk + 10)
+	rows := new(snowflakeRows)
+	rows.RowType = []execResponseRowType{
+		{Name: "c1", Type: "fixed"},
+		{Name: "c2", Type: "text"},
+	}
+	rows.ChunkDownloader = &snowflakeChunkDownloader{
+		ctx:           WithMaxResultRows(context.Background(), maxRows),
+		Total:         int64(1 + numChunks*rowsInChunk),
+		ChunkMetas:    cm,
+		TotalRowIndex: int64(-1),
+		FuncDownload:  downloadChunkTest,
+		RowSet:        rowSetType{JSON: [][]*string{{&v1, &v2}}},
+	}
+	rows.ChunkDownloader.start()
+	dest := make([]driver.Value, 2)
+	var cnt int64
+	var err error
+	for {
+		if err = rows.Next(dest); err != nil {
+			break
+		}
+		cnt++
+	}
+	if cnt != maxRows {
+		t.Fatalf("wrong number of rows. expected: %v, got: %v", maxRows, cnt)
+	}
+	driverErr, ok := err.(*SnowflakeError)
+	if !ok || driverErr.Number != ErrResultTruncated {
+		t.Fatalf("should have failed with ErrResultTruncated. err: %v", err)
+	}
+	if err = rows.Next(dest); err != driverErr {
+		t.Fatalf("should have failed with the same error. err: %v", err)
+	}
+	if !rows.ChunkDownloader.stopped() {
+		t.Fatal("the downloads should have been stopped")
+	}
+}
+
+func TestMaxResultBytes(t *testing.T) {
+	numChunks := 4
+	cm := make([]execResponseChunk, 0)
+	for i := 0; i < numChunks; i++ {
+		cm = append(cm, execResponseChunk{URL: fmt.Sprintf("dummyURL%v", i+1), RowCount: 3})
+	}
+	backupMaxChunkDownloadWorkers := MaxChunkDownloadWorkers
+	MaxChunkDownloadWorkers = 1
+	defer func() { MaxChunkDownloadWorkers = backupMaxChunkDownloadWorkers }()
+	rows := new(snowflakeRows)
+	rows.RowType = []execResponseRowType{
+		{Name: "c1", Type: "fixed"},
+		{Name: "c2", Type: "text"},
+	}
+	rows.ChunkDownloader = &snowflakeChunkDownloader{
+		sc: &snowflakeConn{
+			rest: &snowflakeRestful{RequestTimeout: defaultRequestTimeout},
+		},
+		ctx:                WithMaxResultBytes(context.Background(), int64(len(progressChunkBody))),
+		Total:              int64(numChunks * 3),
+		ChunkMetas:         cm,
+		TotalRowIndex:      int64(-1),
+		CellCount:          2,
+		FuncDownload:       downloadChunk,
+		FuncDownloadHelper: downloadChunkHelper,
+		FuncGet:            getChunkTestProgress,
+	}
+	rows.ChunkDownloader.start()
+	dest := make([]driver.Value, 2)
+	cnt := 0
+	var err error
+	for {
+		if err = rows.Next(dest); err != nil {
+			break
+		}
+		cnt++
+	}
+	if cnt != 3 {
+		t.Fatalf("only the first chunk should have been read. got: %v rows", cnt)
+	}
+	if driverErr, ok := err.(*SnowflakeError); !ok || driverErr.Number != ErrResultTruncated {
+		t.Fatalf("should have failed with ErrResultTruncated. err: %v", err)
+	}
+}
+
+func TestRowsWithChunkRetriesPerChunk(t *testing.T) {
+	numChunks := 8
+	cm := make([]execResponseChunk, 0)
+	for i := 0; i < numChunks; i++ {
+		cm = append(cm, execResponseChunk{URL: fmt.Sprintf("dummyURL%v", i+1), RowCount: rowsInChunk})
+	}
+	// every other chunk fails a few times, more than maxChunkDownloaderErrorCounter in total
+	failures := make(map[int]int)
+	v1, v2 := "0", "Test0"
+	rows := new(snowflakeRows)
+	rows.RowType = []execResponseRowType{
+		{Name: "c1", Type: "fixed"},
+		{Name: "c2", Type: "text"},
+	}
+	rows.ChunkDownloader = &snowflakeChunkDownloader{
+		ctx:           context.Background(),
+		Total:         int64(1 + numChunks*rowsInChunk),
+		ChunkMetas:    cm,
+		TotalRowIndex: int64(-1),
+		FuncDownload: func(ctx context.Context, scd *snowflakeChunkDownloader, idx int) {
+			scd.ChunksMutex.Lock()
+			failed := idx%2 == 1 && failures[idx] < maxChunkDownloaderErrorCounter-1
+			if failed {
+				failures[idx]++
+			}
+			scd.ChunksMutex.Unlock()
+			if failed {
+				scd.ChunksError <- &chunkError{Index: idx, Error: fmt.Errorf("dummy error. idx: %v", idx)}
+				scd.ChunksMutex.Lock()
+				scd.DoneDownloadCond.Broadcast()
+				scd.ChunksMutex.Unlock()
+				return
+			}
+			downloadChunkTest(ctx, scd, idx)
+		},
+		RowSet: rowSetType{JSON: [][]*string{{&v1, &v2}}},
+	}
+	if err := rows.ChunkDownloader.start(); err != nil {
+		t.Fatal(err)
+	}
+	dest := make([]driver.Value, 2)
+	cnt := 0
+	for {
+		err := rows.Next(dest)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("the retries should be counted per chunk. err: %v", err)
+		}
+		cnt++
+	}
+	if expected := 1 + numChunks*rowsInChunk; cnt != expected {
+		t.Fatalf("wrong number of rows. expected: %v, got: %v", expected, cnt)
+	}
+}
+
+func TestRowsWithSkipBadChunks(t *testing.T) {
+	numChunks := 12
+	cc := make([][]*string, 0)
+	for i := 0; i < 100; i++ {
+		v1 := fmt.Sprintf("%v", i)
+		v2 := fmt.Sprintf("Test%v", i)
+		cc = append(cc, []*string{&v1, &v2})
+	}
+	rt := []execResponseRowType{
+		{Name: "c1", ByteLength: 10, Length: 10, Type: "FIXED", Scale: 0, Nullable: true},
+		{Name: "c2", ByteLength: 100000, Length: 100000, Type: "TEXT", Scale: 0, Nullable: false},
+	}
+	cm := make([]execResponseChunk, 0)
+	for i := 0; i < numChunks; i++ {
+		cm = append(cm, execResponseChunk{URL: fmt.Sprintf("dummyURL%v", i+1), RowCount: rowsInChunk})
+	}
+	var skipped []SkippedChunk
+	var scd *snowflakeChunkDownloader
+	ctx := WithSkipBadChunks(context.Background(), func(c SkippedChunk) {
+		// the callback must not be called under ChunksMutex
+		locked := make(chan struct{})
+		go func() {
+			scd.ChunksMutex.Lock()
+			scd.ChunksMutex.Unlock()
+			close(locked)
+		}()
+		select {
+		case <-locked:
+		case <-time.After(time.Second):
+			t.Error("the callback was called holding ChunksMutex")
+		}
+		skipped = append(skipped, c)
+	})
+	rows := new(snowflakeRows)
+	rows.RowType = rt
+	scd = &snowflakeChunkDownloader{
+		ctx:           ctx,
+		queryID:       "qid",
+		Total:         int64(len(cc) + numChunks*rowsInChunk),
+		ChunkMetas:    cm,
+		TotalRowIndex: int64(-1),
+		Qrmk:          "HOHOHO",
+		FuncDownload:  downloadChunkTestErrorFail,
+		RowSet:        rowSetType{JSON: cc},
+	}
+	rows.ChunkDownloader = scd
+	if err := rows.ChunkDownloader.start(); err != nil {
+		t.Fatal(err)
+	}
+	cnt := 0
+	dest := make([]driver.Value, 2)
+	for {
+		err := rows.Next(dest)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("the bad chunk should have been skipped. err: %v", err)
+		}
+		cnt++
+	}
+	if expected := len(cc) + (numChunks-1)*rowsInChunk; cnt != expected {
+		t.Fatalf("wrong number of rows. expected: %v, got: %v", expected, cnt)
+	}
+	if len(skipped) != 1 || skipped[0].Index != 6 || skipped[0].Rows != rowsInChunk ||
+		skipped[0].QueryID != "qid" || skipped[0].Err == nil {
+		t.Fatalf("unexpected skipped chunks: %+v", skipped)
+	}
+}
+
+func TestChunkDecodeWorkers(t *testing.T) {
+	backupMaxChunkDecodeWorkers := MaxChunkDecodeWorkers
+	defer func() { MaxChunkDecodeWorkers = backupMaxChunkDecodeWorkers }()
+	MaxChunkDecodeWorkers = 0
+	if n := chunkDecodeWorkers(); n != runtime.GOMAXPROCS(0) {
+		t.Fatalf("should default to GOMAXPROCS. got: %v", n)
+	}
+	MaxChunkDecodeWorkers = 1
+
+	numChunks := 8
+	cm := make([]execResponseChunk, 0)
+	for i := 0; i < numChunks; i++ {
+		cm = append(cm, execResponseChunk{URL: fmt.Sprintf("dummyURL%v", i+1), RowCount: 3})
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rows := new(snowflakeRows)
+	rows.RowType = []execResponseRowType{
+		{Name: "c1", Type: "fixed"},
+		{Name: "c2", Type: "text"},
+	}
+	rows.ChunkDownloader = &snowflakeChunkDownloader{
+		sc: &snowflakeConn{
+			rest: &snowflakeRestful{RequestTimeout: defaultRequestTimeout},
+		},
+		ctx:                ctx,
+		Total:              int64(numChunks * 3),
+		ChunkMetas:         cm,
+		TotalRowIndex:      int64(-1),
+		CellCount:          2,
+		FuncDownload:       downloadChunk,
+		FuncDownloadHelper: downloadChunkHelper,
+		FuncGet:            getChunkTestProgress,
+	}
+	if err := rows.ChunkDownloader.start(); err != nil {
+		t.Fatal(err)
+	}
+	dest := make([]driver.Value, 2)
+	cnt := 0
+	var err error
+	for {
+		if err = rows.Next(dest); err != nil {
+			break
+		}
+		if dest[1] != string(rune('a'+cnt%3)) {
+			t.Fatalf("the rows should be in order. row %v: %v", cnt, dest)
+		}
+		cnt++
+	}
+	if err != io.EOF || cnt != numChunks*3 {
+		t.Fatalf("failed to read all rows. rows: %v, err: %v", cnt, err)
+	}
+
+	scd := rows.ChunkDownloader
+	if err = scd.acquireDecodeWorker(); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if err = scd.acquireDecodeWorker(); err != context.Canceled {
+		t.Fatalf("should wait for the decode worker until the context is canceled. err: %v", err)
+	}
+	scd.releaseDecodeWorker()
+}
+
+// goroutineStacks returns the stacks of all goroutines by goroutine ID.
+func goroutineStacks() map[string]string {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	stacks := make(map[string]string)
+	for _, stack := range strings.Split(string(buf), "\n\n") {
+		// goroutine 7 [running]:
+		if fields := strings.Fields(stack); len(fields) > 1 && fields[0] == "goroutine" {
+			stacks[fields[1]] = stack
+		}
+	}
+	return stacks
+}
+
+// checkGoroutines fails the test if a goroutine that didn't exist before the test, e.g., of a chunk download,
+// is still running after a while, as goleak.VerifyNone does.
+func checkGoroutines(t *testing.T, before map[string]string) {
+	for i := 0; ; i++ {
+		var leaked []string
+		for id, stack := range goroutineStacks() {
+			if _, ok := before[id]; !ok {
+				leaked = append(leaked, stack)
+			}
+		}
+		if len(leaked) == 0 {
+			return
+		}
+		if i > 500 {
+			t.Fatalf("the goroutines started by the test should have terminated. goroutines:\n%v",
+				strings.Join(leaked, "\n\n"))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// newBlockingChunkDownloader returns the chunk downloader whose downloads wait until release is closed or the
+// context is done, counting the downloads in calls.
+func newBlockingChunkDownloader(ctx context.Context, numChunks int, release chan struct{}, calls *int32) *snowflakeChunkDownloader {
+	cm := make([]execResponseChunk, 0)
+	for i := 0; i < numChunks; i++ {
+		cm = append(cm, execResponseChunk{URL: fmt.Sprintf("dummyURL%v", i+1), RowCount: 3})
+	}
+	return &snowflakeChunkDownloader{
+		sc: &snowflakeConn{
+			rest: &snowflakeRestful{RequestTimeout: defaultRequestTimeout},
+		},
+		ctx:                ctx,
+		Total:              int64(numChunks * 3),
+		ChunkMetas:         cm,
+		TotalRowIndex:      int64(-1),
+		CellCount:          2,
+		FuncDownload:       downloadChunk,
+		FuncDownloadHelper: downloadChunkHelper,
+		FuncGet: func(ctx context.Context, scd *snowflakeChunkDownloader, u string, h map[string]string, timeout time.Duration) (
+			*http.Response, error) {
+			atomic.AddInt32(calls, 1)
+			select {
+			case <-release:
+				return getChunkTestProgress(ctx, scd, u, h, timeout)
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		},
+	}
+}
+
+func TestChunkDownloaderCloseEarly(t *testing.T) {
+	goroutines := goroutineStacks()
+	backupMaxChunkDownloadWorkers := MaxChunkDownloadWorkers
+	MaxChunkDownloadWorkers = 2
+	defer func() { MaxChunkDownloadWorkers = backupMaxChunkDownloadWorkers }()
+	var calls int32
+	rows := new(snowflakeRows)
+	rows.ChunkDownloader = newBlockingChunkDownloader(context.Background(), 8, make(chan struct{}), &calls)
+	if err := rows.ChunkDownloader.start(); err != nil {
+		t.Fatal(err)
+	}
+	for atomic.LoadInt32(&calls) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	if err := rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// the downloads are canceled, so that close returns without releasing them
+	checkGoroutines(t, goroutines)
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Fatalf("no chunk should be downloaded after close. downloads: %v", n)
+	}
+	if rows.ChunkDownloader.Chunks != nil {
+		t.Fatalf("the chunks should be released. chunks: %v", rows.ChunkDownloader.Chunks)
+	}
+	if err := rows.ChunkDownloader.ctx.Err(); err != context.Canceled {
+		t.Fatalf("the downloads should be canceled. err: %v", err)
+	}
+	if err := rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestChunkDownloaderContextCanceled(t *testing.T) {
+	goroutines := goroutineStacks()
+	backupMaxChunkDownloadWorkers := MaxChunkDownloadWorkers
+	MaxChunkDownloadWorkers = 2
+	defer func() { MaxChunkDownloadWorkers = backupMaxChunkDownloadWorkers }()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var calls int32
+	rows := new(snowflakeRows)
+	rows.RowType = []execResponseRowType{
+		{Name: "c1", Type: "fixed"},
+		{Name: "c2", Type: "text"},
+	}
+	rows.ChunkDownloader = newBlockingChunkDownloader(ctx, 8, make(chan struct{}), &calls)
+	if err := rows.ChunkDownloader.start(); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for atomic.LoadInt32(&calls) < 2 {
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+	}()
+	if err := rows.Next(make([]driver.Value, 2)); err != context.Canceled {
+		t.Fatalf("the canceled context should stop the download. err: %v", err)
+	}
+	if err := rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+	checkGoroutines(t, goroutines)
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Fatalf("the canceled downloads should not be retried. downloads: %v", n)
+	}
+}
+
+func TestRowsRowCounts(t *testing.T) {
+	v1, v2 := "1", "a"
+	cm := []execResponseChunk{{URL: "dummyURL1", RowCount: rowsInChunk}, {URL: "dummyURL2", RowCount: rowsInChunk}}
+	rows := new(snowflakeRows)
+	rows.ChunkDownloader = &snowflakeChunkDownloader{
+		ctx:           context.Background(),
+		Total:         int64(2 + 2*rowsInChunk),
+		ChunkMetas:    cm,
+		TotalRowIndex: int64(-1),
+		FuncDownload:  downloadChunkTest,
+		RowSet:        rowSetType{JSON: [][]*string{{&v1, &v2}, {&v1, &v2}}},
+	}
+	if err := rows.ChunkDownloader.start(); err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var sr SnowflakeRows = rows
+	if n := sr.TotalRows(); n != int64(2+2*rowsInChunk) {
+		t.Fatalf("unexpected total rows: %v", n)
+	}
+	if counts := sr.ChunkRowCounts(); !reflect.DeepEqual(counts, []int{2, rowsInChunk, rowsInChunk}) {
+		t.Fatalf("unexpected chunk row counts: %v", counts)
+	}
+	if n := (&snowflakeRows{}).TotalRows(); n != 0 {
+		t.Fatalf("the rows without a result should have no rows. got: %v", n)
+	}
+}
+
+func TestRowsNullValues(t *testing.T) {
+	empty := ""
+	rows := new(snowflakeRows)
+	rows.RowType = []execResponseRowType{{Name: "c1", Type: "text"}, {Name: "c2", Type: "fixed"}}
+	rows.ChunkDownloader = &snowflakeChunkDownloader{
+		ctx:           context.Background(),
+		Total:         2,
+		TotalRowIndex: int64(-1),
+		RowSet:        rowSetType{JSON: [][]*string{{nil, nil}, {&empty, nil}}},
+	}
+	if err := rows.ChunkDownloader.start(); err != nil {
+		t.Fatal(err)
+	}
+	dest := make([]driver.Value, 2)
+	if err := rows.Next(dest); err != nil {
+		t.Fatal(err)
+	}
+	if dest[0] != nil || dest[1] != nil {
+		t.Fatalf("NULL should be nil. got: %v", dest)
+	}
+	if err := rows.Next(dest); err != nil {
+		t.Fatal(err)
+	}
+	if dest[0] != "" {
+		t.Fatalf("the empty string should stay empty. got: %v", dest)
+	}
+}
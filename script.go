@@ -0,0 +1,314 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// StatementResult is the result of a statement of a script run by ExecScript.
+type StatementResult struct {
+	Index        int    // position of the statement in the script, from 0
+	Line         int    // line of the script the statement starts at, from 1
+	Statement    string // text of the statement without the terminating semicolon
+	QueryID      string
+	RowsAffected int64  // number of rows changed by a DML statement, 0 otherwise
+	Status       string // status message of Snowflake for a statement that is not a DML statement
+	// Duration is the time the statement took to run. The statements run in one multi-statement query have the
+	// duration of the whole query.
+	Duration time.Duration
+	Err      error // the reason of the failure, nil if the statement succeeded
+	// NotRun is set for the statements after the first one of a batch whose multi-statement query failed as a
+	// whole, as it is not known which of them were run. The error of the query is the Err of the first statement.
+	NotRun bool
+}
+
+// ScriptOptions are the options of ExecScript.
+type ScriptOptions struct {
+	// BatchSize is the number of statements run together in a multi-statement query. By default, the statements
+	// are run one at a time.
+	BatchSize int
+	// ContinueOnError runs the rest of the script after a statement fails. By default, ExecScript stops at the
+	// first failure.
+	ContinueOnError bool
+	// OnResult, if set, is called with the result of every statement as soon as it is run, e.g., to report the
+	// progress of a long script.
+	OnResult func(StatementResult)
+}
+
+// ExecScript reads the SQL script, e.g., a migration in a .sql file, and runs its statements in order on the
+// session of the Client. The statements are separated by semicolons outside of the string literals, the $$
+// literals, the quoted identifiers and the comments, and are read one at a time, so that a script of any size
+// can be run. A Snowflake Scripting block must be enclosed in $$ for its semicolons not to end the statement.
+//
+// It returns the results of the statements run, and the first error unless the options have ContinueOnError.
+// A malformed script, e.g., with a string literal that is not terminated, fails once its statements up to the
+// malformed one are run.
+func (c *Client) ExecScript(ctx context.Context, script io.Reader, opts *ScriptOptions) ([]StatementResult, error) {
+	if opts == nil {
+		opts = &ScriptOptions{}
+	}
+	batchSize := opts.BatchSize
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	sr := newStatementReader(script)
+	var results []StatementResult
+	var firstErr error
+	for {
+		var batch []StatementResult
+		var readErr error
+		for len(batch) < batchSize {
+			line, stmt, err := sr.next()
+			if err != nil {
+				readErr = err
+				break
+			}
+			batch = append(batch, StatementResult{Index: len(results) + len(batch), Line: line, Statement: stmt})
+		}
+		if len(batch) > 0 {
+			c.execBatch(ctx, batch)
+			for _, res := range batch {
+				if opts.OnResult != nil {
+					opts.OnResult(res)
+				}
+				if res.Err != nil && firstErr == nil {
+					firstErr = res.Err
+				}
+			}
+			results = append(results, batch...)
+		}
+		if readErr == io.EOF {
+			return results, firstErr
+		}
+		if readErr != nil {
+			return results, readErr
+		}
+		if firstErr != nil && !opts.ContinueOnError {
+			return results, firstErr
+		}
+	}
+}
+
+// execBatch runs the statements, in a multi-statement query if there are more than one, and sets their results.
+func (c *Client) execBatch(ctx context.Context, batch []StatementResult) {
+	query := batch[0].Statement
+	if len(batch) > 1 {
+		stmts := make([]string, len(batch))
+		for i, res := range batch {
+			stmts[i] = res.Statement
+		}
+		query = strings.Join(stmts, ";\n")
+		var err error
+		if ctx, err = WithMultiStatement(ctx, len(batch)); err != nil {
+			failBatch(batch, err)
+			return
+		}
+	}
+	start := time.Now()
+	res, err := c.sc.ExecContext(ctx, query, nil)
+	duration := time.Since(start)
+	for i := range batch {
+		batch[i].Duration = duration
+	}
+	if err != nil {
+		var me *MultiStatementError
+		if !errors.As(err, &me) {
+			failBatch(batch, err)
+			return
+		}
+		for i, child := range me.children {
+			if i < len(batch) {
+				batch[i].QueryID, batch[i].RowsAffected = child.queryID, child.rowCounts.Total()
+			}
+		}
+		for _, ce := range me.Errors {
+			if ce.Index < len(batch) {
+				batch[ce.Index].QueryID, batch[ce.Index].Err = ce.QueryID, ce.Err
+			}
+		}
+		return
+	}
+	if len(batch) == 1 {
+		er := c.execResult(res)
+		batch[0].QueryID, batch[0].RowsAffected = er.QueryID(), er.RowCounts().Total()
+		if ddl, ok := res.(SnowflakeDDLResult); ok {
+			batch[0].Status = ddl.Status()
+		}
+		return
+	}
+	for i, child := range res.(*snowflakeResult).children {
+		if i < len(batch) {
+			batch[i].QueryID, batch[i].RowsAffected = child.queryID, child.rowCounts.Total()
+		}
+	}
+}
+
+// failBatch sets the error of the batch that failed as a whole to its first statement, and marks the others as
+// not run.
+func failBatch(batch []StatementResult, err error) {
+	batch[0].Err = err
+	for i := range batch[1:] {
+		batch[i+1].NotRun = true
+	}
+}
+
+// SplitStatements splits the SQL script into its statements, the same way as ExecScript, without the terminating
+// semicolons. The statements that have only comments are dropped.
+func SplitStatements(script string) ([]string, error) {
+	sr := newStatementReader(strings.NewReader(script))
+	var stmts []string
+	for {
+		_, stmt, err := sr.next()
+		if err == io.EOF {
+			return stmts, nil
+		}
+		if err != nil {
+			return stmts, err
+		}
+		stmts = append(stmts, stmt)
+	}
+}
+
+// statementReader reads the statements of a SQL script one at a time.
+type statementReader struct {
+	r    *bufio.Reader
+	line int
+}
+
+func newStatementReader(r io.Reader) *statementReader {
+	return &statementReader{r: bufio.NewReader(r), line: 1}
+}
+
+// next returns the next statement that is not only comments, and the line it starts at. It returns io.EOF at the
+// end of the script.
+func (sr *statementReader) next() (int, string, error) {
+	var buf strings.Builder
+	content := false
+	start := 0
+	for {
+		c, err := sr.readByte()
+		if err == io.EOF {
+			if content {
+				return start, strings.TrimSpace(buf.String()), nil
+			}
+			return 0, "", io.EOF
+		}
+		if err != nil {
+			return 0, "", err
+		}
+		if start == 0 && !isSpace(c) {
+			start = sr.line
+		}
+		if c == ';' {
+			if content {
+				return start, strings.TrimSpace(buf.String()), nil
+			}
+			buf.Reset()
+			start = 0
+			continue
+		}
+		buf.WriteByte(c)
+		next, _ := sr.r.Peek(1)
+		comment := false
+		switch {
+		case c == '\'':
+			err = sr.copyUntil(&buf, "'", true, start)
+		case c == '"':
+			err = sr.copyUntil(&buf, `"`, false, start)
+		case c == '$' && len(next) == 1 && next[0] == '$':
+			sr.copyByte(&buf)
+			err = sr.copyUntil(&buf, "$$", false, start)
+		case (c == '-' || c == '/') && len(next) == 1 && next[0] == c:
+			// line comments start with -- or //
+			if err = sr.copyUntil(&buf, "\n", false, start); err == io.ErrUnexpectedEOF {
+				err = nil
+			}
+			comment = true
+		case c == '/' && len(next) == 1 && next[0] == '*':
+			sr.copyByte(&buf)
+			err = sr.copyUntil(&buf, "*/", false, start)
+			comment = true
+		}
+		if err != nil {
+			return 0, "", err
+		}
+		if !comment && !isSpace(c) {
+			content = true
+		}
+	}
+}
+
+// copyUntil copies the script up to and including the terminator, skipping the characters escaped with a
+// backslash if escapes is true. If the script ends before the terminator, it returns io.ErrUnexpectedEOF for a
+// line comment, which may end the script, and an error for the others.
+func (sr *statementReader) copyUntil(buf *strings.Builder, terminator string, escapes bool, start int) error {
+	for {
+		c, err := sr.readByte()
+		if err == io.EOF {
+			if terminator == "\n" {
+				return io.ErrUnexpectedEOF
+			}
+			return fmt.Errorf("the statement at line %v has an unterminated %v", start, unterminatedName(terminator))
+		}
+		if err != nil {
+			return err
+		}
+		buf.WriteByte(c)
+		if escapes && c == '\\' {
+			if _, err = sr.copyByte(buf); err != nil && err != io.EOF {
+				return err
+			}
+			continue
+		}
+		if c != terminator[0] {
+			continue
+		}
+		if len(terminator) == 1 {
+			return nil
+		}
+		if next, _ := sr.r.Peek(1); len(next) == 1 && next[0] == terminator[1] {
+			sr.copyByte(buf)
+			return nil
+		}
+	}
+}
+
+func unterminatedName(terminator string) string {
+	switch terminator {
+	case "'":
+		return "string literal"
+	case `"`:
+		return "quoted identifier"
+	case "$$":
+		return "$$ literal"
+	default:
+		return "comment"
+	}
+}
+
+func (sr *statementReader) copyByte(buf *strings.Builder) (byte, error) {
+	c, err := sr.readByte()
+	if err == nil {
+		buf.WriteByte(c)
+	}
+	return c, err
+}
+
+func (sr *statementReader) readByte() (byte, error) {
+	c, err := sr.r.ReadByte()
+	if c == '\n' && err == nil {
+		sr.line++
+	}
+	return c, err
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
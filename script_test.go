@@ -0,0 +1,153 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestSplitStatements(t *testing.T) {
+	script := `-- create the table
+CREATE TABLE t (s STRING, "a;b" INT);
+INSERT INTO t VALUES ('it''s; here', 1), ('back\'slash;', 2);;
+/* a comment; with a semicolon */
+CREATE FUNCTION f() RETURNS STRING AS $$ 'x;y' $$;
+/* only; a comment */;
+// a line comment
+SELECT 1 // trailing comment
+`
+	stmts, err := SplitStatements(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"-- create the table\nCREATE TABLE t (s STRING, \"a;b\" INT)",
+		`INSERT INTO t VALUES ('it''s; here', 1), ('back\'slash;', 2)`,
+		"/* a comment; with a semicolon */\nCREATE FUNCTION f() RETURNS STRING AS $$ 'x;y' $$",
+		"// a line comment\nSELECT 1 // trailing comment",
+	}
+	if !reflect.DeepEqual(stmts, expected) {
+		t.Fatalf("unexpected statements. expected: %q, got: %q", expected, stmts)
+	}
+
+	for _, script := range []string{"SELECT 'a", `SELECT "a`, "SELECT $$a$", "SELECT 1 /* a"} {
+		if _, err = SplitStatements(script); err == nil {
+			t.Errorf("the script should be malformed: %v", script)
+		}
+	}
+	if stmts, err = SplitStatements(" ;\n-- nothing\n"); err != nil || len(stmts) != 0 {
+		t.Errorf("the script should have no statements. got: %q, err: %v", stmts, err)
+	}
+}
+
+// scriptTestClient returns the Client whose statements fail if they have FAIL and are an INSERT of one row if they
+// start with INSERT.
+func scriptTestClient() *fakeQueryClient {
+	c := newFakeQueryClient(nil)
+	c.respond = func(req execRequest) (*execResponse, error) {
+		qid := fmt.Sprintf("qid-%v", len(c.requests))
+		if strings.Contains(req.SQLText, "FAIL") {
+			return &execResponse{Data: execResponseData{QueryID: qid}, Code: "1003", Message: "syntax error"}, nil
+		}
+		if !strings.HasPrefix(req.SQLText, "INSERT") {
+			return fakeQueryResponse(qid, nil), nil
+		}
+		resp := fakeQueryResponse(qid, []execResponseRowType{{Name: "number of rows inserted", Type: "fixed"}}, []string{"1"})
+		resp.Data.StatementTypeID = statementTypeIDInsert
+		return resp, nil
+	}
+	return c
+}
+
+func TestExecScript(t *testing.T) {
+	script := "CREATE TABLE t (i INT);\nINSERT INTO t VALUES (1);\n\nFAIL;\nINSERT INTO t VALUES (2)"
+	c := scriptTestClient()
+	var reported []StatementResult
+	results, err := c.ExecScript(context.Background(), strings.NewReader(script),
+		&ScriptOptions{OnResult: func(res StatementResult) { reported = append(reported, res) }})
+	if driverErr, ok := err.(*SnowflakeError); !ok || driverErr.Number != 1003 {
+		t.Fatalf("the script should fail with the error of the statement. err: %v", err)
+	}
+	if queries := c.queries(); len(queries) != 3 || len(results) != 3 || !reflect.DeepEqual(results, reported) {
+		t.Fatalf("the script should stop at the failure. queries: %q, results: %+v", queries, results)
+	}
+	if r := results[0]; r.Index != 0 || r.Line != 1 || r.QueryID != "qid-1" || r.Statement != "CREATE TABLE t (i INT)" || r.Err != nil {
+		t.Errorf("unexpected result of the DDL: %+v", r)
+	}
+	if r := results[1]; r.Index != 1 || r.Line != 2 || r.QueryID != "qid-2" || r.RowsAffected != 1 || r.Err != nil {
+		t.Errorf("unexpected result of the INSERT: %+v", r)
+	}
+	if r := results[2]; r.Index != 2 || r.Line != 4 || r.Err != err {
+		t.Errorf("unexpected result of the failure: %+v", r)
+	}
+
+	c = scriptTestClient()
+	results, err = c.ExecScript(context.Background(), strings.NewReader(script), &ScriptOptions{ContinueOnError: true})
+	if queries := c.queries(); err == nil || len(queries) != 4 || len(results) != 4 || results[3].RowsAffected != 1 || results[3].Line != 5 {
+		t.Fatalf("the script should continue after the failure. queries: %q, results: %+v, err: %v", queries, results, err)
+	}
+
+	c = scriptTestClient()
+	results, err = c.ExecScript(context.Background(), strings.NewReader("SELECT 1; SELECT 'a"), nil)
+	if queries := c.queries(); err == nil || len(queries) != 1 || len(results) != 1 || results[0].Err != nil {
+		t.Fatalf("the statements before the malformed one should be run. queries: %q, results: %+v, err: %v",
+			queries, results, err)
+	}
+}
+
+func TestExecScriptBatches(t *testing.T) {
+	var body []byte
+	c := &Client{sc: &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{
+			FuncPostQuery: func(ctx context.Context, sr *snowflakeRestful, params *url.Values, headers map[string]string, b []byte, timeout time.Duration, requestID *uuid.UUID) (*execResponse, error) {
+				body = append([]byte(nil), b...)
+				return postMultiStatementMock(ctx, sr, params, headers, b, timeout, requestID)
+			},
+			FuncGet: getMultiStatementChildMock,
+		},
+	}}
+	script := "INSERT INTO t1 VALUES(1); INSERT INTO t2 VALUES(1); SELECT 1;"
+	results, err := c.ExecScript(context.Background(), strings.NewReader(script), &ScriptOptions{BatchSize: 3})
+	if err == nil || len(results) != 3 {
+		t.Fatalf("the batch should fail. results: %+v, err: %v", results, err)
+	}
+	var req execRequest
+	if err = json.Unmarshal(body, &req); err != nil {
+		t.Fatal(err)
+	}
+	if req.SQLText != "INSERT INTO t1 VALUES(1);\nINSERT INTO t2 VALUES(1);\nSELECT 1" ||
+		req.Parameters[string(MultiStatementCount)] != float64(3) {
+		t.Fatalf("the statements should be run in one multi-statement query. request: %+v", req)
+	}
+	if r := results[0]; r.Err != nil || r.QueryID != "child-0" || r.RowsAffected != 3 {
+		t.Errorf("the first statement should succeed with the result of its child query: %+v", r)
+	}
+	for _, r := range results[1:] {
+		if r.Err == nil || r.QueryID != fmt.Sprintf("child-%v", r.Index) || r.Duration != results[0].Duration {
+			t.Errorf("the statement should fail with the error of its child query: %+v", r)
+		}
+	}
+
+	c.sc.rest.FuncPostQuery = func(context.Context, *snowflakeRestful, *url.Values, map[string]string, []byte, time.Duration, *uuid.UUID) (*execResponse, error) {
+		return nil, fmt.Errorf("connection reset")
+	}
+	results, err = c.ExecScript(context.Background(), strings.NewReader(script), &ScriptOptions{BatchSize: 3})
+	if err == nil || len(results) != 3 || results[0].Err == nil || results[0].NotRun {
+		t.Fatalf("the first statement should have the error of the batch. results: %+v, err: %v", results, err)
+	}
+	for _, r := range results[1:] {
+		if r.Err != nil || !r.NotRun {
+			t.Errorf("the other statements of the batch should not be known to have run: %+v", r)
+		}
+	}
+}
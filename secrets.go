@@ -0,0 +1,38 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"strings"
+)
+
+// secretFieldWords are the words, in lower case without the underscores and the dashes, of the names of the JSON
+// fields with credentials: the password of the login request, the tokens of its response, the QRMK and the chunk
+// headers of the query response, and the stage credentials and the master key of the PUT and GET responses, e.g.,
+// AWS_KEY_ID, AWS_SECRET_KEY, AWS_TOKEN, AZURE_SAS_TOKEN and queryStageMasterKey.
+var secretFieldWords = []string{
+	"password",
+	"passcode",
+	"token",
+	"secret",
+	"privatekey",
+	"proofkey",
+	"samlresponse",
+	"qrmk",
+	"masterkey",
+	"awskeyid",
+	"chunkheaders",
+	"customerkey",
+}
+
+// isSecretField returns true if the JSON field of the name has a credential, which the wire trace and the
+// cassettes scrub.
+func isSecretField(name string) bool {
+	name = strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(name))
+	for _, word := range secretFieldWords {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
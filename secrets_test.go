@@ -0,0 +1,59 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// putResponseFixture is the response of a PUT with the credentials of the stage and the master key of the files.
+const putResponseFixture = `{"data":{"command":"UPLOAD","src_locations":["/tmp/data.csv"],"parallel":4,"autoCompress":true,` +
+	`"overwrite":false,"sourceCompression":"auto_detect","stageInfo":{"locationType":"S3",` +
+	`"location":"sfc-stage/tables/12345/","path":"tables/12345/","region":"us-west-2","isClientSideEncrypted":true,` +
+	`"creds":{"AWS_KEY_ID":"ASIAKEYID1234567","AWS_SECRET_KEY":"secretKey+abcdef","AWS_TOKEN":"sessionTokenABCDEF",` +
+	`"AZURE_SAS_TOKEN":"sv=2019&sig=sasSignature","GCS_ACCESS_TOKEN":"gcsAccessToken"}},` +
+	`"encryptionMaterial":{"queryStageMasterKey":"bWFzdGVyS2V5TWFzdGVyS2V5","queryId":"01a2b3c4","smkId":1234},` +
+	`"presignedUrl":"https://sfc-stage.s3.amazonaws.com/data.csv.gz?X-Amz-Signature=presignedSignature"},` +
+	`"code":null,"message":null,"success":true}`
+
+func TestSecretsPutResponse(t *testing.T) {
+	secrets := []string{"ASIAKEYID1234567", "secretKey+abcdef", "sessionTokenABCDEF", "sasSignature", "gcsAccessToken",
+		"bWFzdGVyS2V5TWFzdGVyS2V5"}
+	kept := []string{"sfc-stage/tables/12345/", "us-west-2", "01a2b3c4", "/tmp/data.csv"}
+
+	scrubbedBody := scrubCassetteBody([]byte(putResponseFixture))
+	var trace bytes.Buffer
+	writeTraceBody(&trace, []byte(putResponseFixture), int64(len(putResponseFixture)))
+	for name, out := range map[string]string{"cassette": scrubbedBody, "wire trace": trace.String()} {
+		for _, secret := range secrets {
+			if strings.Contains(out, secret) {
+				t.Errorf("%v should be scrubbed from the %v: %v", secret, name, out)
+			}
+		}
+		for _, value := range kept {
+			if !strings.Contains(out, value) {
+				t.Errorf("%v should be kept in the %v: %v", value, name, out)
+			}
+		}
+	}
+	if strings.Contains(scrubbedBody, "presignedSignature") {
+		t.Errorf("the signature of the presigned URL should be scrubbed from the cassette: %v", scrubbedBody)
+	}
+}
+
+func TestIsSecretField(t *testing.T) {
+	for _, name := range []string{"password", "PASSCODE", "token", "masterToken", "idToken", "proof_key",
+		"RAW_SAML_RESPONSE", "qrmk", "chunkHeaders", "x-amz-server-side-encryption-customer-key", "AWS_KEY_ID",
+		"AWS_SECRET_KEY", "AWS_TOKEN", "AZURE_SAS_TOKEN", "GCS_ACCESS_TOKEN", "queryStageMasterKey"} {
+		if !isSecretField(name) {
+			t.Errorf("%v should be a secret", name)
+		}
+	}
+	for _, name := range []string{"queryId", "location", "sqlText", "rowset", "smkId"} {
+		if isSecretField(name) {
+			t.Errorf("%v should not be a secret", name)
+		}
+	}
+}
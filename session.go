@@ -0,0 +1,98 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"database/sql/driver"
+	"time"
+)
+
+// sessionExpiryMargin is how long before their expiration the tokens of a pooled connection are renewed, so that
+// the connection handed out by database/sql doesn't fail the first query on an expired token.
+const sessionExpiryMargin = time.Minute
+
+// ExportedSession is an authenticated session handed off to another process, e.g., the next invocation of
+// a command line tool, so that it doesn't have to log in again. It contains secrets and must be stored securely.
+type ExportedSession struct {
+	Token           string `json:"token"`
+	MasterToken     string `json:"masterToken"`
+	SessionID       int    `json:"sessionId"`
+	SequenceCounter uint64 `json:"sequenceCounter"`
+}
+
+// ExportSession returns the session of the connection so that it can be resumed by ResumeSession. The session
+// is kept alive on the server when the connection is closed.
+func (sc *snowflakeConn) ExportSession() ExportedSession {
+	sc.keepSession = true
+	return ExportedSession{
+		Token:           sc.rest.Token,
+		MasterToken:     sc.rest.MasterToken,
+		SessionID:       sc.rest.SessionID,
+		SequenceCounter: sc.SequenceCounter,
+	}
+}
+
+// ResumeSession creates a connection that uses the exported session instead of logging in. The Config must be
+// for the same account as the connection the session was exported from. An expired session token is renewed with
+// the master token. The credentials in the Config are not required. Closing the connection keeps the session
+// so that it can be resumed again.
+func (d SnowflakeDriver) ResumeSession(_ context.Context, config Config, session ExportedSession) (driver.Conn, error) {
+	glog.V(2).Info("ResumeSession")
+	if session.Token == "" || session.MasterToken == "" {
+		return nil, &SnowflakeError{
+			Number:  ErrCodeInvalidExportedSession,
+			Message: errMsgInvalidExportedSession,
+		}
+	}
+	sc, err := newSnowflakeConn(config, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	sc.rest.Token = session.Token
+	sc.rest.MasterToken = session.MasterToken
+	sc.rest.SessionID = session.SessionID
+	sc.SequenceCounter = session.SequenceCounter
+	sc.keepSession = true
+	sc.info = ConnectionInfo{SessionID: session.SessionID, Parameters: map[string]string{}}
+	sc.startHeartBeat()
+	return sc, nil
+}
+
+// ResetSession implements driver.SessionResetter. database/sql calls it before a pooled connection is reused. The
+// tokens that expire within sessionExpiryMargin are renewed. If the session has expired or fails to be renewed,
+// driver.ErrBadConn is returned so that database/sql discards the connection and opens a new one, instead of the
+// next query failing on the expired session.
+func (sc *snowflakeConn) ResetSession(ctx context.Context) error {
+	if sc.rest == nil {
+		return driver.ErrBadConn
+	}
+	tokenExpiry, masterTokenExpiry := sc.rest.getTokenExpiry()
+	now := time.Now()
+	if !masterTokenExpiry.IsZero() && !now.Before(masterTokenExpiry) {
+		glog.V(2).Infof("the session expired at %v", masterTokenExpiry)
+		return driver.ErrBadConn
+	}
+	if expiresSoon(now, tokenExpiry) || expiresSoon(now, masterTokenExpiry) {
+		glog.V(2).Infof("renewing the session. token expiry: %v, master token expiry: %v", tokenExpiry, masterTokenExpiry)
+		if err := sc.rest.FuncRenewSession(ctx, sc.rest, sc.rest.RequestTimeout); err != nil {
+			glog.V(1).Infof("failed to renew the session. err: %v", err)
+			return driver.ErrBadConn
+		}
+	}
+	return nil
+}
+
+// IsValid implements driver.Validator. A connection that is closed or whose session has expired is not valid, so
+// that database/sql doesn't return it to the pool.
+func (sc *snowflakeConn) IsValid() bool {
+	if sc.rest == nil {
+		return false
+	}
+	_, masterTokenExpiry := sc.rest.getTokenExpiry()
+	return masterTokenExpiry.IsZero() || time.Now().Before(masterTokenExpiry)
+}
+
+func expiresSoon(now time.Time, expiry time.Time) bool {
+	return !expiry.IsZero() && !now.Add(sessionExpiryMargin).Before(expiry)
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+)
+
+var sessionClosed bool
+
+func closeSessionMustNotBeCalled(_ context.Context, _ *snowflakeRestful, _ time.Duration) error {
+	sessionClosed = true
+	return errors.New("the session must not be closed")
+}
+
+func TestExportAndResumeSession(t *testing.T) {
+	sc := &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{
+			Token:       "token",
+			MasterToken: "master",
+			SessionID:   123,
+		},
+		SequenceCounter: 7,
+	}
+	var conn SnowflakeConnection = sc
+	session := conn.ExportSession()
+	expected := ExportedSession{Token: "token", MasterToken: "master", SessionID: 123, SequenceCounter: 7}
+	if session != expected {
+		t.Fatalf("unexpected session. expected: %+v, got: %+v", expected, session)
+	}
+	if !sc.keepSession {
+		t.Fatal("the exported session should be kept on close")
+	}
+
+	resumed, err := SnowflakeDriver{}.ResumeSession(context.Background(), Config{Account: "a"}, session)
+	if err != nil {
+		t.Fatalf("failed to resume the session. err: %v", err)
+	}
+	rsc := resumed.(*snowflakeConn)
+	if rsc.rest.Token != "token" || rsc.rest.MasterToken != "master" || rsc.rest.SessionID != 123 ||
+		rsc.SequenceCounter != 7 {
+		t.Fatalf("the session was not resumed. rest: %+v", rsc.rest)
+	}
+	if rsc.ConnectionInfo().SessionID != 123 {
+		t.Fatalf("unexpected session ID: %v", rsc.ConnectionInfo().SessionID)
+	}
+	rsc.rest.FuncCloseSession = closeSessionMustNotBeCalled
+	if err = rsc.Close(); err != nil || sessionClosed {
+		t.Fatalf("the session should have been kept. err: %v", err)
+	}
+
+	_, err = SnowflakeDriver{}.ResumeSession(context.Background(), Config{Account: "a"}, ExportedSession{})
+	if driverErr, ok := err.(*SnowflakeError); !ok || driverErr.Number != ErrCodeInvalidExportedSession {
+		t.Fatalf("should have failed with ErrCodeInvalidExportedSession. err: %v", err)
+	}
+}
+
+func TestResetSessionRenewsExpiringTokens(t *testing.T) {
+	renewed := 0
+	sr := &snowflakeRestful{
+		FuncRenewSession: func(_ context.Context, sr *snowflakeRestful, _ time.Duration) error {
+			renewed++
+			sr.setTokenExpiry(3600, 14400)
+			return nil
+		},
+	}
+	sc := &snowflakeConn{cfg: &Config{Params: map[string]*string{}}, rest: sr}
+
+	// unknown expiration, e.g., of a resumed session
+	if err := sc.ResetSession(context.Background()); err != nil || renewed != 0 || !sc.IsValid() {
+		t.Fatalf("nothing should be done. renewed: %v, err: %v", renewed, err)
+	}
+	sr.setTokenExpiry(30, 14400)
+	if err := sc.ResetSession(context.Background()); err != nil || renewed != 1 {
+		t.Fatalf("the expiring token should be renewed. renewed: %v, err: %v", renewed, err)
+	}
+	if tokenExpiry, _ := sr.getTokenExpiry(); time.Until(tokenExpiry) < 59*time.Minute {
+		t.Fatalf("the expiration should be updated. got: %v", tokenExpiry)
+	}
+	if err := sc.ResetSession(context.Background()); err != nil || renewed != 1 {
+		t.Fatalf("the valid token should not be renewed. renewed: %v, err: %v", renewed, err)
+	}
+
+	sr.FuncRenewSession = renewSessionTestError
+	sr.setTokenExpiry(30, 14400)
+	if err := sc.ResetSession(context.Background()); err != driver.ErrBadConn {
+		t.Fatalf("the connection should be discarded if it fails to renew. err: %v", err)
+	}
+}
+
+func TestResetSessionExpiredSession(t *testing.T) {
+	sc := &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{
+			FuncRenewSession: func(context.Context, *snowflakeRestful, time.Duration) error {
+				return errors.New("the expired session must not be renewed")
+			},
+		},
+	}
+	sc.rest.masterTokenExpiry = time.Now().Add(-time.Second)
+	if sc.IsValid() {
+		t.Fatal("the expired session should not be valid")
+	}
+	if err := sc.ResetSession(context.Background()); err != driver.ErrBadConn {
+		t.Fatalf("the expired session should be discarded. err: %v", err)
+	}
+	sc.cleanup()
+	if sc.IsValid() {
+		t.Fatal("the closed connection should not be valid")
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"strconv"
+	"strings"
+)
+
+// SessionParams is the set of the commonly used session parameters. The zero value of a field leaves the
+// parameter to the account or user default. Parameters not listed here can be set by Config.Params, whose
+// keys are case-insensitive. A parameter set in SessionParams takes precedence over the same one in Params.
+type SessionParams struct {
+	ClientSessionKeepAlive    ConfigBool // CLIENT_SESSION_KEEP_ALIVE: heartbeat the session so that it doesn't expire
+	Autocommit                ConfigBool // AUTOCOMMIT
+	Timezone                  string     // TIMEZONE, e.g., America/Los_Angeles
+	QueryTag                  string     // QUERY_TAG
+	StatementTimeoutInSeconds int        // STATEMENT_TIMEOUT_IN_SECONDS
+	LockTimeout               int        // LOCK_TIMEOUT in seconds
+	ClientResultChunkSize     int        // CLIENT_RESULT_CHUNK_SIZE in MB
+	QueryResultFormat         string     // GO_QUERY_RESULT_FORMAT: json or arrow
+	// CLIENT_SESSION_KEEP_ALIVE_HEARTBEAT_FREQUENCY: seconds between the heartbeats, from 900 to 3600
+	ClientSessionKeepAliveHeartbeatFrequency int
+}
+
+// session parameter value types
+const (
+	sessionParamBool = iota
+	sessionParamInt
+	sessionParamString
+)
+
+// knownSessionParams are the value types of the session parameters validated by the driver, by the
+// lower case name.
+var knownSessionParams = map[string]int{
+	sessionClientSessionKeepAlive:                   sessionParamBool,
+	sessionClientSessionKeepAliveHeartbeatFrequency: sessionParamInt,
+	sessionClientTelemetryEnabled:                   sessionParamBool,
+	"autocommit":                                    sessionParamBool,
+	"timezone":                                      sessionParamString,
+	"query_tag":                                     sessionParamString,
+	"statement_timeout_in_seconds":                  sessionParamInt,
+	"lock_timeout":                                  sessionParamInt,
+	"client_result_chunk_size":                      sessionParamInt,
+	"go_query_result_format":                        sessionParamString,
+	queryContextCacheSizeParam:                      sessionParamInt,
+}
+
+// values returns the parameters set in sp by the lower case name.
+func (sp *SessionParams) values() map[string]string {
+	values := make(map[string]string)
+	setBool := func(name string, v ConfigBool) {
+		switch v {
+		case ConfigBoolTrue:
+			values[name] = "true"
+		case ConfigBoolFalse:
+			values[name] = "false"
+		}
+	}
+	setInt := func(name string, v int) {
+		if v != 0 {
+			values[name] = strconv.Itoa(v)
+		}
+	}
+	setString := func(name string, v string) {
+		if v != "" {
+			values[name] = v
+		}
+	}
+	setBool(sessionClientSessionKeepAlive, sp.ClientSessionKeepAlive)
+	setBool("autocommit", sp.Autocommit)
+	setString("timezone", sp.Timezone)
+	setString("query_tag", sp.QueryTag)
+	setInt("statement_timeout_in_seconds", sp.StatementTimeoutInSeconds)
+	setInt("lock_timeout", sp.LockTimeout)
+	setInt("client_result_chunk_size", sp.ClientResultChunkSize)
+	setString("go_query_result_format", sp.QueryResultFormat)
+	setInt(sessionClientSessionKeepAliveHeartbeatFrequency, sp.ClientSessionKeepAliveHeartbeatFrequency)
+	return values
+}
+
+// normalizeSessionParams returns the session parameters of the config by the lower case name, with
+// SessionParams merged into Params.
+func normalizeSessionParams(cfg *Config) map[string]*string {
+	params := make(map[string]*string, len(cfg.Params))
+	for k, v := range cfg.Params {
+		params[strings.ToLower(k)] = v
+	}
+	for k, v := range cfg.SessionParams.values() {
+		v := v
+		params[k] = &v
+	}
+	if _, ok := params[sessionClientTelemetryEnabled]; cfg.DisableTelemetry && !ok {
+		disabled := "false"
+		params[sessionClientTelemetryEnabled] = &disabled
+	}
+	return params
+}
+
+// validateSessionParams checks the values of the known session parameters.
+func validateSessionParams(cfg *Config) error {
+	for k, v := range normalizeSessionParams(cfg) {
+		typ, ok := knownSessionParams[k]
+		if !ok || v == nil {
+			continue
+		}
+		var err error
+		switch typ {
+		case sessionParamBool:
+			_, err = strconv.ParseBool(*v)
+		case sessionParamInt:
+			_, err = strconv.Atoi(*v)
+		}
+		invalid := err != nil
+		if format := strings.ToLower(*v); k == "go_query_result_format" && format != jsonFormat && format != arrowFormat {
+			invalid = true
+		}
+		if invalid {
+			return &SnowflakeError{
+				Number:      ErrCodeInvalidSessionParameter,
+				Message:     errMsgInvalidSessionParameter,
+				MessageArgs: []interface{}{strings.ToUpper(k), *v},
+			}
+		}
+	}
+	return nil
+}
+
+// SessionParameterChange is a change of a session parameter observed in a response from Snowflake, e.g., after
+// ALTER SESSION SET TIMEZONE or at login.
+type SessionParameterChange struct {
+	SessionID int
+	Name      string // upper case name of the parameter
+	OldValue  string
+	NewValue  string
+	// Added is true if the connection had no value of the parameter, e.g., at login.
+	Added bool
+}
+
+// SessionParameterListener is called for every change of a session parameter of a connection. It is called
+// synchronously while the response is processed and may be called concurrently for different connections,
+// so it should return quickly.
+type SessionParameterListener func(SessionParameterChange)
@@ -0,0 +1,128 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"testing"
+)
+
+func TestNormalizeSessionParams(t *testing.T) {
+	keepAlive := "false"
+	tag := "etl"
+	cfg := &Config{
+		Params: map[string]*string{
+			"CLIENT_SESSION_KEEP_ALIVE": &keepAlive,
+			"Query_Tag":                 &tag,
+		},
+		SessionParams: SessionParams{
+			ClientSessionKeepAlive: ConfigBoolTrue,
+			Timezone:               "UTC",
+		},
+	}
+	params := normalizeSessionParams(cfg)
+	if v := params[sessionClientSessionKeepAlive]; v == nil || *v != "true" {
+		t.Fatalf("SessionParams should take precedence. got: %v", v)
+	}
+	if v := params["query_tag"]; v == nil || *v != "etl" {
+		t.Fatalf("the key of Params should be normalized. got: %v", v)
+	}
+	if v := params["timezone"]; v == nil || *v != "UTC" {
+		t.Fatalf("unexpected timezone: %v", v)
+	}
+	if len(params) != 3 {
+		t.Fatalf("unexpected params: %v", params)
+	}
+}
+
+func TestClientSessionKeepAliveUpperCase(t *testing.T) {
+	cfg, err := ParseDSN("u:p@a.snowflakecomputing.com:443?CLIENT_SESSION_KEEP_ALIVE=true")
+	if err != nil {
+		t.Fatalf("failed to parse the DSN. err: %v", err)
+	}
+	sc, err := newSnowflakeConn(*cfg, false, nil)
+	if err != nil {
+		t.Fatalf("failed to create a connection. err: %v", err)
+	}
+	if !sc.isClientSessionKeepAliveEnabled() {
+		t.Fatal("the session keep alive should be enabled")
+	}
+}
+
+func TestValidateSessionParams(t *testing.T) {
+	for _, dsn := range []string{
+		"u:p@a.snowflakecomputing.com:443?client_session_keep_alive=yes",
+		"u:p@a.snowflakecomputing.com:443?STATEMENT_TIMEOUT_IN_SECONDS=1m",
+		"u:p@a.snowflakecomputing.com:443?go_query_result_format=parquet",
+	} {
+		_, err := ParseDSN(dsn)
+		if driverErr, ok := err.(*SnowflakeError); !ok || driverErr.Number != ErrCodeInvalidSessionParameter {
+			t.Errorf("should have failed to parse %v. err: %v", dsn, err)
+		}
+	}
+	if _, err := ParseDSN("u:p@a.snowflakecomputing.com:443?GO_QUERY_RESULT_FORMAT=JSON&unknown_param=x"); err != nil {
+		t.Fatalf("failed to parse the DSN. err: %v", err)
+	}
+}
+
+func TestDSNWithSessionParams(t *testing.T) {
+	keepAlive := "false"
+	cfg := &Config{
+		Account:  "a",
+		User:     "u",
+		Password: "p",
+		Params:   map[string]*string{"CLIENT_SESSION_KEEP_ALIVE": &keepAlive},
+		SessionParams: SessionParams{
+			ClientSessionKeepAlive:    ConfigBoolTrue,
+			StatementTimeoutInSeconds: 60,
+		},
+	}
+	dsn, err := DSN(cfg)
+	if err != nil {
+		t.Fatalf("failed to build the DSN. err: %v", err)
+	}
+	parsed, err := ParseDSN(dsn)
+	if err != nil {
+		t.Fatalf("failed to parse the DSN. err: %v", err)
+	}
+	params := normalizeSessionParams(parsed)
+	if v := params[sessionClientSessionKeepAlive]; v == nil || *v != "true" {
+		t.Fatalf("unexpected keep alive: %v, DSN: %v", v, dsn)
+	}
+	if v := params["statement_timeout_in_seconds"]; v == nil || *v != "60" {
+		t.Fatalf("unexpected statement timeout: %v, DSN: %v", v, dsn)
+	}
+}
+
+func TestSessionParameterListener(t *testing.T) {
+	var changes []SessionParameterChange
+	tz := "UTC"
+	sc := &snowflakeConn{
+		cfg: &Config{
+			Params: map[string]*string{"timezone": &tz},
+			SessionParameterListener: func(change SessionParameterChange) {
+				changes = append(changes, change)
+			},
+		},
+		rest: &snowflakeRestful{SessionID: 123},
+	}
+	sc.populateSessionParameters([]nameValueParameter{
+		{Name: "TIMEZONE", Value: "UTC"},
+		{Name: "SERVICE_NAME", Value: "sv1"},
+	})
+	sc.populateSessionParameters([]nameValueParameter{
+		{Name: "TIMEZONE", Value: "America/Los_Angeles"},
+		{Name: "SERVICE_NAME", Value: "sv1"},
+	})
+	expected := []SessionParameterChange{
+		{SessionID: 123, Name: "SERVICE_NAME", NewValue: "sv1", Added: true},
+		{SessionID: 123, Name: "TIMEZONE", OldValue: "UTC", NewValue: "America/Los_Angeles"},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("unexpected changes: %+v", changes)
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Errorf("unexpected change. expected: %+v, got: %+v", expected[i], changes[i])
+		}
+	}
+}
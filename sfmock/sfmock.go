@@ -0,0 +1,414 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+// Package sfmock is an in-process fake of the Snowflake REST API for unit testing applications that use
+// the gosnowflake driver without a Snowflake account.
+//
+// A Server accepts the logins of the driver and answers the statements with the responses of the
+// expectations whose patterns match the SQL text:
+//
+//	srv := sfmock.NewServer()
+//	defer srv.Close()
+//	srv.Expect(`^SELECT name FROM users`).Return(sfmock.Rows(
+//		[]sfmock.Column{{Name: "NAME", Type: "text"}},
+//		[]interface{}{"alice"},
+//		[]interface{}{"bob"}))
+//	srv.Expect(`^INSERT INTO users`).Return(sfmock.Inserted(1))
+//
+//	db := sql.OpenDB(gosnowflake.NewConnector(gosnowflake.SnowflakeDriver{}, srv.Config()))
+//
+// The statements that match no expectation fail with the error number UnexpectedStatement.
+package sfmock
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	sf "github.com/snowflakedb/gosnowflake"
+)
+
+// UnexpectedStatement is the error number of the statements that match no expectation.
+const UnexpectedStatement = 999999
+
+// statement type IDs of the DML statements
+const (
+	statementTypeInsert = 0x3100
+	statementTypeUpdate = 0x3200
+	statementTypeDelete = 0x3300
+)
+
+// Column is a column of a result.
+type Column struct {
+	Name      string
+	Type      string // Snowflake data type in lower case, e.g., fixed, real, text, boolean, date, timestamp_ntz
+	Precision int64
+	Scale     int64
+	Nullable  bool
+}
+
+// Response is a canned response to a statement.
+type Response struct {
+	columns         []Column
+	rows            [][]*string
+	statementTypeID int64
+	errNumber       int
+	sqlState        string
+	message         string
+	err             error
+}
+
+// Rows returns a response with a result of the columns and the rows. The values are nil for NULL, strings,
+// numbers, bools, time.Time and []byte, and are sent the way Snowflake sends the values of the column types.
+func Rows(columns []Column, rows ...[]interface{}) *Response {
+	resp := &Response{columns: columns}
+	for _, row := range rows {
+		if len(row) != len(columns) {
+			resp.err = fmt.Errorf("sfmock: %v values for %v columns", len(row), len(columns))
+			return resp
+		}
+		values := make([]*string, len(row))
+		for i, v := range row {
+			values[i] = wireValue(columns[i], v)
+		}
+		resp.rows = append(resp.rows, values)
+	}
+	return resp
+}
+
+// Inserted returns the response to an INSERT statement.
+func Inserted(n int64) *Response {
+	return dmlResponse(statementTypeInsert, "number of rows inserted", n)
+}
+
+// Updated returns the response to an UPDATE statement.
+func Updated(n int64) *Response {
+	return dmlResponse(statementTypeUpdate, "number of rows updated", n)
+}
+
+// Deleted returns the response to a DELETE statement.
+func Deleted(n int64) *Response {
+	return dmlResponse(statementTypeDelete, "number of rows deleted", n)
+}
+
+// Succeeded returns the response to a statement that returns a status, e.g., CREATE TABLE.
+func Succeeded(status string) *Response {
+	return Rows([]Column{{Name: "status", Type: "text"}}, []interface{}{status})
+}
+
+// Error returns the response to a statement that fails with the error.
+func Error(number int, sqlState string, message string) *Response {
+	return &Response{errNumber: number, sqlState: sqlState, message: message}
+}
+
+func dmlResponse(statementTypeID int64, column string, n int64) *Response {
+	resp := Rows([]Column{{Name: column, Type: "fixed"}}, []interface{}{n})
+	resp.statementTypeID = statementTypeID
+	return resp
+}
+
+// wireValue returns the string of the value in the JSON result format.
+func wireValue(col Column, v interface{}) *string {
+	if v == nil {
+		return nil
+	}
+	var s string
+	switch x := v.(type) {
+	case string:
+		s = x
+	case []byte:
+		s = hex.EncodeToString(x)
+	case bool:
+		s = strconv.FormatBool(x)
+	case time.Time:
+		switch strings.ToLower(col.Type) {
+		case "date":
+			s = strconv.FormatInt(x.Unix()/86400, 10)
+		case "time":
+			midnight := time.Date(x.Year(), x.Month(), x.Day(), 0, 0, 0, 0, x.Location())
+			d := x.Sub(midnight)
+			s = fmt.Sprintf("%d.%09d", int64(d/time.Second), int64(d%time.Second))
+		case "timestamp_tz":
+			_, offset := x.Zone()
+			s = fmt.Sprintf("%d.%09d %d", x.Unix(), x.Nanosecond(), offset/60+1440)
+		default:
+			s = fmt.Sprintf("%d.%09d", x.Unix(), x.Nanosecond())
+		}
+	case fmt.Stringer:
+		s = x.String()
+	default:
+		s = fmt.Sprint(x)
+	}
+	return &s
+}
+
+// Expectation is a pattern of the statements and the response to them.
+type Expectation struct {
+	re    *regexp.Regexp
+	resp  *Response
+	times int
+	calls int
+}
+
+// Return sets the response to the statements. The default response is Succeeded("Statement executed successfully.").
+func (e *Expectation) Return(resp *Response) *Expectation {
+	e.resp = resp
+	return e
+}
+
+// Times limits the number of statements the expectation answers. The next expectations that match answer
+// the rest. By default, the expectation answers any number of statements.
+func (e *Expectation) Times(n int) *Expectation {
+	e.times = n
+	return e
+}
+
+// Statement is a statement received by the Server.
+type Statement struct {
+	QueryID  string
+	SQL      string
+	Bindings map[string]Binding
+	Async    bool
+}
+
+// Binding is a value bound to a statement.
+type Binding struct {
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// Server is a fake Snowflake listening on a local HTTP port.
+type Server struct {
+	srv *httptest.Server
+
+	// SessionParameters are returned by the logins.
+	SessionParameters map[string]string
+
+	mu           sync.Mutex
+	expectations []*Expectation
+	statements   []Statement
+	results      map[string][]byte
+}
+
+// NewServer starts a Server.
+func NewServer() *Server {
+	s := &Server{results: make(map[string][]byte)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/session/v1/login-request", s.handleLogin)
+	mux.HandleFunc("/session/token-request", s.handleRenew)
+	mux.HandleFunc("/session/heartbeat", s.handleSuccess)
+	mux.HandleFunc("/session", s.handleSuccess)
+	mux.HandleFunc("/queries/v1/query-request", s.handleQuery)
+	mux.HandleFunc("/queries/v1/abort-request", s.handleSuccess)
+	mux.HandleFunc("/queries/", s.handleResult)
+	mux.HandleFunc("/monitoring/queries/", s.handleMonitoring)
+	s.srv = httptest.NewServer(mux)
+	return s
+}
+
+// Close shuts down the Server.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// Config returns a Config that connects to the Server.
+func (s *Server) Config() sf.Config {
+	u, _ := url.Parse(s.srv.URL)
+	port, _ := strconv.Atoi(u.Port())
+	return sf.Config{
+		Account:  "sfmock",
+		User:     "sfmock",
+		Password: "sfmock",
+		Protocol: "http",
+		Host:     u.Hostname(),
+		Port:     port,
+	}
+}
+
+// Expect adds an expectation of the statements that match the regular expression.
+func (s *Server) Expect(pattern string) *Expectation {
+	e := &Expectation{
+		re:   regexp.MustCompile(pattern),
+		resp: Succeeded("Statement executed successfully."),
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expectations = append(s.expectations, e)
+	return e
+}
+
+// Statements returns the statements received so far.
+func (s *Server) Statements() []Statement {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Statement(nil), s.statements...)
+}
+
+// ExpectationsWereMet returns an error if an expectation limited by Times answered fewer statements, or
+// an expectation answered no statement.
+func (s *Server) ExpectationsWereMet() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range s.expectations {
+		if e.calls == 0 || (e.times > 0 && e.calls < e.times) {
+			return fmt.Errorf("sfmock: expectation %q answered %v statements", e.re, e.calls)
+		}
+	}
+	return nil
+}
+
+func (s *Server) respond(w http.ResponseWriter, data interface{}, code string, message string, success bool) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data":    data,
+		"code":    code,
+		"message": message,
+		"success": success,
+	})
+}
+
+func (s *Server) handleSuccess(w http.ResponseWriter, _ *http.Request) {
+	s.respond(w, nil, "", "", true)
+}
+
+func (s *Server) handleLogin(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	params := make([]map[string]interface{}, 0, len(s.SessionParameters))
+	for k, v := range s.SessionParameters {
+		params = append(params, map[string]interface{}{"name": k, "value": v})
+	}
+	s.mu.Unlock()
+	s.respond(w, map[string]interface{}{
+		"token":                   "sfmock-token",
+		"masterToken":             "sfmock-master-token",
+		"validityInSeconds":       3600,
+		"masterValidityInSeconds": 14400,
+		"sessionId":               1,
+		"serverVersion":           "sfmock",
+		"parameters":              params,
+	}, "", "", true)
+}
+
+func (s *Server) handleRenew(w http.ResponseWriter, _ *http.Request) {
+	s.respond(w, map[string]interface{}{
+		"sessionToken": "sfmock-token",
+		"masterToken":  "sfmock-master-token",
+		"sessionId":    1,
+	}, "", "", true)
+}
+
+func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		SQLText   string             `json:"sqlText"`
+		AsyncExec bool               `json:"asyncExec"`
+		Bindings  map[string]Binding `json:"bindings"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	qid := uuid.New().String()
+	s.mu.Lock()
+	s.statements = append(s.statements, Statement{QueryID: qid, SQL: req.SQLText, Bindings: req.Bindings, Async: req.AsyncExec})
+	resp := s.match(req.SQLText)
+	s.mu.Unlock()
+
+	data, code, message, success := s.result(qid, resp)
+	if success {
+		// the result can be fetched by the query ID afterwards
+		b, err := json.Marshal(map[string]interface{}{"data": data, "code": code, "message": message, "success": success})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.mu.Lock()
+		s.results[qid] = b
+		s.mu.Unlock()
+	}
+	if req.AsyncExec && success {
+		s.respond(w, map[string]interface{}{"queryId": qid, "getResultUrl": "/queries/" + qid + "/result"}, "333334", "", true)
+		return
+	}
+	s.respond(w, data, code, message, success)
+}
+
+// match returns the response of the first expectation that matches the statement. The caller must hold mu.
+func (s *Server) match(sql string) *Response {
+	for _, e := range s.expectations {
+		if e.times > 0 && e.calls >= e.times {
+			continue
+		}
+		if e.re.MatchString(sql) {
+			e.calls++
+			return e.resp
+		}
+	}
+	return Error(UnexpectedStatement, "42000", "sfmock: unexpected statement: "+sql)
+}
+
+func (s *Server) result(qid string, resp *Response) (data map[string]interface{}, code string, message string, success bool) {
+	if resp.err != nil {
+		return map[string]interface{}{"queryId": qid}, strconv.Itoa(UnexpectedStatement), resp.err.Error(), false
+	}
+	if resp.errNumber != 0 {
+		return map[string]interface{}{"queryId": qid, "sqlState": resp.sqlState}, strconv.Itoa(resp.errNumber), resp.message, false
+	}
+	rowType := make([]map[string]interface{}, len(resp.columns))
+	for i, c := range resp.columns {
+		rowType[i] = map[string]interface{}{
+			"name":      c.Name,
+			"type":      c.Type,
+			"precision": c.Precision,
+			"scale":     c.Scale,
+			"nullable":  c.Nullable,
+		}
+	}
+	rows := resp.rows
+	if rows == nil {
+		rows = [][]*string{}
+	}
+	return map[string]interface{}{
+		"queryId":           qid,
+		"rowtype":           rowType,
+		"rowset":            rows,
+		"total":             len(rows),
+		"returned":          len(rows),
+		"statementTypeId":   resp.statementTypeID,
+		"queryResultFormat": "json",
+	}, "", "", true
+}
+
+func (s *Server) handleResult(w http.ResponseWriter, r *http.Request) {
+	qid := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/queries/"), "/result")
+	s.mu.Lock()
+	b, ok := s.results[qid]
+	s.mu.Unlock()
+	if !ok {
+		s.respond(w, map[string]interface{}{"queryId": qid, "sqlState": "02000"}, "612", "sfmock: no result of query "+qid, false)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
+
+func (s *Server) handleMonitoring(w http.ResponseWriter, r *http.Request) {
+	qid := strings.TrimPrefix(r.URL.Path, "/monitoring/queries/")
+	s.mu.Lock()
+	_, ok := s.results[qid]
+	s.mu.Unlock()
+	status := "SUCCESS"
+	if !ok {
+		status = "FAILED_WITH_ERROR"
+	}
+	s.respond(w, map[string]interface{}{
+		"queries": []map[string]interface{}{{"id": qid, "status": status}},
+	}, "", "", true)
+}
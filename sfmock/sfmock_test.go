@@ -0,0 +1,106 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package sfmock
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	sf "github.com/snowflakedb/gosnowflake"
+)
+
+func TestServer(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	created := time.Date(2020, 5, 1, 12, 30, 0, 0, time.UTC)
+	srv.Expect(`^SELECT id, name, created FROM users`).Return(Rows(
+		[]Column{{Name: "ID", Type: "fixed"}, {Name: "NAME", Type: "text", Nullable: true}, {Name: "CREATED", Type: "timestamp_ntz", Scale: 9}},
+		[]interface{}{1, "alice", created},
+		[]interface{}{2, nil, created}))
+	srv.Expect(`^INSERT INTO users`).Return(Inserted(1)).Times(1)
+
+	db := sql.OpenDB(sf.NewConnector(sf.SnowflakeDriver{}, srv.Config()))
+	defer db.Close()
+
+	rows, err := db.Query("SELECT id, name, created FROM users")
+	if err != nil {
+		t.Fatalf("failed to query. err: %v", err)
+	}
+	var names []sql.NullString
+	for rows.Next() {
+		var id int64
+		var name sql.NullString
+		var ts time.Time
+		if err = rows.Scan(&id, &name, &ts); err != nil {
+			t.Fatalf("failed to scan. err: %v", err)
+		}
+		if !ts.Equal(created) {
+			t.Fatalf("unexpected timestamp: %v", ts)
+		}
+		names = append(names, name)
+	}
+	if err = rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names[0].String != "alice" || names[1].Valid {
+		t.Fatalf("unexpected names: %v", names)
+	}
+
+	res, err := db.Exec("INSERT INTO users VALUES(?, ?)", 3, "carol")
+	if err != nil {
+		t.Fatalf("failed to insert. err: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n != 1 {
+		t.Fatalf("unexpected number of rows: %v", n)
+	}
+	statements := srv.Statements()
+	if last := statements[len(statements)-1]; last.Bindings["2"].Value != "carol" {
+		t.Fatalf("unexpected bindings: %+v", last.Bindings)
+	}
+
+	// the expectation answers only one statement
+	_, err = db.Exec("INSERT INTO users VALUES(4, 'dave')")
+	if e, ok := err.(*sf.SnowflakeError); !ok || e.Number != UnexpectedStatement {
+		t.Fatalf("should have failed with an unexpected statement. err: %v", err)
+	}
+	if err = srv.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServerAsync(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.Expect(`^CALL`).Return(Rows([]Column{{Name: "RESULT", Type: "text"}}, []interface{}{"done"}))
+	srv.Expect(`^DROP`).Return(Error(2003, "02000", "Table 'T' does not exist"))
+
+	ctx := context.Background()
+	client, err := sf.NewClient(ctx, srv.Config())
+	if err != nil {
+		t.Fatalf("failed to log in. err: %v", err)
+	}
+	defer client.Close()
+	qid, err := client.SubmitAsync(ctx, "CALL proc()")
+	if err != nil {
+		t.Fatalf("failed to submit. err: %v", err)
+	}
+	rs, err := client.FetchResult(ctx, qid)
+	if err != nil {
+		t.Fatalf("failed to fetch the result. err: %v", err)
+	}
+	defer rs.Close()
+	var result string
+	if !rs.Next() {
+		t.Fatalf("no row. err: %v", rs.Err())
+	}
+	if err = rs.Scan(&result); err != nil || result != "done" {
+		t.Fatalf("unexpected result: %v, err: %v", result, err)
+	}
+
+	_, err = client.Exec(ctx, "DROP TABLE t")
+	if e, ok := err.(*sf.SnowflakeError); !ok || e.Number != 2003 || e.SQLState != "02000" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,384 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+// Package sfshow parses the results of the SHOW commands of Snowflake into typed Go structs for admin
+// automation tools:
+//
+//	whs, err := sfshow.Warehouses(ctx, db, &sfshow.Options{Like: "ETL%"})
+//	for _, wh := range whs {
+//		fmt.Println(wh.Name, wh.State, wh.Size, wh.AutoSuspend)
+//	}
+//
+// The columns of the SHOW commands are added and renamed across Snowflake versions. The columns are matched
+// to the fields by name case-insensitively, including the former names of the renamed columns. A field whose
+// column is missing is left zero, and the columns that match no field are kept as strings in the Other map,
+// so that a new Snowflake version doesn't break the parsing.
+//
+// Scan parses the result of any other SHOW command into a slice of a struct with show tags.
+package sfshow
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Queryer runs a query. *sql.DB, *sql.Conn and *sql.Tx are Queryers.
+type Queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// Options narrows the objects of a SHOW command.
+type Options struct {
+	// Like is the case-insensitive pattern of the object names with the SQL wildcards % and _ (optional)
+	Like string
+	// In is the scope of the objects in SQL, e.g., ACCOUNT, DATABASE mydb or SCHEMA mydb.public (optional).
+	// It is not escaped.
+	In string
+}
+
+// Warehouse is a row of SHOW WAREHOUSES.
+type Warehouse struct {
+	Name            string    `show:"name"`
+	State           string    `show:"state"`
+	Type            string    `show:"type"`
+	Size            string    `show:"size,warehouse_size"`
+	MinClusterCount int64     `show:"min_cluster_count"`
+	MaxClusterCount int64     `show:"max_cluster_count"`
+	StartedClusters int64     `show:"started_clusters"`
+	Running         int64     `show:"running"`
+	Queued          int64     `show:"queued"`
+	IsDefault       bool      `show:"is_default,default"`
+	IsCurrent       bool      `show:"is_current,current"`
+	AutoSuspend     int64     `show:"auto_suspend"` // seconds. zero if the warehouse never suspends automatically
+	AutoResume      bool      `show:"auto_resume"`
+	ScalingPolicy   string    `show:"scaling_policy"`
+	ResourceMonitor string    `show:"resource_monitor"`
+	CreatedOn       time.Time `show:"created_on"`
+	ResumedOn       time.Time `show:"resumed_on"`
+	UpdatedOn       time.Time `show:"updated_on"`
+	Owner           string    `show:"owner"`
+	Comment         string    `show:"comment"`
+
+	Other map[string]string `show:"*"`
+}
+
+// Database is a row of SHOW DATABASES.
+type Database struct {
+	Name          string    `show:"name"`
+	CreatedOn     time.Time `show:"created_on"`
+	IsDefault     bool      `show:"is_default,default"`
+	IsCurrent     bool      `show:"is_current,current"`
+	Origin        string    `show:"origin"`
+	Owner         string    `show:"owner"`
+	Comment       string    `show:"comment"`
+	Options       string    `show:"options"`
+	RetentionTime int64     `show:"retention_time"` // days of Time Travel
+	Kind          string    `show:"kind"`
+
+	Other map[string]string `show:"*"`
+}
+
+// Schema is a row of SHOW SCHEMAS.
+type Schema struct {
+	Name          string    `show:"name"`
+	DatabaseName  string    `show:"database_name"`
+	CreatedOn     time.Time `show:"created_on"`
+	IsDefault     bool      `show:"is_default,default"`
+	IsCurrent     bool      `show:"is_current,current"`
+	Owner         string    `show:"owner"`
+	Comment       string    `show:"comment"`
+	Options       string    `show:"options"`
+	RetentionTime int64     `show:"retention_time"`
+
+	Other map[string]string `show:"*"`
+}
+
+// Table is a row of SHOW TABLES.
+type Table struct {
+	Name                string    `show:"name"`
+	DatabaseName        string    `show:"database_name"`
+	SchemaName          string    `show:"schema_name"`
+	Kind                string    `show:"kind"`
+	CreatedOn           time.Time `show:"created_on"`
+	Comment             string    `show:"comment"`
+	ClusterBy           string    `show:"cluster_by"`
+	Rows                int64     `show:"rows"`
+	Bytes               int64     `show:"bytes"`
+	Owner               string    `show:"owner"`
+	RetentionTime       int64     `show:"retention_time"`
+	AutomaticClustering bool      `show:"automatic_clustering,auto_clustering_on"`
+	ChangeTracking      bool      `show:"change_tracking"`
+	IsExternal          bool      `show:"is_external"`
+
+	Other map[string]string `show:"*"`
+}
+
+// User is a row of SHOW USERS.
+type User struct {
+	Name               string    `show:"name"`
+	LoginName          string    `show:"login_name"`
+	DisplayName        string    `show:"display_name"`
+	FirstName          string    `show:"first_name"`
+	LastName           string    `show:"last_name"`
+	Email              string    `show:"email"`
+	CreatedOn          time.Time `show:"created_on"`
+	Disabled           bool      `show:"disabled"`
+	MustChangePassword bool      `show:"must_change_password"`
+	SnowflakeLock      bool      `show:"snowflake_lock"`
+	DefaultWarehouse   string    `show:"default_warehouse"`
+	DefaultNamespace   string    `show:"default_namespace"`
+	DefaultRole        string    `show:"default_role"`
+	LastSuccessLogin   time.Time `show:"last_success_login"`
+	ExpiresAtTime      time.Time `show:"expires_at_time"`
+	LockedUntilTime    time.Time `show:"locked_until_time"`
+	HasPassword        bool      `show:"has_password"`
+	HasRSAPublicKey    bool      `show:"has_rsa_public_key"`
+	Owner              string    `show:"owner"`
+	Comment            string    `show:"comment"`
+
+	Other map[string]string `show:"*"`
+}
+
+// Role is a row of SHOW ROLES.
+type Role struct {
+	Name            string    `show:"name"`
+	CreatedOn       time.Time `show:"created_on"`
+	IsDefault       bool      `show:"is_default,default"`
+	IsCurrent       bool      `show:"is_current,current"`
+	IsInherited     bool      `show:"is_inherited"`
+	AssignedToUsers int64     `show:"assigned_to_users"`
+	GrantedToRoles  int64     `show:"granted_to_roles"`
+	GrantedRoles    int64     `show:"granted_roles"`
+	Owner           string    `show:"owner"`
+	Comment         string    `show:"comment"`
+
+	Other map[string]string `show:"*"`
+}
+
+// Warehouses runs SHOW WAREHOUSES.
+func Warehouses(ctx context.Context, q Queryer, opts *Options) ([]Warehouse, error) {
+	var ret []Warehouse
+	return ret, show(ctx, q, "WAREHOUSES", opts, &ret)
+}
+
+// Databases runs SHOW DATABASES.
+func Databases(ctx context.Context, q Queryer, opts *Options) ([]Database, error) {
+	var ret []Database
+	return ret, show(ctx, q, "DATABASES", opts, &ret)
+}
+
+// Schemas runs SHOW SCHEMAS.
+func Schemas(ctx context.Context, q Queryer, opts *Options) ([]Schema, error) {
+	var ret []Schema
+	return ret, show(ctx, q, "SCHEMAS", opts, &ret)
+}
+
+// Tables runs SHOW TABLES.
+func Tables(ctx context.Context, q Queryer, opts *Options) ([]Table, error) {
+	var ret []Table
+	return ret, show(ctx, q, "TABLES", opts, &ret)
+}
+
+// Users runs SHOW USERS.
+func Users(ctx context.Context, q Queryer, opts *Options) ([]User, error) {
+	var ret []User
+	return ret, show(ctx, q, "USERS", opts, &ret)
+}
+
+// Roles runs SHOW ROLES.
+func Roles(ctx context.Context, q Queryer, opts *Options) ([]Role, error) {
+	var ret []Role
+	return ret, show(ctx, q, "ROLES", opts, &ret)
+}
+
+func show(ctx context.Context, q Queryer, objects string, opts *Options, dest interface{}) error {
+	rows, err := q.QueryContext(ctx, showCommand(objects, opts))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	return Scan(rows, dest)
+}
+
+// showCommand returns the SHOW command of the objects.
+func showCommand(objects string, opts *Options) string {
+	cmd := "SHOW " + objects
+	if opts == nil {
+		return cmd
+	}
+	if opts.Like != "" {
+		cmd += " LIKE '" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(opts.Like) + "'"
+	}
+	if opts.In != "" {
+		cmd += " IN " + opts.In
+	}
+	return cmd
+}
+
+// Scan appends the rest of the rows to the slice of structs that dest points to. The columns are matched by
+// name case-insensitively with the show tags of the fields, which list the names of the column separated by
+// commas, e.g., `show:"is_default,default"`. A field of map[string]string tagged `show:"*"` gets the columns
+// that match no field. The values are converted to the string, bool, integer, float and time.Time fields.
+// The NULL values leave the fields zero.
+func Scan(rows *sql.Rows, dest interface{}) error {
+	slice := reflect.ValueOf(dest)
+	if slice.Kind() != reflect.Ptr || slice.Elem().Kind() != reflect.Slice ||
+		slice.Elem().Type().Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("sfshow: dest must be a pointer to a slice of structs. got: %T", dest)
+	}
+	slice = slice.Elem()
+	elemType := slice.Type().Elem()
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	fields, other := fieldsOf(elemType)
+	index := make([]int, len(columns)) // field index of the column, or -1
+	for i, column := range columns {
+		idx, ok := fields[strings.ToLower(column)]
+		if !ok {
+			idx = -1
+		}
+		index[i] = idx
+	}
+	values := make([]interface{}, len(columns))
+	ptrs := make([]interface{}, len(columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	for rows.Next() {
+		if err = rows.Scan(ptrs...); err != nil {
+			return err
+		}
+		elem := reflect.New(elemType).Elem()
+		for i, v := range values {
+			if index[i] < 0 {
+				if other >= 0 && v != nil {
+					m := elem.Field(other)
+					if m.IsNil() {
+						m.Set(reflect.MakeMap(m.Type()))
+					}
+					m.SetMapIndex(reflect.ValueOf(strings.ToLower(columns[i])), reflect.ValueOf(toString(v)))
+				}
+				continue
+			}
+			if err = assign(elem.Field(index[i]), v); err != nil {
+				return fmt.Errorf("sfshow: failed to convert column %v: %v", columns[i], err)
+			}
+		}
+		slice.Set(reflect.Append(slice, elem))
+	}
+	return rows.Err()
+}
+
+// fieldsOf returns the field indexes of the struct keyed by the lower case column names, and the index of the
+// field for the other columns, or -1.
+func fieldsOf(t reflect.Type) (map[string]int, int) {
+	fields := make(map[string]int)
+	other := -1
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, ok := f.Tag.Lookup("show")
+		if !ok || f.PkgPath != "" {
+			continue
+		}
+		if tag == "*" {
+			if f.Type == reflect.TypeOf(map[string]string(nil)) {
+				other = i
+			}
+			continue
+		}
+		for _, name := range strings.Split(tag, ",") {
+			if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+				fields[name] = i
+			}
+		}
+	}
+	return fields, other
+}
+
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999 -0700",
+	"2006-01-02 15:04:05.999999999 -07:00",
+	"2006-01-02 15:04:05.999999999",
+}
+
+// assign converts the value of the column to the type of the field.
+func assign(field reflect.Value, v interface{}) error {
+	if v == nil {
+		return nil
+	}
+	if field.Type() == reflect.TypeOf(time.Time{}) {
+		switch x := v.(type) {
+		case time.Time:
+			field.Set(reflect.ValueOf(x))
+			return nil
+		case string:
+			if x == "" {
+				return nil
+			}
+			for _, layout := range timeLayouts {
+				if t, err := time.Parse(layout, x); err == nil {
+					field.Set(reflect.ValueOf(t))
+					return nil
+				}
+			}
+		}
+		return fmt.Errorf("not a time: %v", v)
+	}
+	s := toString(v)
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(s)
+	case reflect.Bool:
+		switch strings.ToLower(strings.TrimSpace(s)) {
+		case "true", "y", "yes", "on", "1":
+			field.SetBool(true)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s = strings.TrimSpace(s)
+		if s == "" || strings.EqualFold(s, "null") {
+			return nil
+		}
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			// e.g., 1.0 of a fixed column with a scale
+			f, ferr := strconv.ParseFloat(s, 64)
+			if ferr != nil {
+				return err
+			}
+			n = int64(f)
+		}
+		field.SetInt(n)
+	case reflect.Float32, reflect.Float64:
+		s = strings.TrimSpace(s)
+		if s == "" || strings.EqualFold(s, "null") {
+			return nil
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %v", field.Type())
+	}
+	return nil
+}
+
+func toString(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case []byte:
+		return string(x)
+	case time.Time:
+		return x.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package sfshow
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	sf "github.com/snowflakedb/gosnowflake"
+	"github.com/snowflakedb/gosnowflake/sfmock"
+)
+
+func TestWarehouses(t *testing.T) {
+	srv := sfmock.NewServer()
+	defer srv.Close()
+	created := time.Date(2020, 5, 1, 12, 30, 0, 0, time.UTC)
+	// an older column name, warehouse_size, and a new column unknown to the struct
+	srv.Expect(`^SHOW WAREHOUSES LIKE 'ETL\\'%' IN ACCOUNT$`).Return(sfmock.Rows(
+		[]sfmock.Column{
+			{Name: "name", Type: "text"},
+			{Name: "state", Type: "text"},
+			{Name: "warehouse_size", Type: "text"},
+			{Name: "max_cluster_count", Type: "fixed"},
+			{Name: "is_default", Type: "text"},
+			{Name: "auto_suspend", Type: "fixed"},
+			{Name: "auto_resume", Type: "text"},
+			{Name: "created_on", Type: "timestamp_ltz", Scale: 9},
+			{Name: "comment", Type: "text"},
+			{Name: "future_column", Type: "text"},
+		},
+		[]interface{}{"ETL'WH", "SUSPENDED", "X-Small", 2, "N", 600, "true", created, nil, "x"},
+		[]interface{}{"ETL'WH2", "STARTED", "Large", 1, "Y", nil, "false", created, "nightly", nil}))
+
+	db := sql.OpenDB(sf.NewConnector(sf.SnowflakeDriver{}, srv.Config()))
+	defer db.Close()
+
+	whs, err := Warehouses(context.Background(), db, &Options{Like: "ETL'%", In: "ACCOUNT"})
+	if err != nil {
+		t.Fatalf("failed to show warehouses. err: %v", err)
+	}
+	if len(whs) != 2 {
+		t.Fatalf("unexpected number of warehouses: %v", len(whs))
+	}
+	wh := whs[0]
+	if wh.Name != "ETL'WH" || wh.State != "SUSPENDED" || wh.Size != "X-Small" || wh.MaxClusterCount != 2 ||
+		wh.IsDefault || wh.AutoSuspend != 600 || !wh.AutoResume || !wh.CreatedOn.Equal(created) || wh.Comment != "" {
+		t.Fatalf("unexpected warehouse: %+v", wh)
+	}
+	if wh.Other["future_column"] != "x" {
+		t.Fatalf("the unknown column should be kept. other: %v", wh.Other)
+	}
+	if wh = whs[1]; !wh.IsDefault || wh.AutoSuspend != 0 || wh.Comment != "nightly" || wh.Other != nil {
+		t.Fatalf("unexpected warehouse: %+v", wh)
+	}
+}
+
+func TestScan(t *testing.T) {
+	srv := sfmock.NewServer()
+	defer srv.Close()
+	srv.Expect(`^SHOW SEQUENCES$`).Return(sfmock.Rows(
+		[]sfmock.Column{{Name: "name", Type: "text"}, {Name: "next_value", Type: "fixed"}, {Name: "interval", Type: "fixed"}},
+		[]interface{}{"SEQ1", 101, 1}))
+	db := sql.OpenDB(sf.NewConnector(sf.SnowflakeDriver{}, srv.Config()))
+	defer db.Close()
+
+	rows, err := db.Query("SHOW SEQUENCES")
+	if err != nil {
+		t.Fatalf("failed to query. err: %v", err)
+	}
+	defer rows.Close()
+	var seqs []struct {
+		Name      string `show:"name"`
+		NextValue int64  `show:"next_value"`
+		Interval  int
+	}
+	if err = Scan(rows, &seqs); err != nil {
+		t.Fatalf("failed to scan. err: %v", err)
+	}
+	if len(seqs) != 1 || seqs[0].Name != "SEQ1" || seqs[0].NextValue != 101 || seqs[0].Interval != 0 {
+		t.Fatalf("unexpected sequences: %+v", seqs)
+	}
+	if err = Scan(rows, seqs); err == nil {
+		t.Fatal("should fail for a dest that isn't a pointer to a slice")
+	}
+}
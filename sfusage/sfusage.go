@@ -0,0 +1,303 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+// Package sfusage queries the ACCOUNT_USAGE views of the SNOWFLAKE database into typed Go structs for FinOps
+// tooling. The rows are returned a page at a time in the order of their start times:
+//
+//	filter := &sfusage.QueryHistoryFilter{Start: time.Now().Add(-24 * time.Hour), WarehouseName: "ETL_WH"}
+//	for {
+//		page, err := sfusage.QueryHistory(ctx, db, filter)
+//		if err != nil {
+//			return err
+//		}
+//		for _, q := range page.Queries {
+//			fmt.Println(q.QueryID, q.TotalElapsedTime, q.CreditsUsedCloudServices)
+//		}
+//		if page.Next == "" {
+//			break
+//		}
+//		filter.After = page.Next
+//	}
+//
+// The pages are keyed by the start time and the ID of the last row, so that the rows are neither skipped nor
+// repeated while new rows arrive. The role of the connection needs access to the SNOWFLAKE database, and the
+// views lag behind the activity by up to a few hours.
+package sfusage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultPageSize is the number of rows of a page if the filter doesn't set it.
+const defaultPageSize = 1000
+
+// timestampFormat is the format of the timestamps bound to the queries, which matches timestampLayout.
+const timestampFormat = `YYYY-MM-DD"T"HH24:MI:SS.FF9TZH:TZM`
+
+const timestampLayout = "2006-01-02T15:04:05.000000000-07:00"
+
+// Queryer runs a query. *sql.DB, *sql.Conn and *sql.Tx are Queryers.
+type Queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// Query is a row of the QUERY_HISTORY view.
+type Query struct {
+	QueryID                  string
+	QueryText                string
+	QueryType                string
+	QueryTag                 string
+	DatabaseName             string
+	SchemaName               string
+	UserName                 string
+	RoleName                 string
+	WarehouseName            string
+	WarehouseSize            string
+	ExecutionStatus          string
+	ErrorCode                string
+	ErrorMessage             string
+	StartTime                time.Time
+	EndTime                  time.Time
+	TotalElapsedTime         time.Duration
+	CompilationTime          time.Duration
+	ExecutionTime            time.Duration
+	QueuedOverloadTime       time.Duration
+	BytesScanned             int64
+	RowsProduced             int64
+	CreditsUsedCloudServices float64
+}
+
+// QueryHistoryFilter selects the rows of QUERY_HISTORY.
+type QueryHistoryFilter struct {
+	Start         time.Time // the earliest start time of the queries (optional)
+	End           time.Time // the start times of the queries are before End (optional)
+	WarehouseName string    // (optional)
+	UserName      string    // (optional)
+	PageSize      int       // the maximum number of rows of a page. 1000 by default
+	After         string    // Next of the previous page, or empty for the first page
+}
+
+// QueryHistoryPage is a page of QUERY_HISTORY.
+type QueryHistoryPage struct {
+	Queries []Query
+	Next    string // the cursor of the next page, or empty if this is the last page
+}
+
+// WarehouseMetering is a row of the WAREHOUSE_METERING_HISTORY view, i.e., the credits a warehouse used in an hour.
+type WarehouseMetering struct {
+	StartTime                time.Time
+	EndTime                  time.Time
+	WarehouseID              int64
+	WarehouseName            string
+	CreditsUsed              float64
+	CreditsUsedCompute       float64
+	CreditsUsedCloudServices float64
+}
+
+// WarehouseMeteringFilter selects the rows of WAREHOUSE_METERING_HISTORY.
+type WarehouseMeteringFilter struct {
+	Start         time.Time // the earliest start time of the hours (optional)
+	End           time.Time // the start times of the hours are before End (optional)
+	WarehouseName string    // (optional)
+	PageSize      int       // the maximum number of rows of a page. 1000 by default
+	After         string    // Next of the previous page, or empty for the first page
+}
+
+// WarehouseMeteringPage is a page of WAREHOUSE_METERING_HISTORY.
+type WarehouseMeteringPage struct {
+	Metering []WarehouseMetering
+	Next     string // the cursor of the next page, or empty if this is the last page
+}
+
+// QueryHistory returns a page of the SNOWFLAKE.ACCOUNT_USAGE.QUERY_HISTORY view.
+func QueryHistory(ctx context.Context, q Queryer, filter *QueryHistoryFilter) (*QueryHistoryPage, error) {
+	if filter == nil {
+		filter = &QueryHistoryFilter{}
+	}
+	b := newQueryBuilder("QUERY_ID")
+	b.timeRange(filter.Start, filter.End)
+	b.equal("WAREHOUSE_NAME", filter.WarehouseName)
+	b.equal("USER_NAME", filter.UserName)
+	if err := b.after(filter.After); err != nil {
+		return nil, err
+	}
+	rows, err := q.QueryContext(ctx, b.query(
+		"QUERY_ID, QUERY_TEXT, QUERY_TYPE, QUERY_TAG, DATABASE_NAME, SCHEMA_NAME, USER_NAME, ROLE_NAME, "+
+			"WAREHOUSE_NAME, WAREHOUSE_SIZE, EXECUTION_STATUS, ERROR_CODE, ERROR_MESSAGE, START_TIME, END_TIME, "+
+			"TOTAL_ELAPSED_TIME, COMPILATION_TIME, EXECUTION_TIME, QUEUED_OVERLOAD_TIME, BYTES_SCANNED, "+
+			"ROWS_PRODUCED, CREDITS_USED_CLOUD_SERVICES",
+		"QUERY_HISTORY", filter.PageSize), b.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	page := &QueryHistoryPage{}
+	for rows.Next() {
+		var r Query
+		var text, typ, tag, db, schema, user, role, wh, size, status, code, msg sql.NullString
+		var end sql.NullTime
+		var elapsed, compilation, execution, queued, scanned, produced sql.NullInt64
+		var credits sql.NullFloat64
+		if err = rows.Scan(&r.QueryID, &text, &typ, &tag, &db, &schema, &user, &role, &wh, &size, &status,
+			&code, &msg, &r.StartTime, &end, &elapsed, &compilation, &execution, &queued, &scanned, &produced,
+			&credits); err != nil {
+			return nil, err
+		}
+		r.QueryText, r.QueryType, r.QueryTag = text.String, typ.String, tag.String
+		r.DatabaseName, r.SchemaName, r.UserName, r.RoleName = db.String, schema.String, user.String, role.String
+		r.WarehouseName, r.WarehouseSize, r.ExecutionStatus = wh.String, size.String, status.String
+		r.ErrorCode, r.ErrorMessage = code.String, msg.String
+		r.EndTime = end.Time
+		r.TotalElapsedTime = milliseconds(elapsed)
+		r.CompilationTime = milliseconds(compilation)
+		r.ExecutionTime = milliseconds(execution)
+		r.QueuedOverloadTime = milliseconds(queued)
+		r.BytesScanned, r.RowsProduced = scanned.Int64, produced.Int64
+		r.CreditsUsedCloudServices = credits.Float64
+		page.Queries = append(page.Queries, r)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if n := pageSize(filter.PageSize); len(page.Queries) > n {
+		page.Queries = page.Queries[:n]
+		last := page.Queries[n-1]
+		page.Next = encodeCursor(last.StartTime, last.QueryID)
+	}
+	return page, nil
+}
+
+// WarehouseMeteringHistory returns a page of the SNOWFLAKE.ACCOUNT_USAGE.WAREHOUSE_METERING_HISTORY view.
+func WarehouseMeteringHistory(ctx context.Context, q Queryer, filter *WarehouseMeteringFilter) (*WarehouseMeteringPage, error) {
+	if filter == nil {
+		filter = &WarehouseMeteringFilter{}
+	}
+	b := newQueryBuilder("WAREHOUSE_ID")
+	b.timeRange(filter.Start, filter.End)
+	b.equal("WAREHOUSE_NAME", filter.WarehouseName)
+	if err := b.after(filter.After); err != nil {
+		return nil, err
+	}
+	rows, err := q.QueryContext(ctx, b.query(
+		"START_TIME, END_TIME, WAREHOUSE_ID, WAREHOUSE_NAME, CREDITS_USED, CREDITS_USED_COMPUTE, "+
+			"CREDITS_USED_CLOUD_SERVICES",
+		"WAREHOUSE_METERING_HISTORY", filter.PageSize), b.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	page := &WarehouseMeteringPage{}
+	for rows.Next() {
+		var r WarehouseMetering
+		var name sql.NullString
+		var credits, compute, cloud sql.NullFloat64
+		if err = rows.Scan(&r.StartTime, &r.EndTime, &r.WarehouseID, &name, &credits, &compute, &cloud); err != nil {
+			return nil, err
+		}
+		r.WarehouseName = name.String
+		r.CreditsUsed, r.CreditsUsedCompute, r.CreditsUsedCloudServices = credits.Float64, compute.Float64, cloud.Float64
+		page.Metering = append(page.Metering, r)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if n := pageSize(filter.PageSize); len(page.Metering) > n {
+		page.Metering = page.Metering[:n]
+		last := page.Metering[n-1]
+		page.Next = encodeCursor(last.StartTime, strconv.FormatInt(last.WarehouseID, 10))
+	}
+	return page, nil
+}
+
+// queryBuilder builds the query of a page ordered by START_TIME and the ID column.
+type queryBuilder struct {
+	id    string
+	conds []string
+	args  []interface{}
+}
+
+func newQueryBuilder(id string) *queryBuilder {
+	return &queryBuilder{id: id}
+}
+
+func (b *queryBuilder) timeRange(start time.Time, end time.Time) {
+	if !start.IsZero() {
+		b.conds = append(b.conds, "START_TIME >= "+toTimestamp)
+		b.args = append(b.args, start.Format(timestampLayout))
+	}
+	if !end.IsZero() {
+		b.conds = append(b.conds, "START_TIME < "+toTimestamp)
+		b.args = append(b.args, end.Format(timestampLayout))
+	}
+}
+
+func (b *queryBuilder) equal(column string, value string) {
+	if value != "" {
+		b.conds = append(b.conds, column+" = ?")
+		b.args = append(b.args, value)
+	}
+}
+
+// after selects the rows after the cursor.
+func (b *queryBuilder) after(cursor string) error {
+	if cursor == "" {
+		return nil
+	}
+	start, id, err := decodeCursor(cursor)
+	if err != nil {
+		return err
+	}
+	ts := start.Format(timestampLayout)
+	b.conds = append(b.conds, fmt.Sprintf("(START_TIME > %v OR (START_TIME = %v AND %v > ?))", toTimestamp, toTimestamp, b.id))
+	b.args = append(b.args, ts, ts, id)
+	return nil
+}
+
+// query returns the query of a page of the view. One more row than the page size is fetched to tell if there
+// is a next page.
+func (b *queryBuilder) query(columns string, view string, size int) string {
+	query := "SELECT " + columns + " FROM SNOWFLAKE.ACCOUNT_USAGE." + view
+	if len(b.conds) > 0 {
+		query += " WHERE " + strings.Join(b.conds, " AND ")
+	}
+	return query + fmt.Sprintf(" ORDER BY START_TIME, %v LIMIT %d", b.id, pageSize(size)+1)
+}
+
+var toTimestamp = "TO_TIMESTAMP_LTZ(?, '" + timestampFormat + "')"
+
+func pageSize(n int) int {
+	if n <= 0 {
+		return defaultPageSize
+	}
+	return n
+}
+
+func milliseconds(v sql.NullInt64) time.Duration {
+	return time.Duration(v.Int64) * time.Millisecond
+}
+
+// encodeCursor returns the cursor of the page after the row with the start time and the ID.
+func encodeCursor(start time.Time, id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(start.Format(timestampLayout) + "|" + id))
+}
+
+func decodeCursor(cursor string) (time.Time, string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", fmt.Errorf("sfusage: invalid cursor: %v", err)
+	}
+	parts := strings.SplitN(string(b), "|", 2)
+	if len(parts) != 2 {
+		return time.Time{}, "", fmt.Errorf("sfusage: invalid cursor: %v", cursor)
+	}
+	start, err := time.Parse(timestampLayout, parts[0])
+	if err != nil {
+		return time.Time{}, "", fmt.Errorf("sfusage: invalid cursor: %v", err)
+	}
+	return start, parts[1], nil
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package sfusage
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	sf "github.com/snowflakedb/gosnowflake"
+	"github.com/snowflakedb/gosnowflake/sfmock"
+)
+
+var queryHistoryColumns = []sfmock.Column{
+	{Name: "QUERY_ID", Type: "text"},
+	{Name: "QUERY_TEXT", Type: "text"},
+	{Name: "QUERY_TYPE", Type: "text"},
+	{Name: "QUERY_TAG", Type: "text"},
+	{Name: "DATABASE_NAME", Type: "text"},
+	{Name: "SCHEMA_NAME", Type: "text"},
+	{Name: "USER_NAME", Type: "text"},
+	{Name: "ROLE_NAME", Type: "text"},
+	{Name: "WAREHOUSE_NAME", Type: "text"},
+	{Name: "WAREHOUSE_SIZE", Type: "text"},
+	{Name: "EXECUTION_STATUS", Type: "text"},
+	{Name: "ERROR_CODE", Type: "text"},
+	{Name: "ERROR_MESSAGE", Type: "text"},
+	{Name: "START_TIME", Type: "timestamp_ltz", Scale: 9},
+	{Name: "END_TIME", Type: "timestamp_ltz", Scale: 9},
+	{Name: "TOTAL_ELAPSED_TIME", Type: "fixed"},
+	{Name: "COMPILATION_TIME", Type: "fixed"},
+	{Name: "EXECUTION_TIME", Type: "fixed"},
+	{Name: "QUEUED_OVERLOAD_TIME", Type: "fixed"},
+	{Name: "BYTES_SCANNED", Type: "fixed"},
+	{Name: "ROWS_PRODUCED", Type: "fixed"},
+	{Name: "CREDITS_USED_CLOUD_SERVICES", Type: "fixed", Scale: 9},
+}
+
+func queryHistoryRow(id string, start time.Time) []interface{} {
+	return []interface{}{id, "SELECT 1", "SELECT", nil, "DB", "PUBLIC", "ALICE", "ANALYST", "ETL_WH", "X-Small",
+		"SUCCESS", nil, nil, start, start.Add(1500 * time.Millisecond), 1500, 100, 1400, 0, 1024, 1, "0.000012000"}
+}
+
+func TestQueryHistory(t *testing.T) {
+	srv := sfmock.NewServer()
+	defer srv.Close()
+	t0 := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	// the first page fetches one more row than the page size
+	srv.Expect(`^SELECT QUERY_ID, .* FROM SNOWFLAKE\.ACCOUNT_USAGE\.QUERY_HISTORY WHERE START_TIME >= TO_TIMESTAMP_LTZ\(\?, .*\) AND WAREHOUSE_NAME = \? ORDER BY START_TIME, QUERY_ID LIMIT 3$`).
+		Return(sfmock.Rows(queryHistoryColumns,
+			queryHistoryRow("q1", t0), queryHistoryRow("q2", t0), queryHistoryRow("q3", t0.Add(time.Minute)))).Times(1)
+	srv.Expect(`AND \(START_TIME > TO_TIMESTAMP_LTZ\(\?, .*\) OR \(START_TIME = TO_TIMESTAMP_LTZ\(\?, .*\) AND QUERY_ID > \?\)\) ORDER BY`).
+		Return(sfmock.Rows(queryHistoryColumns, queryHistoryRow("q3", t0.Add(time.Minute)))).Times(1)
+
+	db := sql.OpenDB(sf.NewConnector(sf.SnowflakeDriver{}, srv.Config()))
+	defer db.Close()
+
+	filter := &QueryHistoryFilter{Start: t0, WarehouseName: "ETL_WH", PageSize: 2}
+	page, err := QueryHistory(context.Background(), db, filter)
+	if err != nil {
+		t.Fatalf("failed to query the history. err: %v", err)
+	}
+	if len(page.Queries) != 2 || page.Next == "" {
+		t.Fatalf("unexpected page. queries: %v, next: %q", len(page.Queries), page.Next)
+	}
+	q := page.Queries[1]
+	if q.QueryID != "q2" || q.UserName != "ALICE" || q.QueryTag != "" || !q.StartTime.Equal(t0) ||
+		q.TotalElapsedTime != 1500*time.Millisecond || q.BytesScanned != 1024 || q.CreditsUsedCloudServices != 0.000012 {
+		t.Fatalf("unexpected query: %+v", q)
+	}
+
+	filter.After = page.Next
+	if page, err = QueryHistory(context.Background(), db, filter); err != nil {
+		t.Fatalf("failed to query the next page. err: %v", err)
+	}
+	if len(page.Queries) != 1 || page.Queries[0].QueryID != "q3" || page.Next != "" {
+		t.Fatalf("unexpected last page: %+v", page)
+	}
+	stmts := srv.Statements()
+	if b := stmts[len(stmts)-1].Bindings; b["3"].Value != "2020-05-01T12:00:00.000000000+00:00" || b["5"].Value != "q2" {
+		t.Fatalf("the next page should start after the last row. bindings: %v", b)
+	}
+	if err = srv.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	filter.After = "not a cursor"
+	if _, err = QueryHistory(context.Background(), db, filter); err == nil {
+		t.Fatal("should fail for an invalid cursor")
+	}
+}
+
+func TestWarehouseMeteringHistory(t *testing.T) {
+	srv := sfmock.NewServer()
+	defer srv.Close()
+	t0 := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	srv.Expect(`FROM SNOWFLAKE\.ACCOUNT_USAGE\.WAREHOUSE_METERING_HISTORY ORDER BY START_TIME, WAREHOUSE_ID LIMIT 1001$`).
+		Return(sfmock.Rows([]sfmock.Column{
+			{Name: "START_TIME", Type: "timestamp_ltz", Scale: 9},
+			{Name: "END_TIME", Type: "timestamp_ltz", Scale: 9},
+			{Name: "WAREHOUSE_ID", Type: "fixed"},
+			{Name: "WAREHOUSE_NAME", Type: "text"},
+			{Name: "CREDITS_USED", Type: "fixed", Scale: 9},
+			{Name: "CREDITS_USED_COMPUTE", Type: "fixed", Scale: 9},
+			{Name: "CREDITS_USED_CLOUD_SERVICES", Type: "fixed", Scale: 9},
+		}, []interface{}{t0, t0.Add(time.Hour), 7, "ETL_WH", "1.250000000", "1.000000000", "0.250000000"}))
+
+	db := sql.OpenDB(sf.NewConnector(sf.SnowflakeDriver{}, srv.Config()))
+	defer db.Close()
+
+	page, err := WarehouseMeteringHistory(context.Background(), db, nil)
+	if err != nil {
+		t.Fatalf("failed to query the metering history. err: %v", err)
+	}
+	if len(page.Metering) != 1 || page.Next != "" {
+		t.Fatalf("unexpected page: %+v", page)
+	}
+	m := page.Metering[0]
+	if m.WarehouseID != 7 || m.WarehouseName != "ETL_WH" || m.CreditsUsed != 1.25 || m.CreditsUsedCloudServices != 0.25 ||
+		!m.EndTime.Equal(t0.Add(time.Hour)) {
+		t.Fatalf("unexpected metering: %+v", m)
+	}
+}
@@ -0,0 +1,414 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	sqlAPIStatementsPath = "/api/v2/statements"
+
+	headerAuthorizationTokenTypeKey = "X-Snowflake-Authorization-Token-Type"
+	headerBearerToken               = "Bearer %v"
+)
+
+// sqlAPIRequest is the body of a statement submitted to the SQL API.
+type sqlAPIRequest struct {
+	Statement  string                       `json:"statement"`
+	Database   string                       `json:"database,omitempty"`
+	Schema     string                       `json:"schema,omitempty"`
+	Warehouse  string                       `json:"warehouse,omitempty"`
+	Role       string                       `json:"role,omitempty"`
+	Bindings   map[string]execBindParameter `json:"bindings,omitempty"`
+	Parameters map[string]interface{}       `json:"parameters,omitempty"`
+}
+
+type sqlAPIPartition struct {
+	RowCount         int   `json:"rowCount"`
+	UncompressedSize int64 `json:"uncompressedSize"`
+	CompressedSize   int64 `json:"compressedSize"`
+}
+
+type sqlAPIResultSetMetaData struct {
+	NumRows       int64                 `json:"numRows"`
+	RowType       []execResponseRowType `json:"rowType"`
+	PartitionInfo []sqlAPIPartition     `json:"partitionInfo"`
+}
+
+// sqlAPIResponse is the result, the status or the error of a statement submitted to the SQL API.
+type sqlAPIResponse struct {
+	Code               string                  `json:"code"`
+	SQLState           string                  `json:"sqlState"`
+	Message            string                  `json:"message"`
+	StatementHandle    string                  `json:"statementHandle"`
+	StatementStatusURL string                  `json:"statementStatusUrl"`
+	ResultSetMetaData  sqlAPIResultSetMetaData `json:"resultSetMetaData"`
+	Data               [][]*string             `json:"data"`
+	Stats              map[string]int64        `json:"stats"`
+
+	status int // the HTTP status
+}
+
+// sqlAPIClient submits the statements of a connection to the SQL API instead of the internal endpoints. The
+// SQL API is stateless: every statement is authorized by the JWT or the OAuth token of the Config and runs in
+// the database, the schema, the warehouse and the role of the Config.
+type sqlAPIClient struct {
+	cfg *Config
+
+	mu      sync.Mutex
+	handles map[uuid.UUID]string // the statement handles of the requests in flight, to cancel them
+}
+
+func errSQLAPINotSupported(feature string) *SnowflakeError {
+	return &SnowflakeError{
+		Number:      ErrCodeSQLAPINotSupported,
+		SQLState:    SQLStateFeatureNotSupported,
+		Message:     errMsgSQLAPINotSupported,
+		MessageArgs: []interface{}{feature},
+	}
+}
+
+// openSQLAPI opens a connection that submits its statements to the SQL API. There is no session to log in to,
+// renew or keep alive, so the connection is usable as soon as the credentials are available.
+func openSQLAPI(ctx context.Context, sc *snowflakeConn) (*snowflakeConn, error) {
+	var err error
+	switch {
+	case sc.cfg.Authenticator != AuthTypeJwt && sc.cfg.Authenticator != AuthTypeOAuth:
+		err = errSQLAPINotSupported("authenticator " + sc.cfg.Authenticator.String())
+	case sc.cfg.PingMode == PingModeHeartbeat:
+		err = errSQLAPINotSupported("pingMode " + sc.cfg.PingMode.String())
+	case sc.cfg.CredentialsProvider != nil:
+		err = applyCredentials(ctx, sc.cfg)
+	}
+	if err != nil {
+		sc.cleanup()
+		return nil, err
+	}
+	sc.sqlAPI = &sqlAPIClient{cfg: sc.cfg, handles: make(map[uuid.UUID]string)}
+	sc.rest.FuncPostQuery = sc.sqlAPI.postStatement
+	sc.rest.FuncCancelQuery = sc.sqlAPI.cancelStatement
+	sc.rest.FuncRenewSession = func(context.Context, *snowflakeRestful, time.Duration) error { return nil }
+	sc.rest.FuncCloseSession = func(context.Context, *snowflakeRestful, time.Duration) error { return nil }
+	sc.info = ConnectionInfo{
+		Database:  sc.cfg.Database,
+		Schema:    sc.cfg.Schema,
+		Warehouse: sc.cfg.Warehouse,
+		Role:      sc.cfg.Role,
+	}
+	if err = sc.runOpenHook(ctx); err != nil {
+		sc.cleanup()
+		return nil, err
+	}
+	return sc, nil
+}
+
+// authorize sets the authorization headers of a request to the SQL API. A JWT is generated for every request,
+// so that it doesn't expire during long queries.
+func (api *sqlAPIClient) authorize(headers map[string]string) error {
+	if api.cfg.Authenticator == AuthTypeJwt {
+		token, err := prepareJWTToken(api.cfg)
+		if err != nil {
+			return err
+		}
+		headers[headerAuthorizationKey] = fmt.Sprintf(headerBearerToken, token)
+		headers[headerAuthorizationTokenTypeKey] = "KEYPAIR_JWT"
+		return nil
+	}
+	headers[headerAuthorizationKey] = fmt.Sprintf(headerBearerToken, api.cfg.Token)
+	headers[headerAuthorizationTokenTypeKey] = "OAUTH"
+	return nil
+}
+
+// postStatement submits the query request built by execOnce to the SQL API and waits for its result. It
+// cancels the statement if the context is done in the meantime.
+func (api *sqlAPIClient) postStatement(
+	ctx context.Context,
+	sr *snowflakeRestful,
+	params *url.Values,
+	headers map[string]string,
+	body []byte,
+	timeout time.Duration,
+	requestID *uuid.UUID) (
+	*execResponse, error) {
+	data, err := api.submitStatement(ctx, sr, params, headers, body, timeout, requestID)
+	// the handle is kept until the statement is canceled below, if need be
+	defer func() {
+		api.mu.Lock()
+		delete(api.handles, *requestID)
+		api.mu.Unlock()
+	}()
+	if err != context.Canceled && err != context.DeadlineExceeded {
+		return data, err
+	}
+	if err = api.cancelStatement(context.TODO(), sr, requestID, timeout); err != nil {
+		return nil, err
+	}
+	return nil, ctx.Err()
+}
+
+func (api *sqlAPIClient) submitStatement(
+	ctx context.Context,
+	sr *snowflakeRestful,
+	params *url.Values,
+	headers map[string]string,
+	body []byte,
+	timeout time.Duration,
+	requestID *uuid.UUID) (
+	*execResponse, error) {
+	var req execRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+	switch {
+	case req.DescribeOnly:
+		return nil, errSQLAPINotSupported("describing statements")
+	case req.AsyncExec:
+		return nil, errSQLAPINotSupported("asynchronous queries")
+	}
+	apiReq := sqlAPIRequest{
+		Statement: req.SQLText,
+		Database:  api.cfg.Database,
+		Schema:    api.cfg.Schema,
+		Warehouse: api.cfg.Warehouse,
+		Role:      api.cfg.Role,
+		Bindings:  req.Bindings,
+	}
+	for name, value := range req.Parameters {
+		switch name {
+		case string(MultiStatementCount):
+			if fmt.Sprint(value) != "1" {
+				return nil, errSQLAPINotSupported("multi-statement queries")
+			}
+		case "GO_QUERY_RESULT_FORMAT":
+			// the results of the SQL API are always in JSON
+		default:
+			if apiReq.Parameters == nil {
+				apiReq.Parameters = make(map[string]interface{})
+			}
+			apiReq.Parameters[name] = value
+		}
+	}
+	apiBody, err := json.Marshal(apiReq)
+	if err != nil {
+		return nil, err
+	}
+
+	headers["accept"] = headerContentTypeApplicationJSON
+	if err = api.authorize(headers); err != nil {
+		return nil, err
+	}
+	params.Add(requestIDKey, requestID.String())
+	if isIdempotent(ctx) {
+		// the statement is not run again if the request ID has already run it
+		params.Add("retry", "true")
+	}
+	fullURL := sr.getFullURL(sqlAPIStatementsPath, params)
+	resp, err := newRetryHTTP(ctx, sr.Client, http.NewRequest, fullURL, headers, timeout).
+		doPost().setBody(apiBody).doAccept(http.StatusAccepted).doRaise4XX(true).execute()
+	if err != nil {
+		return nil, err
+	}
+	respd, err := decodeSQLAPIResponse(resp, fullURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if respd.status == http.StatusAccepted {
+		// the statement handle is known before the statement completes
+		api.mu.Lock()
+		api.handles[*requestID] = respd.StatementHandle
+		api.mu.Unlock()
+		sendQueryID(ctx, respd.StatementHandle)
+		defer sr.startPolling(respd.StatementHandle)()
+	}
+	poller := newQueryPoller(queryPollInterval)
+	for respd.status == http.StatusAccepted {
+		if err = poller.wait(ctx); err != nil {
+			return nil, err
+		}
+		statusURL, err := sr.getResultURL(respd.StatementStatusURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		if err = api.authorize(headers); err != nil {
+			return nil, err
+		}
+		if err = sr.waitRateLimit(ctx); err != nil {
+			return nil, err
+		}
+		glog.V(2).Infof("polling the statement %v", respd.StatementHandle)
+		resp, err = newRetryHTTP(ctx, sr.Client, http.NewRequest, statusURL, headers, timeout).
+			doAccept(http.StatusAccepted).doRaise4XX(true).execute()
+		if err != nil {
+			return nil, err
+		}
+		if respd, err = decodeSQLAPIResponse(resp, statusURL); err != nil {
+			return nil, err
+		}
+	}
+	return api.execResponse(sr, respd), nil
+}
+
+// decodeSQLAPIResponse decodes the response of the SQL API, or returns an error if it isn't about a statement.
+func decodeSQLAPIResponse(resp *http.Response, fullURL *url.URL) (*sqlAPIResponse, error) {
+	defer drainAndClose(resp.Body)
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusAccepted, http.StatusUnprocessableEntity:
+		respd := &sqlAPIResponse{status: resp.StatusCode}
+		if err := json.NewDecoder(resp.Body).Decode(respd); err != nil {
+			glog.V(1).Infof("failed to decode JSON. err: %v", err)
+			return nil, err
+		}
+		return respd, nil
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		glog.V(1).Infof("failed to extract HTTP response body. err: %v", err)
+		return nil, err
+	}
+	glog.V(1).Infof("HTTP: %v, URL: %v, Body: %v", resp.StatusCode, fullURL, b)
+	glog.V(1).Infof("Header: %v", resp.Header)
+	glog.Flush()
+	return nil, &SnowflakeError{
+		Number:      ErrFailedToPostQuery,
+		SQLState:    SQLStateConnectionFailure,
+		Message:     errMsgFailedToPostQuery,
+		MessageArgs: []interface{}{resp.StatusCode, fullURL},
+	}
+}
+
+// execResponse converts the result of the SQL API to the response of the internal endpoints. The partitions
+// after the first one are downloaded as the chunks of the result.
+func (api *sqlAPIClient) execResponse(sr *snowflakeRestful, respd *sqlAPIResponse) *execResponse {
+	if respd.status == http.StatusUnprocessableEntity {
+		return &execResponse{
+			Code:    respd.Code,
+			Message: respd.Message,
+			Data:    execResponseData{QueryID: respd.StatementHandle, SQLState: respd.SQLState},
+		}
+	}
+	data := execResponseData{
+		QueryID:           respd.StatementHandle,
+		SQLState:          respd.SQLState,
+		RowType:           respd.ResultSetMetaData.RowType,
+		RowSet:            respd.Data,
+		Total:             respd.ResultSetMetaData.NumRows,
+		Returned:          int64(len(respd.Data)),
+		QueryResultFormat: jsonFormat,
+		// the statements don't change the context of the next ones
+		FinalDatabaseName:  api.cfg.Database,
+		FinalSchemaName:    api.cfg.Schema,
+		FinalWarehouseName: api.cfg.Warehouse,
+		FinalRoleName:      api.cfg.Role,
+	}
+	if len(respd.Stats) > 0 {
+		// the rows affected are in the result as with the internal endpoints
+		data.StatementTypeID = statementTypeIDDml
+	}
+	for i := 1; i < len(respd.ResultSetMetaData.PartitionInfo); i++ {
+		partition := respd.ResultSetMetaData.PartitionInfo[i]
+		params := &url.Values{}
+		params.Add("partition", strconv.Itoa(i))
+		data.Chunks = append(data.Chunks, execResponseChunk{
+			URL:              sr.getFullURL(sqlAPIStatementsPath+"/"+respd.StatementHandle, params).String(),
+			RowCount:         partition.RowCount,
+			UncompressedSize: partition.UncompressedSize,
+			CompressedSize:   partition.CompressedSize,
+		})
+	}
+	return &execResponse{Code: respd.Code, Message: respd.Message, Success: true, Data: data}
+}
+
+// getPartition downloads a partition of the result of a statement as a chunk. The headers of the chunk
+// downloader are replaced by the authorization headers, and the rows of the partition are unwrapped from its
+// JSON object to be decoded as the rows of a chunk.
+func (api *sqlAPIClient) getPartition(
+	ctx context.Context,
+	scd *snowflakeChunkDownloader,
+	fullURL string,
+	_ map[string]string,
+	timeout time.Duration) (
+	*http.Response, error) {
+	u, err := url.Parse(fullURL)
+	if err != nil {
+		return nil, err
+	}
+	headers := map[string]string{
+		"accept":     headerContentTypeApplicationJSON,
+		"User-Agent": scd.sc.rest.getUserAgent(),
+	}
+	if err = api.authorize(headers); err != nil {
+		return nil, err
+	}
+	resp, err := newRetryHTTP(ctx, scd.sc.rest.Client, http.NewRequest, u, headers, timeout).execute()
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+	defer drainAndClose(resp.Body)
+	var partition struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&partition); err != nil {
+		return nil, err
+	}
+	// the chunks are the rows without the enclosing brackets
+	rows := bytes.TrimSpace(partition.Data)
+	rows = bytes.TrimSuffix(bytes.TrimPrefix(rows, []byte("[")), []byte("]"))
+	resp.Body = ioutil.NopCloser(bytes.NewReader(rows))
+	return resp, nil
+}
+
+// cancelStatement cancels the statement submitted by the request, if it is in progress.
+func (api *sqlAPIClient) cancelStatement(ctx context.Context, sr *snowflakeRestful, requestID *uuid.UUID, timeout time.Duration) error {
+	api.mu.Lock()
+	handle := api.handles[*requestID]
+	api.mu.Unlock()
+	if handle == "" {
+		return nil
+	}
+	glog.V(2).Infof("cancel the statement %v", handle)
+	headers := map[string]string{
+		"Content-Type": headerContentTypeApplicationJSON,
+		"accept":       headerContentTypeApplicationJSON,
+		"User-Agent":   sr.getUserAgent(),
+	}
+	if err := api.authorize(headers); err != nil {
+		return err
+	}
+	fullURL := sr.getFullURL(sqlAPIStatementsPath+"/"+handle+"/cancel", &url.Values{})
+	resp, err := newRetryHTTP(ctx, sr.Client, http.NewRequest, fullURL, headers, timeout).
+		doPost().doRaise4XX(true).execute()
+	if err != nil {
+		return err
+	}
+	defer drainAndClose(resp.Body)
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	return &SnowflakeError{
+		Number:      ErrFailedToCancelQuery,
+		SQLState:    SQLStateConnectionFailure,
+		Message:     errMsgFailedToCancelQuery,
+		MessageArgs: []interface{}{resp.StatusCode, fullURL},
+	}
+}
+
+// getChunkFunc returns the function downloading the chunks of the results of the connection.
+func (sc *snowflakeConn) getChunkFunc() func(context.Context, *snowflakeChunkDownloader, string, map[string]string, time.Duration) (*http.Response, error) {
+	if sc != nil && sc.sqlAPI != nil {
+		return sc.sqlAPI.getPartition
+	}
+	return getChunk
+}
@@ -0,0 +1,203 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSQLAPI(t *testing.T) {
+	var submitted []sqlAPIRequest
+	var submittedParams []url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" || r.Header.Get(headerAuthorizationTokenTypeKey) != "OAUTH" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == "POST" && r.URL.Path == sqlAPIStatementsPath:
+			var req sqlAPIRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Error(err)
+			}
+			submitted = append(submitted, req)
+			submittedParams = append(submittedParams, r.URL.Query())
+			switch req.Statement {
+			case "SELECT C1 FROM T":
+				w.WriteHeader(http.StatusAccepted)
+				io.WriteString(w, `{"code":"333334","statementHandle":"h1","statementStatusUrl":"/api/v2/statements/h1?requestId=x"}`)
+			case "INSERT INTO T VALUES (?), (?)":
+				io.WriteString(w, `{"code":"090001","statementHandle":"h2","resultSetMetaData":{"numRows":1,`+
+					`"rowType":[{"name":"number of rows inserted","type":"fixed"}],"partitionInfo":[{"rowCount":1}]},`+
+					`"data":[["2"]],"stats":{"numRowsInserted":2}}`)
+			default:
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				io.WriteString(w, `{"code":"000904","sqlState":"42000","message":"invalid identifier 'X'","statementHandle":"h3"}`)
+			}
+		case r.Method == "GET" && r.URL.Path == sqlAPIStatementsPath+"/h1":
+			if r.URL.Query().Get("partition") == "1" {
+				io.WriteString(w, `{"data":[["3"]]}`)
+				return
+			}
+			io.WriteString(w, `{"code":"090001","statementHandle":"h1","resultSetMetaData":{"numRows":3,`+
+				`"rowType":[{"name":"C1","type":"fixed"}],"partitionInfo":[{"rowCount":2},{"rowCount":1}]},`+
+				`"data":[["1"],["2"]]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{
+		Account:       "a",
+		Host:          u.Hostname(),
+		Port:          port,
+		Protocol:      "http",
+		Authenticator: AuthTypeOAuth,
+		Token:         "tok",
+		Database:      "D",
+		Warehouse:     "W",
+		UseSQLAPI:     true,
+	}
+	conn, err := SnowflakeDriver{}.openWithClient(context.Background(), cfg, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to open. err: %v", err)
+	}
+	sc := conn.(*snowflakeConn)
+	defer sc.Close()
+
+	rows, err := sc.QueryContext(context.Background(), "SELECT C1 FROM T", nil)
+	if err != nil {
+		t.Fatalf("failed to query. err: %v", err)
+	}
+	dest := make([]driver.Value, 1)
+	var got []string
+	for rows.Next(dest) == nil {
+		got = append(got, dest[0].(string))
+	}
+	rows.Close()
+	if len(got) != 3 || got[0] != "1" || got[2] != "3" {
+		t.Fatalf("the rows of all the partitions should be returned. rows: %v", got)
+	}
+	if sc.QueryID != "h1" || sc.cfg.Database != "D" {
+		t.Fatalf("unexpected query ID or context. query ID: %v, database: %v", sc.QueryID, sc.cfg.Database)
+	}
+
+	result, err := sc.ExecContext(WithIdempotencyKey(context.Background(), "msg-1"), "INSERT INTO T VALUES (?), (?)", []driver.NamedValue{
+		{Ordinal: 1, Value: int64(1)},
+		{Ordinal: 2, Value: int64(2)},
+	})
+	if err != nil {
+		t.Fatalf("failed to insert. err: %v", err)
+	}
+	if n, err := result.RowsAffected(); err != nil || n != 2 {
+		t.Fatalf("unexpected rows affected: %v, err: %v", n, err)
+	}
+	req := submitted[1]
+	if req.Database != "D" || req.Warehouse != "W" || len(req.Bindings) != 2 || req.Bindings["2"].Type != "FIXED" {
+		t.Fatalf("unexpected request: %+v", req)
+	}
+	if params := submittedParams[1]; params.Get(requestIDKey) != IdempotentRequestID("msg-1") ||
+		params.Get("retry") != "true" || submittedParams[0].Get("retry") != "" {
+		t.Fatalf("the idempotent statement should be submitted as a retry of its request ID. params: %v", submittedParams)
+	}
+
+	_, err = sc.QueryContext(context.Background(), "SELECT X FROM T", nil)
+	if driverErr, ok := err.(*SnowflakeError); !ok || driverErr.Number != 904 || driverErr.QueryID != "h3" {
+		t.Fatalf("should have failed with the error of the statement. err: %v", err)
+	}
+
+	ctx, err := WithMultiStatement(context.Background(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = sc.QueryContext(ctx, "SELECT 1; SELECT 2", nil)
+	if driverErr, ok := err.(*SnowflakeError); !ok || driverErr.Number != ErrCodeSQLAPINotSupported {
+		t.Fatalf("multi-statement queries should not be supported. err: %v", err)
+	}
+}
+
+func TestSQLAPIAuthenticator(t *testing.T) {
+	cfg := Config{Account: "a", User: "u", Password: "p", UseSQLAPI: true}
+	_, err := SnowflakeDriver{}.openWithClient(context.Background(), cfg, nil, nil)
+	if driverErr, ok := err.(*SnowflakeError); !ok || driverErr.Number != ErrCodeSQLAPINotSupported {
+		t.Fatalf("the password authenticator should not be supported. err: %v", err)
+	}
+}
+
+func TestSQLAPICancel(t *testing.T) {
+	origInterval := queryPollInterval
+	queryPollInterval = time.Millisecond
+	defer func() { queryPollInterval = origInterval }()
+
+	canceled := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST" && r.URL.Path == sqlAPIStatementsPath:
+			w.WriteHeader(http.StatusAccepted)
+			io.WriteString(w, `{"code":"333334","statementHandle":"h1","statementStatusUrl":"/api/v2/statements/h1"}`)
+		case r.Method == "GET" && r.URL.Path == sqlAPIStatementsPath+"/h1":
+			w.WriteHeader(http.StatusAccepted)
+			io.WriteString(w, `{"code":"333334","statementHandle":"h1","statementStatusUrl":"/api/v2/statements/h1"}`)
+		case r.Method == "POST" && r.URL.Path == sqlAPIStatementsPath+"/h1/cancel":
+			canceled <- r.URL.Path
+			io.WriteString(w, `{"code":"000604","statementHandle":"h1"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{
+		Account:       "a",
+		Host:          u.Hostname(),
+		Port:          port,
+		Protocol:      "http",
+		Authenticator: AuthTypeOAuth,
+		Token:         "tok",
+		UseSQLAPI:     true,
+	}
+	conn, err := SnowflakeDriver{}.openWithClient(context.Background(), cfg, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to open. err: %v", err)
+	}
+	sc := conn.(*snowflakeConn)
+	defer sc.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err = sc.QueryContext(ctx, "CALL long_running_procedure()", nil); err == nil {
+		t.Fatal("the query should have been canceled")
+	}
+	select {
+	case <-canceled:
+	default:
+		t.Fatal("the statement should have been canceled")
+	}
+	if n := len(sc.sqlAPI.handles); n != 0 {
+		t.Fatalf("the handle should be released after the cancel. handles: %v", sc.sqlAPI.handles)
+	}
+}
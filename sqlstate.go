@@ -15,4 +15,6 @@ const (
 	SQLStateConnectionFailure = "08006"
 	// SQLStateFeatureNotSupported is a SQL State code indicating the feature is not enabled.
 	SQLStateFeatureNotSupported = "0A000"
+	// SQLStateProgramLimitExceeded is a SQL State code indicating a limit, e.g., the size of a request, is exceeded.
+	SQLStateProgramLimitExceeded = "54000"
 )
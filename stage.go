@@ -0,0 +1,114 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// stageLastModifiedLayout is the layout of the last_modified column of LIST, e.g., Tue, 1 Sep 2020 10:11:12 GMT.
+const stageLastModifiedLayout = "Mon, 2 Jan 2006 15:04:05 MST"
+
+// StageFile is a file in a stage, a row of LIST.
+type StageFile struct {
+	Name         string // path of the file starting with the lower case stage name, e.g., orders_stage/2020/a.csv.gz
+	Size         int64  // bytes, after the compression and the encryption
+	MD5          string // hex MD5 of the staged file, which is not that of the local file for an internal stage
+	LastModified time.Time
+}
+
+// RemovedStageFile is a file removed from a stage, a row of REMOVE.
+type RemovedStageFile struct {
+	Name   string
+	Result string // "removed"
+}
+
+// ListStage lists the files in the stage location, e.g., @orders_stage/2020, @%orders for a table stage or @~ for
+// the user stage. pattern is the regular expression the paths of the files must match, as PATTERN of LIST, or
+// empty for every file.
+func (c *Client) ListStage(ctx context.Context, stage string, pattern string) ([]StageFile, error) {
+	columns, rows, err := c.queryStage(ctx, "LIST", stage, pattern)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]StageFile, len(rows))
+	for i, row := range rows {
+		file := &files[i]
+		file.Name = columns.text(row, "name")
+		file.MD5 = columns.text(row, "md5")
+		if s := columns.text(row, "size"); s != "" {
+			if file.Size, err = strconv.ParseInt(s, 10, 64); err != nil {
+				return nil, fmt.Errorf("invalid size of %v: %v", file.Name, s)
+			}
+		}
+		if s := columns.text(row, "last_modified"); s != "" {
+			if file.LastModified, err = time.Parse(stageLastModifiedLayout, s); err != nil {
+				return nil, fmt.Errorf("invalid last modified time of %v: %v", file.Name, s)
+			}
+		}
+	}
+	return files, nil
+}
+
+// RemoveStageFiles removes the files in the stage location that match the pattern, as PATTERN of REMOVE, or
+// every file in it if the pattern is empty, and returns the removed files.
+func (c *Client) RemoveStageFiles(ctx context.Context, stage string, pattern string) ([]RemovedStageFile, error) {
+	columns, rows, err := c.queryStage(ctx, "REMOVE", stage, pattern)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]RemovedStageFile, len(rows))
+	for i, row := range rows {
+		files[i] = RemovedStageFile{Name: columns.text(row, "name"), Result: columns.text(row, "result")}
+	}
+	return files, nil
+}
+
+// stageColumns are the indexes of the columns of LIST and REMOVE by name, so that a column added by a new
+// Snowflake version doesn't break the parsing.
+type stageColumns map[string]int
+
+func (cols stageColumns) text(row []driver.Value, name string) string {
+	idx, ok := cols[name]
+	if !ok || row[idx] == nil {
+		return ""
+	}
+	return fmt.Sprint(row[idx])
+}
+
+// queryStage runs LIST or REMOVE on the stage location, which is not escaped, with the pattern.
+func (c *Client) queryStage(ctx context.Context, command string, stage string, pattern string) (
+	stageColumns, [][]driver.Value, error) {
+	if !strings.HasPrefix(stage, "@") {
+		stage = "@" + stage
+	}
+	query := command + " " + stage
+	if pattern != "" {
+		query += " PATTERN = '" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(pattern) + "'"
+	}
+	rows, err := c.sc.QueryContext(ctx, query, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	columns := make(stageColumns)
+	for i, name := range rows.Columns() {
+		columns[strings.ToLower(name)] = i
+	}
+	var ret [][]driver.Value
+	for {
+		row := make([]driver.Value, len(rows.Columns()))
+		if err = rows.Next(row); err == io.EOF {
+			return columns, ret, nil
+		} else if err != nil {
+			return nil, nil, err
+		}
+		ret = append(ret, row)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestStageFiles(t *testing.T) {
+	var sqlText string
+	text := func(ss ...string) []*string {
+		row := make([]*string, len(ss))
+		for i := range ss {
+			row[i] = &ss[i]
+		}
+		return row
+	}
+	c := &Client{sc: &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{
+			FuncPostQuery: func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte, _ time.Duration, _ *uuid.UUID) (*execResponse, error) {
+				var req execRequest
+				if err := json.Unmarshal(body, &req); err != nil {
+					return nil, err
+				}
+				sqlText = req.SQLText
+				data := execResponseData{QueryID: "qid", QueryResultFormat: jsonFormat}
+				if req.SQLText[0] == 'L' {
+					// a column unknown to StageFile, and the columns in another order
+					data.RowType = []execResponseRowType{{Name: "size", Type: "fixed"}, {Name: "name", Type: "text"},
+						{Name: "md5", Type: "text"}, {Name: "last_modified", Type: "text"}, {Name: "future", Type: "text"}}
+					data.RowSet = [][]*string{
+						text("1234", "orders_stage/2020/a.csv.gz", "0f343b0931126a20f133d67c2b018a3b", "Tue, 1 Sep 2020 10:11:12 GMT", "x"),
+						{nil, text("orders_stage/2020/b.csv.gz")[0], nil, nil, nil},
+					}
+				} else {
+					data.RowType = []execResponseRowType{{Name: "name", Type: "text"}, {Name: "result", Type: "text"}}
+					data.RowSet = [][]*string{text("orders_stage/2020/a.csv.gz", "removed")}
+				}
+				data.Total = int64(len(data.RowSet))
+				return &execResponse{Data: data, Code: "0", Success: true}, nil
+			},
+		},
+	}}
+
+	files, err := c.ListStage(context.Background(), "orders_stage/2020", `.*\.csv\.gz|o'brien`)
+	if err != nil {
+		t.Fatalf("failed to list. err: %v", err)
+	}
+	if sqlText != `LIST @orders_stage/2020 PATTERN = '.*\\.csv\\.gz|o\'brien'` {
+		t.Fatalf("unexpected statement: %v", sqlText)
+	}
+	if len(files) != 2 {
+		t.Fatalf("unexpected files: %+v", files)
+	}
+	expected := StageFile{
+		Name:         "orders_stage/2020/a.csv.gz",
+		Size:         1234,
+		MD5:          "0f343b0931126a20f133d67c2b018a3b",
+		LastModified: time.Date(2020, 9, 1, 10, 11, 12, 0, time.UTC),
+	}
+	if f := files[0]; f.Name != expected.Name || f.Size != expected.Size || f.MD5 != expected.MD5 ||
+		!f.LastModified.Equal(expected.LastModified) {
+		t.Fatalf("unexpected file: %+v", f)
+	}
+	if f := files[1]; f.Name != "orders_stage/2020/b.csv.gz" || f.Size != 0 || !f.LastModified.IsZero() {
+		t.Fatalf("unexpected file: %+v", f)
+	}
+
+	removed, err := c.RemoveStageFiles(context.Background(), "@~", "")
+	if err != nil {
+		t.Fatalf("failed to remove. err: %v", err)
+	}
+	if sqlText != "REMOVE @~" {
+		t.Fatalf("unexpected statement: %v", sqlText)
+	}
+	if len(removed) != 1 || removed[0] != (RemovedStageFile{Name: "orders_stage/2020/a.csv.gz", Result: "removed"}) {
+		t.Fatalf("unexpected removed files: %+v", removed)
+	}
+}
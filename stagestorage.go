@@ -0,0 +1,514 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The storages of the stages. The response of PUT and GET has the location of the stage in the storage, e.g., an S3
+// bucket and a path, and the temporary credentials to access it, so that the files are transferred directly between
+// the driver and the storage. A file is stored with the digest of its content, and with the metadata of its
+// encryption if the stage is client-side encrypted, in the metadata of the object, as the other Snowflake clients
+// do.
+
+const (
+	// azureStorageVersion is the version of the Azure Blob Storage API, the first one uploading files up to 5000
+	// MiB with a single request.
+	azureStorageVersion = "2019-12-12"
+	// blobEncryptionAlgorithm is the algorithm of the encryptiondata metadata of Azure and GCS.
+	blobEncryptionAlgorithm = "AES_CBC_256"
+)
+
+// stageFileMeta is the metadata stored with a file in a stage.
+type stageFileMeta struct {
+	digest     string              // base64 encoded SHA-256 of the content before it is encrypted
+	encryption *encryptionMetadata // nil if the file is not encrypted
+}
+
+// stageStorage is the storage of a stage, where the files are named relative to the location of the stage.
+type stageStorage interface {
+	// head returns the metadata of the file, or nil if the stage has no such file.
+	head(ctx context.Context, name string) (*stageFileMeta, error)
+	// upload stores the content of size bytes as the file, with the metadata.
+	upload(ctx context.Context, name string, content io.Reader, size int64, meta *stageFileMeta) error
+	// download returns the content of the file as it is stored, and its metadata.
+	download(ctx context.Context, name string) (io.ReadCloser, *stageFileMeta, error)
+}
+
+// newStageStorage returns the storage of the stage of a PUT or GET response.
+func newStageStorage(client *http.Client, info *execResponseStageInfo) (stageStorage, error) {
+	switch strings.ToUpper(info.LocationType) {
+	case "LOCAL_FS":
+		dir := info.Location
+		if strings.HasPrefix(dir, "~") {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, err
+			}
+			dir = home + dir[1:]
+		}
+		return localStorage{dir: dir}, nil
+	case "S3", "AZURE", "GCS":
+		s := &cloudStorage{
+			client:        client,
+			info:          info,
+			locationType:  strings.ToUpper(info.LocationType),
+			bucket:        info.Location,
+			presignedURLs: make(map[string]string),
+		}
+		if idx := strings.IndexByte(s.bucket, '/'); idx >= 0 {
+			s.bucket, s.prefix = s.bucket[:idx], s.bucket[idx+1:]
+		}
+		if s.prefix != "" && !strings.HasSuffix(s.prefix, "/") {
+			s.prefix += "/"
+		}
+		return s, nil
+	}
+	return nil, fmt.Errorf("unsupported location type of the stage: %v", info.LocationType)
+}
+
+// fileTransferData runs the PUT or GET command, which only returns the stage of the files rather than transferring
+// them, and returns its response data. expected is the command of the response, UPLOAD or DOWNLOAD.
+func (sc *snowflakeConn) fileTransferData(ctx context.Context, command string, expected string) (
+	*execResponseData, error) {
+	// the command is run on behalf of the driver, so it doesn't take the idempotency key of the context
+	data, err := sc.exec(ctx, command, false, true, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(data.Data.Command, expected) || data.Data.StageInfo == nil {
+		return nil, fmt.Errorf("unexpected response of %v: %v", command, data.Data.Command)
+	}
+	return &data.Data, nil
+}
+
+// fileKeyWrapper returns the FileKeyWrapper of the Config, or the one wrapping the file keys with the master key of
+// the stage.
+func (sc *snowflakeConn) fileKeyWrapper() FileKeyWrapper {
+	if sc.cfg.FileKeyWrapper != nil {
+		return sc.cfg.FileKeyWrapper
+	}
+	return masterKeyWrapper{}
+}
+
+// quoteFileTransferPath returns the path of PUT or GET in single quotes.
+func quoteFileTransferPath(path string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(path) + "'"
+}
+
+// localStorage is a stage in a directory of the local file system, e.g., that of the tests of Snowflake. The digest
+// of a file is computed from its content rather than stored.
+type localStorage struct {
+	dir string
+}
+
+func (s localStorage) head(_ context.Context, name string) (*stageFileMeta, error) {
+	f, err := os.Open(filepath.Join(s.dir, name))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return &stageFileMeta{digest: base64.StdEncoding.EncodeToString(h.Sum(nil))}, nil
+}
+
+func (s localStorage) upload(_ context.Context, name string, content io.Reader, _ int64, _ *stageFileMeta) error {
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return err
+	}
+	// the file is written to a temporary file first, so that a failed upload doesn't leave a partial file
+	f, err := ioutil.TempFile(s.dir, "."+name+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, content)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filepath.Join(s.dir, name))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+func (s localStorage) download(_ context.Context, name string) (io.ReadCloser, *stageFileMeta, error) {
+	f, err := os.Open(filepath.Join(s.dir, name))
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, &stageFileMeta{}, nil
+}
+
+// cloudStorage is the bucket of S3 or GCS, or the container of Azure, of a stage.
+type cloudStorage struct {
+	client       *http.Client
+	info         *execResponseStageInfo
+	locationType string // S3, AZURE or GCS
+	bucket       string
+	prefix       string // path of the stage in the bucket, ending with a slash unless it is empty
+	// presignedURLs are the URLs of the files of a GCS stage that has no access token, by name
+	presignedURLs map[string]string
+}
+
+// needsPresignedURLs returns true if the files are transferred with a presigned URL each, which only the response
+// of the PUT or GET of the file has.
+func (s *cloudStorage) needsPresignedURLs() bool {
+	return s.locationType == "GCS" && s.info.Creds.GcsAccessToken == ""
+}
+
+// objectURL returns the URL of the file. An endpoint with a scheme, e.g., that of a test server, is the base URL
+// of the paths of the buckets.
+func (s *cloudStorage) objectURL(name string) string {
+	key := uriEncodePath(s.prefix + name)
+	if strings.Contains(s.info.EndPoint, "://") {
+		return strings.TrimSuffix(s.info.EndPoint, "/") + "/" + uriEncodePath(s.bucket) + "/" + key
+	}
+	switch s.locationType {
+	case "S3":
+		host := "s3." + s.region() + ".amazonaws.com"
+		if s.info.EndPoint != "" {
+			host = s.info.EndPoint
+		}
+		return "https://" + s.bucket + "." + host + "/" + key
+	case "AZURE":
+		host := "blob.core.windows.net"
+		if s.info.EndPoint != "" {
+			host = s.info.EndPoint
+		}
+		return "https://" + s.info.StorageAccount + "." + host + "/" + uriEncodePath(s.bucket) + "/" + key
+	default:
+		host := "storage.googleapis.com"
+		if s.info.EndPoint != "" {
+			host = s.info.EndPoint
+		}
+		return "https://" + host + "/" + uriEncodePath(s.bucket) + "/" + key
+	}
+}
+
+func (s *cloudStorage) region() string {
+	if s.info.Region == "" {
+		return "us-east-1"
+	}
+	return s.info.Region
+}
+
+// do sends the request of the method for the file, authorized with the credentials of the stage, or with the
+// presigned URL of the file.
+func (s *cloudStorage) do(ctx context.Context, method string, name string, header http.Header, body io.Reader,
+	size int64) (*http.Response, error) {
+	u, presigned := s.presignedURLs[name]
+	if !presigned {
+		u = s.objectURL(name)
+	}
+	if size == 0 {
+		// an empty body is not sent in chunks
+		body = http.NoBody
+	}
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = size
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if !presigned {
+		switch s.locationType {
+		case "S3":
+			signAWSV4(req, &s.info.Creds, s.region(), time.Now())
+		case "AZURE":
+			req.Header.Set("x-ms-version", azureStorageVersion)
+			sas := strings.TrimPrefix(s.info.Creds.AzureSasToken, "?")
+			if req.URL.RawQuery != "" && sas != "" {
+				sas = "&" + sas
+			}
+			req.URL.RawQuery += sas
+		case "GCS":
+			req.Header.Set("Authorization", "Bearer "+s.info.Creds.GcsAccessToken)
+		}
+	}
+	return s.client.Do(req.WithContext(ctx))
+}
+
+func (s *cloudStorage) head(ctx context.Context, name string) (*stageFileMeta, error) {
+	if _, ok := s.presignedURLs[name]; ok {
+		// a presigned URL of PUT is valid for the upload only, so the file is uploaded without checking its digest
+		return nil, nil
+	}
+	resp, err := s.do(ctx, http.MethodHead, name, nil, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer drainAndClose(resp.Body)
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return s.parseMeta(resp.Header)
+	case http.StatusNotFound:
+		return nil, nil
+	}
+	return nil, &SnowflakeError{
+		Number:      ErrFailedToUploadFile,
+		SQLState:    SQLStateConnectionFailure,
+		Message:     errMsgFailedToUploadFile,
+		MessageArgs: []interface{}{name, resp.StatusCode},
+	}
+}
+
+func (s *cloudStorage) upload(ctx context.Context, name string, content io.Reader, size int64,
+	meta *stageFileMeta) error {
+	header, err := s.metaHeader(meta)
+	if err != nil {
+		return err
+	}
+	if s.locationType == "AZURE" {
+		header.Set("x-ms-blob-type", "BlockBlob")
+	}
+	resp, err := s.do(ctx, http.MethodPut, name, header, content, size)
+	if err != nil {
+		return err
+	}
+	defer drainAndClose(resp.Body)
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return &SnowflakeError{
+			Number:      ErrFailedToUploadFile,
+			SQLState:    SQLStateConnectionFailure,
+			Message:     errMsgFailedToUploadFile,
+			MessageArgs: []interface{}{name, resp.StatusCode},
+		}
+	}
+	return nil
+}
+
+func (s *cloudStorage) download(ctx context.Context, name string) (io.ReadCloser, *stageFileMeta, error) {
+	resp, err := s.do(ctx, http.MethodGet, name, nil, nil, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		drainAndClose(resp.Body)
+		return nil, nil, &SnowflakeError{
+			Number:      ErrFailedToDownloadFile,
+			SQLState:    SQLStateConnectionFailure,
+			Message:     errMsgFailedToDownloadFile,
+			MessageArgs: []interface{}{name, resp.StatusCode},
+		}
+	}
+	meta, err := s.parseMeta(resp.Header)
+	if err != nil {
+		drainAndClose(resp.Body)
+		return nil, nil, err
+	}
+	return resp.Body, meta, nil
+}
+
+// metaHeaderPrefix returns the prefix of the headers of the metadata of the objects.
+func (s *cloudStorage) metaHeaderPrefix() string {
+	switch s.locationType {
+	case "S3":
+		return "x-amz-meta-"
+	case "AZURE":
+		return "x-ms-meta-"
+	default:
+		return "x-goog-meta-"
+	}
+}
+
+// blobEncryptionData is the encryptiondata metadata of the encrypted files of Azure and GCS.
+type blobEncryptionData struct {
+	EncryptionMode    string `json:"EncryptionMode"`
+	WrappedContentKey struct {
+		KeyID        string `json:"KeyId"`
+		EncryptedKey string `json:"EncryptedKey"`
+		Algorithm    string `json:"Algorithm"`
+	} `json:"WrappedContentKey"`
+	EncryptionAgent struct {
+		Protocol            string `json:"Protocol"`
+		EncryptionAlgorithm string `json:"EncryptionAlgorithm"`
+	} `json:"EncryptionAgent"`
+	ContentEncryptionIV string `json:"ContentEncryptionIV"`
+	KeyWrappingMetadata struct {
+		EncryptionLibrary string `json:"EncryptionLibrary"`
+	} `json:"KeyWrappingMetadata"`
+}
+
+// metaHeader returns the headers storing the metadata with the file. The digest is sfc-digest, but sfcdigest on
+// Azure, whose metadata names are C# identifiers.
+func (s *cloudStorage) metaHeader(meta *stageFileMeta) (http.Header, error) {
+	prefix := s.metaHeaderPrefix()
+	header := make(http.Header)
+	if s.locationType == "AZURE" {
+		header.Set(prefix+"sfcdigest", meta.digest)
+	} else {
+		header.Set(prefix+"sfc-digest", meta.digest)
+	}
+	enc := meta.encryption
+	if enc == nil {
+		return header, nil
+	}
+	if s.locationType == "S3" {
+		header.Set(prefix+"x-amz-key", enc.key)
+		header.Set(prefix+"x-amz-iv", enc.iv)
+		header.Set(prefix+"x-amz-matdesc", enc.matdesc)
+		return header, nil
+	}
+	var data blobEncryptionData
+	data.EncryptionMode = "FullBlob"
+	data.WrappedContentKey.KeyID = "symmKey1"
+	data.WrappedContentKey.EncryptedKey = enc.key
+	data.WrappedContentKey.Algorithm = blobEncryptionAlgorithm
+	data.EncryptionAgent.Protocol = "1.0"
+	data.EncryptionAgent.EncryptionAlgorithm = blobEncryptionAlgorithm
+	data.ContentEncryptionIV = enc.iv
+	data.KeyWrappingMetadata.EncryptionLibrary = "Java 5.3.0"
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	header.Set(prefix+"encryptiondata", string(b))
+	header.Set(prefix+"matdesc", enc.matdesc)
+	return header, nil
+}
+
+// parseMeta returns the metadata of the file stored in the headers.
+func (s *cloudStorage) parseMeta(header http.Header) (*stageFileMeta, error) {
+	prefix := s.metaHeaderPrefix()
+	meta := &stageFileMeta{digest: header.Get(prefix + "sfc-digest")}
+	if s.locationType == "AZURE" {
+		meta.digest = header.Get(prefix + "sfcdigest")
+	}
+	if s.locationType == "S3" {
+		if key := header.Get(prefix + "x-amz-key"); key != "" {
+			meta.encryption = &encryptionMetadata{
+				key:     key,
+				iv:      header.Get(prefix + "x-amz-iv"),
+				matdesc: header.Get(prefix + "x-amz-matdesc"),
+			}
+		}
+		return meta, nil
+	}
+	if b := header.Get(prefix + "encryptiondata"); b != "" {
+		var data blobEncryptionData
+		if err := json.Unmarshal([]byte(b), &data); err != nil {
+			return nil, fmt.Errorf("invalid encryption data of the file: %v", err)
+		}
+		meta.encryption = &encryptionMetadata{
+			key:     data.WrappedContentKey.EncryptedKey,
+			iv:      data.ContentEncryptionIV,
+			matdesc: header.Get(prefix + "matdesc"),
+		}
+	}
+	return meta, nil
+}
+
+// uriEncodePath encodes every byte of the path but the unreserved characters of RFC 3986 and the slashes, which is
+// the canonical URI of the Signature Version 4 of AWS, and a valid path of the other storages.
+func uriEncodePath(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-._~/", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// signAWSV4 signs the request to S3 with the Signature Version 4 of AWS. Every header of the request is signed, and
+// the payload is not unless the request has its SHA-256 in X-Amz-Content-Sha256.
+func signAWSV4(req *http.Request, creds *execResponseCredentials, region string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if req.Header.Get("X-Amz-Content-Sha256") == "" {
+		req.Header.Set("X-Amz-Content-Sha256", "UNSIGNED-PAYLOAD")
+	}
+	if creds.AwsToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.AwsToken)
+	}
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		trimmed := make([]string, len(values))
+		for i, v := range values {
+			trimmed[i] = strings.Join(strings.Fields(v), " ")
+		}
+		headers[strings.ToLower(name)] = strings.Join(trimmed, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	query := req.URL.Query()
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var canonicalQuery []string
+	for _, k := range keys {
+		values := query[k]
+		sort.Strings(values)
+		for _, v := range values {
+			canonicalQuery = append(canonicalQuery, uriEncodePath(k)+"="+strings.ReplaceAll(uriEncodePath(v), "/", "%2F"))
+		}
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		strings.Join(canonicalQuery, "&"),
+		canonicalHeaders.String(),
+		signedHeaders,
+		req.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+	scope := amzDate[:8] + "/" + region + "/s3/aws4_request"
+	digest := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(digest[:])
+	key := []byte("AWS4" + creds.AwsSecretKey)
+	for _, part := range []string{amzDate[:8], region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+creds.AwsKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+hex.EncodeToString(hmacSHA256(key, stringToSign)))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -5,6 +5,11 @@ package gosnowflake
 import (
 	"context"
 	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
 )
 
 type paramKey string
@@ -17,6 +22,7 @@ const (
 type snowflakeStmt struct {
 	sc    *snowflakeConn
 	query string
+	desc  *statementDescription // nil unless Config.DescribeCacheSize is set
 }
 
 func (stmt *snowflakeStmt) Close() error {
@@ -27,7 +33,10 @@ func (stmt *snowflakeStmt) Close() error {
 
 func (stmt *snowflakeStmt) NumInput() int {
 	glog.V(2).Infoln("Stmt.NumInput")
-	// Go Snowflake doesn't know the number of binding parameters.
+	if stmt.desc != nil {
+		return stmt.desc.numberOfBinds
+	}
+	// Go Snowflake doesn't know the number of binding parameters unless the statement is described.
 	return -1
 }
 
@@ -51,6 +60,235 @@ func (stmt *snowflakeStmt) Query(args []driver.Value) (driver.Rows, error) {
 	return stmt.sc.Query(stmt.query, args)
 }
 
+// WithFetchResultByID returns a context that makes QueryContext fetch the result of the query
+// identified by queryID instead of executing the given query text. The query must have completed.
+func WithFetchResultByID(ctx context.Context, queryID string) context.Context {
+	return context.WithValue(ctx, fetchResultByID, queryID)
+}
+
+// WithMaxResultRows returns a context that limits the number of rows read from the results of the queries
+// run with it. Reading beyond n rows returns an error with the code ErrResultTruncated and stops downloading
+// the rest of the result.
+func WithMaxResultRows(ctx context.Context, n int64) context.Context {
+	return context.WithValue(ctx, maxResultRows, n)
+}
+
+// WithMaxResultBytes returns a context that limits the number of bytes downloaded for the result chunks of
+// the queries run with it. Once more than n bytes are downloaded, reading the next chunk returns an error with
+// the code ErrResultTruncated and no further chunk is downloaded.
+func WithMaxResultBytes(ctx context.Context, n int64) context.Context {
+	return context.WithValue(ctx, maxResultBytes, n)
+}
+
+// SkippedChunk describes a result chunk that was skipped because it failed to be downloaded or decoded.
+type SkippedChunk struct {
+	QueryID string
+	Index   int   // zero based index of the chunk among the chunks to download
+	Rows    int   // number of rows in the chunk that are not returned
+	Err     error // the last error of the chunk
+}
+
+// SkippedChunkFunc is called when a result chunk is skipped.
+type SkippedChunkFunc func(SkippedChunk)
+
+// WithSkipBadChunks returns a context that skips the result chunks of the queries run with it that fail to
+// be downloaded or decoded after the retries, instead of failing the iteration of the rows. fn is called for
+// every skipped chunk, and the iteration continues with the next chunk. This is meant for best-effort exports,
+// where a partial result is better than none.
+func WithSkipBadChunks(ctx context.Context, fn SkippedChunkFunc) context.Context {
+	return context.WithValue(ctx, skipBadChunks, fn)
+}
+
+// WithRawResults returns a context that returns the values of the results of the queries run with it as they are
+// received, without converting them to Go types. The queries request the results in the JSON format, so that
+// every value is the string on the wire, or nil for NULL. A result in the Arrow format, e.g., fetched by
+// WithFetchResultByID, returns the Arrow scalars: integers, float64, bool, string, []byte, decimal128.Num for
+// NUMBER, int32 days for DATE, and []interface{} of the fields for the structs of timestamps.
+func WithRawResults(ctx context.Context) context.Context {
+	return context.WithValue(ctx, rawResults, true)
+}
+
+func isRawResults(ctx context.Context) bool {
+	raw, _ := ctx.Value(rawResults).(bool)
+	return raw
+}
+
+// TimeFormat is the Go type of the TIME values of the results. The values keep the nanosecond precision of
+// TIME(9) in every format.
+type TimeFormat int
+
+const (
+	// TimeFormatZeroDate returns TIME values as time.Time on January 1, year 1, UTC. This is the default.
+	TimeFormatZeroDate TimeFormat = iota
+	// TimeFormatEpochDate returns TIME values as time.Time on January 1, 1970, UTC.
+	TimeFormatEpochDate
+	// TimeFormatDuration returns TIME values as time.Duration since midnight.
+	TimeFormatDuration
+)
+
+// WithTimeFormat returns a context that returns the TIME values of the results of the queries run with it in
+// the format, in both the JSON and Arrow result formats.
+func WithTimeFormat(ctx context.Context, format TimeFormat) context.Context {
+	return context.WithValue(ctx, timeFormat, format)
+}
+
+// formatTime converts a TIME value on the zero date to the format.
+func formatTime(v driver.Value, format TimeFormat) driver.Value {
+	t, ok := v.(time.Time)
+	if !ok {
+		return v
+	}
+	d := t.Sub(time.Time{})
+	switch format {
+	case TimeFormatEpochDate:
+		return time.Unix(0, int64(d)).UTC()
+	case TimeFormatDuration:
+		return d
+	}
+	return v
+}
+
+// allowedHTTPHeaderPrefixes are the canonical name prefixes of the HTTP headers allowed by WithHTTPHeaders. The
+// headers used by the driver and Snowflake, e.g., Authorization and X-Snowflake-Service, are never allowed.
+var allowedHTTPHeaderPrefixes = []string{
+	"X-Correlation-",
+	"X-Request-",
+	"X-Tenant-",
+	"X-Trace-",
+	"X-Audit-",
+	"Traceparent",
+	"Tracestate",
+}
+
+// maxHTTPHeaderValueLength is the maximum length of the value of a header of WithHTTPHeaders.
+const maxHTTPHeaderValueLength = 1024
+
+// WithHTTPHeaders returns a context that attaches the extra HTTP headers, e.g., correlation IDs and tenant tags for
+// audit trails, to the query requests run with it. Only the headers whose names start with X-Correlation-,
+// X-Request-, X-Tenant-, X-Trace- or X-Audit-, and the Traceparent and Tracestate headers are allowed. The values
+// must be printable ASCII of up to 1024 characters. Otherwise, an error with the code ErrCodeInvalidHTTPHeader
+// is returned.
+func WithHTTPHeaders(ctx context.Context, headers map[string]string) (context.Context, error) {
+	h := make(map[string]string, len(headers))
+	for name, value := range headers {
+		canonical := http.CanonicalHeaderKey(name)
+		if !isAllowedHTTPHeader(canonical) {
+			return ctx, invalidHTTPHeaderError(name, "not allowed")
+		}
+		if len(value) > maxHTTPHeaderValueLength {
+			return ctx, invalidHTTPHeaderError(name, "too long")
+		}
+		for i := 0; i < len(value); i++ {
+			if value[i] < ' ' || value[i] > '~' {
+				return ctx, invalidHTTPHeaderError(name, "invalid character")
+			}
+		}
+		h[canonical] = value
+	}
+	return context.WithValue(ctx, httpHeaders, h), nil
+}
+
+func isAllowedHTTPHeader(canonical string) bool {
+	for _, prefix := range allowedHTTPHeaderPrefixes {
+		if strings.HasPrefix(canonical, prefix) && (canonical == prefix || strings.HasSuffix(prefix, "-")) {
+			return true
+		}
+	}
+	return false
+}
+
+func invalidHTTPHeaderError(name string, reason string) *SnowflakeError {
+	return &SnowflakeError{
+		Number:      ErrCodeInvalidHTTPHeader,
+		Message:     errMsgInvalidHTTPHeader,
+		MessageArgs: []interface{}{name, reason},
+	}
+}
+
+// maxQueryTagLength is the maximum length of QUERY_TAG.
+const maxQueryTagLength = 2000
+
+// WithQueryTag returns a context that sets QUERY_TAG of the queries run with it, instead of the QUERY_TAG of the
+// session, so that the queries can be attributed, e.g., to a team or a job for chargeback in QUERY_HISTORY.
+func WithQueryTag(ctx context.Context, tag string) context.Context {
+	return context.WithValue(ctx, queryTagKey, tag)
+}
+
+// WithQueryLabels returns a context that sets QUERY_TAG of the queries run with it to the labels as a JSON object,
+// e.g., {"job":"nightly","team":"billing"}, which can be parsed by PARSE_JSON(QUERY_TAG) in QUERY_HISTORY. The
+// tag of WithQueryTag, if any, is added as the label "tag". The labels are merged with those of the parent
+// context, overriding the labels of the same names.
+func WithQueryLabels(ctx context.Context, labels map[string]string) context.Context {
+	merged := make(map[string]string, len(labels))
+	if parent, ok := ctx.Value(queryLabels).(map[string]string); ok {
+		for k, v := range parent {
+			merged[k] = v
+		}
+	}
+	for k, v := range labels {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, queryLabels, merged)
+}
+
+// queryTag returns the QUERY_TAG set by WithQueryTag and WithQueryLabels, or an empty string if not set. An
+// error with the code ErrCodeInvalidQueryTag is returned if it is longer than Snowflake allows.
+func queryTag(ctx context.Context) (string, error) {
+	tag, _ := ctx.Value(queryTagKey).(string)
+	if labels, ok := ctx.Value(queryLabels).(map[string]string); ok && len(labels) > 0 {
+		if _, ok := labels["tag"]; !ok && tag != "" {
+			labels = WithQueryLabels(ctx, map[string]string{"tag": tag}).Value(queryLabels).(map[string]string)
+		}
+		b, err := json.Marshal(labels)
+		if err != nil {
+			return "", err
+		}
+		tag = string(b)
+	}
+	if len(tag) > maxQueryTagLength {
+		return "", &SnowflakeError{
+			Number:      ErrCodeInvalidQueryTag,
+			Message:     errMsgInvalidQueryTag,
+			MessageArgs: []interface{}{len(tag), maxQueryTagLength},
+		}
+	}
+	return tag, nil
+}
+
+// WithQueryIDChan returns a context that sends the query ID of every query run with it to the channel as soon as
+// Snowflake assigns it, i.e., when the query is submitted rather than when it completes, so that a long-running
+// query can be monitored, e.g., by QueryStatus, or canceled by its ID while it runs. The query waits until the ID
+// is received or the context is done, so the channel should be buffered or read concurrently. A query that is
+// re-submitted sends the query ID of every attempt.
+func WithQueryIDChan(ctx context.Context, ch chan<- string) context.Context {
+	return context.WithValue(ctx, queryIDChan, &queryIDSender{ch: ch})
+}
+
+// queryIDSender sends the query IDs to the channel of WithQueryIDChan, each once.
+type queryIDSender struct {
+	ch   chan<- string
+	mu   sync.Mutex
+	sent string
+}
+
+// sendQueryID sends the query ID to the channel of WithQueryIDChan, if any, unless it has just been sent.
+func sendQueryID(ctx context.Context, qid string) {
+	s, ok := ctx.Value(queryIDChan).(*queryIDSender)
+	if !ok || qid == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sent == qid {
+		return
+	}
+	select {
+	case s.ch <- qid:
+		s.sent = qid
+	case <-ctx.Done():
+	}
+}
+
 // WithMultiStatement returns a context that allows the user to execute the desired number of sql queries in one query
 func WithMultiStatement(ctx context.Context, num int) (context.Context, error) {
 	return context.WithValue(ctx, MultiStatementCount, num), nil
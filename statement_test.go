@@ -6,7 +6,14 @@ import (
 	"context"
 	"database/sql"
 	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 func TestMultiStatementExecuteNoResultSet(t *testing.T) {
@@ -497,3 +504,97 @@ func TestGetQueryID(t *testing.T) {
 		t.Fatalf("failed to prepare statement. err: %v", err)
 	}
 }
+
+func TestQueryTag(t *testing.T) {
+	var parameters map[string]interface{}
+	sc := &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{
+			FuncPostQuery: func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte, _ time.Duration, _ *uuid.UUID) (*execResponse, error) {
+				var req execRequest
+				if err := json.Unmarshal(body, &req); err != nil {
+					return nil, err
+				}
+				parameters = req.Parameters
+				return &execResponse{Data: execResponseData{QueryID: "q"}, Code: "0", Success: true}, nil
+			},
+		},
+	}
+	ctx := WithQueryLabels(context.Background(), map[string]string{"team": "billing", "job": "daily"})
+	ctx = WithQueryLabels(ctx, map[string]string{"job": "nightly"})
+	testcases := []struct {
+		ctx context.Context
+		tag interface{}
+	}{
+		{context.Background(), nil},
+		{WithQueryTag(context.Background(), "etl"), "etl"},
+		{ctx, `{"job":"nightly","team":"billing"}`},
+		{WithQueryTag(ctx, "etl"), `{"job":"nightly","tag":"etl","team":"billing"}`},
+	}
+	for _, tc := range testcases {
+		parameters = nil
+		if _, err := sc.ExecContext(tc.ctx, "SELECT 1", nil); err != nil {
+			t.Fatalf("failed to exec. err: %v", err)
+		}
+		if tag := parameters["QUERY_TAG"]; tag != tc.tag {
+			t.Errorf("unexpected QUERY_TAG. expected: %v, got: %v", tc.tag, tag)
+		}
+	}
+	if tag, _ := queryTag(ctx); tag != `{"job":"nightly","team":"billing"}` {
+		t.Fatalf("the labels of the parent context should not be changed: %v", tag)
+	}
+
+	_, err := sc.ExecContext(WithQueryTag(context.Background(), strings.Repeat("x", 2001)), "SELECT 1", nil)
+	if driverErr, ok := err.(*SnowflakeError); !ok || driverErr.Number != ErrCodeInvalidQueryTag {
+		t.Fatalf("should fail with ErrCodeInvalidQueryTag. err: %v", err)
+	}
+}
+
+func TestWithQueryIDChan(t *testing.T) {
+	origInterval := queryPollInterval
+	queryPollInterval = time.Millisecond
+	defer func() { queryPollInterval = origInterval }()
+
+	received := make(chan struct{})
+	sc := &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{
+			Token:               "token",
+			FuncPostQuery:       postRestfulQuery,
+			FuncPostQueryHelper: postRestfulQueryHelper,
+			FuncPost: func(_ context.Context, _ *snowflakeRestful, _ *url.URL, _ map[string]string, _ []byte, _ time.Duration, _ bool) (*http.Response, error) {
+				return &http.Response{StatusCode: http.StatusOK, Body: &fakeResponseBody{
+					body: []byte(`{"code":"333333","success":true,"data":{"queryId":"q1","getResultUrl":"/queries/q1/result"}}`)}}, nil
+			},
+			FuncGet: func(ctx context.Context, _ *snowflakeRestful, _ *url.URL, _ map[string]string, _ time.Duration) (*http.Response, error) {
+				select {
+				case <-received:
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				}
+				return &http.Response{StatusCode: http.StatusOK, Body: &fakeResponseBody{
+					body: []byte(`{"code":"0","success":true,"data":{"queryId":"q1"}}`)}}, nil
+			},
+		},
+	}
+	ch := make(chan string)
+	ctx, cancel := context.WithTimeout(WithQueryIDChan(context.Background(), ch), 10*time.Second)
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		_, err := sc.ExecContext(ctx, "CALL long_running()", nil)
+		done <- err
+	}()
+	if qid := <-ch; qid != "q1" {
+		t.Fatalf("unexpected query ID: %v", qid)
+	}
+	close(received)
+	if err := <-done; err != nil {
+		t.Fatalf("failed to execute. err: %v", err)
+	}
+	select {
+	case qid := <-ch:
+		t.Fatalf("the query ID should be sent once. got: %v", qid)
+	default:
+	}
+}
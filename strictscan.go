@@ -0,0 +1,129 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WithStrictScan returns a context whose result sets, returned by QueryResultSet or Client, fail to scan a value
+// into a destination that would lose a part of it, instead of truncating or formatting it: a NUMBER that overflows
+// the integer or has a fraction, a negative NUMBER scanned into an unsigned integer, a NUMBER that the float
+// doesn't represent exactly, a FLOAT that a float32 doesn't represent exactly, or a date or a time scanned
+// into a string. Such a Scan returns a *ConversionError wrapping a SnowflakeError with the code ErrLossyScan.
+//
+// database/sql converts the values into the destinations of Rows.Scan itself, so that the strict mode doesn't apply
+// to it.
+func WithStrictScan(ctx context.Context) context.Context {
+	return context.WithValue(ctx, strictScan, true)
+}
+
+// checkStrictScan returns ErrLossyScan if scanning the value of the column into the destination loses a part of it.
+func checkStrictScan(dest interface{}, v driver.Value, rowType execResponseRowType) error {
+	switch dest.(type) {
+	case *string, *sql.NullString:
+		if _, ok := v.(time.Time); ok {
+			return errLossyScan(dest, fmt.Sprintf("%v is formatted", strings.ToUpper(rowType.Type)))
+		}
+	case *int:
+		return checkStrictInt(dest, v, rowType, strconv.IntSize, true)
+	case *int64, *sql.NullInt64:
+		return checkStrictInt(dest, v, rowType, 64, true)
+	case *int32, *sql.NullInt32:
+		return checkStrictInt(dest, v, rowType, 32, true)
+	case *int16:
+		return checkStrictInt(dest, v, rowType, 16, true)
+	case *int8:
+		return checkStrictInt(dest, v, rowType, 8, true)
+	case *uint:
+		return checkStrictInt(dest, v, rowType, strconv.IntSize, false)
+	case *uint64:
+		return checkStrictInt(dest, v, rowType, 64, false)
+	case *uint32:
+		return checkStrictInt(dest, v, rowType, 32, false)
+	case *uint16:
+		return checkStrictInt(dest, v, rowType, 16, false)
+	case *uint8:
+		return checkStrictInt(dest, v, rowType, 8, false)
+	case *float64, *sql.NullFloat64:
+		if r, ok := fixedRat(v, rowType); ok {
+			if _, exact := r.Float64(); !exact {
+				return errLossyScan(dest, fmt.Sprintf("%v is not exact as a float64", r.RatString()))
+			}
+		}
+	case *float32:
+		if r, ok := fixedRat(v, rowType); ok {
+			if _, exact := r.Float32(); !exact {
+				return errLossyScan(dest, fmt.Sprintf("%v is not exact as a float32", r.RatString()))
+			}
+		} else if f, ok := v.(float64); ok && float64(float32(f)) != f && !math.IsNaN(f) {
+			return errLossyScan(dest, fmt.Sprintf("%v is not exact as a float32", f))
+		}
+	}
+	return nil
+}
+
+// checkStrictInt returns ErrLossyScan if the NUMBER has a fraction or doesn't fit in an integer of the bits, which
+// is signed or not.
+func checkStrictInt(dest interface{}, v driver.Value, rowType execResponseRowType, bits uint, signed bool) error {
+	r, ok := fixedRat(v, rowType)
+	if !ok {
+		return nil
+	}
+	if !r.IsInt() {
+		return errLossyScan(dest, fmt.Sprintf("%v has a fraction", r.FloatString(int(rowType.Scale))))
+	}
+	n := r.Num()
+	if !signed {
+		if n.Sign() < 0 {
+			return errLossyScan(dest, fmt.Sprintf("%v is negative", n))
+		}
+		if n.BitLen() > int(bits) {
+			return errLossyScan(dest, fmt.Sprintf("%v overflows", n))
+		}
+		return nil
+	}
+	limit := new(big.Int).Lsh(big.NewInt(1), bits-1)
+	if n.Cmp(limit) >= 0 || n.Cmp(new(big.Int).Neg(limit)) < 0 {
+		return errLossyScan(dest, fmt.Sprintf("%v overflows", n))
+	}
+	return nil
+}
+
+// fixedRat returns the exact value of a NUMBER, which is a string, an int64, a *big.Int or a *big.Float depending
+// on the result format.
+func fixedRat(v driver.Value, rowType execResponseRowType) (*big.Rat, bool) {
+	if !strings.EqualFold(rowType.Type, "fixed") {
+		return nil, false
+	}
+	switch x := v.(type) {
+	case string:
+		return new(big.Rat).SetString(x)
+	case int64:
+		return new(big.Rat).SetInt64(x), true
+	case *big.Int:
+		return new(big.Rat).SetInt(x), true
+	case *big.Float:
+		r, _ := x.Rat(nil)
+		return r, r != nil
+	}
+	return nil, false
+}
+
+func errLossyScan(dest interface{}, reason string) *SnowflakeError {
+	return &SnowflakeError{
+		Number:      ErrLossyScan,
+		SQLState:    SQLStateNumericValueOutOfRange,
+		Message:     errMsgLossyScan,
+		MessageArgs: []interface{}{reflect.TypeOf(dest).Elem(), reason},
+	}
+}
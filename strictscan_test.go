@@ -0,0 +1,114 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+)
+
+func strictScanTestClient() *fakeQueryClient {
+	return newFakeQueryClient([]execResponseRowType{
+		{Name: "N", Type: "fixed", Precision: 38},
+		{Name: "D", Type: "fixed", Precision: 10, Scale: 2},
+		{Name: "TS", Type: "timestamp_ntz", Scale: 9},
+	}, []string{"12345678901234567", "1.50", "1598955072.123000000"})
+}
+
+func TestStrictScan(t *testing.T) {
+	c := strictScanTestClient()
+	rs, err := c.Query(WithStrictScan(context.Background()), "SELECT n, d, ts FROM t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Close()
+	if !rs.Next() {
+		t.Fatal(rs.Err())
+	}
+	var n int64
+	var f float64
+	var s string
+	var i int
+	if err = rs.Scan(&n, &f, new(interface{})); err != nil || n != 12345678901234567 || f != 1.5 {
+		t.Fatalf("the exact conversions should succeed. n: %v, f: %v, err: %v", n, f, err)
+	}
+	for _, dest := range [][]interface{}{
+		{&f, new(interface{}), new(interface{})},
+		{&n, &i, new(interface{})},
+		{&n, new(interface{}), &s},
+		{&n, new(interface{}), &sql.NullString{}},
+	} {
+		err = rs.Scan(dest...)
+		var ce *ConversionError
+		var se *SnowflakeError
+		if !errors.As(err, &ce) || !errors.As(err, &se) || se.Number != ErrLossyScan {
+			t.Fatalf("the lossy conversion should fail with ErrLossyScan. err: %v", err)
+		}
+		if ce.Row != 0 || ce.Value == "" {
+			t.Fatalf("the error should have the row and the value. err: %v", ce)
+		}
+	}
+
+	rs, err = c.Query(context.Background(), "SELECT n, d, ts FROM t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Close()
+	if !rs.Next() {
+		t.Fatal(rs.Err())
+	}
+	if err = rs.Scan(&f, new(interface{}), &s); err != nil || s == "" {
+		t.Fatalf("the conversions should succeed without the strict mode. err: %v", err)
+	}
+}
+
+func TestCheckStrictScanBits(t *testing.T) {
+	fixed := execResponseRowType{Name: "N", Type: "fixed"}
+	if err := checkStrictScan(&sql.NullInt32{}, "2147483648", fixed); err == nil {
+		t.Error("the number should overflow an int32")
+	}
+	if err := checkStrictScan(&sql.NullInt32{}, "-2147483648", fixed); err != nil {
+		t.Errorf("the smallest int32 should fit. err: %v", err)
+	}
+	if err := checkStrictScan(new(int64), "9223372036854775807", fixed); err != nil {
+		t.Errorf("the largest int64 should fit. err: %v", err)
+	}
+	if err := checkStrictScan(new(float64), "1.5", execResponseRowType{Type: "real"}); err != nil {
+		t.Errorf("a FLOAT should not be checked. err: %v", err)
+	}
+	for _, c := range []struct {
+		dest  interface{}
+		v     string
+		lossy bool
+	}{
+		{new(int32), "2147483647", false},
+		{new(int32), "2147483648", true},
+		{new(int16), "-32768", false},
+		{new(int16), "32768", true},
+		{new(int8), "127", false},
+		{new(int8), "-129", true},
+		{new(uint), "-1", true},
+		{new(uint64), "18446744073709551615", false},
+		{new(uint64), "18446744073709551616", true},
+		{new(uint32), "4294967296", true},
+		{new(uint16), "65535", false},
+		{new(uint16), "-1", true},
+		{new(uint8), "256", true},
+		{new(float32), "0.5", false},
+		{new(float32), "16777217", true},
+	} {
+		err := checkStrictScan(c.dest, c.v, fixed)
+		if lossy := err != nil; lossy != c.lossy {
+			t.Errorf("wrong check of %v into %T. expected lossy: %v, err: %v", c.v, c.dest, c.lossy, err)
+		}
+	}
+	real32 := execResponseRowType{Type: "real"}
+	if err := checkStrictScan(new(float32), 0.1, real32); err == nil {
+		t.Error("0.1 should not be exact as a float32")
+	}
+	if err := checkStrictScan(new(float32), 0.5, real32); err != nil {
+		t.Errorf("0.5 should be exact as a float32. err: %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// newHTTPClient creates the HTTP client sending the requests of the connections with the Config.
+func newHTTPClient(cfg *Config) *http.Client {
+	var st http.RoundTripper = getTransport(cfg)
+	// the wire trace has the requests as they are sent, after they are authorized
+	st = &wireTraceTransport{base: st}
+	if cfg.RequestAuthorizer != nil {
+		st = &authorizerTransport{authorizer: cfg.RequestAuthorizer, base: st}
+	}
+	if cfg.Cassette != nil {
+		// the cassette records the requests as the driver builds them, before they are authorized
+		st = cfg.Cassette.transport(st)
+	}
+	return &http.Client{
+		// request timeout including reading response body
+		Timeout:       defaultClientTimeout,
+		Transport:     st,
+		CheckRedirect: checkRedirect,
+	}
+}
+
+// transportKey is the part of the Config that the transport depends on.
+type transportKey struct {
+	insecureMode        bool
+	maxIdleConns        int
+	maxIdleConnsPerHost int
+	maxConnsPerHost     int
+	idleConnTimeout     time.Duration
+	enableHTTP2         bool
+}
+
+var (
+	// tunedTransports are the transports tuned by getTransport, shared by the connections of the same parameters
+	// so that they share the connection pool.
+	tunedTransports   = make(map[transportKey]*http.Transport)
+	tunedTransportsMu sync.Mutex
+)
+
+// getTransport returns the transport to use for the connection. If any connection pool
+// parameter is given in the Config, a copy of the base transport is tuned so that the
+// shared default transports are never mutated. The tuned transport is cached by the
+// parameters, so that the connections opened with the same ones share it.
+func getTransport(cfg *Config) *http.Transport {
+	st := SnowflakeTransport
+	if cfg.InsecureMode {
+		// no revocation check with OCSP. Think twice when you want to enable this option.
+		st = snowflakeInsecureTransport
+	}
+	if cfg.MaxIdleConns == 0 && cfg.MaxIdleConnsPerHost == 0 && cfg.MaxConnsPerHost == 0 &&
+		cfg.IdleConnTimeout == 0 && !cfg.EnableHTTP2 {
+		return st
+	}
+	key := transportKey{
+		insecureMode:        cfg.InsecureMode,
+		maxIdleConns:        cfg.MaxIdleConns,
+		maxIdleConnsPerHost: cfg.MaxIdleConnsPerHost,
+		maxConnsPerHost:     cfg.MaxConnsPerHost,
+		idleConnTimeout:     cfg.IdleConnTimeout,
+		enableHTTP2:         cfg.EnableHTTP2,
+	}
+	tunedTransportsMu.Lock()
+	defer tunedTransportsMu.Unlock()
+	if tuned, ok := tunedTransports[key]; ok {
+		return tuned
+	}
+	st = st.Clone()
+	if cfg.MaxIdleConns != 0 {
+		st.MaxIdleConns = cfg.MaxIdleConns
+	}
+	if cfg.MaxIdleConnsPerHost != 0 {
+		st.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	}
+	if cfg.MaxConnsPerHost != 0 {
+		st.MaxConnsPerHost = cfg.MaxConnsPerHost
+	}
+	if cfg.IdleConnTimeout != 0 {
+		st.IdleConnTimeout = cfg.IdleConnTimeout
+	}
+	// HTTP/2 is not attempted by default once a custom TLS config or dialer is set on the transport.
+	st.ForceAttemptHTTP2 = cfg.EnableHTTP2
+	glog.V(2).Infof("transport: MaxIdleConns: %v, MaxIdleConnsPerHost: %v, MaxConnsPerHost: %v, IdleConnTimeout: %v, HTTP2: %v",
+		st.MaxIdleConns, st.MaxIdleConnsPerHost, st.MaxConnsPerHost, st.IdleConnTimeout, st.ForceAttemptHTTP2)
+	tunedTransports[key] = st
+	return st
+}
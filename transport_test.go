@@ -0,0 +1,83 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestGetTransport(t *testing.T) {
+	if st := getTransport(&Config{}); st != SnowflakeTransport {
+		t.Fatal("default transport should be shared when no pool parameter is given")
+	}
+	if st := getTransport(&Config{InsecureMode: true}); st != snowflakeInsecureTransport {
+		t.Fatal("insecure transport should be shared when no pool parameter is given")
+	}
+	cfg := &Config{
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 20,
+		MaxConnsPerHost:     50,
+		IdleConnTimeout:     90 * time.Second,
+		EnableHTTP2:         true,
+	}
+	st := getTransport(cfg)
+	if st == SnowflakeTransport {
+		t.Fatal("tuned transport must not be the shared transport")
+	}
+	if st.MaxIdleConns != 100 || st.MaxIdleConnsPerHost != 20 || st.MaxConnsPerHost != 50 ||
+		st.IdleConnTimeout != 90*time.Second || !st.ForceAttemptHTTP2 {
+		t.Fatalf("transport parameters were not applied. %#v", st)
+	}
+	if st.TLSClientConfig == nil || st.TLSClientConfig.VerifyPeerCertificate == nil {
+		t.Fatal("tuned transport should keep the OCSP revocation check")
+	}
+	if SnowflakeTransport.MaxIdleConns != 10 || SnowflakeTransport.ForceAttemptHTTP2 {
+		t.Fatal("shared transport was modified")
+	}
+	same := *cfg
+	same.Account = "other"
+	if getTransport(&same) != st {
+		t.Fatal("the connections with the same pool parameters should share the tuned transport")
+	}
+	same.InsecureMode = true
+	if getTransport(&same) == st {
+		t.Fatal("the insecure connections should have their own tuned transport")
+	}
+	same.InsecureMode = false
+	same.MaxConnsPerHost = 51
+	if getTransport(&same) == st {
+		t.Fatal("the connections with other pool parameters should have their own tuned transport")
+	}
+}
+
+func TestConnectorSharesHTTPClient(t *testing.T) {
+	cfg := Config{Account: "a", User: "u", Password: "p", MaxIdleConnsPerHost: 16}
+	connector := NewConnector(SnowflakeDriver{}, cfg)
+	wt, ok := connector.client.Transport.(*wireTraceTransport)
+	if !ok {
+		t.Fatalf("the shared client should trace the requests: %v", connector.client.Transport)
+	}
+	if st, ok := wt.base.(*http.Transport); !ok || st.MaxIdleConnsPerHost != 16 {
+		t.Fatalf("the shared client should use the tuned transport: %v", connector.client.Transport)
+	}
+	sc1, err := newSnowflakeConn(connector.cfg, true, connector.client)
+	if err != nil {
+		t.Fatalf("failed to create a connection. err: %v", err)
+	}
+	sc2, err := newSnowflakeConn(connector.cfg, true, connector.client)
+	if err != nil {
+		t.Fatalf("failed to create a connection. err: %v", err)
+	}
+	if sc1.rest.Client != connector.client || sc2.rest.Client != connector.client {
+		t.Fatal("the connections of a Connector should share the HTTP client")
+	}
+	sc3, err := newSnowflakeConn(cfg, true, nil)
+	if err != nil {
+		t.Fatalf("failed to create a connection. err: %v", err)
+	}
+	if sc3.rest.Client == connector.client {
+		t.Fatal("a connection opened without a Connector should have its own HTTP client")
+	}
+}
@@ -0,0 +1,221 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// defaultUnloadFileFormat is the file format of the unloaded files if UnloadOptions doesn't set it.
+const defaultUnloadFileFormat = "TYPE = CSV COMPRESSION = GZIP"
+
+// defaultUnloadParallelism is the number of the files downloaded at a time if UnloadOptions doesn't set it.
+const defaultUnloadParallelism = 4
+
+// UnloadOptions controls how Client.ExportQuery unloads a query result.
+type UnloadOptions struct {
+	// FileFormat is the FILE_FORMAT options of COPY INTO, e.g., TYPE = PARQUET. It is
+	// TYPE = CSV COMPRESSION = GZIP by default.
+	FileFormat string
+	// MaxFileSize is the maximum size of an unloaded file in bytes. Snowflake's default applies if zero.
+	MaxFileSize int64
+	// Parallelism is the number of the files downloaded at a time. It is 4 by default.
+	Parallelism int
+}
+
+// ExportDestination opens the writer of an unloaded file by its name, e.g., data_0_0_0.csv.gz. The writer is
+// closed after the file is downloaded.
+type ExportDestination func(name string) (io.WriteCloser, error)
+
+// ExportToDir returns the ExportDestination that writes the unloaded files to the local directory.
+func ExportToDir(dir string) ExportDestination {
+	return func(name string) (io.WriteCloser, error) {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if rel, err := filepath.Rel(dir, path); err != nil || strings.HasPrefix(rel, "..") {
+			return nil, fmt.Errorf("invalid file name: %v", name)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, err
+		}
+		return os.Create(path)
+	}
+}
+
+// UnloadedFile is a file unloaded by Client.ExportQuery.
+type UnloadedFile struct {
+	Name string
+	Size int64
+}
+
+// UnloadResult is the result of Client.ExportQuery.
+type UnloadResult struct {
+	QueryID string // the query ID of COPY INTO
+	Rows    int64  // number of unloaded rows
+	Files   []UnloadedFile
+}
+
+// ExportQuery unloads the result of the query with COPY INTO a temporary stage, and downloads the unloaded files
+// to the destination in parallel through presigned URLs. This is the fastest way to extract a large result from
+// Snowflake. The temporary stage is dropped when the export completes.
+func (c *Client) ExportQuery(ctx context.Context, query string, dest ExportDestination, opts *UnloadOptions) (*UnloadResult, error) {
+	if opts == nil {
+		opts = &UnloadOptions{}
+	}
+	// the presigned URLs of an internal stage require server side encryption
+	stage := "SF_GO_UNLOAD_" + strings.ToUpper(strings.Replace(uuid.New().String(), "-", "", -1))
+	if _, err := c.sc.ExecContext(withoutIdempotencyKey(ctx), "CREATE TEMPORARY STAGE "+stage+" ENCRYPTION = (TYPE = 'SNOWFLAKE_SSE')", nil); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if _, err := c.sc.ExecContext(context.Background(), "DROP STAGE IF EXISTS "+stage, nil); err != nil {
+			glog.V(1).Infof("failed to drop the stage %v. err: %v", stage, err)
+		}
+	}()
+
+	format := opts.FileFormat
+	if format == "" {
+		format = defaultUnloadFileFormat
+	}
+	copyInto := fmt.Sprintf("COPY INTO @%v/ FROM (%v) FILE_FORMAT = (%v)", stage, query, format)
+	if opts.MaxFileSize > 0 {
+		copyInto += " MAX_FILE_SIZE = " + strconv.FormatInt(opts.MaxFileSize, 10)
+	}
+	ret := &UnloadResult{}
+	rows, err := c.queryValues(ctx, copyInto, &ret.QueryID)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		// rows_unloaded, input_bytes, output_bytes
+		if n, err := strconv.ParseInt(fmt.Sprint(row[0]), 10, 64); err == nil {
+			ret.Rows += n
+		}
+	}
+
+	if rows, err = c.queryValues(ctx, "LIST @"+stage, nil); err != nil {
+		return nil, err
+	}
+	urls := make([]string, len(rows))
+	for i, row := range rows {
+		// name, size, md5, last_modified. the name starts with the lower case stage name
+		name := fmt.Sprint(row[0])
+		if idx := strings.IndexByte(name, '/'); idx >= 0 {
+			name = name[idx+1:]
+		}
+		size, _ := strconv.ParseInt(fmt.Sprint(row[1]), 10, 64)
+		ret.Files = append(ret.Files, UnloadedFile{Name: name, Size: size})
+		presigned, err := c.queryValues(ctx, fmt.Sprintf("SELECT GET_PRESIGNED_URL(@%v, ?)", stage), nil, name)
+		if err != nil {
+			return nil, err
+		}
+		if len(presigned) == 0 {
+			return nil, fmt.Errorf("no presigned URL of %v", name)
+		}
+		urls[i] = fmt.Sprint(presigned[0][0])
+	}
+	if err = c.downloadUnloadedFiles(ctx, ret.Files, urls, dest, opts.Parallelism); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// downloadUnloadedFiles downloads the files from the presigned URLs to the destination, parallelism at a time.
+// The first error cancels the rest of the downloads.
+func (c *Client) downloadUnloadedFiles(ctx context.Context, files []UnloadedFile, urls []string, dest ExportDestination, parallelism int) error {
+	if parallelism <= 0 {
+		parallelism = defaultUnloadParallelism
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	for i := range files {
+		sem <- struct{}{}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(name string, u string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := c.downloadUnloadedFile(ctx, name, u, dest); err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(files[i].Name, urls[i])
+	}
+	wg.Wait()
+	return firstErr
+}
+
+func (c *Client) downloadUnloadedFile(ctx context.Context, name string, u string, dest ExportDestination) error {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.sc.rest.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer drainAndClose(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return &SnowflakeError{
+			Number:      ErrFailedToDownloadFile,
+			SQLState:    SQLStateConnectionFailure,
+			Message:     errMsgFailedToDownloadFile,
+			MessageArgs: []interface{}{name, resp.StatusCode},
+		}
+	}
+	w, err := dest(name)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(w, resp.Body); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// queryValues runs the query and returns all the rows. The query ID is set to queryID unless it is nil.
+func (c *Client) queryValues(ctx context.Context, query string, queryID *string, args ...interface{}) ([][]driver.Value, error) {
+	nvs, err := c.namedValues(args)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := c.sc.QueryContext(ctx, query, nvs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if queryID != nil {
+		*queryID = rows.(SnowflakeResult).QueryID()
+	}
+	var ret [][]driver.Value
+	for {
+		row := make([]driver.Value, len(rows.Columns()))
+		if err = rows.Next(row); err == io.EOF {
+			return ret, nil
+		} else if err != nil {
+			return nil, err
+		}
+		ret = append(ret, row)
+	}
+}
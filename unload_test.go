@@ -0,0 +1,102 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestExportQuery(t *testing.T) {
+	files := map[string]string{"/data_0_0_0.csv.gz": "file0", "/data_0_1_0.csv.gz": "file1"}
+	storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := files[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(content))
+	}))
+	defer storage.Close()
+
+	var mu sync.Mutex
+	var stmts []string
+	text := func(ss ...string) [][]*string {
+		row := make([]*string, len(ss))
+		for i := range ss {
+			row[i] = &ss[i]
+		}
+		return [][]*string{row}
+	}
+	c := &Client{sc: &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{
+			Client: storage.Client(),
+			FuncPostQuery: func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte, _ time.Duration, _ *uuid.UUID) (*execResponse, error) {
+				var req execRequest
+				if err := json.Unmarshal(body, &req); err != nil {
+					return nil, err
+				}
+				mu.Lock()
+				stmts = append(stmts, req.SQLText)
+				mu.Unlock()
+				data := execResponseData{QueryID: "qid", QueryResultFormat: jsonFormat}
+				switch {
+				case strings.HasPrefix(req.SQLText, "COPY INTO"):
+					data.RowType = []execResponseRowType{{Name: "rows_unloaded", Type: "fixed"}, {Name: "input_bytes", Type: "fixed"}, {Name: "output_bytes", Type: "fixed"}}
+					data.RowSet = text("42", "100", "10")
+				case strings.HasPrefix(req.SQLText, "LIST"):
+					data.RowType = []execResponseRowType{{Name: "name", Type: "text"}, {Name: "size", Type: "fixed"}}
+					data.RowSet = append(text("sf_go_unload_x/data_0_0_0.csv.gz", "5"), text("sf_go_unload_x/data_0_1_0.csv.gz", "5")...)
+				case strings.HasPrefix(req.SQLText, "SELECT GET_PRESIGNED_URL"):
+					data.RowType = []execResponseRowType{{Name: "url", Type: "text"}}
+					data.RowSet = text(storage.URL + "/" + req.Bindings["1"].Value.(string) + "?sig=x")
+				default:
+					data.RowType = []execResponseRowType{{Name: "status", Type: "text"}}
+					data.RowSet = text("ok")
+				}
+				data.Total = int64(len(data.RowSet))
+				return &execResponse{Data: data, Code: "0", Success: true}, nil
+			},
+		},
+	}}
+
+	dir := t.TempDir()
+	res, err := c.ExportQuery(context.Background(), "SELECT * FROM t", ExportToDir(dir), &UnloadOptions{MaxFileSize: 1 << 20})
+	if err != nil {
+		t.Fatalf("failed to export. err: %v", err)
+	}
+	if res.Rows != 42 || len(res.Files) != 2 || res.Files[1].Name != "data_0_1_0.csv.gz" || res.Files[1].Size != 5 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	for i := 0; i < 2; i++ {
+		name := res.Files[i].Name
+		b, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil || string(b) != files["/"+name] {
+			t.Fatalf("unexpected file %v: %q, err: %v", name, b, err)
+		}
+	}
+	if !strings.HasPrefix(stmts[0], "CREATE TEMPORARY STAGE SF_GO_UNLOAD_") ||
+		!strings.Contains(stmts[1], "FROM (SELECT * FROM t) FILE_FORMAT = (TYPE = CSV COMPRESSION = GZIP) MAX_FILE_SIZE = 1048576") ||
+		!strings.HasPrefix(stmts[len(stmts)-1], "DROP STAGE IF EXISTS SF_GO_UNLOAD_") {
+		t.Fatalf("unexpected statements: %v", stmts)
+	}
+
+	// a file that fails to be downloaded fails the export
+	delete(files, "/data_0_1_0.csv.gz")
+	_, err = c.ExportQuery(context.Background(), "SELECT * FROM t", ExportToDir(t.TempDir()), nil)
+	if e, ok := err.(*SnowflakeError); !ok || e.Number != ErrFailedToDownloadFile {
+		t.Fatalf("should fail to download. err: %v", err)
+	}
+}
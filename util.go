@@ -7,6 +7,57 @@ import (
 	"time"
 )
 
+type contextKey string
+
+const (
+	// fetchResultByID is the context key of the query ID whose result is fetched by QueryContext
+	fetchResultByID contextKey = "SF_FETCH_RESULT_BY_ID"
+	// resultProgress is the context key of the ResultProgressFunc receiving the download progress
+	resultProgress contextKey = "SF_RESULT_PROGRESS"
+	// maxResultRows is the context key of the maximum number of rows read from a result
+	maxResultRows contextKey = "SF_MAX_RESULT_ROWS"
+	// maxResultBytes is the context key of the maximum number of bytes downloaded for a result
+	maxResultBytes contextKey = "SF_MAX_RESULT_BYTES"
+	// asyncCompletion is the context key of the AsyncCompletionFunc notified when an asynchronous query completes
+	asyncCompletion contextKey = "SF_ASYNC_COMPLETION"
+	// asyncCompletionChan is the context key of the channel the completion of an asynchronous query is sent to
+	asyncCompletionChan contextKey = "SF_ASYNC_COMPLETION_CHAN"
+	// responseExtensions is the context key of the flag to capture the unknown fields of the query response
+	responseExtensions contextKey = "SF_RESPONSE_EXTENSIONS"
+	// noFollowRedirect is the context key of the flag to return redirect responses instead of following them
+	noFollowRedirect contextKey = "SF_NO_FOLLOW_REDIRECT"
+	// skipBadChunks is the context key of the SkippedChunkFunc notified when a result chunk is skipped
+	skipBadChunks contextKey = "SF_SKIP_BAD_CHUNKS"
+	// rawResults is the context key of the flag to return the values of the results without conversion
+	rawResults contextKey = "SF_RAW_RESULTS"
+	// httpHeaders is the context key of the extra HTTP headers of the query requests
+	httpHeaders contextKey = "SF_HTTP_HEADERS"
+	// timeFormat is the context key of the TimeFormat of the TIME values of the results
+	timeFormat contextKey = "SF_TIME_FORMAT"
+	// retryableDML is the context key of the flag to re-submit the statements that change data on retryable errors
+	retryableDML contextKey = "SF_RETRYABLE_DML"
+	// queryTagKey is the context key of the QUERY_TAG of the queries
+	queryTagKey contextKey = "SF_QUERY_TAG"
+	// queryLabels is the context key of the labels set in the QUERY_TAG of the queries as a JSON object
+	queryLabels contextKey = "SF_QUERY_LABELS"
+	// describeOnly is the context key of the flag to describe the query without running it
+	describeOnly contextKey = "SF_DESCRIBE_ONLY"
+	// queryIDChan is the context key of the queryIDSender of the channel receiving the query IDs
+	queryIDChan contextKey = "SF_QUERY_ID_CHAN"
+	// queryLogKey is the context key of the queryLog with the IDs of the query for the logs and the errors
+	queryLogKey contextKey = "SF_QUERY_LOG"
+	// wireTrace is the context key of the wireTracer writing the traces of the HTTP requests
+	wireTrace contextKey = "SF_WIRE_TRACE"
+	// idempotencyKey is the context key of the key from which the request IDs of the statements are derived
+	idempotencyKey contextKey = "SF_IDEMPOTENCY_KEY"
+	// rejectNonFiniteFloats is the context key of the flag to fail on NaN and infinite floats instead of passing them
+	rejectNonFiniteFloats contextKey = "SF_REJECT_NON_FINITE_FLOATS"
+	// uuidResults is the context key of the names of the TEXT columns whose UUIDs ResultSet returns as uuid.UUID
+	uuidResults contextKey = "SF_UUID_RESULTS"
+	// strictScan is the context key of the flag to fail the scans of ResultSet that would lose a part of the values
+	strictScan contextKey = "SF_STRICT_SCAN"
+)
+
 // integer min
 func intMin(a, b int) int {
 	if a < b {
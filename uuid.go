@@ -0,0 +1,58 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// WithUUIDResults returns a context whose result sets, returned by QueryResultSet or Client, return the values of
+// the TEXT columns of the names that are UUIDs in the canonical form of 36 characters, e.g.,
+// 6ba7b810-9dad-11d1-80b4-00c04fd430c8, as uuid.UUID instead of string, e.g., for the rows read with
+// ResultSet.NextBatch or scanned into interface{} values. The names are matched case-insensitively, and the other
+// columns stay string. ResultSet.Scan scans such a value into a string or a uuid.UUID as well.
+//
+// database/sql receives the values as string, as uuid.UUID is not a driver.Value, so that it doesn't apply to
+// Rows.Scan. Scanning the text into a uuid.UUID works without it, as uuid.UUID is an sql.Scanner.
+func WithUUIDResults(ctx context.Context, columns ...string) context.Context {
+	return context.WithValue(ctx, uuidResults, columns)
+}
+
+// uuidBindValue returns the text of the bind variable if it is a uuid.UUID or a [16]byte, which are bound as the
+// canonical form of a UUID.
+func uuidBindValue(v interface{}) (string, bool) {
+	switch u := v.(type) {
+	case uuid.UUID:
+		return u.String(), true
+	case [16]byte:
+		return uuid.UUID(u).String(), true
+	}
+	return "", false
+}
+
+// convertUUIDs replaces the values of the TEXT columns of the names that are UUIDs with uuid.UUID.
+func convertUUIDs(rowType []execResponseRowType, columns []string, dest []driver.Value) {
+	for i, v := range dest {
+		s, ok := v.(string)
+		if !ok || len(s) != 36 || i >= len(rowType) || !strings.EqualFold(rowType[i].Type, "text") ||
+			!isUUIDColumn(columns, rowType[i].Name) {
+			continue
+		}
+		if u, err := uuid.Parse(s); err == nil {
+			dest[i] = u
+		}
+	}
+}
+
+func isUUIDColumn(columns []string, name string) bool {
+	for _, c := range columns {
+		if strings.EqualFold(c, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestUUIDBinds(t *testing.T) {
+	id := uuid.MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	c := newFakeQueryClient([]execResponseRowType{{Name: "ID", Type: "text"}})
+	rs, err := c.Query(context.Background(), "SELECT ?, ?, ?", id, [16]byte(id), []uuid.UUID{id, uuid.Nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs.Close()
+	b, _ := json.Marshal(c.recorded()[0].Bindings)
+	expected := `{"1":{"type":"TEXT","value":"6ba7b810-9dad-11d1-80b4-00c04fd430c8"},` +
+		`"2":{"type":"TEXT","value":"6ba7b810-9dad-11d1-80b4-00c04fd430c8"},` +
+		`"3":{"type":"TEXT","value":["6ba7b810-9dad-11d1-80b4-00c04fd430c8","00000000-0000-0000-0000-000000000000"]}}`
+	if string(b) != expected {
+		t.Fatalf("the UUIDs should be bound as text. expected: %v, got: %s", expected, b)
+	}
+}
+
+func TestUUIDResults(t *testing.T) {
+	values := []string{"6ba7b810-9dad-11d1-80b4-00c04fd430c8", "not a uuid but 36 characters long...."}
+	c := newFakeQueryClient([]execResponseRowType{{Name: "ID", Type: "text"}, {Name: "OTHER", Type: "text"}},
+		[]string{values[0], values[0]}, []string{values[1], values[0]})
+
+	rs, err := c.Query(WithUUIDResults(context.Background(), "id"), "SELECT id, other FROM t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Close()
+	rows, err := rs.NextBatch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u, ok := rows[0][0].(uuid.UUID); !ok || u.String() != values[0] {
+		t.Fatalf("the UUID should be returned as uuid.UUID. got: %T %v", rows[0][0], rows[0][0])
+	}
+	if s, ok := rows[0][1].(string); !ok || s != values[0] {
+		t.Fatalf("the UUID of the other column should be returned as string. got: %T %v", rows[0][1], rows[0][1])
+	}
+	if s, ok := rows[1][0].(string); !ok || s != values[1] {
+		t.Fatalf("the text that is not a UUID should be returned as string. got: %T %v", rows[1][0], rows[1][0])
+	}
+	var s string
+	if err = scanValue(&s, rows[0][0]); err != nil || s != values[0] {
+		t.Fatalf("the UUID should be scanned into a string. got: %v, err: %v", s, err)
+	}
+	var u uuid.UUID
+	if err = scanValue(&u, rows[0][0]); err != nil || u.String() != values[0] {
+		t.Fatalf("the UUID should be scanned into uuid.UUID. got: %v, err: %v", u, err)
+	}
+
+	rs, err = c.Query(WithUUIDResults(context.Background(), "ID"), "SELECT id, other FROM t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Close()
+	var v interface{}
+	if !rs.Next() || rs.Scan(&v, &s) != nil {
+		t.Fatalf("failed to scan the row. err: %v", rs.Err())
+	}
+	if _, ok := v.(uuid.UUID); !ok {
+		t.Fatalf("the UUID should be scanned into interface{} as uuid.UUID. got: %T", v)
+	}
+
+	rs, err = c.Query(context.Background(), "SELECT id, other FROM t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Close()
+	u = uuid.Nil
+	if !rs.Next() || rs.Scan(&u, &s) != nil || u.String() != values[0] {
+		t.Fatalf("the text should be scanned into uuid.UUID. got: %v, err: %v", u, rs.Err())
+	}
+}
+
+func TestUUIDResultsDriverValues(t *testing.T) {
+	c := newFakeQueryClient([]execResponseRowType{{Name: "ID", Type: "text"}},
+		[]string{"6ba7b810-9dad-11d1-80b4-00c04fd430c8"})
+	rows, err := c.sc.QueryContext(WithUUIDResults(context.Background(), "ID"), "SELECT id FROM t", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	dest := make([]driver.Value, 1)
+	if err = rows.Next(dest); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := dest[0].(string); !ok {
+		t.Fatalf("database/sql should receive the UUID as string. got: %T", dest[0])
+	}
+}
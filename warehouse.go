@@ -0,0 +1,36 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"database/sql/driver"
+)
+
+const resumeWarehouseQuery = "ALTER WAREHOUSE IDENTIFIER(?) RESUME IF SUSPENDED"
+
+// IsWarehouseSuspended returns true if the error is returned because the warehouse of the session is suspended
+// and doesn't resume automatically.
+func IsWarehouseSuspended(err error) bool {
+	se, ok := err.(*SnowflakeError)
+	return ok && se.Number == ErrNoActiveWarehouse
+}
+
+// WarmUpWarehouse resumes the warehouse of the connection if it is suspended, so that latency-critical
+// services don't wait for the warehouse to resume on the first query.
+func (sc *snowflakeConn) WarmUpWarehouse(ctx context.Context) error {
+	if sc.rest == nil {
+		return driver.ErrBadConn
+	}
+	if sc.cfg.Warehouse == "" {
+		return &SnowflakeError{
+			Number:  ErrCodeNoWarehouse,
+			Message: errMsgNoWarehouse,
+		}
+	}
+	glog.V(2).Infof("resuming warehouse %v", sc.cfg.Warehouse)
+	_, err := sc.execOnce(ctx, resumeWarehouseQuery, false, true, []driver.NamedValue{
+		{Ordinal: 1, Value: sc.cfg.Warehouse},
+	})
+	return err
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestAutoResumeWarehouse(t *testing.T) {
+	var queries []string
+	resumed := false
+	sc := &snowflakeConn{
+		cfg: &Config{Params: map[string]*string{}, Warehouse: "WH1", AutoResumeWarehouse: true},
+		rest: &snowflakeRestful{
+			FuncPostQuery: func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte, _ time.Duration, _ *uuid.UUID) (*execResponse, error) {
+				var req execRequest
+				if err := json.Unmarshal(body, &req); err != nil {
+					return nil, err
+				}
+				queries = append(queries, req.SQLText)
+				if req.SQLText == resumeWarehouseQuery {
+					if v := req.Bindings["1"].Value; v != "WH1" {
+						t.Errorf("unexpected warehouse: %v", v)
+					}
+					resumed = true
+				} else if !resumed {
+					return &execResponse{
+						Data:    execResponseData{SQLState: "57P03"},
+						Message: "No active warehouse selected in the current session.",
+						Code:    "000606",
+					}, nil
+				}
+				return &execResponse{
+					Data:    execResponseData{FinalWarehouseName: "WH1"},
+					Code:    "0",
+					Success: true,
+				}, nil
+			},
+		},
+	}
+	if _, err := sc.ExecContext(context.Background(), "CREATE TABLE t(c1 int)", nil); err != nil {
+		t.Fatalf("failed to execute the statement. err: %v", err)
+	}
+	if len(queries) != 3 || queries[1] != resumeWarehouseQuery {
+		t.Fatalf("the warehouse should have been resumed before the retry. queries: %v", queries)
+	}
+
+	resumed = false
+	queries = nil
+	sc.cfg.AutoResumeWarehouse = false
+	_, err := sc.ExecContext(context.Background(), "CREATE TABLE t(c1 int)", nil)
+	if !IsWarehouseSuspended(err) {
+		t.Fatalf("should have failed because the warehouse is suspended. err: %v", err)
+	}
+	if len(queries) != 1 {
+		t.Fatalf("the statement should not be retried. queries: %v", queries)
+	}
+}
+
+func TestWarmUpWarehouseNoWarehouse(t *testing.T) {
+	sc := &snowflakeConn{
+		cfg:  &Config{Params: map[string]*string{}},
+		rest: &snowflakeRestful{},
+	}
+	err := sc.WarmUpWarehouse(context.Background())
+	if driverErr, ok := err.(*SnowflakeError); !ok || driverErr.Number != ErrCodeNoWarehouse {
+		t.Fatalf("should have failed without a warehouse. err: %v", err)
+	}
+}
+
+func TestParseDSNAutoResumeWarehouse(t *testing.T) {
+	cfg, err := ParseDSN("u:p@a.snowflakecomputing.com:443?autoResumeWarehouse=true")
+	if err != nil {
+		t.Fatalf("failed to parse the DSN. err: %v", err)
+	}
+	if !cfg.AutoResumeWarehouse {
+		t.Fatal("AutoResumeWarehouse should be set")
+	}
+	dsn, err := DSN(cfg)
+	if err != nil {
+		t.Fatalf("failed to build the DSN. err: %v", err)
+	}
+	if cfg, err = ParseDSN(dsn); err != nil || !cfg.AutoResumeWarehouse {
+		t.Fatalf("AutoResumeWarehouse should be kept in the DSN %v. err: %v", dsn, err)
+	}
+}
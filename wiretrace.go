@@ -0,0 +1,237 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// wireTraceBodyLimit is the number of bytes of a body written to the wire trace.
+const wireTraceBodyLimit = 4096
+
+// wireTraceRedacted replaces the credentials in the wire trace.
+const wireTraceRedacted = "REDACTED"
+
+// wireTraceRedactedHeaders are the headers whose values are not written to the wire trace.
+var wireTraceRedactedHeaders = map[string]bool{
+	http.CanonicalHeaderKey(headerAuthorizationKey):    true,
+	"Proxy-Authorization":                              true,
+	"Cookie":                                           true,
+	"Set-Cookie":                                       true,
+	http.CanonicalHeaderKey(headerSseCKey):             true,
+	http.CanonicalHeaderKey(headerAzureIdentityHeader): true,
+}
+
+// wireTraceRedactedParams are the names, in lower case, of the URL parameters whose values are not written to
+// the wire trace, e.g., the signatures of the presigned URLs of the result chunks.
+var wireTraceRedactedParams = map[string]bool{
+	"token":                true,
+	"sig":                  true,
+	"signature":            true,
+	"x-amz-signature":      true,
+	"x-amz-credential":     true,
+	"x-amz-security-token": true,
+	"x-goog-signature":     true,
+	"x-goog-credential":    true,
+	"googleaccessid":       true,
+}
+
+// wireTraceStringField matches the JSON string fields, also at the end of a truncated body. The values of the
+// ones with a credential, see isSecretField, are redacted.
+var wireTraceStringField = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"\s*:\s*"(?:[^"\\]|\\.)*(?:"|\\?\z)`)
+
+// wireTracer writes the traces of the HTTP requests to the writer, one at a time.
+type wireTracer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// WithWireTrace returns a context that writes a trace of every HTTP request sent for the operations run with it,
+// e.g., the login of a connection or a query and the download of its result, to w: the method, the URL, the
+// headers and the first bytes of the body of the request and of the response, and the time until the response.
+// The credentials, e.g., the password, the session token and the signatures of the presigned URLs, are redacted,
+// but the SQL text and the data are not. The traces of concurrent requests are not interleaved.
+func WithWireTrace(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, wireTrace, &wireTracer{w: w})
+}
+
+// wireTraceTransport writes the traces of the requests with a wire trace in their context.
+type wireTraceTransport struct {
+	base http.RoundTripper
+}
+
+func (t *wireTraceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	tracer, ok := req.Context().Value(wireTrace).(*wireTracer)
+	if !ok {
+		return t.base.RoundTrip(req)
+	}
+	var trace bytes.Buffer
+	fmt.Fprintf(&trace, "--> %v %v\n", req.Method, redactURL(req.URL))
+	writeTraceHeaders(&trace, req.Header)
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := peekRequestBody(req)
+		if err != nil {
+			return nil, err
+		}
+		writeTraceBody(&trace, body, req.ContentLength)
+	}
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	elapsed := time.Since(start)
+	if err != nil {
+		fmt.Fprintf(&trace, "<-- error after %v. err: %v\n\n", elapsed, err)
+		tracer.write(trace.Bytes())
+		return resp, err
+	}
+	fmt.Fprintf(&trace, "<-- %v (%v) %v %v\n", resp.Status, elapsed, req.Method, redactURL(req.URL))
+	writeTraceHeaders(&trace, resp.Header)
+	if resp.Body != nil && resp.Body != http.NoBody {
+		writeTraceBody(&trace, peekResponseBody(resp), resp.ContentLength)
+	}
+	trace.WriteString("\n")
+	tracer.write(trace.Bytes())
+	return resp, nil
+}
+
+func (t *wireTracer) write(trace []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, err := t.w.Write(trace); err != nil {
+		glog.V(1).Infof("failed to write the wire trace. err: %v", err)
+	}
+}
+
+// peekRequestBody returns the first bytes of the body of the request, leaving the body to be sent.
+func peekRequestBody(req *http.Request) ([]byte, error) {
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		return ioutil.ReadAll(io.LimitReader(body, wireTraceBodyLimit+1))
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(req.Body, wireTraceBodyLimit+1))
+	if err != nil {
+		req.Body.Close()
+		return nil, err
+	}
+	req.Body = &peekedBody{Reader: io.MultiReader(bytes.NewReader(b), req.Body), body: req.Body}
+	return b, nil
+}
+
+// peekResponseBody returns the first bytes of the body of the response, which are read again by the caller.
+func peekResponseBody(resp *http.Response) []byte {
+	b, err := ioutil.ReadAll(io.LimitReader(resp.Body, wireTraceBodyLimit+1))
+	rest := io.Reader(resp.Body)
+	if err != nil {
+		// the caller gets the error when reading the body
+		rest = &errReader{err}
+	}
+	resp.Body = &peekedBody{Reader: io.MultiReader(bytes.NewReader(b), rest), body: resp.Body}
+	return b
+}
+
+// peekedBody reads the bytes read for the trace before the rest of the body.
+type peekedBody struct {
+	io.Reader
+	body io.ReadCloser
+}
+
+func (b *peekedBody) Close() error {
+	return b.body.Close()
+}
+
+// errReader returns the error of reading the body for the trace to the caller.
+type errReader struct {
+	err error
+}
+
+func (r *errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+func writeTraceHeaders(trace *bytes.Buffer, header http.Header) {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := strings.Join(header[name], ", ")
+		if wireTraceRedactedHeaders[http.CanonicalHeaderKey(name)] {
+			value = wireTraceRedacted
+		}
+		fmt.Fprintf(trace, "%v: %v\n", name, value)
+	}
+}
+
+// writeTraceBody writes the first wireTraceBodyLimit bytes of the body, or its size if it is binary, e.g., a
+// compressed result chunk. size is the length of the whole body, or -1 if it is unknown.
+func writeTraceBody(trace *bytes.Buffer, body []byte, size int64) {
+	truncated := len(body) > wireTraceBodyLimit
+	if truncated {
+		body = body[:wireTraceBodyLimit]
+	}
+	text := body
+	if truncated && len(text) > utf8.UTFMax {
+		// the last rune may be cut
+		text = text[:len(text)-utf8.UTFMax]
+	}
+	if !utf8.Valid(text) || bytes.IndexByte(text, 0) >= 0 {
+		fmt.Fprintf(trace, "<binary body. size: %v>\n", size)
+		return
+	}
+	trace.Write(redactSecretFields(body))
+	if truncated {
+		fmt.Fprintf(trace, "... <truncated. size: %v>", size)
+	}
+	trace.WriteString("\n")
+}
+
+// redactSecretFields returns the JSON text with the values of the string fields with a credential redacted.
+func redactSecretFields(body []byte) []byte {
+	var redacted []byte
+	last := 0
+	for _, m := range wireTraceStringField.FindAllSubmatchIndex(body, -1) {
+		if !isSecretField(string(body[m[2]:m[3]])) {
+			continue
+		}
+		redacted = append(redacted, body[last:m[0]]...)
+		redacted = append(redacted, body[m[0]:m[3]+1]...)
+		redacted = append(redacted, `:"`+wireTraceRedacted+`"`...)
+		last = m[1]
+	}
+	if redacted == nil {
+		return body
+	}
+	return append(redacted, body[last:]...)
+}
+
+// redactURL returns the URL without the values of the parameters with credentials.
+func redactURL(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.String()
+	}
+	q := u.Query()
+	for name := range q {
+		if wireTraceRedactedParams[strings.ToLower(name)] {
+			q[name] = []string{wireTraceRedacted}
+		}
+	}
+	redactedURL := *u
+	redactedURL.RawQuery = q.Encode()
+	return redactedURL.String()
+}
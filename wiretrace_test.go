@@ -0,0 +1,125 @@
+// Copyright (c) 2020 Snowflake Computing Inc. All right reserved.
+
+package gosnowflake
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWireTrace(t *testing.T) {
+	large := `{"rowset":"` + strings.Repeat("x", 2*wireTraceBodyLimit) + `"}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/large" {
+			w.Write([]byte(large))
+			return
+		}
+		if r.URL.Path == "/echo" {
+			body, _ := ioutil.ReadAll(r.Body)
+			w.Write(body)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		if !strings.Contains(string(body), `"PASSWORD":"secret1"`) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Set-Cookie", "session=secret2")
+		w.Write([]byte(`{"data":{"token":"secret3","masterToken":"secret4","sessionId":42},"success":true}`))
+	}))
+	defer server.Close()
+	client := newHTTPClient(&Config{})
+
+	var trace bytes.Buffer
+	ctx := WithWireTrace(context.Background(), &trace)
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/session/v1/login-request?request_guid=g1&token=secret5",
+		strings.NewReader(`{"data":{"LOGIN_NAME":"u","PASSWORD":"secret1"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(headerAuthorizationKey, "Snowflake Token=\"secret6\"")
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "secret3") {
+		t.Fatalf("the request and the response should not be changed. status: %v, body: %s", resp.Status, body)
+	}
+	for _, expected := range []string{
+		"--> POST " + server.URL + "/session/v1/login-request?request_guid=g1&token=REDACTED",
+		`"LOGIN_NAME":"u"`,
+		"<-- 200 OK (",
+		`"sessionId":42`,
+	} {
+		if !strings.Contains(trace.String(), expected) {
+			t.Errorf("the trace should have %q. trace:\n%v", expected, trace.String())
+		}
+	}
+	for i := 1; i <= 6; i++ {
+		if secret := "secret" + string(rune('0'+i)); strings.Contains(trace.String(), secret) {
+			t.Errorf("%v should be redacted. trace:\n%v", secret, trace.String())
+		}
+	}
+
+	trace.Reset()
+	req, _ = http.NewRequest(http.MethodGet, server.URL+"/large", nil)
+	resp, err = client.Do(req.WithContext(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != large {
+		t.Fatalf("the whole body should be read. length: %v", len(body))
+	}
+	if trace.Len() > 2*wireTraceBodyLimit || !strings.Contains(trace.String(), "<truncated. size: ") {
+		t.Fatalf("the body should be truncated in the trace. length: %v", trace.Len())
+	}
+
+	trace.Reset()
+	// a body without GetBody is read for the trace only up to the limit
+	req, _ = http.NewRequest(http.MethodPost, server.URL+"/echo", ioutil.NopCloser(strings.NewReader(large)))
+	resp, err = client.Do(req.WithContext(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != large {
+		t.Fatalf("the whole body of the request should be sent. length: %v", len(body))
+	}
+	if !strings.Contains(trace.String(), "<truncated. size: ") {
+		t.Fatalf("the body of the request should be truncated in the trace. trace length: %v", trace.Len())
+	}
+
+	trace.Reset()
+	resp, err = client.Get(server.URL + "/large")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if trace.Len() != 0 {
+		t.Fatalf("the requests without the wire trace should not be traced. trace:\n%v", trace.String())
+	}
+}
+
+func TestWireTraceTruncatedSecret(t *testing.T) {
+	var trace bytes.Buffer
+	body := []byte(strings.Repeat(" ", wireTraceBodyLimit-20) + `{"token":"` + strings.Repeat("s", 100) + `"}`)
+	writeTraceBody(&trace, body, int64(len(body)))
+	if strings.Contains(trace.String(), "sss") {
+		t.Fatalf("the secret at the end of a truncated body should be redacted. trace: %v", trace.String())
+	}
+	trace.Reset()
+	writeTraceBody(&trace, []byte{0x1f, 0x8b, 0, 0}, 4)
+	if trace.String() != "<binary body. size: 4>\n" {
+		t.Fatalf("a binary body should not be written. trace: %q", trace.String())
+	}
+}